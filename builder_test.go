@@ -0,0 +1,260 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package gidari
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+func TestConfigBuilder(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			wtr.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		wtr.Header().Set("Content-Type", "application/json")
+		_, _ = wtr.Write([]byte(`[{"id":"BTC","name":"Bitcoin"},{"id":"ETH","name":"Ethereum"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+
+	var committed []config.RequestProgress
+
+	err := NewConfig().
+		WithURL(server.URL).
+		WithBearer("token").
+		WithRateLimit(5, time.Second).
+		AddConnectionString("file://" + dir).
+		AddRequest(&config.Request{Endpoint: "/currencies"}).
+		WithProgress(func(progress config.RequestProgress) { committed = append(committed, progress) }).
+		Upsert(context.Background())
+	if err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	if len(committed) != 1 || committed[0].Table != "currencies" || committed[0].Status != config.RequestCommitted {
+		t.Errorf("expected the request to be committed, got %+v", committed)
+	}
+
+	bytes, err := os.ReadFile(filepath.Join(dir, "currencies.ndjson"))
+	if err != nil {
+		t.Fatalf("failed to read table file: %v", err)
+	}
+
+	if lines := strings.Count(string(bytes), "\n"); lines != 2 {
+		t.Errorf("expected 2 records, got %d:\n%s", lines, bytes)
+	}
+
+	if _, err := NewConfig().WithURL(server.URL).Build(); !errors.Is(err, config.ErrMissingConfigField) {
+		t.Errorf("expected %v, got %v", config.ErrMissingConfigField, err)
+	}
+}
+
+// fieldsLogger records the fields of the messages that are logged with it.
+type fieldsLogger struct {
+	mutex  sync.Mutex
+	fields []map[string]interface{}
+}
+
+func (logger *fieldsLogger) Log(_ tools.LogLevel, _ string, fields map[string]interface{}) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	logger.fields = append(logger.fields, fields)
+}
+
+func TestTransportRunID(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		wtr.Header().Set("Content-Type", "application/json")
+		_, _ = wtr.Write([]byte(`[{"id":"BTC","name":"Bitcoin"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	logger := &fieldsLogger{}
+
+	cfg, err := NewConfig().
+		WithURL(server.URL).
+		WithRateLimit(5, time.Second).
+		AddConnectionString("file://" + dir).
+		AddRequest(&config.Request{Endpoint: "/currencies"}).
+		WithLogger(logger).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	cfg.Metadata = true
+
+	runIDs := make(map[interface{}]bool)
+
+	for run := 0; run < 2; run++ {
+		if err := Transport(context.Background(), cfg); err != nil {
+			t.Fatalf("failed to transport: %v", err)
+		}
+	}
+
+	// Every message of a run is logged with its ID, and each run has its own.
+	for _, fields := range logger.fields {
+		if fields["runID"] == nil {
+			t.Fatalf("expected every message to be logged with the run ID, got %v", fields)
+		}
+
+		runIDs[fields["runID"]] = true
+	}
+
+	if len(runIDs) != 2 {
+		t.Errorf("expected the messages of 2 runs, got %v", runIDs)
+	}
+
+	bytes, err := os.ReadFile(filepath.Join(dir, "currencies.ndjson"))
+	if err != nil {
+		t.Fatalf("failed to read table file: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(bytes)), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to decode record: %v", err)
+		}
+
+		if !runIDs[record["_gidari_run_id"]] {
+			t.Errorf("expected the record to have the ID of its run, got %v", record["_gidari_run_id"])
+		}
+	}
+}
+
+func TestTransportCancelled(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		// The products are only fetched once the run has been cancelled.
+		if req.URL.Path == "/products" {
+			<-req.Context().Done()
+
+			return
+		}
+
+		wtr.Header().Set("Content-Type", "application/json")
+		_, _ = wtr.Write([]byte(`[{"id":"BTC","name":"Bitcoin"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+
+	cfg, err := NewConfig().
+		WithURL(server.URL).
+		WithRateLimit(5, time.Second).
+		AddConnectionString("file://" + dir).
+		AddRequest(&config.Request{Endpoint: "/currencies"}).
+		AddRequest(&config.Request{Endpoint: "/products"}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var statuses []string
+
+	// The run is cancelled once the currencies have been committed.
+	cfg.Progress = func(progress config.RequestProgress) {
+		statuses = append(statuses, progress.Status)
+
+		cancel()
+	}
+
+	if err := Transport(ctx, cfg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be cancelled, got %v", err)
+	}
+
+	if want := []string{config.RequestCommitted, config.RequestRolledBack}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("expected the currencies to be committed and the products rolled back, got %v", statuses)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "currencies.ndjson")); err != nil {
+		t.Errorf("expected the currencies to be written: %v", err)
+	}
+}
+
+func TestTransportMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	// The upstream is too slow for the run to finish in its max duration.
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, err := NewConfig().
+		WithURL(server.URL).
+		WithRateLimit(5, time.Second).
+		AddConnectionString("file://" + t.TempDir()).
+		AddRequest(&config.Request{Endpoint: "/currencies"}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	cfg.MaxDuration = "50ms"
+
+	err = Transport(context.Background(), cfg)
+	if !errors.Is(err, ErrMaxDuration) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the run to exceed its max duration, got %v", err)
+	}
+}
+
+func TestTransportStalled(t *testing.T) {
+	t.Parallel()
+
+	// The upstream hangs, so the web worker that fetches from it makes no progress.
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, err := NewConfig().
+		WithURL(server.URL).
+		WithRateLimit(5, time.Second).
+		AddConnectionString("file://" + t.TempDir()).
+		AddRequest(&config.Request{Endpoint: "/currencies"}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	cfg.Watchdog = &config.Watchdog{Stall: "50ms", Fail: true}
+
+	if err := Transport(context.Background(), cfg); !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected the run to fail with %v, got %v", ErrStalled, err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultAdaptiveInterval is how often the number of busy workers is adjusted if the interval is not configured, and
+// defaultAdaptiveErrorRate the fraction of failures that the workers back off on if the rate is not configured.
+const (
+	defaultAdaptiveInterval  = 2 * time.Second
+	defaultAdaptiveErrorRate = 0.05
+)
+
+// Adaptive adjusts how many of the web and storage workers are busy at once, between the minimum and their counts.
+// One more worker is let through each interval that the latency and the error rate of the workers are healthy, and
+// half of them are held back once either degrades, so that the worker counts do not need to be tuned for each API.
+type Adaptive struct {
+	// MinWorkers is the fewest web or storage workers that are busy at once. It defaults to 1.
+	MinWorkers int `yaml:"minWorkers"`
+
+	// Interval is how often the number of busy workers is adjusted, as a Go duration, e.g. "5s". It defaults to
+	// "2s".
+	Interval string `yaml:"interval"`
+
+	// MaxLatency is the average latency of a fetch or a write above which the workers back off, as a Go duration,
+	// e.g. "500ms". The workers back off once the average latency is twice the lowest they have had by default.
+	MaxLatency string `yaml:"maxLatency"`
+
+	// MaxErrorRate is the fraction of fetches or writes that may fail before the workers back off, e.g. 0.1. It
+	// defaults to 0.05.
+	MaxErrorRate float64 `yaml:"maxErrorRate"`
+}
+
+// MinimumWorkers will return the fewest web or storage workers that are busy at once.
+func (adaptive *Adaptive) MinimumWorkers() int {
+	if adaptive.MinWorkers > 0 {
+		return adaptive.MinWorkers
+	}
+
+	return 1
+}
+
+// IntervalDuration will return how often the number of busy workers is adjusted.
+func (adaptive *Adaptive) IntervalDuration() (time.Duration, error) {
+	if adaptive.Interval == "" {
+		return defaultAdaptiveInterval, nil
+	}
+
+	interval, err := time.ParseDuration(adaptive.Interval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("%w: interval %q", ErrInvalidAdaptive, adaptive.Interval)
+	}
+
+	return interval, nil
+}
+
+// MaxLatencyDuration will return the average latency above which the workers back off, or zero if it is relative to
+// the lowest latency of the workers.
+func (adaptive *Adaptive) MaxLatencyDuration() (time.Duration, error) {
+	if adaptive.MaxLatency == "" {
+		return 0, nil
+	}
+
+	latency, err := time.ParseDuration(adaptive.MaxLatency)
+	if err != nil || latency <= 0 {
+		return 0, fmt.Errorf("%w: max latency %q", ErrInvalidAdaptive, adaptive.MaxLatency)
+	}
+
+	return latency, nil
+}
+
+// MaximumErrorRate will return the fraction of fetches or writes that may fail before the workers back off.
+func (adaptive *Adaptive) MaximumErrorRate() float64 {
+	if adaptive.MaxErrorRate > 0 {
+		return adaptive.MaxErrorRate
+	}
+
+	return defaultAdaptiveErrorRate
+}
+
+func (adaptive *Adaptive) validate() error {
+	if adaptive.MinWorkers < 0 {
+		return fmt.Errorf("%w: %d min workers", ErrInvalidAdaptive, adaptive.MinWorkers)
+	}
+
+	if adaptive.MaxErrorRate < 0 || adaptive.MaxErrorRate >= 1 {
+		return fmt.Errorf("%w: max error rate %v", ErrInvalidAdaptive, adaptive.MaxErrorRate)
+	}
+
+	if _, err := adaptive.IntervalDuration(); err != nil {
+		return err
+	}
+
+	_, err := adaptive.MaxLatencyDuration()
+
+	return err
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptive(t *testing.T) {
+	t.Parallel()
+
+	adaptive := &Adaptive{}
+	if adaptive.MinimumWorkers() != 1 || adaptive.MaximumErrorRate() != defaultAdaptiveErrorRate {
+		t.Errorf("expected the defaults, got %d workers and an error rate of %v", adaptive.MinimumWorkers(),
+			adaptive.MaximumErrorRate())
+	}
+
+	if interval, err := adaptive.IntervalDuration(); err != nil || interval != defaultAdaptiveInterval {
+		t.Errorf("expected an interval of %v, got %v and %v", defaultAdaptiveInterval, interval, err)
+	}
+
+	if latency, err := adaptive.MaxLatencyDuration(); err != nil || latency != 0 {
+		t.Errorf("expected no max latency, got %v and %v", latency, err)
+	}
+
+	adaptive = &Adaptive{MinWorkers: 2, Interval: "5s", MaxLatency: "500ms", MaxErrorRate: 0.1}
+	if err := adaptive.validate(); err != nil {
+		t.Fatalf("failed to validate: %v", err)
+	}
+
+	if latency, _ := adaptive.MaxLatencyDuration(); latency != 500*time.Millisecond {
+		t.Errorf("expected a max latency of 500ms, got %v", latency)
+	}
+
+	for _, invalid := range []*Adaptive{
+		{MinWorkers: -1},
+		{Interval: "5"},
+		{Interval: "0s"},
+		{MaxLatency: "-1s"},
+		{MaxErrorRate: -0.1},
+		{MaxErrorRate: 1},
+	} {
+		if err := invalid.validate(); !errors.Is(err, ErrInvalidAdaptive) {
+			t.Errorf("%+v: expected %v, got %v", invalid, ErrInvalidAdaptive, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Coalesce accumulates the records of the chunks of each request, which are written to the request's table, and
+// writes them together once there are enough records or they have waited long enough, rather than writing each chunk
+// on its own. This cuts the round-trips to storage of timeseries requests with many small chunks.
+type Coalesce struct {
+	// Records is the number of records that are accumulated before they are written. The records are then split
+	// into writes of the batch size, if there is one.
+	Records int `yaml:"records"`
+
+	// Interval is how long records are accumulated for before they are written, as a Go duration, e.g. "2s".
+	Interval string `yaml:"interval"`
+}
+
+// IntervalDuration will return how long records are accumulated for, or zero if they are only written once there are
+// enough of them.
+func (coalesce *Coalesce) IntervalDuration() (time.Duration, error) {
+	if coalesce.Interval == "" {
+		return 0, nil
+	}
+
+	interval, err := time.ParseDuration(coalesce.Interval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("%w: interval %q", ErrInvalidCoalesce, coalesce.Interval)
+	}
+
+	return interval, nil
+}
+
+func (coalesce *Coalesce) validate() error {
+	if coalesce.Records < 0 {
+		return fmt.Errorf("%w: %d records", ErrInvalidCoalesce, coalesce.Records)
+	}
+
+	if coalesce.Records == 0 && coalesce.Interval == "" {
+		return fmt.Errorf("%w: records or interval is required", ErrInvalidCoalesce)
+	}
+
+	_, err := coalesce.IntervalDuration()
+
+	return err
+}
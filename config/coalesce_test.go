@@ -0,0 +1,35 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	t.Parallel()
+
+	coalesce := &Coalesce{Records: 1000, Interval: "2s"}
+	if interval, err := coalesce.IntervalDuration(); err != nil || interval != 2*time.Second {
+		t.Errorf("expected an interval of 2s, got %v and %v", interval, err)
+	}
+
+	for _, coalesce := range []*Coalesce{{Records: 1000}, {Interval: "1s"}} {
+		if err := coalesce.validate(); err != nil {
+			t.Errorf("%+v: expected no error, got %v", coalesce, err)
+		}
+	}
+
+	for _, coalesce := range []*Coalesce{{}, {Records: -1}, {Records: 10, Interval: "0s"}, {Interval: "2"}} {
+		if err := coalesce.validate(); !errors.Is(err, ErrInvalidCoalesce) {
+			t.Errorf("%+v: expected %v, got %v", coalesce, ErrInvalidCoalesce, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultCommandTimeout is how long a command may run for each response if no timeout is configured.
+const defaultCommandTimeout = 30 * time.Second
+
+// Command is an external command, e.g. a "jq" filter, that the records of each response of a request are piped
+// through. The command reads the records as JSON on its stdin, and writes the records to store to its stdout as JSON
+// or NDJSON.
+type Command struct {
+	// Path is the path of the executable, or its name if it is in the PATH, e.g. "jq".
+	Path string `yaml:"path"`
+
+	// Args are the arguments of the command, e.g. ["-c", ".data[] | {id, price}"].
+	Args []string `yaml:"args"`
+
+	// Timeout is how long the command may run for each response, e.g. "10s". The default is 30 seconds.
+	Timeout string `yaml:"timeout"`
+}
+
+// TimeoutDuration will return how long the command may run for each response.
+func (cmd *Command) TimeoutDuration() time.Duration {
+	timeout, err := time.ParseDuration(cmd.Timeout)
+	if err != nil || timeout <= 0 {
+		return defaultCommandTimeout
+	}
+
+	return timeout
+}
+
+// validateCommand will check that the command of the request has a path and a valid timeout.
+func (req *Request) validateCommand() error {
+	cmd := req.Command
+	if cmd == nil {
+		return nil
+	}
+
+	if cmd.Path == "" {
+		return fmt.Errorf("%w: path is required on %q", ErrInvalidCommand, req.Endpoint)
+	}
+
+	if cmd.Timeout != "" {
+		if timeout, err := time.ParseDuration(cmd.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("%w: invalid timeout %q on %q", ErrInvalidCommand, cmd.Timeout, req.Endpoint)
+		}
+	}
+
+	return nil
+}
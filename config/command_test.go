@@ -0,0 +1,43 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name    string
+		command *Command
+		wantErr error
+	}{
+		{name: "none"},
+		{name: "jq", command: &Command{Path: "jq", Args: []string{"-c", ".data[]"}, Timeout: "10s"}},
+		{name: "no path", command: &Command{Args: []string{"."}}, wantErr: ErrInvalidCommand},
+		{name: "invalid timeout", command: &Command{Path: "jq", Timeout: "soon"}, wantErr: ErrInvalidCommand},
+		{name: "negative timeout", command: &Command{Path: "jq", Timeout: "-1s"}, wantErr: ErrInvalidCommand},
+	} {
+		req := &Request{Endpoint: "/candles", Command: tcase.command}
+		if err := req.validateCommand(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+
+	if timeout := (&Command{Timeout: "10s"}).TimeoutDuration(); timeout != 10*time.Second {
+		t.Errorf("expected 10s, got %v", timeout)
+	}
+
+	if timeout := (&Command{}).TimeoutDuration(); timeout != defaultCommandTimeout {
+		t.Errorf("expected the default timeout, got %v", timeout)
+	}
+}
@@ -13,15 +13,46 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/alpstable/gidari/internal/proto"
 	"github.com/alpstable/gidari/tools"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v2"
 )
 
+// Transaction scopes for the writes of a configuration.
+const (
+	// TransactionRequest will commit the writes of each request in its own transaction. This is the default.
+	TransactionRequest = "request"
+
+	// TransactionRun will commit all of the writes of a run in a single transaction on each destination.
+	TransactionRun = "run"
+)
+
+// Verifications of the writes of each request.
+const (
+	// VerifyRows will compare the number of records written to each destination with the number fetched.
+	VerifyRows = "rows"
+
+	// VerifyChecksum will also read the records back out of each destination by their primary keys, and compare a
+	// checksum of their fields with the records that were fetched.
+	VerifyChecksum = "checksum"
+)
+
+// Formats of the logs of a run.
+const (
+	// LogFormatText will log each message as a line of text. This is the default.
+	LogFormatText = "text"
+
+	// LogFormatJSON will log each message as a JSON object, with the request, table, and chunk of the message as
+	// fields, so that the logs can be indexed by a log pipeline.
+	LogFormatJSON = "json"
+)
+
 // APIKey is one method of HTTP(s) transport that requires a passphrase, key, and secret.
 type APIKey struct {
 	Passphrase string `yaml:"passphrase"`
@@ -41,30 +72,235 @@ type Authentication struct {
 }
 
 // Config is the configuration used to query data from the web using HTTP requests and storing that data using
-// the repositories defined by the "ConnectionStrings" list.
+// the repositories defined by the "ConnectionStrings" and "Destinations" lists.
 type Config struct {
-	RawURL            string           `yaml:"url"`
-	Authentication    Authentication   `yaml:"authentication"`
-	ConnectionStrings []string         `yaml:"connectionStrings"`
-	Requests          []*Request       `yaml:"requests"`
-	RateLimitConfig   *RateLimitConfig `yaml:"rateLimit"`
+	RawURL            string            `yaml:"url"`
+	Authentication    Authentication    `yaml:"authentication"`
+	ConnectionStrings []string          `yaml:"connectionStrings"`
+	Destinations      []*Destination    `yaml:"destinations"`
+	Requests          []*Request        `yaml:"requests"`
+	Tables            map[string]*Table `yaml:"tables"`
+	RateLimitConfig   *RateLimitConfig  `yaml:"rateLimit"`
+
+	// BatchSize is the maximum number of records in each write to storage. If it is zero, each response from the
+	// web API is written at once.
+	BatchSize int `yaml:"batchSize"`
+
+	// WebWorkerCount is the number of workers that fetch the chunks of the requests concurrently, and
+	// StorageWorkerCount is the number of workers that write the requests to storage concurrently, each in its own
+	// transactions, so that fetch-heavy APIs and write-heavy destinations can be balanced. They default to 8 and 2
+	// workers for each CPU, since the workers mostly wait on the network. Requests are written one at a time by
+	// a single worker in the "run" transaction scope.
+	WebWorkerCount     int `yaml:"webWorkerCount"`
+	StorageWorkerCount int `yaml:"storageWorkerCount"`
+
+	// Adaptive adjusts how many of the web and storage workers are busy at once to the latency and the errors of
+	// their fetches and writes. Every worker is busy when there is work by default.
+	Adaptive *Adaptive `yaml:"adaptive"`
+
+	// JobBuffer is the number of fetched chunks of each request that are buffered until its storage worker writes
+	// them. Once the buffer is full, the web workers wait for the storage worker before they fetch more chunks, so
+	// that a fast API and a slow destination do not fill memory with fetched payloads. Backpressure is logged when
+	// it engages, and in the summary of the run. The default is 16 chunks.
+	JobBuffer int `yaml:"jobBuffer"`
+
+	// MaxConnsPerHost is the number of connections to the web API that the web workers of a run share, which are
+	// kept open between requests. The web workers wait for a connection once they are all in use. It defaults to
+	// the number of web workers, so that each of them can keep a connection.
+	MaxConnsPerHost int `yaml:"maxConnsPerHost"`
+
+	// MemoryLimit is how much memory the fetched payloads that are queued for the storage workers may hold, e.g.
+	// "512MB". Once it is reached, the payloads are spilled to temporary files in SpillDir, and read back as the
+	// storage workers write them, so that large backfills run on small machines. SpillDir defaults to the temporary
+	// directory of the system, and is only supported in local configurations. Payloads are not limited by default.
+	MemoryLimit string `yaml:"memoryLimit"`
+	SpillDir    string `yaml:"spillDir"`
+
+	// StreamRecords is the number of records of a response that are decoded, transformed, and sent to storage at a
+	// time, so that peak memory is bounded regardless of the size of responses, and the records of a response are
+	// written while the rest of it is received. Only responses that are JSON lists are streamed. A response that
+	// fails once some of its records have been sent fails its request, rather than being skipped as poison. Each
+	// response is read as a whole by default.
+	StreamRecords int `yaml:"streamRecords"`
+
+	// SplitRecords is the number of records above which a response is split into slices of that many records, which
+	// SplitWorkers write concurrently, so that one storage worker does not write a response of 500k records on its
+	// own. Each split worker writes in its own transactions, which are committed or rolled back with the transactions
+	// of the request, so each of them holds a connection to every destination of the request. The records of a
+	// split response are not written in order, and responses are only split in the "request" transaction scope.
+	// SplitWorkers defaults to 4. Responses are not split by default.
+	SplitRecords int `yaml:"splitRecords"`
+	SplitWorkers int `yaml:"splitWorkers"`
+
+	// Coalesce accumulates the records of the chunks of each request, and writes them together once there are enough
+	// of them or they have waited long enough. Each chunk is written on its own by default.
+	Coalesce *Coalesce `yaml:"coalesce"`
+
+	// Transaction is the scope of the storage transactions: "request" or "run". In "run" mode, the tables of every
+	// request are committed together on each destination, so that a partially refreshed set of tables is never
+	// observed. The default scope is "request".
+	Transaction string `yaml:"transaction"`
+
+	// TruncateFirst truncates the tables of the requests that truncate them once, before anything is fetched, in a
+	// transaction on each destination that is committed before the requests are written. A request that then fails
+	// leaves its table truncated. By default, each table is truncated in the transaction of the first request that
+	// truncates it, and rolled back with it. It has no effect in the "run" transaction scope.
+	TruncateFirst bool `yaml:"truncateFirst"`
+
+	// Metadata adds the time each record was fetched, the URL it was fetched from, and the ID of the run to every
+	// stored record, in the "_gidari_fetched_at", "_gidari_source_url", and "_gidari_run_id" fields.
+	Metadata bool `yaml:"metadata"`
+
+	// Audit writes a record of each request of a run, with its URL, time range, record count, duration, and status,
+	// to the "gidari_runs" table of each destination that its table is routed to, so that every stored record can be
+	// traced to the run that wrote it with "_gidari_run_id".
+	Audit bool `yaml:"audit"`
+
+	// DeadLetter is where the payloads that fail to be decoded, and the payloads of requests that fail to be
+	// upserted after their retries, are written with the error, so that their data is not lost. Failed payloads are
+	// discarded by default.
+	DeadLetter *DeadLetter `yaml:"deadLetter"`
+
+	// PoisonThreshold is the number of times a payload must fail to be decoded or transformed before it is skipped
+	// as poison and written to the dead letter. Until then, the chunk of the payload fails its request, so that it is
+	// fetched again if the request is retried with the "retry-N" error policy. Payloads are skipped the first time
+	// they fail by default.
+	PoisonThreshold int `yaml:"poisonThreshold"`
+
+	// RequeuePanics will run a job that panicked once more before it fails, i.e. the chunk that a web worker
+	// panicked while fetching, or the request that the repository worker panicked while writing. Jobs that panic
+	// fail at once by default, without crashing the run.
+	RequeuePanics bool `yaml:"requeuePanics"`
+
+	// Checkpoint is the file that the progress of the run is saved to as each request is committed, so that a run
+	// that crashed, was cancelled, or failed can be resumed with "Resume". The file is removed once a run succeeds.
+	Checkpoint string `yaml:"checkpoint"`
+
+	// QueueDir is the directory of a durable queue that the responses of the run are saved to as they are fetched,
+	// until their request is committed, so that a run that crashed can be resumed with "Resume" without fetching the
+	// chunks that were fetched but not yet written again. Responses are only held in memory by default.
+	QueueDir string `yaml:"queueDir"`
+
+	// DrainTimeout is how long the writes in flight of a run that is cancelled are given to finish, as a Go
+	// duration, e.g. "1m". Chunks are no longer fetched once the run is cancelled. The default is 30 seconds.
+	DrainTimeout string `yaml:"drainTimeout"`
 
-	Logger         *logrus.Logger
+	// MaxDuration is how long the run may take as a Go duration, e.g. "50m", after which it stops fetching chunks
+	// and winds down as if it had been cancelled, so that a scheduled run cannot overlap the next one. Runs are not
+	// limited by default.
+	MaxDuration string `yaml:"maxDuration"`
+
+	// Verify is how the writes of each request are verified once they are committed: "rows" or "checksum".
+	// Discrepancies are reported in the summary of the run. Writes are not verified by default.
+	Verify string `yaml:"verify"`
+
+	// TablePrefix and TableSuffix are added to the name of every table in storage, so that runs for different
+	// environments can share a database. Destination routes and table configurations use the names without them.
+	TablePrefix string `yaml:"tablePrefix"`
+	TableSuffix string `yaml:"tableSuffix"`
+
+	// Retry is how storage transactions that fail with a transient error are retried. Transactions are not retried
+	// by default.
+	Retry *Retry `yaml:"retry"`
+
+	// RetryBudget limits the retries of the run as a whole. Retries are not limited by default.
+	RetryBudget *RetryBudget `yaml:"retryBudget"`
+
+	// Watchdog reports the workers of the run that stall, and optionally fails the run. Workers are not watched by
+	// default.
+	Watchdog *Watchdog `yaml:"watchdog"`
+
+	// Naming is the convention that the names of the fields of every table are converted to before they are
+	// stored: "snake_case", "camelCase", or "PascalCase", e.g. so that Postgres columns do not need to be quoted.
+	// Names are not converted by default.
+	Naming string `yaml:"naming"`
+
+	// Decoding is how the records of tables that declare their fields are handled if they have unexpected fields or
+	// miss declared fields: "lenient", which logs the differences, or "strict", which fails their chunk. The default
+	// is "lenient".
+	Decoding string `yaml:"decoding"`
+
+	// LogFormat is the format of the logs of the run: "text" or "json". The default format is "text".
+	LogFormat string `yaml:"logFormat"`
+
+	// LogSample aggregates the logs of each chunk that is fetched and each batch that is upserted, so that a backfill
+	// of many chunks does not produce unusable log volume. One message is logged for every "LogSample" chunks or
+	// batches of a request, with their totals, and the rest are logged once the request has been written. Every chunk
+	// and batch is logged by default.
+	LogSample int `yaml:"logSample"`
+
+	// AuthProbe is the endpoint that "gidari auth test" requests to check the credentials of the configuration. The
+	// first request of the configuration is made by default.
+	AuthProbe string `yaml:"authProbe"`
+
+	// Vars are the variables of the configuration, e.g. a product or an account, that the endpoint, query values,
+	// and table of every request reference as a Go template, e.g. "/products/{{ .product }}/candles", so that one
+	// configuration can be reused by changing its variables.
+	Vars map[string]string `yaml:"vars"`
+
+	// RequestDefaults are the fields that every request inherits unless it sets them itself.
+	RequestDefaults *RequestDefaults `yaml:"requestDefaults"`
+
+	// Notify is where the summary of the run is sent once it has completed or failed, when it is run with the gidari
+	// command.
+	Notify *Notify `yaml:"notify"`
+
+	Logger         tools.Logger
 	StgConstructor proto.Constructor
 	Truncate       bool
 
+	// Progress is called with the outcome of each request once its writes have been committed or rolled back, e.g.
+	// to track the progress of a run that is queued by a service. It is not called if it is nil.
+	Progress func(RequestProgress) `yaml:"-"`
+
+	// OnFetch and OnUpsert are called as the chunks of each request are fetched and their records are upserted,
+	// e.g. to display the live progress of a long backfill. They may be called concurrently, and are not called if
+	// they are nil.
+	OnFetch  func(FetchProgress)  `yaml:"-"`
+	OnUpsert func(UpsertProgress) `yaml:"-"`
+
+	// DumpDir is the directory that the headers of each request to the web API and the raw body of its response are
+	// written to, if it is not empty, e.g. to diagnose the quirks of an API. Only the requests that match one of the
+	// DumpRequests names are dumped, or every request if there are none, see "FilterRequests".
+	DumpDir      string   `yaml:"-"`
+	DumpRequests []string `yaml:"-"`
+
+	// Resume skips the chunks of the requests that were committed by a previous run, according to its Checkpoint,
+	// e.g. after a crash or a cancellation.
+	Resume bool `yaml:"-"`
+
+	// AllowCommands allows the requests of the configuration to pipe their records through a command, which runs
+	// on the host, see "Request.Command". It is set by the "--allow-commands" flag of the CLI, and is never set for
+	// configurations that are sent to a service.
+	AllowCommands bool `yaml:"-"`
+
+	// TracerProvider provides the tracer that the fetch, transform, and upsert of each request are traced with,
+	// e.g. to export the traces of a run to Jaeger or Tempo. The global tracer provider is used if it is nil.
+	TracerProvider trace.TracerProvider `yaml:"-"`
+
+	// Include are the files that a configuration file includes, which are merged into it by "New" and "ReadFile".
+	// Configurations that are parsed with includes are invalid, so that configurations sent to a service cannot
+	// read its files.
+	Include interface{} `yaml:"include"`
+
+	// Secrets is the file of the credentials of a configuration file, which is merged into it by "New" and
+	// "ReadFile". Like includes, configurations that are parsed with a secrets file are invalid.
+	Secrets interface{} `yaml:"secrets"`
+
+	// Profiles are the fields of each environment of the configuration, e.g. "dev", "staging", and "prod", which are
+	// merged over the configuration by "SelectProfile". Profiles that are not selected are ignored.
+	Profiles map[string]interface{} `yaml:"profiles"`
+
 	URL *url.URL `yaml:"-"`
 }
 
-// New takes a YAML byte slice and returns a new transport configuration for upserting data to storage.
+// New takes a YAML file and returns a new transport configuration for upserting data to storage.
 //
 // For web requests defined on the transport configuration, the default HTTP Request Method is "GET". Furthermore,
 // if rate limit data has not been defined for a request it will inherit the rate limit data from the transport config.
-func New(_ context.Context, file *os.File) (*Config, error) {
-	var cfg Config
-
-	cfg.Logger = logrus.New()
-
+// References to environment variables anywhere in the file are expanded, and the files that it includes are merged, see
+// "ReadFile". Files with the ".json" extension are parsed as JSON.
+func New(ctx context.Context, file *os.File) (*Config, error) {
 	info, err := file.Stat()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get file stat for reading: %w", err)
@@ -77,17 +313,142 @@ func New(_ context.Context, file *os.File) (*Config, error) {
 		return nil, fmt.Errorf("unable to read file: %w", err)
 	}
 
+	bytes, err = resolveFile(file.Name(), bytes, FormatAuto)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(ctx, bytes)
+}
+
+// Parse takes a YAML byte slice and returns a new transport configuration, with the same defaults as "New".
+func Parse(_ context.Context, bytes []byte) (*Config, error) {
+	cfg, err := unmarshal(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Prepare(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ParseRemote takes the YAML of a configuration that was sent by a remote caller, e.g. to a service, and returns it
+// like "Parse". Features that run commands or read files on the host are only supported in local configurations, so a
+// configuration that uses them fails with an error that matches "ErrLocalOnly" before it is prepared.
+func ParseRemote(_ context.Context, bytes []byte) (*Config, error) {
+	cfg, err := unmarshal(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.CheckRemote(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Prepare(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// unmarshal will return the configuration of the YAML, with the default logger.
+func unmarshal(bytes []byte) (*Config, error) {
+	var cfg Config
+
+	cfg.Logger = tools.NewLogrusLogger(logrus.New())
+
 	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal YAML: %w", err)
 	}
 
+	return &cfg, nil
+}
+
+// CheckRemote will return an error that matches "ErrLocalOnly" if the configuration uses a feature that is only
+// supported in local configurations, because it runs commands or reads or writes files on the host, e.g. the command
+// of a request, the schema file of a table, or the checkpoint of the run.
+func (cfg *Config) CheckRemote() error {
+	if cfg.Checkpoint != "" {
+		return fmt.Errorf("%w: checkpoint", ErrLocalOnly)
+	}
+
+	if cfg.DeadLetter != nil && cfg.DeadLetter.Dir != "" {
+		return fmt.Errorf("%w: dead letter dir", ErrLocalOnly)
+	}
+
+	if cfg.QueueDir != "" {
+		return fmt.Errorf("%w: queue dir", ErrLocalOnly)
+	}
+
+	if cfg.SpillDir != "" {
+		return fmt.Errorf("%w: spill dir", ErrLocalOnly)
+	}
+
+	for _, req := range cfg.Requests {
+		if req == nil {
+			continue
+		}
+
+		if req.Command != nil {
+			return fmt.Errorf("%w: command on %q", ErrLocalOnly, req.Endpoint)
+		}
+
+		if req.Script != nil && req.Script.File != "" {
+			return fmt.Errorf("%w: script file on %q", ErrLocalOnly, req.Endpoint)
+		}
+
+		if req.Protobuf != nil && req.Protobuf.Descriptor != "" {
+			return fmt.Errorf("%w: protobuf descriptor on %q", ErrLocalOnly, req.Endpoint)
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Tables))
+	for name := range cfg.Tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if table := cfg.Tables[name]; table != nil && table.SchemaFile != "" {
+			return fmt.Errorf("%w: schemaFile on %q", ErrLocalOnly, name)
+		}
+	}
+
+	return nil
+}
+
+// Prepare will read the schema files of the tables, validate the configuration, and set the URL, the rate limiter, and
+// the defaults of its requests. It is called by "New" and "Parse", and must be called on configurations that are
+// constructed in Go before they are run.
+func (cfg *Config) Prepare() error {
+	if logger, ok := cfg.Logger.(*tools.LogrusLogger); ok && cfg.LogFormat == LogFormatJSON {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	cfg.applyRequestDefaults()
+
+	if err := cfg.expandVars(); err != nil {
+		return err
+	}
+
+	if err := cfg.loadSchemas(); err != nil {
+		return err
+	}
+
 	if err := cfg.Validate(); err != nil {
-		return nil, err
+		return err
 	}
 
+	var err error
+
 	cfg.URL, err = url.Parse(cfg.RawURL)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse URL: %w", err)
+		return fmt.Errorf("unable to parse URL: %w", err)
 	}
 
 	// create a rate limiter to pass to all "flattenedRequest". This has to be defined outside of the scope of
@@ -102,32 +463,219 @@ func New(_ context.Context, file *os.File) (*Config, error) {
 		}
 
 		if req.Table == "" {
-			endpointParts := strings.Split(req.Endpoint, "/")
-			req.Table = endpointParts[len(endpointParts)-1]
+			req.Table = defaultTable(req.Endpoint)
 		}
 
 		req.RateLimiter = rateLimiter
 	}
 
-	return &cfg, nil
+	return nil
+}
+
+// defaultTable will return the table of a request without one, which is the last part of the path of its endpoint.
+func defaultTable(endpoint string) string {
+	endpointParts := strings.Split(endpoint, "/")
+
+	return endpointParts[len(endpointParts)-1]
+}
+
+// StorageTable will return the name of the table in storage, with the configured prefix and suffix.
+func (cfg *Config) StorageTable(table string) string {
+	return cfg.TablePrefix + table + cfg.TableSuffix
 }
 
 // Validate will ensure that the configuration is valid for querying the web API.
 func (cfg *Config) Validate() error {
+	if problems := cfg.problems(); len(problems) > 0 {
+		return problems[0]
+	}
+
+	if cfg.ConnectionStrings == nil && cfg.Destinations == nil {
+		logWarn := tools.LogFormatter{
+			Msg: "no connectionStrings or destinations specified in the config file",
+		}
+		logWarn.Log(cfg.Logger, tools.LogLevelWarn)
+	}
+
+	return nil
+}
+
+// problems will return every reason that the configuration is invalid, in the order that "Validate" checks them.
+func (cfg *Config) problems() []error {
+	var problems []error
+
+	if cfg.Include != nil {
+		problems = append(problems, fmt.Errorf("%w: includes are only merged in configuration files", ErrInvalidInclude))
+	}
+
+	if cfg.Secrets != nil {
+		problems = append(problems, fmt.Errorf("%w: secrets are only merged in configuration files", ErrInvalidSecrets))
+	}
+
 	if cfg.RateLimitConfig == nil {
-		return MissingConfigFieldError("rateLimit")
+		problems = append(problems, MissingConfigFieldError("rateLimit"))
+	} else if err := cfg.RateLimitConfig.validate(); err != nil {
+		problems = append(problems, ErrInvalidRateLimit)
+	} else if err := cfg.RateLimitConfig.validateRedis(); err != nil {
+		problems = append(problems, err)
 	}
 
-	if err := cfg.RateLimitConfig.validate(); err != nil {
-		return ErrInvalidRateLimit
+	if cfg.BatchSize < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidBatchSize, cfg.BatchSize))
 	}
 
-	if cfg.ConnectionStrings == nil {
-		logWarn := tools.LogFormatter{
-			Msg: "no connectionStrings specified in the config file",
+	if cfg.WebWorkerCount < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d web workers", ErrInvalidWorkerCount, cfg.WebWorkerCount))
+	}
+
+	if cfg.StorageWorkerCount < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d storage workers", ErrInvalidWorkerCount,
+			cfg.StorageWorkerCount))
+	}
+
+	if cfg.StreamRecords < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidStreamRecords, cfg.StreamRecords))
+	}
+
+	if cfg.SplitRecords < 0 || cfg.SplitWorkers < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d records by %d workers", ErrInvalidSplit, cfg.SplitRecords,
+			cfg.SplitWorkers))
+	}
+
+	if cfg.JobBuffer < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidJobBuffer, cfg.JobBuffer))
+	}
+
+	if _, err := cfg.MemoryLimitBytes(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if cfg.MaxConnsPerHost < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidMaxConnsPerHost, cfg.MaxConnsPerHost))
+	}
+
+	if cfg.Transaction != "" && cfg.Transaction != TransactionRequest && cfg.Transaction != TransactionRun {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidTransaction, cfg.Transaction))
+	}
+
+	if cfg.Verify != "" && cfg.Verify != VerifyRows && cfg.Verify != VerifyChecksum {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidVerify, cfg.Verify))
+	}
+
+	if cfg.LogFormat != "" && cfg.LogFormat != LogFormatText && cfg.LogFormat != LogFormatJSON {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidLogFormat, cfg.LogFormat))
+	}
+
+	if cfg.LogSample < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidLogSample, cfg.LogSample))
+	}
+
+	if cfg.PoisonThreshold < 0 {
+		problems = append(problems, fmt.Errorf("%w: %d", ErrInvalidPoisonThreshold, cfg.PoisonThreshold))
+	}
+
+	if !validNaming(cfg.Naming) {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidNaming, cfg.Naming))
+	}
+
+	if !validDecoding(cfg.Decoding) {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidDecoding, cfg.Decoding))
+	}
+
+	if _, err := cfg.DrainTimeoutDuration(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if _, err := cfg.MaxRunDuration(); err != nil {
+		problems = append(problems, err)
+	}
+
+	if cfg.Retry != nil {
+		if err := cfg.Retry.validate(); err != nil {
+			problems = append(problems, err)
 		}
-		cfg.Logger.Warn(logWarn.String())
 	}
 
-	return nil
+	if cfg.RetryBudget != nil {
+		if err := cfg.RetryBudget.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if cfg.Coalesce != nil {
+		if err := cfg.Coalesce.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if cfg.Watchdog != nil {
+		if err := cfg.Watchdog.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if cfg.Adaptive != nil {
+		if err := cfg.Adaptive.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if cfg.Notify != nil {
+		if err := cfg.Notify.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if cfg.DeadLetter != nil {
+		if err := cfg.DeadLetter.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for idx, dest := range cfg.Destinations {
+		// An empty item of the list, e.g. a stray "-", is a destination without a connection string.
+		if dest == nil {
+			problems = append(problems, MissingConfigFieldError(fmt.Sprintf("destinations[%d]", idx)))
+
+			continue
+		}
+
+		if err := dest.validate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Tables))
+	for name := range cfg.Tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		table := cfg.Tables[name]
+		if table == nil {
+			continue
+		}
+
+		if err := table.validate(name); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for _, req := range cfg.Requests {
+		if err := req.validate(); err != nil {
+			problems = append(problems, err)
+		}
+
+		if err := req.validateOnError(cfg.Transaction); err != nil {
+			problems = append(problems, err)
+		}
+
+		if err := req.validateTransforms(cfg.Verify); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	return problems
 }
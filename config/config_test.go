@@ -0,0 +1,502 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		burst := 1
+		period := time.Second
+
+		for _, tcase := range []struct {
+			transaction string
+			wantErr     error
+		}{
+			{transaction: ""},
+			{transaction: TransactionRequest},
+			{transaction: TransactionRun},
+			{transaction: "destination", wantErr: ErrInvalidTransaction},
+		} {
+			cfg := Config{
+				ConnectionStrings: []string{"stdout://"},
+				RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+				Transaction:       tcase.transaction,
+				Logger:            tools.NewLogrusLogger(logrus.New()),
+			}
+
+			if err := cfg.Validate(); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%q: expected %v, got %v", tcase.transaction, tcase.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		t.Parallel()
+
+		burst := 1
+		period := time.Second
+
+		for _, tcase := range []struct {
+			verify  string
+			wantErr error
+		}{
+			{verify: ""},
+			{verify: VerifyRows},
+			{verify: VerifyChecksum},
+			{verify: "count", wantErr: ErrInvalidVerify},
+		} {
+			cfg := Config{
+				ConnectionStrings: []string{"stdout://"},
+				RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+				Verify:            tcase.verify,
+				Logger:            tools.NewLogrusLogger(logrus.New()),
+			}
+
+			if err := cfg.Validate(); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%q: expected %v, got %v", tcase.verify, tcase.wantErr, err)
+			}
+		}
+	})
+}
+
+func TestConfigLogFormat(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		logFormat string
+		json      bool
+		wantErr   error
+	}{
+		{logFormat: ""},
+		{logFormat: LogFormatText},
+		{logFormat: LogFormatJSON, json: true},
+		{logFormat: "logfmt", wantErr: ErrInvalidLogFormat},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			LogFormat:         tcase.logFormat,
+			Logger:            tools.NewLogrusLogger(logrus.New()),
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.logFormat, tcase.wantErr, err)
+		}
+
+		logger, _ := cfg.Logger.(*tools.LogrusLogger)
+		if _, ok := logger.Formatter.(*logrus.JSONFormatter); ok != tcase.json {
+			t.Errorf("%q: expected JSON logs to be %v, got %T", tcase.logFormat, tcase.json, logger.Formatter)
+		}
+	}
+}
+
+func TestConfigLogSample(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		logSample int
+		wantErr   error
+	}{
+		{logSample: 0},
+		{logSample: 100},
+		{logSample: -1, wantErr: ErrInvalidLogSample},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			LogSample:         tcase.logSample,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d: expected %v, got %v", tcase.logSample, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestConfigPoisonThreshold(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		poisonThreshold int
+		wantErr         error
+	}{
+		{poisonThreshold: 0},
+		{poisonThreshold: 3},
+		{poisonThreshold: -1, wantErr: ErrInvalidPoisonThreshold},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			PoisonThreshold:   tcase.poisonThreshold,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d: expected %v, got %v", tcase.poisonThreshold, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestConfigWorkerCount(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		web, storage int
+		wantErr      error
+	}{
+		{},
+		{web: 64, storage: 4},
+		{web: -1, wantErr: ErrInvalidWorkerCount},
+		{storage: -1, wantErr: ErrInvalidWorkerCount},
+	} {
+		cfg := Config{
+			RawURL:             "https://api.example.com",
+			ConnectionStrings:  []string{"stdout://"},
+			RateLimitConfig:    &RateLimitConfig{Burst: &burst, Period: &period},
+			WebWorkerCount:     tcase.web,
+			StorageWorkerCount: tcase.storage,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d/%d: expected %v, got %v", tcase.web, tcase.storage, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr != nil {
+			continue
+		}
+
+		if cfg.WebWorkers() < 1 || cfg.StorageWorkers() < 1 {
+			t.Errorf("%d/%d: expected workers, got %d/%d", tcase.web, tcase.storage, cfg.WebWorkers(),
+				cfg.StorageWorkers())
+		}
+
+		if tcase.web > 0 && cfg.WebWorkers() != tcase.web {
+			t.Errorf("expected %d web workers, got %d", tcase.web, cfg.WebWorkers())
+		}
+
+		if tcase.storage > 0 && cfg.StorageWorkers() != tcase.storage {
+			t.Errorf("expected %d storage workers, got %d", tcase.storage, cfg.StorageWorkers())
+		}
+	}
+}
+
+func TestConfigJobBuffer(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		jobBuffer int
+		expected  int
+		wantErr   error
+	}{
+		{jobBuffer: 0, expected: defaultJobBuffer},
+		{jobBuffer: 4, expected: 4},
+		{jobBuffer: -1, wantErr: ErrInvalidJobBuffer},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			JobBuffer:         tcase.jobBuffer,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d: expected %v, got %v", tcase.jobBuffer, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr == nil && cfg.JobBufferSize() != tcase.expected {
+			t.Errorf("%d: expected a buffer of %d, got %d", tcase.jobBuffer, tcase.expected, cfg.JobBufferSize())
+		}
+	}
+}
+
+func TestConfigMaxConnsPerHost(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		maxConns int
+		expected int
+		wantErr  error
+	}{
+		{maxConns: 0, expected: 12},
+		{maxConns: 4, expected: 4},
+		{maxConns: -1, wantErr: ErrInvalidMaxConnsPerHost},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			WebWorkerCount:    12,
+			MaxConnsPerHost:   tcase.maxConns,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d: expected %v, got %v", tcase.maxConns, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr == nil && cfg.MaxConns() != tcase.expected {
+			t.Errorf("%d: expected %d connections, got %d", tcase.maxConns, tcase.expected, cfg.MaxConns())
+		}
+	}
+}
+
+func TestConfigSplit(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		records  int
+		workers  int
+		expected int
+		wantErr  error
+	}{
+		{records: 0, workers: 0, expected: defaultSplitWorkers},
+		{records: 1000, workers: 8, expected: 8},
+		{records: -1, wantErr: ErrInvalidSplit},
+		{records: 1000, workers: -1, wantErr: ErrInvalidSplit},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			SplitRecords:      tcase.records,
+			SplitWorkers:      tcase.workers,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d/%d: expected %v, got %v", tcase.records, tcase.workers, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr == nil && cfg.SplitWorkerCount() != tcase.expected {
+			t.Errorf("%d/%d: expected %d workers, got %d", tcase.records, tcase.workers, tcase.expected,
+				cfg.SplitWorkerCount())
+		}
+	}
+}
+
+func TestConfigRateLimitRedis(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		redis   string
+		wantErr error
+	}{
+		{redis: ""},
+		{redis: "redis://localhost:6379"},
+		{redis: "rediss://:secret@redis.example.com:6380/2"},
+		{redis: "localhost:6379", wantErr: ErrInvalidRedis},
+		{redis: "http://localhost:6379", wantErr: ErrInvalidRedis},
+		{redis: "redis://", wantErr: ErrInvalidRedis},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period, Redis: tcase.redis},
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.redis, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestConfigStreamRecords(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		streamRecords int
+		wantErr       error
+	}{
+		{streamRecords: 0},
+		{streamRecords: 1000},
+		{streamRecords: -1, wantErr: ErrInvalidStreamRecords},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			StreamRecords:     tcase.streamRecords,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%d: expected %v, got %v", tcase.streamRecords, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestConfigDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	burst := 1
+	period := time.Second
+
+	for _, tcase := range []struct {
+		deadLetter *DeadLetter
+		wantErr    error
+	}{
+		{deadLetter: nil},
+		{deadLetter: &DeadLetter{Dir: "/var/lib/gidari/dead"}},
+		{deadLetter: &DeadLetter{Table: "dead_letters"}},
+		{deadLetter: &DeadLetter{}, wantErr: ErrInvalidDeadLetter},
+	} {
+		cfg := Config{
+			RawURL:            "https://api.example.com",
+			ConnectionStrings: []string{"stdout://"},
+			RateLimitConfig:   &RateLimitConfig{Burst: &burst, Period: &period},
+			DeadLetter:        tcase.deadLetter,
+		}
+
+		if err := cfg.Prepare(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%+v: expected %v, got %v", tcase.deadLetter, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse(context.Background(), []byte(`
+url: https://api.example.com
+connectionStrings: ["stdout://"]
+rateLimit:
+  burst: 1
+  period: 1s
+requests:
+  - endpoint: /products/candles
+`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if cfg.URL.Host != "api.example.com" || cfg.Logger == nil {
+		t.Errorf("unexpected configuration %+v", cfg)
+	}
+
+	if req := cfg.Requests[0]; req.Method != http.MethodGet || req.Table != "candles" || req.RateLimiter == nil {
+		t.Errorf("expected request defaults, got %+v", req)
+	}
+
+	if _, err := Parse(context.Background(), []byte(`url: https://api.example.com`)); err == nil {
+		t.Errorf("expected an error for a configuration without a rate limit")
+	}
+}
+
+func TestParseRemote(t *testing.T) {
+	t.Parallel()
+
+	yml := `
+url: https://api.example.com
+connectionStrings: ["stdout://"]
+rateLimit:
+  burst: 1
+  period: 1s
+requests:
+  - endpoint: /products/candles
+`
+
+	if _, err := ParseRemote(context.Background(), []byte(yml)); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	remote := yml + "    command:\n      path: sh\n"
+	if _, err := ParseRemote(context.Background(), []byte(remote)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	// Schema files are read from the host, so remote configurations cannot reference them.
+	schemaFile := yml + "tables:\n  candles:\n    schemaFile: /etc/passwd\n"
+	if _, err := ParseRemote(context.Background(), []byte(schemaFile)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	// Checkpoints are written over and removed from the host, so remote configurations cannot choose them.
+	checkpoint := yml + "checkpoint: /etc/passwd\n"
+	if _, err := ParseRemote(context.Background(), []byte(checkpoint)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	// Scripts can only be inline, since the script file would be read from the host.
+	scriptFile := yml + "    script:\n      file: /etc/passwd\n"
+	if _, err := ParseRemote(context.Background(), []byte(scriptFile)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	script := yml + "    script:\n      source: |\n        def transform(record):\n          return record\n"
+	if _, err := ParseRemote(context.Background(), []byte(script)); err != nil {
+		t.Errorf("failed to parse an inline script: %v", err)
+	}
+
+	descriptor := yml + "    format: protobuf\n    protobuf:\n      descriptor: /etc/passwd\n      message: market.v1.Candles\n"
+	if _, err := ParseRemote(context.Background(), []byte(descriptor)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	// Dead letters can only be written to the tables of the destinations.
+	deadLetter := yml + "deadLetter:\n  dir: /etc\n"
+	if _, err := ParseRemote(context.Background(), []byte(deadLetter)); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	if _, err := ParseRemote(context.Background(), []byte(yml+"deadLetter:\n  table: dead_letters\n")); err != nil {
+		t.Errorf("failed to parse a dead letter table: %v", err)
+	}
+
+	if _, err := ParseRemote(context.Background(), []byte(yml+"queueDir: /etc\n")); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	if _, err := ParseRemote(context.Background(), []byte(yml+"spillDir: /etc\n")); !errors.Is(err, ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", ErrLocalOnly, err)
+	}
+
+	// Local configurations can still have commands, which only run if they are allowed.
+	if _, err := Parse(context.Background(), []byte(remote)); err != nil {
+		t.Errorf("failed to parse a local configuration: %v", err)
+	}
+}
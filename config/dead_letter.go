@@ -0,0 +1,30 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import "fmt"
+
+// DeadLetter is where the payloads that fail to be decoded or upserted are written, with the context of the failure,
+// so that their data is not lost and can be replayed once the cause has been fixed.
+type DeadLetter struct {
+	// Dir is the directory that each payload is written to as a JSON file. It is only supported in local
+	// configurations, see "Config.CheckRemote".
+	Dir string `yaml:"dir"`
+
+	// Table is the table that each payload is written to as a record, on each destination that the table of its
+	// request is routed to. It has the prefix and suffix of the configuration.
+	Table string `yaml:"table"`
+}
+
+func (letter *DeadLetter) validate() error {
+	if letter.Dir == "" && letter.Table == "" {
+		return fmt.Errorf("%w: dir or table is required", ErrInvalidDeadLetter)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alpstable/gidari/internal/decoder"
+)
+
+// Formats of the responses of a request.
+const (
+	// ResponseFormatJSON is the default format of a response, which is stored as it is received.
+	ResponseFormatJSON = decoder.FormatJSON
+
+	// ResponseFormatCSV is the format of responses that are CSV files, whose rows are decoded into records.
+	ResponseFormatCSV = decoder.FormatCSV
+
+	// ResponseFormatXML is the format of responses that are XML documents, whose repeating record elements are
+	// decoded into records.
+	ResponseFormatXML = decoder.FormatXML
+
+	// ResponseFormatNDJSON is the format of responses that are newline-delimited JSON, also known as JSON Lines,
+	// with a record on each line. The responses of a JSON request are decoded as NDJSON if their content type is
+	// NDJSON, e.g. "application/x-ndjson".
+	ResponseFormatNDJSON = decoder.FormatNDJSON
+
+	// ResponseFormatProtobuf is the format of responses that are protobuf messages, which are decoded with the
+	// descriptor of the message.
+	ResponseFormatProtobuf = decoder.FormatProtobuf
+
+	// ResponseFormatMessagePack is the format of responses that are MessagePack.
+	ResponseFormatMessagePack = decoder.FormatMessagePack
+)
+
+// CSV is how the rows of the CSV responses of a request are decoded into records.
+type CSV struct {
+	// Delimiter is the character that separates the fields of a row. The default is ",".
+	Delimiter string `yaml:"delimiter"`
+
+	// Header is whether the first row of a response names the columns. The default is true.
+	Header *bool `yaml:"header"`
+
+	// Columns are the names of the columns, in order. They replace the names of the header, if there is one, and are
+	// required if there is not.
+	Columns []string `yaml:"columns"`
+}
+
+// XML is how the XML responses of a request are decoded into records.
+type XML struct {
+	// RecordPath is the path of the repeating record element from the root element, with the local names of the
+	// elements separated by "/", e.g. "rss/channel/item". By default, each child element of the root element is a
+	// record.
+	RecordPath string `yaml:"recordPath"`
+}
+
+// Protobuf is how the protobuf responses of a request are decoded into records.
+type Protobuf struct {
+	// Descriptor is the path of the serialized "FileDescriptorSet" that describes the message, relative to the
+	// working directory, e.g. the output of "protoc --include_imports --descriptor_set_out". It is only supported in
+	// local configurations, see "Config.CheckRemote".
+	Descriptor string `yaml:"descriptor"`
+
+	// Message is the full name of the message of each response, e.g. "market.v1.Candles".
+	Message string `yaml:"message"`
+
+	// Delimited is whether each response is a stream of messages that are each prefixed with their size as a varint,
+	// with each message a record. Otherwise each response is a single message.
+	Delimited bool `yaml:"delimited"`
+}
+
+// Path will return the local names of the elements from the root element to the record element, or nil if each
+// child element of the root element is a record.
+func (xml *XML) Path() []string {
+	if xml == nil || strings.Trim(xml.RecordPath, "/") == "" {
+		return nil
+	}
+
+	return strings.Split(strings.Trim(xml.RecordPath, "/"), "/")
+}
+
+// Comma will return the delimiter of the fields of a row, which is "," by default.
+func (csv *CSV) Comma() rune {
+	if csv == nil || csv.Delimiter == "" {
+		return ','
+	}
+
+	comma, _ := utf8.DecodeRuneInString(csv.Delimiter)
+
+	return comma
+}
+
+// HasHeader will return true if the first row of a response names the columns, which it does by default.
+func (csv *CSV) HasHeader() bool {
+	return csv == nil || csv.Header == nil || *csv.Header
+}
+
+// validateFormat will check that the format of the responses of the request is built in or registered, and that its
+// options are for the format and can decode a record.
+func (req *Request) validateFormat() error {
+	if req.CSV != nil && req.Format != ResponseFormatCSV {
+		return fmt.Errorf("%w: csv requires the %q format on %q", ErrInvalidResponseFormat, ResponseFormatCSV,
+			req.Endpoint)
+	}
+
+	if req.XML != nil && req.Format != ResponseFormatXML {
+		return fmt.Errorf("%w: xml requires the %q format on %q", ErrInvalidResponseFormat, ResponseFormatXML,
+			req.Endpoint)
+	}
+
+	if req.Protobuf != nil && req.Format != ResponseFormatProtobuf {
+		return fmt.Errorf("%w: protobuf requires the %q format on %q", ErrInvalidResponseFormat,
+			ResponseFormatProtobuf, req.Endpoint)
+	}
+
+	switch req.Format {
+	case "", ResponseFormatJSON, ResponseFormatNDJSON, ResponseFormatMessagePack:
+		return nil
+	case ResponseFormatProtobuf:
+		if req.Protobuf == nil || req.Protobuf.Descriptor == "" || req.Protobuf.Message == "" {
+			return fmt.Errorf("%w: protobuf.descriptor and protobuf.message are required on %q",
+				ErrInvalidResponseFormat, req.Endpoint)
+		}
+
+		return nil
+	case ResponseFormatCSV:
+		return req.CSV.validate(req.Endpoint)
+	case ResponseFormatXML:
+		for _, name := range req.XML.Path() {
+			if name == "" {
+				return fmt.Errorf("%w: empty element in record path %q on %q", ErrInvalidResponseFormat,
+					req.XML.RecordPath, req.Endpoint)
+			}
+		}
+
+		return nil
+	default:
+		if decoder.KnownFormat(req.Format) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %q on %q", ErrInvalidResponseFormat, req.Format, req.Endpoint)
+	}
+}
+
+// validate will check that the CSV options of the request can decode a row.
+func (csv *CSV) validate(endpoint string) error {
+	if csv == nil {
+		return nil
+	}
+
+	if csv.Delimiter != "" {
+		comma := csv.Comma()
+		if utf8.RuneCountInString(csv.Delimiter) != 1 || comma == '"' || comma == '\r' || comma == '\n' ||
+			comma == utf8.RuneError {
+			return fmt.Errorf("%w: invalid delimiter %q on %q", ErrInvalidResponseFormat, csv.Delimiter, endpoint)
+		}
+	}
+
+	if !csv.HasHeader() && len(csv.Columns) == 0 {
+		return fmt.Errorf("%w: columns are required without a header on %q", ErrInvalidResponseFormat, endpoint)
+	}
+
+	seen := make(map[string]bool, len(csv.Columns))
+
+	for _, column := range csv.Columns {
+		if column == "" || seen[column] {
+			return fmt.Errorf("%w: empty or duplicate column %q on %q", ErrInvalidResponseFormat, column, endpoint)
+		}
+
+		seen[column] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/decoder"
+)
+
+func TestValidateFormat(t *testing.T) {
+	t.Parallel()
+
+	noHeader := false
+
+	if err := decoder.RegisterFormat("config-test", &decoder.NDJSON{}); err != nil {
+		t.Fatalf("failed to register format: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		name    string
+		format  string
+		csv     *CSV
+		xml     *XML
+		pb      *Protobuf
+		wantErr error
+	}{
+		{name: "default"},
+		{name: "json", format: ResponseFormatJSON},
+		{name: "csv", format: ResponseFormatCSV},
+		{name: "csv options", format: ResponseFormatCSV, csv: &CSV{Delimiter: "\t", Columns: []string{"id", "name"}}},
+		{name: "no header", format: ResponseFormatCSV, csv: &CSV{Header: &noHeader, Columns: []string{"id"}}},
+		{name: "ndjson", format: ResponseFormatNDJSON},
+		{name: "xml", format: ResponseFormatXML},
+		{name: "xml record path", format: ResponseFormatXML, xml: &XML{RecordPath: "/rss/channel/item"}},
+		{name: "xml without format", xml: &XML{RecordPath: "feed/entry"}, wantErr: ErrInvalidResponseFormat},
+		{name: "csv with xml", format: ResponseFormatXML, csv: &CSV{}, wantErr: ErrInvalidResponseFormat},
+		{
+			name:    "empty element",
+			format:  ResponseFormatXML,
+			xml:     &XML{RecordPath: "rss//item"},
+			wantErr: ErrInvalidResponseFormat,
+		},
+		{name: "msgpack", format: ResponseFormatMessagePack},
+		{
+			name:   "protobuf",
+			format: ResponseFormatProtobuf,
+			pb:     &Protobuf{Descriptor: "market.pb", Message: "market.v1.Candles"},
+		},
+		{name: "protobuf without message", format: ResponseFormatProtobuf, wantErr: ErrInvalidResponseFormat},
+		{
+			name:    "protobuf without descriptor",
+			format:  ResponseFormatProtobuf,
+			pb:      &Protobuf{Message: "market.v1.Candles"},
+			wantErr: ErrInvalidResponseFormat,
+		},
+		{
+			name:    "protobuf without format",
+			pb:      &Protobuf{Descriptor: "market.pb", Message: "market.v1.Candles"},
+			wantErr: ErrInvalidResponseFormat,
+		},
+		{name: "registered", format: "config-test"},
+		{name: "unknown", format: "yaml", wantErr: ErrInvalidResponseFormat},
+		{name: "csv without format", csv: &CSV{Delimiter: ";"}, wantErr: ErrInvalidResponseFormat},
+		{name: "long delimiter", format: ResponseFormatCSV, csv: &CSV{Delimiter: ";;"}, wantErr: ErrInvalidResponseFormat},
+		{name: "quote delimiter", format: ResponseFormatCSV, csv: &CSV{Delimiter: `"`}, wantErr: ErrInvalidResponseFormat},
+		{
+			name:    "no header or columns",
+			format:  ResponseFormatCSV,
+			csv:     &CSV{Header: &noHeader},
+			wantErr: ErrInvalidResponseFormat,
+		},
+		{
+			name:    "duplicate column",
+			format:  ResponseFormatCSV,
+			csv:     &CSV{Columns: []string{"id", "id"}},
+			wantErr: ErrInvalidResponseFormat,
+		},
+	} {
+		req := &Request{Endpoint: "/candles", Format: tcase.format, CSV: tcase.csv, XML: tcase.xml, Protobuf: tcase.pb}
+		if err := req.validateFormat(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+
+	var csv *CSV
+	if csv.Comma() != ',' || !csv.HasHeader() {
+		t.Errorf("expected a comma and a header by default")
+	}
+
+	if path := (&XML{RecordPath: "/rss/channel/item"}).Path(); len(path) != 3 || path[2] != "item" {
+		t.Errorf("expected the record path, got %v", path)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// Pool is the connection pool of a storage device. Settings that are zero keep the default of the storage device.
+type Pool struct {
+	// MaxOpenConns is the maximum number of open connections.
+	MaxOpenConns int `yaml:"maxOpenConns"`
+
+	// MaxIdleConns is the maximum number of idle connections that are kept open.
+	MaxIdleConns int `yaml:"maxIdleConns"`
+
+	// ConnMaxLifetime is the maximum amount of time a connection is reused, as a Go duration, e.g. "30m".
+	ConnMaxLifetime string `yaml:"connMaxLifetime"`
+}
+
+// Lifetime will return the maximum amount of time a connection is reused, or zero if it is not set.
+func (pool *Pool) Lifetime() (time.Duration, error) {
+	if pool.ConnMaxLifetime == "" {
+		return 0, nil
+	}
+
+	lifetime, err := time.ParseDuration(pool.ConnMaxLifetime)
+	if err != nil || lifetime < 0 {
+		return 0, fmt.Errorf("%w: connMaxLifetime %q", ErrInvalidPool, pool.ConnMaxLifetime)
+	}
+
+	return lifetime, nil
+}
+
+func (pool *Pool) validate() error {
+	if pool.MaxOpenConns < 0 || pool.MaxIdleConns < 0 {
+		return fmt.Errorf("%w: negative number of connections", ErrInvalidPool)
+	}
+
+	_, err := pool.Lifetime()
+
+	return err
+}
+
+// Defaults of the write backoff of a destination that does not configure it.
+const (
+	defaultWriteBackoffPause  = time.Second
+	defaultWriteBackoffPeriod = 30 * time.Second
+)
+
+// WriteBackoff is how the writes to a storage device back off when it reports contention, such as lock timeouts or
+// running out of connections. Writes are paused for the pause, which doubles if the contention persists, and are then
+// made one at a time for the period.
+type WriteBackoff struct {
+	// Pause is how long writes are paused for after contention, as a Go duration, e.g. "1s".
+	Pause string `yaml:"pause"`
+
+	// Period is how long writes are made one at a time after the pause, as a Go duration, e.g. "30s".
+	Period string `yaml:"period"`
+}
+
+// Durations will return the pause and period of the write backoff, keeping the default of any that is not set.
+func (backoff *WriteBackoff) Durations() (time.Duration, time.Duration, error) {
+	pause, period := defaultWriteBackoffPause, defaultWriteBackoffPeriod
+	if backoff == nil {
+		return pause, period, nil
+	}
+
+	for _, setting := range []struct {
+		value    string
+		duration *time.Duration
+	}{{backoff.Pause, &pause}, {backoff.Period, &period}} {
+		if setting.value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(setting.value)
+		if err != nil || duration < 0 {
+			return 0, 0, fmt.Errorf("%w: %q", ErrInvalidWriteBackoff, setting.value)
+		}
+
+		*setting.duration = duration
+	}
+
+	return pause, period, nil
+}
+
+// Destination is a storage device that only receives the tables matched by its filters. Filters are glob patterns
+// in the syntax of "path.Match", e.g. "candles" or "candles_*".
+type Destination struct {
+	// ConnectionString is the connection string of the storage device.
+	ConnectionString string `yaml:"connectionString"`
+
+	// Include are the table patterns to store. If it is empty, every table is stored unless it is excluded.
+	Include []string `yaml:"include"`
+
+	// Exclude are the table patterns to never store, even if they are included.
+	Exclude []string `yaml:"exclude"`
+
+	// BatchSize is the maximum number of records in each write to the storage device, overriding the batch size of
+	// the configuration.
+	BatchSize int `yaml:"batchSize"`
+
+	// Pool configures the connection pool of the storage device, e.g. to keep the workers of a run from exhausting
+	// the connections of a database.
+	Pool *Pool `yaml:"pool"`
+
+	// WriteBackoff configures how the writes to the storage device back off when it is contended.
+	WriteBackoff *WriteBackoff `yaml:"writeBackoff"`
+}
+
+func (dest *Destination) validate() error {
+	if dest.ConnectionString == "" {
+		return MissingConfigFieldError("destinations.connectionString")
+	}
+
+	if dest.BatchSize < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidBatchSize, dest.BatchSize)
+	}
+
+	if dest.Pool != nil {
+		if err := dest.Pool.validate(); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := dest.WriteBackoff.Durations(); err != nil {
+		return err
+	}
+
+	for _, pattern := range append(append([]string{}, dest.Include...), dest.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidTablePattern, pattern)
+		}
+	}
+
+	return nil
+}
+
+// matchAny returns "true" if the table matches any of the patterns.
+func matchAny(patterns []string, table string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Routes will return "true" if records for the table should be stored on the destination.
+func (dest *Destination) Routes(table string) bool {
+	if len(dest.Include) > 0 && !matchAny(dest.Include, table) {
+		return false
+	}
+
+	return !matchAny(dest.Exclude, table)
+}
+
+// DestinationList will return every destination on the configuration. Each of the "ConnectionStrings" is a
+// destination that receives every table, followed by the filtered "Destinations". Empty destinations are skipped.
+func (cfg *Config) DestinationList() []*Destination {
+	list := make([]*Destination, 0, len(cfg.ConnectionStrings)+len(cfg.Destinations))
+	for _, dns := range cfg.ConnectionStrings {
+		list = append(list, &Destination{ConnectionString: dns})
+	}
+
+	for _, dest := range cfg.Destinations {
+		if dest != nil {
+			list = append(list, dest)
+		}
+	}
+
+	return list
+}
+
+// BatchSizeFor will return the maximum number of records in each write to the destination.
+func (cfg *Config) BatchSizeFor(dest *Destination) int {
+	if dest.BatchSize > 0 {
+		return dest.BatchSize
+	}
+
+	return cfg.BatchSize
+}
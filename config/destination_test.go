@@ -0,0 +1,160 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDestination(t *testing.T) {
+	t.Parallel()
+
+	t.Run("routes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name     string
+			dest     Destination
+			table    string
+			expected bool
+		}{
+			{name: "no filters", dest: Destination{}, table: "candles", expected: true},
+			{name: "included", dest: Destination{Include: []string{"candles"}}, table: "candles", expected: true},
+			{name: "not included", dest: Destination{Include: []string{"candles"}}, table: "accounts"},
+			{name: "glob", dest: Destination{Include: []string{"candles_*"}}, table: "candles_1m", expected: true},
+			{name: "excluded", dest: Destination{Exclude: []string{"accounts"}}, table: "accounts"},
+			{
+				name:  "included and excluded",
+				dest:  Destination{Include: []string{"candles_*"}, Exclude: []string{"candles_1s"}},
+				table: "candles_1s",
+			},
+		} {
+			if got := tcase.dest.Routes(tcase.table); got != tcase.expected {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.expected, got)
+			}
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+
+		dest := Destination{ConnectionString: "stdout://", Include: []string{"candles["}}
+		if err := dest.validate(); !errors.Is(err, ErrInvalidTablePattern) {
+			t.Errorf("expected %v, got %v", ErrInvalidTablePattern, err)
+		}
+
+		dest = Destination{Include: []string{"candles"}}
+		if err := dest.validate(); !errors.Is(err, ErrMissingConfigField) {
+			t.Errorf("expected %v, got %v", ErrMissingConfigField, err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		// A stray "-" or "~" in the list of destinations is an empty destination.
+		cfg, err := Parse(context.Background(), []byte(`
+url: https://api.example.com
+destinations: [~, {connectionString: "stdout://"}]
+rateLimit:
+  burst: 1
+  period: 1s
+requests:
+  - endpoint: /products/candles
+`))
+		if !errors.Is(err, ErrMissingConfigField) || !strings.Contains(err.Error(), "destinations[0]") {
+			t.Errorf("expected %v for destinations[0], got %v and %+v", ErrMissingConfigField, err, cfg)
+		}
+
+		list := (&Config{Destinations: []*Destination{nil, {ConnectionString: "stdout://"}}}).DestinationList()
+		if len(list) != 1 || list[0].ConnectionString != "stdout://" {
+			t.Errorf("expected the empty destination to be skipped, got %+v", list)
+		}
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{BatchSize: 500}
+
+		if size := cfg.BatchSizeFor(&Destination{}); size != 500 {
+			t.Errorf("expected the configuration batch size, got %d", size)
+		}
+
+		if size := cfg.BatchSizeFor(&Destination{BatchSize: 10}); size != 10 {
+			t.Errorf("expected the destination batch size, got %d", size)
+		}
+
+		dest := Destination{ConnectionString: "stdout://", BatchSize: -1}
+		if err := dest.validate(); !errors.Is(err, ErrInvalidBatchSize) {
+			t.Errorf("expected %v, got %v", ErrInvalidBatchSize, err)
+		}
+	})
+
+	t.Run("pool", func(t *testing.T) {
+		t.Parallel()
+
+		pool := &Pool{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: "30m"}
+
+		dest := Destination{ConnectionString: "postgresql://localhost:5432/db", Pool: pool}
+		if err := dest.validate(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if lifetime, err := pool.Lifetime(); err != nil || lifetime != 30*time.Minute {
+			t.Errorf("expected a lifetime of 30m, got %v and %v", lifetime, err)
+		}
+
+		for _, pool := range []*Pool{{MaxOpenConns: -1}, {ConnMaxLifetime: "30"}, {ConnMaxLifetime: "-1m"}} {
+			dest.Pool = pool
+			if err := dest.validate(); !errors.Is(err, ErrInvalidPool) {
+				t.Errorf("expected %v for %+v, got %v", ErrInvalidPool, pool, err)
+			}
+		}
+	})
+
+	t.Run("write backoff", func(t *testing.T) {
+		t.Parallel()
+
+		var unset *WriteBackoff
+		if pause, period, err := unset.Durations(); err != nil || pause != time.Second || period != 30*time.Second {
+			t.Errorf("expected the defaults, got %v, %v and %v", pause, period, err)
+		}
+
+		backoff := &WriteBackoff{Pause: "250ms"}
+		if pause, period, err := backoff.Durations(); err != nil || pause != 250*time.Millisecond ||
+			period != 30*time.Second {
+			t.Errorf("expected a pause of 250ms and the default period, got %v, %v and %v", pause, period, err)
+		}
+
+		for _, backoff := range []*WriteBackoff{{Pause: "1"}, {Period: "-1s"}} {
+			dest := Destination{ConnectionString: "stdout://", WriteBackoff: backoff}
+			if err := dest.validate(); !errors.Is(err, ErrInvalidWriteBackoff) {
+				t.Errorf("expected %v for %+v, got %v", ErrInvalidWriteBackoff, backoff, err)
+			}
+		}
+	})
+
+	t.Run("destination list", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{
+			ConnectionStrings: []string{"stdout://"},
+			Destinations:      []*Destination{{ConnectionString: "file:///tmp", Include: []string{"candles"}}},
+		}
+
+		list := cfg.DestinationList()
+		if len(list) != 2 || list[0].ConnectionString != "stdout://" || len(list[1].Include) != 1 {
+			t.Errorf("unexpected destination list %v", list)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultDrainTimeout is how long the writes in flight of a run that is cancelled are drained for if no drain timeout
+// is configured.
+const defaultDrainTimeout = 30 * time.Second
+
+// DrainTimeoutDuration will return how long the writes in flight of a run that is cancelled are given to finish.
+func (cfg *Config) DrainTimeoutDuration() (time.Duration, error) {
+	if cfg.DrainTimeout == "" {
+		return defaultDrainTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(cfg.DrainTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDrainTimeout, cfg.DrainTimeout)
+	}
+
+	return timeout, nil
+}
+
+// MaxRunDuration will return how long the run may take, or zero if it is not limited.
+func (cfg *Config) MaxRunDuration() (time.Duration, error) {
+	if cfg.MaxDuration == "" {
+		return 0, nil
+	}
+
+	maxDuration, err := time.ParseDuration(cfg.MaxDuration)
+	if err != nil || maxDuration <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMaxDuration, cfg.MaxDuration)
+	}
+
+	return maxDuration, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfigDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name         string
+		drainTimeout string
+		expected     time.Duration
+		wantErr      error
+	}{
+		{name: "default", expected: 30 * time.Second},
+		{name: "drain timeout", drainTimeout: "5s", expected: 5 * time.Second},
+		{name: "no drain", drainTimeout: "0s"},
+		{name: "invalid", drainTimeout: "5", wantErr: ErrInvalidDrainTimeout},
+		{name: "negative", drainTimeout: "-1s", wantErr: ErrInvalidDrainTimeout},
+	} {
+		cfg := &Config{DrainTimeout: tcase.drainTimeout}
+
+		timeout, err := cfg.DrainTimeoutDuration()
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if timeout != tcase.expected {
+			t.Errorf("%s: expected a drain timeout of %v, got %v", tcase.name, tcase.expected, timeout)
+		}
+	}
+}
+
+func TestConfigMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name        string
+		maxDuration string
+		expected    time.Duration
+		wantErr     error
+	}{
+		{name: "unlimited"},
+		{name: "max duration", maxDuration: "50m", expected: 50 * time.Minute},
+		{name: "zero", maxDuration: "0s", wantErr: ErrInvalidMaxDuration},
+		{name: "invalid", maxDuration: "1h-ish", wantErr: ErrInvalidMaxDuration},
+	} {
+		cfg := &Config{MaxDuration: tcase.maxDuration}
+
+		maxDuration, err := cfg.MaxRunDuration()
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if maxDuration != tcase.expected {
+			t.Errorf("%s: expected a max duration of %v, got %v", tcase.name, tcase.expected, maxDuration)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envPattern matches "${VAR}" and "${VAR:-default}" references to environment variables, and the "$${" escape.
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv will replace every "${VAR}" in the YAML with the value of the environment variable, and every
+// "${VAR:-default}" with the value of the variable or the default if the variable is unset or empty. "$${" is
+// replaced with a literal "${". Variables that are unset and have no default are an error, so that a missing
+// variable is not silently written as an empty value.
+//
+// Expansion is not part of "Parse", so that configurations sent to a service cannot read its environment.
+func ExpandEnv(bytes []byte) ([]byte, error) {
+	var undefined []string
+
+	expanded := envPattern.ReplaceAllStringFunc(string(bytes), func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+
+		match := envPattern.FindStringSubmatch(ref)
+		name, hasDefault, def := match[1], match[2] != "", match[3]
+
+		value, ok := os.LookupEnv(name)
+		if hasDefault && value == "" {
+			return def
+		}
+
+		if !ok {
+			undefined = append(undefined, name)
+		}
+
+		return value
+	})
+
+	if len(undefined) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUndefinedVariable, strings.Join(undefined, ", "))
+	}
+
+	return []byte(expanded), nil
+}
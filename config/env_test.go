@@ -0,0 +1,54 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GIDARI_TEST_ENV", "prod")
+	t.Setenv("GIDARI_TEST_EMPTY", "")
+
+	for _, tcase := range []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr error
+	}{
+		{name: "variable", yaml: "tablePrefix: ${GIDARI_TEST_ENV}_", want: "tablePrefix: prod_"},
+		{name: "default unused", yaml: "url: ${GIDARI_TEST_ENV:-dev}", want: "url: prod"},
+		{name: "default unset", yaml: "url: ${GIDARI_TEST_UNSET:-https://dev.example.com}", want: "url: https://dev.example.com"},
+		{name: "default empty", yaml: "url: ${GIDARI_TEST_EMPTY:-dev}", want: "url: dev"},
+		{name: "empty default", yaml: "suffix: ${GIDARI_TEST_UNSET:-}", want: "suffix: "},
+		{name: "empty", yaml: "suffix: ${GIDARI_TEST_EMPTY}", want: "suffix: "},
+		{name: "escape", yaml: "match: $${GIDARI_TEST_ENV} $HOME", want: "match: ${GIDARI_TEST_ENV} $HOME"},
+		{name: "undefined", yaml: "url: ${GIDARI_TEST_UNSET}\ntable: ${GIDARI_TEST_MISSING}", wantErr: ErrUndefinedVariable},
+	} {
+		got, err := ExpandEnv([]byte(tcase.yaml))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+
+			continue
+		}
+
+		if err != nil {
+			if !strings.Contains(err.Error(), "GIDARI_TEST_UNSET, GIDARI_TEST_MISSING") {
+				t.Errorf("%s: expected every undefined variable, got %v", tcase.name, err)
+			}
+
+			continue
+		}
+
+		if string(got) != tcase.want {
+			t.Errorf("%s: expected %q, got %q", tcase.name, tcase.want, got)
+		}
+	}
+}
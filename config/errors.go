@@ -10,13 +10,75 @@ package config
 import "fmt"
 
 var (
+	ErrCommandNotAllowed        = fmt.Errorf("command is not allowed")
+	ErrDuplicateRequest         = fmt.Errorf("duplicate request")
 	ErrFetchingTimeseriesChunks = fmt.Errorf("failed to fetch timeseries chunks")
+	ErrInvalidAdaptive          = fmt.Errorf("invalid adaptive concurrency")
+	ErrInvalidAuthentication    = fmt.Errorf("invalid authentication")
+	ErrInvalidBatchSize         = fmt.Errorf("invalid batch size")
+	ErrInvalidCoalesce          = fmt.Errorf("invalid coalesce")
+	ErrInvalidColumn            = fmt.Errorf("invalid column mapping")
+	ErrInvalidCoercion          = fmt.Errorf("invalid coercion")
+	ErrInvalidCommand           = fmt.Errorf("invalid command")
+	ErrInvalidConfigPath        = fmt.Errorf("invalid config path")
+	ErrInvalidConflict          = fmt.Errorf("invalid conflict strategy")
+	ErrInvalidDeadLetter        = fmt.Errorf("invalid dead letter")
+	ErrInvalidDecoding          = fmt.Errorf("invalid decoding")
 	ErrInvalidRateLimit         = fmt.Errorf("invalid rate limit configuration")
+	ErrInvalidRedis             = fmt.Errorf("invalid redis URL")
+	ErrInvalidResponseFormat    = fmt.Errorf("invalid response format")
+	ErrInvalidRetention         = fmt.Errorf("invalid retention")
+	ErrInvalidRetry             = fmt.Errorf("invalid retry policy")
+	ErrInvalidRetryBudget       = fmt.Errorf("invalid retry budget")
+	ErrInvalidSecrets           = fmt.Errorf("invalid secrets file")
+	ErrInvalidDocument          = fmt.Errorf("invalid document configuration")
+	ErrInvalidDrainTimeout      = fmt.Errorf("invalid drain timeout")
+	ErrInvalidFields            = fmt.Errorf("invalid fields")
+	ErrInvalidFilter            = fmt.Errorf("invalid filter")
+	ErrInvalidFormat            = fmt.Errorf("invalid config format")
+	ErrInvalidHashKey           = fmt.Errorf("invalid hash key")
+	ErrInvalidInclude           = fmt.Errorf("invalid include")
+	ErrInvalidJobBuffer         = fmt.Errorf("invalid job buffer")
+	ErrInvalidLogFormat         = fmt.Errorf("invalid log format")
+	ErrInvalidLogSample         = fmt.Errorf("invalid log sample")
+	ErrInvalidMask              = fmt.Errorf("invalid mask")
+	ErrInvalidMaxConnsPerHost   = fmt.Errorf("invalid max connections per host")
+	ErrInvalidMaxDuration       = fmt.Errorf("invalid max duration")
+	ErrInvalidMemoryLimit       = fmt.Errorf("invalid memory limit")
+	ErrInvalidNaming            = fmt.Errorf("invalid naming convention")
+	ErrInvalidNotify            = fmt.Errorf("invalid notification")
+	ErrInvalidOnError           = fmt.Errorf("invalid error policy")
+	ErrInvalidPoisonThreshold   = fmt.Errorf("invalid poison threshold")
+	ErrInvalidPool              = fmt.Errorf("invalid connection pool")
+	ErrInvalidPrimaryKey        = fmt.Errorf("invalid primary key")
+	ErrInvalidProfile           = fmt.Errorf("invalid profile")
+	ErrInvalidQuarantine        = fmt.Errorf("invalid quarantine")
+	ErrInvalidSchema            = fmt.Errorf("invalid schema")
+	ErrInvalidScript            = fmt.Errorf("invalid script")
+	ErrInvalidSelector          = fmt.Errorf("invalid selector")
+	ErrInvalidSoftDelete        = fmt.Errorf("invalid soft delete")
+	ErrInvalidSplit             = fmt.Errorf("invalid split")
+	ErrInvalidStreamRecords     = fmt.Errorf("invalid stream records")
+	ErrInvalidTablePattern      = fmt.Errorf("invalid table pattern")
+	ErrInvalidTimeRange         = fmt.Errorf("invalid time range")
+	ErrInvalidTimeseries        = fmt.Errorf("invalid timeseries")
+	ErrInvalidTransaction       = fmt.Errorf("invalid transaction scope")
+	ErrInvalidTransforms        = fmt.Errorf("invalid transforms")
+	ErrInvalidTruncate          = fmt.Errorf("invalid truncate")
+	ErrInvalidVariable          = fmt.Errorf("invalid variable")
+	ErrInvalidVerify            = fmt.Errorf("invalid verification")
+	ErrInvalidWatchdog          = fmt.Errorf("invalid watchdog")
+	ErrInvalidWriteBackoff      = fmt.Errorf("invalid write backoff")
+	ErrInvalidWorkerCount       = fmt.Errorf("invalid worker count")
+	ErrInvalidWriteMode         = fmt.Errorf("invalid write mode")
+	ErrLocalOnly                = fmt.Errorf("only supported in local configurations")
 	ErrMissingConfigField       = fmt.Errorf("missing config field")
 	ErrMissingRateLimitField    = fmt.Errorf("missing rate limit field")
 	ErrMissingTimeseriesField   = fmt.Errorf("missing timeseries field")
 	ErrSettingTimeseriesChunks  = fmt.Errorf("failed to set timeseries chunks")
 	ErrUnableToParse            = fmt.Errorf("unable to parse")
+	ErrUndefinedVariable        = fmt.Errorf("undefined environment variable")
+	ErrUnknownScheme            = fmt.Errorf("unknown storage scheme")
 	ErrNoRequests               = fmt.Errorf("no requests defined")
 )
 
@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldPath will split the path of a field that is included or excluded into the fields of the nested objects that it
+// names, e.g. ["meta", "request_id"] for "meta.request_id".
+func FieldPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// validateFields will check that the fields that are included and excluded on the request are named, and that no field
+// is both included and excluded.
+func (req *Request) validateFields() error {
+	for _, paths := range [][]string{req.IncludeFields, req.ExcludeFields} {
+		for _, path := range paths {
+			for _, field := range FieldPath(path) {
+				if field == "" {
+					return fmt.Errorf("%w: empty field in %q on %q", ErrInvalidFields, path, req.Endpoint)
+				}
+			}
+		}
+	}
+
+	included := make(map[string]bool, len(req.IncludeFields))
+	for _, path := range req.IncludeFields {
+		included[path] = true
+	}
+
+	for _, path := range req.ExcludeFields {
+		if included[path] {
+			return fmt.Errorf("%w: %q is both included and excluded on %q", ErrInvalidFields, path, req.Endpoint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateFields(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name    string
+		include []string
+		exclude []string
+		wantErr error
+	}{
+		{name: "none"},
+		{name: "include", include: []string{"id", "meta.request_id"}},
+		{name: "exclude", exclude: []string{"debug", "meta.trace"}},
+		{name: "nested exclude", include: []string{"meta"}, exclude: []string{"meta.trace"}},
+		{name: "empty", include: []string{""}, wantErr: ErrInvalidFields},
+		{name: "empty nested", exclude: []string{"meta..trace"}, wantErr: ErrInvalidFields},
+		{name: "trailing dot", exclude: []string{"meta."}, wantErr: ErrInvalidFields},
+		{name: "both", include: []string{"id", "debug"}, exclude: []string{"debug"}, wantErr: ErrInvalidFields},
+	} {
+		req := &Request{Endpoint: "/candles", IncludeFields: tcase.include, ExcludeFields: tcase.exclude}
+		if err := req.validateFields(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
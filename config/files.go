@@ -0,0 +1,72 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configExts are the extensions of the configuration files in a directory.
+var configExts = map[string]bool{".yml": true, ".yaml": true, ".json": true, ".toml": true}
+
+// Files will return the configuration files at the path, so that a large setup can be split into one file per web
+// API. The path is either a file, a directory whose ".yml", ".yaml", ".json", and ".toml" files are returned, or
+// a glob pattern in the syntax of "filepath.Match", e.g. "configs/*.yaml". The files are sorted by name.
+func Files(path string) ([]string, error) {
+	if path == StdinPath {
+		return []string{path}, nil
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidConfigPath, path)
+		}
+
+		return nonEmptyFiles(path, files)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get file stat for reading: %w", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config directory: %w", err)
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		if !entry.IsDir() && configExts[filepath.Ext(entry.Name())] {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	return nonEmptyFiles(path, files)
+}
+
+// nonEmptyFiles will sort the files, returning an error if there are none.
+func nonEmptyFiles(path string, files []string) ([]string, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no configuration files match %q", ErrInvalidConfigPath, path)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
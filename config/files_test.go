@@ -0,0 +1,67 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"coinbase.yaml", "apis/polygon.yml", "apis/README.md", "kraken.yml", "polygon.json", "kraken.toml"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte("url: x"), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	for _, tcase := range []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr error
+	}{
+		{name: "file", path: "kraken.yml", want: []string{"kraken.yml"}},
+		{name: "directory", path: "", want: []string{"coinbase.yaml", "kraken.toml", "kraken.yml", "polygon.json"}},
+		{name: "nested directory", path: "apis", want: []string{"apis/polygon.yml"}},
+		{name: "glob", path: "*.y*ml", want: []string{"coinbase.yaml", "kraken.yml"}},
+		{name: "no matches", path: "*.ini", wantErr: ErrInvalidConfigPath},
+		{name: "invalid glob", path: "[", wantErr: ErrInvalidConfigPath},
+	} {
+		files, err := Files(filepath.Join(dir, tcase.path))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+
+			continue
+		}
+
+		want := make([]string, len(tcase.want))
+		for idx, name := range tcase.want {
+			want[idx] = filepath.Join(dir, name)
+		}
+
+		if err == nil && !reflect.DeepEqual(files, want) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, want, files)
+		}
+	}
+
+	if _, err := Files(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
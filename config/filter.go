@@ -0,0 +1,59 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import "strings"
+
+// FilterRequests will remove every request that does not match one of the "only" names, if there are any, and every
+// request that matches one of the "skip" names, e.g. to re-run a single table without editing the configuration. A
+// request matches a name that is its table, with or without the prefix and suffix of the configuration, or its
+// endpoint, with or without the query.
+func (cfg *Config) FilterRequests(only, skip []string) {
+	if len(only) == 0 && len(skip) == 0 {
+		return
+	}
+
+	requests := make([]*Request, 0, len(cfg.Requests))
+
+	for _, req := range cfg.Requests {
+		if len(only) > 0 && !cfg.matchesRequest(req, only) {
+			continue
+		}
+
+		if cfg.matchesRequest(req, skip) {
+			continue
+		}
+
+		requests = append(requests, req)
+	}
+
+	cfg.Requests = requests
+}
+
+// DumpsRequest will return true if the request and response of each chunk of the request are written to the dump
+// directory, see "DumpDir".
+func (cfg *Config) DumpsRequest(req *Request) bool {
+	return cfg.DumpDir != "" && (len(cfg.DumpRequests) == 0 || cfg.matchesRequest(req, cfg.DumpRequests))
+}
+
+// matchesRequest will return true if any of the names match the request.
+func (cfg *Config) matchesRequest(req *Request, names []string) bool {
+	table := req.Table
+	if table == "" {
+		table = defaultTable(req.Endpoint)
+	}
+
+	for _, name := range names {
+		switch name {
+		case table, cfg.StorageTable(table), req.Endpoint, strings.Split(req.Endpoint, "?")[0]:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterRequests(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name       string
+		only, skip []string
+		want       []string
+	}{
+		{name: "none", want: []string{"/accounts", "/currencies", "/products/BTC-USD/candles?granularity=60"}},
+		{name: "only table", only: []string{"candles"}, want: []string{"/products/BTC-USD/candles?granularity=60"}},
+		{name: "only storage table", only: []string{"cb_currencies"}, want: []string{"/currencies"}},
+		{
+			name: "only endpoint", only: []string{"/accounts", "/products/BTC-USD/candles"},
+			want: []string{"/accounts", "/products/BTC-USD/candles?granularity=60"},
+		},
+		{name: "skip", skip: []string{"accounts"}, want: []string{"/currencies", "/products/BTC-USD/candles?granularity=60"}},
+		{name: "only and skip", only: []string{"accounts", "currencies"}, skip: []string{"/accounts"}, want: []string{"/currencies"}},
+		{name: "no match", only: []string{"orders"}, want: []string{}},
+	} {
+		tcase := tcase
+
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &Config{
+				TablePrefix: "cb_",
+				Requests: []*Request{
+					{Endpoint: "/accounts"},
+					{Endpoint: "/currencies", Table: "currencies"},
+					{Endpoint: "/products/BTC-USD/candles?granularity=60", Table: "candles"},
+				},
+			}
+
+			cfg.FilterRequests(tcase.only, tcase.skip)
+
+			endpoints := make([]string, 0, len(cfg.Requests))
+			for _, req := range cfg.Requests {
+				endpoints = append(endpoints, req.Endpoint)
+			}
+
+			if !reflect.DeepEqual(endpoints, tcase.want) {
+				t.Errorf("expected %v, got %v", tcase.want, endpoints)
+			}
+		})
+	}
+}
+
+func TestDumpsRequest(t *testing.T) {
+	t.Parallel()
+
+	candles := &Request{Endpoint: "/products/BTC-USD/candles", Table: "candles"}
+	accounts := &Request{Endpoint: "/accounts"}
+
+	cfg := &Config{}
+	if cfg.DumpsRequest(candles) {
+		t.Errorf("expected no requests to be dumped without a directory")
+	}
+
+	cfg.DumpDir = "dumps"
+	if !cfg.DumpsRequest(candles) || !cfg.DumpsRequest(accounts) {
+		t.Errorf("expected every request to be dumped without names")
+	}
+
+	cfg.DumpRequests = []string{"candles"}
+	if !cfg.DumpsRequest(candles) || cfg.DumpsRequest(accounts) {
+		t.Errorf("expected only the candles request to be dumped")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Formats of a configuration file.
+const (
+	// FormatAuto detects the format of a file by its extension.
+	FormatAuto = "auto"
+
+	// FormatYAML is the default format of a configuration file.
+	FormatYAML = "yaml"
+
+	// FormatJSON is the format of files with the ".json" extension, e.g. configurations that are generated by other
+	// tools.
+	FormatJSON = "json"
+
+	// FormatTOML is the format of files with the ".toml" extension.
+	FormatTOML = "toml"
+)
+
+// FormatFromPath will return the format of the configuration file, by its extension.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatYAML
+	}
+}
+
+// toYAML will convert the configuration in the format to YAML, so that every format is parsed into the same
+// configuration.
+func toYAML(bytes []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return bytes, nil
+	case FormatJSON:
+		var doc interface{}
+		if err := json.Unmarshal(bytes, &doc); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal JSON: %w", err)
+		}
+
+		return marshalYAML(doc)
+	case FormatTOML:
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(bytes), &doc); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal TOML: %w", err)
+		}
+
+		return marshalYAML(doc)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+}
+
+// marshalYAML will marshal the document that was unmarshaled from another format.
+func marshalYAML(doc interface{}) ([]byte, error) {
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal YAML: %w", err)
+	}
+
+	return out, nil
+}
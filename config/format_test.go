@@ -0,0 +1,98 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadFileFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	jsonConfig := `{
+	"url": "https://api.pro.coinbase.com",
+	"connectionStrings": ["stdout://"],
+	"rateLimit": {"burst": 5, "period": "1s"},
+	"requests": [{"endpoint": "/products/BTC-USD/candles", "primaryKey": ["time"]}]
+}`
+
+	tomlConfig := `
+url = "https://api.pro.coinbase.com"
+connectionStrings = ["stdout://"]
+
+[rateLimit]
+burst = 5
+period = "1s"
+
+[[requests]]
+endpoint = "/products/BTC-USD/candles"
+primaryKey = ["time"]
+`
+
+	for name, body := range map[string]string{
+		"config.json":      jsonConfig,
+		"config.generated": jsonConfig,
+		"config.toml":      tomlConfig,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	for _, tcase := range []struct {
+		name    string
+		format  string
+		wantErr error
+	}{
+		{name: "config.json", format: FormatAuto},
+		{name: "config.generated", format: FormatJSON},
+		{name: "config.json", format: FormatYAML},
+		{name: "config.toml", format: FormatAuto},
+		{name: "config.json", format: "xml", wantErr: ErrInvalidFormat},
+	} {
+		bytes, err := ReadFile(filepath.Join(dir, tcase.name), tcase.format)
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s %s: expected %v, got %v", tcase.name, tcase.format, tcase.wantErr, err)
+
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		cfg, err := Parse(context.Background(), bytes)
+		if err != nil {
+			t.Fatalf("%s %s: failed to parse: %v", tcase.name, tcase.format, err)
+		}
+
+		if *cfg.RateLimitConfig.Burst != 5 || *cfg.RateLimitConfig.Period != time.Second ||
+			cfg.Requests[0].PrimaryKey[0] != "time" {
+			t.Errorf("%s %s: unexpected config %+v", tcase.name, tcase.format, cfg)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{url: x}"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "invalid.json"), FormatAuto); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "invalid.json"), FormatTOML); err == nil {
+		t.Error("expected an error for invalid TOML")
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// StdinPath is the path of a configuration that is read from stdin.
+const StdinPath = "-"
+
+// includeKey is the key of the files that a configuration file includes.
+const includeKey = "include"
+
+// ReadFile will read the configuration file in the format, expanding its environment variables and merging the files
+// that it includes, and return it as YAML for "Parse". If the format is "auto", it is detected by the extension of the
+// file, as is the format of every included file. Shared fragments, e.g. the authentication, rate limit, or connection
+// strings of many configurations, can be kept in one file and included by the others:
+//
+//	include:
+//	  - shared/coinbase.yaml
+//	  - shared/storage.yaml
+//
+// Included paths, like the schema files of tables, are relative to the including file, and included files can include
+// other files. The fragments are merged in order, and then the including file is merged over them: maps are merged key
+// by key, and any other value, including a list, replaces the value of the earlier file.
+//
+// The secrets file that the configuration references, if any, is merged over it once its includes are merged.
+//
+// If the path is "-", the configuration is read from stdin, e.g. when it is generated by an orchestrator. Its includes
+// are relative to the working directory, and its format is YAML unless it is set.
+func ReadFile(path, format string) ([]byte, error) {
+	var bytes []byte
+
+	var err error
+
+	if path == StdinPath {
+		bytes, err = io.ReadAll(os.Stdin)
+	} else {
+		bytes, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+
+	return resolveFile(path, bytes, format)
+}
+
+// resolveFile will expand the environment variables of the configuration file that was read from the path, convert
+// it from its format to YAML, and merge the files that it includes and its secrets file.
+func resolveFile(path string, bytes []byte, format string) ([]byte, error) {
+	if format == FormatAuto {
+		format = FormatFromPath(path)
+	}
+
+	bytes, err := ExpandEnv(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err = toYAML(bytes, format)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := includeFile(path, bytes, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	if doc == nil {
+		if err := yaml.Unmarshal(bytes, &doc); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal YAML of %s: %w", path, err)
+		}
+
+		resolved, err := resolveSchemaFiles(doc, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+
+		// Files without includes, secrets, or relative schema files are returned as they were written, so that
+		// errors refer to their lines.
+		if _, ok := doc[secretsKey]; !ok && !resolved {
+			return bytes, nil
+		}
+	} else if _, err := resolveSchemaFiles(doc, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if err := mergeSecrets(doc, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal YAML: %w", err)
+	}
+
+	return out, nil
+}
+
+// includeFile will return the YAML document of the file merged over the files that it includes, or nil if it does
+// not include any files. "including" are the files that are being included, to detect cycles.
+func includeFile(path string, bytes []byte, including map[string]bool) (map[interface{}]interface{}, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML of %s: %w", path, err)
+	}
+
+	includes, err := includePaths(path, doc[includeKey])
+	if err != nil || includes == nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve %s: %w", path, err)
+	}
+
+	including[abs] = true
+	defer delete(including, abs)
+
+	merged := map[interface{}]interface{}{}
+
+	for _, include := range includes {
+		fragment, err := includeFragment(include, including)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeYAML(merged, fragment)
+	}
+
+	delete(doc, includeKey)
+	mergeYAML(merged, doc)
+
+	return merged, nil
+}
+
+// includeFragment will return the YAML document of the included file, with the files that it includes.
+func includeFragment(path string, including map[string]bool) (map[interface{}]interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve %s: %w", path, err)
+	}
+
+	if including[abs] {
+		return nil, fmt.Errorf("%w: %s includes itself", ErrInvalidInclude, path)
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInclude, err)
+	}
+
+	bytes, err = ExpandEnv(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err = toYAML(bytes, FormatFromPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	fragment, err := includeFile(path, bytes, including)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fragment does not include any files.
+	if fragment == nil {
+		if err := yaml.Unmarshal(bytes, &fragment); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal YAML of %s: %w", path, err)
+		}
+	}
+
+	// The secrets file of the fragment is relative to the fragment, not to the file that includes it.
+	if secrets, ok := fragment[secretsKey].(string); ok && secrets != "" && !filepath.IsAbs(secrets) {
+		fragment[secretsKey] = filepath.Join(filepath.Dir(path), secrets)
+	}
+
+	// So are the schema files of its tables. The schema files of the files that it includes are already resolved.
+	if _, err := resolveSchemaFiles(fragment, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	return fragment, nil
+}
+
+// includePaths will return the paths of the files that the file includes, relative to the directory of the file. The
+// include is a path or a list of paths.
+func includePaths(path string, include interface{}) ([]string, error) {
+	var paths []string
+
+	switch include := include.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		paths = []string{include}
+	case []interface{}:
+		for _, val := range include {
+			str, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %v is not a path in %s", ErrInvalidInclude, val, path)
+			}
+
+			paths = append(paths, str)
+		}
+	default:
+		return nil, fmt.Errorf("%w: include must be a path or a list of paths in %s", ErrInvalidInclude, path)
+	}
+
+	for idx, include := range paths {
+		if !filepath.IsAbs(include) {
+			paths[idx] = filepath.Join(filepath.Dir(path), include)
+		}
+	}
+
+	return paths, nil
+}
+
+// mergeYAML will merge the override document into the base document. Maps are merged key by key, and any other value
+// of the override replaces the value of the base.
+func mergeYAML(base, override map[interface{}]interface{}) {
+	for key, val := range override {
+		baseMap, baseOK := base[key].(map[interface{}]interface{})
+		overrideMap, overrideOK := val.(map[interface{}]interface{})
+
+		if baseOK && overrideOK {
+			mergeYAML(baseMap, overrideMap)
+
+			continue
+		}
+
+		base[key] = val
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadFile(t *testing.T) {
+	t.Setenv("GIDARI_TEST_PGHOST", "db.internal")
+
+	dir := t.TempDir()
+
+	write := func(name, yaml string) string {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		return path
+	}
+
+	write("shared/coinbase.yaml", `
+include: storage.yaml
+url: https://api.pro.coinbase.com
+authentication:
+  auth2:
+    bearer: token
+rateLimit:
+  burst: 5
+  period: 1s
+`)
+	write("shared/storage.yaml", `
+connectionStrings:
+  - postgresql://${GIDARI_TEST_PGHOST}:5432/db
+`)
+
+	path := write("candles.yaml", `
+include:
+  - shared/coinbase.yaml
+rateLimit:
+  burst: 1
+requests:
+  - endpoint: /products/BTC-USD/candles
+`)
+
+	bytes, err := ReadFile(path, FormatAuto)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	cfg, err := Parse(context.Background(), bytes)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if cfg.RawURL != "https://api.pro.coinbase.com" || cfg.Authentication.Auth2 == nil {
+		t.Errorf("expected the included web API, got %q %+v", cfg.RawURL, cfg.Authentication)
+	}
+
+	if len(cfg.ConnectionStrings) != 1 || cfg.ConnectionStrings[0] != "postgresql://db.internal:5432/db" {
+		t.Errorf("expected the nested include with its variables expanded, got %v", cfg.ConnectionStrings)
+	}
+
+	if *cfg.RateLimitConfig.Burst != 1 || *cfg.RateLimitConfig.Period != time.Second {
+		t.Errorf("expected the rate limit to be merged, got %d %v", *cfg.RateLimitConfig.Burst,
+			*cfg.RateLimitConfig.Period)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+
+	defer file.Close()
+
+	if _, err := New(context.Background(), file); err != nil {
+		t.Errorf("expected New to merge the includes, got %v", err)
+	}
+
+	for _, tcase := range []struct {
+		name string
+		yaml string
+	}{
+		{name: "cycle.yaml", yaml: "include: cycle.yaml\n"},
+		{name: "missing.yaml", yaml: "include: [shared/missing.yaml]\n"},
+		{name: "invalid.yaml", yaml: "include: {url: x}\n"},
+	} {
+		if _, err := ReadFile(write(tcase.name, tcase.yaml), FormatAuto); !errors.Is(err, ErrInvalidInclude) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, ErrInvalidInclude, err)
+		}
+	}
+
+	// Includes are only merged in files.
+	if _, err := Parse(context.Background(), []byte("include: shared/coinbase.yaml\n")); !errors.Is(err,
+		ErrInvalidInclude) {
+		t.Errorf("expected %v, got %v", ErrInvalidInclude, err)
+	}
+}
+
+func TestReadFileStdin(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	stdin := os.Stdin
+	os.Stdin = reader
+
+	t.Cleanup(func() {
+		os.Stdin = stdin
+
+		reader.Close()
+	})
+
+	go func() {
+		defer writer.Close()
+
+		_, _ = writer.WriteString(`{"url": "https://api.pro.coinbase.com"}`)
+	}()
+
+	bytes, err := ReadFile(StdinPath, FormatJSON)
+	if err != nil {
+		t.Fatalf("failed to read stdin: %v", err)
+	}
+
+	if string(bytes) != "url: https://api.pro.coinbase.com\n" {
+		t.Errorf("unexpected configuration %q", bytes)
+	}
+
+	if files, err := Files(StdinPath); err != nil || len(files) != 1 || files[0] != StdinPath {
+		t.Errorf("expected stdin, got %v %v", files, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// Lint takes the YAML of a configuration and returns every problem with it, instead of only the first problem that a
+// run would fail on. On top of the checks of "Validate", fields that are not part of the configuration, missing
+// required fields, timeseries that cannot be chunked, storage schemes that cannot be constructed, and requests that
+// would write the same records twice are reported. The configuration is valid if there are no problems.
+func Lint(bytes []byte) []error {
+	var cfg Config
+
+	var problems []error
+
+	if err := yaml.UnmarshalStrict(bytes, &cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []error{fmt.Errorf("unable to unmarshal YAML: %w", err)}
+		}
+
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, fmt.Errorf("%w: %s", ErrUnableToParse, msg))
+		}
+
+		// Lint the fields that could be unmarshaled.
+		cfg = Config{}
+		if err := yaml.Unmarshal(bytes, &cfg); err != nil {
+			return problems
+		}
+	}
+
+	cfg.applyRequestDefaults()
+
+	if err := cfg.expandVars(); err != nil {
+		problems = append(problems, err)
+	}
+
+	problems = append(problems, cfg.requiredProblems()...)
+	problems = append(problems, cfg.problems()...)
+
+	for _, dest := range cfg.DestinationList() {
+		if dest.ConnectionString == "" {
+			continue
+		}
+
+		if scheme := proto.SchemeFromConnectionString(dest.ConnectionString); !proto.KnownScheme(scheme) {
+			problems = append(problems, fmt.Errorf("%w: %q", ErrUnknownScheme, scheme))
+		}
+	}
+
+	for _, req := range cfg.Requests {
+		if req.Timeseries != nil {
+			problems = append(problems, req.timeseriesProblems()...)
+		}
+	}
+
+	return append(problems, cfg.duplicateRequests()...)
+}
+
+// requiredProblems will return the fields that a run requires and that are missing.
+func (cfg *Config) requiredProblems() []error {
+	var problems []error
+
+	if cfg.RawURL == "" {
+		problems = append(problems, MissingConfigFieldError("url"))
+	} else if _, err := url.Parse(cfg.RawURL); err != nil {
+		problems = append(problems, fmt.Errorf("%w: %v", UnableToParseError("url"), err))
+	}
+
+	if len(cfg.Requests) == 0 {
+		problems = append(problems, ErrNoRequests)
+	}
+
+	for idx, req := range cfg.Requests {
+		if req.Endpoint == "" {
+			problems = append(problems, MissingConfigFieldError(fmt.Sprintf("requests[%d].endpoint", idx)))
+		}
+	}
+
+	return problems
+}
+
+// timeseriesProblems will return the reasons that the timeseries of the request cannot be chunked.
+func (req *Request) timeseriesProblems() []error {
+	timeseries := req.Timeseries
+
+	if timeseries.StartName == "" || timeseries.EndName == "" {
+		return []error{fmt.Errorf("%w: startName and endName are required on %q", ErrInvalidTimeseries,
+			req.Endpoint)}
+	}
+
+	var problems []error
+
+	if timeseries.Period <= 0 {
+		problems = append(problems, fmt.Errorf("%w: period must be positive on %q", ErrInvalidTimeseries,
+			req.Endpoint))
+	}
+
+	layout := time.RFC3339
+	if timeseries.Layout != nil {
+		layout = *timeseries.Layout
+	}
+
+	query := req.query()
+
+	for _, name := range []string{timeseries.StartName, timeseries.EndName} {
+		if len(query[name]) != 1 {
+			problems = append(problems, fmt.Errorf("%w: one %q query parameter is required on %q",
+				ErrInvalidTimeseries, name, req.Endpoint))
+
+			continue
+		}
+
+		if _, err := time.Parse(layout, query[name][0]); err != nil {
+			problems = append(problems, fmt.Errorf("%w: %q does not match layout %q on %q", ErrInvalidTimeseries,
+				name, layout, req.Endpoint))
+		}
+	}
+
+	return problems
+}
+
+// query will return the query parameters of the endpoint of the request, with its query.
+func (req *Request) query() url.Values {
+	query := url.Values{}
+
+	if endpoint, err := url.Parse(req.Endpoint); err == nil {
+		query = endpoint.Query()
+	}
+
+	for key, value := range req.Query {
+		query.Set(key, value)
+	}
+
+	return query
+}
+
+// duplicateRequests will return the requests that fetch the same records as an earlier request and write them to the
+// same table.
+func (cfg *Config) duplicateRequests() []error {
+	var problems []error
+
+	seen := make(map[string]bool, len(cfg.Requests))
+
+	for _, req := range cfg.Requests {
+		method := req.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		table := req.Table
+		if table == "" {
+			table = defaultTable(req.Endpoint)
+		}
+
+		query := req.query()
+
+		key := fmt.Sprintf("%s %s?%s %s", method, strings.Split(req.Endpoint, "?")[0], query.Encode(), table)
+		if seen[key] {
+			problems = append(problems, fmt.Errorf("%w: %s %q is written to %q more than once", ErrDuplicateRequest,
+				method, req.Endpoint, table))
+		}
+
+		seen[key] = true
+	}
+
+	return problems
+}
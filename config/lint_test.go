@@ -0,0 +1,104 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name string
+		yaml string
+		want []error
+	}{
+		{
+			name: "valid",
+			yaml: `
+url: https://api.pro.coinbase.com
+connectionStrings:
+  - stdout://
+rateLimit:
+  burst: 5
+  period: 1s
+requests:
+  - endpoint: /products/BTC-USD/candles
+    query:
+      start: "2022-05-10T00:00:00Z"
+      end: "2022-05-11T00:00:00Z"
+    timeseries:
+      startName: start
+      endName: end
+      period: 3600
+  - endpoint: /products/ETH-USD/candles
+`,
+		},
+		{
+			name: "every problem",
+			yaml: `
+urll: https://api.pro.coinbase.com
+connectionStrings:
+  - stdout://
+  - mysql://localhost:3306/db
+batchSize: -1
+transaction: destination
+requests:
+  - endpoint: /products/BTC-USD/candles?start=2022-05-10
+    timeseries:
+      startName: start
+      endName: end
+  - endpoint: /accounts
+  - endpoint: /accounts
+  - table: orders
+`,
+			want: []error{
+				ErrUnableToParse,
+				ErrMissingConfigField,
+				ErrMissingConfigField,
+				ErrMissingConfigField,
+				ErrInvalidBatchSize,
+				ErrInvalidTransaction,
+				ErrUnknownScheme,
+				ErrInvalidTimeseries,
+				ErrInvalidTimeseries,
+				ErrInvalidTimeseries,
+				ErrDuplicateRequest,
+			},
+		},
+		{
+			name: "no requests",
+			yaml: `
+url: https://api.pro.coinbase.com
+rateLimit:
+  burst: 5
+  period: 1s
+`,
+			want: []error{ErrNoRequests},
+		},
+	} {
+		problems := Lint([]byte(tcase.yaml))
+		if len(problems) != len(tcase.want) {
+			t.Errorf("%s: expected %d problems, got %v", tcase.name, len(tcase.want), problems)
+
+			continue
+		}
+
+		for idx, want := range tcase.want {
+			if !errors.Is(problems[idx], want) {
+				t.Errorf("%s: expected problem %d to be %v, got %v", tcase.name, idx, want, problems[idx])
+			}
+		}
+	}
+
+	if problems := Lint([]byte("url: [")); len(problems) != 1 {
+		t.Errorf("expected one problem for invalid YAML, got %v", problems)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import "fmt"
+
+const (
+	// MaskHash replaces the value of a field with the hex-encoded SHA-256 hash of it, or its HMAC-SHA256 if the mask
+	// has a salt, so that the masked values can still be joined and counted.
+	MaskHash = "hash"
+
+	// MaskTruncate keeps the first characters of the value of a field, or the last ones if the length is negative.
+	MaskTruncate = "truncate"
+
+	// MaskNull replaces the value of a field with null.
+	MaskNull = "null"
+)
+
+// Mask is a transform that hashes, truncates, or nulls out the value of a field of each record of a request, e.g. an
+// email or an account number, before the record is stored.
+type Mask struct {
+	// Field is the field that is masked. The fields of nested objects are separated by dots, e.g. "owner.email".
+	Field string `yaml:"field"`
+
+	// Action is how the value of the field is masked: "hash", "truncate", or "null".
+	Action string `yaml:"action"`
+
+	// Length is the number of characters that "truncate" keeps from the start of the value, or from its end if it is
+	// negative, e.g. -4 for the last four digits of an account number.
+	Length int `yaml:"length"`
+
+	// Salt is the secret key of the HMAC that "hash" uses, e.g. "${GIDARI_MASK_SALT}", so that the hashes of values
+	// that are easy to guess cannot be reversed by hashing every candidate.
+	Salt string `yaml:"salt"`
+}
+
+// validateMask will check that each masked field of the request is named and has a valid action, and that only the
+// action that uses them has a length or a salt.
+func (req *Request) validateMask() error {
+	for _, mask := range req.Mask {
+		if mask == nil {
+			return fmt.Errorf("%w: empty mask on %q", ErrInvalidMask, req.Endpoint)
+		}
+
+		for _, field := range FieldPath(mask.Field) {
+			if field == "" {
+				return fmt.Errorf("%w: empty field in %q on %q", ErrInvalidMask, mask.Field, req.Endpoint)
+			}
+		}
+
+		switch mask.Action {
+		case MaskHash, MaskTruncate, MaskNull:
+		default:
+			return fmt.Errorf("%w: invalid action %q for %q on %q", ErrInvalidMask, mask.Action, mask.Field,
+				req.Endpoint)
+		}
+
+		if (mask.Action == MaskTruncate) != (mask.Length != 0) {
+			return fmt.Errorf("%w: length is required with, and only supported by, %q for %q on %q", ErrInvalidMask,
+				MaskTruncate, mask.Field, req.Endpoint)
+		}
+
+		if mask.Salt != "" && mask.Action != MaskHash {
+			return fmt.Errorf("%w: salt is only supported by %q for %q on %q", ErrInvalidMask, MaskHash, mask.Field,
+				req.Endpoint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateMask(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name    string
+		mask    *Mask
+		wantErr error
+	}{
+		{name: "hash", mask: &Mask{Field: "email", Action: MaskHash}},
+		{name: "salted hash", mask: &Mask{Field: "owner.email", Action: MaskHash, Salt: "secret"}},
+		{name: "truncate", mask: &Mask{Field: "account", Action: MaskTruncate, Length: -4}},
+		{name: "null", mask: &Mask{Field: "ssn", Action: MaskNull}},
+		{name: "nil", wantErr: ErrInvalidMask},
+		{name: "no field", mask: &Mask{Action: MaskNull}, wantErr: ErrInvalidMask},
+		{name: "empty nested field", mask: &Mask{Field: "owner.", Action: MaskNull}, wantErr: ErrInvalidMask},
+		{name: "no action", mask: &Mask{Field: "email"}, wantErr: ErrInvalidMask},
+		{name: "invalid action", mask: &Mask{Field: "email", Action: "encrypt"}, wantErr: ErrInvalidMask},
+		{name: "no length", mask: &Mask{Field: "account", Action: MaskTruncate}, wantErr: ErrInvalidMask},
+		{name: "length", mask: &Mask{Field: "email", Action: MaskHash, Length: 4}, wantErr: ErrInvalidMask},
+		{name: "salt", mask: &Mask{Field: "ssn", Action: MaskNull, Salt: "secret"}, wantErr: ErrInvalidMask},
+	} {
+		req := &Request{Endpoint: "/accounts", Mask: []*Mask{tcase.mask}}
+		if err := req.validateMask(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteUnits are the units of a size, by their suffix. Decimal and binary units are both accepted.
+var byteUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"KB", 1e3},
+	{"MB", 1e6},
+	{"GB", 1e9},
+	{"B", 1},
+}
+
+// MemoryLimitBytes will return the number of bytes that the payloads queued for the repository workers may hold in
+// memory, or zero if they are not limited. The limit is a number of bytes with an optional unit, e.g. "512MB" or
+// "1GiB".
+func (cfg *Config) MemoryLimitBytes() (int64, error) {
+	if cfg.MemoryLimit == "" {
+		return 0, nil
+	}
+
+	text := strings.TrimSpace(cfg.MemoryLimit)
+	multiplier := int64(1)
+
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.bytes
+
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMemoryLimit, cfg.MemoryLimit)
+	}
+
+	return size * multiplier, nil
+}
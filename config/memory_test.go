@@ -0,0 +1,44 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryLimitBytes(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		limit    string
+		expected int64
+		wantErr  error
+	}{
+		{limit: "", expected: 0},
+		{limit: "1024", expected: 1024},
+		{limit: "512MB", expected: 512e6},
+		{limit: "1 GiB", expected: 1 << 30},
+		{limit: "64KiB", expected: 64 << 10},
+		{limit: "0MB", wantErr: ErrInvalidMemoryLimit},
+		{limit: "-1", wantErr: ErrInvalidMemoryLimit},
+		{limit: "lots", wantErr: ErrInvalidMemoryLimit},
+		{limit: "1.5GB", wantErr: ErrInvalidMemoryLimit},
+	} {
+		cfg := &Config{MemoryLimit: tcase.limit}
+
+		limit, err := cfg.MemoryLimitBytes()
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.limit, tcase.wantErr, err)
+		}
+
+		if limit != tcase.expected {
+			t.Errorf("%q: expected %d bytes, got %d", tcase.limit, tcase.expected, limit)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Policies for a request that fails, see "Request.OnError". A request that fails without a policy is rolled back, and
+// the run continues with the requests after it, but fails once they have been written.
+const (
+	// OnErrorFail will abort the run once the request fails, so that the requests after it are not written.
+	OnErrorFail = "fail"
+
+	// OnErrorSkip will roll back the request once it fails, and continue the run without failing it.
+	OnErrorSkip = "skip"
+
+	// OnErrorRetry is the prefix of the policy that fetches and writes the request again once it fails, e.g.
+	// "retry-3" retries the request up to 3 times before it fails the run.
+	OnErrorRetry = "retry-"
+)
+
+// OnErrorRetries will return the number of times that the request is retried once it fails, which is "N" if its policy
+// is "retry-N", or 0 otherwise.
+func (req *Request) OnErrorRetries() int {
+	if !strings.HasPrefix(req.OnError, OnErrorRetry) {
+		return 0
+	}
+
+	retries, err := strconv.Atoi(strings.TrimPrefix(req.OnError, OnErrorRetry))
+	if err != nil || retries < 1 {
+		return 0
+	}
+
+	return retries
+}
+
+// validateOnError will return an error if the policy of the request is not "fail", "skip", or "retry-N", or if it
+// cannot be applied in the transaction scope. In "run" mode every request is rolled back with the run, so requests
+// can only fail it.
+func (req *Request) validateOnError(transaction string) error {
+	switch {
+	case req.OnError == "", req.OnError == OnErrorFail:
+		return nil
+	case req.OnError != OnErrorSkip && req.OnErrorRetries() == 0:
+		return fmt.Errorf("%w: %q on %q, expected %q, %q, or %q", ErrInvalidOnError, req.OnError, req.Endpoint,
+			OnErrorFail, OnErrorSkip, OnErrorRetry+"N")
+	case transaction == TransactionRun:
+		return fmt.Errorf("%w: %q is not supported in %q transactions on %q", ErrInvalidOnError, req.OnError,
+			TransactionRun, req.Endpoint)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOnError(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		onError     string
+		transaction string
+		retries     int
+		wantErr     error
+	}{
+		{onError: ""},
+		{onError: OnErrorFail},
+		{onError: OnErrorFail, transaction: TransactionRun},
+		{onError: OnErrorSkip},
+		{onError: "retry-3", retries: 3},
+		{onError: "retry-0", wantErr: ErrInvalidOnError},
+		{onError: "retry-x", wantErr: ErrInvalidOnError},
+		{onError: "retry", wantErr: ErrInvalidOnError},
+		{onError: "abort", wantErr: ErrInvalidOnError},
+		{onError: OnErrorSkip, transaction: TransactionRun, wantErr: ErrInvalidOnError},
+		{onError: "retry-3", transaction: TransactionRun, retries: 3, wantErr: ErrInvalidOnError},
+	} {
+		req := &Request{Endpoint: "/candles", OnError: tcase.onError}
+
+		if err := req.validateOnError(tcase.transaction); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q in %q: expected %v, got %v", tcase.onError, tcase.transaction, tcase.wantErr, err)
+		}
+
+		if retries := req.OnErrorRetries(); retries != tcase.retries {
+			t.Errorf("%q: expected %d retries, got %d", tcase.onError, tcase.retries, retries)
+		}
+	}
+}
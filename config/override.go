@@ -0,0 +1,209 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Overrides replace fields of a configuration at runtime, e.g. to point the same configuration at a staging
+// database. Fields that are not set are not overridden.
+type Overrides struct {
+	// URL replaces the base URL of the web API.
+	URL string
+
+	// ConnectionStrings replace every storage device of the configuration, including its destinations.
+	ConnectionStrings []string
+
+	// Truncate replaces whether every table is emptied before the records of the run are written.
+	Truncate *bool
+
+	// RateLimit replaces the rate limit, as the burst and period separated by a slash, e.g. "5/1s".
+	RateLimit string
+
+	// LogFormat replaces the format of the logs of the run.
+	LogFormat string
+
+	// LogSample replaces the number of chunks and batches that are logged together, if it is not zero.
+	LogSample int
+
+	// MaxDuration replaces how long the run may take, as a Go duration.
+	MaxDuration string
+
+	// Start and End replace the start and end of every timeseries request, as RFC3339 times or dates, e.g.
+	// "2022-11-01", to fetch a specific window again without editing the configuration.
+	Start string
+	End   string
+
+	// Vars replace the variables of the configuration with the same names, and add the others.
+	Vars map[string]string
+}
+
+// Apply will override the fields of the YAML configuration.
+func (overrides *Overrides) Apply(bytes []byte) ([]byte, error) {
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML: %w", err)
+	}
+
+	if doc == nil {
+		doc = map[interface{}]interface{}{}
+	}
+
+	if overrides.URL != "" {
+		doc["url"] = overrides.URL
+	}
+
+	if len(overrides.ConnectionStrings) > 0 {
+		doc["connectionStrings"] = overrides.ConnectionStrings
+		delete(doc, "destinations")
+	}
+
+	if overrides.Truncate != nil {
+		doc["truncate"] = *overrides.Truncate
+	}
+
+	if overrides.LogFormat != "" {
+		doc["logFormat"] = overrides.LogFormat
+	}
+
+	if overrides.LogSample != 0 {
+		doc["logSample"] = overrides.LogSample
+	}
+
+	if overrides.MaxDuration != "" {
+		doc["maxDuration"] = overrides.MaxDuration
+	}
+
+	if overrides.RateLimit != "" {
+		burst, period, err := ParseRateLimit(overrides.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		doc["rateLimit"] = map[string]interface{}{"burst": burst, "period": period.String()}
+	}
+
+	if len(overrides.Vars) > 0 {
+		vars, _ := doc["vars"].(map[interface{}]interface{})
+		if vars == nil {
+			vars = map[interface{}]interface{}{}
+			doc["vars"] = vars
+		}
+
+		for name, value := range overrides.Vars {
+			vars[name] = value
+		}
+	}
+
+	if err := overrides.applyTimeRange(doc); err != nil {
+		return nil, err
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal YAML: %w", err)
+	}
+
+	return out, nil
+}
+
+// applyTimeRange will replace the values of the start and end query parameters of every timeseries request of the
+// YAML document, formatted with the layout of the timeseries.
+func (overrides *Overrides) applyTimeRange(doc map[interface{}]interface{}) error {
+	if overrides.Start == "" && overrides.End == "" {
+		return nil
+	}
+
+	start, err := parseTimeOverride("start", overrides.Start)
+	if err != nil {
+		return err
+	}
+
+	end, err := parseTimeOverride("end", overrides.End)
+	if err != nil {
+		return err
+	}
+
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return fmt.Errorf("%w: start %s is not before end %s", ErrInvalidTimeRange, overrides.Start, overrides.End)
+	}
+
+	reqs, _ := doc["requests"].([]interface{})
+	for _, req := range reqs {
+		reqMap, _ := req.(map[interface{}]interface{})
+
+		timeseries, _ := reqMap["timeseries"].(map[interface{}]interface{})
+		if timeseries == nil {
+			continue
+		}
+
+		layout := time.RFC3339
+		if custom, ok := timeseries["layout"].(string); ok && custom != "" {
+			layout = custom
+		}
+
+		query, _ := reqMap["query"].(map[interface{}]interface{})
+		if query == nil {
+			query = map[interface{}]interface{}{}
+			reqMap["query"] = query
+		}
+
+		if name, _ := timeseries["startName"].(string); name != "" && !start.IsZero() {
+			query[name] = start.Format(layout)
+		}
+
+		if name, _ := timeseries["endName"].(string); name != "" && !end.IsZero() {
+			query[name] = end.Format(layout)
+		}
+	}
+
+	return nil
+}
+
+// parseTimeOverride will parse the value of a time override, which is an RFC3339 time or a date in UTC. An empty
+// value is the zero time, which does not override anything.
+func parseTimeOverride(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s %q is not an RFC3339 time or a date", ErrInvalidTimeRange, name, value)
+}
+
+// ParseRateLimit will parse a rate limit of the burst and period separated by a slash, e.g. "5/1s" for five requests
+// per second.
+func ParseRateLimit(rateLimit string) (int, time.Duration, error) {
+	rawBurst, rawPeriod, ok := strings.Cut(rateLimit, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %q is not burst/period", ErrInvalidRateLimit, rateLimit)
+	}
+
+	burst, err := strconv.Atoi(rawBurst)
+	if err != nil || burst <= 0 {
+		return 0, 0, fmt.Errorf("%w: burst %q", ErrInvalidRateLimit, rawBurst)
+	}
+
+	period, err := time.ParseDuration(rawPeriod)
+	if err != nil || period <= 0 {
+		return 0, 0, fmt.Errorf("%w: period %q", ErrInvalidRateLimit, rawPeriod)
+	}
+
+	return burst, period, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SelectProfile will merge the profile with the name over the rest of the YAML configuration, so that one file can
+// hold the URLs, connection strings, and rate limits of every environment. Profiles are merged like includes: maps are
+// merged key by key, and any other value, including a list, replaces the value of the configuration. The profiles are
+// removed from the configuration, and the configuration is returned unchanged if the name is empty.
+func SelectProfile(bytes []byte, name string) ([]byte, error) {
+	if name == "" {
+		return bytes, nil
+	}
+
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML: %w", err)
+	}
+
+	profiles, _ := doc["profiles"].(map[interface{}]interface{})
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not one of %s", ErrInvalidProfile, name, profileNames(profiles))
+	}
+
+	delete(doc, "profiles")
+
+	if profile != nil {
+		profileMap, ok := profile.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %q must be a map", ErrInvalidProfile, name)
+		}
+
+		mergeYAML(doc, profileMap)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal YAML: %w", err)
+	}
+
+	return out, nil
+}
+
+// profileNames will return the sorted names of the profiles, for error messages.
+func profileNames(profiles map[interface{}]interface{}) string {
+	if len(profiles) == 0 {
+		return "no profiles"
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// ProfileNames will return the sorted names of the profiles of the YAML configuration, e.g. to complete them in a
+// shell.
+func ProfileNames(bytes []byte) ([]string, error) {
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML: %w", err)
+	}
+
+	profiles, _ := doc["profiles"].(map[interface{}]interface{})
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, fmt.Sprint(name))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import "time"
+
+// Outcomes of the writes of a request.
+const (
+	// RequestCommitted means that the writes of the request were committed on every destination.
+	RequestCommitted = "committed"
+
+	// RequestRolledBack means that the writes of the request were rolled back.
+	RequestRolledBack = "rolledBack"
+)
+
+// RequestProgress is the outcome of the writes of a request in a run, with the totals of the upserts on every
+// destination that the table of the request is routed to.
+type RequestProgress struct {
+	Endpoint string `json:"endpoint"`
+	Table    string `json:"table"`
+
+	// Status is "committed" or "rolledBack".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	Received int64 `json:"received"`
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Failed   int64 `json:"failed"`
+
+	// Errors are the number of errors of the request in each category: "http" and the status of responses that the
+	// web API rejected, e.g. "http 429", "fetch", "decode", "storage", or "other". Errors that did not fail the
+	// request, such as responses that were discarded since they could not be decoded, are counted too.
+	Errors map[string]int `json:"errors,omitempty"`
+
+	// Stages are how long the chunks of the request spent in each stage of the run.
+	Stages StageTimings `json:"stages"`
+}
+
+// StageTimings are how long the chunks of a request spent in each stage of a run, summed over the chunks, so that the
+// stage that bounds a run can be found. Since chunks are fetched and written concurrently, the sum of the stages can
+// be longer than the run.
+type StageTimings struct {
+	// Fetch is how long the responses took to be received, without waiting for the rate limiter.
+	Fetch time.Duration `json:"fetch"`
+
+	// Decode is how long the bodies took to be validated, and checked against the fields of their table.
+	Decode time.Duration `json:"decode"`
+
+	// Transform is how long the records took to be transformed for their table.
+	Transform time.Duration `json:"transform"`
+
+	// Write is how long the batches of records took to be upserted on every destination.
+	Write time.Duration `json:"write"`
+}
+
+// FetchProgress is the progress of fetching the chunks of a request from the web API in a run. A request that is not a
+// timeseries has one chunk. It is reported for every request with no fetched chunks when the run starts, and then
+// once each chunk has been fetched, or has failed to be fetched.
+type FetchProgress struct {
+	Endpoint string
+	Table    string
+
+	Fetched int
+	Chunks  int
+
+	// RateLimitWait is how long the last chunk waited for the rate limiter before it was fetched.
+	RateLimitWait time.Duration
+
+	// Bytes is the size of the body of the last chunk, which is zero if it failed to be fetched.
+	Bytes int64
+
+	// Latency is how long the last chunk took to be fetched, without waiting for the rate limiter, which is zero if
+	// it failed to be fetched.
+	Latency time.Duration
+}
+
+// UpsertProgress is a batch of the records of a request that has been upserted on a destination. The records are not
+// committed until the request is, see "RequestProgress".
+type UpsertProgress struct {
+	Endpoint string
+	Table    string
+
+	// Upserted is the number of records of the batch that were upserted or matched.
+	Upserted int64
+}
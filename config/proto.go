@@ -0,0 +1,127 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+// FromProto takes the protobuf definition of a transport configuration and returns a new transport configuration,
+// with the same defaults and validation as "Parse".
+func FromProto(_ context.Context, msg *proto.TransportConfig) (*Config, error) {
+	cfg := Config{
+		RawURL:            msg.GetUrl(),
+		Authentication:    authenticationFromProto(msg.GetAuthentication()),
+		ConnectionStrings: msg.GetConnectionStrings(),
+		RateLimitConfig:   rateLimitFromProto(msg.GetRateLimit()),
+		BatchSize:         int(msg.GetBatchSize()),
+		Transaction:       msg.GetTransaction(),
+		Metadata:          msg.GetMetadata(),
+		Verify:            msg.GetVerify(),
+		TablePrefix:       msg.GetTablePrefix(),
+		TableSuffix:       msg.GetTableSuffix(),
+		Naming:            msg.GetNaming(),
+		Logger:            tools.NewLogrusLogger(logrus.New()),
+	}
+
+	for _, dest := range msg.GetDestinations() {
+		cfg.Destinations = append(cfg.Destinations, &Destination{
+			ConnectionString: dest.GetConnectionString(),
+			Include:          dest.GetInclude(),
+			Exclude:          dest.GetExclude(),
+			BatchSize:        int(dest.GetBatchSize()),
+		})
+	}
+
+	for _, req := range msg.GetRequests() {
+		cfg.Requests = append(cfg.Requests, requestFromProto(req))
+	}
+
+	if err := cfg.Prepare(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func authenticationFromProto(msg *proto.TransportConfig_Authentication) Authentication {
+	var auth Authentication
+
+	if apiKey := msg.GetApiKey(); apiKey != nil {
+		auth.APIKey = &APIKey{
+			Passphrase: apiKey.GetPassphrase(),
+			Key:        apiKey.GetKey(),
+			Secret:     apiKey.GetSecret(),
+		}
+	}
+
+	if auth2 := msg.GetAuth2(); auth2 != nil {
+		auth.Auth2 = &Auth2{Bearer: auth2.GetBearer()}
+	}
+
+	return auth
+}
+
+// rateLimitFromProto will return the rate limit of the message, leaving the fields that are not set as nil so that
+// they fail validation.
+func rateLimitFromProto(msg *proto.TransportConfig_RateLimit) *RateLimitConfig {
+	if msg == nil {
+		return nil
+	}
+
+	rateLimit := new(RateLimitConfig)
+
+	if msg.GetBurst() != 0 {
+		burst := int(msg.GetBurst())
+		rateLimit.Burst = &burst
+	}
+
+	if msg.GetPeriod() != nil {
+		period := msg.GetPeriod().AsDuration()
+		rateLimit.Period = &period
+	}
+
+	return rateLimit
+}
+
+func requestFromProto(msg *proto.TransportConfig_Request) *Request {
+	req := &Request{
+		Method:     msg.GetMethod(),
+		Endpoint:   msg.GetEndpoint(),
+		Query:      msg.GetQuery(),
+		Table:      msg.GetTable(),
+		PrimaryKey: msg.GetPrimaryKey(),
+		Conflict:   msg.GetConflict(),
+		WriteMode:  msg.GetWriteMode(),
+		ClobColumn: msg.GetClobColumn(),
+		SoftDelete: msg.GetSoftDelete(),
+	}
+
+	if msg.GetTruncate() {
+		truncate := true
+		req.Truncate = &truncate
+	}
+
+	if timeseries := msg.GetTimeseries(); timeseries != nil {
+		req.Timeseries = &Timeseries{
+			StartName: timeseries.GetStartName(),
+			EndName:   timeseries.GetEndName(),
+			Period:    timeseries.GetPeriod(),
+		}
+
+		if layout := timeseries.GetLayout(); layout != "" {
+			req.Timeseries.Layout = &layout
+		}
+	}
+
+	return req
+}
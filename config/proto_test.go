@@ -0,0 +1,118 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestFromProto(t *testing.T) {
+	t.Parallel()
+
+	rateLimit := &proto.TransportConfig_RateLimit{Burst: 2, Period: durationpb.New(time.Second)}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := FromProto(context.Background(), &proto.TransportConfig{
+			Url: "https://api.example.com",
+			Authentication: &proto.TransportConfig_Authentication{
+				Auth2: &proto.TransportConfig_Auth2{Bearer: "token"},
+			},
+			Destinations: []*proto.TransportConfig_Destination{
+				{ConnectionString: "stdout://", Include: []string{"candles"}},
+			},
+			RateLimit: rateLimit,
+			Requests: []*proto.TransportConfig_Request{
+				{
+					Endpoint: "/products/BTC-USD/candles",
+					Query:    map[string]string{"granularity": "60"},
+					Timeseries: &proto.TransportConfig_Timeseries{
+						StartName: "start",
+						EndName:   "end",
+						Period:    18000,
+					},
+					Truncate:   true,
+					PrimaryKey: []string{"time"},
+				},
+			},
+			Transaction: TransactionRun,
+		})
+		if err != nil {
+			t.Fatalf("failed to convert: %v", err)
+		}
+
+		if cfg.URL.Host != "api.example.com" || cfg.Authentication.Auth2.Bearer != "token" ||
+			cfg.Authentication.APIKey != nil || cfg.Transaction != TransactionRun || cfg.Logger == nil {
+			t.Errorf("unexpected configuration %+v", cfg)
+		}
+
+		if *cfg.RateLimitConfig.Burst != 2 || *cfg.RateLimitConfig.Period != time.Second {
+			t.Errorf("unexpected rate limit %+v", cfg.RateLimitConfig)
+		}
+
+		if dest := cfg.Destinations[0]; dest.ConnectionString != "stdout://" || !dest.Routes("candles") ||
+			dest.Routes("trades") {
+			t.Errorf("unexpected destination %+v", dest)
+		}
+
+		req := cfg.Requests[0]
+		if req.Method != http.MethodGet || req.Table != "candles" || req.RateLimiter == nil {
+			t.Errorf("expected request defaults, got %+v", req)
+		}
+
+		if req.Query["granularity"] != "60" || req.Truncate == nil || !*req.Truncate ||
+			req.Timeseries.Period != 18000 || req.Timeseries.Layout != nil {
+			t.Errorf("unexpected request %+v", req)
+		}
+	})
+
+	for _, tcase := range []struct {
+		name string
+		msg  *proto.TransportConfig
+		err  error
+	}{
+		{
+			name: "missing rate limit",
+			msg:  &proto.TransportConfig{Url: "https://api.example.com"},
+			err:  ErrMissingConfigField,
+		},
+		{
+			name: "missing period",
+			msg: &proto.TransportConfig{
+				RateLimit: &proto.TransportConfig_RateLimit{Burst: 1},
+			},
+			err: ErrInvalidRateLimit,
+		},
+		{
+			name: "invalid conflict",
+			msg: &proto.TransportConfig{
+				RateLimit: rateLimit,
+				Requests:  []*proto.TransportConfig_Request{{Endpoint: "/candles", Conflict: "x"}},
+			},
+			err: ErrInvalidConflict,
+		},
+	} {
+		tcase := tcase
+
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := FromProto(context.Background(), tcase.msg); !errors.Is(err, tcase.err) {
+				t.Errorf("expected %v, got %v", tcase.err, err)
+			}
+		})
+	}
+}
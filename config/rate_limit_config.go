@@ -7,7 +7,11 @@
 //	http://www.apache.org/licenses/LICENSE-2.0
 package config
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
 
 // RateLimitConfig is the data needed for constructing a rate limit for the HTTP requests.
 type RateLimitConfig struct {
@@ -16,6 +20,11 @@ type RateLimitConfig struct {
 
 	// Period is the number of times to allow a burst per second.
 	Period *time.Duration `yaml:"period"`
+
+	// Redis is the URL of a Redis server that the rate limit is shared through, e.g. "redis://:pass@host:6379/0",
+	// so that every process requesting the same web API stays under the rate limit together, e.g. replicas that
+	// share an API key. The rate limit is kept by each process on its own by default.
+	Redis string `yaml:"redis"`
 }
 
 func (rl RateLimitConfig) validate() error {
@@ -29,3 +38,18 @@ func (rl RateLimitConfig) validate() error {
 
 	return nil
 }
+
+// validateRedis will return an error if the Redis URL is set, but is not a "redis" or "rediss" URL with a host. The URL
+// is not in the error, since it can hold a password.
+func (rl RateLimitConfig) validateRedis() error {
+	if rl.Redis == "" {
+		return nil
+	}
+
+	uri, err := url.Parse(rl.Redis)
+	if err != nil || (uri.Scheme != "redis" && uri.Scheme != "rediss") || uri.Host == "" {
+		return fmt.Errorf("%w: must be a redis:// or rediss:// URL with a host", ErrInvalidRedis)
+	}
+
+	return nil
+}
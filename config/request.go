@@ -8,9 +8,95 @@
 package config
 
 import (
+	"fmt"
+	"text/template"
+
+	"github.com/alpstable/gidari/internal/predicate"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/selector"
 	"golang.org/x/time/rate"
 )
 
+// Write modes for storing the records of a request.
+const (
+	// WriteModeUpsert will insert new records and resolve conflicts with existing records. This is the default.
+	WriteModeUpsert = "upsert"
+
+	// WriteModeAppend will always insert records, adding a surrogate key and the time the records were fetched.
+	WriteModeAppend = "append"
+)
+
+// DefaultHashKeyColumn is the column of the hash key of a record if no column is configured.
+const DefaultHashKeyColumn = "gidari_hash"
+
+// HashKey is a stable hash of each record that is used as its primary key, so that a record that is delivered more
+// than once, e.g. by overlapping chunks of a timeseries, is only stored once.
+type HashKey struct {
+	// Column is the column that the hash is stored in. The default column is "gidari_hash".
+	Column string `yaml:"column"`
+
+	// Fields are the fields of the record that are hashed. If it is empty, every field of the record is hashed.
+	Fields []string `yaml:"fields"`
+}
+
+// ColumnName will return the column that the hash is stored in.
+func (key *HashKey) ColumnName() string {
+	if key.Column == "" {
+		return DefaultHashKeyColumn
+	}
+
+	return key.Column
+}
+
+func (key *HashKey) validate(req *Request) error {
+	if len(req.PrimaryKey) > 0 || req.WriteMode == WriteModeAppend {
+		return fmt.Errorf("%w: hashKey is not supported with a primaryKey or in %q mode on %q", ErrInvalidHashKey,
+			WriteModeAppend, req.Endpoint)
+	}
+
+	for _, field := range key.Fields {
+		if field == "" || field == key.ColumnName() {
+			return fmt.Errorf("%w: invalid field %q on %q", ErrInvalidHashKey, field, req.Endpoint)
+		}
+	}
+
+	return nil
+}
+
+// TruncateWhere limits the truncate of a request to the records in the time range of its timeseries, or to the
+// records that match the values of some columns, so that part of a table can be reloaded without emptying it.
+type TruncateWhere struct {
+	// TimeColumn is the column of the time of each record. If it is set, only the records from the start of the
+	// request's timeseries up to but excluding its end are deleted.
+	TimeColumn string `yaml:"timeColumn"`
+
+	// Match are the values, keyed by column, that records must have to be deleted. Each value is a Go template that
+	// is executed with the query parameters of the request, e.g. "{{ .product_id }}".
+	Match map[string]string `yaml:"match"`
+}
+
+func (where *TruncateWhere) validate(req *Request) error {
+	if where.TimeColumn == "" && len(where.Match) == 0 {
+		return fmt.Errorf("%w: timeColumn or match is required on %q", ErrInvalidTruncate, req.Endpoint)
+	}
+
+	if where.TimeColumn != "" && req.Timeseries == nil {
+		return fmt.Errorf("%w: timeColumn requires a timeseries on %q", ErrInvalidTruncate, req.Endpoint)
+	}
+
+	for column, value := range where.Match {
+		if column == "" {
+			return fmt.Errorf("%w: empty match column on %q", ErrInvalidTruncate, req.Endpoint)
+		}
+
+		if _, err := template.New(column).Option("missingkey=error").Parse(value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTruncate, err)
+		}
+	}
+
+	return nil
+}
+
 // Request is the information needed to query the web API for data to transport.
 type Request struct {
 	// Method is the HTTP(s) method used to construct the http request to fetch data for storage.
@@ -32,9 +118,158 @@ type Request struct {
 	// Truncate before upserting on single request
 	Truncate *bool `yaml:"truncate"`
 
+	// TruncateWhere limits the truncate to part of the table. It requires truncate to be true.
+	TruncateWhere *TruncateWhere `yaml:"truncateWhere"`
+
 	ClobColumn string `yaml:"clobColumn"`
 
+	// Format is the format of the responses of the request: "json", "ndjson", "csv", "xml", "protobuf", "msgpack",
+	// or a format that a decoder is registered for. The default is "json".
+	Format string `yaml:"format"`
+
+	// CSV is how the rows of the responses of the request are decoded into records, if they are CSV.
+	CSV *CSV `yaml:"csv"`
+
+	// XML is how the elements of the responses of the request are decoded into records, if they are XML.
+	XML *XML `yaml:"xml"`
+
+	// Protobuf is the message that the responses of the request are decoded as, if they are protobuf.
+	Protobuf *Protobuf `yaml:"protobuf"`
+
+	// Selector selects the records of each response of the request that are wrapped in an envelope, with a subset
+	// of JSONPath, e.g. "$.data.items" or "$.pages[*].items". Each response is stored as it is by default.
+	Selector string `yaml:"selector"`
+
+	// IncludeFields are the only fields of the records of the request that are kept, e.g. ["id", "price"], and
+	// ExcludeFields the fields that are dropped, e.g. ["debug"], before the records are piped through the command,
+	// transformed, or stored. The fields of nested objects are separated by dots, e.g. "meta.request_id".
+	IncludeFields []string `yaml:"includeFields"`
+	ExcludeFields []string `yaml:"excludeFields"`
+
+	// Filter are the predicates that each record of the request must match to be stored, e.g. ["status == \"done\"",
+	// "volume > 0"]. Each predicate compares a field, or a field of a nested object separated by dots, with a quoted
+	// string, a number, "true", "false", or "null", with "==", "!=", ">", ">=", "<", or "<=".
+	Filter []string `yaml:"filter"`
+
+	// PrimaryKey are the fields that uniquely identify a record in the table, used to resolve conflicts when
+	// upserting. If it is empty, the primary keys of the table in storage are used.
+	PrimaryKey []string `yaml:"primaryKey"`
+
+	// Conflict is the strategy for resolving conflicts with existing records in the table: "insert-only",
+	// "replace", "merge-non-null", or "fail". The default strategy is "replace".
+	Conflict string `yaml:"conflict"`
+
+	// WriteMode is how the records are stored: "upsert" or "append". The default mode is "upsert". In "append"
+	// mode, records are never updated, so the primary key and conflict strategy cannot be set.
+	WriteMode string `yaml:"writeMode"`
+
+	// HashKey identifies each record by a hash of its fields, which is used as the primary key of the table. Records
+	// with the same hash are only inserted once, unless another conflict strategy is set. It cannot be used with a
+	// primary key.
+	HashKey *HashKey `yaml:"hashKey"`
+
+	// SoftDelete is the boolean column that flags records as deleted. If it is set, the request is treated as a full
+	// fetch of the table: records in storage that were not fetched are flagged as deleted, and records that were
+	// fetched are flagged as not deleted. It requires the primary key or the hash key to be set.
+	SoftDelete string `yaml:"softDelete"`
+
+	// OnError is what happens to the run when the request fails: "fail" aborts the run, "skip" continues it without
+	// failing it, and "retry-N" fetches and writes the request again up to N times, e.g. so that a flaky endpoint
+	// does not take down a long backfill. By default, the run continues and fails once every request is written.
+	OnError string `yaml:"onError"`
+
+	// Transforms change the table and the records of each chunk of the request depending on its query parameters.
+	Transforms *Transforms `yaml:"transforms"`
+
+	// Inject adds query parameters and segments of the path of each chunk of the request to its records.
+	Inject *Inject `yaml:"inject"`
+
+	// Command is an external command that the records of each response of the request are piped through, before
+	// its transforms. It only runs if the configuration allows commands, see "Config.AllowCommands".
+	Command *Command `yaml:"command"`
+
+	// Script is a Starlark script that modifies, drops, or splits each record of the request, after its transforms.
+	Script *Script `yaml:"script"`
+
+	// Mask hashes, truncates, or nulls out fields of each record of the request, after its script.
+	Mask []*Mask `yaml:"mask"`
+
 	// Chunks of requests should share a rate limiter, probably all of them; inheriting the rate limiter from the
 	// root configuration.
 	RateLimiter *rate.Limiter
 }
+
+func (req *Request) validate() error {
+	if !proto.ValidConflict(req.Conflict) {
+		return fmt.Errorf("%w: %q", ErrInvalidConflict, req.Conflict)
+	}
+
+	switch req.WriteMode {
+	case "", WriteModeUpsert:
+	case WriteModeAppend:
+		if len(req.PrimaryKey) > 0 || req.Conflict != "" {
+			return fmt.Errorf("%w: primaryKey and conflict are not supported in %q mode", ErrInvalidWriteMode,
+				WriteModeAppend)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidWriteMode, req.WriteMode)
+	}
+
+	if err := req.validateFormat(); err != nil {
+		return err
+	}
+
+	if err := req.validateScript(); err != nil {
+		return err
+	}
+
+	if err := req.validateCommand(); err != nil {
+		return err
+	}
+
+	if err := req.validateFields(); err != nil {
+		return err
+	}
+
+	if err := req.validateMask(); err != nil {
+		return err
+	}
+
+	if _, err := selector.Parse(req.Selector); err != nil {
+		return fmt.Errorf("%w on %q: %v", ErrInvalidSelector, req.Endpoint, err)
+	}
+
+	for _, expr := range req.Filter {
+		if _, err := predicate.Parse(expr); err != nil {
+			return fmt.Errorf("%w on %q: %v", ErrInvalidFilter, req.Endpoint, err)
+		}
+	}
+
+	for _, field := range req.PrimaryKey {
+		if field == "" {
+			return fmt.Errorf("%w: empty field on %q", ErrInvalidPrimaryKey, req.Endpoint)
+		}
+	}
+
+	if req.HashKey != nil {
+		if err := req.HashKey.validate(req); err != nil {
+			return err
+		}
+	}
+
+	if req.SoftDelete != "" && len(req.PrimaryKey) == 0 && req.HashKey == nil {
+		return fmt.Errorf("%w: primaryKey or hashKey is required on %q", ErrInvalidSoftDelete, req.Endpoint)
+	}
+
+	if req.TruncateWhere != nil {
+		if req.Truncate == nil || !*req.Truncate {
+			return fmt.Errorf("%w: truncateWhere requires truncate on %q", ErrInvalidTruncate, req.Endpoint)
+		}
+
+		if err := req.TruncateWhere.validate(req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+// RequestDefaults are the fields that every request of a configuration inherits unless it sets them itself, so that
+// they do not need to be repeated on each request.
+type RequestDefaults struct {
+	// Method is the HTTP method of requests without one. The default method is "GET".
+	Method string `yaml:"method"`
+
+	// Query are the query parameters that are added to every request, e.g. an API version. A request that sets the
+	// same parameter keeps its own value.
+	Query map[string]string `yaml:"query"`
+
+	// Truncate is whether requests without truncate empty their table before upserting.
+	Truncate *bool `yaml:"truncate"`
+
+	// OnError is the policy of requests without one for when they fail, see "Request.OnError".
+	OnError string `yaml:"onError"`
+}
+
+// apply will set the defaults on the fields that the request has not set.
+func (defaults *RequestDefaults) apply(req *Request) {
+	if req.Method == "" {
+		req.Method = defaults.Method
+	}
+
+	if len(defaults.Query) > 0 {
+		query := make(map[string]string, len(defaults.Query)+len(req.Query))
+		for key, value := range defaults.Query {
+			query[key] = value
+		}
+
+		for key, value := range req.Query {
+			query[key] = value
+		}
+
+		req.Query = query
+	}
+
+	if req.Truncate == nil && defaults.Truncate != nil {
+		truncate := *defaults.Truncate
+		req.Truncate = &truncate
+	}
+
+	if req.OnError == "" {
+		req.OnError = defaults.OnError
+	}
+}
+
+// applyRequestDefaults will set the request defaults of the configuration on every request.
+func (cfg *Config) applyRequestDefaults() {
+	if cfg.RequestDefaults == nil {
+		return
+	}
+
+	for _, req := range cfg.Requests {
+		if req != nil {
+			cfg.RequestDefaults.apply(req)
+		}
+	}
+}
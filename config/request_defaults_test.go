@@ -0,0 +1,71 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRequestDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse(context.Background(), []byte(`
+url: https://api.pro.coinbase.com
+connectionStrings: ["stdout://"]
+rateLimit:
+  burst: 5
+  period: 1s
+vars:
+  version: v2
+requests:
+  - endpoint: /accounts
+  - endpoint: /orders
+    method: POST
+    truncate: false
+    onError: fail
+    query:
+      version: v3
+      status: open
+requestDefaults:
+  method: PUT
+  truncate: true
+  onError: skip
+  query:
+    version: "{{ .version }}"
+`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	accounts, orders := cfg.Requests[0], cfg.Requests[1]
+
+	if accounts.Method != http.MethodPut || accounts.Truncate == nil || !*accounts.Truncate ||
+		accounts.OnError != OnErrorSkip || len(accounts.Query) != 1 || accounts.Query["version"] != "v2" {
+		t.Errorf("expected the defaults, got %+v", accounts)
+	}
+
+	if orders.Method != http.MethodPost || orders.Truncate == nil || *orders.Truncate ||
+		orders.OnError != OnErrorFail || len(orders.Query) != 2 || orders.Query["version"] != "v3" {
+		t.Errorf("expected the request to keep its own fields, got %+v", orders)
+	}
+
+	// Truncating with a where clause requires truncate, which the defaults can set.
+	if problems := Lint([]byte(`
+url: https://api.pro.coinbase.com
+connectionStrings: ["stdout://"]
+rateLimit: {burst: 5, period: 1s}
+requestDefaults: {truncate: true}
+requests:
+  - endpoint: /accounts
+    truncateWhere: {match: {currency: USD}}
+`)); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
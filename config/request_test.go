@@ -0,0 +1,157 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequestValidate(t *testing.T) {
+	t.Parallel()
+
+	req := Request{Endpoint: "/candles", PrimaryKey: []string{"product_id", "time"}}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.Conflict = "merge-non-null"
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.Conflict = "overwrite"
+	if err := req.validate(); !errors.Is(err, ErrInvalidConflict) {
+		t.Errorf("expected %v, got %v", ErrInvalidConflict, err)
+	}
+
+	req.Conflict = "insert-only"
+	req.WriteMode = WriteModeAppend
+	if err := req.validate(); !errors.Is(err, ErrInvalidWriteMode) {
+		t.Errorf("expected %v for a conflict in append mode, got %v", ErrInvalidWriteMode, err)
+	}
+
+	req = Request{Endpoint: "/candles", WriteMode: WriteModeAppend}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.WriteMode = "overwrite"
+	if err := req.validate(); !errors.Is(err, ErrInvalidWriteMode) {
+		t.Errorf("expected %v, got %v", ErrInvalidWriteMode, err)
+	}
+
+	req.WriteMode = ""
+	req.PrimaryKey = append(req.PrimaryKey, "")
+	if err := req.validate(); !errors.Is(err, ErrInvalidPrimaryKey) {
+		t.Errorf("expected %v, got %v", ErrInvalidPrimaryKey, err)
+	}
+
+	req = Request{Endpoint: "/orders", Selector: "$.data.items"}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.Selector = "data.items"
+	if err := req.validate(); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("expected %v, got %v", ErrInvalidSelector, err)
+	}
+
+	req = Request{Endpoint: "/orders", Filter: []string{`status == "done"`, "volume > 0"}}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.Filter = append(req.Filter, "status = done")
+	if err := req.validate(); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected %v, got %v", ErrInvalidFilter, err)
+	}
+
+	req = Request{Endpoint: "/orders", SoftDelete: "deleted"}
+	if err := req.validate(); !errors.Is(err, ErrInvalidSoftDelete) {
+		t.Errorf("expected %v, got %v", ErrInvalidSoftDelete, err)
+	}
+
+	req.PrimaryKey = []string{"id"}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	hashKey := &HashKey{Fields: []string{"trade_id", "price"}}
+
+	req = Request{Endpoint: "/trades", HashKey: hashKey}
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	req.SoftDelete = "deleted"
+	if err := req.validate(); err != nil {
+		t.Errorf("expected no error for a soft delete with a hash key, got %v", err)
+	}
+
+	req.PrimaryKey = []string{"trade_id"}
+	if err := req.validate(); !errors.Is(err, ErrInvalidHashKey) {
+		t.Errorf("expected %v for a hash key with a primary key, got %v", ErrInvalidHashKey, err)
+	}
+
+	req = Request{Endpoint: "/trades", HashKey: &HashKey{Fields: []string{DefaultHashKeyColumn}}}
+	if err := req.validate(); !errors.Is(err, ErrInvalidHashKey) {
+		t.Errorf("expected %v for hashing the hash column, got %v", ErrInvalidHashKey, err)
+	}
+
+	truncate := true
+
+	for _, tcase := range []struct {
+		name    string
+		req     Request
+		wantErr error
+	}{
+		{
+			name: "time range",
+			req: Request{
+				Truncate:      &truncate,
+				Timeseries:    &Timeseries{},
+				TruncateWhere: &TruncateWhere{TimeColumn: "time"},
+			},
+		},
+		{
+			name: "match",
+			req: Request{
+				Truncate:      &truncate,
+				TruncateWhere: &TruncateWhere{Match: map[string]string{"product_id": "{{ .product_id }}"}},
+			},
+		},
+		{
+			name:    "without truncate",
+			req:     Request{TruncateWhere: &TruncateWhere{Match: map[string]string{"product_id": "BTC-USD"}}},
+			wantErr: ErrInvalidTruncate,
+		},
+		{
+			name:    "empty",
+			req:     Request{Truncate: &truncate, TruncateWhere: &TruncateWhere{}},
+			wantErr: ErrInvalidTruncate,
+		},
+		{
+			name:    "time range without timeseries",
+			req:     Request{Truncate: &truncate, TruncateWhere: &TruncateWhere{TimeColumn: "time"}},
+			wantErr: ErrInvalidTruncate,
+		},
+		{
+			name: "invalid template",
+			req: Request{
+				Truncate:      &truncate,
+				TruncateWhere: &TruncateWhere{Match: map[string]string{"product_id": "{{ .product_id"}},
+			},
+			wantErr: ErrInvalidTruncate,
+		},
+	} {
+		if err := tcase.req.validate(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retry is how the storage transactions of a request, or of a run, are retried when they fail with a transient error,
+// such as a deadlock, a serialization failure, or a lost connection.
+type Retry struct {
+	// Retries is the number of times a transaction is retried.
+	Retries int `yaml:"retries"`
+
+	// Backoff is the wait before the first retry as a Go duration, e.g. "500ms", which doubles after each retry. The
+	// default backoff is one second.
+	Backoff string `yaml:"backoff"`
+}
+
+// defaultRetryBackoff is the wait before the first retry if no backoff is configured.
+const defaultRetryBackoff = time.Second
+
+// BackoffDuration will return the wait before the first retry.
+func (retry *Retry) BackoffDuration() (time.Duration, error) {
+	if retry.Backoff == "" {
+		return defaultRetryBackoff, nil
+	}
+
+	backoff, err := time.ParseDuration(retry.Backoff)
+	if err != nil || backoff < 0 {
+		return 0, fmt.Errorf("%w: backoff %q", ErrInvalidRetry, retry.Backoff)
+	}
+
+	return backoff, nil
+}
+
+func (retry *Retry) validate() error {
+	if retry.Retries < 0 {
+		return fmt.Errorf("%w: negative retries %d", ErrInvalidRetry, retry.Retries)
+	}
+
+	_, err := retry.BackoffDuration()
+
+	return err
+}
+
+// RetryBudget limits the retries of a run as a whole, across the transactions that are retried after a transient
+// error and the requests that are fetched again by their "retry-N" error policy, so that a web API or storage that
+// fails broadly does not multiply retries across every request. Retries are not limited by default.
+type RetryBudget struct {
+	// Retries is the number of retries of the run, or zero if they are not limited.
+	Retries int `yaml:"retries"`
+
+	// Time is how long the run may spend on retries, waiting for their backoff and making them again, as a Go
+	// duration, e.g. "10m". It is not limited if it is empty.
+	Time string `yaml:"time"`
+}
+
+// TimeDuration will return how long the run may spend on retries, or zero if it is not limited.
+func (budget *RetryBudget) TimeDuration() (time.Duration, error) {
+	if budget.Time == "" {
+		return 0, nil
+	}
+
+	limit, err := time.ParseDuration(budget.Time)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%w: time %q", ErrInvalidRetryBudget, budget.Time)
+	}
+
+	return limit, nil
+}
+
+func (budget *RetryBudget) validate() error {
+	if budget.Retries < 0 {
+		return fmt.Errorf("%w: negative retries %d", ErrInvalidRetryBudget, budget.Retries)
+	}
+
+	if budget.Retries == 0 && budget.Time == "" {
+		return fmt.Errorf("%w: retries or time is required", ErrInvalidRetryBudget)
+	}
+
+	_, err := budget.TimeDuration()
+
+	return err
+}
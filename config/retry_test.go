@@ -0,0 +1,73 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		retry    Retry
+		expected time.Duration
+		wantErr  error
+	}{
+		{name: "default backoff", retry: Retry{Retries: 3}, expected: time.Second},
+		{name: "backoff", retry: Retry{Retries: 3, Backoff: "250ms"}, expected: 250 * time.Millisecond},
+		{name: "negative retries", retry: Retry{Retries: -1}, wantErr: ErrInvalidRetry},
+		{name: "invalid backoff", retry: Retry{Backoff: "1"}, wantErr: ErrInvalidRetry},
+		{name: "negative backoff", retry: Retry{Backoff: "-1s"}, wantErr: ErrInvalidRetry},
+	} {
+		if err := tcase.retry.validate(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr != nil {
+			continue
+		}
+
+		if backoff, _ := tcase.retry.BackoffDuration(); backoff != tcase.expected {
+			t.Errorf("%s: expected a backoff of %v, got %v", tcase.name, tcase.expected, backoff)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		budget   RetryBudget
+		expected time.Duration
+		wantErr  error
+	}{
+		{name: "retries", budget: RetryBudget{Retries: 20}},
+		{name: "time", budget: RetryBudget{Time: "10m"}, expected: 10 * time.Minute},
+		{name: "empty", budget: RetryBudget{}, wantErr: ErrInvalidRetryBudget},
+		{name: "negative retries", budget: RetryBudget{Retries: -1}, wantErr: ErrInvalidRetryBudget},
+		{name: "invalid time", budget: RetryBudget{Time: "10"}, wantErr: ErrInvalidRetryBudget},
+		{name: "zero time", budget: RetryBudget{Time: "0s"}, wantErr: ErrInvalidRetryBudget},
+	} {
+		if err := tcase.budget.validate(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if tcase.wantErr != nil {
+			continue
+		}
+
+		if limit, _ := tcase.budget.TimeDuration(); limit != tcase.expected {
+			t.Errorf("%s: expected a time of %v, got %v", tcase.name, tcase.expected, limit)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"bytes"
+	_ "embed" // Embed the starter configuration.
+	"fmt"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// Authentication styles of a starter configuration.
+const (
+	AuthNone   = "none"
+	AuthAPIKey = "apiKey"
+	AuthAuth2  = "auth2"
+)
+
+//go:embed templates/init.yaml
+var starterTemplate string
+
+// Starter is what a starter configuration is generated from.
+type Starter struct {
+	// URL is the base URL of the web API.
+	URL string
+
+	// Auth is the authentication style of the web API: "none", "apiKey", or "auth2". The credentials are
+	// placeholders that must be replaced.
+	Auth string
+
+	// ConnectionString is the storage device that the records are written to.
+	ConnectionString string
+
+	// Endpoint and Table are the timeseries request, and the table that its records are written to.
+	Endpoint string
+	Table    string
+
+	// Start and End are the range of the timeseries request, in the RFC 3339 layout.
+	Start string
+	End   string
+}
+
+// NewStarter will return the options of a starter configuration that writes an hourly timeseries of the last day to
+// stdout, for a web API without authentication.
+func NewStarter(now time.Time) *Starter {
+	end := now.UTC().Truncate(time.Hour)
+
+	return &Starter{
+		URL:              "https://api.example.com",
+		Auth:             AuthNone,
+		ConnectionString: "stdout://",
+		Endpoint:         "/candles",
+		Table:            "candles",
+		Start:            end.Add(-24 * time.Hour).Format(time.RFC3339),
+		End:              end.Format(time.RFC3339),
+	}
+}
+
+// Scaffold will generate the YAML of a starter configuration, with comments that explain each field, so that new
+// users can start from a configuration that runs instead of from the reference.
+func Scaffold(starter *Starter) ([]byte, error) {
+	if starter.Auth != AuthNone && starter.Auth != AuthAPIKey && starter.Auth != AuthAuth2 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAuthentication, starter.Auth)
+	}
+
+	tmpl, err := template.New("init").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(starterTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse starter template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, starter); err != nil {
+		return nil, fmt.Errorf("unable to generate starter configuration: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,366 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// schemaFileKey is the key of the schema file of a table.
+const schemaFileKey = "schemaFile"
+
+// Types of the fields of a schema.
+const (
+	// SchemaString is a string. Numbers and booleans are converted to their text.
+	SchemaString = proto.ColumnTypeString
+
+	// SchemaInteger is a whole number. Strings of whole numbers are converted to numbers.
+	SchemaInteger = proto.ColumnTypeInteger
+
+	// SchemaNumber is a floating point number. Strings of numbers are converted to numbers.
+	SchemaNumber = proto.ColumnTypeNumber
+
+	// SchemaDecimal is an exact decimal number, which is stored as the text of the number so that no precision is
+	// lost, see "CoerceNumeric".
+	SchemaDecimal = proto.ColumnTypeDecimal
+
+	// SchemaBoolean is a boolean. Strings of booleans, and the numbers 0 and 1, are converted to booleans.
+	SchemaBoolean = proto.ColumnTypeBoolean
+
+	// SchemaTimestamp is a time, which is converted to an RFC 3339 timestamp from a string with the layout of the
+	// field, or from a number since the Unix epoch if the field has a unit.
+	SchemaTimestamp = proto.ColumnTypeTimestamp
+
+	// SchemaJSON is an object, a list, or any other value, which is stored as it is.
+	SchemaJSON = proto.ColumnTypeJSON
+)
+
+// Schema is the declared schema of a table, so that the records of the table are checked, coerced, keyed, and stored
+// in the same columns on every destination, instead of each destination inferring the types of the columns from the
+// records that it receives first.
+type Schema struct {
+	// Fields are the columns of the table.
+	Fields []*SchemaField `yaml:"fields"`
+}
+
+// SchemaField is a column of the schema of a table.
+type SchemaField struct {
+	// Name is the name of the column, after the fields of the records are mapped to the columns of the table.
+	Name string `yaml:"name"`
+
+	// Field is the field of the records in the responses of the web API that is stored in the column, before it is
+	// mapped. It defaults to the field that is mapped to the column by the "columns" of the table, or to the name.
+	Field string `yaml:"field"`
+
+	// Type is the type of the column: "string", "integer", "number", "decimal", "boolean", "timestamp", or "json".
+	Type string `yaml:"type"`
+
+	// Layout is the layout of "timestamp" values in the syntax of "time.Parse". The default layout is RFC 3339.
+	Layout string `yaml:"layout"`
+
+	// Unit is the unit of "timestamp" values that are numbers since the Unix epoch: "s", "ms", "us", or "ns".
+	Unit string `yaml:"unit"`
+
+	// Required is true if every record must have a value for the column. Keys are always required.
+	Required bool `yaml:"required"`
+
+	// Key is true if the column is one of the primary keys of the table.
+	Key bool `yaml:"key"`
+}
+
+// IsRequired will return true if every record must have a value for the column.
+func (field *SchemaField) IsRequired() bool {
+	return field.Required || field.Key
+}
+
+// coercion will return the coercion of the values of the column to its type, or nil if they are stored as they are.
+func (field *SchemaField) coercion() *Coercion {
+	switch field.Type {
+	case SchemaString:
+		return &Coercion{Type: CoerceString}
+	case SchemaInteger:
+		return &Coercion{Type: CoerceInteger}
+	case SchemaNumber:
+		return &Coercion{Type: CoerceDecimal}
+	case SchemaDecimal:
+		return &Coercion{Type: CoerceNumeric}
+	case SchemaBoolean:
+		return &Coercion{Type: CoerceBoolean}
+	case SchemaTimestamp:
+		if field.Unit != "" {
+			return &Coercion{Type: CoerceEpoch, Unit: field.Unit}
+		}
+
+		return &Coercion{Type: CoerceTimestamp, Layout: field.Layout}
+	default:
+		return nil
+	}
+}
+
+// Keys will return the columns of the schema that are primary keys, in order.
+func (schema *Schema) Keys() []string {
+	var keys []string
+
+	for _, field := range schema.Fields {
+		if field.Key {
+			keys = append(keys, field.Name)
+		}
+	}
+
+	return keys
+}
+
+// Definition will return the schema as the declared definition of the table in storage.
+func (schema *Schema) Definition(table string) *proto.DefineTableRequest {
+	req := &proto.DefineTableRequest{Table: table, PrimaryKeys: schema.Keys()}
+
+	for _, field := range schema.Fields {
+		req.Columns = append(req.Columns, &proto.ColumnDefinition{
+			Name:     field.Name,
+			Type:     field.Type,
+			Required: field.IsRequired(),
+		})
+	}
+
+	return req
+}
+
+// schemaField will return the field of the records in the responses of the web API that is stored in the column of
+// the schema.
+func (table *Table) schemaField(field *SchemaField) string {
+	if field.Field != "" {
+		return field.Field
+	}
+
+	for name, column := range table.Columns {
+		if column == field.Name {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// RequiredFields will return the fields that every record of the table must have in the responses of the web API. If
+// the table has a schema, they are the fields of its required columns, otherwise they are all of its fields.
+func (table *Table) RequiredFields() []string {
+	if table.Schema == nil {
+		return table.Fields
+	}
+
+	var fields []string
+
+	for _, field := range table.Schema.Fields {
+		if field.IsRequired() {
+			fields = append(fields, table.schemaField(field))
+		}
+	}
+
+	return fields
+}
+
+// RequiredColumns will return the columns that every record of the table must have a value for once it is mapped, which
+// are the required columns of its schema.
+func (table *Table) RequiredColumns() []string {
+	if table.Schema == nil {
+		return nil
+	}
+
+	var columns []string
+
+	for _, field := range table.Schema.Fields {
+		if field.IsRequired() {
+			columns = append(columns, field.Name)
+		}
+	}
+
+	return columns
+}
+
+// applySchema will declare the fields of the columns of the schema of the table, unless the table declares its own,
+// and add the coercions of the columns to the types of the schema, unless the table has its own coercion for them.
+func (table *Table) applySchema() {
+	coerce := make(map[string]*Coercion, len(table.Coerce)+len(table.Schema.Fields))
+	for column, coercion := range table.Coerce {
+		coerce[column] = coercion
+	}
+
+	declare := len(table.Fields) == 0
+	fields := table.Fields
+
+	for _, field := range table.Schema.Fields {
+		if _, ok := coerce[field.Name]; !ok {
+			if coercion := field.coercion(); coercion != nil {
+				coerce[field.Name] = coercion
+			}
+		}
+
+		if declare {
+			fields = append(fields, table.schemaField(field))
+		}
+	}
+
+	table.Coerce = coerce
+	table.Fields = fields
+}
+
+// validateSchema will check that the table has at most one of a schema and a schema file, and that each column of its
+// schema is named once and has a known type, with a layout or a unit only if it is a timestamp.
+func (table *Table) validateSchema(name string) error {
+	if table.Schema != nil && table.SchemaFile != "" {
+		return fmt.Errorf("%w: schema and schemaFile are both set on %q", ErrInvalidSchema, name)
+	}
+
+	if table.Schema == nil {
+		return nil
+	}
+
+	if len(table.Schema.Fields) == 0 {
+		return fmt.Errorf("%w: no fields on %q", ErrInvalidSchema, name)
+	}
+
+	columns := make(map[string]bool, len(table.Schema.Fields))
+
+	for _, field := range table.Schema.Fields {
+		if field == nil || field.Name == "" {
+			return fmt.Errorf("%w: unnamed field on %q", ErrInvalidSchema, name)
+		}
+
+		if columns[field.Name] {
+			return fmt.Errorf("%w: duplicate field %q on %q", ErrInvalidSchema, field.Name, name)
+		}
+
+		columns[field.Name] = true
+
+		switch field.Type {
+		case SchemaString, SchemaInteger, SchemaNumber, SchemaDecimal, SchemaBoolean, SchemaTimestamp, SchemaJSON:
+		default:
+			return fmt.Errorf("%w: unknown type %q of %q on %q", ErrInvalidSchema, field.Type, field.Name, name)
+		}
+
+		if (field.Layout != "" || field.Unit != "") && field.Type != SchemaTimestamp {
+			return fmt.Errorf("%w: layout and unit are only supported for %q, on %q of %q", ErrInvalidSchema,
+				SchemaTimestamp, field.Name, name)
+		}
+
+		if field.Layout != "" && field.Unit != "" || !epochUnits[field.Unit] {
+			return fmt.Errorf("%w: invalid unit %q of %q on %q", ErrInvalidSchema, field.Unit, field.Name, name)
+		}
+	}
+
+	return nil
+}
+
+// loadSchemas will read the schema file of each table that has one, and then use the keys of the schema of the table
+// of each request as the primary key of the request, unless the request has its own primary key or hash key, or only
+// appends records.
+func (cfg *Config) loadSchemas() error {
+	names := make([]string, 0, len(cfg.Tables))
+	for name := range cfg.Tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		table := cfg.Tables[name]
+		if table == nil || table.SchemaFile == "" || table.Schema != nil {
+			continue
+		}
+
+		schema, err := readSchema(table.SchemaFile)
+		if err != nil {
+			return fmt.Errorf("%w: table %q: %v", ErrInvalidSchema, name, err)
+		}
+
+		table.Schema = schema
+		table.SchemaFile = ""
+	}
+
+	for _, req := range cfg.Requests {
+		if req == nil || len(req.PrimaryKey) > 0 || req.HashKey != nil || req.WriteMode == WriteModeAppend {
+			continue
+		}
+
+		name := req.Table
+		if name == "" {
+			name = defaultTable(req.Endpoint)
+		}
+
+		if table := cfg.Tables[name]; table != nil && table.Schema != nil {
+			req.PrimaryKey = table.Schema.Keys()
+		}
+	}
+
+	return nil
+}
+
+// resolveSchemaFiles will resolve the relative schema files of the tables of the YAML document of a configuration
+// file, including the tables of its profiles, against the directory of the file, so that they do not depend on the
+// working directory. It returns true if any schema file was resolved.
+func resolveSchemaFiles(doc map[interface{}]interface{}, dir string) (bool, error) {
+	docs := []interface{}{doc}
+
+	if profiles, ok := doc["profiles"].(map[interface{}]interface{}); ok {
+		for _, profile := range profiles {
+			docs = append(docs, profile)
+		}
+	}
+
+	var resolved bool
+
+	for _, doc := range docs {
+		doc, _ := doc.(map[interface{}]interface{})
+		tables, _ := doc["tables"].(map[interface{}]interface{})
+
+		for _, table := range tables {
+			table, _ := table.(map[interface{}]interface{})
+
+			path, ok := table[schemaFileKey].(string)
+			if !ok || path == "" || filepath.IsAbs(path) {
+				continue
+			}
+
+			abs, err := filepath.Abs(filepath.Join(dir, path))
+			if err != nil {
+				return false, fmt.Errorf("%w: unable to resolve %s: %v", ErrInvalidSchema, path, err)
+			}
+
+			table[schemaFileKey] = abs
+			resolved = true
+		}
+	}
+
+	return resolved, nil
+}
+
+// readSchema will read the schema file, in the format of its extension.
+func readSchema(path string) (*Schema, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read schema file: %w", err)
+	}
+
+	bytes, err = toYAML(bytes, FormatFromPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	if err := yaml.UnmarshalStrict(bytes, &schema); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal schema file: %w", err)
+	}
+
+	return &schema, nil
+}
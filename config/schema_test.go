@@ -0,0 +1,205 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name    string
+		table   *Table
+		wantErr error
+	}{
+		{name: "none", table: &Table{}},
+		{name: "file", table: &Table{SchemaFile: "trades.yaml"}},
+		{name: "schema", table: &Table{Schema: &Schema{Fields: []*SchemaField{
+			{Name: "id", Type: SchemaInteger, Key: true},
+			{Name: "time", Type: SchemaTimestamp, Layout: "2006-01-02"},
+			{Name: "epoch", Type: SchemaTimestamp, Unit: "ms"},
+		}}}},
+		{
+			name:    "schema and file",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{{Name: "id", Type: SchemaJSON}}}, SchemaFile: "x"},
+			wantErr: ErrInvalidSchema,
+		},
+		{name: "no fields", table: &Table{Schema: &Schema{}}, wantErr: ErrInvalidSchema},
+		{
+			name:    "unnamed",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{{Type: SchemaString}}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name:    "nil field",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{nil}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name: "duplicate",
+			table: &Table{Schema: &Schema{Fields: []*SchemaField{
+				{Name: "id", Type: SchemaString}, {Name: "id", Type: SchemaInteger},
+			}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name:    "unknown type",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{{Name: "id", Type: "uuid"}}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name:    "layout",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{{Name: "id", Type: SchemaString, Layout: "x"}}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name: "layout and unit",
+			table: &Table{Schema: &Schema{Fields: []*SchemaField{
+				{Name: "time", Type: SchemaTimestamp, Layout: "2006", Unit: "s"},
+			}}},
+			wantErr: ErrInvalidSchema,
+		},
+		{
+			name:    "unit",
+			table:   &Table{Schema: &Schema{Fields: []*SchemaField{{Name: "time", Type: SchemaTimestamp, Unit: "h"}}}},
+			wantErr: ErrInvalidSchema,
+		},
+	} {
+		if err := tcase.table.validate("trades"); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
+
+func TestLoadSchemas(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "trades.yaml")
+	if err := os.WriteFile(yamlFile, []byte("fields:\n  - name: trade_id\n    type: integer\n    key: true\n"+
+		"  - name: price\n    field: px\n    type: decimal\n    required: true\n"), 0o600); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+
+	jsonFile := filepath.Join(dir, "orders.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"fields":[{"name":"id","type":"string","key":true},`+
+		`{"name":"size","type":"number"}]}`), 0o600); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+
+	cfg := &Config{
+		Tables: map[string]*Table{
+			"trades": {SchemaFile: yamlFile, Coerce: map[string]*Coercion{"price": {Type: CoerceDecimal}}},
+			"orders": {SchemaFile: jsonFile, Columns: map[string]string{"qty": "size"}},
+		},
+		Requests: []*Request{
+			{Endpoint: "/trades"},
+			{Endpoint: "/orders", PrimaryKey: []string{"order_id"}},
+			{Endpoint: "/v2/orders", Table: "orders", WriteMode: WriteModeAppend},
+		},
+	}
+
+	if err := cfg.loadSchemas(); err != nil {
+		t.Fatalf("failed to load schemas: %v", err)
+	}
+
+	if keys := cfg.Requests[0].PrimaryKey; !reflect.DeepEqual(keys, []string{"trade_id"}) {
+		t.Errorf("expected the keys of the schema, got %v", keys)
+	}
+
+	if keys := cfg.Requests[1].PrimaryKey; !reflect.DeepEqual(keys, []string{"order_id"}) {
+		t.Errorf("expected the primary key of the request, got %v", keys)
+	}
+
+	if keys := cfg.Requests[2].PrimaryKey; len(keys) != 0 {
+		t.Errorf("expected no primary key in append mode, got %v", keys)
+	}
+
+	trades := cfg.TableFor("trades")
+	if !reflect.DeepEqual(trades.Fields, []string{"trade_id", "px"}) ||
+		!reflect.DeepEqual(trades.RequiredFields(), []string{"trade_id", "px"}) ||
+		!reflect.DeepEqual(trades.RequiredColumns(), []string{"trade_id", "price"}) {
+		t.Errorf("unexpected fields %v, %v, and %v", trades.Fields, trades.RequiredFields(), trades.RequiredColumns())
+	}
+
+	if trades.Coerce["trade_id"].Type != CoerceInteger || trades.Coerce["price"].Type != CoerceDecimal {
+		t.Errorf("expected the coercions of the schema and the table, got %v", trades.Coerce)
+	}
+
+	if len(cfg.Tables["trades"].Coerce) != 1 {
+		t.Errorf("expected the table to be unchanged, got %v", cfg.Tables["trades"].Coerce)
+	}
+
+	orders := cfg.TableFor("orders")
+	if !reflect.DeepEqual(orders.Fields, []string{"id", "qty"}) || orders.Coerce["size"].Type != CoerceDecimal {
+		t.Errorf("unexpected fields %v and coercions %v", orders.Fields, orders.Coerce)
+	}
+
+	cfg.Tables["missing"] = &Table{SchemaFile: filepath.Join(dir, "missing.yaml")}
+	if err := cfg.loadSchemas(); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected %v, got %v", ErrInvalidSchema, err)
+	}
+}
+
+func TestResolveSchemaFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for path, content := range map[string]string{
+		"configs/candles.yaml": "include: shared/tables.yaml\ntables:\n  trades:\n    schemaFile: schemas/trades.yaml\n" +
+			"profiles:\n  prod:\n    tables:\n      quotes:\n        schemaFile: schemas/quotes.yaml\n",
+		"configs/shared/tables.yaml": "tables:\n  orders:\n    schemaFile: schemas/orders.yaml\n" +
+			"  fills:\n    schemaFile: /srv/schemas/fills.yaml\n",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	bytes, err := ReadFile(filepath.Join(dir, "configs", "candles.yaml"), FormatAuto)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	bytes, err = SelectProfile(bytes, "prod")
+	if err != nil {
+		t.Fatalf("failed to select profile: %v", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	// Schema files are relative to the file that declares them, not to the working directory.
+	for table, expected := range map[string]string{
+		"trades": filepath.Join(dir, "configs", "schemas", "trades.yaml"),
+		"quotes": filepath.Join(dir, "configs", "schemas", "quotes.yaml"),
+		"orders": filepath.Join(dir, "configs", "shared", "schemas", "orders.yaml"),
+		"fills":  "/srv/schemas/fills.yaml",
+	} {
+		if got := cfg.Tables[table].SchemaFile; got != expected {
+			t.Errorf("%s: expected %q, got %q", table, expected, got)
+		}
+	}
+}
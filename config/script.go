@@ -0,0 +1,48 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+
+	"go.starlark.net/syntax"
+)
+
+// Script is a Starlark script that transforms each record of a request, for the quirks of a web API that the
+// declarative configuration cannot express. The script defines a "transform" function that receives each record as a
+// dict, and the query parameters of its chunk as a second argument if it takes two. It returns the record to store it,
+// None to drop it, or a list of records to split it.
+type Script struct {
+	// Source is the source of the script.
+	Source string `yaml:"source"`
+
+	// File is the path of the file with the source of the script, relative to the working directory, if the source is
+	// not set. It is only supported in local configurations, see "Config.CheckRemote".
+	File string `yaml:"file"`
+}
+
+// validateScript will check that the script of the request has either a source or a file, and that its source can be
+// parsed.
+func (req *Request) validateScript() error {
+	script := req.Script
+	if script == nil {
+		return nil
+	}
+
+	if (script.Source == "") == (script.File == "") {
+		return fmt.Errorf("%w: one of source or file is required on %q", ErrInvalidScript, req.Endpoint)
+	}
+
+	if script.Source != "" {
+		if _, err := syntax.Parse(req.Endpoint, script.Source, 0); err != nil {
+			return fmt.Errorf("%w on %q: %v", ErrInvalidScript, req.Endpoint, err)
+		}
+	}
+
+	return nil
+}
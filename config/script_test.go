@@ -0,0 +1,35 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateScript(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name    string
+		script  *Script
+		wantErr error
+	}{
+		{name: "none"},
+		{name: "source", script: &Script{Source: "def transform(record):\n    return record\n"}},
+		{name: "file", script: &Script{File: "scripts/candles.star"}},
+		{name: "empty", script: &Script{}, wantErr: ErrInvalidScript},
+		{name: "source and file", script: &Script{Source: "x = 1", File: "x.star"}, wantErr: ErrInvalidScript},
+		{name: "syntax", script: &Script{Source: "def transform(record)\n"}, wantErr: ErrInvalidScript},
+	} {
+		req := &Request{Endpoint: "/candles", Script: tcase.script}
+		if err := req.validateScript(); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"gopkg.in/yaml.v2"
+)
+
+// secretsKey is the key of the secrets file of a configuration file.
+const secretsKey = "secrets"
+
+// secretsPermMask are the permission bits of a secrets file that must not be set, i.e. access by the group or others.
+const secretsPermMask = 0o077
+
+// secretKeys are the only keys that a secrets file can set.
+var secretKeys = map[string]bool{"authentication": true, "connectionStrings": true, "destinations": true}
+
+// mergeSecrets will merge the secrets file that the YAML document of the configuration file references over it, so
+// that a configuration can be shared without its credentials. A secrets file can only set the authentication,
+// connection strings, and destinations, and must only be readable by its owner. Its path is relative to the file that
+// references it, its environment variables are expanded, and its format is detected by its extension. The document
+// is not changed if it does not reference a secrets file.
+func mergeSecrets(doc map[interface{}]interface{}, dir string) error {
+	ref, ok := doc[secretsKey]
+	if !ok {
+		return nil
+	}
+
+	path, ok := ref.(string)
+	if !ok || path == "" {
+		return fmt.Errorf("%w: secrets must be a path", ErrInvalidSecrets)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	secrets, err := readSecrets(path)
+	if err != nil {
+		return err
+	}
+
+	delete(doc, secretsKey)
+	mergeYAML(doc, secrets)
+
+	return nil
+}
+
+// readSecrets will return the YAML document of the secrets file.
+func readSecrets(path string) (map[interface{}]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecrets, err)
+	}
+
+	// Windows does not have permission bits for the group and others.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&secretsPermMask != 0 {
+		return nil, fmt.Errorf("%w: %s can be read by other users, restrict it with chmod 600", ErrInvalidSecrets,
+			path)
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecrets, err)
+	}
+
+	bytes, err = ExpandEnv(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err = toYAML(bytes, FormatFromPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(bytes, &secrets); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML of %s: %w", path, err)
+	}
+
+	for key := range secrets {
+		if name, ok := key.(string); !ok || !secretKeys[name] {
+			return nil, fmt.Errorf("%w: %v is not authentication, connectionStrings, or destinations in %s",
+				ErrInvalidSecrets, key, path)
+		}
+	}
+
+	return secrets, nil
+}
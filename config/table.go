@@ -0,0 +1,311 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DropColumn is the column that a field is mapped to in order to drop it from the records of a table.
+const DropColumn = "-"
+
+// Types that the values of a column can be coerced to.
+const (
+	// CoerceTimestamp will parse a string with the coercion's layout into an RFC 3339 timestamp.
+	CoerceTimestamp = "timestamp"
+
+	// CoerceDecimal will parse a string into a number.
+	CoerceDecimal = "decimal"
+
+	// CoerceEpoch will convert a number, or a string of a number, since the Unix epoch into an RFC 3339 timestamp.
+	CoerceEpoch = "epoch"
+
+	// CoerceNumeric will convert a number, or a string of a number, into a string with the exact text of the
+	// number, so that no precision is lost by storing it as a 64-bit float.
+	CoerceNumeric = "numeric"
+
+	// CoerceInteger will convert a whole number, or a string of one, into an integer.
+	CoerceInteger = "integer"
+
+	// CoerceBoolean will convert a boolean, a string of one such as "true" or "1", or the numbers 0 and 1 into a
+	// boolean.
+	CoerceBoolean = "boolean"
+
+	// CoerceString will convert a string, number, or boolean into a string.
+	CoerceString = "string"
+)
+
+// Naming conventions that the names of the fields of records can be converted to.
+const (
+	// NamingSnakeCase will convert names to lower case words separated by underscores, e.g. "trade_id".
+	NamingSnakeCase = "snake_case"
+
+	// NamingCamelCase will convert names to words without separators, where every word but the first is
+	// capitalized, e.g. "tradeId".
+	NamingCamelCase = "camelCase"
+
+	// NamingPascalCase will convert names to capitalized words without separators, e.g. "TradeId".
+	NamingPascalCase = "PascalCase"
+)
+
+// Decoding modes, which are how the fields of the records of a table are checked against the fields it declares.
+const (
+	// DecodingLenient logs the fields that are unexpected or missing from the records of a response, and stores
+	// them anyway.
+	DecodingLenient = "lenient"
+
+	// DecodingStrict fails the chunk of a response whose records have unexpected fields or miss declared fields, so
+	// that changes to the web API are detected before they reach storage.
+	DecodingStrict = "strict"
+)
+
+// validNaming will return true if the naming convention is empty or known.
+func validNaming(naming string) bool {
+	switch naming {
+	case "", NamingSnakeCase, NamingCamelCase, NamingPascalCase:
+		return true
+	}
+
+	return false
+}
+
+// validDecoding will return true if the decoding mode is empty or known.
+func validDecoding(decoding string) bool {
+	return decoding == "" || decoding == DecodingLenient || decoding == DecodingStrict
+}
+
+// epochUnits are the units of the time since the Unix epoch for "epoch" coercions.
+var epochUnits = map[string]bool{"": true, "s": true, "ms": true, "us": true, "ns": true}
+
+// Coercion is the conversion of the values of a column before they are stored, e.g. for web APIs that return numbers
+// as strings.
+type Coercion struct {
+	// Type is the type to convert the values to: "timestamp", "decimal", "epoch", "numeric", "integer", "boolean",
+	// or "string".
+	Type string `yaml:"type"`
+
+	// Layout is the layout of "timestamp" values in the syntax of "time.Parse". The default layout is RFC 3339.
+	Layout string `yaml:"layout"`
+
+	// Unit is the unit of "epoch" values: "s", "ms", "us", or "ns". The default unit is seconds.
+	Unit string `yaml:"unit"`
+}
+
+func (coercion *Coercion) validate() error {
+	switch coercion.Type {
+	case CoerceTimestamp, CoerceDecimal, CoerceEpoch, CoerceNumeric, CoerceInteger, CoerceBoolean, CoerceString:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidCoercion, coercion.Type)
+	}
+
+	if coercion.Layout != "" && coercion.Type != CoerceTimestamp {
+		return fmt.Errorf("%w: layout is only supported for %q", ErrInvalidCoercion, CoerceTimestamp)
+	}
+
+	if coercion.Unit != "" && coercion.Type != CoerceEpoch {
+		return fmt.Errorf("%w: unit is only supported for %q", ErrInvalidCoercion, CoerceEpoch)
+	}
+
+	if !epochUnits[coercion.Unit] {
+		return fmt.Errorf("%w: unknown unit %q", ErrInvalidCoercion, coercion.Unit)
+	}
+
+	return nil
+}
+
+// Retention is how long the records of a table are kept, by the time in one of its columns.
+type Retention struct {
+	// Column is the column of the time of each record.
+	Column string `yaml:"column"`
+
+	// MaxAge is how long records are kept after their time, as a Go duration or a number of days, e.g. "36h" or
+	// "90d".
+	MaxAge string `yaml:"maxAge"`
+}
+
+// Age will return the maximum age of the records.
+func (retention *Retention) Age() (time.Duration, error) {
+	if strings.HasSuffix(retention.MaxAge, "d") {
+		count, err := strconv.Atoi(strings.TrimSuffix(retention.MaxAge, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidRetention, retention.MaxAge)
+		}
+
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(retention.MaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidRetention, err)
+	}
+
+	return age, nil
+}
+
+func (retention *Retention) validate(name string) error {
+	if retention.Column == "" {
+		return fmt.Errorf("%w: column is required on %q", ErrInvalidRetention, name)
+	}
+
+	age, err := retention.Age()
+	if err != nil {
+		return fmt.Errorf("table %q: %w", name, err)
+	}
+
+	if age <= 0 {
+		return fmt.Errorf("%w: maxAge must be positive on %q", ErrInvalidRetention, name)
+	}
+
+	return nil
+}
+
+// Table is the configuration for how the records of a table are stored.
+type Table struct {
+	// Columns maps the fields of the records to the names of the columns they are stored in. Fields mapped to "-"
+	// are dropped, and fields that are not mapped are stored in a column with the same name.
+	Columns map[string]string `yaml:"columns"`
+
+	// Coerce are the conversions of the values of columns, keyed by the column name after mapping. Null values are
+	// never converted.
+	Coerce map[string]*Coercion `yaml:"coerce"`
+
+	// Document is the column to store each entire record in as a single JSON document, after mapping and coercion.
+	// If it is empty, the fields of the records are stored in their own columns.
+	Document string `yaml:"document"`
+
+	// DocumentKeys are the fields of the records that are also stored in their own columns in "document" mode, e.g.
+	// the primary key of the table.
+	DocumentKeys []string `yaml:"documentKeys"`
+
+	// Retention deletes the records of the table that are older than its maximum age after each run.
+	Retention *Retention `yaml:"retention"`
+
+	// Naming is the convention that the names of fields, including the fields of nested objects, are converted to
+	// before they are stored: "snake_case", "camelCase", or "PascalCase". Fields that are mapped to a column keep
+	// the name of their column. It overrides the naming convention of the configuration.
+	Naming string `yaml:"naming"`
+
+	// Fields are the fields that the records of the table have in the responses of the web API, before they are
+	// mapped. If they are declared, the records of each response are checked against them with the decoding mode.
+	Fields []string `yaml:"fields"`
+
+	// Decoding is how records with unexpected or missing fields are handled: "lenient" or "strict". It overrides
+	// the decoding mode of the configuration.
+	Decoding string `yaml:"decoding"`
+
+	// Schema declares the columns of the table, their types, and which of them are required or keys. It drives the
+	// fields that the records are checked against, the coercions of the columns, the primary keys of the requests of
+	// the table, and the columns of the table if storage creates it, see "Schema".
+	Schema *Schema `yaml:"schema"`
+
+	// SchemaFile is the path of a YAML, JSON, or TOML file with the schema of the table, if "Schema" is not set. A
+	// relative path is relative to the configuration file that declares it, see "ReadFile". Configurations that are
+	// sent to a service cannot have schema files, see "ParseRemote".
+	SchemaFile string `yaml:"schemaFile"`
+
+	// Quarantine is the table that the records which fail to be mapped or coerced are written to, with their error,
+	// so that the other records of their response are still stored. It has the prefix and suffix of the
+	// configuration. If it is empty, a record that fails fails its entire response.
+	Quarantine string `yaml:"quarantine"`
+}
+
+func (table *Table) validate(name string) error {
+	fields := make(map[string]string, len(table.Columns))
+
+	for field, column := range table.Columns {
+		if column == "" {
+			return fmt.Errorf("%w: empty column for field %q on %q", ErrInvalidColumn, field, name)
+		}
+
+		if column == DropColumn {
+			continue
+		}
+
+		if other, ok := fields[column]; ok {
+			return fmt.Errorf("%w: fields %q and %q are both mapped to %q on %q", ErrInvalidColumn, other, field,
+				column, name)
+		}
+
+		fields[column] = field
+	}
+
+	if !validNaming(table.Naming) {
+		return fmt.Errorf("%w: %q on %q", ErrInvalidNaming, table.Naming, name)
+	}
+
+	if !validDecoding(table.Decoding) {
+		return fmt.Errorf("%w: %q on %q", ErrInvalidDecoding, table.Decoding, name)
+	}
+
+	for _, field := range table.Fields {
+		if field == "" {
+			return fmt.Errorf("%w: empty field on %q", ErrInvalidDecoding, name)
+		}
+	}
+
+	if err := table.validateSchema(name); err != nil {
+		return err
+	}
+
+	if table.Quarantine == name {
+		return fmt.Errorf("%w: %q cannot be quarantined to itself", ErrInvalidQuarantine, name)
+	}
+
+	if table.Document == "" && len(table.DocumentKeys) > 0 {
+		return fmt.Errorf("%w: documentKeys require a document column on %q", ErrInvalidDocument, name)
+	}
+
+	for _, key := range table.DocumentKeys {
+		if key == "" || key == table.Document {
+			return fmt.Errorf("%w: invalid document key %q on %q", ErrInvalidDocument, key, name)
+		}
+	}
+
+	for column, coercion := range table.Coerce {
+		if coercion == nil {
+			return fmt.Errorf("%w: empty coercion for %q on %q", ErrInvalidCoercion, column, name)
+		}
+
+		if err := coercion.validate(); err != nil {
+			return fmt.Errorf("column %q on %q: %w", column, name, err)
+		}
+	}
+
+	if table.Retention != nil {
+		return table.Retention.validate(name)
+	}
+
+	return nil
+}
+
+// TableFor will return the configuration of the table, which is empty if the table is not configured. The naming
+// convention and the decoding mode of the configuration are used if the table does not have its own, and the fields
+// and coercions of its schema are added to its own.
+func (cfg *Config) TableFor(name string) *Table {
+	resolved := Table{}
+	if table, ok := cfg.Tables[name]; ok && table != nil {
+		resolved = *table
+	}
+
+	if resolved.Naming == "" {
+		resolved.Naming = cfg.Naming
+	}
+
+	if resolved.Decoding == "" {
+		resolved.Decoding = cfg.Decoding
+	}
+
+	if resolved.Schema != nil {
+		resolved.applySchema()
+	}
+
+	return &resolved
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name    string
+			columns map[string]string
+			wantErr error
+		}{
+			{name: "rename", columns: map[string]string{"px": "price", "sz": "size"}},
+			{name: "drop", columns: map[string]string{"_links": DropColumn, "_meta": DropColumn}},
+			{name: "empty column", columns: map[string]string{"px": ""}, wantErr: ErrInvalidColumn},
+			{name: "duplicate column", columns: map[string]string{"px": "price", "p": "price"}, wantErr: ErrInvalidColumn},
+		} {
+			table := Table{Columns: tcase.columns}
+			if err := table.validate("candles"); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("validate coercions", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name     string
+			coercion *Coercion
+			wantErr  error
+		}{
+			{name: "timestamp", coercion: &Coercion{Type: CoerceTimestamp, Layout: "2006-01-02"}},
+			{name: "decimal", coercion: &Coercion{Type: CoerceDecimal}},
+			{name: "epoch", coercion: &Coercion{Type: CoerceEpoch, Unit: "ms"}},
+			{name: "numeric", coercion: &Coercion{Type: CoerceNumeric}},
+			{name: "empty", wantErr: ErrInvalidCoercion},
+			{name: "unknown type", coercion: &Coercion{Type: "int"}, wantErr: ErrInvalidCoercion},
+			{name: "layout", coercion: &Coercion{Type: CoerceEpoch, Layout: "2006"}, wantErr: ErrInvalidCoercion},
+			{name: "unit", coercion: &Coercion{Type: CoerceDecimal, Unit: "s"}, wantErr: ErrInvalidCoercion},
+			{name: "unknown unit", coercion: &Coercion{Type: CoerceEpoch, Unit: "m"}, wantErr: ErrInvalidCoercion},
+		} {
+			table := Table{Coerce: map[string]*Coercion{"price": tcase.coercion}}
+			if err := table.validate("candles"); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("validate document", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name    string
+			table   Table
+			wantErr error
+		}{
+			{name: "document", table: Table{Document: "doc", DocumentKeys: []string{"id"}}},
+			{name: "keys without document", table: Table{DocumentKeys: []string{"id"}}, wantErr: ErrInvalidDocument},
+			{name: "empty key", table: Table{Document: "doc", DocumentKeys: []string{""}}, wantErr: ErrInvalidDocument},
+			{name: "document key", table: Table{Document: "doc", DocumentKeys: []string{"doc"}}, wantErr: ErrInvalidDocument},
+		} {
+			if err := tcase.table.validate("candles"); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("validate naming", func(t *testing.T) {
+		t.Parallel()
+
+		for _, naming := range []string{"", NamingSnakeCase, NamingCamelCase, NamingPascalCase} {
+			table := Table{Naming: naming}
+			if err := table.validate("candles"); err != nil {
+				t.Errorf("%q: unexpected error %v", naming, err)
+			}
+		}
+
+		table := Table{Naming: "kebab-case"}
+		if err := table.validate("candles"); !errors.Is(err, ErrInvalidNaming) {
+			t.Errorf("expected %v, got %v", ErrInvalidNaming, err)
+		}
+	})
+
+	t.Run("validate decoding", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			table   Table
+			wantErr error
+		}{
+			{table: Table{}},
+			{table: Table{Fields: []string{"id", "price"}, Decoding: DecodingStrict}},
+			{table: Table{Decoding: DecodingLenient}},
+			{table: Table{Decoding: "loose"}, wantErr: ErrInvalidDecoding},
+			{table: Table{Fields: []string{"id", ""}}, wantErr: ErrInvalidDecoding},
+		} {
+			if err := tcase.table.validate("candles"); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%+v: expected %v, got %v", tcase.table, tcase.wantErr, err)
+			}
+		}
+	})
+
+	t.Run("validate quarantine", func(t *testing.T) {
+		t.Parallel()
+
+		table := Table{Quarantine: "candles_quarantine"}
+		if err := table.validate("candles"); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+
+		table = Table{Quarantine: "candles"}
+		if err := table.validate("candles"); !errors.Is(err, ErrInvalidQuarantine) {
+			t.Errorf("expected %v, got %v", ErrInvalidQuarantine, err)
+		}
+	})
+
+	t.Run("validate retention", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name      string
+			retention *Retention
+			age       time.Duration
+			wantErr   error
+		}{
+			{name: "days", retention: &Retention{Column: "time", MaxAge: "90d"}, age: 90 * 24 * time.Hour},
+			{name: "duration", retention: &Retention{Column: "time", MaxAge: "36h"}, age: 36 * time.Hour},
+			{name: "no column", retention: &Retention{MaxAge: "90d"}, wantErr: ErrInvalidRetention},
+			{name: "invalid age", retention: &Retention{Column: "time", MaxAge: "3 months"}, wantErr: ErrInvalidRetention},
+			{name: "zero age", retention: &Retention{Column: "time", MaxAge: "0d"}, wantErr: ErrInvalidRetention},
+		} {
+			table := Table{Retention: tcase.retention}
+			if err := table.validate("candles"); !errors.Is(err, tcase.wantErr) {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+
+			if age, _ := tcase.retention.Age(); tcase.wantErr == nil && age != tcase.age {
+				t.Errorf("%s: expected age %v, got %v", tcase.name, tcase.age, age)
+			}
+		}
+	})
+
+	t.Run("table for", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{
+			Tables: map[string]*Table{
+				"candles": {Columns: map[string]string{"px": "price"}},
+				"orders":  {Naming: NamingCamelCase, Decoding: DecodingLenient},
+			},
+			Naming:   NamingSnakeCase,
+			Decoding: DecodingStrict,
+		}
+
+		if table := cfg.TableFor("candles"); table.Columns["px"] != "price" || table.Naming != NamingSnakeCase {
+			t.Errorf("expected the candles configuration, got %+v", table)
+		}
+
+		if table := cfg.TableFor("orders"); table.Naming != NamingCamelCase || table.Decoding != DecodingLenient {
+			t.Errorf("expected the orders naming convention and decoding, got %+v", table)
+		}
+
+		if table := cfg.TableFor("candles"); table.Decoding != DecodingStrict {
+			t.Errorf("expected the decoding of the configuration, got %q", table.Decoding)
+		}
+
+		if table := cfg.TableFor("trades"); table == nil || len(table.Columns) != 0 || table.Naming != NamingSnakeCase {
+			t.Errorf("expected an empty configuration, got %+v", table)
+		}
+
+		if cfg.Tables["candles"].Naming != "" {
+			t.Errorf("expected the candles configuration to be unchanged")
+		}
+	})
+}
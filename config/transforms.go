@@ -0,0 +1,98 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// Transforms change the table that the records of a request are stored in and the records themselves, depending on
+// the query parameters of each chunk of the request, so that what is specific to a web API lives in the
+// configuration. The templates are Go templates that are executed with the query parameters of the chunk, including
+// the start and end of a timeseries chunk, e.g. "{{ .product_id }}".
+type Transforms struct {
+	// Table is the template of the table that the records of each chunk are stored in, e.g.
+	// "{{ .product_id }}_candles". The records are still transformed with the configuration of the request's table,
+	// and the table of the request is the one that is truncated and reported.
+	Table string `yaml:"table"`
+
+	// Fields are the templates of the fields that are added to each record, keyed by field, e.g.
+	// {"product_id": "{{ .product_id }}"}. A field that the record already has is replaced.
+	Fields map[string]string `yaml:"fields"`
+
+	// Values remap the values of fields, keyed by field and then by the value, e.g. {"side": {"b": "buy"}}. Values
+	// are compared as strings, and values that are not remapped are kept.
+	Values map[string]map[string]string `yaml:"values"`
+}
+
+// Inject adds the query parameters and the segments of the path of each chunk of a request to its records, e.g. the
+// product of a candles endpoint whose records do not name it. The values are added as strings, which the coercions
+// of the table can convert.
+type Inject struct {
+	// Query are the query parameters that are added to each record, as fields of the same name.
+	Query []string `yaml:"query"`
+
+	// Path are the segments of the path of the endpoint that are added to each record, keyed by field, counting
+	// from 0, e.g. {"product_id": 1} adds "BTC-USD" for "/products/BTC-USD/candles". A negative index counts from
+	// the last segment. The segments are also parameters of the templates of the transforms of the request.
+	Path map[string]int `yaml:"path"`
+}
+
+// validateTransforms will check that the fields that are injected into the records of the request are named, that the
+// templates of the transforms of the request can be parsed, and that the request does not rename its table if the
+// table of the request is truncated, soft deleted from, or read back to verify its checksum.
+func (req *Request) validateTransforms(verify string) error {
+	if req.Inject != nil {
+		for _, param := range req.Inject.Query {
+			if param == "" {
+				return fmt.Errorf("%w: empty query parameter to inject on %q", ErrInvalidTransforms, req.Endpoint)
+			}
+		}
+
+		for field := range req.Inject.Path {
+			if field == "" {
+				return fmt.Errorf("%w: empty path field to inject on %q", ErrInvalidTransforms, req.Endpoint)
+			}
+		}
+	}
+
+	transforms := req.Transforms
+	if transforms == nil {
+		return nil
+	}
+
+	if transforms.Table != "" {
+		if _, err := template.New("table").Option("missingkey=error").Parse(transforms.Table); err != nil {
+			return fmt.Errorf("%w: table on %q: %v", ErrInvalidTransforms, req.Endpoint, err)
+		}
+
+		if req.Truncate != nil && *req.Truncate || req.SoftDelete != "" || verify == VerifyChecksum {
+			return fmt.Errorf("%w: table is not supported with truncate, softDelete, or %q verification on %q",
+				ErrInvalidTransforms, VerifyChecksum, req.Endpoint)
+		}
+	}
+
+	for field, text := range transforms.Fields {
+		if field == "" {
+			return fmt.Errorf("%w: empty field on %q", ErrInvalidTransforms, req.Endpoint)
+		}
+
+		if _, err := template.New(field).Option("missingkey=error").Parse(text); err != nil {
+			return fmt.Errorf("%w: field %q on %q: %v", ErrInvalidTransforms, field, req.Endpoint, err)
+		}
+	}
+
+	for field := range transforms.Values {
+		if field == "" {
+			return fmt.Errorf("%w: empty value field on %q", ErrInvalidTransforms, req.Endpoint)
+		}
+	}
+
+	return nil
+}
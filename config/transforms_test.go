@@ -0,0 +1,82 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	t.Parallel()
+
+	truncate := true
+
+	for _, tcase := range []struct {
+		name       string
+		transforms *Transforms
+		inject     *Inject
+		truncate   *bool
+		softDelete string
+		verify     string
+		wantErr    error
+	}{
+		{name: "none"},
+		{
+			name: "valid",
+			transforms: &Transforms{
+				Table:  "{{ .product_id }}_candles",
+				Fields: map[string]string{"product_id": "{{ .product_id }}"},
+				Values: map[string]map[string]string{"side": {"b": "buy"}},
+			},
+			verify: VerifyRows,
+		},
+		{name: "fields with truncate", transforms: &Transforms{Fields: map[string]string{"x": "y"}}, truncate: &truncate},
+		{name: "table", transforms: &Transforms{Table: "{{ .product_id"}, wantErr: ErrInvalidTransforms},
+		{name: "field", transforms: &Transforms{Fields: map[string]string{"x": "{{"}}, wantErr: ErrInvalidTransforms},
+		{name: "empty field", transforms: &Transforms{Fields: map[string]string{"": "y"}}, wantErr: ErrInvalidTransforms},
+		{
+			name:       "empty value field",
+			transforms: &Transforms{Values: map[string]map[string]string{"": {"b": "buy"}}},
+			wantErr:    ErrInvalidTransforms,
+		},
+		{name: "inject", inject: &Inject{Query: []string{"granularity"}, Path: map[string]int{"product_id": 1}}},
+		{name: "empty query", inject: &Inject{Query: []string{""}}, wantErr: ErrInvalidTransforms},
+		{name: "empty path field", inject: &Inject{Path: map[string]int{"": 1}}, wantErr: ErrInvalidTransforms},
+		{
+			name:       "table with truncate",
+			transforms: &Transforms{Table: "candles_{{ .granularity }}"},
+			truncate:   &truncate,
+			wantErr:    ErrInvalidTransforms,
+		},
+		{
+			name:       "table with soft delete",
+			transforms: &Transforms{Table: "candles_{{ .granularity }}"},
+			softDelete: "deleted",
+			wantErr:    ErrInvalidTransforms,
+		},
+		{
+			name:       "table with checksum",
+			transforms: &Transforms{Table: "candles_{{ .granularity }}"},
+			verify:     VerifyChecksum,
+			wantErr:    ErrInvalidTransforms,
+		},
+	} {
+		req := &Request{
+			Endpoint:   "/candles",
+			Transforms: tcase.transforms,
+			Inject:     tcase.inject,
+			Truncate:   tcase.truncate,
+			SoftDelete: tcase.softDelete,
+		}
+
+		if err := req.validateTransforms(tcase.verify); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+	}
+}
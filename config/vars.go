@@ -0,0 +1,67 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// expandVars will execute the endpoint, query values, and table of every request as a Go template with the variables
+// of the configuration. A variable that is referenced and not defined is an error, so that it is not silently
+// requested as an empty value. Values without a template are not changed.
+func (cfg *Config) expandVars() error {
+	for _, req := range cfg.Requests {
+		if req == nil {
+			continue
+		}
+
+		var err error
+
+		if req.Endpoint, err = cfg.expandVar(req.Endpoint); err != nil {
+			return err
+		}
+
+		if req.Table, err = cfg.expandVar(req.Table); err != nil {
+			return err
+		}
+
+		for key, value := range req.Query {
+			if req.Query[key], err = cfg.expandVar(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandVar will execute the value as a Go template with the variables of the configuration.
+func (cfg *Config) expandVar(value string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New("vars").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidVariable, err)
+	}
+
+	vars := cfg.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrInvalidVariable, value, err)
+	}
+
+	return out.String(), nil
+}
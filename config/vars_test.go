@@ -0,0 +1,88 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	t.Parallel()
+
+	yaml := []byte(`
+url: https://api.pro.coinbase.com
+connectionStrings: ["stdout://"]
+rateLimit:
+  burst: 5
+  period: 1s
+vars:
+  product: BTC-USD
+  granularity: 60
+requests:
+  - endpoint: /products/{{ .product }}/candles
+    query:
+      granularity: "{{ .granularity }}"
+  - endpoint: /products/{{ .product }}/trades
+    table: "{{ .product }}_trades"
+  - endpoint: /currencies
+`)
+
+	for _, tcase := range []struct {
+		name      string
+		overrides *Overrides
+		product   string
+	}{
+		{name: "vars", overrides: &Overrides{}, product: "BTC-USD"},
+		{name: "override", overrides: &Overrides{Vars: map[string]string{"product": "ETH-USD"}}, product: "ETH-USD"},
+	} {
+		bytes, err := tcase.overrides.Apply(yaml)
+		if err != nil {
+			t.Fatalf("%s: failed to apply overrides: %v", tcase.name, err)
+		}
+
+		cfg, err := Parse(context.Background(), bytes)
+		if err != nil {
+			t.Fatalf("%s: failed to parse: %v", tcase.name, err)
+		}
+
+		if want := "/products/" + tcase.product + "/candles"; cfg.Requests[0].Endpoint != want ||
+			cfg.Requests[0].Query["granularity"] != "60" || cfg.Requests[0].Table != "candles" {
+			t.Errorf("%s: expected %s?granularity=60, got %+v", tcase.name, want, cfg.Requests[0])
+		}
+
+		if want := tcase.product + "_trades"; cfg.Requests[1].Table != want {
+			t.Errorf("%s: expected table %q, got %q", tcase.name, want, cfg.Requests[1].Table)
+		}
+
+		if cfg.Requests[2].Endpoint != "/currencies" {
+			t.Errorf("%s: expected requests without variables to be unchanged, got %q", tcase.name,
+				cfg.Requests[2].Endpoint)
+		}
+	}
+
+	for _, tcase := range []struct {
+		name string
+		yaml string
+	}{
+		{name: "undefined", yaml: "requests: [{endpoint: \"/products/{{ .missing }}/candles\"}]"},
+		{name: "no vars", yaml: "vars: {}\nrequests: [{endpoint: /currencies, query: {id: \"{{ .id }}\"}}]"},
+		{name: "invalid template", yaml: "requests: [{endpoint: \"/products/{{ .product }/candles\"}]"},
+	} {
+		bytes := []byte("url: https://api.pro.coinbase.com\nrateLimit: {burst: 5, period: 1s}\n" + tcase.yaml)
+
+		if _, err := Parse(context.Background(), bytes); !errors.Is(err, ErrInvalidVariable) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, ErrInvalidVariable, err)
+		}
+
+		if problems := Lint(bytes); len(problems) == 0 || !errors.Is(problems[0], ErrInvalidVariable) {
+			t.Errorf("%s: expected lint to report %v, got %v", tcase.name, ErrInvalidVariable, problems)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Watchdog watches the web and repository workers of a run, and reports the workers that are busy but have made no
+// progress for the stall period, e.g. because of a storage connection that hangs.
+type Watchdog struct {
+	// Stall is how long a worker may make no progress before it is reported as stalled, as a Go duration, e.g. "5m".
+	Stall string `yaml:"stall"`
+
+	// Fail will wind the run down, as if it had been cancelled, once a worker has stalled. Stalled workers are only
+	// logged otherwise.
+	Fail bool `yaml:"fail"`
+}
+
+// StallDuration will return how long a worker may make no progress before it is reported as stalled.
+func (watchdog *Watchdog) StallDuration() (time.Duration, error) {
+	stall, err := time.ParseDuration(watchdog.Stall)
+	if err != nil || stall <= 0 {
+		return 0, fmt.Errorf("%w: stall %q", ErrInvalidWatchdog, watchdog.Stall)
+	}
+
+	return stall, nil
+}
+
+func (watchdog *Watchdog) validate() error {
+	_, err := watchdog.StallDuration()
+
+	return err
+}
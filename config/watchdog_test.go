@@ -0,0 +1,30 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	t.Parallel()
+
+	watchdog := &Watchdog{Stall: "5m", Fail: true}
+	if stall, err := watchdog.StallDuration(); err != nil || stall != 5*time.Minute {
+		t.Errorf("expected a stall of 5m, got %v and %v", stall, err)
+	}
+
+	for _, stall := range []string{"", "5", "0s", "-1m"} {
+		watchdog := &Watchdog{Stall: stall}
+		if err := watchdog.validate(); !errors.Is(err, ErrInvalidWatchdog) {
+			t.Errorf("%q: expected %v, got %v", stall, ErrInvalidWatchdog, err)
+		}
+	}
+}
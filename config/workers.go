@@ -0,0 +1,70 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package config
+
+import "runtime"
+
+// defaultWebWorkersPerCPU and defaultStorageWorkersPerCPU are the number of web and storage workers for each CPU if
+// their counts are not configured. Workers spend most of their time waiting on the network, so there are many more
+// of them than CPUs. Fewer storage workers are started, since each of them holds a transaction open on every
+// destination.
+const (
+	defaultWebWorkersPerCPU     = 8
+	defaultStorageWorkersPerCPU = 2
+)
+
+// defaultSplitWorkers is the number of workers that write the slices of a split response if it is not configured.
+const defaultSplitWorkers = 4
+
+// defaultJobBuffer is the number of fetched chunks of each request that are buffered if the buffer is not configured.
+const defaultJobBuffer = 16
+
+// WebWorkers will return the number of workers that fetch the chunks of the requests concurrently.
+func (cfg *Config) WebWorkers() int {
+	if cfg.WebWorkerCount > 0 {
+		return cfg.WebWorkerCount
+	}
+
+	return defaultWebWorkersPerCPU * runtime.NumCPU()
+}
+
+// StorageWorkers will return the number of workers that write the requests to storage concurrently.
+func (cfg *Config) StorageWorkers() int {
+	if cfg.StorageWorkerCount > 0 {
+		return cfg.StorageWorkerCount
+	}
+
+	return defaultStorageWorkersPerCPU * runtime.NumCPU()
+}
+
+// SplitWorkerCount will return the number of workers that write the slices of a split response concurrently.
+func (cfg *Config) SplitWorkerCount() int {
+	if cfg.SplitWorkers > 0 {
+		return cfg.SplitWorkers
+	}
+
+	return defaultSplitWorkers
+}
+
+// JobBufferSize will return the number of fetched chunks of each request that are buffered until they are written.
+func (cfg *Config) JobBufferSize() int {
+	if cfg.JobBuffer > 0 {
+		return cfg.JobBuffer
+	}
+
+	return defaultJobBuffer
+}
+
+// MaxConns will return the number of connections to the web API that the web workers share.
+func (cfg *Config) MaxConns() int {
+	if cfg.MaxConnsPerHost > 0 {
+		return cfg.MaxConnsPerHost
+	}
+
+	return cfg.WebWorkers()
+}
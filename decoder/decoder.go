@@ -0,0 +1,59 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+
+// Package decoder is the public interface for response decoders. Custom decoders can be registered for a format,
+// which a request selects with its "format", or for the content type of responses, so that proprietary formats are
+// decoded like any built-in format:
+//
+//	func init() {
+//		decoder.Register("acme", decoder.Func(func(data []byte) ([]byte, error) {
+//			return acme.ToJSON(data)
+//		}))
+//	}
+package decoder
+
+import (
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/decoder"
+)
+
+// Decoder decodes the body of a response into JSON: a record as an object, or records as a list of objects. The
+// records are then selected, transformed, and stored like those of any other response. A decoder is shared by the
+// workers of a run, so it must be safe for concurrent use.
+type Decoder = decoder.Decoder
+
+// ErrDecode is matched by the errors of the built-in decoders. A response that fails to be decoded fails its chunk,
+// or is skipped as poison.
+var ErrDecode = decoder.ErrDecode
+
+// Func is a function that decodes the body of a response.
+type Func func(data []byte) ([]byte, error)
+
+// Decode will call the function.
+func (fn Func) Decode(data []byte) ([]byte, error) {
+	return fn(data)
+}
+
+// Register will make the decoder available to requests with the format, e.g. "format: acme". Register panics if the
+// format is built in or has already been registered, so it should be called from an "init" function.
+func Register(format string, dec Decoder) {
+	if err := decoder.RegisterFormat(format, dec); err != nil {
+		panic(fmt.Sprintf("decoder: unable to register %q: %v", format, err))
+	}
+}
+
+// RegisterContentType will make the decoder decode the responses with the content type, e.g.
+// "application/vnd.acme+bin", of requests whose format is JSON, the default. The parameters of the content type, e.g.
+// its charset, are ignored. RegisterContentType panics if the content type is invalid or has already been registered,
+// so it should be called from an "init" function.
+func RegisterContentType(contentType string, dec Decoder) {
+	if err := decoder.RegisterContentType(contentType, dec); err != nil {
+		panic(fmt.Sprintf("decoder: unable to register content type %q: %v", contentType, err))
+	}
+}
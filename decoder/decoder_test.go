@@ -0,0 +1,67 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/decoder"
+)
+
+// registerPanics returns "true" if registering the format or content type with the function panics.
+func registerPanics(register func(string, Decoder), name string, dec Decoder) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+
+	register(name, dec)
+
+	return false
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	// "pipes" decodes rows of fields separated by pipes into records of their fields.
+	pipes := Func(func(data []byte) ([]byte, error) {
+		var rows []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			rows = append(rows, `{"fields":"`+strings.ReplaceAll(line, "|", ",")+`"}`)
+		}
+
+		return []byte("[" + strings.Join(rows, ",") + "]"), nil
+	})
+
+	Register("pipes", pipes)
+	RegisterContentType("text/x-pipes", pipes)
+
+	dec, ok := decoder.LookupFormat("pipes")
+	if !ok {
+		t.Fatalf("expected the format to be registered")
+	}
+
+	if _, ok := decoder.LookupContentType("text/x-pipes; charset=utf-8"); !ok {
+		t.Errorf("expected the content type to be registered")
+	}
+
+	data, err := dec.Decode([]byte("a|b\nc|d\n"))
+	if expected := `[{"fields":"a,b"},{"fields":"c,d"}]`; err != nil || string(data) != expected {
+		t.Errorf("expected %s, got %s and %v", expected, data, err)
+	}
+
+	for _, format := range []string{"pipes", "csv", "json", ""} {
+		if !registerPanics(Register, format, pipes) {
+			t.Errorf("expected registering %q to panic", format)
+		}
+	}
+
+	for _, contentType := range []string{"text/x-pipes", "not a content type"} {
+		if !registerPanics(RegisterContentType, contentType, pipes) {
+			t.Errorf("expected registering %q to panic", contentType)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/alpstable/gidari"
 	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
 	"github.com/sirupsen/logrus"
 )
 
@@ -48,7 +49,7 @@ func TestUpsert(t *testing.T) {
 				t.Fatalf("error creating config: %v", err)
 			}
 
-			cfg.Logger = logrus.New()
+			cfg.Logger = tools.NewLogrusLogger(logrus.New())
 
 			// Fill in the authentication details for the fixture.
 			cfgAuth := cfg.Authentication
@@ -15,9 +15,38 @@ import (
 
 	"github.com/alpstable/gidari/config"
 	"github.com/alpstable/gidari/internal/transport"
+	"github.com/alpstable/gidari/tools"
 )
 
-// Transport will construct the transport operation using a "transport.Config" object.
+// PartialError is the error of a run that completed with some of its requests rolled back, which lists the tables,
+// endpoints, and chunks of the requests that failed. The other requests were committed. Use "errors.As" to get it
+// from the error of "Transport".
+type PartialError = transport.PartialError
+
+// RequestFailure is a request that was rolled back in a run that completed with a "PartialError".
+type RequestFailure = transport.RequestFailure
+
+var (
+	// ErrMaxDuration is matched by the error of a run that was wound down because it took longer than the
+	// "MaxDuration" of its configuration.
+	ErrMaxDuration = transport.ErrMaxDuration
+
+	// ErrSchema is matched by the error of a run with a chunk whose records do not have the fields that their
+	// table declares, with the strict decoding mode.
+	ErrSchema = transport.ErrSchema
+
+	// ErrStalled is matched by the error of a run that was wound down because one of its workers made no progress
+	// for the stall period of the "Watchdog" of its configuration.
+	ErrStalled = transport.ErrStalled
+
+	// ErrPanic is matched by the error of a run with a chunk or request that a worker panicked on. The panic is
+	// recovered, so that the other requests of the run are still written.
+	ErrPanic = transport.ErrPanic
+)
+
+// Transport will construct the transport operation using a "transport.Config" object. If the context is cancelled,
+// the writes in flight are given the "DrainTimeout" of the configuration to finish, and the error of the context is
+// returned.
 func Transport(ctx context.Context, cfg *config.Config) error {
 	if err := transport.Upsert(ctx, cfg); err != nil {
 		return fmt.Errorf("unable to upsert the config: %w", err)
@@ -34,7 +63,9 @@ func TransportFile(ctx context.Context, file *os.File) error {
 	}
 
 	// Disable logger
-	cfg.Logger.SetOutput(io.Discard)
+	if logger, ok := cfg.Logger.(*tools.LogrusLogger); ok {
+		logger.SetOutput(io.Discard)
+	}
 
 	if err != nil {
 		return fmt.Errorf("unable to create new config: %w", err)
@@ -0,0 +1,161 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// readHeaderTimeout is how long the server waits for the headers of a request.
+const readHeaderTimeout = 10 * time.Second
+
+// ErrInvalidOptions is returned by NewServer for options that cannot be served.
+var ErrInvalidOptions = errors.New("invalid bench options")
+
+// Options are the synthetic latency and payloads of the mock web API.
+type Options struct {
+	// Latency is how long the server waits before it responds to each request.
+	Latency time.Duration
+
+	// Records is the number of records in each response, and RecordSize is the size of the payload of each record
+	// in bytes.
+	Records    int
+	RecordSize int
+}
+
+// Server is a mock web API on the loopback interface, which responds to every request with a list of synthetic
+// records after the latency of its options, so that a configuration can be run against it to measure the throughput
+// of gidari without a real web API. Every record has a unique "id", so that each of them is inserted.
+type Server struct {
+	opts    Options
+	payload string
+	server  *http.Server
+	url     string
+
+	// seq is the ID of the last record, and requests and bytes are the number of requests that were served and the
+	// size of their responses.
+	seq      int64
+	requests int64
+	bytes    int64
+}
+
+// NewServer will start a mock web API with the options.
+func NewServer(opts Options) (*Server, error) {
+	if opts.Latency < 0 || opts.Records < 0 || opts.RecordSize < 0 {
+		return nil, fmt.Errorf("%w: latency, records, and record size must not be negative", ErrInvalidOptions)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen: %w", err)
+	}
+
+	srv := &Server{
+		opts:    opts,
+		payload: strings.Repeat("x", opts.RecordSize),
+		url:     "http://" + listener.Addr().String(),
+	}
+
+	srv.server = &http.Server{Handler: srv, ReadHeaderTimeout: readHeaderTimeout}
+
+	go func() { _ = srv.server.Serve(listener) }()
+
+	return srv, nil
+}
+
+// URL will return the base URL of the server.
+func (srv *Server) URL() string {
+	return srv.url
+}
+
+// Requests will return the number of requests that the server has responded to.
+func (srv *Server) Requests() int64 {
+	return atomic.LoadInt64(&srv.requests)
+}
+
+// Bytes will return the size of the responses of the server.
+func (srv *Server) Bytes() int64 {
+	return atomic.LoadInt64(&srv.bytes)
+}
+
+// Close will stop the server.
+func (srv *Server) Close() error {
+	if err := srv.server.Close(); err != nil {
+		return fmt.Errorf("unable to close bench server: %w", err)
+	}
+
+	return nil
+}
+
+// ServeHTTP will respond to the request with the records of a response once the latency has passed, or not at all if
+// the request is cancelled first.
+func (srv *Server) ServeHTTP(wtr http.ResponseWriter, req *http.Request) {
+	if srv.opts.Latency > 0 {
+		if err := sleep(req.Context(), srv.opts.Latency); err != nil {
+			return
+		}
+	}
+
+	body := srv.records()
+
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+	if _, err := wtr.Write(body); err != nil {
+		return
+	}
+
+	atomic.AddInt64(&srv.requests, 1)
+	atomic.AddInt64(&srv.bytes, int64(len(body)))
+}
+
+// records will return the JSON list of the records of a response.
+func (srv *Server) records() []byte {
+	first := atomic.AddInt64(&srv.seq, int64(srv.opts.Records)) - int64(srv.opts.Records)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	var body strings.Builder
+
+	body.Grow(srv.opts.Records * (len(srv.payload) + len(now) + 64))
+	body.WriteByte('[')
+
+	for idx := 0; idx < srv.opts.Records; idx++ {
+		if idx > 0 {
+			body.WriteByte(',')
+		}
+
+		id := strconv.FormatInt(first+int64(idx)+1, 10)
+
+		body.WriteString(`{"id":"` + id + `","seq":` + id + `,"time":"` + now + `","payload":"` + srv.payload + `"}`)
+	}
+
+	body.WriteByte(']')
+
+	return []byte(body.String())
+}
+
+// sleep will wait for the duration, or return the error of the context if it is done first.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("bench request cancelled: %w", ctx.Err())
+	}
+}
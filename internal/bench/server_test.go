@@ -0,0 +1,90 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	srv, err := NewServer(Options{Latency: 20 * time.Millisecond, Records: 3, RecordSize: 8})
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	t.Cleanup(func() { srv.Close() })
+
+	var ids []string
+
+	for idx := 0; idx < 2; idx++ {
+		start := time.Now()
+
+		rsp, err := http.Get(srv.URL() + "/candles?granularity=60")
+		if err != nil {
+			t.Fatalf("failed to request: %v", err)
+		}
+
+		body, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+
+		if err != nil {
+			t.Fatalf("failed to read response: %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("expected the response to take the latency, took %v", elapsed)
+		}
+
+		var records []struct {
+			ID      string `json:"id"`
+			Payload string `json:"payload"`
+		}
+
+		if err := json.Unmarshal(body, &records); err != nil {
+			t.Fatalf("failed to decode %s: %v", body, err)
+		}
+
+		if len(records) != 3 {
+			t.Fatalf("expected 3 records, got %s", body)
+		}
+
+		for _, record := range records {
+			if record.Payload != "xxxxxxxx" {
+				t.Errorf("expected a payload of 8 bytes, got %q", record.Payload)
+			}
+
+			ids = append(ids, record.ID)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("expected unique IDs, got %v", ids)
+		}
+
+		seen[id] = true
+	}
+
+	if srv.Requests() != 2 || srv.Bytes() == 0 {
+		t.Errorf("expected 2 requests with their bytes, got %d and %d", srv.Requests(), srv.Bytes())
+	}
+
+	if _, err := NewServer(Options{Records: -1}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected %v, got %v", ErrInvalidOptions, err)
+	}
+}
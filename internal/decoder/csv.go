@@ -0,0 +1,107 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// utf8BOM is the byte order mark that some CSV files start with.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// CSV decodes the rows of a CSV file into records, keyed by the names of the columns. The fields are decoded as
+// strings, and empty fields as empty strings.
+type CSV struct {
+	// Comma is the character that separates the fields of a row.
+	Comma rune
+
+	// Header is whether the first row names the columns.
+	Header bool
+
+	// Columns are the names of the columns, which replace the names of the header, if there is one.
+	Columns []string
+}
+
+// Decode will decode the rows of the CSV file into a JSON list of records. Every row must have as many fields as
+// there are columns.
+func (dec *CSV) Decode(data []byte) ([]byte, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	reader.Comma = dec.Comma
+	reader.ReuseRecord = true
+
+	columns := dec.Columns
+	if len(columns) > 0 {
+		reader.FieldsPerRecord = len(columns)
+	}
+
+	if dec.Header {
+		header, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return []byte("[]"), nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid CSV header: %v", ErrDecode, err)
+		}
+
+		if len(columns) == 0 {
+			columns = append([]string(nil), header...)
+		}
+
+		if err := checkColumns(columns); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]map[string]string, 0)
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid CSV: %v", ErrDecode, err)
+		}
+
+		record := make(map[string]string, len(columns))
+		for idx, column := range columns {
+			record[column] = row[idx]
+		}
+
+		records = append(records, record)
+	}
+
+	out, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	return out, nil
+}
+
+// checkColumns will check that the names of the columns of a header are not empty or duplicated.
+func checkColumns(columns []string) error {
+	seen := make(map[string]bool, len(columns))
+
+	for _, column := range columns {
+		if column == "" || seen[column] {
+			return fmt.Errorf("%w: empty or duplicate CSV column %q", ErrDecode, column)
+		}
+
+		seen[column] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		dec      *CSV
+		data     string
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "header",
+			dec:      &CSV{Comma: ',', Header: true},
+			data:     "\xef\xbb\xbfid,name\n1,\"a, b\"\n2,\n",
+			expected: `[{"id":"1","name":"a, b"},{"id":"2","name":""}]`,
+		},
+		{
+			name:     "columns replace the header",
+			dec:      &CSV{Comma: ';', Header: true, Columns: []string{"x", "y"}},
+			data:     "id;name\r\n1;a\r\n",
+			expected: `[{"x":"1","y":"a"}]`,
+		},
+		{
+			name:     "no header",
+			dec:      &CSV{Comma: '\t', Columns: []string{"x", "y"}},
+			data:     "1\ta\n2\tb",
+			expected: `[{"x":"1","y":"a"},{"x":"2","y":"b"}]`,
+		},
+		{name: "empty", dec: &CSV{Comma: ',', Header: true}, expected: `[]`},
+		{name: "header only", dec: &CSV{Comma: ',', Header: true}, data: "id,name\n", expected: `[]`},
+		{name: "short row", dec: &CSV{Comma: ',', Header: true}, data: "id,name\n1\n", wantErr: ErrDecode},
+		{name: "duplicate column", dec: &CSV{Comma: ',', Header: true}, data: "id,id\n1,2\n", wantErr: ErrDecode},
+		{name: "unclosed quote", dec: &CSV{Comma: ',', Header: true}, data: "id\n\"1\n", wantErr: ErrDecode},
+	} {
+		data, err := tcase.dec.Decode([]byte(tcase.data))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if string(data) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, data)
+		}
+	}
+}
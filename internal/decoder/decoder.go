@@ -0,0 +1,20 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+
+// Package decoder decodes the bodies of responses that are not JSON into JSON lists of records, so that they are
+// transformed and stored like any other response.
+package decoder
+
+import "fmt"
+
+var ErrDecode = fmt.Errorf("failed to decode response")
+
+// Decoder decodes the body of a response into a JSON list of records.
+type Decoder interface {
+	Decode(data []byte) ([]byte, error)
+}
@@ -0,0 +1,311 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// msgpackMaxDepth is the maximum depth of the maps and arrays of a MessagePack value, so that a malicious response
+// cannot exhaust the stack.
+const msgpackMaxDepth = 512
+
+// msgpackTimestamp is the type of the MessagePack extension of timestamps.
+const msgpackTimestamp = -1
+
+// MessagePack decodes MessagePack into records. A response with a single map is a record and one with a single array
+// is a list of records, and a response with several values is a stream of records. Binary values are encoded as base64
+// strings, timestamps as RFC 3339 strings, and the keys of maps that are not strings as their text.
+type MessagePack struct{}
+
+// Decode will decode the MessagePack values of the data into JSON.
+func (dec *MessagePack) Decode(data []byte) ([]byte, error) {
+	reader := &msgpackReader{data: data}
+
+	values := make([]interface{}, 0, 1)
+
+	for reader.pos < len(data) {
+		value, err := reader.value(0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid MessagePack at byte %d: %v", ErrDecode, reader.pos, err)
+		}
+
+		values = append(values, value)
+	}
+
+	var decoded interface{} = values
+	if len(values) == 1 {
+		decoded = values[0]
+	}
+
+	out, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to marshal MessagePack: %v", ErrDecode, err)
+	}
+
+	return out, nil
+}
+
+// msgpackReader reads the values of MessagePack data.
+type msgpackReader struct {
+	data []byte
+	pos  int
+}
+
+// next will return the next n bytes of the data.
+func (reader *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(reader.data)-reader.pos {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	bytes := reader.data[reader.pos : reader.pos+n]
+	reader.pos += n
+
+	return bytes, nil
+}
+
+// uint will read an unsigned big-endian integer of the size, in bytes.
+func (reader *msgpackReader) uint(size int) (uint64, error) {
+	bytes, err := reader.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(bytes[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(bytes)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(bytes)), nil
+	default:
+		return binary.BigEndian.Uint64(bytes), nil
+	}
+}
+
+// length will read a length of the size, in bytes.
+func (reader *msgpackReader) length(size int) (int, error) {
+	length, err := reader.uint(size)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every element has at least one byte, so a length that is longer than the data is invalid.
+	if length > uint64(len(reader.data)-reader.pos) {
+		return 0, fmt.Errorf("length %d is longer than the data", length)
+	}
+
+	return int(length), nil
+}
+
+// value will read the next value of the data, at the depth of the maps and arrays that it is in. The formats are
+// decoded in the order of the MessagePack specification.
+func (reader *msgpackReader) value(depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, fmt.Errorf("values are nested deeper than %d", msgpackMaxDepth)
+	}
+
+	head, err := reader.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	format := head[0]
+
+	switch {
+	case format <= 0x7f:
+		return int64(format), nil
+	case format >= 0xe0:
+		return int64(int8(format)), nil
+	case format <= 0x8f:
+		return reader.mapValue(int(format&0x0f), depth)
+	case format <= 0x9f:
+		return reader.arrayValue(int(format&0x0f), depth)
+	case format <= 0xbf:
+		return reader.str(int(format & 0x1f))
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		return reader.bin(1 << (format - 0xc4))
+	case 0xc7, 0xc8, 0xc9:
+		size, err := reader.length(1 << (format - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return reader.ext(size)
+	case 0xca:
+		bits, err := reader.uint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		// The float is encoded with the precision of a float32, so that it is stored as it was sent.
+		return json.Number(strconv.FormatFloat(float64(math.Float32frombits(uint32(bits))), 'g', -1, 32)), nil
+	case 0xcb:
+		bits, err := reader.uint(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(bits), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return reader.uint(1 << (format - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (format - 0xd0)
+
+		bits, err := reader.uint(size)
+		if err != nil {
+			return nil, err
+		}
+
+		// The integer is sign-extended from its size.
+		shift := 64 - 8*size
+
+		return int64(bits<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return reader.ext(1 << (format - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		size, err := reader.length(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return reader.str(size)
+	case 0xdc, 0xdd:
+		size, err := reader.length(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return reader.arrayValue(size, depth)
+	case 0xde, 0xdf:
+		size, err := reader.length(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return reader.mapValue(size, depth)
+	default:
+		return nil, fmt.Errorf("invalid format 0x%x", format)
+	}
+}
+
+// str will read a string of the size.
+func (reader *msgpackReader) str(size int) (string, error) {
+	bytes, err := reader.next(size)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// bin will read binary data whose length is of the size, in bytes.
+func (reader *msgpackReader) bin(size int) ([]byte, error) {
+	length, err := reader.length(size)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := reader.next(length)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), bytes...), nil
+}
+
+// ext will read an extension with data of the size. Only timestamps are supported.
+func (reader *msgpackReader) ext(size int) (interface{}, error) {
+	head, err := reader.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if extType := int8(head[0]); extType != msgpackTimestamp {
+		return nil, fmt.Errorf("unsupported extension type %d", extType)
+	}
+
+	data, err := reader.next(size)
+	if err != nil {
+		return nil, err
+	}
+
+	var stamp time.Time
+
+	switch size {
+	case 4:
+		stamp = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+	case 8:
+		bits := binary.BigEndian.Uint64(data)
+		stamp = time.Unix(int64(bits&(1<<34-1)), int64(bits>>34))
+	case 12:
+		stamp = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+	default:
+		return nil, fmt.Errorf("invalid timestamp of %d bytes", size)
+	}
+
+	return stamp.UTC().Format(time.RFC3339Nano), nil
+}
+
+// arrayValue will read an array of the size.
+func (reader *msgpackReader) arrayValue(size, depth int) ([]interface{}, error) {
+	array := make([]interface{}, 0, size)
+
+	for idx := 0; idx < size; idx++ {
+		value, err := reader.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		array = append(array, value)
+	}
+
+	return array, nil
+}
+
+// mapValue will read a map of the size, with the keys that are not strings as their text.
+func (reader *msgpackReader) mapValue(size, depth int) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, size)
+
+	for idx := 0; idx < size; idx++ {
+		key, err := reader.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := reader.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := key.(type) {
+		case string:
+			fields[key] = value
+		case []byte:
+			fields[string(key)] = value
+		default:
+			fields[fmt.Sprint(key)] = value
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,87 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMessagePack(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		data     []byte
+		expected string
+		wantErr  error
+	}{
+		{
+			// [{"id": 1, "px": 1.5, "ok": true, "tags": ["a"], "neg": -2, "none": nil}]
+			name: "list",
+			data: []byte{
+				0x91, 0x86,
+				0xa2, 'i', 'd', 0x01,
+				0xa2, 'p', 'x', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+				0xa2, 'o', 'k', 0xc3,
+				0xa4, 't', 'a', 'g', 's', 0x91, 0xa1, 'a',
+				0xa3, 'n', 'e', 'g', 0xfe,
+				0xa4, 'n', 'o', 'n', 'e', 0xc0,
+			},
+			expected: `[{"id":1,"neg":-2,"none":null,"ok":true,"px":1.5,"tags":["a"]}]`,
+		},
+		{
+			// {1: uint16 300, "f": float32 0.1, "b": bin "hi", "i": int16 -300, "s": str8 "x"}
+			name: "sized formats",
+			data: []byte{
+				0x85,
+				0x01, 0xcd, 0x01, 0x2c,
+				0xa1, 'f', 0xca, 0x3d, 0xcc, 0xcc, 0xcd,
+				0xa1, 'b', 0xc4, 0x02, 'h', 'i',
+				0xa1, 'i', 0xd1, 0xfe, 0xd4,
+				0xa1, 's', 0xd9, 0x01, 'x',
+			},
+			expected: `{"1":300,"b":"aGk=","f":0.1,"i":-300,"s":"x"}`,
+		},
+		{
+			// A stream of two maps with timestamps of 32 and 96 bits.
+			name: "stream",
+			data: []byte{
+				0x81, 0xa1, 't', 0xd6, 0xff, 0x00, 0x00, 0x00, 0x3c,
+				0x81, 0xa1, 't', 0xc7, 0x0c, 0xff, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0x3c,
+			},
+			expected: `[{"t":"1970-01-01T00:01:00Z"},{"t":"1970-01-01T00:01:00.000000001Z"}]`,
+		},
+		{name: "empty", data: nil, expected: `[]`},
+		{name: "truncated", data: []byte{0x92, 0x01}, wantErr: ErrDecode},
+		{name: "long string", data: []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}, wantErr: ErrDecode},
+		{name: "unused format", data: []byte{0xc1}, wantErr: ErrDecode},
+		{name: "unsupported extension", data: []byte{0xd4, 0x01, 0x00}, wantErr: ErrDecode},
+	} {
+		data, err := (&MessagePack{}).Decode(tcase.data)
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if string(data) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, data)
+		}
+	}
+
+	// Values that are nested too deeply fail to decode rather than exhaust the stack.
+	nested := make([]byte, msgpackMaxDepth+2)
+	for idx := range nested {
+		nested[idx] = 0x91
+	}
+
+	if _, err := (&MessagePack{}).Decode(nested); !errors.Is(err, ErrDecode) {
+		t.Errorf("expected %v for nested values, got %v", ErrDecode, err)
+	}
+}
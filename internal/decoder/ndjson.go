@@ -0,0 +1,72 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+)
+
+// ndjsonContentTypes are the media types of newline-delimited JSON responses.
+var ndjsonContentTypes = map[string]bool{
+	"application/x-ndjson":     true,
+	"application/ndjson":       true,
+	"application/jsonl":        true,
+	"application/x-jsonl":      true,
+	"application/jsonlines":    true,
+	"application/x-jsonlines":  true,
+	"application/json-lines":   true,
+	"application/x-json-lines": true,
+}
+
+// IsNDJSON will return true if the content type of a response is newline-delimited JSON.
+func IsNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && ndjsonContentTypes[mediaType]
+}
+
+// NDJSON decodes newline-delimited JSON, also known as JSON Lines, with a record on each line. Blank lines are
+// skipped.
+type NDJSON struct{}
+
+// Decode will decode the lines of the data into a JSON list of records.
+func (dec *NDJSON) Decode(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var out bytes.Buffer
+
+	out.WriteByte('[')
+
+	for count := 0; ; count++ {
+		var record json.RawMessage
+
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid NDJSON: %v", ErrDecode, err)
+		}
+
+		if count > 0 {
+			out.WriteByte(',')
+		}
+
+		out.Write(record)
+	}
+
+	out.WriteByte(']')
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNDJSON(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		data     string
+		expected string
+		wantErr  error
+	}{
+		{name: "lines", data: "{\"id\":1}\n\n{\"id\":2}\r\n", expected: `[{"id":1},{"id":2}]`},
+		{name: "no trailing newline", data: `{"id":1}`, expected: `[{"id":1}]`},
+		{name: "empty", data: "", expected: `[]`},
+		{name: "invalid line", data: "{\"id\":1}\n{\"id\":\n", wantErr: ErrDecode},
+	} {
+		data, err := (&NDJSON{}).Decode([]byte(tcase.data))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if string(data) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, data)
+		}
+	}
+
+	for contentType, expected := range map[string]bool{
+		"application/x-ndjson":            true,
+		"application/jsonl; charset=utf8": true,
+		"application/json":                false,
+		"":                                false,
+	} {
+		if IsNDJSON(contentType) != expected {
+			t.Errorf("%q: expected %v", contentType, expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var ErrDescriptor = fmt.Errorf("invalid protobuf descriptor")
+
+// Protobuf decodes protobuf messages into records, with the descriptor of the message. The fields of a record are the
+// fields of the message with the names of the ".proto" file, encoded with the JSON mapping of protobuf, e.g. 64-bit
+// integers as strings.
+type Protobuf struct {
+	message protoreflect.MessageDescriptor
+	types   *protoregistry.Types
+
+	// delimited is whether the data is a stream of messages, each prefixed with its size as a varint.
+	delimited bool
+}
+
+// NewProtobuf will return a decoder of the message with the full name, e.g. "market.v1.Candles", from the serialized
+// "FileDescriptorSet", e.g. the output of "protoc --include_imports --descriptor_set_out". If the messages are
+// delimited, the data is a stream of messages, each prefixed with its size as a varint, and each message is a record.
+// Otherwise the data is a single message, whose records are selected with the selector of the request if they are
+// in a repeated field.
+func NewProtobuf(descriptorSet []byte, message string, delimited bool) (*Protobuf, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(descriptorSet, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDescriptor, err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDescriptor, err)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, fmt.Errorf("%w: message %q: %v", ErrDescriptor, message, err)
+	}
+
+	msgDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a message", ErrDescriptor, message)
+	}
+
+	// The types of the descriptor set resolve the messages of "google.protobuf.Any" fields.
+	types := new(protoregistry.Types)
+
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		for idx := 0; idx < file.Messages().Len(); idx++ {
+			registerMessage(types, file.Messages().Get(idx))
+		}
+
+		return true
+	})
+
+	return &Protobuf{message: msgDesc, types: types, delimited: delimited}, nil
+}
+
+// registerMessage will register the dynamic type of the message, and of the messages nested in it.
+func registerMessage(types *protoregistry.Types, desc protoreflect.MessageDescriptor) {
+	// A message that is registered twice is ignored.
+	_ = types.RegisterMessage(dynamicpb.NewMessageType(desc))
+
+	for idx := 0; idx < desc.Messages().Len(); idx++ {
+		registerMessage(types, desc.Messages().Get(idx))
+	}
+}
+
+// Decode will decode the message, or the stream of delimited messages, into JSON.
+func (dec *Protobuf) Decode(data []byte) ([]byte, error) {
+	if !dec.delimited {
+		return dec.decodeMessage(data)
+	}
+
+	var out bytes.Buffer
+
+	out.WriteByte('[')
+
+	for count := 0; len(data) > 0; count++ {
+		size, n := protowire.ConsumeVarint(data)
+		if n < 0 || size > uint64(len(data)-n) {
+			return nil, fmt.Errorf("%w: invalid size of protobuf message %d", ErrDecode, count)
+		}
+
+		record, err := dec.decodeMessage(data[n : n+int(size)])
+		if err != nil {
+			return nil, err
+		}
+
+		if count > 0 {
+			out.WriteByte(',')
+		}
+
+		out.Write(record)
+
+		data = data[n+int(size):]
+	}
+
+	out.WriteByte(']')
+
+	return out.Bytes(), nil
+}
+
+// decodeMessage will decode a message into a JSON object.
+func (dec *Protobuf) decodeMessage(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(dec.message)
+
+	if err := (proto.UnmarshalOptions{Resolver: dec.types}).Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("%w: invalid protobuf %q: %v", ErrDecode, dec.message.FullName(), err)
+	}
+
+	encoded, err := protojson.MarshalOptions{UseProtoNames: true, Resolver: dec.types}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to marshal protobuf %q: %v", ErrDecode, dec.message.FullName(), err)
+	}
+
+	// The JSON of protobuf is not stable, so the whitespace that it adds is removed.
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, encoded); err != nil {
+		return nil, fmt.Errorf("failed to compact protobuf JSON: %w", err)
+	}
+
+	return compact.Bytes(), nil
+}
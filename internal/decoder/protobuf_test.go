@@ -0,0 +1,164 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// candlesDescriptorSet will return the serialized descriptor set of a "market.Candles" message with a list of
+// "market.Candle" messages.
+func candlesDescriptorSet(t *testing.T) []byte {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type,
+		label descriptorpb.FieldDescriptorProto_Label, typeName string,
+	) *descriptorpb.FieldDescriptorProto {
+		desc := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  label.Enum(),
+		}
+
+		if typeName != "" {
+			desc.TypeName = proto.String(typeName)
+		}
+
+		return desc
+	}
+
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("market.proto"),
+		Package: proto.String("market"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Candle"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("product_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					field("time", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+					field("open", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+				},
+			},
+			{
+				Name: proto.String("Candles"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("candles", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+						descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ".market.Candle"),
+				},
+			},
+		},
+	}}}
+
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+
+	return data
+}
+
+// newCandle will return the serialized candle of the decoder.
+func newCandle(t *testing.T, dec *Protobuf, productID string, time int64, open float64) []byte {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(dec.message)
+	msg.Set(dec.message.Fields().ByName("product_id"), protoreflect.ValueOfString(productID))
+	msg.Set(dec.message.Fields().ByName("time"), protoreflect.ValueOfInt64(time))
+	msg.Set(dec.message.Fields().ByName("open"), protoreflect.ValueOfFloat64(open))
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal candle: %v", err)
+	}
+
+	return data
+}
+
+func TestProtobuf(t *testing.T) {
+	t.Parallel()
+
+	descriptorSet := candlesDescriptorSet(t)
+
+	candle, err := NewProtobuf(descriptorSet, "market.Candle", true)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+
+	first := newCandle(t, candle, "BTC-USD", 1, 1.5)
+	second := newCandle(t, candle, "ETH-USD", 2, 0)
+
+	// The candles are delimited by their sizes.
+	var stream []byte
+	for _, msg := range [][]byte{first, second} {
+		stream = protowire.AppendVarint(stream, uint64(len(msg)))
+		stream = append(stream, msg...)
+	}
+
+	data, err := candle.Decode(stream)
+	if err != nil {
+		t.Fatalf("failed to decode stream: %v", err)
+	}
+
+	expected := `[{"product_id":"BTC-USD","time":"1","open":1.5},{"product_id":"ETH-USD","time":"2"}]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	if _, err := candle.Decode(stream[:len(stream)-1]); !errors.Is(err, ErrDecode) {
+		t.Errorf("expected %v for a truncated stream, got %v", ErrDecode, err)
+	}
+
+	// A single message is decoded as an object, whose records are in a repeated field.
+	candles, err := NewProtobuf(descriptorSet, "market.Candles", false)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, first)
+
+	if data, err = candles.Decode(msg); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+
+	expected = `{"candles":[{"product_id":"BTC-USD","time":"1","open":1.5}]}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	if _, err := candles.Decode([]byte{0xff}); !errors.Is(err, ErrDecode) {
+		t.Errorf("expected %v for an invalid message, got %v", ErrDecode, err)
+	}
+
+	for _, tcase := range []struct {
+		descriptorSet []byte
+		message       string
+	}{
+		{descriptorSet: []byte("not a descriptor"), message: "market.Candle"},
+		{descriptorSet: descriptorSet, message: "market.Trade"},
+		{descriptorSet: descriptorSet, message: "market"},
+	} {
+		if _, err := NewProtobuf(tcase.descriptorSet, tcase.message, false); !errors.Is(err, ErrDescriptor) {
+			t.Errorf("%q: expected %v, got %v", tcase.message, ErrDescriptor, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Formats of responses that are built in.
+const (
+	FormatJSON        = "json"
+	FormatNDJSON      = "ndjson"
+	FormatCSV         = "csv"
+	FormatXML         = "xml"
+	FormatProtobuf    = "protobuf"
+	FormatMessagePack = "msgpack"
+)
+
+var (
+	ErrInvalidFormat    = fmt.Errorf("invalid format")
+	ErrFormatRegistered = fmt.Errorf("format is already registered")
+)
+
+var (
+	registryMutex sync.RWMutex
+
+	// formats are the decoders registered for formats that are not built in, and contentTypes those registered for
+	// the media types of responses.
+	formats      = make(map[string]Decoder)
+	contentTypes = make(map[string]Decoder)
+)
+
+// builtinFormat returns "true" if the format is built in.
+func builtinFormat(format string) bool {
+	switch format {
+	case "", FormatJSON, FormatNDJSON, FormatCSV, FormatXML, FormatProtobuf, FormatMessagePack:
+		return true
+	default:
+		return false
+	}
+}
+
+// RegisterFormat will register the decoder for the responses of requests with the format. Built-in formats cannot be
+// registered, and each format can only be registered once.
+func RegisterFormat(format string, dec Decoder) error {
+	if strings.TrimSpace(format) == "" || dec == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, ok := formats[format]; ok || builtinFormat(format) {
+		return fmt.Errorf("%w: %q", ErrFormatRegistered, format)
+	}
+
+	formats[format] = dec
+
+	return nil
+}
+
+// RegisterContentType will register the decoder for responses with the media type, e.g. "application/vnd.acme+bin",
+// whose parameters are ignored. Each media type can only be registered once.
+func RegisterContentType(contentType string, dec Decoder) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || dec == nil {
+		return fmt.Errorf("%w: content type %q", ErrInvalidFormat, contentType)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, ok := contentTypes[mediaType]; ok {
+		return fmt.Errorf("%w: content type %q", ErrFormatRegistered, mediaType)
+	}
+
+	contentTypes[mediaType] = dec
+
+	return nil
+}
+
+// LookupFormat will return the decoder registered for the format, if there is one.
+func LookupFormat(format string) (Decoder, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	dec, ok := formats[format]
+
+	return dec, ok
+}
+
+// LookupContentType will return the decoder registered for the media type of the content type, if there is one.
+func LookupContentType(contentType string) (Decoder, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	dec, ok := contentTypes[mediaType]
+
+	return dec, ok
+}
+
+// KnownFormat returns "true" if the responses of a request with the format can be decoded, either because the format
+// is built in or because a decoder is registered for it.
+func KnownFormat(format string) bool {
+	if builtinFormat(format) {
+		return true
+	}
+
+	_, ok := LookupFormat(format)
+
+	return ok
+}
@@ -0,0 +1,68 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	dec := &NDJSON{}
+
+	if err := RegisterFormat("registry-test", dec); err != nil {
+		t.Fatalf("failed to register format: %v", err)
+	}
+
+	if found, ok := LookupFormat("registry-test"); !ok || found != dec || !KnownFormat("registry-test") {
+		t.Errorf("expected the registered format, got %v", found)
+	}
+
+	for _, tcase := range []struct {
+		format  string
+		dec     Decoder
+		wantErr error
+	}{
+		{format: "registry-test", dec: dec, wantErr: ErrFormatRegistered},
+		{format: FormatCSV, dec: dec, wantErr: ErrFormatRegistered},
+		{format: "", dec: dec, wantErr: ErrInvalidFormat},
+		{format: "registry-nil", wantErr: ErrInvalidFormat},
+	} {
+		if err := RegisterFormat(tcase.format, tcase.dec); !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.format, tcase.wantErr, err)
+		}
+	}
+
+	if KnownFormat("registry-unknown") || !KnownFormat(FormatXML) {
+		t.Errorf("expected only built-in and registered formats to be known")
+	}
+
+	if err := RegisterContentType("application/vnd.registry-test+bin", dec); err != nil {
+		t.Fatalf("failed to register content type: %v", err)
+	}
+
+	if found, ok := LookupContentType("application/vnd.registry-test+bin; charset=utf-8"); !ok || found != dec {
+		t.Errorf("expected the registered content type, got %v", found)
+	}
+
+	if err := RegisterContentType("application/vnd.registry-test+bin", dec); !errors.Is(err, ErrFormatRegistered) {
+		t.Errorf("expected %v, got %v", ErrFormatRegistered, err)
+	}
+
+	if err := RegisterContentType("not a content type", dec); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected %v, got %v", ErrInvalidFormat, err)
+	}
+
+	if _, ok := LookupContentType("application/json"); ok {
+		t.Errorf("expected no decoder for JSON")
+	}
+}
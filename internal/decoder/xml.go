@@ -0,0 +1,163 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMLText is the field that holds the text of an element that also has attributes or child elements.
+const XMLText = "#text"
+
+// XML decodes the elements of an XML document that are at the record path into records. The attributes and child
+// elements of a record element are its fields, keyed by their local names. A child element with only text is decoded
+// as a string, one with attributes or child elements of its own as an object, and elements that are repeated as a
+// list.
+type XML struct {
+	// RecordPath are the local names of the elements from the root element to the record element, e.g.
+	// ["rss", "channel", "item"]. If it is empty, each child element of the root element is a record.
+	RecordPath []string
+}
+
+// Decode will decode the record elements of the XML document into a JSON list of records, in the order they appear.
+// The document must be encoded in UTF-8.
+func (dec *XML) Decode(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	records := make([]map[string]interface{}, 0)
+
+	// path are the local names of the elements that the decoder is in.
+	var path []string
+
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid XML: %v", ErrDecode, err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			path = append(path, tok.Name.Local)
+			if !dec.isRecord(path) {
+				continue
+			}
+
+			record, err := readElement(decoder, tok)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, record)
+			path = path[:len(path)-1]
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+
+	out, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	return out, nil
+}
+
+// isRecord will return true if the path of an element is the record path.
+func (dec *XML) isRecord(path []string) bool {
+	if len(dec.RecordPath) == 0 {
+		return len(path) == 2
+	}
+
+	if len(path) != len(dec.RecordPath) {
+		return false
+	}
+
+	for idx, name := range dec.RecordPath {
+		if path[idx] != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readElement will read the element that starts with the token into an object of its attributes, its child
+// elements, and its text, if it has any of them.
+func readElement(decoder *xml.Decoder, start xml.StartElement) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+
+		addField(fields, attr.Name.Local, attr.Value)
+	}
+
+	var text strings.Builder
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid XML: %v", ErrDecode, err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := readElement(decoder, tok)
+			if err != nil {
+				return nil, err
+			}
+
+			// A child element with only text is decoded as its text.
+			if value, ok := child[XMLText]; ok && len(child) == 1 {
+				addField(fields, tok.Name.Local, value)
+			} else if len(child) == 0 {
+				addField(fields, tok.Name.Local, "")
+			} else {
+				addField(fields, tok.Name.Local, child)
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			if trimmed := strings.TrimSpace(text.String()); trimmed != "" {
+				fields[XMLText] = trimmed
+			}
+
+			return fields, nil
+		}
+	}
+}
+
+// addField will add the value to the fields, appending it to a list if the field is repeated.
+func addField(fields map[string]interface{}, name string, value interface{}) {
+	existing, ok := fields[name]
+	if !ok {
+		fields[name] = value
+
+		return
+	}
+
+	if list, ok := existing.([]interface{}); ok {
+		fields[name] = append(list, value)
+
+		return
+	}
+
+	fields[name] = []interface{}{existing, value}
+}
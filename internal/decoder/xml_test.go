@@ -0,0 +1,69 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestXML(t *testing.T) {
+	t.Parallel()
+
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Prices</title>
+    <item id="1">
+      <title>BTC</title>
+      <dc:creator>a</dc:creator>
+      <tag>x</tag>
+      <tag>y</tag>
+      <price currency="USD">1.50</price>
+      <empty/>
+    </item>
+    <item id="2">two</item>
+  </channel>
+</rss>`
+
+	for _, tcase := range []struct {
+		name     string
+		dec      *XML
+		data     string
+		expected string
+		wantErr  error
+	}{
+		{
+			name: "record path",
+			dec:  &XML{RecordPath: []string{"rss", "channel", "item"}},
+			data: feed,
+			expected: `[{"creator":"a","empty":"","id":"1","price":{"#text":"1.50","currency":"USD"},` +
+				`"tag":["x","y"],"title":"BTC"},{"#text":"two","id":"2"}]`,
+		},
+		{
+			name:     "children of the root",
+			dec:      &XML{},
+			data:     `<prices><price id="1"/><price id="2"/></prices>`,
+			expected: `[{"id":"1"},{"id":"2"}]`,
+		},
+		{name: "no records", dec: &XML{RecordPath: []string{"rss", "item"}}, data: feed, expected: `[]`},
+		{name: "unclosed", dec: &XML{}, data: `<prices><price>`, wantErr: ErrDecode},
+		{name: "mismatched", dec: &XML{}, data: `<prices><price></prices>`, wantErr: ErrDecode},
+	} {
+		data, err := tcase.dec.Decode([]byte(tcase.data))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if string(data) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, data)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Avro primitive types used by inferred schemas, as defined by the specification:
+// https://avro.apache.org/docs/1.11.1/specification/
+const (
+	avroNull    = "null"
+	avroBoolean = "boolean"
+	avroDouble  = "double"
+	avroString  = "string"
+
+	avroSyncSize = 16
+)
+
+var (
+	ErrInvalidAvroFile = fmt.Errorf("invalid avro file")
+
+	avroMagic = []byte{'O', 'b', 'j', 1}
+
+	// avroInvalidName matches the characters that are not allowed in Avro names.
+	avroInvalidName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// avroField is a field in an Avro record schema. Every field is a union of "null" and a primitive type.
+type avroField struct {
+	Name string        `json:"name"`
+	Type []interface{} `json:"type"`
+}
+
+// avroSchema is an Avro record schema.
+type avroSchema struct {
+	Type   string       `json:"type"`
+	Name   string       `json:"name"`
+	Fields []*avroField `json:"fields"`
+}
+
+// primitive will return the non-null type of the field.
+func (field *avroField) primitive() string {
+	for _, typ := range field.Type {
+		if name, ok := typ.(string); ok && name != avroNull {
+			return name
+		}
+	}
+
+	return avroString
+}
+
+// avroName will convert a string into a valid Avro name.
+func avroName(name string) string {
+	name = avroInvalidName.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// avroEncoder encodes records as an Avro object container file. The schema is a record inferred from the first batch
+// written to an empty file, where each field is a union of "null" and the primitive type of the values in the batch:
+// "boolean", "double" or "string", with nested data written as JSON strings. Subsequent batches are appended using the
+// schema in the file header, fields that are not in the schema are dropped and values that do not match the type of
+// their field are written as null.
+type avroEncoder struct{}
+
+func (avroEncoder) Ext() string { return ".avro" }
+
+func (avroEncoder) Appendable() bool { return true }
+
+// inferAvroSchema will build a record schema from the union of the fields in the records.
+func inferAvroSchema(name string, records []map[string]interface{}) *avroSchema {
+	values := make(map[string][]interface{})
+	for _, record := range records {
+		for key, val := range record {
+			values[avroName(key)] = append(values[avroName(key)], val)
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	schema := &avroSchema{Type: "record", Name: avroName(name)}
+
+	for _, name := range names {
+		typ := avroString
+
+		switch inferParquetType(values[name]) {
+		case parquetBoolean:
+			typ = avroBoolean
+		case parquetDouble:
+			typ = avroDouble
+		}
+
+		schema.Fields = append(schema.Fields, &avroField{Name: name, Type: []interface{}{avroNull, typ}})
+	}
+
+	return schema
+}
+
+// writeAvroLong will write a zigzag-encoded Avro long.
+func writeAvroLong(buf *bytes.Buffer, val int64) {
+	var b [binary.MaxVarintLen64]byte
+
+	buf.Write(b[:binary.PutVarint(b[:], val)])
+}
+
+// writeAvroBytes will write length-prefixed Avro bytes or string.
+func writeAvroBytes(buf *bytes.Buffer, val []byte) {
+	writeAvroLong(buf, int64(len(val)))
+	buf.Write(val)
+}
+
+// readAvroBytes will read length-prefixed Avro bytes or string.
+func readAvroBytes(rdr *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadVarint(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+	}
+
+	val := make([]byte, size)
+	if _, err := io.ReadFull(rdr, val); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+	}
+
+	return val, nil
+}
+
+// readAvroHeader will read the schema and sync marker from the header of an Avro object container file.
+func readAvroHeader(rdr *bufio.Reader) (*avroSchema, []byte, error) {
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(rdr, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return nil, nil, ErrInvalidAvroFile
+	}
+
+	meta := make(map[string][]byte)
+
+	for {
+		count, err := binary.ReadVarint(rdr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+		}
+
+		if count == 0 {
+			break
+		}
+
+		// A negative count is followed by the size of the block in bytes.
+		if count < 0 {
+			count = -count
+
+			if _, err := binary.ReadVarint(rdr); err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+			}
+		}
+
+		for i := int64(0); i < count; i++ {
+			key, err := readAvroBytes(rdr)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if meta[string(key)], err = readAvroBytes(rdr); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	schema := new(avroSchema)
+	if err := json.Unmarshal(meta["avro.schema"], schema); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+	}
+
+	sync := make([]byte, avroSyncSize)
+	if _, err := io.ReadFull(rdr, sync); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAvroFile, err)
+	}
+
+	return schema, sync, nil
+}
+
+// avroHeader will return the encoded header for a new Avro object container file.
+func avroHeader(schema *avroSchema, sync []byte) ([]byte, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal avro schema: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	buf.Write(avroMagic)
+	writeAvroLong(&buf, 2)
+	writeAvroBytes(&buf, []byte("avro.schema"))
+	writeAvroBytes(&buf, schemaJSON)
+	writeAvroBytes(&buf, []byte("avro.codec"))
+	writeAvroBytes(&buf, []byte(avroNull))
+	writeAvroLong(&buf, 0)
+	buf.Write(sync)
+
+	return buf.Bytes(), nil
+}
+
+// encodeAvroValue will write the union branch and value for a field.
+func encodeAvroValue(buf *bytes.Buffer, typ string, val interface{}) error {
+	switch val := val.(type) {
+	case bool:
+		if typ == avroBoolean {
+			writeAvroLong(buf, 1)
+
+			if val {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+
+			return nil
+		}
+	case float64:
+		if typ == avroDouble {
+			var b [8]byte
+
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(val))
+			writeAvroLong(buf, 1)
+			buf.Write(b[:])
+
+			return nil
+		}
+	}
+
+	if typ != avroString || val == nil {
+		writeAvroLong(buf, 0)
+
+		return nil
+	}
+
+	cell, err := csvCell(val)
+	if err != nil {
+		return err
+	}
+
+	writeAvroLong(buf, 1)
+	writeAvroBytes(buf, []byte(cell))
+
+	return nil
+}
+
+func (avroEncoder) Encode(file *os.File, records []map[string]interface{}) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat file: %w", err)
+	}
+
+	var (
+		buf    bytes.Buffer
+		schema *avroSchema
+		sync   []byte
+	)
+
+	if info.Size() == 0 {
+		table := strings.TrimSuffix(filepath.Base(file.Name()), avroEncoder{}.Ext())
+		schema = inferAvroSchema(table, records)
+
+		sync = make([]byte, avroSyncSize)
+		if _, err := rand.Read(sync); err != nil {
+			return fmt.Errorf("unable to generate avro sync marker: %w", err)
+		}
+
+		header, err := avroHeader(schema, sync)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(header)
+	} else {
+		rdr := bufio.NewReader(io.NewSectionReader(file, 0, info.Size()))
+		if schema, sync, err = readAvroHeader(rdr); err != nil {
+			return err
+		}
+	}
+
+	var block bytes.Buffer
+
+	for _, record := range records {
+		fields := make(map[string]interface{}, len(record))
+		for key, val := range record {
+			fields[avroName(key)] = val
+		}
+
+		for _, field := range schema.Fields {
+			if err := encodeAvroValue(&block, field.primitive(), fields[field.Name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	writeAvroLong(&buf, int64(len(records)))
+	writeAvroLong(&buf, int64(block.Len()))
+	buf.Write(block.Bytes())
+	buf.Write(sync)
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("unable to write avro file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,638 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ModeAppend will append records to the end of a table file, creating the file if it does not exist.
+	ModeAppend = "append"
+
+	// ModeReplace will overwrite a table file the first time it is written to by a storage instance, all subsequent
+	// writes from the same instance are appended.
+	ModeReplace = "replace"
+
+	// stageBatchSize is the number of staged records to encode at a time when committing a transaction.
+	stageBatchSize = 1000
+)
+
+var (
+	ErrInvalidFormat       = fmt.Errorf("invalid file format")
+	ErrInvalidMode         = fmt.Errorf("invalid file mode")
+	ErrInvalidPartition    = fmt.Errorf("partitioning requires a part file format")
+	ErrInvalidTable        = fmt.Errorf("invalid table name")
+	ErrNotADirectory       = fmt.Errorf("not a directory")
+	ErrTransactionNotFound = fmt.Errorf("transaction not found")
+)
+
+// fileTxType is a type alias for the file transaction type.
+type fileTxType uint8
+
+const (
+	basicFileTxID fileTxType = iota
+)
+
+// stage holds the temporary files that buffer the writes of a transaction until it is committed. Staged records are
+// always stored as newline-delimited JSON and encoded into the storage format on commit.
+type stage struct {
+	mutex sync.Mutex
+	files map[string]*os.File
+
+	// truncated are the tables truncated within the transaction, which are emptied on commit before the staged
+	// records are written.
+	truncated map[string]bool
+}
+
+// File is a storage device that writes one file per table into a local directory.
+type File struct {
+	dir  string
+	mode string
+	enc  Encoder
+
+	// partitionBy is the date column used to partition part files, if any.
+	partitionBy string
+
+	writeMutex sync.Mutex
+
+	// replaced are the tables that have already been overwritten by this instance in "replace" mode.
+	replaced map[string]bool
+
+	// activeTx are the transactions that are currently active on this storage device. Writes made within the
+	// context of a transaction are staged in temporary files until the transaction is committed.
+	activeTx sync.Map
+}
+
+// New will return a new File storage device. The connection string is of the form
+//
+//	file:///path/to/dir?mode=append&format=ndjson&partitionBy=column
+//
+// where "mode" is optional and one of "append" (default) or "replace", and "format" is optional and one of "ndjson"
+// (default), "csv", "parquet" or "avro". Formats that cannot be appended to, such as "parquet", are written as a
+// directory of part files per table, which can optionally be partitioned by the date of the "partitionBy" column. The
+// directory is created if it does not already exist.
+func New(_ context.Context, dns string) (*File, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse file connection string: %w", err)
+	}
+
+	// Relative paths such as "file://data/out" are parsed with the first segment as the host.
+	dir := filepath.FromSlash(uri.Host + uri.Path)
+	if dir == "" {
+		dir = "."
+	}
+
+	mode := uri.Query().Get("mode")
+	if mode == "" {
+		mode = ModeAppend
+	}
+
+	if mode != ModeAppend && mode != ModeReplace {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMode, mode)
+	}
+
+	enc, err := NewEncoder(uri.Query().Get("format"))
+	if err != nil {
+		return nil, err
+	}
+
+	partitionBy := uri.Query().Get("partitionBy")
+	if partitionBy != "" && enc.Appendable() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPartition, enc.Ext())
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create directory: %w", err)
+	}
+
+	return &File{
+		dir:         dir,
+		mode:        mode,
+		enc:         enc,
+		partitionBy: partitionBy,
+		replaced:    make(map[string]bool),
+		activeTx:    sync.Map{},
+	}, nil
+}
+
+// Close is a no-op for file storage since no file handles are held between writes.
+func (f *File) Close() {}
+
+// IsNoSQL returns "true" since table files are schemaless.
+func (f *File) IsNoSQL() bool { return true }
+
+// Type returns the type of storage.
+func (f *File) Type() uint8 { return proto.FileType }
+
+// tablePath returns the path of the file that holds the data for a table, or the path of the directory that holds the
+// part files for a table if the format cannot be appended to. The table name is validated first, since the path is
+// written to, truncated, and removed.
+func (f *File) tablePath(table string) (string, error) {
+	if err := validateTable(table); err != nil {
+		return "", err
+	}
+
+	if !f.enc.Appendable() {
+		return filepath.Join(f.dir, table), nil
+	}
+
+	return filepath.Join(f.dir, table+f.enc.Ext()), nil
+}
+
+// validateTable will return an error if the table name cannot be the name of a file in the storage directory, so
+// that a table can never be written outside of it.
+func validateTable(table string) error {
+	if table == "" || table == "." || strings.Contains(table, "..") || strings.ContainsAny(table, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidTable, table)
+	}
+
+	return nil
+}
+
+// openTable will open the file for a table according to the storage mode.
+func (f *File) openTable(table string) (*os.File, error) {
+	path, err := f.tablePath(table)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := os.O_CREATE | os.O_RDWR | os.O_APPEND
+
+	if f.mode == ModeReplace && !f.replaced[table] {
+		flags = os.O_CREATE | os.O_RDWR | os.O_TRUNC
+		f.replaced[table] = true
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open table file: %w", err)
+	}
+
+	return file, nil
+}
+
+// writeParts will write the records as new part files in the table directory, one per partition.
+func (f *File) writeParts(table string, records []map[string]interface{}) error {
+	path, err := f.tablePath(table)
+	if err != nil {
+		return err
+	}
+
+	if f.mode == ModeReplace && !f.replaced[table] {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("unable to replace table directory: %w", err)
+		}
+
+		f.replaced[table] = true
+	}
+
+	for partition, partRecords := range partitionRecords(f.partitionBy, records) {
+		dir := filepath.Join(path, filepath.FromSlash(partition))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("unable to create partition directory: %w", err)
+		}
+
+		name := filepath.Join(dir, fmt.Sprintf("part-%s%s", uuid.New().String(), f.enc.Ext()))
+
+		file, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("unable to create part file: %w", err)
+		}
+
+		err = f.enc.Encode(file, partRecords)
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTable will write the records to the table in the storage format.
+func (f *File) writeTable(table string, records []map[string]interface{}) error {
+	if !f.enc.Appendable() {
+		return f.writeParts(table, records)
+	}
+
+	file, err := f.openTable(table)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return f.enc.Encode(file, records)
+}
+
+// recordMaps will convert the records into a slice of maps for encoding.
+func recordMaps(records []*structpb.Struct) []map[string]interface{} {
+	maps := make([]map[string]interface{}, len(records))
+	for idx, record := range records {
+		maps[idx] = record.AsMap()
+	}
+
+	return maps
+}
+
+// getStage will return the transaction stage assigned to the context, if there is one.
+func (f *File) getStage(ctx context.Context) (*stage, error) {
+	txID, ok := ctx.Value(basicFileTxID).(string)
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := f.activeTx.Load(txID)
+	if !ok {
+		return nil, nil
+	}
+
+	stg, ok := val.(*stage)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return stg, nil
+}
+
+// write will write the records to the table file, or to the transaction stage if the context has one.
+func (f *File) write(ctx context.Context, table string, records []*structpb.Struct) error {
+	if err := validateTable(table); err != nil {
+		return err
+	}
+
+	stg, err := f.getStage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stg != nil {
+		stg.mutex.Lock()
+		defer stg.mutex.Unlock()
+
+		tmp, ok := stg.files[table]
+		if !ok {
+			tmp, err = os.CreateTemp(f.dir, fmt.Sprintf(".%s-*.tmp", table))
+			if err != nil {
+				return fmt.Errorf("unable to create stage file: %w", err)
+			}
+
+			stg.files[table] = tmp
+		}
+
+		return ndjson{}.Encode(tmp, recordMaps(records))
+	}
+
+	return f.writeTable(table, recordMaps(records))
+}
+
+// Upsert will append the records on the request to the table file. Records are never matched since files have no
+// notion of a primary key.
+func (f *File) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	records, err := proto.DecodeUpsertRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if len(records) == 0 {
+		return &proto.UpsertResponse{}, nil
+	}
+
+	if err := f.write(ctx, req.GetTable(), records); err != nil {
+		return nil, fmt.Errorf("unable to write records: %w", err)
+	}
+
+	return &proto.UpsertResponse{
+		UpsertedCount: int64(len(records)),
+		ReceivedCount: int64(len(records)),
+		InsertedCount: int64(len(records)),
+	}, nil
+}
+
+// UpsertBinary will write the "property bag" records on the request to the table file.
+func (f *File) UpsertBinary(ctx context.Context,
+	req *proto.UpsertBinaryRequest,
+) (*proto.UpsertBinaryResponse, error) {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	records, err := proto.DecodeUpsertBinaryRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if len(records) == 0 {
+		return &proto.UpsertBinaryResponse{}, nil
+	}
+
+	if err := f.write(ctx, req.GetTable(), records); err != nil {
+		return nil, fmt.Errorf("unable to write records: %w", err)
+	}
+
+	return &proto.UpsertBinaryResponse{}, nil
+}
+
+// truncateTable will empty the file for the table.
+func (f *File) truncateTable(table string) error {
+	path, err := f.tablePath(table)
+	if err != nil {
+		return err
+	}
+
+	if !f.enc.Appendable() {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("unable to truncate table directory %s: %w", table, err)
+		}
+
+		return nil
+	}
+
+	if err := os.Truncate(path, 0); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to truncate table file %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// Truncate will empty the files for the tables on the request. Within a transaction the tables are emptied on
+// commit, and any records staged for them before the truncate are discarded.
+func (f *File) Truncate(ctx context.Context, req *proto.TruncateRequest) (*proto.TruncateResponse, error) {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	stg, err := f.getStage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range req.GetTables() {
+		if err := validateTable(table); err != nil {
+			return nil, err
+		}
+
+		if stg != nil {
+			stg.truncate(table)
+
+			continue
+		}
+
+		if err := f.truncateTable(table); err != nil {
+			return nil, err
+		}
+	}
+
+	return &proto.TruncateResponse{}, nil
+}
+
+// ListTables will return the table files in the storage directory along with their size in bytes.
+func (f *File) ListTables(_ context.Context) (*proto.ListTablesResponse, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory: %w", err)
+	}
+
+	rsp := &proto.ListTablesResponse{TableSet: make(map[string]*proto.Table)}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if !f.enc.Appendable() {
+			if !entry.IsDir() {
+				continue
+			}
+
+			size, err := f.partsSize(filepath.Join(f.dir, name))
+			if err != nil {
+				return nil, err
+			}
+
+			rsp.TableSet[name] = &proto.Table{Size: size}
+
+			continue
+		}
+
+		if entry.IsDir() || filepath.Ext(name) != f.enc.Ext() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("unable to stat table file: %w", err)
+		}
+
+		rsp.TableSet[strings.TrimSuffix(name, f.enc.Ext())] = &proto.Table{Size: info.Size()}
+	}
+
+	return rsp, nil
+}
+
+// partsSize will return the total size in bytes of the part files in a table directory.
+func (f *File) partsSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || filepath.Ext(path) != f.enc.Ext() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("unable to stat part file: %w", err)
+		}
+
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to walk table directory: %w", err)
+	}
+
+	return size, nil
+}
+
+// ListPrimaryKeys will return an empty list of primary keys for every table file, since files have no notion of
+// primary keys.
+func (f *File) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeysResponse, error) {
+	tables, err := f.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+
+	rsp := &proto.ListPrimaryKeysResponse{PKSet: make(map[string]*proto.PrimaryKeys)}
+	for table := range tables.GetTableSet() {
+		rsp.PKSet[table] = &proto.PrimaryKeys{}
+	}
+
+	return rsp, nil
+}
+
+// commitStage will copy the staged data for each table onto the table files and remove the stage files.
+func (f *File) commitStage(stg *stage) error {
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	for table := range stg.truncated {
+		if err := f.truncateTable(table); err != nil {
+			return err
+		}
+	}
+
+	for table, tmp := range stg.files {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("unable to seek stage file: %w", err)
+		}
+
+		if err := f.commitStageFile(table, tmp); err != nil {
+			return fmt.Errorf("unable to commit stage file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// commitStageFile will decode the staged records in batches and write them to the table.
+func (f *File) commitStageFile(table string, tmp io.Reader) error {
+	dec := json.NewDecoder(tmp)
+	batch := make([]map[string]interface{}, 0, stageBatchSize)
+
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			return fmt.Errorf("unable to decode staged record: %w", err)
+		}
+
+		batch = append(batch, record)
+		if len(batch) < stageBatchSize {
+			continue
+		}
+
+		if err := f.writeTable(table, batch); err != nil {
+			return err
+		}
+
+		batch = make([]map[string]interface{}, 0, stageBatchSize)
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return f.writeTable(table, batch)
+}
+
+// truncate will mark the table as truncated and discard the records staged for it.
+func (stg *stage) truncate(table string) {
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	if tmp, ok := stg.files[table]; ok {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		delete(stg.files, table)
+	}
+
+	stg.truncated[table] = true
+}
+
+// discardStage will close and remove all of the stage files.
+func discardStage(stg *stage) {
+	for _, tmp := range stg.files {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+}
+
+// StartTx will start a transaction on the storage directory. Writes sent to the transaction are staged in temporary
+// files, which are appended to the table files on commit and discarded on rollback. Tables truncated in the
+// transaction are emptied on commit, before the staged records are appended.
+func (f *File) StartTx(ctx context.Context) (*proto.Txn, error) {
+	txn := &proto.Txn{
+		FunctionCh: make(chan proto.TxnChanFn),
+		DoneCh:     make(chan error, 1),
+		CommitCh:   make(chan bool, 1),
+	}
+
+	txnID := uuid.New().String()
+	stg := &stage{files: make(map[string]*os.File), truncated: make(map[string]bool)}
+
+	f.activeTx.Store(txnID, stg)
+
+	// Create a copy of the parent context with a transaction ID.
+	fileCtx := context.WithValue(ctx, basicFileTxID, txnID)
+
+	go func() {
+		defer func() {
+			f.activeTx.Delete(txnID)
+			discardStage(stg)
+		}()
+
+		var err error
+
+		for fn := range txn.FunctionCh {
+			if err != nil {
+				continue
+			}
+
+			err = fn(fileCtx, f)
+		}
+
+		if err != nil {
+			txn.DoneCh <- err
+
+			return
+		}
+
+		if <-txn.CommitCh {
+			txn.DoneCh <- f.commitStage(stg)
+		} else {
+			txn.DoneCh <- nil
+		}
+	}()
+
+	return txn, nil
+}
+
+// Ping will return an error if the storage directory is no longer accessible.
+func (f *File) Ping() error {
+	info, err := os.Stat(f.dir)
+	if err != nil {
+		return fmt.Errorf("unable to stat directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s", ErrNotADirectory, f.dir)
+	}
+
+	return nil
+}
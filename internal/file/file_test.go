@@ -0,0 +1,376 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func readTable(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("failed to read table file: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("append", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		for i := 0; i < 2; i++ {
+			stg, err := New(ctx, "file://"+dir)
+			if err != nil {
+				t.Fatalf("failed to create file storage: %v", err)
+			}
+
+			rsp, err := stg.Upsert(ctx, &proto.UpsertRequest{
+				Table: "tests1",
+				Data:  []byte(`[{"id":"1"},{"id":"2"}]`),
+			})
+			if err != nil {
+				t.Fatalf("failed to upsert: %v", err)
+			}
+
+			if rsp.UpsertedCount != 2 {
+				t.Fatalf("expected upserted count to be 2, got %d", rsp.UpsertedCount)
+			}
+
+			if rsp.ReceivedCount != 2 || rsp.InsertedCount != 2 {
+				t.Fatalf("expected 2 records received and inserted, got %d and %d", rsp.ReceivedCount,
+					rsp.InsertedCount)
+			}
+		}
+
+		expected := "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"1\"}\n{\"id\":\"2\"}\n"
+		if got := readTable(t, dir, "tests1.ndjson"); got != expected {
+			t.Fatalf("expected %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		for _, data := range []string{`{"id":"1"}`, `{"id":"2"}`} {
+			stg, err := New(ctx, fmt.Sprintf("file://%s?mode=replace", dir))
+			if err != nil {
+				t.Fatalf("failed to create file storage: %v", err)
+			}
+
+			if _, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: "tests1", Data: []byte(data)}); err != nil {
+				t.Fatalf("failed to upsert: %v", err)
+			}
+		}
+
+		if got := readTable(t, dir, "tests1.ndjson"); got != "{\"id\":\"2\"}\n" {
+			t.Fatalf("expected only the last write, got %q", got)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		stg, err := New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		for _, rollback := range []bool{true, false} {
+			txn, err := stg.StartTx(ctx)
+			if err != nil {
+				t.Fatalf("failed to start transaction: %v", err)
+			}
+
+			txn.Send(func(sctx context.Context, stg proto.Storage) error {
+				_, err := stg.Upsert(sctx, &proto.UpsertRequest{
+					Table: "tests1",
+					Data:  []byte(fmt.Sprintf(`{"rollback":%v}`, rollback)),
+				})
+
+				return err
+			})
+
+			if rollback {
+				err = txn.Rollback()
+			} else {
+				err = txn.Commit()
+			}
+
+			if err != nil {
+				t.Fatalf("failed to resolve transaction: %v", err)
+			}
+		}
+
+		if got := readTable(t, dir, "tests1.ndjson"); got != "{\"rollback\":false}\n" {
+			t.Fatalf("expected only the committed write, got %q", got)
+		}
+
+		tables, err := stg.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+
+		if len(tables.GetTableSet()) != 1 {
+			t.Fatalf("expected stage files to be removed, got tables %v", tables.GetTableSet())
+		}
+
+		if _, err := stg.Truncate(ctx, &proto.TruncateRequest{Tables: []string{"tests1"}}); err != nil {
+			t.Fatalf("failed to truncate: %v", err)
+		}
+
+		if got := readTable(t, dir, "tests1.ndjson"); got != "" {
+			t.Fatalf("expected empty table after truncate, got %q", got)
+		}
+	})
+	t.Run("truncate in transaction", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		stg, err := New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		if _, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: "tests1", Data: []byte(`{"id":"1"}`)}); err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		for _, rollback := range []bool{true, false} {
+			txn, err := stg.StartTx(ctx)
+			if err != nil {
+				t.Fatalf("failed to start transaction: %v", err)
+			}
+
+			txn.Send(func(sctx context.Context, stg proto.Storage) error {
+				for _, data := range []string{`{"id":"2"}`, "", `{"id":"3"}`} {
+					if data == "" {
+						if _, err := stg.Truncate(sctx, &proto.TruncateRequest{Tables: []string{"tests1"}}); err != nil {
+							return err
+						}
+
+						continue
+					}
+
+					if _, err := stg.Upsert(sctx, &proto.UpsertRequest{Table: "tests1", Data: []byte(data)}); err != nil {
+						return err
+					}
+				}
+
+				// The table is only truncated on commit.
+				if got := readTable(t, dir, "tests1.ndjson"); got != "{\"id\":\"1\"}\n" {
+					t.Errorf("expected the table to be unchanged, got %q", got)
+				}
+
+				return nil
+			})
+
+			if rollback {
+				err = txn.Rollback()
+			} else {
+				err = txn.Commit()
+			}
+
+			if err != nil {
+				t.Fatalf("failed to resolve transaction: %v", err)
+			}
+
+			if got := readTable(t, dir, "tests1.ndjson"); rollback && got != "{\"id\":\"1\"}\n" {
+				t.Fatalf("expected the table to be unchanged after rollback, got %q", got)
+			}
+		}
+
+		if got := readTable(t, dir, "tests1.ndjson"); got != "{\"id\":\"3\"}\n" {
+			t.Fatalf("expected only the records written after the truncate, got %q", got)
+		}
+	})
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		stg, err := New(ctx, fmt.Sprintf("file://%s?format=csv", dir))
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		for _, data := range []string{
+			`[{"id":"1","price":1.5},{"id":"2","tags":["a"]}]`,
+			`{"id":"3","price":2,"extra":true}`,
+		} {
+			if _, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: "tests1", Data: []byte(data)}); err != nil {
+				t.Fatalf("failed to upsert: %v", err)
+			}
+		}
+
+		expected := "id,price,tags\n1,1.5,\n2,,\"[\"\"a\"\"]\"\n3,2,\n"
+		if got := readTable(t, dir, "tests1.csv"); got != expected {
+			t.Fatalf("expected %q, got %q", expected, got)
+		}
+	})
+	t.Run("parquet", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		stg, err := New(ctx, fmt.Sprintf("file://%s?format=parquet&partitionBy=time", dir))
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		_, err = stg.Upsert(ctx, &proto.UpsertRequest{
+			Table: "candles",
+			Data:  []byte(`[{"time":"2022-05-10T01:00:00Z","close":1.5},{"time":"2022-05-11T01:00:00Z","close":2}]`),
+		})
+		if err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		for _, partition := range []string{"time=2022-05-10", "time=2022-05-11"} {
+			parts, err := filepath.Glob(filepath.Join(dir, "candles", partition, "part-*.parquet"))
+			if err != nil || len(parts) != 1 {
+				t.Fatalf("expected one part file in %s, got %v: %v", partition, parts, err)
+			}
+
+			data := readTable(t, dir, filepath.Join("candles", partition, filepath.Base(parts[0])))
+			if !strings.HasPrefix(data, "PAR1") || !strings.HasSuffix(data, "PAR1") {
+				t.Fatalf("expected parquet magic bytes in %s", parts[0])
+			}
+		}
+
+		tables, err := stg.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+
+		if tables.GetTableSet()["candles"].GetSize() == 0 {
+			t.Fatalf("expected table size to be greater than zero")
+		}
+	})
+
+	t.Run("partition requires part files", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := New(ctx, fmt.Sprintf("file://%s?partitionBy=time", t.TempDir())); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	})
+	t.Run("avro", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		stg, err := New(ctx, fmt.Sprintf("file://%s?format=avro", dir))
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		for _, data := range []string{`{"id":"1","price":1.5,"live":true}`, `{"id":"2","extra-field":1}`} {
+			if _, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: "tests1", Data: []byte(data)}); err != nil {
+				t.Fatalf("failed to upsert: %v", err)
+			}
+		}
+
+		rdr := bufio.NewReader(strings.NewReader(readTable(t, dir, "tests1.avro")))
+
+		schema, _, err := readAvroHeader(rdr)
+		if err != nil {
+			t.Fatalf("failed to read avro header: %v", err)
+		}
+
+		expected := []string{"id:string", "live:boolean", "price:double"}
+		if len(schema.Fields) != len(expected) {
+			t.Fatalf("expected %d fields, got %d", len(expected), len(schema.Fields))
+		}
+
+		for idx, field := range schema.Fields {
+			if got := field.Name + ":" + field.primitive(); got != expected[idx] {
+				t.Fatalf("expected field %s, got %s", expected[idx], got)
+			}
+		}
+	})
+	t.Run("invalid table", func(t *testing.T) {
+		t.Parallel()
+
+		root := t.TempDir()
+		dir := filepath.Join(root, "data")
+
+		stg, err := New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		for _, table := range []string{"", ".", "../escaped", "nested/table", `nested\table`} {
+			_, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: table, Data: []byte(`[{"id":"1"}]`)})
+			if !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("expected %v for table %q, got %v", ErrInvalidTable, table, err)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(root, "escaped.ndjson")); !os.IsNotExist(err) {
+			t.Errorf("expected no file outside of the storage directory, got %v", err)
+		}
+	})
+	t.Run("truncate invalid table", func(t *testing.T) {
+		t.Parallel()
+
+		root := t.TempDir()
+		dir := filepath.Join(root, "data")
+
+		sibling := filepath.Join(root, "sibling")
+		if err := os.MkdirAll(sibling, 0o755); err != nil {
+			t.Fatalf("failed to create sibling directory: %v", err)
+		}
+
+		stg, err := New(ctx, fmt.Sprintf("file://%s?format=parquet&mode=replace", dir))
+		if err != nil {
+			t.Fatalf("failed to create file storage: %v", err)
+		}
+
+		for _, table := range []string{"", ".", "..", "../sibling"} {
+			_, err := stg.Truncate(ctx, &proto.TruncateRequest{Tables: []string{table}})
+			if !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("expected %v for table %q, got %v", ErrInvalidTable, table, err)
+			}
+
+			if err := stg.writeParts(table, []map[string]interface{}{{"id": "1"}}); !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("expected %v when replacing table %q, got %v", ErrInvalidTable, table, err)
+			}
+		}
+
+		for _, path := range []string{dir, sibling} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("expected %s to be kept, got %v", path, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+const (
+	// FormatNDJSON writes one JSON document per line.
+	FormatNDJSON = "ndjson"
+
+	// FormatCSV writes comma-separated values with a header row.
+	FormatCSV = "csv"
+
+	// FormatParquet writes a Parquet part file per batch.
+	FormatParquet = "parquet"
+
+	// FormatAvro writes an Avro object container file with an inferred schema.
+	FormatAvro = "avro"
+)
+
+// Encoder will write records to a table file in a specific format. The file is opened for reading and appending, so
+// that encoders can inspect data that has already been written, such as a header.
+type Encoder interface {
+	// Ext is the file extension for the format, including the leading dot.
+	Ext() string
+
+	// Encode will write the records to the end of the file.
+	Encode(file *os.File, records []map[string]interface{}) error
+
+	// Appendable is false for formats that cannot be appended to once written, such as columnar formats. Tables
+	// in these formats are written as a directory of immutable part files.
+	Appendable() bool
+}
+
+// NewEncoder will return the encoder for a format name, defaulting to newline-delimited JSON.
+func NewEncoder(format string) (Encoder, error) {
+	switch format {
+	case "", FormatNDJSON:
+		return ndjson{}, nil
+	case FormatCSV:
+		return csvEncoder{}, nil
+	case FormatParquet:
+		return parquetEncoder{}, nil
+	case FormatAvro:
+		return avroEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFormat, format)
+	}
+}
+
+// ndjson encodes records as newline-delimited JSON.
+type ndjson struct{}
+
+func (ndjson) Ext() string { return ".ndjson" }
+
+func (ndjson) Appendable() bool { return true }
+
+func (ndjson) Encode(file *os.File, records []map[string]interface{}) error {
+	enc := json.NewEncoder(file)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("unable to encode record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// csvEncoder encodes records as CSV. The header is derived from the sorted union of the fields in the first batch
+// written to an empty file. Subsequent batches reuse the header already in the file, fields that are not in the header
+// are dropped and missing fields are written as empty cells.
+type csvEncoder struct{}
+
+func (csvEncoder) Ext() string { return ".csv" }
+
+func (csvEncoder) Appendable() bool { return true }
+
+// csvHeader will read the header row from the file, or return nil if the file is empty.
+func csvHeader(file *os.File) ([]string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to stat file: %w", err)
+	}
+
+	if info.Size() == 0 {
+		return nil, nil
+	}
+
+	header, err := csv.NewReader(io.NewSectionReader(file, 0, info.Size())).Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read csv header: %w", err)
+	}
+
+	return header, nil
+}
+
+// csvCell will format a record value as a CSV cell. Nested objects and arrays are written as JSON.
+func csvCell(val interface{}) (string, error) {
+	switch val := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	default:
+		bytes, err := json.Marshal(val)
+		if err != nil {
+			return "", fmt.Errorf("unable to marshal cell: %w", err)
+		}
+
+		return string(bytes), nil
+	}
+}
+
+func (csvEncoder) Encode(file *os.File, records []map[string]interface{}) error {
+	header, err := csvHeader(file)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+
+	if header == nil {
+		fields := make(map[string]bool)
+		for _, record := range records {
+			for field := range record {
+				fields[field] = true
+			}
+		}
+
+		for field := range fields {
+			header = append(header, field)
+		}
+
+		sort.Strings(header)
+
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("unable to write csv header: %w", err)
+		}
+	}
+
+	row := make([]string, len(header))
+
+	for _, record := range records {
+		for idx, field := range header {
+			if row[idx], err = csvCell(record[field]); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("unable to write csv row: %w", err)
+		}
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("unable to flush csv: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Parquet physical types, repetition types, encodings and converted types as defined by the format specification:
+// https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUTF8 = 0
+
+	parquetDataPage = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetEncoder encodes records as a Parquet file with a single row group. Every column is optional, and the
+// physical type of a column is inferred from the batch: columns with only boolean values are BOOLEAN, columns with
+// only numeric values are DOUBLE, and all other columns are UTF8 strings, with nested data written as JSON. Parquet
+// files cannot be appended to, so each batch is written as a new part file.
+type parquetEncoder struct{}
+
+func (parquetEncoder) Ext() string { return ".parquet" }
+
+func (parquetEncoder) Appendable() bool { return false }
+
+// parquetColumn is the data for a single column chunk.
+type parquetColumn struct {
+	name   string
+	ptype  int32
+	values []interface{}
+}
+
+// inferParquetType will return the physical type that can hold all of the non-nil values.
+func inferParquetType(values []interface{}) int32 {
+	var bools, doubles, others int
+
+	for _, val := range values {
+		switch val.(type) {
+		case nil:
+		case bool:
+			bools++
+		case float64:
+			doubles++
+		default:
+			others++
+		}
+	}
+
+	switch {
+	case bools > 0 && doubles == 0 && others == 0:
+		return parquetBoolean
+	case doubles > 0 && bools == 0 && others == 0:
+		return parquetDouble
+	default:
+		return parquetByteArray
+	}
+}
+
+// parquetColumns will pivot the records into sorted columns.
+func parquetColumns(records []map[string]interface{}) []*parquetColumn {
+	fields := make(map[string]bool)
+	for _, record := range records {
+		for field := range record {
+			fields[field] = true
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+
+	sort.Strings(names)
+
+	columns := make([]*parquetColumn, len(names))
+
+	for idx, name := range names {
+		values := make([]interface{}, len(records))
+		for row, record := range records {
+			values[row] = record[name]
+		}
+
+		columns[idx] = &parquetColumn{name: name, ptype: inferParquetType(values), values: values}
+	}
+
+	return columns
+}
+
+// rleDefinitionLevels will encode the definition levels of an optional column using the RLE/bit-packing hybrid
+// encoding, using only RLE runs.
+func rleDefinitionLevels(values []interface{}) []byte {
+	var buf bytes.Buffer
+
+	for start := 0; start < len(values); {
+		level := values[start] != nil
+
+		end := start
+		for end < len(values) && (values[end] != nil) == level {
+			end++
+		}
+
+		writeUvarint(&buf, uint64(end-start)<<1)
+
+		if level {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+
+		start = end
+	}
+
+	return buf.Bytes()
+}
+
+// plainValues will encode the non-nil values of a column using the PLAIN encoding.
+func (col *parquetColumn) plainValues() ([]byte, error) {
+	var buf bytes.Buffer
+
+	var bits, nbits byte
+
+	for _, val := range col.values {
+		if val == nil {
+			continue
+		}
+
+		switch col.ptype {
+		case parquetBoolean:
+			if bval, _ := val.(bool); bval {
+				bits |= 1 << nbits
+			}
+
+			if nbits++; nbits == 8 {
+				buf.WriteByte(bits)
+				bits, nbits = 0, 0
+			}
+		case parquetDouble:
+			var b [8]byte
+
+			fval, _ := val.(float64)
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(fval))
+			buf.Write(b[:])
+		default:
+			cell, err := csvCell(val)
+			if err != nil {
+				return nil, err
+			}
+
+			var b [4]byte
+
+			binary.LittleEndian.PutUint32(b[:], uint32(len(cell)))
+			buf.Write(b[:])
+			buf.WriteString(cell)
+		}
+	}
+
+	if nbits > 0 {
+		buf.WriteByte(bits)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// page will return the data page for the column, prefixed by its header.
+func (col *parquetColumn) page() ([]byte, error) {
+	levels := rleDefinitionLevels(col.values)
+
+	values, err := col.plainValues()
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 4, 4+len(levels)+len(values))
+	binary.LittleEndian.PutUint32(body, uint32(len(levels)))
+	body = append(body, levels...)
+	body = append(body, values...)
+
+	header := newThriftWriter()
+	header.i32Field(1, parquetDataPage)
+	header.i32Field(2, int32(len(body)))
+	header.i32Field(3, int32(len(body)))
+	header.structField(5)
+	header.i32Field(1, int32(len(col.values)))
+	header.i32Field(2, parquetPlain)
+	header.i32Field(3, parquetRLE)
+	header.i32Field(4, parquetRLE)
+	header.endStruct()
+	header.endStruct()
+
+	return append(header.bytes(), body...), nil
+}
+
+func (parquetEncoder) Encode(file *os.File, records []map[string]interface{}) error {
+	columns := parquetColumns(records)
+
+	var buf bytes.Buffer
+
+	buf.Write(parquetMagic)
+
+	offsets := make([]int64, len(columns))
+	sizes := make([]int64, len(columns))
+
+	for idx, col := range columns {
+		page, err := col.page()
+		if err != nil {
+			return err
+		}
+
+		offsets[idx] = int64(buf.Len())
+		sizes[idx] = int64(len(page))
+
+		buf.Write(page)
+	}
+
+	meta := newThriftWriter()
+	meta.i32Field(1, 1)
+
+	// Schema, starting with the root element.
+	meta.listField(2, thriftStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(columns)))
+	meta.endStruct()
+
+	for _, col := range columns {
+		meta.beginStruct()
+		meta.i32Field(1, col.ptype)
+		meta.i32Field(3, parquetOptional)
+		meta.stringField(4, col.name)
+
+		if col.ptype == parquetByteArray {
+			meta.i32Field(6, parquetUTF8)
+		}
+
+		meta.endStruct()
+	}
+
+	meta.i64Field(3, int64(len(records)))
+
+	// A single row group containing every column chunk.
+	meta.listField(4, thriftStruct, 1)
+	meta.beginStruct()
+	meta.listField(1, thriftStruct, len(columns))
+
+	var total int64
+
+	for idx, col := range columns {
+		total += sizes[idx]
+
+		meta.beginStruct()
+		meta.i64Field(2, offsets[idx])
+		meta.structField(3)
+		meta.i32Field(1, col.ptype)
+		meta.listField(2, thriftI32, 2)
+		meta.writeI32(parquetPlain)
+		meta.writeI32(parquetRLE)
+		meta.listField(3, thriftBinary, 1)
+		meta.writeString(col.name)
+		meta.i32Field(4, 0)
+		meta.i64Field(5, int64(len(records)))
+		meta.i64Field(6, sizes[idx])
+		meta.i64Field(7, sizes[idx])
+		meta.i64Field(9, offsets[idx])
+		meta.endStruct()
+		meta.endStruct()
+	}
+
+	meta.i64Field(2, total)
+	meta.i64Field(3, int64(len(records)))
+	meta.endStruct()
+
+	meta.stringField(6, "gidari")
+	meta.endStruct()
+
+	footer := meta.bytes()
+	buf.Write(footer)
+
+	var footerLen [4]byte
+
+	binary.LittleEndian.PutUint32(footerLen[:], uint32(len(footer)))
+	buf.Write(footerLen[:])
+	buf.Write(parquetMagic)
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("unable to write parquet file: %w", err)
+	}
+
+	return nil
+}
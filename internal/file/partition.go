@@ -0,0 +1,57 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// partitionDateLayout is the layout of the date in a partition directory name.
+	partitionDateLayout = "2006-01-02"
+
+	// defaultPartition is the partition for records with a missing or unparsable date, following the Hive
+	// convention.
+	defaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+// PartitionDate will return the date partition for a record value. Strings are parsed as RFC3339 timestamps or dates,
+// and numbers are treated as Unix timestamps in seconds.
+func PartitionDate(val interface{}) string {
+	switch val := val.(type) {
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, partitionDateLayout} {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t.UTC().Format(partitionDateLayout)
+			}
+		}
+	case float64:
+		return time.Unix(int64(val), 0).UTC().Format(partitionDateLayout)
+	}
+
+	return defaultPartition
+}
+
+// partitionRecords will group the records by the date of the column, keyed by the Hive-style relative directory of the
+// partition (e.g. "created_at=2024-01-02"). If column is empty, all records are returned in a single unnamed
+// partition.
+func partitionRecords(column string, records []map[string]interface{}) map[string][]map[string]interface{} {
+	if column == "" {
+		return map[string][]map[string]interface{}{"": records}
+	}
+
+	partitions := make(map[string][]map[string]interface{})
+
+	for _, record := range records {
+		key := fmt.Sprintf("%s=%s", column, PartitionDate(record[column]))
+		partitions[key] = append(partitions[key], record)
+	}
+
+	return partitions
+}
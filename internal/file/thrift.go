@@ -0,0 +1,128 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol types, as defined by the specification:
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+
+	// thriftMaxShortList is the largest list size that can be packed into the list header byte.
+	thriftMaxShortList = 14
+)
+
+// thriftWriter is a minimal writer for the Thrift compact protocol, sufficient to encode Parquet metadata.
+type thriftWriter struct {
+	buf bytes.Buffer
+
+	// lastFieldIDs is the stack of the last field ID written for each open struct, used for delta encoding.
+	lastFieldIDs []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastFieldIDs: []int16{0}}
+}
+
+func (w *thriftWriter) bytes() []byte { return w.buf.Bytes() }
+
+// writeUvarint will write an unsigned LEB128 varint to the buffer.
+func writeUvarint(buf *bytes.Buffer, val uint64) {
+	var b [binary.MaxVarintLen64]byte
+
+	buf.Write(b[:binary.PutUvarint(b[:], val)])
+}
+
+func (w *thriftWriter) writeVarint(val uint64) { writeUvarint(&w.buf, val) }
+
+// writeI32 will write a zigzag-encoded 32-bit integer.
+func (w *thriftWriter) writeI32(val int32) {
+	w.writeVarint(uint64(uint32((val << 1) ^ (val >> 31))))
+}
+
+// writeI64 will write a zigzag-encoded 64-bit integer.
+func (w *thriftWriter) writeI64(val int64) {
+	w.writeVarint(uint64((val << 1) ^ (val >> 63)))
+}
+
+// writeString will write a length-prefixed string.
+func (w *thriftWriter) writeString(val string) {
+	w.writeVarint(uint64(len(val)))
+	w.buf.WriteString(val)
+}
+
+// fieldHeader will write a field header, using the short delta form when possible.
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &w.lastFieldIDs[len(w.lastFieldIDs)-1]
+
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.writeI32(int32(id))
+	}
+
+	*last = id
+}
+
+func (w *thriftWriter) i32Field(id int16, val int32) {
+	w.fieldHeader(id, thriftI32)
+	w.writeI32(val)
+}
+
+func (w *thriftWriter) i64Field(id int16, val int64) {
+	w.fieldHeader(id, thriftI64)
+	w.writeI64(val)
+}
+
+func (w *thriftWriter) stringField(id int16, val string) {
+	w.fieldHeader(id, thriftBinary)
+	w.writeString(val)
+}
+
+// listField will write the header for a list field, the caller must then write "size" elements of "elemType".
+func (w *thriftWriter) listField(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+
+	if size <= thriftMaxShortList {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+
+		return
+	}
+
+	w.buf.WriteByte(0xf0 | elemType)
+	w.writeVarint(uint64(size))
+}
+
+// structField will write the header for a struct field and begin the struct.
+func (w *thriftWriter) structField(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.beginStruct()
+}
+
+// beginStruct will begin a struct, such as a list element.
+func (w *thriftWriter) beginStruct() {
+	w.lastFieldIDs = append(w.lastFieldIDs, 0)
+}
+
+// endStruct will write the stop field for the current struct.
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+
+	if len(w.lastFieldIDs) > 1 {
+		w.lastFieldIDs = w.lastFieldIDs[:len(w.lastFieldIDs)-1]
+	}
+}
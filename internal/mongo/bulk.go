@@ -0,0 +1,44 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unorderedOption is the connection string option used to write to a collection with unordered bulk writes. The value
+// is a comma-separated list of collections, and the option can be repeated.
+const unorderedOption = "unordered"
+
+var ErrInvalidUnordered = fmt.Errorf("invalid unordered option")
+
+// parseUnordered will parse the "unordered" connection string options into the set of collections that are written to
+// with unordered bulk writes.
+func parseUnordered(values []string) (map[string]bool, error) {
+	collections := make(map[string]bool)
+
+	for _, value := range values {
+		for _, collection := range strings.Split(value, ",") {
+			if collection == "" {
+				return nil, fmt.Errorf("%w: empty collection in %q", ErrInvalidUnordered, value)
+			}
+
+			collections[collection] = true
+		}
+	}
+
+	return collections, nil
+}
+
+// ordered will return true if the writes to the collection must be applied in order, stopping at the first error.
+// Unordered writes can be executed in parallel by the server, which is faster for large batches of records that do not
+// depend on each other.
+func (m *Mongo) ordered(collection string) bool {
+	return !m.unordered[collection]
+}
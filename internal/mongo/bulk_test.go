@@ -0,0 +1,41 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseUnordered(t *testing.T) {
+	t.Parallel()
+
+	collections, err := parseUnordered([]string{"candles,trades", "orders"})
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	expected := map[string]bool{"candles": true, "trades": true, "orders": true}
+	if !reflect.DeepEqual(collections, expected) {
+		t.Errorf("expected %v, got %v", expected, collections)
+	}
+
+	mdb := &Mongo{unordered: collections}
+	if mdb.ordered("candles") || !mdb.ordered("accounts") {
+		t.Errorf("expected only accounts to be ordered")
+	}
+
+	for _, value := range []string{"", "candles,", ",trades"} {
+		if _, err := parseUnordered([]string{value}); !errors.Is(err, ErrInvalidUnordered) {
+			t.Errorf("%q: expected %v, got %v", value, ErrInvalidUnordered, err)
+		}
+	}
+}
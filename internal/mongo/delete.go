@@ -0,0 +1,36 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// Delete will delete the documents of the collection that match the request.
+func (m *Mongo) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	cs, err := connstring.ParseAndValidate(m.dns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	coll := m.Client.Database(cs.Database).Collection(req.Table)
+
+	result, err := coll.DeleteMany(ctx, recordFilter(req.Match, req.Range, m.timeseries[req.Table]))
+	if err != nil {
+		return nil, fmt.Errorf("error deleting from collection %s: %w", req.Table, err)
+	}
+
+	return &proto.DeleteResponse{DeletedCount: result.DeletedCount}, nil
+}
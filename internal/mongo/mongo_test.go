@@ -0,0 +1,108 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpsertFilter(t *testing.T) {
+	t.Parallel()
+
+	doc := bson.D{{Key: "id", Value: "1"}, {Key: "time", Value: "2022"}, {Key: "close", Value: 1.5}}
+
+	for _, tcase := range []struct {
+		name     string
+		pks      []string
+		expected bson.D
+	}{
+		{name: "no primary keys", expected: doc},
+		{
+			name:     "primary keys",
+			pks:      []string{"time", "id"},
+			expected: bson.D{{Key: "time", Value: "2022"}, {Key: "id", Value: "1"}},
+		},
+		{
+			name:     "missing primary key",
+			pks:      []string{"product_id"},
+			expected: bson.D{primitive.E{Key: "product_id", Value: nil}},
+		},
+	} {
+		if got := upsertFilter(doc, tcase.pks); !reflect.DeepEqual(got, tcase.expected) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.expected, got)
+		}
+	}
+}
+
+func TestUpsertModel(t *testing.T) {
+	t.Parallel()
+
+	doc := bson.D{{Key: "id", Value: "1"}, {Key: "close", Value: nil}}
+	pks := []string{"id"}
+
+	model, ok := upsertModel(doc, pks, "").(*mongo.ReplaceOneModel)
+	if !ok || !reflect.DeepEqual(model.Replacement, doc) {
+		t.Errorf("expected a replace model, got %v", model)
+	}
+
+	for _, tcase := range []struct {
+		conflict string
+		update   bson.D
+	}{
+		{conflict: proto.ConflictInsertOnly, update: bson.D{{Key: "$setOnInsert", Value: doc}}},
+		{conflict: proto.ConflictFail, update: bson.D{{Key: "$setOnInsert", Value: doc}}},
+		{conflict: proto.ConflictMergeNonNull, update: bson.D{{Key: "$set", Value: bson.D{doc[0]}}}},
+	} {
+		model, ok := upsertModel(doc, pks, tcase.conflict).(*mongo.UpdateOneModel)
+		if !ok || !reflect.DeepEqual(model.Update, tcase.update) || !*model.Upsert {
+			t.Errorf("%s: expected update %v, got %v", tcase.conflict, tcase.update, model)
+		}
+	}
+}
+
+func TestTransientError(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		err        error
+		transient  bool
+		contention bool
+	}{
+		{err: mongo.CommandError{Labels: []string{transientTransactionErrorLabel}}, transient: true},
+		{
+			err:       fmt.Errorf("error in transaction: %w", mongo.CommandError{Code: writeConflictErrorCode}),
+			transient: true, contention: true,
+		},
+		{err: mongo.CommandError{Code: lockTimeoutErrorCode}, transient: true, contention: true},
+		{err: mongo.CommandError{Labels: []string{"NetworkError"}}, transient: true},
+		{err: mongo.CommandError{Code: namespaceExistsErrorCode}},
+		{err: ErrTransactionAborted},
+	} {
+		if got := errors.Is(transientError(tcase.err), proto.ErrTransient); got != tcase.transient {
+			t.Errorf("expected %v to be transient: %v, got %v", tcase.err, tcase.transient, got)
+		}
+
+		if got := errors.Is(transientError(tcase.err), proto.ErrContention); got != tcase.contention {
+			t.Errorf("expected %v to be contention: %v, got %v", tcase.err, tcase.contention, got)
+		}
+	}
+
+	if transientError(nil) != nil {
+		t.Errorf("expected nil for a nil error")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// recordFilter will return the filter that matches the documents with the values of fields and in the time range.
+// The time range is compared as dates on the time field of a time-series collection, and as RFC 3339 strings
+// otherwise.
+func recordFilter(match map[string]interface{}, rng *proto.TimeRange, ts *timeseries) bson.D {
+	fields := make([]string, 0, len(match))
+	for field := range match {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	filter := bson.D{}
+	for _, field := range fields {
+		filter = append(filter, bson.E{Key: field, Value: match[field]})
+	}
+
+	if rng == nil {
+		return filter
+	}
+
+	bound := func(t time.Time) interface{} {
+		if ts != nil && ts.timeField == rng.Column {
+			return t
+		}
+
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	bounds := bson.D{}
+	if !rng.Start.IsZero() {
+		bounds = append(bounds, bson.E{Key: "$gte", Value: bound(rng.Start)})
+	}
+
+	if !rng.End.IsZero() {
+		bounds = append(bounds, bson.E{Key: "$lt", Value: bound(rng.End)})
+	}
+
+	if len(bounds) > 0 {
+		filter = append(filter, bson.E{Key: rng.Column, Value: bounds})
+	}
+
+	return filter
+}
+
+// Query will read the documents of the collection that match the request. Documents are converted to records with
+// relaxed extended JSON, so that e.g. object IDs are read as {"$oid": "..."}.
+func (m *Mongo) Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	cs, err := connstring.ParseAndValidate(m.dns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	opts := options.Find()
+	if req.Range != nil {
+		order := 1
+		if req.Descending {
+			order = -1
+		}
+
+		opts.SetSort(bson.D{{Key: req.Range.Column, Value: order}})
+	}
+
+	if req.Limit > 0 {
+		opts.SetLimit(int64(req.Limit))
+	}
+
+	coll := m.Client.Database(cs.Database).Collection(req.Table)
+
+	cursor, err := coll.Find(ctx, recordFilter(req.Match, req.Range, m.timeseries[req.Table]), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error querying collection %s: %w", req.Table, err)
+	}
+
+	defer cursor.Close(ctx)
+
+	rsp := &proto.QueryResponse{}
+
+	for cursor.Next(ctx) {
+		data, err := bson.MarshalExtJSON(cursor.Current, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal document: %w", err)
+		}
+
+		var hash map[string]interface{}
+		if err := json.Unmarshal(data, &hash); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+		}
+
+		record, err := structpb.NewStruct(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create record: %w", err)
+		}
+
+		rsp.Records = append(rsp.Records, record)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error reading collection %s: %w", req.Table, err)
+	}
+
+	return rsp, nil
+}
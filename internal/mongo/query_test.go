@@ -0,0 +1,55 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRecordFilter(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+	req := &proto.DeleteRequest{
+		Table: "candles",
+		Match: map[string]interface{}{"product_id": "BTC-USD"},
+		Range: &proto.TimeRange{Column: "time", Start: start, End: start.Add(time.Hour)},
+	}
+
+	for _, tcase := range []struct {
+		name       string
+		ts         *timeseries
+		start, end interface{}
+	}{
+		{name: "collection", start: "2022-05-10T00:00:00Z", end: "2022-05-10T01:00:00Z"},
+		{name: "time-series collection", ts: &timeseries{timeField: "time"}, start: start, end: start.Add(time.Hour)},
+	} {
+		expected := bson.D{
+			{Key: "product_id", Value: "BTC-USD"},
+			{Key: "time", Value: bson.D{{Key: "$gte", Value: tcase.start}, {Key: "$lt", Value: tcase.end}}},
+		}
+
+		if got := recordFilter(req.Match, req.Range, tcase.ts); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, expected, got)
+		}
+	}
+
+	got := recordFilter(nil, &proto.TimeRange{Column: "time", End: start}, nil)
+
+	expected := bson.D{{Key: "time", Value: bson.D{{Key: "$lt", Value: "2022-05-10T00:00:00Z"}}}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v without a lower bound, got %v", expected, got)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// reconcileFilters will return the filters that match the documents that were not fetched and the documents that
+// were, but whose deleted flag does not reflect it.
+func reconcileFilters(req *proto.ReconcileRequest) (bson.D, bson.D) {
+	fetched := bson.A{}
+
+	for _, key := range req.Keys {
+		filter := bson.D{}
+		for _, pk := range req.PrimaryKeys {
+			filter = append(filter, bson.E{Key: pk, Value: key[pk]})
+		}
+
+		fetched = append(fetched, filter)
+	}
+
+	deleted := bson.D{{Key: req.DeletedColumn, Value: bson.D{{Key: "$ne", Value: true}}}}
+	if len(fetched) == 0 {
+		return deleted, nil
+	}
+
+	absent := append(bson.D{{Key: "$nor", Value: fetched}}, deleted...)
+	present := bson.D{
+		{Key: "$or", Value: fetched},
+		{Key: req.DeletedColumn, Value: bson.D{{Key: "$ne", Value: false}}},
+	}
+
+	return absent, present
+}
+
+// Reconcile will set the deleted field of the documents in the collection that are not in the fetched keys to true,
+// and of the documents that are to false.
+func (m *Mongo) Reconcile(ctx context.Context, req *proto.ReconcileRequest) (*proto.ReconcileResponse, error) {
+	m.writeMutex.Lock()
+	defer m.writeMutex.Unlock()
+
+	cs, err := connstring.ParseAndValidate(m.dns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	coll := m.Client.Database(cs.Database).Collection(req.Table)
+	absent, present := reconcileFilters(req)
+
+	rsp := &proto.ReconcileResponse{}
+
+	for _, update := range []struct {
+		filter  bson.D
+		deleted bool
+	}{{absent, true}, {present, false}} {
+		if update.filter == nil {
+			continue
+		}
+
+		result, err := coll.UpdateMany(ctx, update.filter,
+			bson.D{{Key: "$set", Value: bson.D{{Key: req.DeletedColumn, Value: update.deleted}}}})
+		if err != nil {
+			return nil, fmt.Errorf("error reconciling collection %s: %w", req.Table, err)
+		}
+
+		rsp.UpdatedCount += result.ModifiedCount
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,61 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReconcileFilters(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fetched", func(t *testing.T) {
+		t.Parallel()
+
+		absent, present := reconcileFilters(&proto.ReconcileRequest{
+			PrimaryKeys:   []string{"id"},
+			Keys:          []map[string]interface{}{{"id": "1"}, {"id": "2"}},
+			DeletedColumn: "deleted",
+		})
+
+		fetched := bson.A{bson.D{{Key: "id", Value: "1"}}, bson.D{{Key: "id", Value: "2"}}}
+
+		expectedAbsent := bson.D{
+			{Key: "$nor", Value: fetched},
+			{Key: "deleted", Value: bson.D{{Key: "$ne", Value: true}}},
+		}
+		if !reflect.DeepEqual(absent, expectedAbsent) {
+			t.Errorf("expected absent filter %v, got %v", expectedAbsent, absent)
+		}
+
+		expectedPresent := bson.D{
+			{Key: "$or", Value: fetched},
+			{Key: "deleted", Value: bson.D{{Key: "$ne", Value: false}}},
+		}
+		if !reflect.DeepEqual(present, expectedPresent) {
+			t.Errorf("expected present filter %v, got %v", expectedPresent, present)
+		}
+	})
+
+	t.Run("none fetched", func(t *testing.T) {
+		t.Parallel()
+
+		absent, present := reconcileFilters(&proto.ReconcileRequest{PrimaryKeys: []string{"id"}, DeletedColumn: "deleted"})
+
+		expected := bson.D{{Key: "deleted", Value: bson.D{{Key: "$ne", Value: true}}}}
+		if !reflect.DeepEqual(absent, expected) || present != nil {
+			t.Errorf("expected every document to be flagged, got %v and %v", absent, present)
+		}
+	})
+}
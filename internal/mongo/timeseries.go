@@ -0,0 +1,122 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timeseriesOption is the connection string option used to configure a time-series collection. The value is of the
+// form "collection:timeField[:metaField[:granularity]]", and the option can be repeated for each collection.
+const timeseriesOption = "timeseries"
+
+var (
+	ErrInvalidTimeseries = fmt.Errorf("invalid timeseries option")
+	ErrInvalidTimeField  = fmt.Errorf("invalid time field")
+)
+
+// timeseries is the configuration for a native time-series collection.
+type timeseries struct {
+	timeField   string
+	metaField   string
+	granularity string
+}
+
+// parseTimeseries will parse the "timeseries" connection string options into a map of collection names to their
+// time-series configuration.
+func parseTimeseries(values []string) (map[string]*timeseries, error) {
+	collections := make(map[string]*timeseries)
+
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTimeseries, value)
+		}
+
+		opts := &timeseries{timeField: parts[1]}
+		if len(parts) > 2 {
+			opts.metaField = parts[2]
+		}
+
+		if len(parts) > 3 {
+			switch parts[3] {
+			case "", "seconds", "minutes", "hours":
+				opts.granularity = parts[3]
+			default:
+				return nil, fmt.Errorf("%w: unknown granularity %q", ErrInvalidTimeseries, parts[3])
+			}
+		}
+
+		if _, ok := collections[parts[0]]; ok {
+			return nil, fmt.Errorf("%w: duplicate collection %q", ErrInvalidTimeseries, parts[0])
+		}
+
+		collections[parts[0]] = opts
+	}
+
+	return collections, nil
+}
+
+// createOptions returns the options to create the time-series collection.
+func (ts *timeseries) createOptions() *options.CreateCollectionOptions {
+	tsOpts := options.TimeSeries().SetTimeField(ts.timeField)
+	if ts.metaField != "" {
+		tsOpts.SetMetaField(ts.metaField)
+	}
+
+	if ts.granularity != "" {
+		tsOpts.SetGranularity(ts.granularity)
+	}
+
+	return options.CreateCollection().SetTimeSeriesOptions(tsOpts)
+}
+
+// parseTime will convert a decoded time value into a time. Strings are parsed as RFC 3339 timestamps and numbers
+// as seconds since the Unix epoch.
+func parseTime(val interface{}) (time.Time, error) {
+	switch val := val.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, val)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidTimeField, err)
+		}
+
+		return t, nil
+	case float64:
+		sec, frac := math.Modf(val)
+
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: unsupported type %T", ErrInvalidTimeField, val)
+	}
+}
+
+// document will convert the record into a document for the time-series collection, replacing the value of the time
+// field with a BSON date.
+func (ts *timeseries) document(record map[string]interface{}) (bson.M, error) {
+	val, ok := record[ts.timeField]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is missing", ErrInvalidTimeField, ts.timeField)
+	}
+
+	t, err := parseTime(val)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", ts.timeField, err)
+	}
+
+	doc := bson.M(record)
+	doc[ts.timeField] = t
+
+	return doc, nil
+}
@@ -0,0 +1,73 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeseries(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parse", func(t *testing.T) {
+		t.Parallel()
+
+		collections, err := parseTimeseries([]string{"candles:time:product_id:minutes", "trades:ts"})
+		if err != nil {
+			t.Fatalf("failed to parse timeseries: %v", err)
+		}
+
+		candles := collections["candles"]
+		if candles == nil || candles.timeField != "time" || candles.metaField != "product_id" ||
+			candles.granularity != "minutes" {
+			t.Errorf("unexpected candles options %+v", candles)
+		}
+
+		if trades := collections["trades"]; trades == nil || trades.timeField != "ts" || trades.metaField != "" {
+			t.Errorf("unexpected trades options %+v", trades)
+		}
+
+		for _, value := range []string{"candles", "candles:", ":time", "candles:time:meta:days", "a:b:c:d:e"} {
+			if _, err := parseTimeseries([]string{value}); !errors.Is(err, ErrInvalidTimeseries) {
+				t.Errorf("%q: expected %v, got %v", value, ErrInvalidTimeseries, err)
+			}
+		}
+
+		if _, err := parseTimeseries([]string{"candles:time", "candles:ts"}); !errors.Is(err, ErrInvalidTimeseries) {
+			t.Errorf("expected %v for a duplicate collection, got %v", ErrInvalidTimeseries, err)
+		}
+	})
+
+	t.Run("document", func(t *testing.T) {
+		t.Parallel()
+
+		expected := time.Date(2022, 11, 1, 12, 30, 0, 0, time.UTC)
+		ts := &timeseries{timeField: "time"}
+
+		for _, val := range []interface{}{"2022-11-01T12:30:00Z", float64(expected.Unix())} {
+			doc, err := ts.document(map[string]interface{}{"time": val, "close": 1.5})
+			if err != nil {
+				t.Fatalf("failed to convert document: %v", err)
+			}
+
+			if got, ok := doc["time"].(time.Time); !ok || !got.Equal(expected) {
+				t.Errorf("expected time %v, got %v", expected, doc["time"])
+			}
+		}
+
+		for _, record := range []map[string]interface{}{{"close": 1.5}, {"time": "yesterday"}, {"time": true}} {
+			if _, err := ts.document(record); !errors.Is(err, ErrInvalidTimeField) {
+				t.Errorf("%v: expected %v, got %v", record, ErrInvalidTimeField, err)
+			}
+		}
+	})
+}
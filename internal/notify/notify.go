@@ -0,0 +1,154 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/report"
+)
+
+// timeout is how long each notification is sent for before it fails.
+const timeout = 30 * time.Second
+
+var ErrUnexpectedStatus = fmt.Errorf("unexpected status")
+
+// sendMail sends an email, which is replaced in tests.
+var sendMail = smtp.SendMail
+
+// Send will send the summary of the run to the Slack webhook, the HTTP webhook, and the email of the notification, if
+// the run is notified. Every notification is sent even if an earlier one fails, and the first error is returned.
+func Send(ctx context.Context, notify *config.Notify, run *report.Run) error {
+	if notify == nil || !notify.Notifies(run.Status == report.RunFailed) {
+		return nil
+	}
+
+	summary, err := text(run)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var errs []error
+
+	if notify.Slack != "" {
+		errs = append(errs, postJSON(ctx, notify.Slack, map[string]string{"text": "```" + summary + "```"}))
+	}
+
+	if notify.Webhook != "" {
+		errs = append(errs, postJSON(ctx, notify.Webhook, run))
+	}
+
+	if notify.Email != nil {
+		errs = append(errs, email(notify.Email, subject(run), summary))
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subject will return the subject of the notification of the run, e.g. "gidari: candles.yaml failed".
+func subject(run *report.Run) string {
+	return fmt.Sprintf("gidari: %s %s", run.Config, run.Status)
+}
+
+// text will return the text of the notification of the run: the summary table of its requests, followed by the
+// error of the run if it failed, see "report.Run.WriteSummary".
+func text(run *report.Run) (string, error) {
+	var summary strings.Builder
+	if err := run.WriteSummary(&summary); err != nil {
+		return "", err
+	}
+
+	if run.Error != "" {
+		fmt.Fprintf(&summary, "error: %s\n", run.Error)
+	}
+
+	return summary.String(), nil
+}
+
+// postJSON will post the body as JSON to the URL.
+func postJSON(ctx context.Context, rawURL string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to create notification request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send notification: %w", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < http.StatusOK || rsp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+		return fmt.Errorf("%w: %s: %s", ErrUnexpectedStatus, rsp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// email will send the summary by email, authenticating with the user and password of the SMTP URL if it has them.
+func email(cfg *config.Email, subject, summary string) error {
+	rurl, err := url.Parse(cfg.SMTP)
+	if err != nil {
+		return fmt.Errorf("unable to parse smtp url: %w", err)
+	}
+
+	var auth smtp.Auth
+
+	if user := rurl.User; user != nil {
+		password, _ := user.Password()
+		auth = smtp.PlainAuth("", user.Username(), password, rurl.Hostname())
+	}
+
+	if err := sendMail(rurl.Host, auth, cfg.From, cfg.To, message(cfg, subject, summary)); err != nil {
+		return fmt.Errorf("unable to send email: %w", err)
+	}
+
+	return nil
+}
+
+// message will return the email of the summary.
+func message(cfg *config.Email, subject, summary string) []byte {
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(summary, "\n", "\r\n"))
+
+	return []byte(msg.String())
+}
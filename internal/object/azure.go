@@ -0,0 +1,325 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// azureVersion is the version of the Blob service REST API used for requests.
+const azureVersion = "2021-08-06"
+
+var (
+	ErrMissingAzureContainer   = fmt.Errorf("missing azure container")
+	ErrMissingAzureAccount     = fmt.Errorf("missing azure storage account")
+	ErrMissingAzureCredentials = fmt.Errorf("missing azure storage account key or sas token")
+)
+
+// azureBucket is an Azure Blob Storage container accessed through the Blob service REST API. Requests are authorized
+// with either a Shared Key or a shared access signature.
+type azureBucket struct {
+	client    *http.Client
+	endpoint  *url.URL
+	account   string
+	container string
+
+	// key is the decoded storage account key used for Shared Key authorization. If it is nil, the SAS token is
+	// added to every request instead.
+	key []byte
+	sas url.Values
+
+	now func() time.Time
+}
+
+// NewAzureBlob will return an object storage device for an Azure Blob Storage container. The connection string is of
+// the form
+//
+//	azblob://[account:key@]container/prefix?format=ndjson&endpoint=http://127.0.0.1:10000/devstoreaccount1
+//
+// The account and key default to the "AZURE_STORAGE_ACCOUNT" and "AZURE_STORAGE_KEY" environment variables. If there
+// is no key, requests are authorized with the shared access signature in the "sas" parameter or the
+// "AZURE_STORAGE_SAS_TOKEN" environment variable. If "endpoint" is set, requests are made to that account URL
+// instead, such as the Azurite emulator.
+func NewAzureBlob(_ context.Context, dns string) (*Object, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse azure connection string: %w", err)
+	}
+
+	if uri.Host == "" {
+		return nil, ErrMissingAzureContainer
+	}
+
+	bkt := &azureBucket{
+		client:    &http.Client{},
+		account:   os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		container: uri.Host,
+		now:       time.Now,
+	}
+
+	key := os.Getenv("AZURE_STORAGE_KEY")
+	if uri.User != nil {
+		bkt.account = uri.User.Username()
+		key, _ = uri.User.Password()
+	}
+
+	if bkt.account == "" {
+		return nil, ErrMissingAzureAccount
+	}
+
+	if key != "" {
+		if bkt.key, err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("unable to decode azure storage account key: %w", err)
+		}
+	} else {
+		sas := firstNonEmpty(uri.Query().Get("sas"), os.Getenv("AZURE_STORAGE_SAS_TOKEN"))
+		if sas == "" {
+			return nil, ErrMissingAzureCredentials
+		}
+
+		if bkt.sas, err = url.ParseQuery(strings.TrimPrefix(sas, "?")); err != nil {
+			return nil, fmt.Errorf("unable to parse azure sas token: %w", err)
+		}
+	}
+
+	endpoint := firstNonEmpty(uri.Query().Get("endpoint"),
+		fmt.Sprintf("https://%s.blob.core.windows.net", bkt.account))
+	if bkt.endpoint, err = url.Parse(strings.TrimSuffix(endpoint, "/")); err != nil {
+		return nil, fmt.Errorf("unable to parse azure endpoint: %w", err)
+	}
+
+	return newObject(bkt, uri, proto.AzureBlobType)
+}
+
+// blobURL will return the URL of a blob in the container, or of the container itself if the key is empty.
+func (bkt *azureBucket) blobURL(key string, query url.Values) *url.URL {
+	rurl := *bkt.endpoint
+
+	blobPath := rurl.Path + "/" + bkt.container
+	if key != "" {
+		blobPath += "/" + key
+	}
+
+	if bkt.key == nil {
+		if query == nil {
+			query = url.Values{}
+		}
+
+		for name, vals := range bkt.sas {
+			query[name] = vals
+		}
+	}
+
+	rurl.Path = blobPath
+	rurl.RawPath = awsEscape(blobPath, true)
+	rurl.RawQuery = query.Encode()
+
+	return &rurl
+}
+
+// sign will authorize the request with a Shared Key, as described by
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (bkt *azureBucket) sign(req *http.Request) {
+	var canonicalHeaders strings.Builder
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			names = append(names, lower)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	canonicalResource := "/" + bkt.account + req.URL.EscapedPath()
+
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+
+	for name := range query {
+		params = append(params, name)
+	}
+
+	sort.Strings(params)
+
+	for _, name := range params {
+		vals := append([]string{}, query[name]...)
+		sort.Strings(vals)
+
+		canonicalResource += "\n" + strings.ToLower(name) + ":" + strings.Join(vals, ",")
+	}
+
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, which is superseded by the x-ms-date header.
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalHeaders.String() + canonicalResource,
+	}, "\n")
+
+	hash := hmac.New(sha256.New, bkt.key)
+
+	// Don't handle error because hash.Write method never returns an error.
+	hash.Write([]byte(stringToSign))
+
+	req.Header.Set("Authorization", "SharedKey "+bkt.account+":"+base64.StdEncoding.EncodeToString(hash.Sum(nil)))
+}
+
+// do will authorize and send the request, returning an error if the response status is not 2xx.
+func (bkt *azureBucket) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Ms-Date", bkt.now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+
+	if bkt.key != nil {
+		bkt.sign(req)
+	}
+
+	rsp, err := bkt.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if rsp.StatusCode < http.StatusOK || rsp.StatusCode >= http.StatusMultipleChoices {
+		defer rsp.Body.Close()
+
+		return nil, UnexpectedStatusError(rsp.Status, rsp.Body)
+	}
+
+	return rsp, nil
+}
+
+func (bkt *azureBucket) put(ctx context.Context, key string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, bkt.blobURL(key, nil).String(), body)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+// azureEnumerationResults is the response body of the "List Blobs" operation.
+type azureEnumerationResults struct {
+	Blobs []struct {
+		Name          string `xml:"Name"`
+		ContentLength int64  `xml:"Properties>Content-Length"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (bkt *azureBucket) list(ctx context.Context, prefix string) ([]objectInfo, error) {
+	var (
+		objects []objectInfo
+		marker  string
+	)
+
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, bkt.blobURL("", query).String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+
+		rsp, err := bkt.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result azureEnumerationResults
+
+		err = xml.NewDecoder(rsp.Body).Decode(&result)
+		rsp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode list response: %w", err)
+		}
+
+		for _, blob := range result.Blobs {
+			objects = append(objects, objectInfo{key: blob.Name, size: blob.ContentLength})
+		}
+
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+
+		marker = result.NextMarker
+	}
+}
+
+func (bkt *azureBucket) delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, bkt.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+func (bkt *azureBucket) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead,
+		bkt.blobURL("", url.Values{"restype": {"container"}}).String(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
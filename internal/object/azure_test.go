@@ -0,0 +1,152 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// fakeAzure is an in-memory Azure Blob Storage server that supports the path-style operations used by the Azure
+// bucket, in the form used by the Azurite emulator.
+type fakeAzure struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (srv *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	sas := r.URL.Query().Get("sig") == "signature"
+	if !sas && !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:") {
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/devstoreaccount1/container"), "/")
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		srv.objects[key] = body
+
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(srv.objects, key)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodHead:
+	case http.MethodGet:
+		var result azureEnumerationResults
+
+		keys := make([]string, 0, len(srv.objects))
+		for key := range srv.objects {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				result.Blobs = append(result.Blobs, struct {
+					Name          string `xml:"Name"`
+					ContentLength int64  `xml:"Properties>Content-Length"`
+				}{key, int64(len(srv.objects[key]))})
+			}
+		}
+
+		_ = xml.NewEncoder(w).Encode(result)
+	}
+}
+
+func TestAzureBlob(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name string
+		auth string
+	}{
+		{"shared key", "devstoreaccount1:" + url.QueryEscape("a2V5") + "@"},
+		{"sas", "devstoreaccount1@"},
+	} {
+		tcase := tcase
+
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			srv := &fakeAzure{objects: make(map[string][]byte)}
+
+			server := httptest.NewServer(srv)
+			defer server.Close()
+
+			dns := fmt.Sprintf("azblob://%scontainer/landing?endpoint=%s", tcase.auth,
+				url.QueryEscape(server.URL+"/devstoreaccount1"))
+			if tcase.name == "sas" {
+				dns += "&sas=" + url.QueryEscape("sv=2021-08-06&sig=signature")
+			}
+
+			stg, err := NewAzureBlob(ctx, dns)
+			if err != nil {
+				t.Fatalf("failed to create azure blob storage: %v", err)
+			}
+
+			if err := stg.Ping(); err != nil {
+				t.Fatalf("failed to ping: %v", err)
+			}
+
+			_, err = stg.Upsert(ctx, &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"close":1}]`)})
+			if err != nil {
+				t.Fatalf("failed to upsert: %v", err)
+			}
+
+			for key := range srv.objects {
+				if !strings.HasPrefix(key, "landing/table=candles/dt=") || !strings.HasSuffix(key, ".ndjson") {
+					t.Fatalf("unexpected object key %q", key)
+				}
+			}
+
+			tables, err := stg.ListTables(ctx)
+			if err != nil {
+				t.Fatalf("failed to list tables: %v", err)
+			}
+
+			if len(srv.objects) != 1 || tables.GetTableSet()["candles"].GetSize() == 0 {
+				t.Fatalf("expected one object for table candles, got %v", tables.GetTableSet())
+			}
+
+			if _, err := stg.Truncate(ctx, &proto.TruncateRequest{Tables: []string{"candles"}}); err != nil {
+				t.Fatalf("failed to truncate: %v", err)
+			}
+
+			if len(srv.objects) != 0 {
+				t.Fatalf("expected no objects after truncate, got %d", len(srv.objects))
+			}
+		})
+	}
+}
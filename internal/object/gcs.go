@@ -0,0 +1,371 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+const (
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+
+	gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+	// gcsTokenLifetime is the lifetime requested for access tokens, the maximum allowed by Google.
+	gcsTokenLifetime = time.Hour
+
+	// gcsTokenExpiryDelta is how long before expiry an access token is refreshed.
+	gcsTokenExpiryDelta = time.Minute
+)
+
+var (
+	ErrMissingGCSBucket    = fmt.Errorf("missing gcs bucket")
+	ErrInvalidGCSKey       = fmt.Errorf("invalid gcs service account key")
+	ErrMissingGCSAuthToken = fmt.Errorf("missing gcs access token")
+)
+
+// gcsServiceAccount is the subset of a Google service account key file used to request access tokens.
+type gcsServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// gcsTokenSource returns OAuth 2.0 access tokens for requests to Google Cloud Storage.
+type gcsTokenSource struct {
+	client  *http.Client
+	account *gcsServiceAccount
+	key     *rsa.PrivateKey
+	now     func() time.Time
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newGCSTokenSource will create a token source from a service account key file.
+func newGCSTokenSource(client *http.Client, name string) (*gcsTokenSource, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account key: %w", err)
+	}
+
+	var account gcsServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGCSKey, err)
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, ErrInvalidGCSKey
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGCSKey, err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: private key is not RSA", ErrInvalidGCSKey)
+	}
+
+	return &gcsTokenSource{client: client, account: &account, key: key, now: time.Now}, nil
+}
+
+// assertion will return a signed JWT that is exchanged for an access token.
+func (src *gcsTokenSource) assertion() (string, error) {
+	now := src.now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("unable to encode jwt header: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   src.account.ClientEmail,
+		"scope": gcsScope,
+		"aud":   src.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(gcsTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to encode jwt claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, src.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign jwt: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// accessToken will return a cached access token, or request a new one if it has expired.
+func (src *gcsTokenSource) accessToken(ctx context.Context) (string, error) {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	if src.token != "" && src.now().Before(src.expiry.Add(-gcsTokenExpiryDelta)) {
+		return src.token, nil
+	}
+
+	assertion, err := src.assertion()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, src.account.TokenURI,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("unable to create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rsp, err := src.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return "", UnexpectedStatusError(rsp.Status, rsp.Body)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(rsp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to decode access token: %w", err)
+	}
+
+	if token.AccessToken == "" {
+		return "", ErrMissingGCSAuthToken
+	}
+
+	src.token = token.AccessToken
+	src.expiry = src.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return src.token, nil
+}
+
+// gcsBucket is a Google Cloud Storage bucket accessed through the JSON API.
+type gcsBucket struct {
+	client   *http.Client
+	endpoint string
+	name     string
+
+	// tokens requests access tokens using a service account key. If it is nil, the static token is used instead.
+	tokens *gcsTokenSource
+	token  string
+}
+
+// NewGCS will return an object storage device for a Google Cloud Storage bucket. The connection string is of the form
+//
+//	gs://bucket/prefix?credentials=/path/to/key.json&format=ndjson
+//
+// Requests are authorized with the service account key file in "credentials", which defaults to the
+// "GOOGLE_APPLICATION_CREDENTIALS" environment variable. Otherwise the access token in the
+// "GOOGLE_OAUTH_ACCESS_TOKEN" environment variable is used. If "endpoint" is set, requests are made to that host
+// instead, such as a local emulator, and may be unauthorized.
+func NewGCS(_ context.Context, dns string) (*Object, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse gcs connection string: %w", err)
+	}
+
+	if uri.Host == "" {
+		return nil, ErrMissingGCSBucket
+	}
+
+	bkt := &gcsBucket{
+		client:   &http.Client{},
+		endpoint: strings.TrimSuffix(firstNonEmpty(uri.Query().Get("endpoint"), defaultGCSEndpoint), "/"),
+		name:     uri.Host,
+		token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+
+	credentials := firstNonEmpty(uri.Query().Get("credentials"), os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	if credentials != "" {
+		if bkt.tokens, err = newGCSTokenSource(bkt.client, credentials); err != nil {
+			return nil, err
+		}
+	}
+
+	return newObject(bkt, uri, proto.GCSType)
+}
+
+// objectURL will return the URL of a JSON API resource in the bucket.
+func (bkt *gcsBucket) objectURL(resource string, query url.Values) string {
+	if len(query) == 0 {
+		return bkt.endpoint + resource
+	}
+
+	return bkt.endpoint + resource + "?" + query.Encode()
+}
+
+// do will authorize and send the request, returning an error if the response status is not 2xx.
+func (bkt *gcsBucket) do(req *http.Request) (*http.Response, error) {
+	token := bkt.token
+
+	if bkt.tokens != nil {
+		var err error
+		if token, err = bkt.tokens.accessToken(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rsp, err := bkt.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if rsp.StatusCode < http.StatusOK || rsp.StatusCode >= http.StatusMultipleChoices {
+		defer rsp.Body.Close()
+
+		return nil, UnexpectedStatusError(rsp.Status, rsp.Body)
+	}
+
+	return rsp, nil
+}
+
+func (bkt *gcsBucket) put(ctx context.Context, key string, body io.Reader, size int64) error {
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		bkt.objectURL("/upload/storage/v1/b/"+url.PathEscape(bkt.name)+"/o", query), body)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+// gcsListResult is the response body of the "objects.list" method.
+type gcsListResult struct {
+	Items []struct {
+		Name string `json:"name"`
+		Size int64  `json:"size,string"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (bkt *gcsBucket) list(ctx context.Context, prefix string) ([]objectInfo, error) {
+	var (
+		objects []objectInfo
+		token   string
+	)
+
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name,size),nextPageToken"}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			bkt.objectURL("/storage/v1/b/"+url.PathEscape(bkt.name)+"/o", query), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+
+		rsp, err := bkt.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result gcsListResult
+
+		err = json.NewDecoder(rsp.Body).Decode(&result)
+		rsp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode list response: %w", err)
+		}
+
+		for _, item := range result.Items {
+			objects = append(objects, objectInfo{key: item.Name, size: item.Size})
+		}
+
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+
+		token = result.NextPageToken
+	}
+}
+
+func (bkt *gcsBucket) delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete,
+		bkt.objectURL("/storage/v1/b/"+url.PathEscape(bkt.name)+"/o/"+url.PathEscape(key), nil), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+func (bkt *gcsBucket) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		bkt.objectURL("/storage/v1/b/"+url.PathEscape(bkt.name), url.Values{"fields": {"name"}}), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
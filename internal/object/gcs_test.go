@@ -0,0 +1,219 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// fakeGCS is an in-memory Google Cloud Storage server that supports the JSON API methods used by the GCS bucket, and
+// the service account token endpoint.
+type fakeGCS struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func (srv *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	if r.URL.Path == "/token" {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+		body, _ := io.ReadAll(r.Body)
+		srv.objects[r.URL.Query().Get("name")] = body
+	case r.Method == http.MethodDelete:
+		delete(srv.objects, strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/bucket/o":
+		var result gcsListResult
+
+		keys := make([]string, 0, len(srv.objects))
+		for key := range srv.objects {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				result.Items = append(result.Items, struct {
+					Name string `json:"name"`
+					Size int64  `json:"size,string"`
+				}{key, int64(len(srv.objects[key]))})
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/bucket":
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// writeServiceAccount will write a service account key file that requests tokens from the token URI.
+func writeServiceAccount(t *testing.T, tokenURI string) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	data, err := json.Marshal(gcsServiceAccount{
+		ClientEmail: "gidari@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal service account: %v", err)
+	}
+
+	name := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("failed to write service account: %v", err)
+	}
+
+	return name
+}
+
+func TestGCS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("naming", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			template string
+			valid    bool
+		}{
+			{"", true},
+			{"{table}/{date}/{uuid}{ext}", true},
+			{"raw/{table}.v1/{uuid}{ext}", true},
+			{"{date}/{table}/{uuid}{ext}", false},
+			{"{table}/{date}{ext}", false},
+			{"{table}-{uuid}{ext}", false},
+		} {
+			nmg, err := newNaming(tcase.template)
+			if (err == nil) != tcase.valid {
+				t.Fatalf("unexpected error for %q: %v", tcase.template, err)
+			}
+
+			if err != nil {
+				continue
+			}
+
+			table, ok := nmg.tableFromKey(nmg.key("candles", "2024-01-02", "id", ".ndjson"))
+			if !ok || table != "candles" {
+				t.Fatalf("expected table candles for %q, got %q", tcase.template, table)
+			}
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		srv := &fakeGCS{objects: make(map[string][]byte)}
+
+		server := httptest.NewServer(srv)
+		defer server.Close()
+
+		dns := fmt.Sprintf("gs://bucket/landing?naming=%s&compression=gzip&endpoint=%s&credentials=%s",
+			url.QueryEscape("raw/{table}/{date}/{uuid}{ext}"), url.QueryEscape(server.URL),
+			url.QueryEscape(writeServiceAccount(t, server.URL+"/token")))
+
+		stg, err := NewGCS(ctx, dns)
+		if err != nil {
+			t.Fatalf("failed to create gcs storage: %v", err)
+		}
+
+		if err := stg.Ping(); err != nil {
+			t.Fatalf("failed to ping: %v", err)
+		}
+
+		_, err = stg.Upsert(ctx, &proto.UpsertRequest{
+			Table: "candles",
+			Data:  []byte(`[{"close":1},{"close":2}]`),
+		})
+		if err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		for key, body := range srv.objects {
+			if !strings.HasPrefix(key, "landing/raw/candles/") || !strings.HasSuffix(key, ".ndjson.gz") {
+				t.Fatalf("unexpected object key %q", key)
+			}
+
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to read gzip body: %v", err)
+			}
+
+			data, _ := io.ReadAll(zr)
+			if !strings.Contains(string(data), `"close"`) {
+				t.Fatalf("unexpected object body %q", data)
+			}
+		}
+
+		tables, err := stg.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+
+		if len(srv.objects) != 1 || tables.GetTableSet()["candles"].GetSize() == 0 {
+			t.Fatalf("expected one object for table candles, got %v", tables.GetTableSet())
+		}
+
+		if _, err := stg.Truncate(ctx, &proto.TruncateRequest{Tables: []string{"candles"}}); err != nil {
+			t.Fatalf("failed to truncate: %v", err)
+		}
+
+		if len(srv.objects) != 0 {
+			t.Fatalf("expected no objects after truncate, got %d", len(srv.objects))
+		}
+	})
+}
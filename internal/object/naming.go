@@ -0,0 +1,87 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultNaming is the default object key template.
+	DefaultNaming = "table={table}/dt={date}/part-{uuid}{ext}"
+
+	tablePlaceholder = "{table}"
+	datePlaceholder  = "{date}"
+	uuidPlaceholder  = "{uuid}"
+	extPlaceholder   = "{ext}"
+)
+
+var ErrInvalidNaming = fmt.Errorf("invalid object naming template")
+
+// naming is a template for object keys, relative to the storage prefix. The template supports the placeholders
+// "{table}", "{date}", "{uuid}" and "{ext}". The "{table}" placeholder must be in a directory segment of the key so
+// that the objects of a table can be listed by prefix, and "{uuid}" is required so that part files never overwrite
+// each other.
+type naming struct {
+	template string
+
+	// tableHead is the text before the "{table}" placeholder, and tableTail is the text after the placeholder up to
+	// the end of its directory segment.
+	tableHead string
+	tableTail string
+}
+
+func newNaming(template string) (*naming, error) {
+	if template == "" {
+		template = DefaultNaming
+	}
+
+	head, rest, ok := strings.Cut(template, tablePlaceholder)
+	if !ok || !strings.Contains(template, uuidPlaceholder) {
+		return nil, fmt.Errorf("%w: %q must contain %s and %s", ErrInvalidNaming, template, tablePlaceholder,
+			uuidPlaceholder)
+	}
+
+	tail, _, ok := strings.Cut(rest, "/")
+	if !ok || strings.Contains(head, "{") || strings.Contains(tail, "{") {
+		return nil, fmt.Errorf("%w: %q must have %s alone in a directory segment", ErrInvalidNaming, template,
+			tablePlaceholder)
+	}
+
+	return &naming{template: template, tableHead: head, tableTail: tail}, nil
+}
+
+// key will render the template for a part file.
+func (n *naming) key(table, date, id, ext string) string {
+	return strings.NewReplacer(
+		tablePlaceholder, table,
+		datePlaceholder, date,
+		uuidPlaceholder, id,
+		extPlaceholder, ext,
+	).Replace(n.template)
+}
+
+// tableKey will return the key prefix of all of the objects for a table.
+func (n *naming) tableKey(table string) string {
+	return n.tableHead + table + n.tableTail + "/"
+}
+
+// tableFromKey will return the table of an object key, relative to the storage prefix.
+func (n *naming) tableFromKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, n.tableHead) {
+		return "", false
+	}
+
+	segment, _, ok := strings.Cut(strings.TrimPrefix(key, n.tableHead), "/")
+	if !ok || !strings.HasSuffix(segment, n.tableTail) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(segment, n.tableTail), true
+}
@@ -0,0 +1,564 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/internal/file"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// CompressionNone writes part files uncompressed.
+	CompressionNone = "none"
+
+	// CompressionGzip writes gzip-compressed part files with a ".gz" extension.
+	CompressionGzip = "gzip"
+)
+
+var (
+	ErrInvalidCompression  = fmt.Errorf("invalid compression")
+	ErrTransactionNotFound = fmt.Errorf("transaction not found")
+	ErrUnexpectedStatus    = fmt.Errorf("unexpected status")
+)
+
+// UnexpectedStatusError is returned when an object store responds with an unexpected HTTP status.
+func UnexpectedStatusError(status string, body io.Reader) error {
+	msg, _ := io.ReadAll(io.LimitReader(body, 1024))
+
+	return fmt.Errorf("%w: %s: %s", ErrUnexpectedStatus, status, strings.TrimSpace(string(msg)))
+}
+
+// objectInfo is the metadata for an object in a bucket.
+type objectInfo struct {
+	key  string
+	size int64
+}
+
+// bucket is the interface to an object store bucket.
+type bucket interface {
+	// put will upload the body to the key.
+	put(ctx context.Context, key string, body io.Reader, size int64) error
+
+	// list will return all of the objects with the key prefix.
+	list(ctx context.Context, prefix string) ([]objectInfo, error)
+
+	// delete will remove the object at the key.
+	delete(ctx context.Context, key string) error
+
+	// ping will return an error if the bucket is not accessible.
+	ping(ctx context.Context) error
+}
+
+// objectTxType is a type alias for the object transaction type.
+type objectTxType uint8
+
+const (
+	basicObjectTxID objectTxType = iota
+)
+
+// stagedPart is an encoded part file that is waiting to be uploaded.
+type stagedPart struct {
+	key  string
+	path string
+}
+
+// stage holds the encoded part files of a transaction until it is committed.
+type stage struct {
+	mutex sync.Mutex
+	parts []stagedPart
+
+	// truncated are the tables truncated within the transaction, whose objects are deleted on commit before the
+	// staged parts are uploaded.
+	truncated []string
+}
+
+// Object is a storage device that writes immutable part files to an object store bucket, partitioned by table and
+// date. By default objects are written with keys of the form
+//
+//	prefix/table=candles/dt=2024-01-02/part-<uuid>.ndjson
+//
+// where the date is the day the data was written, or the date of the "partitionBy" column if one is set.
+type Object struct {
+	bucket      bucket
+	prefix      string
+	naming      *naming
+	enc         file.Encoder
+	gzip        bool
+	partitionBy string
+	storageType uint8
+
+	// now returns the current time, used for the default date partition.
+	now func() time.Time
+
+	// activeTx are the transactions that are currently active on this storage device. Parts written within the
+	// context of a transaction are staged on the local disk until the transaction is committed.
+	activeTx sync.Map
+}
+
+// newObject will construct an object storage device from the common connection string parameters "format",
+// "partitionBy", "naming" and "compression".
+func newObject(bkt bucket, uri *url.URL, storageType uint8) (*Object, error) {
+	enc, err := file.NewEncoder(uri.Query().Get("format"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create encoder: %w", err)
+	}
+
+	nmg, err := newNaming(uri.Query().Get("naming"))
+	if err != nil {
+		return nil, err
+	}
+
+	var compressed bool
+
+	switch compression := uri.Query().Get("compression"); compression {
+	case "", CompressionNone:
+	case CompressionGzip:
+		compressed = true
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCompression, compression)
+	}
+
+	return &Object{
+		bucket:      bkt,
+		prefix:      strings.Trim(uri.Path, "/"),
+		naming:      nmg,
+		enc:         enc,
+		gzip:        compressed,
+		partitionBy: uri.Query().Get("partitionBy"),
+		storageType: storageType,
+		now:         time.Now,
+		activeTx:    sync.Map{},
+	}, nil
+}
+
+// Close is a no-op for object storage since requests are not made over a persistent connection.
+func (obj *Object) Close() {}
+
+// IsNoSQL returns "true" since objects are schemaless.
+func (obj *Object) IsNoSQL() bool { return true }
+
+// Type returns the type of storage.
+func (obj *Object) Type() uint8 { return obj.storageType }
+
+// Ping will return an error if the bucket is not accessible.
+func (obj *Object) Ping() error {
+	if err := obj.bucket.ping(context.Background()); err != nil {
+		return fmt.Errorf("connection lost: %w", err)
+	}
+
+	return nil
+}
+
+// prefixed will return the key relative to the bucket root.
+func (obj *Object) prefixed(key string) string {
+	if obj.prefix == "" {
+		return key
+	}
+
+	return obj.prefix + "/" + key
+}
+
+// ext returns the file extension of the part files, including the compression extension.
+func (obj *Object) ext() string {
+	if obj.gzip {
+		return obj.enc.Ext() + ".gz"
+	}
+
+	return obj.enc.Ext()
+}
+
+// partitionRecords will group the records by their date partition.
+func (obj *Object) partitionRecords(records []*structpb.Struct) map[string][]map[string]interface{} {
+	partitions := make(map[string][]map[string]interface{})
+	today := obj.now().UTC().Format("2006-01-02")
+
+	for _, record := range records {
+		rec := record.AsMap()
+
+		date := today
+		if obj.partitionBy != "" {
+			date = file.PartitionDate(rec[obj.partitionBy])
+		}
+
+		partitions[date] = append(partitions[date], rec)
+	}
+
+	return partitions
+}
+
+// encodePart will encode the records into a part file in a temporary directory, returning the path of the file.
+func (obj *Object) encodePart(table string, records []map[string]interface{}) (string, error) {
+	dir, err := os.MkdirTemp("", "gidari-object-*")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary directory: %w", err)
+	}
+
+	name := filepath.Join(dir, table+obj.enc.Ext())
+
+	part, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("unable to create part file: %w", err)
+	}
+	defer part.Close()
+
+	if err := obj.enc.Encode(part, records); err != nil {
+		os.RemoveAll(dir)
+
+		return "", fmt.Errorf("unable to encode part file: %w", err)
+	}
+
+	if !obj.gzip {
+		return name, nil
+	}
+
+	if err := gzipFile(part, name+".gz"); err != nil {
+		os.RemoveAll(dir)
+
+		return "", err
+	}
+
+	return name + ".gz", nil
+}
+
+// gzipFile will write a gzip-compressed copy of the file to the named path.
+func gzipFile(src *os.File, name string) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to seek part file: %w", err)
+	}
+
+	dst, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to create compressed part file: %w", err)
+	}
+	defer dst.Close()
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		return fmt.Errorf("unable to compress part file: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("unable to compress part file: %w", err)
+	}
+
+	return nil
+}
+
+// upload will upload the part file to the key and remove the part file.
+func (obj *Object) upload(ctx context.Context, part stagedPart) error {
+	defer os.RemoveAll(filepath.Dir(part.path))
+
+	body, err := os.Open(part.path)
+	if err != nil {
+		return fmt.Errorf("unable to open part file: %w", err)
+	}
+	defer body.Close()
+
+	info, err := body.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat part file: %w", err)
+	}
+
+	if err := obj.bucket.put(ctx, part.key, body, info.Size()); err != nil {
+		return fmt.Errorf("unable to upload %s: %w", part.key, err)
+	}
+
+	return nil
+}
+
+// getStage will return the transaction stage assigned to the context, if there is one.
+func (obj *Object) getStage(ctx context.Context) (*stage, error) {
+	txID, ok := ctx.Value(basicObjectTxID).(string)
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := obj.activeTx.Load(txID)
+	if !ok {
+		return nil, nil
+	}
+
+	stg, ok := val.(*stage)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return stg, nil
+}
+
+// write will encode a part file for each date partition of the records, and either upload it or stage it if the
+// context has a transaction.
+func (obj *Object) write(ctx context.Context, table string, records []*structpb.Struct) error {
+	stg, err := obj.getStage(ctx)
+	if err != nil {
+		return err
+	}
+
+	for date, partRecords := range obj.partitionRecords(records) {
+		name, err := obj.encodePart(table, partRecords)
+		if err != nil {
+			return err
+		}
+
+		part := stagedPart{
+			key:  obj.prefixed(obj.naming.key(table, date, uuid.New().String(), obj.ext())),
+			path: name,
+		}
+
+		if stg != nil {
+			stg.mutex.Lock()
+			stg.parts = append(stg.parts, part)
+			stg.mutex.Unlock()
+
+			continue
+		}
+
+		if err := obj.upload(ctx, part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Upsert will write the records on the request as new objects. Records are never matched since objects have no notion
+// of a primary key.
+func (obj *Object) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+	records, err := proto.DecodeUpsertRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if len(records) == 0 {
+		return &proto.UpsertResponse{}, nil
+	}
+
+	if err := obj.write(ctx, req.GetTable(), records); err != nil {
+		return nil, fmt.Errorf("unable to write records: %w", err)
+	}
+
+	return &proto.UpsertResponse{
+		UpsertedCount: int64(len(records)),
+		ReceivedCount: int64(len(records)),
+		InsertedCount: int64(len(records)),
+	}, nil
+}
+
+// UpsertBinary will write the "property bag" records on the request as new objects.
+func (obj *Object) UpsertBinary(ctx context.Context,
+	req *proto.UpsertBinaryRequest,
+) (*proto.UpsertBinaryResponse, error) {
+	records, err := proto.DecodeUpsertBinaryRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if len(records) == 0 {
+		return &proto.UpsertBinaryResponse{}, nil
+	}
+
+	if err := obj.write(ctx, req.GetTable(), records); err != nil {
+		return nil, fmt.Errorf("unable to write records: %w", err)
+	}
+
+	return &proto.UpsertBinaryResponse{}, nil
+}
+
+// truncateTable will delete all of the objects for the table.
+func (obj *Object) truncateTable(ctx context.Context, table string) error {
+	objects, err := obj.bucket.list(ctx, obj.prefixed(obj.naming.tableKey(table)))
+	if err != nil {
+		return fmt.Errorf("unable to list objects for %s: %w", table, err)
+	}
+
+	for _, info := range objects {
+		if err := obj.bucket.delete(ctx, info.key); err != nil {
+			return fmt.Errorf("unable to delete %s: %w", info.key, err)
+		}
+	}
+
+	return nil
+}
+
+// Truncate will delete all of the objects for the tables on the request. Within a transaction the objects are
+// deleted on commit, and any parts staged for the tables before the truncate are discarded.
+func (obj *Object) Truncate(ctx context.Context, req *proto.TruncateRequest) (*proto.TruncateResponse, error) {
+	stg, err := obj.getStage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range req.GetTables() {
+		if stg != nil {
+			stg.truncate(obj.prefixed(obj.naming.tableKey(table)), table)
+
+			continue
+		}
+
+		if err := obj.truncateTable(ctx, table); err != nil {
+			return nil, err
+		}
+	}
+
+	return &proto.TruncateResponse{}, nil
+}
+
+// ListTables will return the tables under the prefix, along with the total size in bytes of their objects.
+func (obj *Object) ListTables(ctx context.Context) (*proto.ListTablesResponse, error) {
+	prefix := obj.prefixed("")
+
+	objects, err := obj.bucket.list(ctx, prefix+obj.naming.tableHead)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list objects: %w", err)
+	}
+
+	rsp := &proto.ListTablesResponse{TableSet: make(map[string]*proto.Table)}
+
+	for _, info := range objects {
+		table, ok := obj.naming.tableFromKey(strings.TrimPrefix(info.key, prefix))
+		if !ok {
+			continue
+		}
+
+		if rsp.TableSet[table] == nil {
+			rsp.TableSet[table] = &proto.Table{}
+		}
+
+		rsp.TableSet[table].Size += info.size
+	}
+
+	return rsp, nil
+}
+
+// ListPrimaryKeys will return an empty list of primary keys for every table, since objects have no notion of primary
+// keys.
+func (obj *Object) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeysResponse, error) {
+	tables, err := obj.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+
+	rsp := &proto.ListPrimaryKeysResponse{PKSet: make(map[string]*proto.PrimaryKeys)}
+	for table := range tables.GetTableSet() {
+		rsp.PKSet[table] = &proto.PrimaryKeys{}
+	}
+
+	return rsp, nil
+}
+
+// commitStage will delete the objects of the truncated tables and upload all of the staged part files.
+func (obj *Object) commitStage(ctx context.Context, stg *stage) error {
+	for _, table := range stg.truncated {
+		if err := obj.truncateTable(ctx, table); err != nil {
+			discardStage(stg)
+
+			return err
+		}
+	}
+
+	for idx, part := range stg.parts {
+		if err := obj.upload(ctx, part); err != nil {
+			// Remove the part files that were not uploaded.
+			discardStage(&stage{parts: stg.parts[idx+1:]})
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// truncate will mark the table as truncated and discard the parts staged under the table's key prefix.
+func (stg *stage) truncate(prefix, table string) {
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	parts := stg.parts[:0]
+
+	for _, part := range stg.parts {
+		if strings.HasPrefix(part.key, prefix) {
+			discardStage(&stage{parts: []stagedPart{part}})
+
+			continue
+		}
+
+		parts = append(parts, part)
+	}
+
+	stg.parts = parts
+	stg.truncated = append(stg.truncated, table)
+}
+
+// discardStage will remove all of the staged part files.
+func discardStage(stg *stage) {
+	for _, part := range stg.parts {
+		os.RemoveAll(filepath.Dir(part.path))
+	}
+}
+
+// StartTx will start a transaction on the bucket. Part files written to the transaction are staged on the local disk,
+// and uploaded on commit or discarded on rollback. Tables truncated in the transaction are deleted on commit, before
+// the staged parts are uploaded.
+func (obj *Object) StartTx(ctx context.Context) (*proto.Txn, error) {
+	txn := &proto.Txn{
+		FunctionCh: make(chan proto.TxnChanFn),
+		DoneCh:     make(chan error, 1),
+		CommitCh:   make(chan bool, 1),
+	}
+
+	txnID := uuid.New().String()
+	stg := &stage{}
+
+	obj.activeTx.Store(txnID, stg)
+
+	// Create a copy of the parent context with a transaction ID.
+	objCtx := context.WithValue(ctx, basicObjectTxID, txnID)
+
+	go func() {
+		defer obj.activeTx.Delete(txnID)
+
+		var err error
+
+		for fn := range txn.FunctionCh {
+			if err != nil {
+				continue
+			}
+
+			err = fn(objCtx, obj)
+		}
+
+		if err != nil {
+			discardStage(stg)
+			txn.DoneCh <- err
+
+			return
+		}
+
+		if <-txn.CommitCh {
+			txn.DoneCh <- obj.commitStage(ctx, stg)
+		} else {
+			discardStage(stg)
+			txn.DoneCh <- nil
+		}
+	}()
+
+	return txn, nil
+}
@@ -0,0 +1,363 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package object
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+
+	// s3UnsignedPayload is used in place of the payload hash so that bodies can be streamed without hashing them
+	// first.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	amzDateLayout = "20060102T150405Z"
+)
+
+var ErrMissingS3Bucket = fmt.Errorf("missing s3 bucket")
+
+// s3Credentials are the AWS credentials used to sign requests.
+type s3Credentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// s3Bucket is an S3 bucket accessed through the S3 REST API, with requests signed using AWS Signature Version 4.
+type s3Bucket struct {
+	client    *http.Client
+	endpoint  *url.URL
+	name      string
+	pathStyle bool
+	region    string
+	creds     s3Credentials
+	now       func() time.Time
+}
+
+// NewS3 will return an object storage device for an S3 bucket. The connection string is of the form
+//
+//	s3://[accessKeyID:secretAccessKey@]bucket/prefix?region=us-east-1&endpoint=http://localhost:9000&format=ndjson
+//
+// Credentials default to the "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY" and "AWS_SESSION_TOKEN" environment
+// variables, and the region defaults to "AWS_REGION". If "endpoint" is set, requests are made using path-style URLs
+// for compatibility with S3-compatible object stores.
+func NewS3(_ context.Context, dns string) (*Object, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse s3 connection string: %w", err)
+	}
+
+	if uri.Host == "" {
+		return nil, ErrMissingS3Bucket
+	}
+
+	bkt := &s3Bucket{
+		client: &http.Client{},
+		name:   uri.Host,
+		region: firstNonEmpty(uri.Query().Get("region"), os.Getenv("AWS_REGION"), defaultS3Region),
+		creds: s3Credentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		now: time.Now,
+	}
+
+	if uri.User != nil {
+		bkt.creds.accessKeyID = uri.User.Username()
+		bkt.creds.secretAccessKey, _ = uri.User.Password()
+	}
+
+	if endpoint := uri.Query().Get("endpoint"); endpoint != "" {
+		if bkt.endpoint, err = url.Parse(endpoint); err != nil {
+			return nil, fmt.Errorf("unable to parse s3 endpoint: %w", err)
+		}
+
+		bkt.pathStyle = true
+	} else {
+		bkt.endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", bkt.name, bkt.region)}
+	}
+
+	return newObject(bkt, uri, proto.S3Type)
+}
+
+// firstNonEmpty will return the first non-empty string.
+func firstNonEmpty(vals ...string) string {
+	for _, val := range vals {
+		if val != "" {
+			return val
+		}
+	}
+
+	return ""
+}
+
+// awsEscape will percent-encode a string as required by Signature Version 4, leaving only unreserved characters.
+// Slashes are left unencoded if "path" is true.
+func awsEscape(val string, path bool) string {
+	var bldr strings.Builder
+
+	for _, char := range []byte(val) {
+		switch {
+		case 'A' <= char && char <= 'Z', 'a' <= char && char <= 'z', '0' <= char && char <= '9',
+			char == '-', char == '_', char == '.', char == '~', path && char == '/':
+			bldr.WriteByte(char)
+		default:
+			fmt.Fprintf(&bldr, "%%%02X", char)
+		}
+	}
+
+	return bldr.String()
+}
+
+// canonicalQuery will return the query string sorted and encoded as required by Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var pairs []string
+
+	for _, key := range keys {
+		vals := append([]string{}, query[key]...)
+		sort.Strings(vals)
+
+		for _, val := range vals {
+			pairs = append(pairs, awsEscape(key, false)+"="+awsEscape(val, false))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// objectURL will return the URL of a key in the bucket.
+func (bkt *s3Bucket) objectURL(key string, query url.Values) *url.URL {
+	rurl := *bkt.endpoint
+
+	objectPath := "/" + key
+	if bkt.pathStyle {
+		objectPath = strings.TrimSuffix(rurl.Path, "/") + "/" + bkt.name
+		if key != "" {
+			objectPath += "/" + key
+		}
+	}
+
+	rurl.Path = objectPath
+	rurl.RawPath = awsEscape(objectPath, true)
+	rurl.RawQuery = canonicalQuery(query)
+
+	return &rurl
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+
+	// Don't handle error because hash.Write method never returns an error.
+	hash.Write([]byte(data))
+
+	return hash.Sum(nil)
+}
+
+// sign will sign the request using AWS Signature Version 4. The host and all of the headers on the request are signed.
+func (bkt *s3Bucket) sign(req *http.Request, payloadHash string) {
+	now := bkt.now().UTC()
+	amzDate := now.Format(amzDateLayout)
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	if bkt.creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", bkt.creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, vals := range req.Header {
+		trimmed := make([]string, len(vals))
+		for idx, val := range vals {
+			trimmed[idx] = strings.Join(strings.Fields(val), " ")
+		}
+
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{date, bkt.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+bkt.creds.secretAccessKey), date)
+	key = hmacSHA256(key, bkt.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		bkt.creds.accessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// do will sign and send the request, returning an error if the response status is not 2xx.
+func (bkt *s3Bucket) do(req *http.Request) (*http.Response, error) {
+	bkt.sign(req, s3UnsignedPayload)
+
+	rsp, err := bkt.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if rsp.StatusCode < http.StatusOK || rsp.StatusCode >= http.StatusMultipleChoices {
+		defer rsp.Body.Close()
+
+		return nil, UnexpectedStatusError(rsp.Status, rsp.Body)
+	}
+
+	return rsp, nil
+}
+
+func (bkt *s3Bucket) put(ctx context.Context, key string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, bkt.objectURL(key, nil).String(), body)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+// s3ListBucketResult is the response body of the "ListObjectsV2" action.
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (bkt *s3Bucket) list(ctx context.Context, prefix string) ([]objectInfo, error) {
+	var (
+		objects []objectInfo
+		token   string
+	)
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, bkt.objectURL("", query).String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+
+		rsp, err := bkt.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListBucketResult
+
+		err = xml.NewDecoder(rsp.Body).Decode(&result)
+		rsp.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode list response: %w", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, objectInfo{key: content.Key, size: content.Size})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+func (bkt *s3Bucket) delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, bkt.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
+
+func (bkt *s3Bucket) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, bkt.objectURL("", nil).String(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	rsp, err := bkt.do(req)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Body.Close()
+}
@@ -0,0 +1,288 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var ErrTransactionNotFound = fmt.Errorf("transaction not found")
+
+// pipeTxType is a type alias for the pipe transaction type.
+type pipeTxType uint8
+
+const (
+	basicPipeTxID pipeTxType = iota
+)
+
+// stage buffers the encoded records of a transaction until it is committed.
+type stage struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+	sizes map[string]int64
+}
+
+// Pipe is a storage device that writes records as newline-delimited JSON to a stream, such as stdout, so that the
+// output can be piped into other tools.
+type Pipe struct {
+	out io.Writer
+
+	// tableField is the field that the table name is written to on each record, if any.
+	tableField string
+
+	writeMutex sync.Mutex
+
+	// sizes are the number of bytes written for each table.
+	sizes map[string]int64
+
+	// activeTx are the transactions that are currently active on this storage device. Records written within the
+	// context of a transaction are buffered in memory until the transaction is committed.
+	activeTx sync.Map
+}
+
+// New will return a Pipe storage device that writes to stdout. The connection string is of the form
+//
+//	stdout://?tableField=table
+//
+// where "tableField" is optional and names a field to add to every record with the name of its table, so that the
+// records of different tables can be told apart in the stream.
+func New(_ context.Context, dns string) (*Pipe, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse stdout connection string: %w", err)
+	}
+
+	return newPipe(os.Stdout, uri.Query().Get("tableField")), nil
+}
+
+func newPipe(out io.Writer, tableField string) *Pipe {
+	return &Pipe{
+		out:        out,
+		tableField: tableField,
+		sizes:      make(map[string]int64),
+		activeTx:   sync.Map{},
+	}
+}
+
+// Close is a no-op since the stream is owned by the process.
+func (p *Pipe) Close() {}
+
+// IsNoSQL returns "true" since records are written as schemaless JSON.
+func (p *Pipe) IsNoSQL() bool { return true }
+
+// Type returns the type of storage.
+func (p *Pipe) Type() uint8 { return proto.StdoutType }
+
+// Ping always succeeds since the stream is always available.
+func (p *Pipe) Ping() error { return nil }
+
+// encode will encode the records as newline-delimited JSON.
+func (p *Pipe) encode(table string, records []*structpb.Struct) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	for _, record := range records {
+		rec := record.AsMap()
+		if p.tableField != "" {
+			rec[p.tableField] = table
+		}
+
+		if err := enc.Encode(rec); err != nil {
+			return nil, fmt.Errorf("unable to encode record: %w", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// getStage will return the transaction stage assigned to the context, if there is one.
+func (p *Pipe) getStage(ctx context.Context) (*stage, error) {
+	txID, ok := ctx.Value(basicPipeTxID).(string)
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := p.activeTx.Load(txID)
+	if !ok {
+		return nil, nil
+	}
+
+	stg, ok := val.(*stage)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return stg, nil
+}
+
+// flush will write the encoded records to the stream and record the number of bytes written for each table.
+func (p *Pipe) flush(data []byte, sizes map[string]int64) error {
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+
+	if _, err := p.out.Write(data); err != nil {
+		return fmt.Errorf("unable to write records: %w", err)
+	}
+
+	for table, size := range sizes {
+		p.sizes[table] += size
+	}
+
+	return nil
+}
+
+// write will write the records to the stream, or stage them if the context has a transaction.
+func (p *Pipe) write(ctx context.Context, table string, records []*structpb.Struct) error {
+	data, err := p.encode(table, records)
+	if err != nil {
+		return err
+	}
+
+	stg, err := p.getStage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stg == nil {
+		return p.flush(data, map[string]int64{table: int64(len(data))})
+	}
+
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	stg.buf.Write(data)
+	stg.sizes[table] += int64(len(data))
+
+	return nil
+}
+
+// Upsert will write the records on the request to the stream. Records are never matched since a stream has no notion
+// of a primary key.
+func (p *Pipe) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+	records, err := proto.DecodeUpsertRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if err := p.write(ctx, req.GetTable(), records); err != nil {
+		return nil, err
+	}
+
+	return &proto.UpsertResponse{
+		UpsertedCount: int64(len(records)),
+		ReceivedCount: int64(len(records)),
+		InsertedCount: int64(len(records)),
+	}, nil
+}
+
+// UpsertBinary will write the "property bag" records on the request to the stream.
+func (p *Pipe) UpsertBinary(ctx context.Context, req *proto.UpsertBinaryRequest) (*proto.UpsertBinaryResponse, error) {
+	records, err := proto.DecodeUpsertBinaryRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if err := p.write(ctx, req.GetTable(), records); err != nil {
+		return nil, err
+	}
+
+	return &proto.UpsertBinaryResponse{}, nil
+}
+
+// Truncate is a no-op since records that have been written to a stream cannot be removed.
+func (p *Pipe) Truncate(_ context.Context, _ *proto.TruncateRequest) (*proto.TruncateResponse, error) {
+	return &proto.TruncateResponse{}, nil
+}
+
+// ListTables will return the tables that have been written to the stream by this storage device, along with the
+// number of bytes written for each.
+func (p *Pipe) ListTables(_ context.Context) (*proto.ListTablesResponse, error) {
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+
+	rsp := &proto.ListTablesResponse{TableSet: make(map[string]*proto.Table)}
+	for table, size := range p.sizes {
+		rsp.TableSet[table] = &proto.Table{Size: size}
+	}
+
+	return rsp, nil
+}
+
+// ListPrimaryKeys will return an empty list of primary keys for every table, since a stream has no notion of primary
+// keys.
+func (p *Pipe) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeysResponse, error) {
+	tables, err := p.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+
+	rsp := &proto.ListPrimaryKeysResponse{PKSet: make(map[string]*proto.PrimaryKeys)}
+	for table := range tables.GetTableSet() {
+		rsp.PKSet[table] = &proto.PrimaryKeys{}
+	}
+
+	return rsp, nil
+}
+
+// StartTx will start a transaction on the stream. Records written to the transaction are buffered in memory, and
+// written on commit or discarded on rollback.
+func (p *Pipe) StartTx(ctx context.Context) (*proto.Txn, error) {
+	txn := &proto.Txn{
+		FunctionCh: make(chan proto.TxnChanFn),
+		DoneCh:     make(chan error, 1),
+		CommitCh:   make(chan bool, 1),
+	}
+
+	txnID := uuid.New().String()
+	stg := &stage{sizes: make(map[string]int64)}
+
+	p.activeTx.Store(txnID, stg)
+
+	// Create a copy of the parent context with a transaction ID.
+	pipeCtx := context.WithValue(ctx, basicPipeTxID, txnID)
+
+	go func() {
+		defer p.activeTx.Delete(txnID)
+
+		var err error
+
+		for fn := range txn.FunctionCh {
+			if err != nil {
+				continue
+			}
+
+			err = fn(pipeCtx, p)
+		}
+
+		if err != nil {
+			txn.DoneCh <- err
+
+			return
+		}
+
+		if <-txn.CommitCh {
+			txn.DoneCh <- p.flush(stg.buf.Bytes(), stg.sizes)
+		} else {
+			txn.DoneCh <- nil
+		}
+	}()
+
+	return txn, nil
+}
@@ -0,0 +1,92 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestPipe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("upsert", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+
+		var out bytes.Buffer
+
+		stg := newPipe(&out, "table")
+
+		_, err := stg.Upsert(ctx, &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":1},{"id":2}]`)})
+		if err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		expected := "{\"id\":1,\"table\":\"candles\"}\n{\"id\":2,\"table\":\"candles\"}\n"
+		if out.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, out.String())
+		}
+
+		tables, err := stg.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+
+		if size := tables.GetTableSet()["candles"].GetSize(); size != int64(len(expected)) {
+			t.Fatalf("expected size %d, got %d", len(expected), size)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		for _, commit := range []bool{true, false} {
+			ctx := context.Background()
+
+			var out bytes.Buffer
+
+			stg := newPipe(&out, "")
+
+			txn, err := stg.StartTx(ctx)
+			if err != nil {
+				t.Fatalf("failed to start transaction: %v", err)
+			}
+
+			txn.Send(func(sctx context.Context, stg proto.Storage) error {
+				_, err := stg.Upsert(sctx, &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":1}]`)})
+
+				return err
+			})
+
+			if out.Len() != 0 {
+				t.Fatalf("expected no output before commit, got %q", out.String())
+			}
+
+			if commit {
+				err = txn.Commit()
+			} else {
+				err = txn.Rollback()
+			}
+
+			if err != nil {
+				t.Fatalf("failed to close transaction: %v", err)
+			}
+
+			if expected := map[bool]string{true: "{\"id\":1}\n"}[commit]; out.String() != expected {
+				t.Fatalf("expected %q after commit=%v, got %q", expected, commit, out.String())
+			}
+		}
+	})
+}
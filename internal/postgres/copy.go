@@ -0,0 +1,129 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// copyTable is the temporary table that records are copied into before they are merged into their table.
+const copyTable = "gidari_copy"
+
+// copyMergeQuery will return the statement that merges the records copied into the temporary table into the table,
+// resolving conflicts as described by "conflictClause".
+func (meta *pgmeta) copyMergeQuery(table string, pks []string, conflict string) string {
+	cols := quoteIdentifiers(meta.cols[table])
+
+	return fmt.Sprintf(`INSERT INTO %s(%s) SELECT %s FROM %s`, table, cols, cols, copyTable) +
+		meta.conflictClause(table, pks, conflict)
+}
+
+// copyTx will return the transaction on the context. If there is none, a transaction is started that must be
+// committed by the caller, since the temporary table only exists for the session that creates it.
+func (pg *Postgres) copyTx(ctx context.Context) (*sql.Tx, bool, error) {
+	if txID, ok := ctx.Value(basicPostgressTxID).(string); ok {
+		if tx, ok := pg.activeTx.Load(txID); ok {
+			tx, ok := tx.(*sql.Tx)
+			if !ok {
+				return nil, false, ErrTransactionNotFound
+			}
+
+			return tx, false, nil
+		}
+	}
+
+	tx, err := pg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, transientError(err)
+	}
+
+	return tx, true, nil
+}
+
+// copyUpsert will load the records into a temporary table with COPY, and then merge them into the table with a
+// single upsert statement. This is much faster than inserting large batches 1000 records at a time.
+func (pg *Postgres) copyUpsert(ctx context.Context, table string, pks []string, conflict string,
+	records []*structpb.Struct,
+) (*proto.UpsertResponse, error) {
+	tx, owned, err := pg.copyTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transaction: %w", err)
+	}
+
+	if owned {
+		// Rolling back a committed transaction does nothing.
+		defer func() { _ = tx.Rollback() }()
+	}
+
+	cols := pg.meta.cols[table]
+
+	create := fmt.Sprintf(`CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP`, copyTable, table)
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("unable to create temporary table: %w", err)
+	}
+
+	if err := copyRecords(ctx, tx, cols, records); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, pg.meta.copyMergeQuery(table, pks, conflict))
+	if err != nil {
+		return nil, upsertError(err)
+	}
+
+	inserted, updated, err := scanUpserted(rows)
+	if err != nil {
+		return nil, upsertError(err)
+	}
+
+	// The temporary table is dropped so that another batch can be copied in the same transaction.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, copyTable)); err != nil {
+		return nil, fmt.Errorf("unable to drop temporary table: %w", err)
+	}
+
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("unable to commit transaction: %w", transientError(err))
+		}
+	}
+
+	return &proto.UpsertResponse{InsertedCount: inserted, UpdatedCount: updated}, nil
+}
+
+// copyRecords will copy the records into the temporary table.
+func copyRecords(ctx context.Context, tx *sql.Tx, cols []string, records []*structpb.Struct) error {
+	args, err := flattenPartition(cols, records)
+	if err != nil {
+		return fmt.Errorf("unable to flatten partition: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(copyTable, cols...))
+	if err != nil {
+		return fmt.Errorf("unable to prepare copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for start := 0; start < len(args); start += len(cols) {
+		if _, err := stmt.ExecContext(ctx, args[start:start+len(cols)]...); err != nil {
+			return fmt.Errorf("unable to copy record: %w", err)
+		}
+	}
+
+	// Executing the statement without arguments flushes the copied records.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("unable to copy records: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import "testing"
+
+func TestCopyMergeQuery(t *testing.T) {
+	t.Parallel()
+
+	meta := &pgmeta{
+		cols: map[string][]string{"candles": {"id", "close"}},
+		pks:  map[string][]string{"candles": {"id"}},
+	}
+
+	for _, tcase := range []struct {
+		conflict string
+		expected string
+	}{
+		{
+			expected: `INSERT INTO candles("id","close") SELECT "id","close" FROM gidari_copy ON CONFLICT ("id") DO UPDATE SET "close" = EXCLUDED."close" RETURNING (xmax = 0)`,
+		},
+		{
+			conflict: "insert-only",
+			expected: `INSERT INTO candles("id","close") SELECT "id","close" FROM gidari_copy ON CONFLICT ("id") DO NOTHING RETURNING (xmax = 0)`,
+		},
+		{
+			conflict: "fail",
+			expected: `INSERT INTO candles("id","close") SELECT "id","close" FROM gidari_copy RETURNING (xmax = 0)`,
+		},
+	} {
+		if got := meta.copyMergeQuery("candles", nil, tcase.conflict); got != tcase.expected {
+			t.Errorf("conflict %q: expected %q, got %q", tcase.conflict, tcase.expected, got)
+		}
+	}
+}
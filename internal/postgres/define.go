@@ -0,0 +1,82 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// pgColumnTypes are the Postgres types of the columns of declared tables.
+var pgColumnTypes = map[string]string{
+	proto.ColumnTypeString:    pgText,
+	proto.ColumnTypeInteger:   pgBigint,
+	proto.ColumnTypeNumber:    pgDouble,
+	proto.ColumnTypeDecimal:   pgNumeric,
+	proto.ColumnTypeBoolean:   pgBoolean,
+	proto.ColumnTypeTimestamp: pgTimestamp,
+	proto.ColumnTypeJSON:      pgJSONB,
+}
+
+// defineTable will return the definition of the declared table. If there is an overflow column, it is added as a
+// JSONB column.
+func defineTable(req *proto.DefineTableRequest, overflowColumn string) (*tableDef, error) {
+	def := &tableDef{
+		types:    make(map[string]string, len(req.Columns)+1),
+		required: make(map[string]bool),
+		pks:      req.PrimaryKeys,
+	}
+
+	for _, col := range req.Columns {
+		pgType, ok := pgColumnTypes[col.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q of column %q", col.Type, col.Name)
+		}
+
+		if _, ok := def.types[col.Name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", col.Name)
+		}
+
+		def.cols = append(def.cols, col.Name)
+		def.types[col.Name] = pgType
+
+		if col.Required {
+			def.required[col.Name] = true
+		}
+	}
+
+	if _, ok := def.types[overflowColumn]; !ok && overflowColumn != "" {
+		def.cols = append(def.cols, overflowColumn)
+		def.types[overflowColumn] = pgJSONB
+	}
+
+	sort.Strings(def.cols)
+
+	return def, nil
+}
+
+// DefineTable will declare the schema of the table, so that the table is created with the declared columns, types, and
+// primary keys if it does not exist and "createTables" is set, instead of ones inferred from the first records that
+// are written to it. Required columns are NOT NULL. Tables that already exist are not changed.
+func (pg *Postgres) DefineTable(_ context.Context, req *proto.DefineTableRequest) error {
+	def, err := defineTable(req, pg.opts.overflowColumn)
+	if err != nil {
+		return fmt.Errorf("unable to define table %s: %w", req.Table, err)
+	}
+
+	pg.writeMutex.Lock()
+	defer pg.writeMutex.Unlock()
+
+	pg.partitionDef(req.Table, def)
+	pg.created[req.Table] = def
+
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestDefineTable(t *testing.T) {
+	t.Parallel()
+
+	req := &proto.DefineTableRequest{
+		Table: "trades",
+		Columns: []*proto.ColumnDefinition{
+			{Name: "trade_id", Type: proto.ColumnTypeInteger, Required: true},
+			{Name: "price", Type: proto.ColumnTypeDecimal},
+			{Name: "side", Type: proto.ColumnTypeString},
+			{Name: "settled", Type: proto.ColumnTypeBoolean},
+			{Name: "meta", Type: proto.ColumnTypeJSON},
+			{Name: "size", Type: proto.ColumnTypeNumber},
+		},
+		PrimaryKeys: []string{"trade_id"},
+	}
+
+	pg := &Postgres{opts: &connectionOptions{overflowColumn: "extra"}, created: make(map[string]*tableDef)}
+	if err := pg.DefineTable(context.Background(), req); err != nil {
+		t.Fatalf("failed to define table: %v", err)
+	}
+
+	expected := `CREATE TABLE IF NOT EXISTS trades ("extra" JSONB,"meta" JSONB,"price" NUMERIC,"settled" BOOLEAN,` +
+		`"side" TEXT,"size" DOUBLE PRECISION,"trade_id" BIGINT NOT NULL,PRIMARY KEY ("trade_id"))`
+	if got := pg.created["trades"].createStmt("trades"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	// The partition column of a partitioned table is added to the columns and the primary key.
+	pg.opts.partitions = map[string]*partitioning{"trades": {column: "time"}}
+	if err := pg.DefineTable(context.Background(), req); err != nil {
+		t.Fatalf("failed to define table: %v", err)
+	}
+
+	if def := pg.created["trades"]; def.types["time"] != pgTimestamp || len(def.pks) != 2 || def.partitionBy != "time" {
+		t.Errorf("expected a partitioned definition, got %+v", def)
+	}
+
+	for _, cols := range [][]*proto.ColumnDefinition{
+		{{Name: "id", Type: "uuid"}},
+		{{Name: "id", Type: proto.ColumnTypeString}, {Name: "id", Type: proto.ColumnTypeInteger}},
+	} {
+		if err := pg.DefineTable(context.Background(), &proto.DefineTableRequest{Table: "x", Columns: cols}); err == nil {
+			t.Errorf("expected an error for %v", cols)
+		}
+	}
+}
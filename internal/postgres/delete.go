@@ -0,0 +1,56 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/lib/pq"
+)
+
+// deleteStmt will return the statement that deletes the records of the table that match the request, and the
+// arguments for its parameters.
+func deleteStmt(req *proto.DeleteRequest) (string, []interface{}) {
+	where, args := whereClause(req.Match, req.Range)
+
+	return fmt.Sprintf("DELETE FROM %s%s", pq.QuoteIdentifier(req.Table), where), args
+}
+
+// Delete will delete the records of the table that match the request.
+func (pg *Postgres) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	pg.writeMutex.Lock()
+	defer pg.writeMutex.Unlock()
+
+	prepareContextFn, err := pg.getPrepareContextFn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get preparer: %w", err)
+	}
+
+	query, args := deleteStmt(req)
+
+	stmt, err := prepareContextFn(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare statement: %w", err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute delete: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get deleted count: %w", err)
+	}
+
+	return &proto.DeleteResponse{DeletedCount: deleted}, nil
+}
@@ -0,0 +1,47 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestDeleteStmt(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	stmt, args := deleteStmt(&proto.DeleteRequest{
+		Table: "candles",
+		Match: map[string]interface{}{"product_id": "BTC-USD", "exchange": "coinbase"},
+		Range: &proto.TimeRange{Column: "time", Start: start, End: start.Add(time.Hour)},
+	})
+
+	expected := `DELETE FROM "candles" WHERE "exchange" = $1 AND "product_id" = $2 AND "time" >= $3 AND "time" < $4`
+	if stmt != expected {
+		t.Errorf("expected %s, got %s", expected, stmt)
+	}
+
+	expectedArgs := []interface{}{"coinbase", "BTC-USD", "2022-05-10T00:00:00Z", "2022-05-10T01:00:00Z"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected %v, got %v", expectedArgs, args)
+	}
+
+	stmt, args = deleteStmt(&proto.DeleteRequest{Table: "candles", Range: &proto.TimeRange{Column: "time", End: start}})
+
+	expected = `DELETE FROM "candles" WHERE "time" < $1`
+	if stmt != expected || !reflect.DeepEqual(args, []interface{}{"2022-05-10T00:00:00Z"}) {
+		t.Errorf("expected %s without a lower bound, got %s with %v", expected, stmt, args)
+	}
+}
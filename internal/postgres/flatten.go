@@ -0,0 +1,76 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// defaultFlattenSeparator is the separator between the names of the parent and child fields of a flattened column.
+const defaultFlattenSeparator = "_"
+
+// separator will return the separator between the names of the parent and child fields of a flattened column.
+func (opts *connectionOptions) separator() string {
+	if opts.flattenSeparator == "" {
+		return defaultFlattenSeparator
+	}
+
+	return opts.flattenSeparator
+}
+
+// flattenRecords will return a copy of the records with the fields of nested objects moved into columns of the form
+// "parent_child", up to the depth of nesting. A depth of zero flattens every level. Lists, empty objects, and objects
+// deeper than the depth are left as they are.
+func flattenRecords(records []*structpb.Struct, separator string, depth int) ([]*structpb.Struct, error) {
+	flattened := make([]*structpb.Struct, len(records))
+
+	for idx, record := range records {
+		flat := make(map[string]interface{})
+		flattenInto(flat, "", record.AsMap(), separator, depth, 1)
+
+		var err error
+
+		if flattened[idx], err = structpb.NewStruct(flat); err != nil {
+			return nil, fmt.Errorf("unable to create flattened record: %w", err)
+		}
+	}
+
+	return flattened, nil
+}
+
+// flattenInto will set the fields of the object on the flat record, with their names prefixed by the name of their
+// parent. Fields are visited in order, so that the columns of a record are the same each time it is flattened.
+func flattenInto(flat map[string]interface{}, prefix string, obj map[string]interface{}, separator string,
+	depth, level int,
+) {
+	fields := make([]string, 0, len(obj))
+	for field := range obj {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		column := field
+		if prefix != "" {
+			column = prefix + separator + field
+		}
+
+		nested, ok := obj[field].(map[string]interface{})
+		if ok && len(nested) > 0 && (depth == 0 || level <= depth) {
+			flattenInto(flat, column, nested, separator, depth, level+1)
+
+			continue
+		}
+
+		flat[column] = obj[field]
+	}
+}
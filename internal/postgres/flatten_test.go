@@ -0,0 +1,63 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFlattenRecords(t *testing.T) {
+	t.Parallel()
+
+	record, err := structpb.NewStruct(map[string]interface{}{
+		"id":    "1",
+		"quote": map[string]interface{}{"usd": map[string]interface{}{"price": 1.5}, "tags": []interface{}{"a"}},
+		"meta":  map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create record: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		name      string
+		separator string
+		depth     int
+		expected  map[string]interface{}
+	}{
+		{
+			name:      "every level",
+			separator: "_",
+			expected: map[string]interface{}{
+				"id": "1", "quote_usd_price": 1.5, "quote_tags": []interface{}{"a"}, "meta": map[string]interface{}{},
+			},
+		},
+		{
+			name:      "depth",
+			separator: ".",
+			depth:     1,
+			expected: map[string]interface{}{
+				"id": "1", "quote.usd": map[string]interface{}{"price": 1.5}, "quote.tags": []interface{}{"a"},
+				"meta": map[string]interface{}{},
+			},
+		},
+	} {
+		flattened, err := flattenRecords([]*structpb.Struct{record}, tcase.separator, tcase.depth)
+		if err != nil {
+			t.Fatalf("%s: failed to flatten records: %v", tcase.name, err)
+		}
+
+		if got := flattened[0].AsMap(); !reflect.DeepEqual(got, tcase.expected) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.expected, got)
+		}
+	}
+}
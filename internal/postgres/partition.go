@@ -0,0 +1,135 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Intervals of the partitions of a partitioned table.
+const (
+	partitionDaily   = "day"
+	partitionMonthly = "month"
+)
+
+var ErrInvalidPartition = fmt.Errorf("invalid partition option")
+
+// partitioning is how a table is partitioned by range on a time column.
+type partitioning struct {
+	column   string
+	interval string
+}
+
+// parsePartitions will parse the "partition" connection string options into a map of table names to their
+// partitioning. Each option is of the form "table:column[:interval]", where the interval is "month" by default.
+func parsePartitions(values []string) (map[string]*partitioning, error) {
+	tables := make(map[string]*partitioning)
+
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPartition, value)
+		}
+
+		opts := &partitioning{column: parts[1], interval: partitionMonthly}
+		if len(parts) > 2 {
+			switch parts[2] {
+			case partitionDaily, partitionMonthly:
+				opts.interval = parts[2]
+			default:
+				return nil, fmt.Errorf("%w: unknown interval %q", ErrInvalidPartition, parts[2])
+			}
+		}
+
+		if _, ok := tables[parts[0]]; ok {
+			return nil, fmt.Errorf("%w: duplicate table %q", ErrInvalidPartition, parts[0])
+		}
+
+		tables[parts[0]] = opts
+	}
+
+	return tables, nil
+}
+
+// bounds will return the start and end of the partition that the time is in.
+func (p *partitioning) bounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+
+	if p.interval == partitionDaily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+		return start, start.AddDate(0, 0, 1)
+	}
+
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return start, start.AddDate(0, 1, 0)
+}
+
+// createStmt will return the statement to create the partition of the table that starts at the time, if it does not
+// exist. Partitions are named after the table and their start, e.g. "candles_2022_05" or "candles_2022_05_10".
+func (p *partitioning) createStmt(table string, start time.Time) string {
+	layout := "2006_01"
+	if p.interval == partitionDaily {
+		layout = "2006_01_02"
+	}
+
+	start, end := p.bounds(start)
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s_%s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')", table,
+		start.Format(layout), table, start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
+
+// starts will return the start of each partition that the records are in, in order. The partition column of every
+// record must be an RFC 3339 timestamp.
+func (p *partitioning) starts(records []*structpb.Struct) ([]time.Time, error) {
+	seen := make(map[time.Time]bool)
+	starts := []time.Time{}
+
+	for _, record := range records {
+		val := record.GetFields()[p.column].GetStringValue()
+
+		t, err := time.Parse(time.RFC3339Nano, val)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is not an RFC 3339 timestamp: %q", ErrInvalidPartition, p.column, val)
+		}
+
+		if start, _ := p.bounds(t); !seen[start] {
+			seen[start] = true
+			starts = append(starts, start)
+		}
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	return starts, nil
+}
+
+// createPartitions will create the partitions of the table that start at the times, if they do not exist.
+func (pg *Postgres) createPartitions(ctx context.Context, table string, p *partitioning, pcf sqlPrepareContextFn,
+	starts []time.Time,
+) error {
+	for _, start := range starts {
+		stmt, err := pcf(ctx, p.createStmt(table, start))
+		if err != nil {
+			return fmt.Errorf("unable to prepare statement: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return fmt.Errorf("unable to create partition of %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPartition(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parse", func(t *testing.T) {
+		t.Parallel()
+
+		for _, values := range [][]string{
+			{"candles"},
+			{":time"},
+			{"candles:time:day:extra"},
+			{"candles:time", "candles:ts"},
+		} {
+			if _, err := parsePartitions(values); !errors.Is(err, ErrInvalidPartition) {
+				t.Errorf("%v: expected %v, got %v", values, ErrInvalidPartition, err)
+			}
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		t.Parallel()
+
+		records := make([]*structpb.Struct, 0, 3)
+
+		for _, ts := range []string{"2022-06-01T00:00:00Z", "2022-05-31T23:59:59.5Z", "2022-05-10T01:00:00Z"} {
+			record, err := structpb.NewStruct(map[string]interface{}{"time": ts})
+			if err != nil {
+				t.Fatalf("failed to create record: %v", err)
+			}
+
+			records = append(records, record)
+		}
+
+		for _, tcase := range []struct {
+			interval string
+			expected []string
+		}{
+			{
+				interval: partitionMonthly,
+				expected: []string{
+					"CREATE TABLE IF NOT EXISTS candles_2022_05 PARTITION OF candles " +
+						"FOR VALUES FROM ('2022-05-01T00:00:00Z') TO ('2022-06-01T00:00:00Z')",
+					"CREATE TABLE IF NOT EXISTS candles_2022_06 PARTITION OF candles " +
+						"FOR VALUES FROM ('2022-06-01T00:00:00Z') TO ('2022-07-01T00:00:00Z')",
+				},
+			},
+			{
+				interval: partitionDaily,
+				expected: []string{
+					"CREATE TABLE IF NOT EXISTS candles_2022_05_10 PARTITION OF candles " +
+						"FOR VALUES FROM ('2022-05-10T00:00:00Z') TO ('2022-05-11T00:00:00Z')",
+					"CREATE TABLE IF NOT EXISTS candles_2022_05_31 PARTITION OF candles " +
+						"FOR VALUES FROM ('2022-05-31T00:00:00Z') TO ('2022-06-01T00:00:00Z')",
+					"CREATE TABLE IF NOT EXISTS candles_2022_06_01 PARTITION OF candles " +
+						"FOR VALUES FROM ('2022-06-01T00:00:00Z') TO ('2022-06-02T00:00:00Z')",
+				},
+			},
+		} {
+			p := &partitioning{column: "time", interval: tcase.interval}
+
+			starts, err := p.starts(records)
+			if err != nil {
+				t.Fatalf("failed to find partitions: %v", err)
+			}
+
+			if len(starts) != len(tcase.expected) {
+				t.Fatalf("%s: expected %d partitions, got %v", tcase.interval, len(tcase.expected), starts)
+			}
+
+			for idx, start := range starts {
+				if got := p.createStmt("candles", start); got != tcase.expected[idx] {
+					t.Errorf("%s: expected %q, got %q", tcase.interval, tcase.expected[idx], got)
+				}
+			}
+		}
+
+		record, _ := structpb.NewStruct(map[string]interface{}{"time": 1652144400})
+
+		p := &partitioning{column: "time", interval: partitionMonthly}
+		if _, err := p.starts([]*structpb.Struct{record}); !errors.Is(err, ErrInvalidPartition) {
+			t.Errorf("expected %v, got %v", ErrInvalidPartition, err)
+		}
+	})
+}
@@ -10,13 +10,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"net"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/alpstable/gidari/internal/proto"
 	"github.com/google/uuid"
@@ -48,8 +53,9 @@ const (
 type sqlPrepareContextFn func(context.Context, string) (*sql.Stmt, error)
 
 type pgmeta struct {
-	// cols are the columns for a specific table.
-	cols map[string][]string
+	// cols are the columns for a specific table, and types are the data types of the columns in the same order.
+	cols  map[string][]string
+	types map[string][]string
 
 	// pks are the primary keys for a specific table.
 	pks map[string][]string
@@ -58,9 +64,19 @@ type pgmeta struct {
 	bytes map[string]int64
 }
 
+// hasColumn returns "true" if the table has the column.
+func (meta *pgmeta) hasColumn(table, name string) bool {
+	return containsString(meta.cols[table], name)
+}
+
 func (meta *pgmeta) isPK(table, name string) bool {
-	for _, pk := range meta.pks[table] {
-		if pk == name {
+	return containsString(meta.pks[table], name)
+}
+
+// containsString returns "true" if the slice contains the string.
+func containsString(slice []string, str string) bool {
+	for _, elem := range slice {
+		if elem == str {
 			return true
 		}
 	}
@@ -106,27 +122,50 @@ func formatPlaceholders(numCols int, numRows int, symbol string) string {
 
 // flattenPartition will take a slice of structures, extract data from their fields, and append it to a slice.
 // This will "flatten" the data to be used in conjunctino with placeholders in a SQL query.
-func flattenPartition(columns []string, partition []*structpb.Struct) []interface{} {
+func flattenPartition(columns []string, partition []*structpb.Struct) ([]interface{}, error) {
 	var args []interface{}
 
 	for _, record := range partition {
 		hash := record.AsMap()
 		for _, column := range columns {
-			args = append(args, hash[column])
+			arg := hash[column]
+
+			// Objects and lists are stored as JSON.
+			switch arg.(type) {
+			case map[string]interface{}, []interface{}:
+				data, err := json.Marshal(arg)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrFailedToMarshalJSON, err)
+				}
+
+				arg = string(data)
+			}
+
+			args = append(args, arg)
 		}
 	}
 
-	return args
+	return args, nil
+}
+
+// conflictKeys will return the keys that identify a record on the table. Keys on the request take precedence over the
+// primary keys of the table.
+func (meta *pgmeta) conflictKeys(table string, pks []string) []string {
+	if len(pks) > 0 {
+		return pks
+	}
+
+	return meta.pks[table]
 }
 
 // exclusionConstraints will return a string of non-primary key columns to "exclude" if they are not changed in the
 // context of a Postgres insert. That is, if a column is not changed, it will not be updated. All columns beside primary
 // keys must be included in the "excluded" clause.
-func (meta *pgmeta) exclusionConstraints(table string) []string {
+func (meta *pgmeta) exclusionConstraints(table string, pks []string) []string {
 	var constraints []string
 
 	for _, column := range meta.cols[table] {
-		if !meta.isPK(table, column) {
+		if !containsString(pks, column) {
 			constraints = append(constraints, fmt.Sprintf("\"%s\" = EXCLUDED.\"%s\"", column, column))
 		}
 	}
@@ -134,13 +173,60 @@ func (meta *pgmeta) exclusionConstraints(table string) []string {
 	return constraints
 }
 
-// upsertStatement will return a postgres upsert statement for the meta object.
-func (meta *pgmeta) upsertStmt(ctx context.Context, table string, pcf sqlPrepareContextFn, vol int) (*sql.Stmt, error) {
-	query := fmt.Sprintf(`INSERT INTO %s(%s) VALUES %s ON CONFLICT (%s) DO UPDATE SET %s`, table,
-		strings.Join(meta.cols[table], ","),
-		formatPlaceholders(len(meta.cols[table]), vol, "$"),
-		strings.Join(meta.pks[table], ","),
-		strings.Join(meta.exclusionConstraints(table), ","))
+// mergeConstraints will return the non-primary key columns to update with the value of the inserted record, unless
+// the inserted value is null.
+func (meta *pgmeta) mergeConstraints(table string, pks []string) []string {
+	var constraints []string
+
+	for _, column := range meta.cols[table] {
+		if !containsString(pks, column) {
+			constraints = append(constraints, fmt.Sprintf("\"%s\" = COALESCE(EXCLUDED.\"%s\", %s.\"%s\")",
+				column, column, table, column))
+		}
+	}
+
+	return constraints
+}
+
+// conflictClause will return the clause of an upsert statement that resolves conflicts on the given keys or the primary
+// keys of the table with the conflict strategy, followed by the clause that returns whether each row was inserted.
+// Tables without keys are inserted into, and conflicts on tables where every column is a key are ignored.
+func (meta *pgmeta) conflictClause(table string, pks []string, conflict string) string {
+	var clause string
+
+	if pks := meta.conflictKeys(table, pks); len(pks) > 0 && conflict != proto.ConflictFail {
+		var constraints []string
+
+		switch conflict {
+		case proto.ConflictMergeNonNull:
+			constraints = meta.mergeConstraints(table, pks)
+		case proto.ConflictInsertOnly:
+		default:
+			constraints = meta.exclusionConstraints(table, pks)
+		}
+
+		if len(constraints) > 0 {
+			clause = fmt.Sprintf(` ON CONFLICT (%s) DO UPDATE SET %s`, quoteIdentifiers(pks),
+				strings.Join(constraints, ","))
+		} else {
+			clause = fmt.Sprintf(` ON CONFLICT (%s) DO NOTHING`, quoteIdentifiers(pks))
+		}
+	}
+
+	// A row that was inserted has no deleting transaction, so that inserted rows can be told apart from updated
+	// rows.
+	return clause + ` RETURNING (xmax = 0)`
+}
+
+// upsertStatement will return a postgres upsert statement for the meta object, resolving conflicts as described by
+// "conflictClause".
+func (meta *pgmeta) upsertStmt(ctx context.Context, table string, pks []string, conflict string,
+	pcf sqlPrepareContextFn, vol int,
+) (*sql.Stmt, error) {
+	query := fmt.Sprintf(`INSERT INTO %s(%s) VALUES %s`, table,
+		quoteIdentifiers(meta.cols[table]),
+		formatPlaceholders(len(meta.cols[table]), vol, "$"))
+	query += meta.conflictClause(table, pks, conflict)
 
 	stmt, err := pcf(ctx, query)
 	if err != nil {
@@ -200,6 +286,7 @@ func (pg *Postgres) loadMeta(ctx context.Context, garbageC bool) error {
 	defer rows.Close()
 
 	pg.meta.cols = make(map[string][]string)
+	pg.meta.types = make(map[string][]string)
 	pg.meta.pks = make(map[string][]string)
 	pg.meta.bytes = make(map[string]int64)
 
@@ -209,9 +296,10 @@ func (pg *Postgres) loadMeta(ctx context.Context, garbageC bool) error {
 			column     string
 			primaryKey bool
 			bytes      int64
+			dataType   string
 		)
 
-		if err := rows.Scan(&column, &table, &primaryKey, &bytes); err != nil {
+		if err := rows.Scan(&column, &table, &primaryKey, &bytes, &dataType); err != nil {
 			return fmt.Errorf("unable to scan row: %w", err)
 		}
 
@@ -220,6 +308,7 @@ func (pg *Postgres) loadMeta(ctx context.Context, garbageC bool) error {
 		}
 
 		pg.meta.cols[table] = append(pg.meta.cols[table], column)
+		pg.meta.types[table] = append(pg.meta.types[table], dataType)
 		pg.meta.bytes[table] = bytes
 	}
 
@@ -228,23 +317,31 @@ func (pg *Postgres) loadMeta(ctx context.Context, garbageC bool) error {
 
 // Close will close the underlying database / transaction.
 func (pg *Postgres) Close() {
+	if pg.stmts != nil {
+		pg.stmts.close()
+	}
+
 	if pg.DB != nil {
 		pg.DB.Close()
 	}
 }
 
-// ListColumns will set a complete list of available columns per table on the response.
+// ListColumns will set a complete list of available columns per table on the response, along with their data types.
 func (pg *Postgres) ListColumns(ctx context.Context) (*proto.ListColumnsResponse, error) {
 	if err := pg.loadMeta(ctx, false); err != nil {
 		return nil, fmt.Errorf("unable to load postgres metadata: %w", err)
 	}
 
-	var rsp proto.ListColumnsResponse
+	rsp := &proto.ListColumnsResponse{ColSet: make(map[string]*proto.Columns)}
+
 	for table, columns := range pg.meta.cols {
-		rsp.ColSet[table].List = append(rsp.ColSet[table].List, columns...)
+		rsp.ColSet[table] = &proto.Columns{
+			List:  append([]string{}, columns...),
+			Types: append([]string{}, pg.meta.types[table]...),
+		}
 	}
 
-	return &rsp, nil
+	return rsp, nil
 }
 
 // ListPrimaryKeys will list all primary keys for all of the tables in the database defined by the DNS used to create
@@ -267,7 +364,8 @@ func (pg *Postgres) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeys
 	return rsp, nil
 }
 
-// ListTables will set a complete list of available tables on the response.
+// ListTables will set a complete list of available tables on the response. The number of rows of each table is the
+// estimate of the statistics of the database, since counting the rows of every table scans all of them.
 func (pg *Postgres) ListTables(ctx context.Context) (*proto.ListTablesResponse, error) {
 	// Since tables have a "size" associated with them, we need to garbage collect the database before we can
 	// get a complete list of tables.
@@ -275,15 +373,46 @@ func (pg *Postgres) ListTables(ctx context.Context) (*proto.ListTablesResponse,
 		return nil, fmt.Errorf("unable to load postgres metadata: %w", err)
 	}
 
+	counts, err := pg.rowEstimates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rsp := &proto.ListTablesResponse{TableSet: make(map[string]*proto.Table)}
 
 	for table := range pg.meta.cols {
-		rsp.TableSet[table] = &proto.Table{Size: pg.meta.bytes[table]}
+		rsp.TableSet[table] = &proto.Table{Size: pg.meta.bytes[table], RowCount: counts[table]}
 	}
 
 	return rsp, nil
 }
 
+// rowEstimates will return the estimated number of live rows of each table, keyed by table.
+func (pg *Postgres) rowEstimates(ctx context.Context) (map[string]int64, error) {
+	rows, err := pg.DB.QueryContext(ctx, string(pgRowEstimates))
+	if err != nil {
+		return nil, fmt.Errorf("unable to query row estimates: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+
+	for rows.Next() {
+		var (
+			table string
+			count int64
+		)
+
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", err)
+		}
+
+		counts[table] = count
+	}
+
+	return counts, rows.Err()
+}
+
 // Truncate will truncate a table.
 func (pg *Postgres) Truncate(ctx context.Context, req *proto.TruncateRequest) (*proto.TruncateResponse, error) {
 	// If the table is not specified, return an error.
@@ -296,7 +425,12 @@ func (pg *Postgres) Truncate(ctx context.Context, req *proto.TruncateRequest) (*
 		return nil, ErrNoTables
 	}
 
-	stmt, err := pg.DB.PrepareContext(ctx, fmt.Sprintf(string(pgTruncatedTables), strings.Join(tables, ",")))
+	prepareContextFn, err := pg.getPrepareContextFn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get preparer: %w", err)
+	}
+
+	stmt, err := prepareContextFn(ctx, fmt.Sprintf(string(pgTruncatedTables), strings.Join(tables, ",")))
 	if err != nil {
 		return nil, fmt.Errorf("unable to prepare statement: %w", err)
 	}
@@ -329,37 +463,176 @@ func (pg *Postgres) getPrepareContextFn(ctx context.Context) (sqlPrepareContextF
 	return pg.DB.PrepareContext, nil
 }
 
-func (pg *Postgres) upsert(ctx context.Context, table string, records []*structpb.Struct) error {
+func (pg *Postgres) upsert(ctx context.Context, table string, pks []string, conflict string,
+	records []*structpb.Struct,
+) (*proto.UpsertResponse, error) {
 	prepareContextFn, err := pg.getPrepareContextFn(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to get preparer: %w", err)
+		return nil, fmt.Errorf("unable to get preparer: %w", err)
 	}
 
 	if err := pg.loadMeta(ctx, false); err != nil {
-		return fmt.Errorf("unable to load postgres metadata: %w", err)
+		return nil, fmt.Errorf("unable to load postgres metadata: %w", err)
 	}
 
+	if pg.opts.flatten {
+		if records, err = flattenRecords(records, pg.opts.separator(), pg.opts.flattenDepth); err != nil {
+			return nil, fmt.Errorf("unable to flatten records: %w", err)
+		}
+	}
+
+	// The partitions of a partitioned table are found before the table is created, so that records without a valid
+	// partition column are rejected.
+	partition, partitioned := pg.opts.partitions[table]
+
+	var partitionStarts []time.Time
+	if partitioned {
+		if partitionStarts, err = partition.starts(records); err != nil {
+			return nil, fmt.Errorf("unable to partition records: %w", err)
+		}
+	}
+
+	if _, ok := pg.meta.cols[table]; !ok && pg.opts.createTables {
+		if err := pg.createTable(ctx, table, pks, prepareContextFn, records); err != nil {
+			return nil, fmt.Errorf("unable to create table: %w", err)
+		}
+	}
+
+	if partitioned {
+		if err := pg.createPartitions(ctx, table, partition, prepareContextFn, partitionStarts); err != nil {
+			return nil, fmt.Errorf("unable to create partitions: %w", err)
+		}
+	}
+
+	// Fields that are not columns on the table are either added as columns, moved to the overflow column, or
+	// dropped.
+	if fields := pg.meta.unknownFields(table, records); len(fields) > 0 {
+		switch {
+		case pg.opts.addColumns:
+			if err := pg.addColumns(ctx, table, prepareContextFn, fields, records); err != nil {
+				return nil, fmt.Errorf("unable to add columns: %w", err)
+			}
+		case pg.opts.overflowColumn != "":
+			if records, err = pg.overflow(table, fields, records); err != nil {
+				return nil, fmt.Errorf("unable to overflow fields: %w", err)
+			}
+		}
+	}
+
+	// Large batches are copied into a temporary table and merged into the table in a single statement.
+	if pg.opts.copyThreshold > 0 && len(records) >= pg.opts.copyThreshold {
+		rsp, err := pg.copyUpsert(ctx, table, pks, conflict, records)
+		if err != nil {
+			return nil, fmt.Errorf("unable to copy records: %w", err)
+		}
+
+		rsp.UpsertedCount = rsp.InsertedCount
+		rsp.MatchedCount = rsp.UpdatedCount
+
+		return rsp, nil
+	}
+
+	rsp := &proto.UpsertResponse{}
+
+	// Upsert statements are cached, since the same statement is used for most batches of a table.
+	prepareUpsertFn := pg.stmtCacheFor(ctx).prepareFn(prepareContextFn)
+
 	// Upsert 1000 records at a time, the maximum number of records that can be inserted in a single statement on a
 	// postgres database.
 	for _, partition := range proto.PartitionStructs(defaultPartitionSize, records) {
-		stmt, err := pg.meta.upsertStmt(ctx, table, prepareContextFn, len(partition))
+		stmt, err := pg.meta.upsertStmt(ctx, table, pks, conflict, prepareUpsertFn, len(partition))
 		if err != nil {
-			return fmt.Errorf("unable to prepare statement: %w", err)
+			return nil, fmt.Errorf("unable to prepare statement: %w", err)
 		}
 
 		// Execute upsert.
-		arguments := flattenPartition(pg.meta.cols[table], partition)
-		if _, err := stmt.ExecContext(ctx, arguments...); err != nil {
-			return fmt.Errorf("unable to execute upsert: %w", err)
+		arguments, err := flattenPartition(pg.meta.cols[table], partition)
+		if err != nil {
+			return nil, fmt.Errorf("unable to flatten partition: %w", err)
+		}
+
+		rows, err := stmt.QueryContext(ctx, arguments...)
+		if err != nil {
+			return nil, upsertError(err)
 		}
+
+		inserted, updated, err := scanUpserted(rows)
+		if err != nil {
+			return nil, upsertError(err)
+		}
+
+		rsp.InsertedCount += inserted
+		rsp.UpdatedCount += updated
 	}
 
-	return nil
+	rsp.UpsertedCount = rsp.InsertedCount
+	rsp.MatchedCount = rsp.UpdatedCount
+
+	return rsp, nil
+}
+
+// scanUpserted will count the rows returned by an upsert statement that were inserted and that were updated.
+func scanUpserted(rows *sql.Rows) (int64, int64, error) {
+	defer rows.Close()
+
+	var inserted, updated int64
+
+	for rows.Next() {
+		var isInsert bool
+		if err := rows.Scan(&isInsert); err != nil {
+			return 0, 0, fmt.Errorf("unable to scan row: %w", err)
+		}
+
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	return inserted, updated, rows.Err()
+}
+
+// transientError will mark deadlocks, serialization failures, and lost connections as transient errors, which are
+// expected to succeed if the transaction is retried. Deadlocks, lock timeouts, and running out of connections are
+// marked as contention too. Other errors are returned as is.
+func transientError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == "40P01" || pqErr.Code == "55P03" || pqErr.Code == "53300" {
+			return proto.Contention(err)
+		}
+
+		if pqErr.Code == "40001" || pqErr.Code.Class() == "08" {
+			return proto.Transient(err)
+		}
+
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return proto.Transient(err)
+	}
+
+	return err
+}
+
+// upsertError will wrap an error executing an upsert statement. A unique violation is a conflict with an existing
+// record.
+func upsertError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %v", proto.ErrConflict, err)
+	}
+
+	return fmt.Errorf("unable to execute upsert: %w", err)
 }
 
 // Upsert will insert the records on the request if they do not exist in the database. On conflict, it will use the
-// PK on the request record to update the data in the database. An upsert request will update the entire table
-// for a given record, include fields that have not been set directly.
+// primary keys on the request, or the PK of the table, to resolve the conflict with the strategy on the request.
+// Primary keys on the request must have a unique constraint on the table. An upsert request will update the entire
+// table for a given record, include fields that have not been set directly.
 func (pg *Postgres) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
 	pg.writeMutex.Lock()
 	defer pg.writeMutex.Unlock()
@@ -375,11 +648,18 @@ func (pg *Postgres) Upsert(ctx context.Context, req *proto.UpsertRequest) (*prot
 	}
 
 	table := req.GetTable()
-	if err := pg.upsert(ctx, table, records); err != nil {
+
+	rsp, err := pg.upsert(ctx, table, req.GetPrimaryKeys(), req.GetConflict(), records)
+	if err != nil {
 		return nil, fmt.Errorf("unable to upsert: %w", err)
 	}
 
-	return &proto.UpsertResponse{}, nil
+	// Records that conflict with an existing row are not returned if the conflict is ignored, e.g. by the
+	// insert-only strategy or an unchanged hash key, so they are left unchanged rather than failed. A record that
+	// cannot be written fails the whole upsert, so none of them are counted as failed.
+	rsp.ReceivedCount = int64(len(records))
+
+	return rsp, nil
 }
 
 // Postgres is a wrapper around the sql.DB object.
@@ -399,13 +679,26 @@ type Postgres struct {
 	// the method. The transaction ID is added to the context in the "StartTx" method. The transaction ID is
 	// removed from the context in the "CommitTx" and "RollbackTx" methods.
 	activeTx sync.Map
+
+	// opts are the gidari-specific options on the connection string, and created are the definitions of the
+	// tables that have been created.
+	opts    *connectionOptions
+	created map[string]*tableDef
+
+	// stmts are the upsert statements prepared on the database, and txStmts are the upsert statements prepared on
+	// each active transaction, keyed by the transaction ID. They are reused by every batch written to the same table.
+	stmts   *stmtCache
+	txStmts sync.Map
 }
 
 // New will return a new Postgres option for querying data through a Postgres DB.
 func New(ctx context.Context, connectionURL string) (*Postgres, error) {
 	postgres := new(Postgres)
 
-	var err error
+	connectionURL, opts, err := parseConnectionURL(connectionURL)
+	if err != nil {
+		return nil, err
+	}
 
 	postgres.DB, err = sql.Open("postgres", connectionURL)
 	if err != nil {
@@ -417,6 +710,9 @@ func New(ctx context.Context, connectionURL string) (*Postgres, error) {
 	postgres.metaMutex = sync.Mutex{}
 	postgres.writeMutex = sync.Mutex{}
 	postgres.activeTx = sync.Map{}
+	postgres.opts = opts
+	postgres.created = make(map[string]*tableDef)
+	postgres.stmts = newStmtCache()
 
 	return postgres, nil
 }
@@ -467,6 +763,24 @@ func (pg *Postgres) setMaxOpenConns() {
 	pg.DB.SetMaxOpenConns(int(n))
 }
 
+// ConfigurePool will configure the connection pool of the Postgres connection, overriding the maximum number of open
+// connections that is set when the connection is created.
+func (pg *Postgres) ConfigurePool(_ context.Context, req *proto.PoolRequest) error {
+	if req.MaxOpenConns > 0 {
+		pg.DB.SetMaxOpenConns(req.MaxOpenConns)
+	}
+
+	if req.MaxIdleConns > 0 {
+		pg.DB.SetMaxIdleConns(req.MaxIdleConns)
+	}
+
+	if req.ConnMaxLifetime > 0 {
+		pg.DB.SetConnMaxLifetime(req.ConnMaxLifetime)
+	}
+
+	return nil
+}
+
 // StartTx will start a transaction on the Postgres connection. The transaction ID is returned and should be used
 // to commit or rollback the transaction.
 func (pg *Postgres) StartTx(ctx context.Context) (*proto.Txn, error) {
@@ -482,10 +796,11 @@ func (pg *Postgres) StartTx(ctx context.Context) (*proto.Txn, error) {
 
 	pgtx, err := pg.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return txn, fmt.Errorf("failed to start transaction: %w", err)
+		return txn, fmt.Errorf("failed to start transaction: %w", transientError(err))
 	}
 
 	pg.activeTx.Store(txnID, pgtx)
+	pg.txStmts.Store(txnID, newStmtCache())
 
 	// Create a copy of the parent context with a transaction ID.
 	pgCtx := context.WithValue(ctx, basicPostgressTxID, txnID)
@@ -494,6 +809,7 @@ func (pg *Postgres) StartTx(ctx context.Context) (*proto.Txn, error) {
 		defer func() {
 			// Remove the transaction from the activeTx map.
 			pg.activeTx.Delete(txnID)
+			pg.txStmts.Delete(txnID)
 		}()
 
 		for fn := range txn.FunctionCh {
@@ -505,13 +821,15 @@ func (pg *Postgres) StartTx(ctx context.Context) (*proto.Txn, error) {
 		}
 
 		if err != nil {
-			txn.DoneCh <- err
+			// Release the connection, the error of the function takes precedence over the rollback.
+			_ = pgtx.Rollback()
+			txn.DoneCh <- transientError(err)
 
 			return
 		}
 
 		if <-txn.CommitCh {
-			txn.DoneCh <- pgtx.Commit()
+			txn.DoneCh <- transientError(pgtx.Commit())
 		} else {
 			txn.DoneCh <- pgtx.Rollback()
 		}
@@ -539,7 +857,7 @@ func (pg *Postgres) UpsertBinary(ctx context.Context,
 	}
 
 	table := req.GetTable()
-	if err := pg.upsert(ctx, table, records); err != nil {
+	if _, err := pg.upsert(ctx, table, nil, "", records); err != nil {
 		return nil, fmt.Errorf("unable to upsert: %w", err)
 	}
 
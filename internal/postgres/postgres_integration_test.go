@@ -153,3 +153,37 @@ func TestPostgres(t *testing.T) {
 		)
 	})
 }
+
+func TestUpsertInsertOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	pg, err := New(ctx, defaultConnectionString+"&createTables=true")
+	if err != nil {
+		t.Fatalf("failed to connect to the database: %v", err)
+	}
+
+	req := &proto.UpsertRequest{
+		Table:       "insert_only_tests1",
+		Data:        []byte(`[{"id":"1","price":1.5},{"id":"2","price":2.5}]`),
+		PrimaryKeys: []string{"id"},
+		Conflict:    proto.ConflictInsertOnly,
+	}
+
+	// Re-upserting the same rows leaves them unchanged, which is not a failure.
+	for run := 0; run < 2; run++ {
+		rsp, err := pg.Upsert(ctx, req)
+		if err != nil {
+			t.Fatalf("run %d: failed to upsert: %v", run, err)
+		}
+
+		if rsp.ReceivedCount != 2 || rsp.FailedCount != 0 {
+			t.Errorf("run %d: expected 2 records received and none failed, got %+v", run, rsp)
+		}
+
+		if run > 0 && (rsp.InsertedCount != 0 || rsp.UpdatedCount != 0) {
+			t.Errorf("run %d: expected the rows to be unchanged, got %+v", run, rsp)
+		}
+	}
+}
@@ -12,9 +12,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/lib/pq"
 )
 
 func TestPGMeta(t *testing.T) {
@@ -114,19 +119,46 @@ func TestPGMeta(t *testing.T) {
 
 	upsertTests := []struct {
 		tableName   string
+		pks         []string
+		conflict    string
 		expectedSQL string
 	}{
 		{
 			tableName:   "table1",
-			expectedSQL: `INSERT INTO table1(0,jason,big ben) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET "0" = EXCLUDED."0","jason" = EXCLUDED."jason","big ben" = EXCLUDED."big ben"`,
+			expectedSQL: `INSERT INTO table1("0","jason","big ben") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "0" = EXCLUDED."0","jason" = EXCLUDED."jason","big ben" = EXCLUDED."big ben" RETURNING (xmax = 0)`,
 		},
 		{
 			tableName:   "table2",
-			expectedSQL: `INSERT INTO table2(1,john,bakers street) VALUES ($1,$2,$3) ON CONFLICT (id,name) DO UPDATE SET "1" = EXCLUDED."1","john" = EXCLUDED."john","bakers street" = EXCLUDED."bakers street"`,
+			expectedSQL: `INSERT INTO table2("1","john","bakers street") VALUES ($1,$2,$3) ON CONFLICT ("id","name") DO UPDATE SET "1" = EXCLUDED."1","john" = EXCLUDED."john","bakers street" = EXCLUDED."bakers street" RETURNING (xmax = 0)`,
 		},
 		{
 			tableName:   "table3",
-			expectedSQL: `INSERT INTO table3(2,harry,leicester square) VALUES ($1,$2,$3) ON CONFLICT (id,name,address) DO UPDATE SET "2" = EXCLUDED."2","harry" = EXCLUDED."harry","leicester square" = EXCLUDED."leicester square"`,
+			expectedSQL: `INSERT INTO table3("2","harry","leicester square") VALUES ($1,$2,$3) ON CONFLICT ("id","name","address") DO UPDATE SET "2" = EXCLUDED."2","harry" = EXCLUDED."harry","leicester square" = EXCLUDED."leicester square" RETURNING (xmax = 0)`,
+		},
+		{
+			tableName:   "table1",
+			pks:         []string{"0", "jason"},
+			expectedSQL: `INSERT INTO table1("0","jason","big ben") VALUES ($1,$2,$3) ON CONFLICT ("0","jason") DO UPDATE SET "big ben" = EXCLUDED."big ben" RETURNING (xmax = 0)`,
+		},
+		{
+			tableName:   "table2",
+			pks:         []string{"1", "john", "bakers street"},
+			expectedSQL: `INSERT INTO table2("1","john","bakers street") VALUES ($1,$2,$3) ON CONFLICT ("1","john","bakers street") DO NOTHING RETURNING (xmax = 0)`,
+		},
+		{
+			tableName:   "table1",
+			conflict:    "insert-only",
+			expectedSQL: `INSERT INTO table1("0","jason","big ben") VALUES ($1,$2,$3) ON CONFLICT ("id") DO NOTHING RETURNING (xmax = 0)`,
+		},
+		{
+			tableName:   "table2",
+			conflict:    "merge-non-null",
+			expectedSQL: `INSERT INTO table2("1","john","bakers street") VALUES ($1,$2,$3) ON CONFLICT ("id","name") DO UPDATE SET "1" = COALESCE(EXCLUDED."1", table2."1"),"john" = COALESCE(EXCLUDED."john", table2."john"),"bakers street" = COALESCE(EXCLUDED."bakers street", table2."bakers street") RETURNING (xmax = 0)`,
+		},
+		{
+			tableName:   "table3",
+			conflict:    "fail",
+			expectedSQL: `INSERT INTO table3("2","harry","leicester square") VALUES ($1,$2,$3) RETURNING (xmax = 0)`,
 		},
 	}
 
@@ -145,10 +177,41 @@ func TestPGMeta(t *testing.T) {
 				return &sql.Stmt{}, nil
 			}
 
-			_, err := pdb.meta.upsertStmt(ctx, test.tableName, mockPCF, 1)
+			_, err := pdb.meta.upsertStmt(ctx, test.tableName, test.pks, test.conflict, mockPCF, 1)
 			if err != nil {
 				t.Fatalf("failed to create upsert statement: %v", err)
 			}
 		})
 	}
 }
+
+func TestTransientError(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		err        error
+		transient  bool
+		contention bool
+	}{
+		{err: &pq.Error{Code: "40P01"}, transient: true, contention: true},
+		{err: &pq.Error{Code: "55P03"}, transient: true, contention: true},
+		{err: &pq.Error{Code: "53300"}, transient: true, contention: true},
+		{err: &pq.Error{Code: "40001"}, transient: true},
+		{err: &pq.Error{Code: "08006"}, transient: true},
+		{err: fmt.Errorf("unable to execute upsert: %w", driver.ErrBadConn), transient: true},
+		{err: &pq.Error{Code: "23505"}},
+		{err: fmt.Errorf("unable to flatten partition")},
+	} {
+		if got := errors.Is(transientError(tcase.err), proto.ErrTransient); got != tcase.transient {
+			t.Errorf("expected %v to be transient: %v, got %v", tcase.err, tcase.transient, got)
+		}
+
+		if got := errors.Is(transientError(tcase.err), proto.ErrContention); got != tcase.contention {
+			t.Errorf("expected %v to be contention: %v, got %v", tcase.err, tcase.contention, got)
+		}
+	}
+
+	if transientError(nil) != nil {
+		t.Errorf("expected nil for a nil error")
+	}
+}
@@ -19,3 +19,6 @@ var pgTruncatedTables []byte
 
 //go:embed queries/garbage_collect.sql
 var pgGarbageCollect []byte
+
+//go:embed queries/row_estimates.sql
+var pgRowEstimates []byte
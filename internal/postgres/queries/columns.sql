@@ -12,7 +12,8 @@ SELECT c.column_name,
            ELSE
                0
        END AS primary_key,
-       pg_relation_size(quote_ident(c.table_name)) AS bytes
+       pg_relation_size(quote_ident(c.table_name)) AS bytes,
+       c.data_type
 FROM information_schema.columns c
     INNER JOIN information_schema.tables t
         ON t.table_name = c.table_name
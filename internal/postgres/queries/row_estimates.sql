@@ -0,0 +1,4 @@
+SELECT relname,
+       n_live_tup
+FROM pg_stat_user_tables
+WHERE schemaname = 'public'
@@ -0,0 +1,178 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// whereClause will return the clause that filters the records of a table by the values of columns and a time range,
+// and the arguments for its parameters. Times are passed as RFC 3339 strings, so that they can be compared with both
+// timestamp columns and text columns of RFC 3339 timestamps. If there is no filter, the clause is empty.
+func whereClause(match map[string]interface{}, rng *proto.TimeRange) (string, []interface{}) {
+	columns := make([]string, 0, len(match))
+	for column := range match {
+		columns = append(columns, column)
+	}
+
+	sort.Strings(columns)
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	for _, column := range columns {
+		args = append(args, match[column])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(column), len(args)))
+	}
+
+	if rng != nil {
+		if !rng.Start.IsZero() {
+			args = append(args, rng.Start.UTC().Format(time.RFC3339))
+			conditions = append(conditions, fmt.Sprintf("%s >= $%d", pq.QuoteIdentifier(rng.Column), len(args)))
+		}
+
+		if !rng.End.IsZero() {
+			args = append(args, rng.End.UTC().Format(time.RFC3339))
+			conditions = append(conditions, fmt.Sprintf("%s < $%d", pq.QuoteIdentifier(rng.Column), len(args)))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// queryStmt will return the statement that reads the records of the table that match the request, and the arguments
+// for its parameters.
+func queryStmt(req *proto.QueryRequest) (string, []interface{}) {
+	where, args := whereClause(req.Match, req.Range)
+
+	stmt := fmt.Sprintf("SELECT * FROM %s%s", pq.QuoteIdentifier(req.Table), where)
+	if req.Range != nil {
+		stmt += fmt.Sprintf(" ORDER BY %s", pq.QuoteIdentifier(req.Range.Column))
+
+		if req.Descending {
+			stmt += " DESC"
+		}
+	}
+
+	if req.Limit > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", req.Limit)
+	}
+
+	return stmt, args
+}
+
+// decodeValue will convert a value scanned from a column into a value that can be stored on a record. JSON columns
+// are decoded, times are formatted as RFC 3339 timestamps, and other byte values are read as text.
+func decodeValue(val interface{}, dbType string) (interface{}, error) {
+	switch val := val.(type) {
+	case []byte:
+		if dbType != "JSON" && dbType != "JSONB" {
+			return string(val), nil
+		}
+
+		var decoded interface{}
+		if err := json.Unmarshal(val, &decoded); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToUnmarshalJSON, err)
+		}
+
+		return decoded, nil
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano), nil
+	default:
+		return val, nil
+	}
+}
+
+// scanRecords will read each row into a record keyed by column.
+func scanRecords(rows *sql.Rows) ([]*structpb.Struct, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get column types: %w", err)
+	}
+
+	records := []*structpb.Struct{}
+
+	for rows.Next() {
+		vals := make([]interface{}, len(columnTypes))
+		ptrs := make([]interface{}, len(columnTypes))
+
+		for idx := range vals {
+			ptrs[idx] = &vals[idx]
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", err)
+		}
+
+		hash := make(map[string]interface{}, len(columnTypes))
+
+		for idx, columnType := range columnTypes {
+			if hash[columnType.Name()], err = decodeValue(vals[idx], columnType.DatabaseTypeName()); err != nil {
+				return nil, err
+			}
+		}
+
+		record, err := structpb.NewStruct(hash)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create record: %w", err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read rows: %w", err)
+	}
+
+	return records, nil
+}
+
+// Query will read the records of the table that match the request.
+func (pg *Postgres) Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	prepareContextFn, err := pg.getPrepareContextFn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get preparer: %w", err)
+	}
+
+	query, args := queryStmt(req)
+
+	stmt, err := prepareContextFn(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare statement: %w", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query: %w", err)
+	}
+
+	defer rows.Close()
+
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.QueryResponse{Records: records}, nil
+}
@@ -0,0 +1,94 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	t.Run("statement", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+		for _, tcase := range []struct {
+			req          *proto.QueryRequest
+			expected     string
+			expectedArgs []interface{}
+		}{
+			{
+				req:          &proto.QueryRequest{Table: "candles"},
+				expected:     `SELECT * FROM "candles"`,
+				expectedArgs: []interface{}{},
+			},
+			{
+				req: &proto.QueryRequest{
+					Table: "candles",
+					Match: map[string]interface{}{"product_id": "BTC-USD"},
+					Range: &proto.TimeRange{Column: "time", Start: start},
+					Limit: 10,
+				},
+				expected:     `SELECT * FROM "candles" WHERE "product_id" = $1 AND "time" >= $2 ORDER BY "time" LIMIT 10`,
+				expectedArgs: []interface{}{"BTC-USD", "2022-05-10T00:00:00Z"},
+			},
+			{
+				req: &proto.QueryRequest{
+					Table:      "candles",
+					Range:      &proto.TimeRange{Column: "_gidari_fetched_at"},
+					Limit:      1,
+					Descending: true,
+				},
+				expected:     `SELECT * FROM "candles" ORDER BY "_gidari_fetched_at" DESC LIMIT 1`,
+				expectedArgs: []interface{}{},
+			},
+		} {
+			stmt, args := queryStmt(tcase.req)
+			if stmt != tcase.expected || !reflect.DeepEqual(args, tcase.expectedArgs) {
+				t.Errorf("expected %s with %v, got %s with %v", tcase.expected, tcase.expectedArgs, stmt, args)
+			}
+		}
+	})
+
+	t.Run("decode value", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			val      interface{}
+			dbType   string
+			expected interface{}
+		}{
+			{val: []byte("1.50"), dbType: "NUMERIC", expected: "1.50"},
+			{val: []byte(`{"usd":1.5}`), dbType: "JSONB", expected: map[string]interface{}{"usd": 1.5}},
+			{
+				val:      time.Date(2022, 5, 10, 1, 0, 0, 500, time.FixedZone("EST", -5*60*60)),
+				dbType:   "TIMESTAMPTZ",
+				expected: "2022-05-10T06:00:00.0000005Z",
+			},
+			{val: int64(1), dbType: "INT8", expected: int64(1)},
+			{val: nil, dbType: "TEXT", expected: nil},
+		} {
+			got, err := decodeValue(tcase.val, tcase.dbType)
+			if err != nil {
+				t.Fatalf("failed to decode %v: %v", tcase.val, err)
+			}
+
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("%s: expected %v, got %v", tcase.dbType, tcase.expected, got)
+			}
+		}
+	})
+}
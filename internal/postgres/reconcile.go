@@ -0,0 +1,71 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/lib/pq"
+)
+
+// reconcileStmt will return the statement that sets the deleted column of each record in the table to whether the
+// record is absent from the fetched keys. The keys are passed as a JSON list of objects, which are converted to the
+// column types of the table with "jsonb_populate_recordset". Only records whose flag changes are updated.
+func reconcileStmt(table string, pks []string, deletedColumn string) string {
+	matches := make([]string, len(pks))
+	for idx, pk := range pks {
+		matches[idx] = fmt.Sprintf("fetched.%[1]s = rec.%[1]s", pq.QuoteIdentifier(pk))
+	}
+
+	absent := fmt.Sprintf("(NOT EXISTS (SELECT 1 FROM jsonb_populate_recordset(NULL::%s, $1::jsonb) AS fetched "+
+		"WHERE %s))", pq.QuoteIdentifier(table), strings.Join(matches, " AND "))
+
+	return fmt.Sprintf("UPDATE %[1]s AS rec SET %[2]s = %[3]s WHERE rec.%[2]s IS DISTINCT FROM %[3]s",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(deletedColumn), absent)
+}
+
+// Reconcile will set the deleted column of the records in the table that are not in the fetched keys to true, and of
+// the records that are to false.
+func (pg *Postgres) Reconcile(ctx context.Context, req *proto.ReconcileRequest) (*proto.ReconcileResponse, error) {
+	pg.writeMutex.Lock()
+	defer pg.writeMutex.Unlock()
+
+	prepareContextFn, err := pg.getPrepareContextFn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get preparer: %w", err)
+	}
+
+	// Marshal an empty list rather than null when no records were fetched.
+	keys, err := json.Marshal(append([]map[string]interface{}{}, req.Keys...))
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal keys: %w", err)
+	}
+
+	stmt, err := prepareContextFn(ctx, reconcileStmt(req.Table, req.PrimaryKeys, req.DeletedColumn))
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare statement: %w", err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, string(keys))
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute reconcile: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get updated count: %w", err)
+	}
+
+	return &proto.ReconcileResponse{UpdatedCount: updated}, nil
+}
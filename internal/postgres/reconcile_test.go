@@ -0,0 +1,27 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import "testing"
+
+func TestReconcileStmt(t *testing.T) {
+	t.Parallel()
+
+	got := reconcileStmt("candles", []string{"product_id", "time"}, "deleted")
+
+	expected := `UPDATE "candles" AS rec SET "deleted" = (NOT EXISTS (SELECT 1 FROM ` +
+		`jsonb_populate_recordset(NULL::"candles", $1::jsonb) AS fetched WHERE ` +
+		`fetched."product_id" = rec."product_id" AND fetched."time" = rec."time")) WHERE rec."deleted" IS DISTINCT FROM ` +
+		`(NOT EXISTS (SELECT 1 FROM jsonb_populate_recordset(NULL::"candles", $1::jsonb) AS fetched WHERE ` +
+		`fetched."product_id" = rec."product_id" AND fetched."time" = rec."time"))`
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,429 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Connection string options that are specific to gidari. They are removed from the connection string before
+// connecting, since Postgres would reject them as unknown runtime parameters.
+const (
+	// createTablesOption opts in to creating tables that do not exist.
+	createTablesOption = "createTables"
+
+	// addColumnsOption opts in to adding columns for fields that do not exist on a table.
+	addColumnsOption = "addColumns"
+
+	// overflowColumnOption is the name of a JSONB column that stores the fields that do not exist on a table.
+	overflowColumnOption = "overflowColumn"
+
+	// flattenOption opts in to flattening nested objects into columns.
+	flattenOption = "flatten"
+
+	// flattenSeparatorOption is the separator between the names of the parent and child fields of a flattened
+	// column.
+	flattenSeparatorOption = "flattenSeparator"
+
+	// flattenDepthOption is the number of levels of nested objects to flatten, where zero flattens every level.
+	flattenDepthOption = "flattenDepth"
+
+	// partitionOption is a table to partition by range on a time column, of the form "table:column[:interval]". It
+	// can be repeated for each table.
+	partitionOption = "partition"
+
+	// copyThresholdOption is the number of records in a batch from which it is loaded with COPY into a temporary
+	// table and merged into the table, instead of being inserted, where zero never uses COPY.
+	copyThresholdOption = "copyThreshold"
+)
+
+// inferredPrimaryKey is the field used as the primary key of a created table, if every record has a value for it.
+const inferredPrimaryKey = "id"
+
+// Postgres types for inferred columns.
+const (
+	pgBoolean   = "BOOLEAN"
+	pgBigint    = "BIGINT"
+	pgDouble    = "DOUBLE PRECISION"
+	pgText      = "TEXT"
+	pgTimestamp = "TIMESTAMPTZ"
+	pgJSONB     = "JSONB"
+	pgNumeric   = "NUMERIC"
+)
+
+var (
+	ErrInvalidOption         = fmt.Errorf("invalid connection string option")
+	ErrMissingOverflowColumn = fmt.Errorf("missing overflow column")
+)
+
+// connectionOptions are the gidari-specific options on a connection string.
+type connectionOptions struct {
+	createTables     bool
+	addColumns       bool
+	overflowColumn   string
+	flatten          bool
+	flattenSeparator string
+	flattenDepth     int
+	copyThreshold    int
+	partitions       map[string]*partitioning
+}
+
+// tableDef is the definition of a table that is inferred from records.
+type tableDef struct {
+	cols  []string
+	types map[string]string
+	pks   []string
+
+	// required are the columns that are NOT NULL, which only declared tables have.
+	required map[string]bool
+
+	// partitionBy is the column to partition the table by range on, if the table is partitioned.
+	partitionBy string
+}
+
+// parseConnectionURL will remove the gidari-specific options from a URL connection string, returning the connection
+// string for the driver and the options. Connection strings that are not URLs are returned as is.
+func parseConnectionURL(connectionURL string) (string, *connectionOptions, error) {
+	opts := new(connectionOptions)
+
+	if !strings.HasPrefix(connectionURL, "postgres://") && !strings.HasPrefix(connectionURL, "postgresql://") {
+		return connectionURL, opts, nil
+	}
+
+	uri, err := url.Parse(connectionURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to parse connection string: %w", err)
+	}
+
+	query := uri.Query()
+
+	for option, dst := range map[string]*bool{
+		createTablesOption: &opts.createTables,
+		addColumnsOption:   &opts.addColumns,
+		flattenOption:      &opts.flatten,
+	} {
+		if !query.Has(option) {
+			continue
+		}
+
+		if *dst, err = strconv.ParseBool(query.Get(option)); err != nil {
+			return "", nil, fmt.Errorf("%w: %s: %v", ErrInvalidOption, option, err)
+		}
+
+		query.Del(option)
+	}
+
+	if query.Has(overflowColumnOption) {
+		opts.overflowColumn = query.Get(overflowColumnOption)
+		query.Del(overflowColumnOption)
+	}
+
+	if query.Has(flattenSeparatorOption) {
+		opts.flattenSeparator = query.Get(flattenSeparatorOption)
+		query.Del(flattenSeparatorOption)
+	}
+
+	if query.Has(flattenDepthOption) {
+		opts.flattenDepth, err = strconv.Atoi(query.Get(flattenDepthOption))
+		if err != nil || opts.flattenDepth < 0 {
+			return "", nil, fmt.Errorf("%w: %s: %q", ErrInvalidOption, flattenDepthOption,
+				query.Get(flattenDepthOption))
+		}
+
+		query.Del(flattenDepthOption)
+	}
+
+	if query.Has(copyThresholdOption) {
+		opts.copyThreshold, err = strconv.Atoi(query.Get(copyThresholdOption))
+		if err != nil || opts.copyThreshold < 0 {
+			return "", nil, fmt.Errorf("%w: %s: %q", ErrInvalidOption, copyThresholdOption,
+				query.Get(copyThresholdOption))
+		}
+
+		query.Del(copyThresholdOption)
+	}
+
+	if query.Has(partitionOption) {
+		if opts.partitions, err = parsePartitions(query[partitionOption]); err != nil {
+			return "", nil, fmt.Errorf("%w: %s: %v", ErrInvalidOption, partitionOption, err)
+		}
+
+		query.Del(partitionOption)
+	}
+
+	uri.RawQuery = query.Encode()
+
+	return uri.String(), opts, nil
+}
+
+// inferType will return the Postgres type for a decoded value, or an empty string if the value is null.
+func inferType(val interface{}) string {
+	switch val := val.(type) {
+	case bool:
+		return pgBoolean
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < math.MaxInt64 {
+			return pgBigint
+		}
+
+		return pgDouble
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return pgTimestamp
+		}
+
+		return pgText
+	case map[string]interface{}, []interface{}:
+		return pgJSONB
+	default:
+		return ""
+	}
+}
+
+// mergeTypes will return the type of a column that holds values of both types.
+func mergeTypes(current, next string) string {
+	switch {
+	case current == "" || current == next:
+		return next
+	case next == "":
+		return current
+	case current == pgBigint && next == pgDouble, current == pgDouble && next == pgBigint:
+		return pgDouble
+	default:
+		return pgText
+	}
+}
+
+// inferTable will infer the definition of a table from the records. Every field is a nullable column, and the "id"
+// field is the primary key if every record has a value for it. If there is an overflow column, it is added as a JSONB
+// column.
+func inferTable(records []*structpb.Struct, overflowColumn string) *tableDef {
+	def := &tableDef{types: make(map[string]string)}
+	hasPK := len(records) > 0
+
+	for _, record := range records {
+		hash := record.AsMap()
+		if hash[inferredPrimaryKey] == nil {
+			hasPK = false
+		}
+
+		for field, val := range hash {
+			current, ok := def.types[field]
+			if !ok {
+				def.cols = append(def.cols, field)
+			}
+
+			def.types[field] = mergeTypes(current, inferType(val))
+		}
+	}
+
+	if _, ok := def.types[overflowColumn]; !ok && overflowColumn != "" {
+		def.cols = append(def.cols, overflowColumn)
+		def.types[overflowColumn] = pgJSONB
+	}
+
+	sort.Strings(def.cols)
+
+	for _, col := range def.cols {
+		if def.types[col] == "" {
+			def.types[col] = pgText
+		}
+	}
+
+	if hasPK {
+		def.pks = []string{inferredPrimaryKey}
+	}
+
+	return def
+}
+
+// createStmt will return the statement to create the table, if it does not exist.
+func (def *tableDef) createStmt(table string) string {
+	columns := make([]string, 0, len(def.cols)+1)
+	for _, col := range def.cols {
+		column := fmt.Sprintf("%s %s", pq.QuoteIdentifier(col), def.types[col])
+		if def.required[col] {
+			column += " NOT NULL"
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(def.pks) > 0 {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", quoteIdentifiers(def.pks)))
+	}
+
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(columns, ","))
+	if def.partitionBy != "" {
+		stmt += fmt.Sprintf(" PARTITION BY RANGE (%s)", pq.QuoteIdentifier(def.partitionBy))
+	}
+
+	return stmt
+}
+
+// quoteIdentifiers will quote and join the identifiers.
+func quoteIdentifiers(identifiers []string) string {
+	quoted := make([]string, len(identifiers))
+	for idx, identifier := range identifiers {
+		quoted[idx] = pq.QuoteIdentifier(identifier)
+	}
+
+	return strings.Join(quoted, ",")
+}
+
+// createTable will create the table if it does not exist in the database, inferring the columns from the records.
+// If there are primary keys on the request, they are the primary keys of the table. The definition is cached, so that
+// a table created in a transaction that has not been committed yet is not inferred again from a different set of
+// records.
+func (pg *Postgres) createTable(ctx context.Context, table string, pks []string, pcf sqlPrepareContextFn,
+	records []*structpb.Struct,
+) error {
+	def, ok := pg.created[table]
+	if !ok {
+		def = inferTable(records, pg.opts.overflowColumn)
+		if len(pks) > 0 {
+			def.pks = pks
+		}
+
+		pg.partitionDef(table, def)
+		pg.created[table] = def
+	}
+
+	stmt, err := pcf(ctx, def.createStmt(table))
+	if err != nil {
+		return fmt.Errorf("unable to prepare statement: %w", err)
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("unable to create table %s: %w", table, err)
+	}
+
+	pg.metaMutex.Lock()
+	defer pg.metaMutex.Unlock()
+
+	pg.meta.cols[table] = def.cols
+	pg.meta.pks[table] = def.pks
+
+	return nil
+}
+
+// partitionDef will partition the definition of the table by range on its partition column, if the table is
+// partitioned. The primary key of a partitioned table must include the partition column.
+func (pg *Postgres) partitionDef(table string, def *tableDef) {
+	p, ok := pg.opts.partitions[table]
+	if !ok {
+		return
+	}
+
+	def.partitionBy = p.column
+	def.types[p.column] = pgTimestamp
+
+	if !containsString(def.cols, p.column) {
+		def.cols = append(def.cols, p.column)
+		sort.Strings(def.cols)
+	}
+
+	if len(def.pks) > 0 && !containsString(def.pks, p.column) {
+		def.pks = append(append([]string{}, def.pks...), p.column)
+	}
+}
+
+// unknownFields will return the fields on the records that are not columns on the table, in sorted order.
+func (meta *pgmeta) unknownFields(table string, records []*structpb.Struct) []string {
+	cols := make(map[string]bool, len(meta.cols[table]))
+	for _, col := range meta.cols[table] {
+		cols[col] = true
+	}
+
+	var fields []string
+
+	for _, record := range records {
+		for field := range record.GetFields() {
+			if !cols[field] {
+				cols[field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// addColumns will add a column to the table for each of the fields, inferring the column types from the records.
+func (pg *Postgres) addColumns(ctx context.Context, table string, pcf sqlPrepareContextFn, fields []string,
+	records []*structpb.Struct,
+) error {
+	def := inferTable(records, "")
+
+	for _, field := range fields {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, pq.QuoteIdentifier(field),
+			def.types[field])
+
+		stmt, err := pcf(ctx, query)
+		if err != nil {
+			return fmt.Errorf("unable to prepare statement: %w", err)
+		}
+
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return fmt.Errorf("unable to add column %s to %s: %w", field, table, err)
+		}
+	}
+
+	pg.metaMutex.Lock()
+	defer pg.metaMutex.Unlock()
+
+	pg.meta.cols[table] = append(pg.meta.cols[table], fields...)
+
+	return nil
+}
+
+// overflow will move the fields that are not columns on the table into the overflow column of each record.
+func (pg *Postgres) overflow(table string, fields []string, records []*structpb.Struct) ([]*structpb.Struct, error) {
+	column := pg.opts.overflowColumn
+	if !pg.meta.hasColumn(table, column) {
+		return nil, fmt.Errorf("%w: %s on %s", ErrMissingOverflowColumn, column, table)
+	}
+
+	overflowed := make([]*structpb.Struct, len(records))
+
+	for idx, record := range records {
+		extra := &structpb.Struct{Fields: make(map[string]*structpb.Value)}
+		rec := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(record.GetFields()))}
+
+		for field, val := range record.GetFields() {
+			rec.Fields[field] = val
+		}
+
+		for _, field := range fields {
+			if val, ok := rec.Fields[field]; ok {
+				extra.Fields[field] = val
+				delete(rec.Fields, field)
+			}
+		}
+
+		if len(extra.Fields) > 0 {
+			rec.Fields[column] = structpb.NewStructValue(extra)
+		}
+
+		overflowed[idx] = rec
+	}
+
+	return overflowed, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// stmtCache is a cache of prepared statements keyed by their query, so that the upsert statement for a table, a set of
+// columns, and a number of records is only prepared once.
+type stmtCache struct {
+	mutex sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache() *stmtCache {
+	return &stmtCache{stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepareFn will return a function that prepares a statement with "pcf" the first time its query is seen, and
+// returns the cached statement after that.
+func (cache *stmtCache) prepareFn(pcf sqlPrepareContextFn) sqlPrepareContextFn {
+	return func(ctx context.Context, query string) (*sql.Stmt, error) {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+
+		if stmt, ok := cache.stmts[query]; ok {
+			return stmt, nil
+		}
+
+		stmt, err := pcf(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.stmts[query] = stmt
+
+		return stmt, nil
+	}
+}
+
+// close will close every statement in the cache.
+func (cache *stmtCache) close() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for query, stmt := range cache.stmts {
+		stmt.Close()
+		delete(cache.stmts, query)
+	}
+}
+
+// stmtCacheFor will return the statement cache of the transaction on the context, or the statement cache of the
+// database if there is no transaction. Statements that are prepared on a transaction are closed with it.
+func (pg *Postgres) stmtCacheFor(ctx context.Context) *stmtCache {
+	if txID, ok := ctx.Value(basicPostgressTxID).(string); ok {
+		if cache, ok := pg.txStmts.Load(txID); ok {
+			if cache, ok := cache.(*stmtCache); ok {
+				return cache
+			}
+		}
+	}
+
+	return pg.stmts
+}
@@ -0,0 +1,54 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	prepared := map[string]int{}
+	pcf := func(_ context.Context, query string) (*sql.Stmt, error) {
+		prepared[query]++
+
+		return &sql.Stmt{}, nil
+	}
+
+	cache := newStmtCache()
+	prepareFn := cache.prepareFn(pcf)
+
+	for _, query := range []string{"INSERT INTO candles", "INSERT INTO candles", "INSERT INTO trades"} {
+		if _, err := prepareFn(ctx, query); err != nil {
+			t.Fatalf("failed to prepare %q: %v", query, err)
+		}
+	}
+
+	if prepared["INSERT INTO candles"] != 1 || prepared["INSERT INTO trades"] != 1 {
+		t.Errorf("expected each query to be prepared once, got %v", prepared)
+	}
+
+	pdb := &Postgres{stmts: cache}
+	txCache := newStmtCache()
+	pdb.txStmts.Store("tx", txCache)
+
+	if got := pdb.stmtCacheFor(ctx); got != cache {
+		t.Errorf("expected the database cache without a transaction")
+	}
+
+	if got := pdb.stmtCacheFor(context.WithValue(ctx, basicPostgressTxID, "tx")); got != txCache {
+		t.Errorf("expected the transaction cache with a transaction")
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+
+// Package predicate matches records against simple declarative predicates, each of which compares a field with a
+// literal, e.g. `status == "done"` or `volume > 0`. The field may name a field of a nested object with dots, e.g.
+// "quote.price", and the literal is a quoted string, a number, "true", "false", or "null".
+package predicate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = fmt.Errorf("invalid predicate")
+
+// operator compares the value of a field with a literal.
+type operator string
+
+const (
+	opEqual          operator = "=="
+	opNotEqual       operator = "!="
+	opGreater        operator = ">"
+	opGreaterOrEqual operator = ">="
+	opLess           operator = "<"
+	opLessOrEqual    operator = "<="
+)
+
+// operators are the operators, with the operators that are prefixes of others after them.
+var operators = []operator{opEqual, opNotEqual, opGreaterOrEqual, opLessOrEqual, opGreater, opLess}
+
+// literalKind is the kind of the literal of a predicate.
+type literalKind uint8
+
+const (
+	literalString literalKind = iota
+	literalNumber
+	literalBool
+	literalNull
+)
+
+// Predicate is a parsed predicate.
+type Predicate struct {
+	expr    string
+	path    []string
+	op      operator
+	kind    literalKind
+	str     string
+	number  float64
+	boolean bool
+}
+
+// Parse will parse the predicate, e.g. `status == "done"`.
+func Parse(expr string) (*Predicate, error) {
+	start := strings.IndexAny(expr, "=!<>")
+	if start < 0 {
+		return nil, fmt.Errorf("%w: %q has no operator", ErrInvalid, expr)
+	}
+
+	pred := &Predicate{expr: expr, path: strings.Split(strings.TrimSpace(expr[:start]), ".")}
+
+	for _, field := range pred.path {
+		if field == "" || strings.ContainsAny(field, " \t") {
+			return nil, fmt.Errorf("%w: %q has an invalid field", ErrInvalid, expr)
+		}
+	}
+
+	rest := expr[start:]
+
+	for _, op := range operators {
+		if strings.HasPrefix(rest, string(op)) {
+			pred.op = op
+			rest = rest[len(op):]
+
+			break
+		}
+	}
+
+	if pred.op == "" {
+		return nil, fmt.Errorf("%w: %q has an invalid operator", ErrInvalid, expr)
+	}
+
+	if err := pred.parseLiteral(strings.TrimSpace(rest)); err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalid, expr, err)
+	}
+
+	return pred, nil
+}
+
+// parseLiteral will parse the literal that the field is compared with.
+func (pred *Predicate) parseLiteral(literal string) error {
+	switch {
+	case literal == "":
+		return fmt.Errorf("no literal")
+	case literal == "null":
+		pred.kind = literalNull
+	case literal == "true" || literal == "false":
+		pred.kind = literalBool
+		pred.boolean = literal == "true"
+	case literal[0] == '"':
+		str, err := strconv.Unquote(literal)
+		if err != nil {
+			return fmt.Errorf("invalid string %s", literal)
+		}
+
+		pred.kind = literalString
+		pred.str = str
+	case literal[0] == '\'':
+		if len(literal) < 2 || literal[len(literal)-1] != '\'' {
+			return fmt.Errorf("invalid string %s", literal)
+		}
+
+		pred.kind = literalString
+		pred.str = literal[1 : len(literal)-1]
+	default:
+		number, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return fmt.Errorf("invalid literal %s", literal)
+		}
+
+		pred.kind = literalNumber
+		pred.number = number
+	}
+
+	if (pred.kind == literalNull || pred.kind == literalBool) && pred.op != opEqual &&
+		pred.op != opNotEqual {
+		return fmt.Errorf("%s only supports %s and %s", literal, opEqual, opNotEqual)
+	}
+
+	return nil
+}
+
+// String will return the predicate as it was parsed.
+func (pred *Predicate) String() string {
+	return pred.expr
+}
+
+// Match will return true if the record matches the predicate. A field that the record does not have is null. Strings
+// are compared with a number as the number they hold, and numbers with a string as the text of the number. A value
+// that cannot be compared with the literal only matches "!=".
+func (pred *Predicate) Match(record map[string]interface{}) bool {
+	var val interface{} = record
+
+	for _, field := range pred.path {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			val = nil
+
+			break
+		}
+
+		val = obj[field]
+	}
+
+	cmp, ok := pred.compare(val)
+	if !ok {
+		return pred.op == opNotEqual
+	}
+
+	switch pred.op {
+	case opEqual:
+		return cmp == 0
+	case opNotEqual:
+		return cmp != 0
+	case opGreater:
+		return cmp > 0
+	case opGreaterOrEqual:
+		return cmp >= 0
+	case opLess:
+		return cmp < 0
+	case opLessOrEqual:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+// compare will compare the value with the literal, returning -1, 0, or 1, or false if they cannot be compared.
+func (pred *Predicate) compare(val interface{}) (int, bool) {
+	switch pred.kind {
+	case literalNull:
+		if val == nil {
+			return 0, true
+		}
+
+		return 1, true
+	case literalBool:
+		boolean, ok := val.(bool)
+		if !ok || boolean != pred.boolean {
+			return 1, true
+		}
+
+		return 0, true
+	case literalNumber:
+		number, ok := toNumber(val)
+		if !ok {
+			return 0, false
+		}
+
+		return compareNumbers(number, pred.number), true
+	case literalString:
+		str, ok := toString(val)
+		if !ok {
+			return 0, false
+		}
+
+		return strings.Compare(str, pred.str), true
+	default:
+		return 0, false
+	}
+}
+
+// toNumber will return the value as a number, if it is a number or a string that holds one.
+func toNumber(val interface{}) (float64, bool) {
+	var text string
+
+	switch val := val.(type) {
+	case json.Number:
+		text = val.String()
+	case float64:
+		return val, true
+	case string:
+		text = strings.TrimSpace(val)
+	default:
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(text, 64)
+
+	return number, err == nil
+}
+
+// toString will return the value as a string, if it is a string or a number.
+func toString(val interface{}) (string, bool) {
+	switch val := val.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// compareNumbers will return -1, 0, or 1 if the first number is less than, equal to, or greater than the second.
+func compareNumbers(lhs, rhs float64) int {
+	switch {
+	case lhs < rhs:
+		return -1
+	case lhs > rhs:
+		return 1
+	default:
+		return 0
+	}
+}
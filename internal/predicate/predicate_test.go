@@ -0,0 +1,90 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package predicate
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{`status == "done"`, `volume>0`, `quote.price <= -1.5e3`, `side != 'buy'`,
+		`deleted == false`, `closed_at != null`, `time >= "2022-05-01T00:00:00Z"`} {
+		pred, err := Parse(expr)
+		if err != nil {
+			t.Errorf("%q: failed to parse: %v", expr, err)
+
+			continue
+		}
+
+		if pred.String() != expr {
+			t.Errorf("%q: expected the predicate, got %q", expr, pred.String())
+		}
+	}
+
+	for _, expr := range []string{``, `status`, `== "done"`, `status = "done"`, `status == `, `status == done`,
+		`status == "done`, `status == 'done`, `quote..price > 0`, `my field > 0`, `deleted > true`, `x < null`} {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: expected %v, got %v", expr, ErrInvalid, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"status":"done","volume":"1.5","size":0,"deleted":false,`+
+		`"closed_at":null,"quote":{"price":100},"tags":["a"]}`), &record); err != nil {
+		t.Fatalf("failed to unmarshal record: %v", err)
+	}
+
+	record["size"] = json.Number("0")
+
+	for _, tcase := range []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `status == "done"`, expected: true},
+		{expr: `status != "done"`, expected: false},
+		{expr: `status == 'open'`, expected: false},
+		{expr: `volume > 0`, expected: true},
+		{expr: `volume == 1.50`, expected: true},
+		{expr: `size > 0`, expected: false},
+		{expr: `size == "0"`, expected: true},
+		{expr: `quote.price >= 100`, expected: true},
+		{expr: `quote.price < 100`, expected: false},
+		{expr: `quote.missing == null`, expected: true},
+		{expr: `status.price == null`, expected: true},
+		{expr: `deleted == false`, expected: true},
+		{expr: `deleted != true`, expected: true},
+		{expr: `status == true`, expected: false},
+		{expr: `closed_at == null`, expected: true},
+		{expr: `missing == null`, expected: true},
+		{expr: `status != null`, expected: true},
+		{expr: `missing > 0`, expected: false},
+		{expr: `missing != 0`, expected: true},
+		{expr: `status > 0`, expected: false},
+		{expr: `tags == "a"`, expected: false},
+		{expr: `status >= "d"`, expected: true},
+	} {
+		pred, err := Parse(tcase.expr)
+		if err != nil {
+			t.Fatalf("%q: failed to parse: %v", tcase.expr, err)
+		}
+
+		if matched := pred.Match(record); matched != tcase.expected {
+			t.Errorf("%q: expected %v, got %v", tcase.expr, tcase.expected, matched)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+const percentage = 100
+
+// Display is a live view of the progress of a run in a terminal: the chunks of each request that have been fetched,
+// the records that have been upserted, the last wait for the rate limiter, and the estimated time until every chunk
+// has been fetched. Each frame replaces the previous one, instead of adding a log line for each job.
+type Display struct {
+	mutex sync.Mutex
+	wtr   io.Writer
+	start time.Time
+	now   func() time.Time
+
+	// requests are the progress of each request, in the order they were first reported.
+	requests []*requestProgress
+	wait     time.Duration
+
+	// lines is the number of lines of the last frame, which are erased by the next frame.
+	lines int
+}
+
+// requestProgress is the progress of a request of the run.
+type requestProgress struct {
+	endpoint string
+	table    string
+	fetched  int
+	chunks   int
+	upserted int64
+	status   string
+}
+
+// NewDisplay will return a display that writes its frames to the writer, which is usually stderr.
+func NewDisplay(wtr io.Writer) *Display {
+	return &Display{wtr: wtr, start: time.Now(), now: time.Now}
+}
+
+// Watch will report the progress of the run of the configuration to the display. The progress functions of the
+// configuration are still called.
+func (display *Display) Watch(cfg *config.Config) {
+	progress, onFetch, onUpsert := cfg.Progress, cfg.OnFetch, cfg.OnUpsert
+
+	cfg.OnFetch = func(fetch config.FetchProgress) {
+		display.Fetch(fetch)
+
+		if onFetch != nil {
+			onFetch(fetch)
+		}
+	}
+
+	cfg.OnUpsert = func(upsert config.UpsertProgress) {
+		display.Upsert(upsert)
+
+		if onUpsert != nil {
+			onUpsert(upsert)
+		}
+	}
+
+	cfg.Progress = func(rsp config.RequestProgress) {
+		display.Request(rsp)
+
+		if progress != nil {
+			progress(rsp)
+		}
+	}
+}
+
+// Start will render a frame every interval until the returned function is called, which renders the last frame.
+func (display *Display) Start(interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				display.Render()
+
+				return
+			case <-ticker.C:
+				display.Render()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// request will return the progress of the request, adding it if it has not been reported yet.
+func (display *Display) request(endpoint, table string) *requestProgress {
+	for _, req := range display.requests {
+		if req.endpoint == endpoint && req.table == table {
+			return req
+		}
+	}
+
+	req := &requestProgress{endpoint: endpoint, table: table}
+	display.requests = append(display.requests, req)
+
+	return req
+}
+
+// Fetch will report the chunks of a request that have been fetched.
+func (display *Display) Fetch(progress config.FetchProgress) {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+
+	req := display.request(progress.Endpoint, progress.Table)
+	req.chunks = progress.Chunks
+
+	// Chunks are fetched concurrently, so their progress can be reported out of order.
+	if progress.Fetched > req.fetched {
+		req.fetched = progress.Fetched
+	}
+
+	if progress.Fetched > 0 {
+		display.wait = progress.RateLimitWait
+	}
+}
+
+// Upsert will report records of a request that have been upserted.
+func (display *Display) Upsert(progress config.UpsertProgress) {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+
+	display.request(progress.Endpoint, progress.Table).upserted += progress.Upserted
+}
+
+// Request will report the outcome of a request.
+func (display *Display) Request(progress config.RequestProgress) {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+
+	display.request(progress.Endpoint, progress.Table).status = progress.Status
+}
+
+// Render will replace the last frame with the current progress of the run.
+func (display *Display) Render() {
+	display.mutex.Lock()
+	defer display.mutex.Unlock()
+
+	var frame strings.Builder
+
+	// Move the cursor to the start of the last frame, and erase it.
+	if display.lines > 0 {
+		fmt.Fprintf(&frame, "\x1b[%dA\x1b[J", display.lines)
+	}
+
+	fetched, chunks, upserted := 0, 0, int64(0)
+	for _, req := range display.requests {
+		fetched += req.fetched
+		chunks += req.chunks
+		upserted += req.upserted
+	}
+
+	elapsed := display.now().Sub(display.start)
+
+	eta := "unknown"
+	if fetched > 0 {
+		eta = (elapsed * time.Duration(chunks-fetched) / time.Duration(fetched)).Round(time.Second).String()
+	}
+
+	fmt.Fprintf(&frame, "%d/%d chunks (%d%%), %d records upserted, rate limit wait %s, elapsed %s, eta %s\n",
+		fetched, chunks, percent(fetched, chunks), upserted, display.wait.Round(time.Millisecond),
+		elapsed.Round(time.Second), eta)
+
+	for _, req := range display.requests {
+		fmt.Fprintf(&frame, "  %s -> %s: %d/%d chunks, %d upserted", req.endpoint, req.table, req.fetched, req.chunks,
+			req.upserted)
+
+		if req.status != "" {
+			frame.WriteString(", " + req.status)
+		}
+
+		frame.WriteString("\n")
+	}
+
+	display.lines = len(display.requests) + 1
+
+	// The display is best effort, so a frame that cannot be written is dropped.
+	_, _ = io.WriteString(display.wtr, frame.String())
+}
+
+// percent will return the part of the total as a percentage, which is zero if the total is.
+func percent(part, total int) int {
+	if total == 0 {
+		return 0
+	}
+
+	return part * percentage / total
+}
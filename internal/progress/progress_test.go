@@ -0,0 +1,74 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestDisplay(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	display := NewDisplay(&out)
+	display.start = start
+	display.now = func() time.Time { return now }
+
+	var requests []config.RequestProgress
+
+	cfg := &config.Config{Progress: func(rsp config.RequestProgress) { requests = append(requests, rsp) }}
+	display.Watch(cfg)
+
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Chunks: 4})
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/currencies", Table: "currencies", Chunks: 1})
+
+	display.Render()
+
+	want := "0/5 chunks (0%), 0 records upserted, rate limit wait 0s, elapsed 0s, eta unknown\n" +
+		"  /candles -> candles: 0/4 chunks, 0 upserted\n" +
+		"  /currencies -> currencies: 0/1 chunks, 0 upserted\n"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+
+	now = start.Add(time.Minute)
+
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Fetched: 2, Chunks: 4,
+		RateLimitWait: 250 * time.Millisecond})
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Fetched: 1, Chunks: 4})
+	cfg.OnUpsert(config.UpsertProgress{Endpoint: "/candles", Table: "candles", Upserted: 300})
+	cfg.OnUpsert(config.UpsertProgress{Endpoint: "/candles", Table: "candles", Upserted: 200})
+	cfg.Progress(config.RequestProgress{Endpoint: "/currencies", Table: "currencies", Status: config.RequestCommitted})
+
+	display.Render()
+
+	// The last frame is erased, and the chunks that were reported out of order are not counted twice.
+	want = "\x1b[3A\x1b[J" +
+		"2/5 chunks (40%), 500 records upserted, rate limit wait 0s, elapsed 1m0s, eta 1m30s\n" +
+		"  /candles -> candles: 2/4 chunks, 500 upserted\n" +
+		"  /currencies -> currencies: 0/1 chunks, 0 upserted, committed\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+
+	if len(requests) != 1 {
+		t.Errorf("expected the progress function of the configuration to be called, got %+v", requests)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrListColumnsNotSupported is returned when a storage device does not have a schema of columns to list.
+var ErrListColumnsNotSupported = fmt.Errorf("listing columns is not supported")
+
+// ColumnLister is a storage device with a schema that can list the columns of its tables and their types.
+// Implementing it is optional.
+type ColumnLister interface {
+	ListColumns(context.Context) (*ListColumnsResponse, error)
+}
+
+// ListColumns will list the columns of every table on the storage device, unwrapping storage services. If the storage
+// device does not implement "ColumnLister", ErrListColumnsNotSupported is returned.
+func ListColumns(ctx context.Context, stg Storage) (*ListColumnsResponse, error) {
+	lister, ok := implementation[ColumnLister](stg)
+	if !ok {
+		return nil, ErrListColumnsNotSupported
+	}
+
+	return lister.ListColumns(ctx)
+}
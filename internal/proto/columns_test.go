@@ -0,0 +1,40 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// columnStorage is a storage device that lists a single column.
+type columnStorage struct{ Storage }
+
+func (columnStorage) ListColumns(context.Context) (*ListColumnsResponse, error) {
+	return &ListColumnsResponse{ColSet: map[string]*Columns{"candles": {List: []string{"time"}}}}, nil
+}
+
+func TestListColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	rsp, err := ListColumns(ctx, &StorageService{Storage: &Service{Storage: columnStorage{}}})
+	if err != nil {
+		t.Fatalf("failed to list columns: %v", err)
+	}
+
+	if cols := rsp.GetColSet()["candles"].GetList(); len(cols) != 1 || cols[0] != "time" {
+		t.Errorf("expected the columns of the wrapped storage, got %v", cols)
+	}
+
+	if _, err := ListColumns(ctx, &StorageService{}); !errors.Is(err, ErrListColumnsNotSupported) {
+		t.Errorf("expected %v, got %v", ErrListColumnsNotSupported, err)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import "fmt"
+
+// Strategies for resolving conflicts between an upserted record and an existing record with the same primary keys.
+const (
+	// ConflictInsertOnly will keep the existing record, ignoring the upserted record.
+	ConflictInsertOnly = "insert-only"
+
+	// ConflictReplace will replace the existing record with the upserted record. This is the default strategy.
+	ConflictReplace = "replace"
+
+	// ConflictMergeNonNull will update the existing record with the fields of the upserted record that are not
+	// null.
+	ConflictMergeNonNull = "merge-non-null"
+
+	// ConflictFail will fail the upsert.
+	ConflictFail = "fail"
+)
+
+var ErrConflict = fmt.Errorf("record already exists")
+
+// ValidConflict returns "true" if the conflict strategy is supported. An empty strategy is the default strategy.
+func ValidConflict(conflict string) bool {
+	switch conflict {
+	case "", ConflictInsertOnly, ConflictReplace, ConflictMergeNonNull, ConflictFail:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrDefineTableNotSupported is returned when a storage device does not create its tables from a declared schema.
+var ErrDefineTableNotSupported = fmt.Errorf("defining tables is not supported")
+
+// Types of the columns of a declared table, which each storage device maps to its own types.
+const (
+	ColumnTypeString    = "string"
+	ColumnTypeInteger   = "integer"
+	ColumnTypeNumber    = "number"
+	ColumnTypeDecimal   = "decimal"
+	ColumnTypeBoolean   = "boolean"
+	ColumnTypeTimestamp = "timestamp"
+	ColumnTypeJSON      = "json"
+)
+
+// ColumnDefinition is a declared column of a table.
+type ColumnDefinition struct {
+	// Name is the name of the column.
+	Name string
+
+	// Type is the type of the column, e.g. "integer".
+	Type string
+
+	// Required is true if the column cannot be null.
+	Required bool
+}
+
+// DefineTableRequest is the declared schema of a table, which a storage device uses instead of inferring the schema
+// from the records if it creates the table.
+type DefineTableRequest struct {
+	// Table is the name of the table or collection.
+	Table string
+
+	// Columns are the declared columns of the table.
+	Columns []*ColumnDefinition
+
+	// PrimaryKeys are the columns that uniquely identify a record in the table.
+	PrimaryKeys []string
+}
+
+// TableDefiner is a storage device with a schema that can create its tables from a declared schema, instead of
+// inferring it from the first records that are written. Implementing it is optional.
+type TableDefiner interface {
+	DefineTable(context.Context, *DefineTableRequest) error
+}
+
+// DefineTable will declare the schema of the table on the storage device, unwrapping storage services. If the storage
+// device does not implement "TableDefiner", ErrDefineTableNotSupported is returned.
+func DefineTable(ctx context.Context, stg Storage, req *DefineTableRequest) error {
+	definer, ok := implementation[TableDefiner](stg)
+	if !ok {
+		return ErrDefineTableNotSupported
+	}
+
+	return definer.DefineTable(ctx, req)
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// definerStorage is a storage device that keeps the last table definition.
+type definerStorage struct {
+	Storage
+	req *DefineTableRequest
+}
+
+func (stg *definerStorage) DefineTable(_ context.Context, req *DefineTableRequest) error {
+	stg.req = req
+
+	return nil
+}
+
+func TestDefineTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stg := &definerStorage{}
+	req := &DefineTableRequest{Table: "trades", Columns: []*ColumnDefinition{{Name: "id", Type: ColumnTypeInteger}}}
+
+	if err := DefineTable(ctx, &StorageService{Storage: &Service{Storage: stg}}, req); err != nil {
+		t.Fatalf("failed to define table: %v", err)
+	}
+
+	if stg.req != req {
+		t.Errorf("expected the table of the wrapped storage to be defined, got %v", stg.req)
+	}
+
+	if err := DefineTable(ctx, &StorageService{}, req); !errors.Is(err, ErrDefineTableNotSupported) {
+		t.Errorf("expected %v, got %v", ErrDefineTableNotSupported, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrDeleteNotSupported is returned when a storage device cannot delete the records of a table that match a filter.
+var ErrDeleteNotSupported = fmt.Errorf("delete is not supported")
+
+// TimeRange is the range of times, from "Start" up to but excluding "End", in a column of a table. If "Start" is
+// zero, the range has no lower bound, and if "End" is zero, it has no upper bound.
+type TimeRange struct {
+	Column string
+	Start  time.Time
+	End    time.Time
+}
+
+// DeleteRequest is a request to delete the records of a table that match every field in "Match" and, if it is set,
+// whose time is in "Range".
+type DeleteRequest struct {
+	// Table is the name of the table or collection to delete records from.
+	Table string
+
+	// Match are the values that records must have to be deleted, keyed by column.
+	Match map[string]interface{}
+
+	// Range is the time range that records must be in to be deleted.
+	Range *TimeRange
+}
+
+// DeleteResponse is the response for deleting records from a table.
+type DeleteResponse struct {
+	// DeletedCount is the number of records that were deleted.
+	DeletedCount int64
+}
+
+// Deleter is a storage device that can delete the records of a table that match a filter, e.g. to truncate part of a
+// table. Implementing it is optional.
+type Deleter interface {
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+}
+
+// Delete will delete the records from the table on the storage device, unwrapping storage services. If the storage
+// device does not implement "Deleter", ErrDeleteNotSupported is returned.
+func Delete(ctx context.Context, stg Storage, req *DeleteRequest) (*DeleteResponse, error) {
+	deleter, ok := implementation[Deleter](stg)
+	if !ok {
+		return nil, ErrDeleteNotSupported
+	}
+
+	return deleter.Delete(ctx, req)
+}
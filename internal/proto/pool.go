@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrPoolNotSupported is returned when the connection pool of a storage device cannot be configured.
+var ErrPoolNotSupported = fmt.Errorf("pool configuration is not supported")
+
+// PoolRequest is a request to configure the connection pool of a storage device. Settings that are zero keep the
+// default of the storage device.
+type PoolRequest struct {
+	// MaxOpenConns is the maximum number of open connections.
+	MaxOpenConns int
+
+	// MaxIdleConns is the maximum number of idle connections that are kept open.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum amount of time a connection is reused.
+	ConnMaxLifetime time.Duration
+}
+
+// Pooler is a storage device with a connection pool that can be configured. Implementing it is optional.
+type Pooler interface {
+	ConfigurePool(context.Context, *PoolRequest) error
+}
+
+// ConfigurePool will configure the connection pool of the storage device, unwrapping storage services. If the storage
+// device does not implement "Pooler", ErrPoolNotSupported is returned.
+func ConfigurePool(ctx context.Context, stg Storage, req *PoolRequest) error {
+	pooler, ok := implementation[Pooler](stg)
+	if !ok {
+		return ErrPoolNotSupported
+	}
+
+	return pooler.ConfigurePool(ctx, req)
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// poolStorage is a storage device that keeps the last pool configuration.
+type poolStorage struct {
+	Storage
+	req *PoolRequest
+}
+
+func (stg *poolStorage) ConfigurePool(_ context.Context, req *PoolRequest) error {
+	stg.req = req
+
+	return nil
+}
+
+func TestConfigurePool(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stg := &poolStorage{}
+	req := &PoolRequest{MaxOpenConns: 10}
+
+	if err := ConfigurePool(ctx, &StorageService{Storage: &Service{Storage: stg}}, req); err != nil {
+		t.Fatalf("failed to configure pool: %v", err)
+	}
+
+	if stg.req != req {
+		t.Errorf("expected the pool of the wrapped storage to be configured, got %v", stg.req)
+	}
+
+	if err := ConfigurePool(ctx, &StorageService{}, req); !errors.Is(err, ErrPoolNotSupported) {
+		t.Errorf("expected %v, got %v", ErrPoolNotSupported, err)
+	}
+}
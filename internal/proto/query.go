@@ -0,0 +1,58 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrQueryNotSupported is returned when a storage device cannot read records back out of a table.
+var ErrQueryNotSupported = fmt.Errorf("query is not supported")
+
+// QueryRequest is a request to read the records of a table that match every field in "Match" and, if it is set,
+// whose time is in "Range".
+type QueryRequest struct {
+	// Table is the name of the table or collection to read records from.
+	Table string
+
+	// Match are the values that records must have to be read, keyed by column.
+	Match map[string]interface{}
+
+	// Range is the time range that records must be in to be read. Records are read in the order of its column.
+	Range *TimeRange
+
+	// Limit is the maximum number of records to read, where zero reads every record.
+	Limit int
+
+	// Descending reads the records in the descending order of the column of "Range", e.g. to read the latest record.
+	Descending bool
+}
+
+// QueryResponse is the response for reading records from a table.
+type QueryResponse struct {
+	Records []*structpb.Struct
+}
+
+// Querier is a storage device that can read the records of a table back out. Implementing it is optional.
+type Querier interface {
+	Query(context.Context, *QueryRequest) (*QueryResponse, error)
+}
+
+// Query will read the records from the table on the storage device, unwrapping storage services. If the storage
+// device does not implement "Querier", ErrQueryNotSupported is returned.
+func Query(ctx context.Context, stg Storage, req *QueryRequest) (*QueryResponse, error) {
+	querier, ok := implementation[Querier](stg)
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+
+	return querier.Query(ctx, req)
+}
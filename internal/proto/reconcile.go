@@ -0,0 +1,73 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrReconcileNotSupported is returned when a storage device cannot mark records as deleted.
+var ErrReconcileNotSupported = fmt.Errorf("reconcile is not supported")
+
+// ReconcileRequest is a request to flag the records of a table that were not fetched as deleted.
+type ReconcileRequest struct {
+	// Table is the name of the table or collection to reconcile.
+	Table string
+
+	// PrimaryKeys are the fields that uniquely identify a record.
+	PrimaryKeys []string
+
+	// Keys are the primary key values of every fetched record, keyed by the primary key field.
+	Keys []map[string]interface{}
+
+	// DeletedColumn is the boolean column that is set to true for records that were not fetched, and to false for
+	// records that were.
+	DeletedColumn string
+}
+
+// ReconcileResponse is the response for reconciling a table.
+type ReconcileResponse struct {
+	// UpdatedCount is the number of records whose deleted flag was changed.
+	UpdatedCount int64
+}
+
+// Reconciler is a storage device that can flag the records of a table that are absent from a full fetch as deleted.
+// Implementing it is optional.
+type Reconciler interface {
+	Reconcile(context.Context, *ReconcileRequest) (*ReconcileResponse, error)
+}
+
+// Reconcile will reconcile the table on the storage device, unwrapping storage services. If the storage device does
+// not implement "Reconciler", ErrReconcileNotSupported is returned.
+func Reconcile(ctx context.Context, stg Storage, req *ReconcileRequest) (*ReconcileResponse, error) {
+	reconciler, ok := implementation[Reconciler](stg)
+	if !ok {
+		return nil, ErrReconcileNotSupported
+	}
+
+	return reconciler.Reconcile(ctx, req)
+}
+
+// implementation will return the storage device as the optional interface T, unwrapping storage services.
+func implementation[T any](stg Storage) (T, bool) {
+	for {
+		switch svc := stg.(type) {
+		case T:
+			return svc, true
+		case *StorageService:
+			stg = svc.Storage
+		case *Service:
+			stg = svc.Storage
+		default:
+			var none T
+
+			return none, false
+		}
+	}
+}
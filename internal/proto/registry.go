@@ -0,0 +1,100 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const unknownScheme = "unknown"
+
+var (
+	ErrInvalidScheme    = fmt.Errorf("invalid scheme")
+	ErrSchemeRegistered = fmt.Errorf("scheme is already registered")
+)
+
+var (
+	constructorsMutex sync.RWMutex
+
+	// constructors are the storage constructors registered for schemes that are not built in.
+	constructors = make(map[string]Constructor)
+)
+
+// builtinScheme returns "true" if the scheme belongs to a storage type that is built in.
+func builtinScheme(scheme string) bool {
+	for t := uint8(1); SchemeFromStorageType(t) != unknownScheme; t++ {
+		if SchemeFromStorageType(t) == scheme {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RegisterConstructor will register a storage constructor for connection strings with the scheme. Built-in schemes
+// cannot be registered, and each scheme can only be registered once.
+func RegisterConstructor(scheme string, constructor Constructor) error {
+	if scheme == "" || scheme == unknownScheme || constructor == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidScheme, scheme)
+	}
+
+	constructorsMutex.Lock()
+	defer constructorsMutex.Unlock()
+
+	if _, ok := constructors[scheme]; ok || builtinScheme(scheme) {
+		return fmt.Errorf("%w: %q", ErrSchemeRegistered, scheme)
+	}
+
+	constructors[scheme] = constructor
+
+	return nil
+}
+
+// LookupConstructor will return the storage constructor registered for the scheme, if there is one.
+func LookupConstructor(scheme string) (Constructor, bool) {
+	constructorsMutex.RLock()
+	defer constructorsMutex.RUnlock()
+
+	constructor, ok := constructors[scheme]
+
+	return constructor, ok
+}
+
+// KnownScheme returns "true" if storage can be constructed for connection strings with the scheme, either because it
+// is built in or because a constructor is registered for it.
+func KnownScheme(scheme string) bool {
+	if builtinScheme(scheme) {
+		return true
+	}
+
+	_, ok := LookupConstructor(scheme)
+
+	return ok
+}
+
+// Schemes returns the sorted schemes of connection strings that storage can be constructed for, both built in and
+// registered.
+func Schemes() []string {
+	var schemes []string
+	for t := uint8(1); SchemeFromStorageType(t) != unknownScheme; t++ {
+		schemes = append(schemes, SchemeFromStorageType(t))
+	}
+
+	constructorsMutex.RLock()
+	defer constructorsMutex.RUnlock()
+
+	for scheme := range constructors {
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+
+	return schemes
+}
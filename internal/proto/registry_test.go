@@ -0,0 +1,35 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func TestSchemes(t *testing.T) {
+	t.Parallel()
+
+	constructor := func(context.Context, string) (*StorageService, error) { return nil, nil }
+	if err := RegisterConstructor("schemes-test", constructor); err != nil {
+		t.Fatalf("failed to register constructor: %v", err)
+	}
+
+	schemes := Schemes()
+	if !sort.StringsAreSorted(schemes) {
+		t.Errorf("expected sorted schemes, got %v", schemes)
+	}
+
+	for _, scheme := range []string{"file", "mongodb", "postgresql", "stdout", "schemes-test"} {
+		idx := sort.SearchStrings(schemes, scheme)
+		if idx == len(schemes) || schemes[idx] != scheme {
+			t.Errorf("expected %q in %v", scheme, schemes)
+		}
+	}
+}
@@ -19,6 +19,27 @@ const (
 
 	// MongoType is the byte representation of a mongo database.
 	MongoType = 0x02
+
+	// FileType is the byte representation of a local file storage directory.
+	FileType = 0x03
+
+	// S3Type is the byte representation of an S3 bucket.
+	S3Type = 0x04
+
+	// GCSType is the byte representation of a Google Cloud Storage bucket.
+	GCSType = 0x05
+
+	// AzureBlobType is the byte representation of an Azure Blob Storage container.
+	AzureBlobType = 0x06
+
+	// StdoutType is the byte representation of the standard output stream.
+	StdoutType = 0x07
+
+	// WebhookType is the byte representation of an HTTP webhook.
+	WebhookType = 0x08
+
+	// GRPCType is the byte representation of a remote storage service reached over gRPC.
+	GRPCType = 0x09
 )
 
 var ErrDNSNotSupported = fmt.Errorf("dns is not supported")
@@ -76,8 +97,22 @@ func SchemeFromStorageType(t uint8) string {
 		return "mongodb"
 	case PostgresType:
 		return "postgresql"
+	case FileType:
+		return "file"
+	case S3Type:
+		return "s3"
+	case GCSType:
+		return "gs"
+	case AzureBlobType:
+		return "azblob"
+	case StdoutType:
+		return "stdout"
+	case WebhookType:
+		return "webhook"
+	case GRPCType:
+		return "grpc"
 	default:
-		return "unknown"
+		return unknownScheme
 	}
 }
 
@@ -210,6 +210,8 @@ func (runner TestRunner) closeDB(_ context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.closeDBCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s close db", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -232,6 +234,8 @@ func (runner TestRunner) storageType(_ context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.storageTypeCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s storage type", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -253,6 +257,8 @@ func (runner TestRunner) isNoSQL(_ context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.isNoSQLCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s is no sql db", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -273,6 +279,8 @@ func (runner TestRunner) listTables(_ context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.listTablesCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s list tables", tcase.Name)
 
 		t.Run(name, func(t *testing.T) {
@@ -326,6 +334,8 @@ func (runner TestRunner) listPrimaryKeys(ctx context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.listPrimaryKeysCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s list primary keys", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -376,6 +386,8 @@ func (runner TestRunner) upsertTxn(ctx context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.upsertTxnCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s upsert txn", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -440,6 +452,8 @@ func (runner TestRunner) upsertBinary(ctx context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.upsertBinaryCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s upsert binary", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
@@ -504,6 +518,8 @@ func (runner TestRunner) ping(_ context.Context, t *testing.T) {
 	t.Helper()
 
 	for _, tcase := range runner.pingCases {
+		tcase := tcase
+
 		name := fmt.Sprintf("%s ping db", tcase.Name)
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
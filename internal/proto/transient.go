@@ -0,0 +1,51 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import "fmt"
+
+// ErrTransient is matched by errors that are expected to succeed if the transaction they occurred in is retried, such
+// as deadlocks, serialization failures, and lost connections.
+var ErrTransient = fmt.Errorf("transient storage error")
+
+// ErrContention is matched by transient errors of a storage device that is contended, such as lock timeouts, deadlocks,
+// and running out of connections, which writing to it less at once should relieve.
+var ErrContention = fmt.Errorf("storage contention")
+
+// transientError is an error that matches ErrTransient, while keeping the error it wraps.
+type transientError struct{ err error }
+
+func (e *transientError) Error() string        { return e.err.Error() }
+func (e *transientError) Unwrap() error        { return e.err }
+func (e *transientError) Is(target error) bool { return target == ErrTransient }
+
+// Transient will return the error so that it matches ErrTransient with "errors.Is", or nil if the error is nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &transientError{err: err}
+}
+
+// contentionError is a transient error that also matches ErrContention.
+type contentionError struct{ transientError }
+
+func (e *contentionError) Is(target error) bool {
+	return target == ErrTransient || target == ErrContention
+}
+
+// Contention will return the error so that it matches both ErrContention and ErrTransient with "errors.Is", or nil if
+// the error is nil.
+func Contention(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &contentionError{transientError{err: err}}
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTransient(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("unable to upsert: %w", Transient(ErrConflict))
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the error to match both %v and %v, got %v", ErrTransient, ErrConflict, err)
+	}
+
+	if err.Error() != "unable to upsert: "+ErrConflict.Error() {
+		t.Errorf("expected the message of the wrapped error, got %q", err)
+	}
+
+	if Transient(nil) != nil {
+		t.Errorf("expected nil for a nil error")
+	}
+
+	if errors.Is(ErrConflict, ErrTransient) {
+		t.Errorf("expected %v not to be transient", ErrConflict)
+	}
+}
+
+func TestContention(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("unable to upsert: %w", Contention(ErrConflict))
+	if !errors.Is(err, ErrContention) || !errors.Is(err, ErrTransient) || !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the error to match %v, %v and %v, got %v", ErrContention, ErrTransient, ErrConflict, err)
+	}
+
+	if Contention(nil) != nil {
+		t.Errorf("expected nil for a nil error")
+	}
+
+	if errors.Is(Transient(ErrConflict), ErrContention) {
+		t.Errorf("expected a transient error not to be contention")
+	}
+}
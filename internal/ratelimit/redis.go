@@ -0,0 +1,341 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyPrefix is the prefix of the keys of the rate limits in Redis, which are followed by the host of the web API.
+const KeyPrefix = "gidari:ratelimit:"
+
+// defaultTimeout is how long a command to Redis may take if the context of the request has no deadline.
+const defaultTimeout = 5 * time.Second
+
+// maxIdleConns is the number of connections to Redis that are kept open between requests.
+const maxIdleConns = 4
+
+var (
+	// ErrInvalidURL is returned by NewRedis for URLs that are not "redis" or "rediss" URLs.
+	ErrInvalidURL = errors.New("invalid redis URL")
+
+	// ErrRedis is returned for the error replies of Redis.
+	ErrRedis = errors.New("redis error")
+)
+
+// reserveScript reserves a request under the rate limit, which is a token bucket of "burst" tokens that refills one
+// token every "interval" microseconds. The key holds when the bucket will be full again, see GCRA. The script returns
+// how many microseconds the request must wait before it is made. The clock of Redis is used, so that the clocks of
+// the processes sharing the rate limit do not need to agree.
+const reserveScript = `
+if redis.replicate_commands then redis.replicate_commands() end
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+local full = tonumber(redis.call('GET', KEYS[1]) or 0)
+if full < now then full = now end
+full = full + interval
+redis.call('SET', KEYS[1], string.format('%.0f', full), 'PX', math.ceil((full - now) / 1000) + 1)
+local wait = full - burst * interval - now
+if wait < 0 then wait = 0 end
+return wait
+`
+
+// Redis is a rate limit that is shared through a Redis server, so that every process requesting the same web API
+// stays under the rate limit together. Like "rate.Limiter", it allows "burst" requests at once, and one request for
+// every interval after that. Requests that have to wait keep their place, so that the processes are served in the
+// order that they asked.
+type Redis struct {
+	addr     string
+	tls      *tls.Config
+	username string
+	password string
+	db       int
+
+	key      string
+	burst    int
+	interval time.Duration
+
+	mutex sync.Mutex
+	idle  []*redisConn
+}
+
+// NewRedis will return the rate limit with the key in the Redis server of the URL, e.g. "redis://:pass@host:6379/0",
+// or "rediss://" for TLS. The rate limit allows "burst" requests at once, and one request for every interval after
+// that. No connection is made until the first request.
+func NewRedis(rawURL, key string, burst int, interval time.Duration) (*Redis, error) {
+	uri, err := url.Parse(rawURL)
+	if err != nil || (uri.Scheme != "redis" && uri.Scheme != "rediss") || uri.Host == "" {
+		return nil, fmt.Errorf("%w: must be a redis:// or rediss:// URL with a host", ErrInvalidURL)
+	}
+
+	limiter := &Redis{addr: uri.Host, key: key, burst: burst, interval: interval}
+
+	if uri.Port() == "" {
+		limiter.addr = net.JoinHostPort(uri.Hostname(), "6379")
+	}
+
+	if uri.Scheme == "rediss" {
+		limiter.tls = &tls.Config{ServerName: uri.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+
+	if uri.User != nil {
+		limiter.username = uri.User.Username()
+		limiter.password, _ = uri.User.Password()
+	}
+
+	if path := strings.Trim(uri.Path, "/"); path != "" {
+		if limiter.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("%w: database %q is not a number", ErrInvalidURL, path)
+		}
+	}
+
+	return limiter, nil
+}
+
+// Wait will block until a request may be made under the rate limit, or return the error of the context if it is done
+// first. An error is returned if Redis cannot be reached, rather than making requests that are not limited.
+func (limiter *Redis) Wait(ctx context.Context) error {
+	wait, err := limiter.reserve(ctx)
+	if err != nil {
+		return err
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("rate limit wait cancelled: %w", ctx.Err())
+	}
+}
+
+// reserve will reserve a request under the rate limit, returning how long it must wait before it is made.
+func (limiter *Redis) reserve(ctx context.Context) (time.Duration, error) {
+	conn, err := limiter.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reply, err := conn.do(ctx, "EVAL", reserveScript, "1", limiter.key,
+		strconv.FormatInt(limiter.interval.Microseconds(), 10), strconv.Itoa(limiter.burst))
+	if err != nil {
+		conn.close()
+
+		return 0, fmt.Errorf("failed to reserve rate limit: %w", err)
+	}
+
+	limiter.release(conn)
+
+	wait, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%w: unexpected reply %v to the rate limit script", ErrRedis, reply)
+	}
+
+	return time.Duration(wait) * time.Microsecond, nil
+}
+
+// conn will return an idle connection to Redis, or a new one.
+func (limiter *Redis) conn(ctx context.Context) (*redisConn, error) {
+	limiter.mutex.Lock()
+	if count := len(limiter.idle); count > 0 {
+		conn := limiter.idle[count-1]
+		limiter.idle = limiter.idle[:count-1]
+		limiter.mutex.Unlock()
+
+		return conn, nil
+	}
+	limiter.mutex.Unlock()
+
+	return limiter.dial(ctx)
+}
+
+// release will keep the connection open for the next request, unless enough connections are idle.
+func (limiter *Redis) release(conn *redisConn) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if len(limiter.idle) >= maxIdleConns {
+		conn.close()
+
+		return
+	}
+
+	limiter.idle = append(limiter.idle, conn)
+}
+
+// dial will open a connection to Redis, authenticated and with the database of the URL selected.
+func (limiter *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: defaultTimeout}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", limiter.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	if limiter.tls != nil {
+		netConn = tls.Client(netConn, limiter.tls)
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	var commands [][]string
+
+	switch {
+	case limiter.username != "" && limiter.password != "":
+		commands = append(commands, []string{"AUTH", limiter.username, limiter.password})
+	case limiter.password != "":
+		commands = append(commands, []string{"AUTH", limiter.password})
+	}
+
+	if limiter.db != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(limiter.db)})
+	}
+
+	for _, command := range commands {
+		if _, err := conn.do(ctx, command...); err != nil {
+			conn.close()
+
+			return nil, fmt.Errorf("failed to %s on redis: %w", strings.ToLower(command[0]), err)
+		}
+	}
+
+	return conn, nil
+}
+
+// Close will close the idle connections to Redis.
+func (limiter *Redis) Close() {
+	if limiter == nil {
+		return
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	for _, conn := range limiter.idle {
+		conn.close()
+	}
+
+	limiter.idle = nil
+}
+
+// redisConn is a connection to Redis that sends commands in the RESP protocol.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// do will send the command to Redis and return its reply, which is a string, an integer, nil, or a list of them.
+// Error replies are returned as errors that match ErrRedis.
+func (conn *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+
+	if err := conn.conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	var command strings.Builder
+
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(conn.conn, command.String()); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	return readReply(conn.reader)
+}
+
+// close will close the connection.
+func (conn *redisConn) close() {
+	conn.conn.Close()
+}
+
+// readReply will read a reply in the RESP protocol.
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reply: %w", err)
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("%w: empty reply", ErrRedis)
+	}
+
+	switch kind, rest := line[0], line[1:]; kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, fmt.Errorf("%w: %s", ErrRedis, rest)
+	case ':':
+		return parseInt(rest)
+	case '$':
+		size, err := parseInt(rest)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read reply: %w", err)
+		}
+
+		return string(data[:size]), nil
+	case '*':
+		count, err := parseInt(rest)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+
+		items := make([]interface{}, count)
+		for idx := range items {
+			if items[idx], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected reply %q", ErrRedis, line)
+	}
+}
+
+// parseInt will parse the integer of a reply.
+func parseInt(text string) (int64, error) {
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid integer %q", ErrRedis, text)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,229 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a Redis server that runs the rate limit script in Go, and records the commands it receives.
+type fakeRedis struct {
+	listener net.Listener
+	fail     bool
+
+	mutex    sync.Mutex
+	commands [][]string
+	full     map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T, fail bool) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &fakeRedis{listener: listener, fail: fail, full: make(map[string]time.Time)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (server *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+
+		for idx, item := range items {
+			args[idx], _ = item.(string)
+		}
+
+		server.mutex.Lock()
+		server.commands = append(server.commands, args)
+		server.mutex.Unlock()
+
+		fmt.Fprint(conn, server.reply(args))
+	}
+}
+
+// reply will return the reply to the command, reserving a request like the rate limit script for EVAL.
+func (server *fakeRedis) reply(args []string) string {
+	if args[0] != "EVAL" {
+		return "+OK\r\n"
+	}
+
+	if server.fail {
+		return "-ERR script failed\r\n"
+	}
+
+	interval, _ := strconv.Atoi(args[4])
+	burst, _ := strconv.Atoi(args[5])
+	step := time.Duration(interval) * time.Microsecond
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	now := time.Now()
+
+	full := server.full[args[3]]
+	if full.Before(now) {
+		full = now
+	}
+
+	full = full.Add(step)
+	server.full[args[3]] = full
+
+	wait := full.Add(-time.Duration(burst) * step).Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return fmt.Sprintf(":%d\r\n", wait.Microseconds())
+}
+
+func TestRedis(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeRedis(t, false)
+
+	limiter, err := NewRedis("redis://gidari:secret@"+server.listener.Addr().String()+"/3", KeyPrefix+"api.example.com",
+		2, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+
+	t.Cleanup(limiter.Close)
+
+	start := time.Now()
+
+	for idx := 0; idx < 4; idx++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("failed to wait: %v", err)
+		}
+	}
+
+	// Two requests are allowed at once, and one for every 50ms after that.
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the requests to take at least 100ms, took %v", elapsed)
+	}
+
+	server.mutex.Lock()
+	commands := append([][]string(nil), server.commands...)
+	server.mutex.Unlock()
+
+	handshake := [][]string{{"AUTH", "gidari", "secret"}, {"SELECT", "3"}}
+	if len(commands) < 2 || !reflect.DeepEqual(commands[:2], handshake) {
+		t.Fatalf("expected the connection to be authenticated, got %v", commands)
+	}
+
+	// The connection is reused for every request.
+	if len(commands) != 6 || commands[2][3] != "gidari:ratelimit:api.example.com" {
+		t.Errorf("expected 4 reservations of the key on one connection, got %v", commands)
+	}
+
+	// A request that is cancelled while it waits returns the error of its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRedisError(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeRedis(t, true)
+
+	limiter, err := NewRedis("redis://"+server.listener.Addr().String(), "key", 1, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+
+	if err := limiter.Wait(context.Background()); !errors.Is(err, ErrRedis) {
+		t.Errorf("expected %v, got %v", ErrRedis, err)
+	}
+
+	// Requests are not made if Redis cannot be reached.
+	server.listener.Close()
+
+	limiter, err = NewRedis("redis://"+server.listener.Addr().String(), "key", 1, time.Second)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+
+	if err := limiter.Wait(context.Background()); err == nil {
+		t.Errorf("expected an error connecting to redis")
+	}
+
+	for _, rawURL := range []string{"localhost:6379", "http://localhost:6379", "redis://localhost/db"} {
+		if _, err := NewRedis(rawURL, "key", 1, time.Second); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("%q: expected %v, got %v", rawURL, ErrInvalidURL, err)
+		}
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		reply    string
+		expected interface{}
+		wantErr  error
+	}{
+		{reply: "+OK\r\n", expected: "OK"},
+		{reply: ":42\r\n", expected: int64(42)},
+		{reply: "$5\r\nhe\r\no\r\n", expected: "he\r\no"},
+		{reply: "$-1\r\n", expected: nil},
+		{reply: "*2\r\n:1\r\n$1\r\na\r\n", expected: []interface{}{int64(1), "a"}},
+		{reply: "-ERR unknown command\r\n", wantErr: ErrRedis},
+		{reply: "?\r\n", wantErr: ErrRedis},
+	} {
+		reply, err := readReply(bufio.NewReader(strings.NewReader(tcase.reply)))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.reply, tcase.wantErr, err)
+		}
+
+		if !reflect.DeepEqual(reply, tcase.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tcase.reply, tcase.expected, reply)
+		}
+	}
+}
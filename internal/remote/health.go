@@ -0,0 +1,183 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// Paths of the health endpoints.
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// DefaultReadyTimeout is how long each readiness check may take before it fails.
+const DefaultReadyTimeout = 5 * time.Second
+
+// Statuses of the health endpoints.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// Check is a dependency of the service, e.g. a storage device or a web API, that must be reachable for the service
+// to be ready.
+type Check struct {
+	// Name identifies the dependency in the response of the readiness endpoint.
+	Name string
+
+	// Fn returns an error if the dependency cannot be reached.
+	Fn func(context.Context) error
+}
+
+// StorageCheck will return a check that pings the storage device.
+func StorageCheck(name string, stg proto.Storage) Check {
+	return Check{Name: name, Fn: func(ctx context.Context) error {
+		// Storage devices are pinged without a context, so the ping is abandoned if it takes too long.
+		errs := make(chan error, 1)
+		go func() { errs <- stg.Ping() }()
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("ping timed out: %w", ctx.Err())
+		}
+	}}
+}
+
+// UpstreamCheck will return a check that the web API at the URL can be reached. Any HTTP response, including an error
+// status, means that the web API is reachable. The check is named by the URL, without its password.
+func UpstreamCheck(upstream string) Check {
+	name := upstream
+	if uri, err := url.Parse(upstream); err == nil {
+		name = uri.Redacted()
+	}
+
+	return Check{Name: name, Fn: func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, upstream, nil)
+		if err != nil {
+			return fmt.Errorf("invalid upstream: %w", err)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("upstream unreachable: %w", err)
+		}
+
+		return rsp.Body.Close()
+	}}
+}
+
+// Health is the response of the health endpoints.
+type Health struct {
+	Status string `json:"status"`
+
+	// Checks are the results of the readiness checks, keyed by their name.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthServer serves the liveness and readiness endpoints of service mode, e.g. for Kubernetes probes. The endpoints
+// are not authorized, so that probes do not need the token of the service.
+//
+//	GET /healthz  returns "200 OK" while the process is serving
+//	GET /readyz   returns "200 OK" if every check passes, or "503 Service Unavailable" with the failed checks
+type HealthServer struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewHealthServer will return a server whose readiness endpoint runs the checks, each with the timeout.
+func NewHealthServer(timeout time.Duration, checks ...Check) *HealthServer {
+	return &HealthServer{checks: checks, timeout: timeout}
+}
+
+// ServeHTTP will route the request to the health endpoints.
+func (server *HealthServer) ServeHTTP(wtr http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		writeError(wtr, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", req.Method))
+
+		return
+	}
+
+	switch req.URL.Path {
+	case healthzPath:
+		writeJSON(wtr, http.StatusOK, &Health{Status: HealthOK})
+	case readyzPath:
+		health := server.ready(req.Context())
+
+		code := http.StatusOK
+		if health.Status != HealthOK {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(wtr, code, health)
+	default:
+		writeError(wtr, http.StatusNotFound, fmt.Sprintf("%s not found", req.URL.Path))
+	}
+}
+
+// ready will run every check concurrently and return their results.
+func (server *HealthServer) ready(ctx context.Context) *Health {
+	health := &Health{Status: HealthOK, Checks: make(map[string]string, len(server.checks))}
+
+	var (
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	for _, check := range server.checks {
+		check := check
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, server.timeout)
+			defer cancel()
+
+			result := HealthOK
+			if err := check.Fn(checkCtx); err != nil {
+				result = err.Error()
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			health.Checks[check.Name] = result
+			if result != HealthOK {
+				health.Status = HealthUnavailable
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return health
+}
+
+// ShutdownCheck will return a check that fails once the context is done, so that the service is taken out of load
+// balancing while it drains.
+func ShutdownCheck(ctx context.Context) Check {
+	return Check{Name: "shutdown", Fn: func(context.Context) error {
+		if ctx.Err() != nil {
+			return ErrShuttingDown
+		}
+
+		return nil
+	}}
+}
@@ -0,0 +1,631 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/google/uuid"
+)
+
+// Statuses of a run submitted over HTTP.
+const (
+	RunPending   = "pending"
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// DefaultRunWorkers is the default number of runs that are run at the same time.
+const DefaultRunWorkers = 4
+
+// runsPath is the path of the runs resource of the HTTP API.
+const runsPath = "/runs"
+
+// Actions on a run of the HTTP API.
+const (
+	cancelAction = "cancel"
+	retryAction  = "retry"
+)
+
+// maxConfigSize is the maximum size of a transport configuration sent over HTTP.
+const maxConfigSize = 1 << 20
+
+// ErrShuttingDown is returned when runs are submitted to a server that is shutting down.
+var ErrShuttingDown = fmt.Errorf("server is shutting down")
+
+// Run is the status of a run submitted over HTTP.
+type Run struct {
+	ID          string     `json:"id"`
+	Tenant      string     `json:"tenant,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	SubmittedAt time.Time  `json:"submittedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+
+	// RetryOf is the ID of the run that this run retries.
+	RetryOf string `json:"retryOf,omitempty"`
+
+	// Requests is the progress of the requests of the run that have been committed or rolled back.
+	Requests []config.RequestProgress `json:"requests,omitempty"`
+}
+
+// finished will return true if the run will not change status again.
+func (run *Run) finished() bool {
+	return run.Status == RunSucceeded || run.Status == RunFailed || run.Status == RunCancelled
+}
+
+// HTTPServer runs transport configurations sent over HTTP, so that orchestrators can trigger and monitor runs
+// without shelling out. Submitted runs are queued, and run in the background by the workers of the server. Runs are
+// kept in memory for the life of the server, unless it has a store to persist them to.
+//
+//	POST /runs              queues a run of the YAML or JSON configuration in the body, returning "202 Accepted"
+//	GET  /runs              lists the runs, optionally filtered by their "status"
+//	GET  /runs/{id}         returns the status of the run and the progress of its requests
+//	POST /runs/{id}/cancel  cancels the run if it has not finished
+//	POST /runs/{id}/retry   queues a new run of the configuration of a failed or cancelled run
+//	GET  /status            returns the live status of the runs, see "Status"
+//
+// If the server has tenants, each tenant can only see its own runs.
+type HTTPServer struct {
+	runFn   RunFunc
+	token   string
+	tenants *Tenants
+	store   RunStore
+
+	mutex   sync.Mutex
+	runs    map[string]*StoredRun
+	cancels map[string]context.CancelFunc
+
+	// queue are the IDs of the pending runs in the order they were submitted, and wake signals the workers that a
+	// run has been queued.
+	queue []string
+	wake  chan struct{}
+
+	// draining is set by "Shutdown", which closes stop so that the workers do not start any more runs. The workers
+	// are interrupted by stopWork if they do not finish their runs in time.
+	draining bool
+	stop     chan struct{}
+	stopWork context.CancelFunc
+	workers  sync.WaitGroup
+
+	// live is the progress of the running runs, the status of their tables, and their recent errors.
+	live *liveStatus
+}
+
+// NewHTTPServer will return a server that runs transport configurations with "runFn". If the token is not empty,
+// requests must be authorized with it as a bearer token. Runs are queued until the workers of the server are started.
+func NewHTTPServer(runFn RunFunc, token string) *HTTPServer {
+	return &HTTPServer{
+		runFn:   runFn,
+		token:   token,
+		runs:    make(map[string]*StoredRun),
+		cancels: make(map[string]context.CancelFunc),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		live:    newLiveStatus(),
+	}
+}
+
+// WithTenants will authorize requests with the tokens of the tenants instead of the token of the server, and scope the
+// configuration of each request to its tenant.
+func (server *HTTPServer) WithTenants(tenants *Tenants) *HTTPServer {
+	server.tenants = tenants
+
+	return server
+}
+
+// Restore will persist the runs of the server to the store, and load the runs that are already in it. Runs that had
+// not finished, e.g. because the service was restarted, are queued again from the start.
+func (server *HTTPServer) Restore(store RunStore) error {
+	runs, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].SubmittedAt.Before(runs[j].SubmittedAt) })
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.store = store
+
+	for _, run := range runs {
+		server.runs[run.ID] = run
+
+		if run.finished() {
+			continue
+		}
+
+		run.Status = RunPending
+		run.StartedAt = nil
+		run.Requests = nil
+
+		if err := server.save(run); err != nil {
+			return err
+		}
+
+		server.enqueue(run.ID)
+	}
+
+	return nil
+}
+
+// Start will start the workers that run the queued runs, until the context is done. Runs that are interrupted when
+// the context is done are not finished, so that they are queued again when a store is restored.
+func (server *HTTPServer) Start(ctx context.Context, workers int) {
+	ctx, stopWork := context.WithCancel(ctx)
+
+	server.mutex.Lock()
+	server.stopWork = stopWork
+	server.mutex.Unlock()
+
+	for id := 0; id < workers; id++ {
+		server.workers.Add(1)
+
+		go func() {
+			defer server.workers.Done()
+
+			server.work(ctx)
+		}()
+	}
+}
+
+// Shutdown will stop the server from accepting and starting runs, and wait for the runs that are running to finish,
+// so that their writes are committed instead of abandoned. Queued runs are left pending, so that they are run when a
+// store is restored. If the context is done before the runs finish, they are interrupted and left unfinished, to be
+// queued again from the start, and the error of the context is returned.
+func (server *HTTPServer) Shutdown(ctx context.Context) error {
+	server.mutex.Lock()
+
+	if !server.draining {
+		server.draining = true
+		close(server.stop)
+	}
+
+	stopWork := server.stopWork
+	server.mutex.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		server.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		if stopWork != nil {
+			stopWork()
+		}
+
+		<-drained
+
+		return fmt.Errorf("runs interrupted: %w", ctx.Err())
+	}
+}
+
+// ServeHTTP will route the request to the runs resource.
+func (server *HTTPServer) ServeHTTP(wtr http.ResponseWriter, req *http.Request) {
+	tenant, ok := server.authorize(req)
+	if !ok {
+		writeError(wtr, http.StatusUnauthorized, "invalid bearer token")
+
+		return
+	}
+
+	if req.URL.Path == statusPath {
+		if req.Method != http.MethodGet {
+			writeError(wtr, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", req.Method))
+
+			return
+		}
+
+		server.writeStatus(wtr, tenant)
+
+		return
+	}
+
+	if req.URL.Path == runsPath {
+		switch req.Method {
+		case http.MethodPost:
+			server.submitRun(wtr, req, tenant)
+		case http.MethodGet:
+			server.listRuns(wtr, req.URL.Query().Get("status"), tenant)
+		default:
+			writeError(wtr, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", req.Method))
+		}
+
+		return
+	}
+
+	if !strings.HasPrefix(req.URL.Path, runsPath+"/") {
+		writeError(wtr, http.StatusNotFound, fmt.Sprintf("%s not found", req.URL.Path))
+
+		return
+	}
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, runsPath+"/"), "/")
+
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		server.getRun(wtr, id, tenant)
+	case (action == cancelAction || action == retryAction) && req.Method == http.MethodPost:
+		server.actOnRun(wtr, id, action, tenant)
+	case action == "" || action == cancelAction || action == retryAction:
+		writeError(wtr, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", req.Method))
+	default:
+		writeError(wtr, http.StatusNotFound, fmt.Sprintf("%s not found", req.URL.Path))
+	}
+}
+
+// authorize will return true if the token of the server is empty or the request has it as a bearer token. If the
+// server has tenants, the tenant whose token the request has is returned.
+func (server *HTTPServer) authorize(req *http.Request) (*Tenant, bool) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	if server.tenants != nil {
+		return server.tenants.authorize(token)
+	}
+
+	if server.token == "" {
+		return nil, true
+	}
+
+	return nil, subtle.ConstantTimeCompare([]byte(token), []byte(server.token)) == 1
+}
+
+// submitRun will validate the configuration in the body of the request, scoped to the tenant if there is one, and
+// queue a run of it.
+func (server *HTTPServer) submitRun(wtr http.ResponseWriter, req *http.Request, tenant *Tenant) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxConfigSize))
+	if err != nil {
+		writeError(wtr, http.StatusBadRequest, fmt.Sprintf("unable to read configuration: %v", err))
+
+		return
+	}
+
+	cfg, err := config.ParseRemote(req.Context(), body)
+	if errors.Is(err, config.ErrLocalOnly) {
+		writeError(wtr, http.StatusForbidden, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		writeError(wtr, http.StatusBadRequest, fmt.Sprintf("invalid configuration: %v", err))
+
+		return
+	}
+
+	run := &StoredRun{Run: Run{ID: uuid.New().String(), Status: RunPending}, Config: body}
+
+	if tenant != nil {
+		if err := tenant.apply(cfg); err != nil {
+			writeError(wtr, http.StatusForbidden, err.Error())
+
+			return
+		}
+
+		run.Tenant = tenant.Name
+	}
+
+	server.queueRun(wtr, run)
+}
+
+// queueRun will add the run to the queue, and write its status.
+func (server *HTTPServer) queueRun(wtr http.ResponseWriter, run *StoredRun) {
+	run.SubmittedAt = time.Now().UTC()
+
+	server.mutex.Lock()
+
+	if server.draining {
+		server.mutex.Unlock()
+		writeError(wtr, http.StatusServiceUnavailable, ErrShuttingDown.Error())
+
+		return
+	}
+
+	if err := server.save(run); err != nil {
+		server.mutex.Unlock()
+		writeError(wtr, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	server.runs[run.ID] = run
+	server.enqueue(run.ID)
+	status := run.Run
+	server.mutex.Unlock()
+
+	wtr.Header().Set("Location", runsPath+"/"+run.ID)
+	writeJSON(wtr, http.StatusAccepted, &status)
+}
+
+// enqueue will add the run to the queue and wake a worker. The caller must hold the mutex.
+func (server *HTTPServer) enqueue(id string) {
+	server.queue = append(server.queue, id)
+
+	select {
+	case server.wake <- struct{}{}:
+	default:
+	}
+}
+
+// save will persist the run if the server has a store. The caller must hold the mutex.
+func (server *HTTPServer) save(run *StoredRun) error {
+	if server.store == nil {
+		return nil
+	}
+
+	if err := server.store.Save(run); err != nil {
+		return fmt.Errorf("unable to save run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+// work will run the queued runs one at a time until the context is done or the server is shutting down.
+func (server *HTTPServer) work(ctx context.Context) {
+	for {
+		server.mutex.Lock()
+
+		if server.draining {
+			server.mutex.Unlock()
+
+			return
+		}
+
+		if len(server.queue) == 0 {
+			server.mutex.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-server.stop:
+				return
+			case <-server.wake:
+				continue
+			}
+		}
+
+		id := server.queue[0]
+		server.queue = server.queue[1:]
+
+		// Wake another worker if there are more runs in the queue.
+		if len(server.queue) > 0 {
+			select {
+			case server.wake <- struct{}{}:
+			default:
+			}
+		}
+
+		server.mutex.Unlock()
+
+		server.run(ctx, id)
+	}
+}
+
+// run will run the queued run with the ID, recording the progress of its requests and its outcome. Runs that were
+// cancelled before they started are skipped, and runs are not started once the server is stopping.
+func (server *HTTPServer) run(ctx context.Context, id string) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server.mutex.Lock()
+
+	run := server.runs[id]
+	if run.Status != RunPending || ctx.Err() != nil {
+		server.mutex.Unlock()
+
+		return
+	}
+
+	startedAt := time.Now().UTC()
+	run.Status = RunRunning
+	run.StartedAt = &startedAt
+	server.cancels[id] = cancel
+	_ = server.save(run)
+
+	server.mutex.Unlock()
+
+	err := server.runConfig(runCtx, run)
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	delete(server.cancels, id)
+	delete(server.live.running, id)
+
+	// The run is left unfinished if the server is stopping, so that it is queued again when it is restored.
+	if ctx.Err() != nil {
+		return
+	}
+
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+
+	switch {
+	case runCtx.Err() != nil:
+		run.Status = RunCancelled
+	case err != nil:
+		run.Status = RunFailed
+		run.Error = err.Error()
+	default:
+		run.Status = RunSucceeded
+	}
+
+	server.recordRun(run)
+	_ = server.save(run)
+}
+
+// runConfig will parse the configuration of the run, scope it to the tenant of the run, and run it.
+func (server *HTTPServer) runConfig(ctx context.Context, run *StoredRun) error {
+	cfg, err := config.ParseRemote(ctx, run.Config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if server.tenants != nil {
+		tenant, ok := server.tenants.tenant(run.Tenant)
+		if !ok {
+			return fmt.Errorf("%w: unknown tenant %q", ErrInvalidTenant, run.Tenant)
+		}
+
+		if err := tenant.apply(cfg); err != nil {
+			return err
+		}
+	}
+
+	server.watch(cfg, run)
+
+	cfg.Progress = func(progress config.RequestProgress) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		run.Requests = append(run.Requests, progress)
+		server.recordRequest(run, progress)
+		_ = server.save(run)
+	}
+
+	return server.runFn(ctx, cfg)
+}
+
+// lookup will return the run with the ID. The runs of other tenants are not found. The caller must hold the mutex.
+func (server *HTTPServer) lookup(id string, tenant *Tenant) (*StoredRun, bool) {
+	run, ok := server.runs[id]
+	if ok && tenant != nil && run.Tenant != tenant.Name {
+		return nil, false
+	}
+
+	return run, ok
+}
+
+// getRun will write the status of the run with the ID.
+func (server *HTTPServer) getRun(wtr http.ResponseWriter, id string, tenant *Tenant) {
+	server.mutex.Lock()
+
+	run, ok := server.lookup(id, tenant)
+	if !ok {
+		server.mutex.Unlock()
+		writeError(wtr, http.StatusNotFound, fmt.Sprintf("run %q not found", id))
+
+		return
+	}
+
+	status := run.Run
+	server.mutex.Unlock()
+
+	writeJSON(wtr, http.StatusOK, &status)
+}
+
+// listRuns will write the status of every run of the tenant in the order they were submitted, optionally only the
+// runs with the status.
+func (server *HTTPServer) listRuns(wtr http.ResponseWriter, status string, tenant *Tenant) {
+	server.mutex.Lock()
+
+	runs := make([]Run, 0, len(server.runs))
+
+	for id := range server.runs {
+		run, ok := server.lookup(id, tenant)
+		if ok && (status == "" || run.Status == status) {
+			runs = append(runs, run.Run)
+		}
+	}
+
+	server.mutex.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].SubmittedAt.Before(runs[j].SubmittedAt) })
+
+	writeJSON(wtr, http.StatusOK, runs)
+}
+
+// actOnRun will cancel or retry the run with the ID.
+func (server *HTTPServer) actOnRun(wtr http.ResponseWriter, id, action string, tenant *Tenant) {
+	server.mutex.Lock()
+
+	run, ok := server.lookup(id, tenant)
+	if !ok {
+		server.mutex.Unlock()
+		writeError(wtr, http.StatusNotFound, fmt.Sprintf("run %q not found", id))
+
+		return
+	}
+
+	if action == retryAction {
+		retryable := run.Status == RunFailed || run.Status == RunCancelled
+		retry := &StoredRun{
+			Run:    Run{ID: uuid.New().String(), Tenant: run.Tenant, Status: RunPending, RetryOf: run.ID},
+			Config: run.Config,
+		}
+		server.mutex.Unlock()
+
+		if !retryable {
+			writeError(wtr, http.StatusConflict, fmt.Sprintf("run %q is %s", id, run.Status))
+
+			return
+		}
+
+		server.queueRun(wtr, retry)
+
+		return
+	}
+
+	defer server.mutex.Unlock()
+
+	switch run.Status {
+	case RunPending:
+		finishedAt := time.Now().UTC()
+		run.Status = RunCancelled
+		run.FinishedAt = &finishedAt
+
+		if err := server.save(run); err != nil {
+			writeError(wtr, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+	case RunRunning:
+		// The run is recorded as cancelled once it has stopped.
+		server.cancels[id]()
+	default:
+		writeError(wtr, http.StatusConflict, fmt.Sprintf("run %q is %s", id, run.Status))
+
+		return
+	}
+
+	status := run.Run
+	writeJSON(wtr, http.StatusAccepted, &status)
+}
+
+// writeJSON will write the value as the JSON body of the response.
+func writeJSON(wtr http.ResponseWriter, code int, val interface{}) {
+	wtr.Header().Set("Content-Type", "application/json")
+	wtr.WriteHeader(code)
+
+	_ = json.NewEncoder(wtr).Encode(val)
+}
+
+// writeError will write the message as the JSON error body of the response.
+func writeError(wtr http.ResponseWriter, code int, msg string) {
+	writeJSON(wtr, code, map[string]string{"error": msg})
+}
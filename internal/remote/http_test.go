@@ -0,0 +1,383 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// doHTTP will send the request to the server, decoding the body of the response into "val".
+func doHTTP(t *testing.T, server *httptest.Server, method, path, token, body string, val interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rsp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer rsp.Body.Close()
+
+	_ = json.NewDecoder(rsp.Body).Decode(val)
+
+	return rsp
+}
+
+// awaitRun will poll the run until it has finished.
+func awaitRun(t *testing.T, server *httptest.Server, id string) *Run {
+	t.Helper()
+
+	for {
+		run := new(Run)
+		doHTTP(t, server, http.MethodGet, runsPath+"/"+id, "secret", "", run)
+
+		if run.Status != RunPending && run.Status != RunRunning {
+			return run
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	t.Parallel()
+
+	// release holds the runs until the test has checked that they are queued.
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	httpServer := NewHTTPServer(func(_ context.Context, cfg *config.Config) error {
+		<-release
+
+		if cfg.Requests[0].Table == "fail" {
+			return fmt.Errorf("web API unavailable")
+		}
+
+		cfg.Progress(config.RequestProgress{Table: cfg.Requests[0].Table, Status: config.RequestCommitted})
+
+		return nil
+	}, "secret")
+	httpServer.Start(ctx, 2)
+
+	server := httptest.NewServer(httpServer)
+	t.Cleanup(server.Close)
+
+	do := func(method, path, token, body string) (*http.Response, *Run) {
+		t.Helper()
+
+		run := new(Run)
+
+		return doHTTP(t, server, method, path, token, body, run), run
+	}
+
+	rsp, succeeded := do(http.MethodPost, runsPath, "secret", testConfig)
+	if rsp.StatusCode != http.StatusAccepted || succeeded.Status != RunPending {
+		t.Fatalf("expected a pending run, got %d %+v", rsp.StatusCode, succeeded)
+	}
+
+	if location := rsp.Header.Get("Location"); location != runsPath+"/"+succeeded.ID {
+		t.Errorf("unexpected location %q", location)
+	}
+
+	_, failed := do(http.MethodPost, runsPath, "secret", testConfig+"    table: fail\n")
+
+	close(release)
+
+	run := awaitRun(t, server, succeeded.ID)
+	if run.Status != RunSucceeded || run.StartedAt == nil || run.FinishedAt == nil {
+		t.Errorf("expected the run to succeed, got %+v", run)
+	}
+
+	if len(run.Requests) != 1 || run.Requests[0].Status != config.RequestCommitted {
+		t.Errorf("expected the progress of the request, got %+v", run.Requests)
+	}
+
+	if run := awaitRun(t, server, failed.ID); run.Status != RunFailed || run.Error != "web API unavailable" {
+		t.Errorf("expected the run to fail, got %+v", run)
+	}
+
+	for _, tcase := range []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		code   int
+	}{
+		{name: "unauthorized", method: http.MethodGet, path: runsPath + "/" + succeeded.ID, code: http.StatusUnauthorized},
+		{name: "invalid", method: http.MethodPost, path: runsPath, token: "secret", body: "url: x", code: http.StatusBadRequest},
+		{name: "command", method: http.MethodPost, path: runsPath, token: "secret", body: testConfig + "    command:\n      path: sh\n", code: http.StatusForbidden},
+		{name: "checkpoint", method: http.MethodPost, path: runsPath, token: "secret", body: testConfig + "checkpoint: /etc/passwd\n", code: http.StatusForbidden},
+		{name: "dead letter dir", method: http.MethodPost, path: runsPath, token: "secret", body: testConfig + "deadLetter:\n  dir: /etc\n", code: http.StatusForbidden},
+		{name: "queue dir", method: http.MethodPost, path: runsPath, token: "secret", body: testConfig + "queueDir: /etc\n", code: http.StatusForbidden},
+		{name: "spill dir", method: http.MethodPost, path: runsPath, token: "secret", body: testConfig + "spillDir: /etc\n", code: http.StatusForbidden},
+		{name: "unknown run", method: http.MethodGet, path: runsPath + "/unknown", token: "secret", code: http.StatusNotFound},
+		{name: "method", method: http.MethodDelete, path: runsPath, token: "secret", code: http.StatusMethodNotAllowed},
+		{name: "action method", method: http.MethodGet, path: runsPath + "/" + failed.ID + "/retry", token: "secret", code: http.StatusMethodNotAllowed},
+		{name: "unknown action", method: http.MethodPost, path: runsPath + "/" + failed.ID + "/pause", token: "secret", code: http.StatusNotFound},
+		{name: "path", method: http.MethodGet, path: "/", token: "secret", code: http.StatusNotFound},
+	} {
+		if rsp, _ := do(tcase.method, tcase.path, tcase.token, tcase.body); rsp.StatusCode != tcase.code {
+			t.Errorf("%s: expected %d, got %d", tcase.name, tcase.code, rsp.StatusCode)
+		}
+	}
+}
+
+func TestHTTPServerQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// started receives the table of each run once it is running, and the run blocks until it is cancelled.
+	started := make(chan string, 2)
+
+	httpServer := NewHTTPServer(func(ctx context.Context, cfg *config.Config) error {
+		started <- cfg.Requests[0].Table
+		<-ctx.Done()
+
+		return ctx.Err()
+	}, "secret")
+	httpServer.Start(ctx, 1)
+
+	server := httptest.NewServer(httpServer)
+	t.Cleanup(server.Close)
+
+	running, pending := new(Run), new(Run)
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig, running)
+	<-started
+
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig+"    table: pending\n", pending)
+
+	var runs []Run
+	doHTTP(t, server, http.MethodGet, runsPath+"?status="+RunPending, "secret", "", &runs)
+
+	if len(runs) != 1 || runs[0].ID != pending.ID {
+		t.Fatalf("expected only the pending run, got %+v", runs)
+	}
+
+	t.Run("cancel pending", func(t *testing.T) {
+		run := new(Run)
+
+		rsp := doHTTP(t, server, http.MethodPost, runsPath+"/"+pending.ID+"/cancel", "secret", "", run)
+		if rsp.StatusCode != http.StatusAccepted || run.Status != RunCancelled {
+			t.Fatalf("expected the run to be cancelled, got %d %+v", rsp.StatusCode, run)
+		}
+	})
+
+	t.Run("cancel running", func(t *testing.T) {
+		rsp := doHTTP(t, server, http.MethodPost, runsPath+"/"+running.ID+"/cancel", "secret", "", new(Run))
+		if rsp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected the run to be cancelled, got %d", rsp.StatusCode)
+		}
+
+		if run := awaitRun(t, server, running.ID); run.Status != RunCancelled {
+			t.Fatalf("expected the run to be cancelled, got %+v", run)
+		}
+
+		rsp = doHTTP(t, server, http.MethodPost, runsPath+"/"+running.ID+"/cancel", "secret", "", new(Run))
+		if rsp.StatusCode != http.StatusConflict {
+			t.Errorf("expected a finished run to conflict, got %d", rsp.StatusCode)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		retry := new(Run)
+
+		rsp := doHTTP(t, server, http.MethodPost, runsPath+"/"+pending.ID+"/retry", "secret", "", retry)
+		if rsp.StatusCode != http.StatusAccepted || retry.RetryOf != pending.ID || retry.ID == pending.ID {
+			t.Fatalf("expected a new run retrying the cancelled run, got %d %+v", rsp.StatusCode, retry)
+		}
+
+		if table := <-started; table != "pending" {
+			t.Errorf("expected the configuration of the cancelled run, got table %q", table)
+		}
+
+		rsp = doHTTP(t, server, http.MethodPost, runsPath+"/"+retry.ID+"/retry", "secret", "", new(Run))
+		if rsp.StatusCode != http.StatusConflict {
+			t.Errorf("expected a running run to conflict, got %d", rsp.StatusCode)
+		}
+	})
+}
+
+func TestHTTPServerRestore(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewDirRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	// The first server is stopped while its run is running, and never starts the run that is queued after it. Neither
+	// run is recorded again once the server has stopped.
+	ctx, stop := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	first := NewHTTPServer(func(ctx context.Context, _ *config.Config) error {
+		close(started)
+		<-ctx.Done()
+
+		return ctx.Err()
+	}, "secret")
+
+	if err := first.Restore(store); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	first.Start(ctx, 1)
+
+	firstServer := httptest.NewServer(first)
+	t.Cleanup(firstServer.Close)
+
+	interrupted, queued := new(Run), new(Run)
+	doHTTP(t, firstServer, http.MethodPost, runsPath, "secret", testConfig, interrupted)
+	<-started
+
+	doHTTP(t, firstServer, http.MethodPost, runsPath, "secret", testConfig, queued)
+	stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	second := NewHTTPServer(func(context.Context, *config.Config) error { return nil }, "secret")
+
+	if err := second.Restore(store); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	second.Start(ctx, 1)
+
+	secondServer := httptest.NewServer(second)
+	t.Cleanup(secondServer.Close)
+
+	for _, id := range []string{interrupted.ID, queued.ID} {
+		if run := awaitRun(t, secondServer, id); run.Status != RunSucceeded {
+			t.Errorf("expected the restored run to succeed, got %+v", run)
+		}
+	}
+}
+
+func TestHTTPServerShutdown(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewDirRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+
+	httpServer := NewHTTPServer(func(context.Context, *config.Config) error {
+		close(started)
+		<-release
+
+		return nil
+	}, "secret")
+
+	if err := httpServer.Restore(store); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	httpServer.Start(context.Background(), 1)
+
+	server := httptest.NewServer(httpServer)
+	t.Cleanup(server.Close)
+
+	running, queued := new(Run), new(Run)
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig, running)
+	<-started
+
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig, queued)
+
+	// The running run is drained once it is released, and the queued run is never started.
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- httpServer.Shutdown(context.Background()) }()
+
+	for {
+		rsp := doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig, new(Run))
+		if rsp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+
+	if err := <-shutdown; err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+
+	statuses := map[string]string{running.ID: RunSucceeded, queued.ID: RunPending}
+
+	runs, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load runs: %v", err)
+	}
+
+	for _, run := range runs {
+		if expected, ok := statuses[run.ID]; ok && run.Status != expected {
+			t.Errorf("expected run %s to be %s, got %s", run.ID, expected, run.Status)
+		}
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+
+		httpServer := NewHTTPServer(func(ctx context.Context, _ *config.Config) error {
+			close(started)
+			<-ctx.Done()
+
+			return ctx.Err()
+		}, "secret")
+		httpServer.Start(context.Background(), 1)
+
+		server := httptest.NewServer(httpServer)
+		t.Cleanup(server.Close)
+
+		interrupted := new(Run)
+		doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig, interrupted)
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := httpServer.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		// The interrupted run is left unfinished, so that it is queued again when a store is restored.
+		run := new(Run)
+		doHTTP(t, server, http.MethodGet, runsPath+"/"+interrupted.ID, "secret", "", run)
+
+		if run.Status != RunRunning {
+			t.Errorf("expected the run to be left running, got %+v", run)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IngestServiceName is the full name of the gRPC service that writes streamed records to the destinations of a
+// configuration, which is equivalent to the following definition using the messages in "db.proto":
+//
+//	service Ingest {
+//		rpc Upsert(stream UpsertRequest) returns (UpsertResponse);
+//	}
+//
+// Each request of the stream is a batch of records for a table. The batches are committed together once the client
+// closes the stream, and the response has the totals of the upserts on every destination.
+const IngestServiceName = "proto.Ingest"
+
+const ingestUpsertMethod = "/" + IngestServiceName + "/Upsert"
+
+// StreamFunc writes the batches of records received from "recv", until it returns "io.EOF".
+type StreamFunc func(ctx context.Context, recv func() (*proto.UpsertRequest, error)) (*proto.UpsertResponse, error)
+
+// ingestServiceDesc describes the ingest service for registration on a gRPC server.
+var ingestServiceDesc = grpc.ServiceDesc{
+	ServiceName: IngestServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Upsert",
+			Handler:       ingestUpsertHandler,
+			ClientStreams: true,
+		},
+	},
+}
+
+// ingestUpsertHandler is the client-streaming gRPC handler that writes the batches of the stream.
+func ingestUpsertHandler(srv interface{}, stream grpc.ServerStream) error {
+	server, _ := srv.(*IngestServer)
+	if err := authorizeToken(stream.Context(), server.token); err != nil {
+		return err
+	}
+
+	recv := func() (*proto.UpsertRequest, error) {
+		req := new(proto.UpsertRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return nil, err
+		}
+
+		return req, nil
+	}
+
+	rsp, err := server.streamFn(stream.Context(), recv)
+	if err != nil {
+		return status.Errorf(codes.Aborted, "stream rolled back: %v", err)
+	}
+
+	return stream.SendMsg(rsp)
+}
+
+// IngestServer writes records streamed over gRPC to the destinations of a configuration, so that producers other than
+// web APIs can reuse the storage of gidari.
+type IngestServer struct {
+	streamFn StreamFunc
+	token    string
+}
+
+// NewIngestServer will return a server that writes streams of records with "streamFn". If the token is not empty,
+// requests must be authorized with it as a bearer token.
+func NewIngestServer(streamFn StreamFunc, token string) *IngestServer {
+	return &IngestServer{streamFn: streamFn, token: token}
+}
+
+// Register will register the ingest service on the gRPC server.
+func (server *IngestServer) Register(grpcServer *grpc.Server) {
+	grpcServer.RegisterService(&ingestServiceDesc, server)
+}
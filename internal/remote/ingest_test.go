@@ -0,0 +1,121 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestIngestServer(t *testing.T) {
+	t.Parallel()
+
+	lis := bufconn.Listen(1 << 20)
+
+	grpcServer := grpc.NewServer()
+	NewIngestServer(func(_ context.Context, recv func() (*proto.UpsertRequest, error)) (*proto.UpsertResponse, error) {
+		rsp := &proto.UpsertResponse{}
+
+		for {
+			req, err := recv()
+			if errors.Is(err, io.EOF) {
+				return rsp, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if req.GetTable() == "" {
+				return nil, fmt.Errorf("missing table")
+			}
+
+			rsp.ReceivedCount++
+		}
+	}, "secret").Register(grpcServer)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_ = grpcServer.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		wg.Wait()
+	})
+
+	// upsert will stream the requests and return the response.
+	upsert := func(token string, reqs ...*proto.UpsertRequest) (*proto.UpsertResponse, error) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+token)
+
+		stream, err := conn.NewStream(ctx, &ingestServiceDesc.Streams[0], ingestUpsertMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, req := range reqs {
+			if err := stream.SendMsg(req); err != nil {
+				break
+			}
+		}
+
+		if err := stream.CloseSend(); err != nil {
+			return nil, err
+		}
+
+		rsp := new(proto.UpsertResponse)
+		if err := stream.RecvMsg(rsp); err != nil {
+			return nil, err
+		}
+
+		return rsp, nil
+	}
+
+	candles := &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":1}]`)}
+
+	rsp, err := upsert("secret", candles, candles)
+	if err != nil || rsp.GetReceivedCount() != 2 {
+		t.Fatalf("expected 2 batches to be received, got %v: %v", rsp, err)
+	}
+
+	if _, err := upsert("wrong", candles); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected unauthenticated error, got %v", err)
+	}
+
+	if _, err := upsert("secret", candles, &proto.UpsertRequest{}); status.Code(err) != codes.Aborted {
+		t.Errorf("expected aborted error, got %v", err)
+	}
+}
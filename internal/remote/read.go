@@ -0,0 +1,112 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrInvalidReadRequest = fmt.Errorf("invalid read request")
+
+// descendingOption is the option of read requests that reads the records in the descending order of the range column.
+const descendingOption = "descending"
+
+// read will read the records of a table on the storage device that match the request.
+func read(ctx context.Context, stg proto.Storage, req *proto.ReadRequest) (*proto.ReadResponse, error) {
+	queryReq, err := queryRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	rsp, err := proto.Query(ctx, stg, queryReq)
+	if errors.Is(err, proto.ErrQueryNotSupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.ReadResponse{Records: rsp.Records}, nil
+}
+
+// listColumns will list the columns of every table on the storage device.
+func listColumns(ctx context.Context, stg proto.Storage) (*proto.ListColumnsResponse, error) {
+	rsp, err := proto.ListColumns(ctx, stg)
+	if errors.Is(err, proto.ErrListColumnsNotSupported) {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	}
+
+	return rsp, err
+}
+
+// queryRequest will convert the read request into a query of the storage device. The "required" fields of the read
+// request are the values that records must match.
+func queryRequest(req *proto.ReadRequest) (*proto.QueryRequest, error) {
+	if req.GetTable() == "" {
+		return nil, fmt.Errorf("%w: table is required", ErrInvalidReadRequest)
+	}
+
+	if req.GetLimit() < 0 {
+		return nil, fmt.Errorf("%w: limit %d", ErrInvalidReadRequest, req.GetLimit())
+	}
+
+	queryReq := &proto.QueryRequest{
+		Table:      req.GetTable(),
+		Match:      req.GetRequired().AsMap(),
+		Limit:      int(req.GetLimit()),
+		Descending: req.GetOptions().AsMap()[descendingOption] == true,
+	}
+
+	if req.GetRangeColumn() != "" {
+		if req.GetRangeStart() == nil || req.GetRangeEnd() == nil {
+			return nil, fmt.Errorf("%w: rangeStart and rangeEnd are required with rangeColumn",
+				ErrInvalidReadRequest)
+		}
+
+		queryReq.Range = &proto.TimeRange{
+			Column: req.GetRangeColumn(),
+			Start:  req.GetRangeStart().AsTime(),
+			End:    req.GetRangeEnd().AsTime(),
+		}
+	}
+
+	return queryReq, nil
+}
+
+// readRequest will convert the query into a read request for the remote service.
+func readRequest(req *proto.QueryRequest) (*proto.ReadRequest, error) {
+	required, err := structpb.NewStruct(req.Match)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode match: %w", err)
+	}
+
+	readReq := &proto.ReadRequest{Table: req.Table, Required: required, Limit: int32(req.Limit)}
+
+	if req.Descending {
+		readReq.Options = &structpb.Struct{Fields: map[string]*structpb.Value{
+			descendingOption: structpb.NewBoolValue(true),
+		}}
+	}
+
+	if req.Range != nil {
+		readReq.RangeColumn = req.Range.Column
+		readReq.RangeStart = timestamppb.New(req.Range.Start)
+		readReq.RangeEnd = timestamppb.New(req.Range.End)
+	}
+
+	return readReq, nil
+}
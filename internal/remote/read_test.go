@@ -0,0 +1,125 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// queryStorage is a storage device that reads back canned records, recording the last query.
+type queryStorage struct {
+	proto.Storage
+
+	query *proto.QueryRequest
+}
+
+func (stg *queryStorage) Query(_ context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	stg.query = req
+
+	record, _ := structpb.NewStruct(map[string]interface{}{"id": "BTC-USD", "price": 1.5})
+
+	return &proto.QueryResponse{Records: []*structpb.Struct{record}}, nil
+}
+
+func (stg *queryStorage) ListColumns(context.Context) (*proto.ListColumnsResponse, error) {
+	return &proto.ListColumnsResponse{ColSet: map[string]*proto.Columns{
+		"products": {List: []string{"id"}, Types: []string{"text"}},
+	}}, nil
+}
+
+func TestRemoteRead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("query", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		stg := &queryStorage{}
+		rmt := serveTestStorage(t, stg, "secret", "secret")
+
+		start := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+		rsp, err := rmt.Query(ctx, &proto.QueryRequest{
+			Table: "products",
+			Match: map[string]interface{}{"id": "BTC-USD"},
+			Range: &proto.TimeRange{Column: "time", Start: start, End: start.Add(time.Hour)},
+			Limit: 10,
+		})
+		if err != nil {
+			t.Fatalf("failed to query: %v", err)
+		}
+
+		if len(rsp.Records) != 1 || rsp.Records[0].AsMap()["price"] != 1.5 {
+			t.Errorf("unexpected records %v", rsp.Records)
+		}
+
+		query := stg.query
+		if query.Table != "products" || query.Match["id"] != "BTC-USD" || query.Limit != 10 ||
+			query.Range.Column != "time" || !query.Range.Start.Equal(start) ||
+			!query.Range.End.Equal(start.Add(time.Hour)) {
+			t.Errorf("unexpected query %+v", query)
+		}
+
+		columns, err := rmt.ListColumns(ctx)
+		if err != nil {
+			t.Fatalf("failed to list columns: %v", err)
+		}
+
+		if types := columns.GetColSet()["products"].GetTypes(); len(types) != 1 || types[0] != "text" {
+			t.Errorf("unexpected columns %v", columns)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		rmt := serveTestStorage(t, &queryStorage{}, "", "")
+
+		for _, req := range []*proto.ReadRequest{
+			{},
+			{Table: "products", Limit: -1},
+			{Table: "products", RangeColumn: "time"},
+		} {
+			err := rmt.invoke(context.Background(), readMethod, req, &proto.ReadResponse{})
+			if status.Code(unwrapStatus(err)) != codes.InvalidArgument {
+				t.Errorf("expected %v for %v, got %v", codes.InvalidArgument, req, err)
+			}
+		}
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		rmt, _ := newTestRemote(t, "", "")
+
+		_, err := rmt.Query(ctx, &proto.QueryRequest{Table: "products"})
+		if !errors.Is(err, proto.ErrQueryNotSupported) {
+			t.Errorf("expected %v, got %v", proto.ErrQueryNotSupported, err)
+		}
+
+		if _, err := rmt.ListColumns(ctx); !errors.Is(err, proto.ErrListColumnsNotSupported) {
+			t.Errorf("expected %v, got %v", proto.ErrListColumnsNotSupported, err)
+		}
+
+		err = rmt.invoke(ctx, readMethod, &proto.ReadRequest{Table: "products"}, &proto.ReadResponse{})
+		if status.Code(unwrapStatus(err)) != codes.Unimplemented {
+			t.Errorf("expected %v, got %v", codes.Unimplemented, err)
+		}
+	})
+}
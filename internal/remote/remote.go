@@ -0,0 +1,330 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var ErrTransactionNotFound = fmt.Errorf("transaction not found")
+
+// remoteTxType is a type alias for the remote transaction type.
+type remoteTxType uint8
+
+const (
+	basicRemoteTxID remoteTxType = iota
+)
+
+// stage holds the requests of a transaction until it is committed.
+type stage struct {
+	mutex    sync.Mutex
+	requests []func(context.Context) error
+}
+
+// Remote is a storage device that forwards requests over gRPC to a remote storage service.
+type Remote struct {
+	conn  *grpc.ClientConn
+	token string
+
+	// activeTx are the transactions that are currently active on this storage device. Requests made within the
+	// context of a transaction are held in memory until the transaction is committed.
+	activeTx sync.Map
+}
+
+// New will return a new Remote storage device. The connection string is of the form
+//
+//	grpc://host:port?token=secret&insecure=true
+//
+// Connections use TLS unless "insecure" is "true". If "token" is set, requests are authorized with it as a bearer
+// token.
+func New(ctx context.Context, dns string) (*Remote, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse grpc connection string: %w", err)
+	}
+
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if uri.Query().Get("insecure") == "true" {
+		creds = insecure.NewCredentials()
+	}
+
+	conn, err := grpc.DialContext(ctx, uri.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial %s: %w", uri.Host, err)
+	}
+
+	return &Remote{conn: conn, token: uri.Query().Get("token"), activeTx: sync.Map{}}, nil
+}
+
+// Close will close the connection to the remote service.
+func (rmt *Remote) Close() { rmt.conn.Close() }
+
+// IsNoSQL returns "true" since the remote storage device is unknown.
+func (rmt *Remote) IsNoSQL() bool { return true }
+
+// Type returns the type of storage.
+func (rmt *Remote) Type() uint8 { return proto.GRPCType }
+
+// invoke will call the method on the remote service, adding the bearer token if there is one.
+func (rmt *Remote) invoke(ctx context.Context, method string, req, rsp interface{}) error {
+	if rmt.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+rmt.token)
+	}
+
+	if err := rmt.conn.Invoke(ctx, method, req, rsp); err != nil {
+		return fmt.Errorf("failed to invoke %s: %w", method, err)
+	}
+
+	return nil
+}
+
+// Ping will return an error if the remote service cannot be reached.
+func (rmt *Remote) Ping() error {
+	if err := rmt.invoke(context.Background(), pingMethod, &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		return fmt.Errorf("connection lost: %w", err)
+	}
+
+	return nil
+}
+
+// getStage will return the transaction stage assigned to the context, if there is one.
+func (rmt *Remote) getStage(ctx context.Context) (*stage, error) {
+	txID, ok := ctx.Value(basicRemoteTxID).(string)
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := rmt.activeTx.Load(txID)
+	if !ok {
+		return nil, nil
+	}
+
+	stg, ok := val.(*stage)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return stg, nil
+}
+
+// Upsert will forward the request to the remote service. Within a transaction the request is staged, and the
+// response only counts the records on the request.
+func (rmt *Remote) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+	stg, err := rmt.getStage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if stg != nil {
+		records, err := proto.DecodeUpsertRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode records: %w", err)
+		}
+
+		stg.mutex.Lock()
+		defer stg.mutex.Unlock()
+
+		stg.requests = append(stg.requests, func(ctx context.Context) error {
+			return rmt.invoke(ctx, upsertMethod, req, &proto.UpsertResponse{})
+		})
+
+		return &proto.UpsertResponse{
+			UpsertedCount: int64(len(records)),
+			ReceivedCount: int64(len(records)),
+		}, nil
+	}
+
+	rsp := &proto.UpsertResponse{}
+	if err := rmt.invoke(ctx, upsertMethod, req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// UpsertBinary will forward the request to the remote service, or stage it if the context has a transaction.
+func (rmt *Remote) UpsertBinary(ctx context.Context,
+	req *proto.UpsertBinaryRequest,
+) (*proto.UpsertBinaryResponse, error) {
+	stg, err := rmt.getStage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if stg != nil {
+		stg.mutex.Lock()
+		defer stg.mutex.Unlock()
+
+		stg.requests = append(stg.requests, func(ctx context.Context) error {
+			return rmt.invoke(ctx, upsertBinaryMethod, req, &proto.UpsertBinaryResponse{})
+		})
+
+		return &proto.UpsertBinaryResponse{}, nil
+	}
+
+	rsp := &proto.UpsertBinaryResponse{}
+	if err := rmt.invoke(ctx, upsertBinaryMethod, req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// Truncate will forward the request to the remote service, or stage it if the context has a transaction.
+func (rmt *Remote) Truncate(ctx context.Context, req *proto.TruncateRequest) (*proto.TruncateResponse, error) {
+	stg, err := rmt.getStage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if stg != nil {
+		stg.mutex.Lock()
+		defer stg.mutex.Unlock()
+
+		stg.requests = append(stg.requests, func(ctx context.Context) error {
+			return rmt.invoke(ctx, truncateMethod, req, &proto.TruncateResponse{})
+		})
+
+		return &proto.TruncateResponse{}, nil
+	}
+
+	rsp := &proto.TruncateResponse{}
+	if err := rmt.invoke(ctx, truncateMethod, req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ListTables will return the tables of the remote storage device.
+func (rmt *Remote) ListTables(ctx context.Context) (*proto.ListTablesResponse, error) {
+	rsp := &proto.ListTablesResponse{}
+	if err := rmt.invoke(ctx, listTablesMethod, &emptypb.Empty{}, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ListPrimaryKeys will return the primary keys of the remote storage device.
+func (rmt *Remote) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeysResponse, error) {
+	rsp := &proto.ListPrimaryKeysResponse{}
+	if err := rmt.invoke(ctx, listPrimaryKeysMethod, &emptypb.Empty{}, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ListColumns will return the columns of the remote storage device. If the remote storage device has no schema,
+// "proto.ErrListColumnsNotSupported" is returned.
+func (rmt *Remote) ListColumns(ctx context.Context) (*proto.ListColumnsResponse, error) {
+	rsp := &proto.ListColumnsResponse{}
+	if err := rmt.invoke(ctx, listColumnsMethod, &emptypb.Empty{}, rsp); err != nil {
+		if status.Code(errors.Unwrap(err)) == codes.Unimplemented {
+			return nil, proto.ErrListColumnsNotSupported
+		}
+
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// Query will read the records of a table on the remote storage device. If the remote storage device cannot read
+// records, "proto.ErrQueryNotSupported" is returned.
+func (rmt *Remote) Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	readReq, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &proto.ReadResponse{}
+	if err := rmt.invoke(ctx, readMethod, readReq, rsp); err != nil {
+		if status.Code(errors.Unwrap(err)) == codes.Unimplemented {
+			return nil, proto.ErrQueryNotSupported
+		}
+
+		return nil, err
+	}
+
+	return &proto.QueryResponse{Records: rsp.GetRecords()}, nil
+}
+
+// commitStage will forward all of the staged requests in the order they were made.
+func (rmt *Remote) commitStage(ctx context.Context, stg *stage) error {
+	for _, req := range stg.requests {
+		if err := req(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartTx will start a transaction on the remote storage device. Requests made in the transaction are held in memory,
+// and forwarded on commit or discarded on rollback. Requests that were forwarded before a failed request of a commit
+// cannot be recalled.
+func (rmt *Remote) StartTx(ctx context.Context) (*proto.Txn, error) {
+	txn := &proto.Txn{
+		FunctionCh: make(chan proto.TxnChanFn),
+		DoneCh:     make(chan error, 1),
+		CommitCh:   make(chan bool, 1),
+	}
+
+	txnID := uuid.New().String()
+	stg := &stage{}
+
+	rmt.activeTx.Store(txnID, stg)
+
+	// Create a copy of the parent context with a transaction ID.
+	rmtCtx := context.WithValue(ctx, basicRemoteTxID, txnID)
+
+	go func() {
+		defer rmt.activeTx.Delete(txnID)
+
+		var err error
+
+		for fn := range txn.FunctionCh {
+			if err != nil {
+				continue
+			}
+
+			err = fn(rmtCtx, rmt)
+		}
+
+		if err != nil {
+			txn.DoneCh <- err
+
+			return
+		}
+
+		if <-txn.CommitCh {
+			txn.DoneCh <- rmt.commitStage(ctx, stg)
+		} else {
+			txn.DoneCh <- nil
+		}
+	}()
+
+	return txn, nil
+}
@@ -0,0 +1,144 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/file"
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestRemote will serve a file storage device over an in-memory connection and return a client for it.
+func newTestRemote(t *testing.T, serverToken, clientToken string) (*Remote, proto.Storage) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	stg, err := file.New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file storage: %v", err)
+	}
+
+	return serveTestStorage(t, stg, serverToken, clientToken), stg
+}
+
+// serveTestStorage will serve the storage device over an in-memory connection and return a client for it.
+func serveTestStorage(t *testing.T, stg proto.Storage, serverToken, clientToken string) *Remote {
+	t.Helper()
+
+	ctx := context.Background()
+
+	lis := bufconn.Listen(1 << 20)
+
+	grpcServer := grpc.NewServer()
+	NewServer(stg, serverToken).Register(grpcServer)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_ = grpcServer.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		wg.Wait()
+	})
+
+	return &Remote{conn: conn, token: clientToken}
+}
+
+func TestRemote(t *testing.T) {
+	t.Parallel()
+
+	t.Run("upsert", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		rmt, stg := newTestRemote(t, "secret", "secret")
+
+		if err := rmt.Ping(); err != nil {
+			t.Fatalf("failed to ping: %v", err)
+		}
+
+		txn, err := rmt.StartTx(ctx)
+		if err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+
+		txn.Send(func(sctx context.Context, stg proto.Storage) error {
+			_, err := stg.Upsert(sctx, &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":1},{"id":2}]`)})
+
+			return err
+		})
+
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+
+		tables, err := rmt.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list tables: %v", err)
+		}
+
+		local, err := stg.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("failed to list local tables: %v", err)
+		}
+
+		size := tables.GetTableSet()["candles"].GetSize()
+		if size == 0 || size != local.GetTableSet()["candles"].GetSize() {
+			t.Fatalf("expected remote and local sizes to match, got %v and %v", tables, local)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		t.Parallel()
+
+		rmt, _ := newTestRemote(t, "secret", "wrong")
+
+		_, err := rmt.Upsert(context.Background(), &proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":1}]`)})
+		if status.Code(unwrapStatus(err)) != codes.Unauthenticated {
+			t.Fatalf("expected unauthenticated error, got %v", err)
+		}
+	})
+}
+
+// unwrapStatus will return the innermost error, which carries the gRPC status.
+func unwrapStatus(err error) error {
+	for {
+		wrapped, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return err
+		}
+
+		err = wrapped.Unwrap()
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StoredRun is a run with the configuration that it was submitted with, so that it can be resumed after a restart or
+// retried. The configuration is never returned by the HTTP API.
+type StoredRun struct {
+	Run
+
+	Config []byte `json:"config"`
+}
+
+// RunStore persists the runs of the HTTP API, so that they survive restarts of the service.
+type RunStore interface {
+	// Save will create or replace the run.
+	Save(run *StoredRun) error
+
+	// Load will return every run in the store.
+	Load() ([]*StoredRun, error)
+}
+
+// runFileExt is the extension of the files of a directory run store.
+const runFileExt = ".json"
+
+// DirRunStore is a run store that keeps each run in a JSON file of a directory, e.g. on a persistent volume.
+type DirRunStore struct {
+	dir string
+}
+
+// NewDirRunStore will return a run store for the directory, creating it if it does not exist.
+func NewDirRunStore(dir string) (*DirRunStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create run directory: %w", err)
+	}
+
+	return &DirRunStore{dir: dir}, nil
+}
+
+// Save will write the run to its file. The file is replaced atomically, so that a run is never partially written.
+func (store *DirRunStore) Save(run *StoredRun) error {
+	bytes, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("unable to encode run: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(store.dir, run.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create run file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("unable to write run file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write run file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(store.dir, run.ID+runFileExt)); err != nil {
+		return fmt.Errorf("unable to replace run file: %w", err)
+	}
+
+	return nil
+}
+
+// Load will read every run file in the directory.
+func (store *DirRunStore) Load() ([]*StoredRun, error) {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read run directory: %w", err)
+	}
+
+	runs := make([]*StoredRun, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), runFileExt) {
+			continue
+		}
+
+		bytes, err := os.ReadFile(filepath.Join(store.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read run file: %w", err)
+		}
+
+		run := new(StoredRun)
+		if err := json.Unmarshal(bytes, run); err != nil {
+			return nil, fmt.Errorf("unable to decode run file %s: %w", entry.Name(), err)
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,64 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestDirRunStore(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+
+	store, err := NewDirRunStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	run := &StoredRun{Run: Run{ID: "run", Status: RunPending}, Config: []byte(testConfig)}
+	if err := store.Save(run); err != nil {
+		t.Fatalf("failed to save run: %v", err)
+	}
+
+	run.Status = RunRunning
+	run.Requests = []config.RequestProgress{{Table: "candles", Status: config.RequestCommitted, Inserted: 2}}
+
+	if err := store.Save(run); err != nil {
+		t.Fatalf("failed to replace run: %v", err)
+	}
+
+	// Files that are not runs are ignored.
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("runs"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	runs, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load runs: %v", err)
+	}
+
+	if len(runs) != 1 || runs[0].Status != RunRunning || string(runs[0].Config) != testConfig ||
+		len(runs[0].Requests) != 1 || runs[0].Requests[0].Inserted != 2 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := store.Load(); err == nil {
+		t.Error("expected an error for a corrupt run file")
+	}
+}
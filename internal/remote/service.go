@@ -0,0 +1,216 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ServiceName is the full name of the gRPC service, which is equivalent to the following definition using the
+// messages in "db.proto":
+//
+//	service Storage {
+//		rpc Upsert(UpsertRequest) returns (UpsertResponse);
+//		rpc UpsertBinary(UpsertBinaryRequest) returns (UpsertBinaryResponse);
+//		rpc Truncate(TruncateRequest) returns (TruncateResponse);
+//		rpc ListTables(google.protobuf.Empty) returns (ListTablesResponse);
+//		rpc ListPrimaryKeys(google.protobuf.Empty) returns (ListPrimaryKeysResponse);
+//		rpc ListColumns(google.protobuf.Empty) returns (ListColumnsResponse);
+//		rpc Read(ReadRequest) returns (ReadResponse);
+//		rpc Ping(google.protobuf.Empty) returns (google.protobuf.Empty);
+//	}
+//
+// "Read" and "ListColumns" fail with "UNIMPLEMENTED" if the storage device cannot read records or has no schema.
+const ServiceName = "proto.Storage"
+
+const (
+	upsertMethod          = "/" + ServiceName + "/Upsert"
+	upsertBinaryMethod    = "/" + ServiceName + "/UpsertBinary"
+	truncateMethod        = "/" + ServiceName + "/Truncate"
+	listTablesMethod      = "/" + ServiceName + "/ListTables"
+	listPrimaryKeysMethod = "/" + ServiceName + "/ListPrimaryKeys"
+	listColumnsMethod     = "/" + ServiceName + "/ListColumns"
+	readMethod            = "/" + ServiceName + "/Read"
+	pingMethod            = "/" + ServiceName + "/Ping"
+
+	// authorizationKey is the metadata key for the bearer token.
+	authorizationKey = "authorization"
+)
+
+// handler returns a unary gRPC method handler that decodes the request and calls "fn" with the storage device of
+// the server.
+func handler[Req any, Rsp any](method string,
+	fn func(proto.Storage, context.Context, *Req) (*Rsp, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor,
+	) (interface{}, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, fmt.Errorf("unable to decode request: %w", err)
+		}
+
+		call := func(ctx context.Context, req interface{}) (interface{}, error) {
+			server, _ := srv.(*Server)
+			if err := server.authorize(ctx); err != nil {
+				return nil, err
+			}
+
+			typedReq, _ := req.(*Req)
+
+			return fn(server.stg, ctx, typedReq)
+		}
+
+		if interceptor == nil {
+			return call(ctx, req)
+		}
+
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, call)
+	}
+}
+
+// serviceDesc describes the storage service for registration on a gRPC server.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Upsert",
+			Handler: handler(upsertMethod,
+				func(stg proto.Storage, ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+					return stg.Upsert(ctx, req)
+				}),
+		},
+		{
+			MethodName: "UpsertBinary",
+			Handler: handler(upsertBinaryMethod, func(stg proto.Storage, ctx context.Context,
+				req *proto.UpsertBinaryRequest,
+			) (*proto.UpsertBinaryResponse, error) {
+				return stg.UpsertBinary(ctx, req)
+			}),
+		},
+		{
+			MethodName: "Truncate",
+			Handler: handler(truncateMethod, func(stg proto.Storage, ctx context.Context,
+				req *proto.TruncateRequest,
+			) (*proto.TruncateResponse, error) {
+				return stg.Truncate(ctx, req)
+			}),
+		},
+		{
+			MethodName: "ListTables",
+			Handler: handler(listTablesMethod,
+				func(stg proto.Storage, ctx context.Context, _ *emptypb.Empty) (*proto.ListTablesResponse, error) {
+					return stg.ListTables(ctx)
+				}),
+		},
+		{
+			MethodName: "ListPrimaryKeys",
+			Handler: handler(listPrimaryKeysMethod, func(stg proto.Storage, ctx context.Context,
+				_ *emptypb.Empty,
+			) (*proto.ListPrimaryKeysResponse, error) {
+				return stg.ListPrimaryKeys(ctx)
+			}),
+		},
+		{
+			MethodName: "ListColumns",
+			Handler: handler(listColumnsMethod, func(stg proto.Storage, ctx context.Context,
+				_ *emptypb.Empty,
+			) (*proto.ListColumnsResponse, error) {
+				return listColumns(ctx, stg)
+			}),
+		},
+		{
+			MethodName: "Read",
+			Handler: handler(readMethod,
+				func(stg proto.Storage, ctx context.Context, req *proto.ReadRequest) (*proto.ReadResponse, error) {
+					return read(ctx, stg, req)
+				}),
+		},
+		{
+			MethodName: "Ping",
+			Handler: handler(pingMethod,
+				func(stg proto.Storage, _ context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+					return &emptypb.Empty{}, stg.Ping()
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// Server serves a storage device over gRPC, so that records can be fetched and written by different processes.
+type Server struct {
+	stg   proto.Storage
+	token string
+}
+
+// NewServer will return a server for the storage device. If the token is not empty, requests must be authorized with
+// it as a bearer token.
+func NewServer(stg proto.Storage, token string) *Server {
+	return &Server{stg: stg, token: token}
+}
+
+// Register will register the storage service on the gRPC server.
+func (server *Server) Register(grpcServer *grpc.Server) {
+	grpcServer.RegisterService(&serviceDesc, server)
+}
+
+// authorize will return an error if the request does not have the bearer token of the server.
+func (server *Server) authorize(ctx context.Context) error {
+	return authorizeToken(ctx, server.token)
+}
+
+// authorizeToken will return an error if the token is not empty and the request does not have it as a bearer token.
+func authorizeToken(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	for _, val := range bearerTokens(ctx) {
+		if subtle.ConstantTimeCompare([]byte(val), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+// authorizeTenant will return the tenant whose token the request has as a bearer token.
+func authorizeTenant(ctx context.Context, tenants *Tenants) (*Tenant, error) {
+	for _, val := range bearerTokens(ctx) {
+		if tenant, ok := tenants.authorize(val); ok {
+			return tenant, nil
+		}
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+// bearerTokens will return the bearer tokens in the metadata of the request.
+func bearerTokens(ctx context.Context) []string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	vals := md.Get(authorizationKey)
+	tokens := make([]string, len(vals))
+
+	for idx, val := range vals {
+		tokens[idx] = strings.TrimPrefix(val, "Bearer ")
+	}
+
+	return tokens
+}
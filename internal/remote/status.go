@@ -0,0 +1,250 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// statusPath is the path of the live status of the HTTP API.
+const statusPath = "/status"
+
+// maxRecentErrors is the number of the most recent errors of runs and requests that the status keeps.
+const maxRecentErrors = 20
+
+// Status is the live status of the runs of the HTTP API, for quick operational checks: the runs that are queued and
+// running, the progress of the requests of each running run, when the table of each request was last committed, and
+// the most recent errors.
+type Status struct {
+	Pending int              `json:"pending"`
+	Running []*RunningStatus `json:"running"`
+	Tables  []*TableStatus   `json:"tables"`
+
+	// RecentErrors are the most recent errors of requests that were rolled back and runs that failed, newest first.
+	RecentErrors []*ErrorStatus `json:"recentErrors"`
+}
+
+// RunningStatus is the progress of a run that is running.
+type RunningStatus struct {
+	ID        string           `json:"id"`
+	Tenant    string           `json:"tenant,omitempty"`
+	StartedAt *time.Time       `json:"startedAt,omitempty"`
+	Requests  []*RequestStatus `json:"requests"`
+}
+
+// RequestStatus is the progress of a request of a running run: the chunks that have been fetched, the records that
+// have been upserted, and its status once it has been committed or rolled back.
+type RequestStatus struct {
+	Endpoint string `json:"endpoint"`
+	Table    string `json:"table"`
+	Fetched  int    `json:"fetched"`
+	Chunks   int    `json:"chunks"`
+	Upserted int64  `json:"upserted"`
+	Status   string `json:"status,omitempty"`
+}
+
+// TableStatus is when the requests of a table were last committed, and when they were last rolled back.
+type TableStatus struct {
+	Tenant        string     `json:"tenant,omitempty"`
+	Table         string     `json:"table"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastRunID     string     `json:"lastRunId,omitempty"`
+	LastErrorAt   *time.Time `json:"lastErrorAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// ErrorStatus is the error of a request that was rolled back, or of a run that failed before any of its requests were.
+type ErrorStatus struct {
+	RunID    string    `json:"runId"`
+	Tenant   string    `json:"tenant,omitempty"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Table    string    `json:"table,omitempty"`
+	Error    string    `json:"error"`
+	At       time.Time `json:"at"`
+}
+
+// tableKey identifies the table of a tenant.
+type tableKey struct {
+	tenant string
+	table  string
+}
+
+// liveStatus is the status of the runs of the server that is not persisted with them.
+type liveStatus struct {
+	running      map[string][]*RequestStatus
+	tables       map[tableKey]*TableStatus
+	recentErrors []*ErrorStatus
+}
+
+func newLiveStatus() *liveStatus {
+	return &liveStatus{
+		running: make(map[string][]*RequestStatus),
+		tables:  make(map[tableKey]*TableStatus),
+	}
+}
+
+// request will return the progress of the request of the running run, adding it if it has not been reported yet.
+func (live *liveStatus) request(runID, endpoint, table string) *RequestStatus {
+	for _, req := range live.running[runID] {
+		if req.Endpoint == endpoint && req.Table == table {
+			return req
+		}
+	}
+
+	req := &RequestStatus{Endpoint: endpoint, Table: table}
+	live.running[runID] = append(live.running[runID], req)
+
+	return req
+}
+
+// addError will add the error to the most recent errors, dropping the oldest error if there are too many.
+func (live *liveStatus) addError(errStatus *ErrorStatus) {
+	live.recentErrors = append([]*ErrorStatus{errStatus}, live.recentErrors...)
+	if len(live.recentErrors) > maxRecentErrors {
+		live.recentErrors = live.recentErrors[:maxRecentErrors]
+	}
+}
+
+// watch will record the live progress of the run of the configuration. The caller must not hold the mutex.
+func (server *HTTPServer) watch(cfg *config.Config, run *StoredRun) {
+	cfg.OnFetch = func(fetch config.FetchProgress) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		req := server.live.request(run.ID, fetch.Endpoint, fetch.Table)
+		req.Chunks = fetch.Chunks
+
+		if fetch.Fetched > req.Fetched {
+			req.Fetched = fetch.Fetched
+		}
+	}
+
+	cfg.OnUpsert = func(upsert config.UpsertProgress) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		server.live.request(run.ID, upsert.Endpoint, upsert.Table).Upserted += upsert.Upserted
+	}
+}
+
+// recordRequest will record the outcome of the request of the run in the status of its table. The caller must hold
+// the mutex.
+func (server *HTTPServer) recordRequest(run *StoredRun, progress config.RequestProgress) {
+	server.live.request(run.ID, progress.Endpoint, progress.Table).Status = progress.Status
+
+	key := tableKey{tenant: run.Tenant, table: progress.Table}
+
+	table, ok := server.live.tables[key]
+	if !ok {
+		table = &TableStatus{Tenant: run.Tenant, Table: progress.Table}
+		server.live.tables[key] = table
+	}
+
+	now := time.Now().UTC()
+
+	if progress.Status == config.RequestCommitted {
+		table.LastSuccessAt = &now
+		table.LastRunID = run.ID
+
+		return
+	}
+
+	table.LastErrorAt = &now
+	table.LastError = progress.Error
+
+	server.live.addError(&ErrorStatus{
+		RunID:    run.ID,
+		Tenant:   run.Tenant,
+		Endpoint: progress.Endpoint,
+		Table:    progress.Table,
+		Error:    progress.Error,
+		At:       now,
+	})
+}
+
+// recordRun will record the error of the run once it has finished, if it failed before any of its requests were
+// rolled back. The caller must hold the mutex.
+func (server *HTTPServer) recordRun(run *StoredRun) {
+	if run.Status != RunFailed {
+		return
+	}
+
+	for _, req := range run.Requests {
+		if req.Status == config.RequestRolledBack {
+			return
+		}
+	}
+
+	server.live.addError(&ErrorStatus{RunID: run.ID, Tenant: run.Tenant, Error: run.Error, At: *run.FinishedAt})
+}
+
+// writeStatus will write the live status of the runs of the tenant.
+func (server *HTTPServer) writeStatus(wtr http.ResponseWriter, tenant *Tenant) {
+	server.mutex.Lock()
+
+	status := Status{Running: []*RunningStatus{}, Tables: []*TableStatus{}, RecentErrors: []*ErrorStatus{}}
+
+	for id := range server.runs {
+		run, ok := server.lookup(id, tenant)
+		if !ok {
+			continue
+		}
+
+		switch run.Status {
+		case RunPending:
+			status.Pending++
+		case RunRunning:
+			running := &RunningStatus{
+				ID:        run.ID,
+				Tenant:    run.Tenant,
+				StartedAt: run.StartedAt,
+				Requests:  []*RequestStatus{},
+			}
+
+			for _, req := range server.live.running[run.ID] {
+				reqStatus := *req
+				running.Requests = append(running.Requests, &reqStatus)
+			}
+
+			status.Running = append(status.Running, running)
+		}
+	}
+
+	for _, table := range server.live.tables {
+		if tenant == nil || table.Tenant == tenant.Name {
+			tableStatus := *table
+			status.Tables = append(status.Tables, &tableStatus)
+		}
+	}
+
+	for _, errStatus := range server.live.recentErrors {
+		if tenant == nil || errStatus.Tenant == tenant.Name {
+			status.RecentErrors = append(status.RecentErrors, errStatus)
+		}
+	}
+
+	server.mutex.Unlock()
+
+	sort.Slice(status.Running, func(i, j int) bool {
+		return status.Running[i].StartedAt.Before(*status.Running[j].StartedAt)
+	})
+
+	sort.Slice(status.Tables, func(i, j int) bool {
+		if status.Tables[i].Tenant != status.Tables[j].Tenant {
+			return status.Tables[i].Tenant < status.Tables[j].Tenant
+		}
+
+		return status.Tables[i].Table < status.Tables[j].Table
+	})
+
+	writeJSON(wtr, http.StatusOK, &status)
+}
@@ -0,0 +1,124 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestHTTPServerStatus(t *testing.T) {
+	t.Parallel()
+
+	// fetched is signaled once the run has fetched its first chunk, and release lets it finish.
+	fetched := make(chan struct{})
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	httpServer := NewHTTPServer(func(_ context.Context, cfg *config.Config) error {
+		req := cfg.Requests[0]
+
+		if req.Table == "unknown" {
+			return fmt.Errorf("web API unavailable")
+		}
+
+		cfg.OnFetch(config.FetchProgress{Endpoint: req.Endpoint, Table: req.Table, Fetched: 1, Chunks: 2})
+		cfg.OnUpsert(config.UpsertProgress{Endpoint: req.Endpoint, Table: req.Table, Upserted: 3})
+
+		fetched <- struct{}{}
+		<-release
+
+		if req.Table == "fail" {
+			cfg.Progress(config.RequestProgress{Endpoint: req.Endpoint, Table: req.Table,
+				Status: config.RequestRolledBack, Error: "404"})
+
+			return fmt.Errorf("1 of 1 requests failed: 404")
+		}
+
+		cfg.Progress(config.RequestProgress{Endpoint: req.Endpoint, Table: req.Table, Status: config.RequestCommitted})
+
+		return nil
+	}, "secret")
+	httpServer.Start(ctx, 1)
+
+	server := httptest.NewServer(httpServer)
+	t.Cleanup(server.Close)
+
+	succeeded := new(Run)
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig+"    table: candles\n", succeeded)
+
+	failed := new(Run)
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig+"    table: fail\n", failed)
+
+	<-fetched
+
+	status := new(Status)
+	if rsp := doHTTP(t, server, http.MethodGet, statusPath, "secret", "", status); rsp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the status, got %d", rsp.StatusCode)
+	}
+
+	if status.Pending != 1 || len(status.Running) != 1 || status.Running[0].ID != succeeded.ID {
+		t.Fatalf("expected 1 pending run and the running run, got %+v", status)
+	}
+
+	want := RequestStatus{Endpoint: "/products/candles", Table: "candles", Fetched: 1, Chunks: 2, Upserted: 3}
+	if requests := status.Running[0].Requests; len(requests) != 1 || *requests[0] != want {
+		t.Errorf("expected the progress of the request, got %+v", requests)
+	}
+
+	close(release)
+	<-fetched
+
+	awaitRun(t, server, succeeded.ID)
+	awaitRun(t, server, failed.ID)
+
+	unknown := new(Run)
+	doHTTP(t, server, http.MethodPost, runsPath, "secret", testConfig+"    table: unknown\n", unknown)
+	awaitRun(t, server, unknown.ID)
+
+	status = new(Status)
+	doHTTP(t, server, http.MethodGet, statusPath, "secret", "", status)
+
+	if status.Pending != 0 || len(status.Running) != 0 || len(status.Tables) != 2 {
+		t.Fatalf("expected no runs and the status of 2 tables, got %+v", status)
+	}
+
+	if candles := status.Tables[0]; candles.Table != "candles" || candles.LastSuccessAt == nil ||
+		candles.LastRunID != succeeded.ID || candles.LastErrorAt != nil {
+		t.Errorf("expected the last success of candles, got %+v", candles)
+	}
+
+	if fail := status.Tables[1]; fail.Table != "fail" || fail.LastSuccessAt != nil || fail.LastError != "404" {
+		t.Errorf("expected the last error of fail, got %+v", fail)
+	}
+
+	// The error of a run is only recorded if none of its requests were rolled back.
+	if len(status.RecentErrors) != 2 || status.RecentErrors[0].RunID != unknown.ID ||
+		status.RecentErrors[0].Error != "web API unavailable" || status.RecentErrors[1].Table != "fail" {
+		t.Errorf("expected the errors of the unknown run and the fail request, got %+v", status.RecentErrors)
+	}
+
+	if rsp := doHTTP(t, server, http.MethodGet, statusPath, "wrong", "", nil); rsp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the status to be authorized, got %d", rsp.StatusCode)
+	}
+
+	if rsp := doHTTP(t, server, http.MethodPost, statusPath, "secret", "", nil); rsp.StatusCode !=
+		http.StatusMethodNotAllowed {
+		t.Errorf("expected only GET to be allowed, got %d", rsp.StatusCode)
+	}
+
+}
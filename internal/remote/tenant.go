@@ -0,0 +1,253 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/alpstable/gidari/config"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ErrInvalidTenant           = fmt.Errorf("invalid tenant")
+	ErrTenantDestinationDenied = fmt.Errorf("destination is not allowed for tenant")
+)
+
+// Tenant is a team that shares a service with other teams. Each tenant has its own bearer token, the storage devices
+// that its configurations may write to, the credentials of its web APIs, and a rate limit that is shared by all of its
+// runs.
+type Tenant struct {
+	// Name identifies the tenant, e.g. in the logs of its runs.
+	Name string `yaml:"name"`
+
+	// Token is the bearer token that the requests of the tenant are authorized with.
+	Token string `yaml:"token"`
+
+	// ConnectionStrings are the storage devices that the configurations of the tenant may write to. Configurations
+	// without any destinations write to all of them, so that the credentials of the storage devices never need to be
+	// shared with the tenant.
+	ConnectionStrings []string `yaml:"connectionStrings"`
+
+	// Authentication is the credentials that the requests of the configurations of the tenant are authenticated with,
+	// replacing the authentication of each configuration, so that each tenant keeps its own API credentials and a
+	// configuration can never use those of another tenant. If it is not set, the authentication of each configuration
+	// is used.
+	Authentication *config.Authentication `yaml:"authentication"`
+
+	// RateLimit limits the requests to web APIs across every run of the tenant, replacing the rate limit of each
+	// configuration. If it is not set, the rate limit of each configuration is used.
+	RateLimit *config.RateLimitConfig `yaml:"rateLimit"`
+
+	limiter *rate.Limiter
+}
+
+// apply will scope the configuration to the tenant, returning an error if it writes to a storage device that the
+// tenant is not allowed to use, or uses a feature that is only supported in local configurations. The authentication
+// and rate limit of the tenant replace those of the configuration.
+func (tenant *Tenant) apply(cfg *config.Config) error {
+	if err := cfg.CheckRemote(); err != nil {
+		return fmt.Errorf("tenant %q: %w", tenant.Name, err)
+	}
+
+	if len(cfg.ConnectionStrings) == 0 && len(cfg.Destinations) == 0 {
+		cfg.ConnectionStrings = append([]string{}, tenant.ConnectionStrings...)
+	}
+
+	for _, dest := range cfg.DestinationList() {
+		if !tenant.allows(dest.ConnectionString) {
+			return fmt.Errorf("%w %q", ErrTenantDestinationDenied, tenant.Name)
+		}
+	}
+
+	if tenant.Authentication != nil {
+		cfg.Authentication = *tenant.Authentication
+	}
+
+	if tenant.limiter != nil {
+		for _, req := range cfg.Requests {
+			req.RateLimiter = tenant.limiter
+		}
+	}
+
+	return nil
+}
+
+// allows will return true if the tenant may write to the storage device.
+func (tenant *Tenant) allows(dns string) bool {
+	for _, allowed := range tenant.ConnectionStrings {
+		if allowed == dns {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tenants are the teams that share a service, so that one deployment can safely run the configurations of multiple
+// teams. Each request is authorized with the token of a tenant, and its configuration is scoped to the tenant.
+type Tenants struct {
+	Tenants []*Tenant `yaml:"tenants"`
+
+	// mutex guards the tenants, which are replaced by "Reload" while requests are authorized.
+	mutex sync.RWMutex
+}
+
+// ParseTenants takes the YAML of the tenants of a service and returns them, with the rate limiter of each tenant.
+func ParseTenants(bytes []byte) (*Tenants, error) {
+	var tenants Tenants
+	if err := yaml.Unmarshal(bytes, &tenants); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal YAML: %w", err)
+	}
+
+	if len(tenants.Tenants) == 0 {
+		return nil, fmt.Errorf("%w: no tenants", ErrInvalidTenant)
+	}
+
+	names := make(map[string]bool, len(tenants.Tenants))
+	tokens := make(map[string]bool, len(tenants.Tenants))
+
+	for _, tenant := range tenants.Tenants {
+		if err := tenant.validate(); err != nil {
+			return nil, err
+		}
+
+		if names[tenant.Name] || tokens[tenant.Token] {
+			return nil, fmt.Errorf("%w: the name and token of %q must be unique", ErrInvalidTenant, tenant.Name)
+		}
+
+		names[tenant.Name] = true
+		tokens[tenant.Token] = true
+
+		if tenant.RateLimit != nil {
+			tenant.limiter = rate.NewLimiter(rate.Every(*tenant.RateLimit.Period), *tenant.RateLimit.Burst)
+		}
+	}
+
+	return &tenants, nil
+}
+
+func (tenant *Tenant) validate() error {
+	if tenant.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidTenant)
+	}
+
+	if tenant.Token == "" || len(tenant.ConnectionStrings) == 0 {
+		return fmt.Errorf("%w: token and connectionStrings are required on %q", ErrInvalidTenant, tenant.Name)
+	}
+
+	if rateLimit := tenant.RateLimit; rateLimit != nil && (rateLimit.Burst == nil || rateLimit.Period == nil) {
+		return fmt.Errorf("%w: rateLimit requires burst and period on %q", ErrInvalidTenant, tenant.Name)
+	}
+
+	return nil
+}
+
+// Reload will replace the tenants with the next tenants, e.g. when the tenants file has changed, without interrupting
+// the requests that are being served. Runs that have started keep the tenant they started with, and queued runs use
+// the tenant they belong to when they start. Tenants whose rate limit is unchanged keep their rate limiter, so that
+// the requests they have made still count against it. The changes are returned, e.g. for the logs.
+func (tenants *Tenants) Reload(next *Tenants) []string {
+	tenants.mutex.Lock()
+	defer tenants.mutex.Unlock()
+
+	current := make(map[string]*Tenant, len(tenants.Tenants))
+	for _, tenant := range tenants.Tenants {
+		current[tenant.Name] = tenant
+	}
+
+	changes := []string{}
+
+	for _, tenant := range next.Tenants {
+		prev, ok := current[tenant.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("added tenant %q", tenant.Name))
+
+			continue
+		}
+
+		delete(current, tenant.Name)
+
+		if fields := tenant.changes(prev); len(fields) > 0 {
+			changes = append(changes, fmt.Sprintf("updated %s of tenant %q", strings.Join(fields, ", "),
+				tenant.Name))
+		}
+
+		if reflect.DeepEqual(tenant.RateLimit, prev.RateLimit) {
+			tenant.limiter = prev.limiter
+		}
+	}
+
+	for _, tenant := range tenants.Tenants {
+		if _, ok := current[tenant.Name]; ok {
+			changes = append(changes, fmt.Sprintf("removed tenant %q", tenant.Name))
+		}
+	}
+
+	tenants.Tenants = next.Tenants
+
+	return changes
+}
+
+// changes will return the fields of the tenant that differ from the previous tenant with the same name.
+func (tenant *Tenant) changes(prev *Tenant) []string {
+	fields := []string{}
+
+	if tenant.Token != prev.Token {
+		fields = append(fields, "token")
+	}
+
+	if !reflect.DeepEqual(tenant.ConnectionStrings, prev.ConnectionStrings) {
+		fields = append(fields, "connectionStrings")
+	}
+
+	if !reflect.DeepEqual(tenant.Authentication, prev.Authentication) {
+		fields = append(fields, "authentication")
+	}
+
+	if !reflect.DeepEqual(tenant.RateLimit, prev.RateLimit) {
+		fields = append(fields, "rateLimit")
+	}
+
+	return fields
+}
+
+// authorize will return the tenant with the token, comparing it with the token of every tenant in constant time.
+func (tenants *Tenants) authorize(token string) (*Tenant, bool) {
+	tenants.mutex.RLock()
+	defer tenants.mutex.RUnlock()
+
+	var match *Tenant
+
+	for _, tenant := range tenants.Tenants {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(tenant.Token)) == 1 {
+			match = tenant
+		}
+	}
+
+	return match, match != nil
+}
+
+// tenant will return the tenant with the name.
+func (tenants *Tenants) tenant(name string) (*Tenant, bool) {
+	tenants.mutex.RLock()
+	defer tenants.mutex.RUnlock()
+
+	for _, tenant := range tenants.Tenants {
+		if tenant.Name == name {
+			return tenant, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,291 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testTenants = `
+tenants:
+  - name: research
+    token: research-secret
+    connectionStrings: ["stdout://", "postgresql://research@localhost:5432/db"]
+    authentication:
+      auth2:
+        bearer: research-api-token
+    rateLimit:
+      burst: 5
+      period: 1s
+  - name: trading
+    token: trading-secret
+    connectionStrings: ["postgresql://trading@localhost:5432/db"]
+`
+
+func TestParseTenants(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := ParseTenants([]byte(testTenants))
+	if err != nil {
+		t.Fatalf("failed to parse tenants: %v", err)
+	}
+
+	if tenant, ok := tenants.authorize("research-secret"); !ok || tenant.Name != "research" || tenant.limiter == nil {
+		t.Errorf("expected the research tenant with a rate limiter, got %+v", tenant)
+	}
+
+	if tenant, ok := tenants.authorize("trading-secret"); !ok || tenant.Name != "trading" || tenant.limiter != nil {
+		t.Errorf("expected the trading tenant without a rate limiter, got %+v", tenant)
+	}
+
+	if _, ok := tenants.authorize(""); ok {
+		t.Errorf("expected an empty token to be unauthorized")
+	}
+
+	for _, tcase := range []struct {
+		name    string
+		tenants string
+	}{
+		{name: "empty", tenants: "tenants: []"},
+		{name: "missing name", tenants: "tenants: [{token: a, connectionStrings: [stdout://]}]"},
+		{name: "missing token", tenants: "tenants: [{name: a, connectionStrings: [stdout://]}]"},
+		{name: "missing connection strings", tenants: "tenants: [{name: a, token: a}]"},
+		{name: "missing period", tenants: "tenants: [{name: a, token: a, connectionStrings: [stdout://], " +
+			"rateLimit: {burst: 1}}]"},
+		{name: "duplicate token", tenants: "tenants: [{name: a, token: a, connectionStrings: [stdout://]}, " +
+			"{name: b, token: a, connectionStrings: [stdout://]}]"},
+	} {
+		if _, err := ParseTenants([]byte(tcase.tenants)); !errors.Is(err, ErrInvalidTenant) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, ErrInvalidTenant, err)
+		}
+	}
+}
+
+func TestTenantsReload(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := ParseTenants([]byte(testTenants))
+	if err != nil {
+		t.Fatalf("failed to parse tenants: %v", err)
+	}
+
+	research, _ := tenants.tenant("research")
+
+	next, err := ParseTenants([]byte(`
+tenants:
+  - name: research
+    token: rotated-secret
+    connectionStrings: ["stdout://", "postgresql://research@localhost:5432/db"]
+    authentication:
+      auth2:
+        bearer: rotated-api-token
+    rateLimit:
+      burst: 5
+      period: 1s
+  - name: analytics
+    token: analytics-secret
+    connectionStrings: ["stdout://"]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse tenants: %v", err)
+	}
+
+	changes := tenants.Reload(next)
+
+	want := []string{`updated token, authentication of tenant "research"`, `added tenant "analytics"`, `removed tenant "trading"`}
+	if strings.Join(changes, "; ") != strings.Join(want, "; ") {
+		t.Errorf("expected changes %q, got %q", want, changes)
+	}
+
+	if _, ok := tenants.authorize("research-secret"); ok {
+		t.Errorf("expected the previous token to be unauthorized")
+	}
+
+	if _, ok := tenants.authorize("trading-secret"); ok {
+		t.Errorf("expected the removed tenant to be unauthorized")
+	}
+
+	// The rate limit is unchanged, so the requests that were made still count against it.
+	if tenant, ok := tenants.authorize("rotated-secret"); !ok || tenant.limiter != research.limiter {
+		t.Errorf("expected the research tenant with its rate limiter, got %+v", tenant)
+	}
+
+	if _, ok := tenants.authorize("analytics-secret"); !ok {
+		t.Errorf("expected the added tenant to be authorized")
+	}
+}
+
+func TestTenantApply(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := ParseTenants([]byte(testTenants))
+	if err != nil {
+		t.Fatalf("failed to parse tenants: %v", err)
+	}
+
+	research, _ := tenants.authorize("research-secret")
+
+	parse := func(cfg string) *config.Config {
+		t.Helper()
+
+		parsed, err := config.Parse(context.Background(), []byte(cfg))
+		if err != nil {
+			t.Fatalf("failed to parse configuration: %v", err)
+		}
+
+		return parsed
+	}
+
+	// Configurations without destinations write to every storage device of the tenant.
+	cfg := parse(strings.Replace(testConfig, `connectionStrings: ["stdout://"]`, "", 1))
+	if err := research.apply(cfg); err != nil {
+		t.Fatalf("failed to apply tenant: %v", err)
+	}
+
+	if len(cfg.ConnectionStrings) != 2 || cfg.Requests[0].RateLimiter != research.limiter {
+		t.Errorf("expected the storage devices and rate limiter of the tenant, got %+v", cfg)
+	}
+
+	// The credentials of the tenant replace those of the configuration.
+	cfg = parse(testConfig + "authentication:\n  auth2:\n    bearer: trading-api-token\n")
+	if err := research.apply(cfg); err != nil {
+		t.Fatalf("failed to apply tenant: %v", err)
+	}
+
+	if auth := cfg.Authentication; auth.Auth2 == nil || auth.Auth2.Bearer != "research-api-token" {
+		t.Errorf("expected the authentication of the tenant, got %+v", auth.Auth2)
+	}
+
+	// Tenants without credentials use those of the configuration.
+	trading, _ := tenants.authorize("trading-secret")
+
+	cfg = parse(strings.Replace(testConfig, "stdout://", "postgresql://trading@localhost:5432/db", 1) +
+		"authentication:\n  auth2:\n    bearer: trading-api-token\n")
+	if err := trading.apply(cfg); err != nil {
+		t.Fatalf("failed to apply tenant: %v", err)
+	}
+
+	if auth := cfg.Authentication; auth.Auth2 == nil || auth.Auth2.Bearer != "trading-api-token" {
+		t.Errorf("expected the authentication of the configuration, got %+v", auth.Auth2)
+	}
+
+	cfg = parse(strings.Replace(testConfig, "stdout://", "postgresql://trading@localhost:5432/db", 1))
+	if err := research.apply(cfg); !errors.Is(err, ErrTenantDestinationDenied) {
+		t.Errorf("expected %v, got %v", ErrTenantDestinationDenied, err)
+	}
+
+	// Commands run on the host of the service, so tenants cannot use them.
+	cfg = parse(testConfig + "    command:\n      path: sh\n")
+	if err := research.apply(cfg); !errors.Is(err, config.ErrLocalOnly) {
+		t.Errorf("expected %v, got %v", config.ErrLocalOnly, err)
+	}
+}
+
+func TestTenantServers(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := ParseTenants([]byte(testTenants))
+	if err != nil {
+		t.Fatalf("failed to parse tenants: %v", err)
+	}
+
+	runFn := func(context.Context, *config.Config) error { return nil }
+
+	t.Run("grpc", func(t *testing.T) {
+		t.Parallel()
+
+		grpcServer := NewTransportServer(runFn, "secret").WithTenants(tenants)
+
+		for _, tcase := range []struct {
+			name  string
+			token string
+			cfg   string
+			code  codes.Code
+		}{
+			{name: "tenant", token: "research-secret", cfg: testConfig, code: codes.OK},
+			{name: "server token", token: "secret", cfg: testConfig, code: codes.Unauthenticated},
+			{name: "denied", token: "trading-secret", cfg: testConfig, code: codes.PermissionDenied},
+		} {
+			ctx := metadata.NewIncomingContext(context.Background(),
+				metadata.Pairs(authorizationKey, "Bearer "+tcase.token))
+
+			_, err := runHandler(grpcServer, ctx, func(req interface{}) error {
+				req.(*wrapperspb.BytesValue).Value = []byte(tcase.cfg)
+
+				return nil
+			}, nil)
+			if status.Code(err) != tcase.code {
+				t.Errorf("%s: expected %v, got %v", tcase.name, tcase.code, err)
+			}
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(NewHTTPServer(runFn, "secret").WithTenants(tenants))
+		t.Cleanup(server.Close)
+
+		do := func(method, path, token, body string) *http.Response {
+			t.Helper()
+
+			req, err := http.NewRequestWithContext(context.Background(), method, server.URL+path,
+				strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rsp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+
+			rsp.Body.Close()
+
+			return rsp
+		}
+
+		rsp := do(http.MethodPost, runsPath, "research-secret", testConfig)
+		if rsp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected the run to start, got %d", rsp.StatusCode)
+		}
+
+		location := rsp.Header.Get("Location")
+
+		if rsp := do(http.MethodGet, location, "research-secret", ""); rsp.StatusCode != http.StatusOK {
+			t.Errorf("expected the tenant to see its run, got %d", rsp.StatusCode)
+		}
+
+		if rsp := do(http.MethodGet, location, "trading-secret", ""); rsp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected other tenants not to see the run, got %d", rsp.StatusCode)
+		}
+
+		if rsp := do(http.MethodPost, runsPath, "trading-secret", testConfig); rsp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected the destination to be denied, got %d", rsp.StatusCode)
+		}
+
+		if rsp := do(http.MethodGet, location, "secret", ""); rsp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected the server token to be unauthorized, got %d", rsp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// TransportServiceName is the full name of the gRPC service that runs transport configurations, which is equivalent
+// to the following definition:
+//
+//	service Transport {
+//		rpc Run(google.protobuf.BytesValue) returns (google.protobuf.Empty);
+//		rpc RunConfig(TransportConfig) returns (google.protobuf.Empty);
+//	}
+//
+// The request of "Run" is the YAML of a transport configuration, and the request of "RunConfig" is its protobuf
+// definition in "config.proto". The configuration is run to completion before the call returns.
+const TransportServiceName = "proto.Transport"
+
+const (
+	runMethod       = "/" + TransportServiceName + "/Run"
+	runConfigMethod = "/" + TransportServiceName + "/RunConfig"
+)
+
+// RunFunc runs a transport configuration.
+type RunFunc func(context.Context, *config.Config) error
+
+// transportServiceDesc describes the transport service for registration on a gRPC server.
+var transportServiceDesc = grpc.ServiceDesc{
+	ServiceName: TransportServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Run",
+			Handler:    runHandler,
+		},
+		{
+			MethodName: "RunConfig",
+			Handler:    runConfigHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// runHandler is the unary gRPC method handler that parses the transport configuration on the request and runs it.
+func runHandler(srv interface{}, ctx context.Context, dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	req := new(wrapperspb.BytesValue)
+	if err := dec(req); err != nil {
+		return nil, fmt.Errorf("unable to decode request: %w", err)
+	}
+
+	call := func(ctx context.Context, req interface{}) (interface{}, error) {
+		server, _ := srv.(*TransportServer)
+
+		tenant, err := server.authorize(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		typedReq, _ := req.(*wrapperspb.BytesValue)
+
+		return server.run(ctx, tenant, typedReq)
+	}
+
+	if interceptor == nil {
+		return call(ctx, req)
+	}
+
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: runMethod}, call)
+}
+
+// runConfigHandler is the unary gRPC method handler that runs the protobuf definition of the transport configuration
+// on the request.
+func runConfigHandler(srv interface{}, ctx context.Context, dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	req := new(proto.TransportConfig)
+	if err := dec(req); err != nil {
+		return nil, fmt.Errorf("unable to decode request: %w", err)
+	}
+
+	call := func(ctx context.Context, req interface{}) (interface{}, error) {
+		server, _ := srv.(*TransportServer)
+
+		tenant, err := server.authorize(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		typedReq, _ := req.(*proto.TransportConfig)
+
+		return server.runConfig(ctx, tenant, typedReq)
+	}
+
+	if interceptor == nil {
+		return call(ctx, req)
+	}
+
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: runConfigMethod}, call)
+}
+
+// TransportServer runs transport configurations sent over gRPC, so that gidari can be run as a shared ingestion
+// service.
+type TransportServer struct {
+	runFn   RunFunc
+	token   string
+	tenants *Tenants
+}
+
+// NewTransportServer will return a server that runs transport configurations with "runFn". If the token is not empty,
+// requests must be authorized with it as a bearer token.
+func NewTransportServer(runFn RunFunc, token string) *TransportServer {
+	return &TransportServer{runFn: runFn, token: token}
+}
+
+// WithTenants will authorize requests with the tokens of the tenants instead of the token of the server, and scope the
+// configuration of each request to its tenant.
+func (server *TransportServer) WithTenants(tenants *Tenants) *TransportServer {
+	server.tenants = tenants
+
+	return server
+}
+
+// Register will register the transport service on the gRPC server.
+func (server *TransportServer) Register(grpcServer *grpc.Server) {
+	grpcServer.RegisterService(&transportServiceDesc, server)
+}
+
+// authorize will return an error if the request does not have the bearer token of the server. If the server has
+// tenants, the tenant whose token the request has is returned.
+func (server *TransportServer) authorize(ctx context.Context) (*Tenant, error) {
+	if server.tenants != nil {
+		return authorizeTenant(ctx, server.tenants)
+	}
+
+	return nil, authorizeToken(ctx, server.token)
+}
+
+// run will parse and run the transport configuration on the request. Configurations that use features that are only
+// supported in local configurations, e.g. commands, are denied.
+func (server *TransportServer) run(ctx context.Context, tenant *Tenant,
+	req *wrapperspb.BytesValue,
+) (*emptypb.Empty, error) {
+	cfg, err := config.ParseRemote(ctx, req.GetValue())
+	if errors.Is(err, config.ErrLocalOnly) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid configuration: %v", err)
+	}
+
+	return server.transport(ctx, tenant, cfg)
+}
+
+// runConfig will convert and run the protobuf definition of the transport configuration.
+func (server *TransportServer) runConfig(ctx context.Context, tenant *Tenant,
+	req *proto.TransportConfig,
+) (*emptypb.Empty, error) {
+	cfg, err := config.FromProto(ctx, req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid configuration: %v", err)
+	}
+
+	return server.transport(ctx, tenant, cfg)
+}
+
+// transport will run the transport configuration to completion, scoped to the tenant if there is one.
+func (server *TransportServer) transport(ctx context.Context, tenant *Tenant,
+	cfg *config.Config,
+) (*emptypb.Empty, error) {
+	if tenant != nil {
+		if err := tenant.apply(cfg); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+
+	if err := server.runFn(ctx, cfg); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to transport data: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
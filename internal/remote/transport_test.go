@@ -0,0 +1,165 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testConfig = `
+url: https://api.example.com
+connectionStrings: ["stdout://"]
+rateLimit:
+  burst: 1
+  period: 1s
+requests:
+  - endpoint: /products/candles
+`
+
+// newTestTransportConn will serve the transport service over an in-memory connection and return a connection to it.
+func newTestTransportConn(t *testing.T, runFn RunFunc) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+
+	grpcServer := grpc.NewServer()
+	NewTransportServer(runFn, "secret").Register(grpcServer)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_ = grpcServer.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		wg.Wait()
+	})
+
+	return conn
+}
+
+func TestTransportServer(t *testing.T) {
+	t.Parallel()
+
+	var ran *config.Config
+
+	conn := newTestTransportConn(t, func(_ context.Context, cfg *config.Config) error {
+		if cfg.Requests[0].Table == "fail" {
+			return fmt.Errorf("web API unavailable")
+		}
+
+		ran = cfg
+
+		return nil
+	})
+
+	run := func(token, cfg string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+token)
+
+		return conn.Invoke(ctx, runMethod, wrapperspb.Bytes([]byte(cfg)), &emptypb.Empty{})
+	}
+
+	if err := run("secret", testConfig); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+
+	if ran == nil || ran.URL.Host != "api.example.com" || ran.Requests[0].Table != "candles" {
+		t.Errorf("expected the configuration to be run, got %+v", ran)
+	}
+
+	for _, tcase := range []struct {
+		name  string
+		token string
+		cfg   string
+		code  codes.Code
+	}{
+		{name: "unauthenticated", token: "wrong", cfg: testConfig, code: codes.Unauthenticated},
+		{name: "invalid", token: "secret", cfg: "url: https://api.example.com", code: codes.InvalidArgument},
+		{name: "failed", token: "secret", cfg: testConfig + "    table: fail\n", code: codes.Internal},
+		{name: "command", token: "secret", cfg: testConfig + "    command:\n      path: sh\n", code: codes.PermissionDenied},
+		{name: "schema file", token: "secret", cfg: testConfig + "tables:\n  candles:\n    schemaFile: /etc/passwd\n",
+			code: codes.PermissionDenied},
+		{name: "checkpoint", token: "secret", cfg: testConfig + "checkpoint: /etc/passwd\n", code: codes.PermissionDenied},
+		{name: "dead letter dir", token: "secret", cfg: testConfig + "deadLetter:\n  dir: /etc\n", code: codes.PermissionDenied},
+		{name: "queue dir", token: "secret", cfg: testConfig + "queueDir: /etc\n", code: codes.PermissionDenied},
+		{name: "spill dir", token: "secret", cfg: testConfig + "spillDir: /etc\n", code: codes.PermissionDenied},
+		{name: "script file", token: "secret", cfg: testConfig + "    script:\n      file: /etc/passwd\n", code: codes.PermissionDenied},
+		{name: "protobuf descriptor", token: "secret", cfg: testConfig + "    protobuf:\n      descriptor: /etc/passwd\n",
+			code: codes.PermissionDenied},
+	} {
+		if err := run(tcase.token, tcase.cfg); status.Code(err) != tcase.code {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.code, err)
+		}
+	}
+}
+
+func TestTransportServerRunConfig(t *testing.T) {
+	t.Parallel()
+
+	var ran *config.Config
+
+	conn := newTestTransportConn(t, func(_ context.Context, cfg *config.Config) error {
+		ran = cfg
+
+		return nil
+	})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer secret")
+
+	msg := &proto.TransportConfig{
+		Url:               "https://api.example.com",
+		ConnectionStrings: []string{"stdout://"},
+		RateLimit:         &proto.TransportConfig_RateLimit{Burst: 1, Period: durationpb.New(time.Second)},
+		Requests:          []*proto.TransportConfig_Request{{Endpoint: "/products/candles"}},
+	}
+
+	if err := conn.Invoke(ctx, runConfigMethod, msg, &emptypb.Empty{}); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+
+	if ran == nil || ran.URL.Host != "api.example.com" || ran.Requests[0].Table != "candles" {
+		t.Errorf("expected the configuration to be run, got %+v", ran)
+	}
+
+	err := conn.Invoke(ctx, runConfigMethod, &proto.TransportConfig{Url: "https://api.example.com"}, &emptypb.Empty{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected %v, got %v", codes.InvalidArgument, err)
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// Statuses of a run in a report.
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunSkipped   = "skipped"
+)
+
+const (
+	percent = 100
+
+	// columnPadding is the number of spaces between the columns of a summary.
+	columnPadding = 2
+)
+
+// Report is a machine-readable record of the runs of one invocation, e.g. for auditing by an ingestion pipeline.
+type Report struct {
+	Runs []*Run `json:"runs"`
+}
+
+// Run is the record of the run of a configuration.
+type Run struct {
+	mutex sync.Mutex
+	now   func() time.Time
+
+	// profile is whether the time that the chunks of each request spent in each stage is recorded.
+	profile bool
+
+	// Config is the path of the configuration that was run.
+	Config string `json:"config"`
+
+	// Status is "succeeded", "failed", or "skipped" if no requests matched the filters of the run.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Duration   string    `json:"duration"`
+
+	// Requests are the records of each request of the run, in the order they were first reported.
+	Requests []*Request `json:"requests"`
+}
+
+// Request is the record of a request of a run.
+type Request struct {
+	Endpoint string `json:"endpoint"`
+	Table    string `json:"table"`
+
+	// Status is "committed" or "rolledBack", or empty if the run stopped before the request was written.
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// URLs is the number of requests to the web API that the request is split into, and Chunks is the number of
+	// them that were fetched, or failed to be fetched.
+	URLs   int `json:"urls"`
+	Chunks int `json:"chunks"`
+
+	// Bytes is the size of the bodies of the chunks that were fetched.
+	Bytes int64 `json:"bytes"`
+
+	// FetchLatencyP50 and FetchLatencyP95 are the median and 95th percentile of how long the chunks took to be
+	// fetched, without waiting for the rate limiter.
+	FetchLatencyP50 string `json:"fetchLatencyP50,omitempty"`
+	FetchLatencyP95 string `json:"fetchLatencyP95,omitempty"`
+
+	Received int64 `json:"received"`
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Failed   int64 `json:"failed"`
+
+	// Upserted is the number of records that were upserted or matched on every destination.
+	Upserted int64 `json:"upserted"`
+
+	// Errors are the number of errors of the request in each category, see "config.RequestProgress".
+	Errors map[string]int `json:"errors,omitempty"`
+
+	// Duration is how long the request took, from the start of the run until its writes were committed or rolled
+	// back.
+	Duration string `json:"duration,omitempty"`
+
+	// Stages are how long the chunks of the request spent in each stage of the run, if the run is profiled.
+	Stages *Stages `json:"stages,omitempty"`
+
+	latencies []time.Duration
+	elapsed   time.Duration
+	stages    config.StageTimings
+}
+
+// Stages are how long the chunks of a request spent in each stage of a run, see "config.StageTimings".
+type Stages struct {
+	Fetch     string `json:"fetch"`
+	Decode    string `json:"decode"`
+	Transform string `json:"transform"`
+	Write     string `json:"write"`
+}
+
+// NewRun will start the record of the run of the configuration at the path.
+func NewRun(path string) *Run {
+	return &Run{Config: path, StartedAt: time.Now().UTC(), now: time.Now, Requests: []*Request{}}
+}
+
+// Profile will record the time that the chunks of each request spend in each stage of the run, to find the stage that
+// bounds it.
+func (run *Run) Profile() {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.profile = true
+}
+
+// Watch will record the progress of the run of the configuration. The progress functions of the configuration are
+// still called.
+func (run *Run) Watch(cfg *config.Config) {
+	progress, onFetch, onUpsert := cfg.Progress, cfg.OnFetch, cfg.OnUpsert
+
+	cfg.OnFetch = func(fetch config.FetchProgress) {
+		run.fetch(fetch)
+
+		if onFetch != nil {
+			onFetch(fetch)
+		}
+	}
+
+	cfg.OnUpsert = func(upsert config.UpsertProgress) {
+		run.upsert(upsert)
+
+		if onUpsert != nil {
+			onUpsert(upsert)
+		}
+	}
+
+	cfg.Progress = func(rsp config.RequestProgress) {
+		run.request(rsp)
+
+		if progress != nil {
+			progress(rsp)
+		}
+	}
+}
+
+// Finish will record the outcome of the run.
+func (run *Run) Finish(err error, skipped bool) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.FinishedAt = run.now().UTC()
+	run.Duration = run.FinishedAt.Sub(run.StartedAt).String()
+
+	for _, req := range run.Requests {
+		if len(req.latencies) == 0 {
+			continue
+		}
+
+		sort.Slice(req.latencies, func(i, j int) bool { return req.latencies[i] < req.latencies[j] })
+
+		req.FetchLatencyP50 = percentile(req.latencies, 50).String()
+		req.FetchLatencyP95 = percentile(req.latencies, 95).String()
+	}
+
+	switch {
+	case err != nil:
+		run.Status = RunFailed
+		run.Error = err.Error()
+	case skipped:
+		run.Status = RunSkipped
+	default:
+		run.Status = RunSucceeded
+	}
+}
+
+// fetch will record the progress of fetching the chunks of a request.
+func (run *Run) fetch(fetch config.FetchProgress) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	req := run.lookup(fetch.Endpoint, fetch.Table)
+	req.URLs = fetch.Chunks
+	req.Bytes += fetch.Bytes
+
+	if fetch.Latency > 0 {
+		req.latencies = append(req.latencies, fetch.Latency)
+	}
+
+	if fetch.Fetched > req.Chunks {
+		req.Chunks = fetch.Fetched
+	}
+}
+
+// upsert will record the records of a request that were upserted.
+func (run *Run) upsert(upsert config.UpsertProgress) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.lookup(upsert.Endpoint, upsert.Table).Upserted += upsert.Upserted
+}
+
+// request will record the outcome of a request.
+func (run *Run) request(rsp config.RequestProgress) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	req := run.lookup(rsp.Endpoint, rsp.Table)
+	req.Status = rsp.Status
+	req.Error = rsp.Error
+	req.Received = rsp.Received
+	req.Inserted = rsp.Inserted
+	req.Updated = rsp.Updated
+	req.Failed = rsp.Failed
+	req.Errors = rsp.Errors
+	req.elapsed = run.now().Sub(run.StartedAt)
+	req.Duration = req.elapsed.String()
+	req.stages = rsp.Stages
+
+	if run.profile {
+		req.Stages = &Stages{
+			Fetch:     rsp.Stages.Fetch.String(),
+			Decode:    rsp.Stages.Decode.String(),
+			Transform: rsp.Stages.Transform.String(),
+			Write:     rsp.Stages.Write.String(),
+		}
+	}
+}
+
+// percentile will return the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	rank := (len(sorted)*pct + percent - 1) / percent
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// lookup will return the record of the request, adding it if it has not been reported yet. The caller must hold the
+// mutex.
+func (run *Run) lookup(endpoint, table string) *Request {
+	for _, req := range run.Requests {
+		if req.Endpoint == endpoint && req.Table == table {
+			return req
+		}
+	}
+
+	req := &Request{Endpoint: endpoint, Table: table}
+	run.Requests = append(run.Requests, req)
+
+	return req
+}
+
+// WriteSummary will write a table of the requests of the run, with the chunks of each request, the median and 95th
+// percentile of how long they took to be fetched, the records written per second, how long the request took, and its
+// errors by category, so that the effect of tuning the rate limit or the workers of a configuration can be seen. The
+// time spent in each stage is added if the run is profiled.
+func (run *Run) WriteSummary(wtr io.Writer) error {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "%s: %s in %s\n", run.Config, run.Status, run.Duration)
+
+	if len(run.Requests) > 0 {
+		table := tabwriter.NewWriter(&text, 0, 0, columnPadding, ' ', 0)
+
+		header := "ENDPOINT\tTABLE\tCHUNKS\tFETCH P50\tFETCH P95\tRECORDS/S\tTIME\tERRORS"
+		if run.profile {
+			header += "\tFETCH\tDECODE\tTRANSFORM\tWRITE"
+		}
+
+		fmt.Fprintln(table, header)
+
+		for _, req := range run.Requests {
+			rate := "-"
+			if req.elapsed > 0 {
+				rate = fmt.Sprintf("%.1f", float64(req.Upserted)/req.elapsed.Seconds())
+			}
+
+			fmt.Fprintf(table, "%s\t%s\t%d/%d\t%s\t%s\t%s\t%s\t%s", req.Endpoint, req.Table, req.Chunks, req.URLs,
+				orDash(req.FetchLatencyP50), orDash(req.FetchLatencyP95), rate, orDash(req.Duration),
+				orDash(errorCounts(req.Errors)))
+
+			if run.profile {
+				stages := req.Stages
+				if stages == nil {
+					stages = &Stages{}
+				}
+
+				fmt.Fprintf(table, "\t%s\t%s\t%s\t%s", orDash(stages.Fetch), orDash(stages.Decode),
+					orDash(stages.Transform), orDash(stages.Write))
+			}
+
+			fmt.Fprintln(table)
+		}
+
+		if err := table.Flush(); err != nil {
+			return fmt.Errorf("unable to format summary: %w", err)
+		}
+	}
+
+	if _, err := io.WriteString(wtr, text.String()); err != nil {
+		return fmt.Errorf("unable to write summary: %w", err)
+	}
+
+	return nil
+}
+
+// WriteStages will write the throughput of each stage of the run: how long the chunks of its requests spent in the
+// stage, and the records and bytes per second that went through the stage in that time, so that the stage that bounds
+// the run can be found. The time of a stage is summed over the chunks, which are fetched and written concurrently, so
+// the throughput is that of a single worker.
+func (run *Run) WriteStages(wtr io.Writer) error {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	var (
+		totals  config.StageTimings
+		records int64
+		bytes   int64
+	)
+
+	for _, req := range run.Requests {
+		totals.Fetch += req.stages.Fetch
+		totals.Decode += req.stages.Decode
+		totals.Transform += req.stages.Transform
+		totals.Write += req.stages.Write
+		records += req.Received
+		bytes += req.Bytes
+	}
+
+	var text strings.Builder
+
+	table := tabwriter.NewWriter(&text, 0, 0, columnPadding, ' ', 0)
+	fmt.Fprintln(table, "STAGE\tTIME\tRECORDS/S\tBYTES/S")
+
+	for _, stage := range []struct {
+		name string
+		time time.Duration
+	}{
+		{"fetch", totals.Fetch},
+		{"decode", totals.Decode},
+		{"transform", totals.Transform},
+		{"write", totals.Write},
+	} {
+		recordRate, byteRate := "-", "-"
+		if stage.time > 0 {
+			recordRate = fmt.Sprintf("%.1f", float64(records)/stage.time.Seconds())
+			byteRate = fmt.Sprintf("%.0f", float64(bytes)/stage.time.Seconds())
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", stage.name, stage.time, recordRate, byteRate)
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("unable to format stages: %w", err)
+	}
+
+	if _, err := io.WriteString(wtr, text.String()); err != nil {
+		return fmt.Errorf("unable to write stages: %w", err)
+	}
+
+	return nil
+}
+
+// errorCounts will return the number of errors in each category in order, e.g. "2 http 429, 1 decode".
+func errorCounts(errs map[string]int) string {
+	categories := make([]string, 0, len(errs))
+	for category := range errs {
+		categories = append(categories, category)
+	}
+
+	sort.Strings(categories)
+
+	counts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		counts = append(counts, fmt.Sprintf("%d %s", errs[category], category))
+	}
+
+	return strings.Join(counts, ", ")
+}
+
+// orDash will return the value, or "-" if it is empty.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// WriteFile will write the report as indented JSON to the file at the path, replacing it if it exists.
+func (report *Report) WriteFile(path string) error {
+	bytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(bytes, '\n'), 0o600); err != nil {
+		return fmt.Errorf("unable to write report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	run := NewRun("candles.yaml")
+	run.StartedAt = start
+	run.now = func() time.Time { return now }
+
+	var fetches, upserts, requests int
+
+	cfg := &config.Config{
+		OnFetch:  func(config.FetchProgress) { fetches++ },
+		OnUpsert: func(config.UpsertProgress) { upserts++ },
+		Progress: func(config.RequestProgress) { requests++ },
+	}
+
+	run.Watch(cfg)
+
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Chunks: 2})
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/trades", Table: "trades", Chunks: 1})
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Fetched: 1, Chunks: 2, Bytes: 100,
+		Latency: 300 * time.Millisecond})
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Fetched: 2, Chunks: 2, Bytes: 50,
+		Latency: 100 * time.Millisecond})
+	cfg.OnUpsert(config.UpsertProgress{Endpoint: "/candles", Table: "candles", Upserted: 3})
+
+	now = start.Add(time.Second)
+	cfg.Progress(config.RequestProgress{Endpoint: "/candles", Table: "candles", Status: config.RequestCommitted,
+		Received: 3, Inserted: 2, Updated: 1})
+
+	now = start.Add(2 * time.Second)
+	cfg.Progress(config.RequestProgress{Endpoint: "/trades", Table: "trades", Status: config.RequestRolledBack,
+		Error: "404", Errors: map[string]int{"http 404": 1}})
+
+	run.Finish(errors.New("1 of 2 requests failed"), false)
+
+	if fetches != 4 || upserts != 1 || requests != 2 {
+		t.Errorf("expected the progress functions to still be called, got %d fetches, %d upserts, and %d requests",
+			fetches, upserts, requests)
+	}
+
+	want := []*Request{
+		{
+			Endpoint: "/candles", Table: "candles", Status: config.RequestCommitted, URLs: 2, Chunks: 2, Bytes: 150,
+			FetchLatencyP50: "100ms", FetchLatencyP95: "300ms", Received: 3, Inserted: 2, Updated: 1, Upserted: 3,
+			Duration: "1s", latencies: []time.Duration{100 * time.Millisecond, 300 * time.Millisecond},
+			elapsed: time.Second,
+		},
+		{
+			Endpoint: "/trades", Table: "trades", Status: config.RequestRolledBack, Error: "404", URLs: 1,
+			Errors: map[string]int{"http 404": 1}, Duration: "2s", elapsed: 2 * time.Second,
+		},
+	}
+	if !reflect.DeepEqual(run.Requests, want) {
+		t.Errorf("expected requests %+v, got %+v", want, run.Requests)
+	}
+
+	if run.Status != RunFailed || run.Error != "1 of 2 requests failed" || run.Duration != "2s" {
+		t.Errorf("expected the run to fail after 2s, got %s %q after %s", run.Status, run.Error, run.Duration)
+	}
+
+	var summary strings.Builder
+	if err := run.WriteSummary(&summary); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	wantSummary := "candles.yaml: failed in 2s\n" +
+		"ENDPOINT  TABLE    CHUNKS  FETCH P50  FETCH P95  RECORDS/S  TIME  ERRORS\n" +
+		"/candles  candles  2/2     100ms      300ms      3.0        1s    -\n" +
+		"/trades   trades   0/1     -          -          0.0        2s    1 http 404\n"
+	if summary.String() != wantSummary {
+		t.Errorf("expected summary:\n%s\ngot:\n%s", wantSummary, summary.String())
+	}
+
+	skipped := NewRun("trades.yaml")
+	skipped.Finish(nil, true)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := (&Report{Runs: []*Run{run, skipped}}).WriteFile(path); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+
+	var written Report
+	if err := json.Unmarshal(bytes, &written); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+
+	if len(written.Runs) != 2 || written.Runs[0].Config != "candles.yaml" || len(written.Runs[0].Requests) != 2 ||
+		written.Runs[1].Status != RunSkipped {
+		t.Errorf("expected both runs in the report, got %s", bytes)
+	}
+}
+
+func TestRunProfile(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	run := NewRun("candles.yaml")
+	run.StartedAt = start
+	run.now = func() time.Time { return start.Add(time.Second) }
+	run.Profile()
+
+	cfg := &config.Config{}
+	run.Watch(cfg)
+
+	cfg.Progress(config.RequestProgress{
+		Endpoint: "/candles",
+		Table:    "candles",
+		Status:   config.RequestCommitted,
+		Stages: config.StageTimings{
+			Fetch:     800 * time.Millisecond,
+			Decode:    20 * time.Millisecond,
+			Transform: 50 * time.Millisecond,
+			Write:     1500 * time.Millisecond,
+		},
+	})
+
+	run.Finish(nil, false)
+
+	want := &Stages{Fetch: "800ms", Decode: "20ms", Transform: "50ms", Write: "1.5s"}
+	if stages := run.Requests[0].Stages; !reflect.DeepEqual(stages, want) {
+		t.Errorf("expected stages %+v, got %+v", want, stages)
+	}
+
+	var summary strings.Builder
+	if err := run.WriteSummary(&summary); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	wantSummary := "candles.yaml: succeeded in 1s\n" +
+		"ENDPOINT  TABLE    CHUNKS  FETCH P50  FETCH P95  RECORDS/S  TIME  ERRORS  FETCH  DECODE  TRANSFORM  WRITE\n" +
+		"/candles  candles  0/0     -          -          0.0        1s    -       800ms  20ms    50ms       1.5s\n"
+	if summary.String() != wantSummary {
+		t.Errorf("expected summary:\n%s\ngot:\n%s", wantSummary, summary.String())
+	}
+}
+
+func TestRunStages(t *testing.T) {
+	t.Parallel()
+
+	run := NewRun("candles.yaml")
+
+	cfg := &config.Config{}
+	run.Watch(cfg)
+
+	cfg.OnFetch(config.FetchProgress{Endpoint: "/candles", Table: "candles", Chunks: 1, Fetched: 1, Bytes: 4000})
+
+	for _, table := range []string{"candles", "trades"} {
+		cfg.Progress(config.RequestProgress{
+			Endpoint: "/" + table,
+			Table:    table,
+			Status:   config.RequestCommitted,
+			Received: 500,
+			Stages: config.StageTimings{
+				Fetch:     time.Second,
+				Decode:    250 * time.Millisecond,
+				Transform: 0,
+				Write:     4 * time.Second,
+			},
+		})
+	}
+
+	var stages strings.Builder
+	if err := run.WriteStages(&stages); err != nil {
+		t.Fatalf("failed to write stages: %v", err)
+	}
+
+	want := "STAGE      TIME   RECORDS/S  BYTES/S\n" +
+		"fetch      2s     500.0      2000\n" +
+		"decode     500ms  2000.0     8000\n" +
+		"transform  0s     -          -\n" +
+		"write      8s     125.0      500\n"
+	if stages.String() != want {
+		t.Errorf("expected stages:\n%s\ngot:\n%s", want, stages.String())
+	}
+}
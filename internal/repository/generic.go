@@ -11,9 +11,14 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/alpstable/gidari/internal/file"
 	"github.com/alpstable/gidari/internal/mongo"
+	"github.com/alpstable/gidari/internal/object"
+	"github.com/alpstable/gidari/internal/pipe"
 	"github.com/alpstable/gidari/internal/postgres"
 	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/remote"
+	"github.com/alpstable/gidari/internal/webhook"
 )
 
 // ErrFailedToCreateRepository is returned when the repository layer fails to create a new repository.
@@ -34,6 +39,24 @@ type Generic interface {
 	proto.Transactor
 
 	Transact(fn func(ctx context.Context, repo Generic) error)
+
+	// Reconcile will flag the records of a table that were not fetched as deleted, if the storage supports it.
+	Reconcile(ctx context.Context, req *proto.ReconcileRequest) (*proto.ReconcileResponse, error)
+
+	// Delete will delete the records of a table that match a filter, if the storage supports it.
+	Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error)
+
+	// ListColumns will list the columns of each table and their types, if the storage has a schema.
+	ListColumns(ctx context.Context) (*proto.ListColumnsResponse, error)
+
+	// Query will read the records of a table that match a filter, if the storage supports it.
+	Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error)
+
+	// ConfigurePool will configure the connection pool of the storage, if the storage supports it.
+	ConfigurePool(ctx context.Context, req *proto.PoolRequest) error
+
+	// DefineTable will declare the schema of a table, if the storage creates its tables from one.
+	DefineTable(ctx context.Context, req *proto.DefineTableRequest) error
 }
 
 // GenericService is the implementation of the Generic service.
@@ -62,8 +85,67 @@ func NewStorage(ctx context.Context, dns string) (*proto.StorageService, error)
 		}
 
 		stg = &proto.StorageService{Storage: pdb}
+	case proto.SchemeFromStorageType(proto.FileType):
+		fdb, err := file.New(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct file storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: fdb}
+	case proto.SchemeFromStorageType(proto.S3Type):
+		sdb, err := object.NewS3(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct s3 storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: sdb}
+	case proto.SchemeFromStorageType(proto.GCSType):
+		gdb, err := object.NewGCS(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct gcs storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: gdb}
+	case proto.SchemeFromStorageType(proto.AzureBlobType):
+		adb, err := object.NewAzureBlob(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct azure blob storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: adb}
+	case proto.SchemeFromStorageType(proto.StdoutType):
+		pdb, err := pipe.New(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct stdout storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: pdb}
+	case proto.SchemeFromStorageType(proto.WebhookType):
+		wdb, err := webhook.New(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct webhook storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: wdb}
+	case proto.SchemeFromStorageType(proto.GRPCType):
+		rdb, err := remote.New(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct grpc storage: %w", err)
+		}
+
+		stg = &proto.StorageService{Storage: rdb}
 	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnkownScheme, scheme)
+		constructor, ok := proto.LookupConstructor(scheme)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnkownScheme, scheme)
+		}
+
+		var err error
+
+		stg, err = constructor(ctx, dns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct %s storage: %w", scheme, err)
+		}
 	}
 
 	return stg, nil
@@ -87,12 +169,18 @@ func NewTx(ctx context.Context, dns string) (*GenericService, error) {
 		return nil, fmt.Errorf("failed to construct storage: %w", err)
 	}
 
-	tx, err := stg.StartTx(ctx)
+	return (&GenericService{stg, nil}).BeginTx(ctx)
+}
+
+// BeginTx returns a new Generic service on the same storage as svc, with a new transaction object. This can be used to
+// run several independent transactions on a single storage connection.
+func (svc *GenericService) BeginTx(ctx context.Context) (*GenericService, error) {
+	tx, err := svc.Storage.StartTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 
-	return &GenericService{stg, tx}, nil
+	return &GenericService{svc.Storage, tx}, nil
 }
 
 // Transact is a helper function that wraps a function in a transaction and commits or rolls back the transaction. If
@@ -117,3 +205,69 @@ func (svc *GenericService) Truncate(ctx context.Context, req *proto.TruncateRequ
 
 	return rsp, nil
 }
+
+// Reconcile flags the records of a table that were not fetched as deleted. If the storage does not support it,
+// "proto.ErrReconcileNotSupported" is returned.
+func (svc *GenericService) Reconcile(ctx context.Context,
+	req *proto.ReconcileRequest,
+) (*proto.ReconcileResponse, error) {
+	rsp, err := proto.Reconcile(ctx, svc.Storage, req)
+	if err != nil {
+		return nil, fmt.Errorf("error reconciling table: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// Delete deletes the records of a table that match a filter. If the storage does not support it,
+// "proto.ErrDeleteNotSupported" is returned.
+func (svc *GenericService) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	rsp, err := proto.Delete(ctx, svc.Storage, req)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting records: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// ListColumns lists the columns of each table and their types. If the storage does not have a schema,
+// "proto.ErrListColumnsNotSupported" is returned.
+func (svc *GenericService) ListColumns(ctx context.Context) (*proto.ListColumnsResponse, error) {
+	rsp, err := proto.ListColumns(ctx, svc.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error listing columns: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// Query reads the records of a table that match a filter. If the storage does not support it,
+// "proto.ErrQueryNotSupported" is returned.
+func (svc *GenericService) Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	rsp, err := proto.Query(ctx, svc.Storage, req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying table: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// ConfigurePool configures the connection pool of the storage. If the storage does not support it,
+// "proto.ErrPoolNotSupported" is returned.
+func (svc *GenericService) ConfigurePool(ctx context.Context, req *proto.PoolRequest) error {
+	if err := proto.ConfigurePool(ctx, svc.Storage, req); err != nil {
+		return fmt.Errorf("error configuring pool: %w", err)
+	}
+
+	return nil
+}
+
+// DefineTable declares the schema of a table. If the storage does not create its tables from a declared schema,
+// "proto.ErrDefineTableNotSupported" is returned.
+func (svc *GenericService) DefineTable(ctx context.Context, req *proto.DefineTableRequest) error {
+	if err := proto.DefineTable(ctx, svc.Storage, req); err != nil {
+		return fmt.Errorf("error defining table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+
+// Package selector selects the records of a JSON response that are wrapped in an envelope, with a subset of JSONPath:
+// the root "$", fields ".name" or "['name']", indexes "[0]" or "[-1]", and wildcards ".*" or "[*]".
+package selector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalid = fmt.Errorf("invalid selector")
+	ErrNoMatch = fmt.Errorf("selector matched nothing")
+	ErrNotJSON = fmt.Errorf("data is not JSON")
+)
+
+// stepKind is the kind of a step of a selector.
+type stepKind uint8
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	stepWildcard
+)
+
+// step selects a field of an object, an element of a list, or every field or element.
+type step struct {
+	kind  stepKind
+	field string
+	index int
+}
+
+// Selector is a parsed selector.
+type Selector struct {
+	path     string
+	steps    []step
+	wildcard bool
+}
+
+// Parse will parse the selector, e.g. "$.data.items". An empty selector returns nil, which selects the data as is.
+func Parse(path string) (*Selector, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%w: %q must start with \"$\"", ErrInvalid, path)
+	}
+
+	sel := &Selector{path: path}
+
+	for rest := path[1:]; rest != ""; {
+		var (
+			next step
+			err  error
+		)
+
+		switch rest[0] {
+		case '.':
+			next, rest, err = parseField(rest[1:])
+		case '[':
+			next, rest, err = parseBracket(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalid, path, err)
+		}
+
+		sel.steps = append(sel.steps, next)
+		sel.wildcard = sel.wildcard || next.kind == stepWildcard
+	}
+
+	return sel, nil
+}
+
+// parseField will parse the field that follows a dot, returning what is left of the selector.
+func parseField(rest string) (step, string, error) {
+	if strings.HasPrefix(rest, "*") {
+		return step{kind: stepWildcard}, rest[1:], nil
+	}
+
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	if end == 0 {
+		return step{}, "", fmt.Errorf("empty field, recursive descent is not supported")
+	}
+
+	return step{kind: stepField, field: rest[:end]}, rest[end:], nil
+}
+
+// parseBracket will parse the wildcard, quoted field, or index that follows an opening bracket, returning what is
+// left of the selector.
+func parseBracket(rest string) (step, string, error) {
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return step{}, "", fmt.Errorf("unclosed bracket")
+	}
+
+	inner, rest := rest[:end], rest[end+1:]
+
+	switch {
+	case inner == "*":
+		return step{kind: stepWildcard}, rest, nil
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return step{kind: stepField, field: inner[1 : len(inner)-1]}, rest, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, "", fmt.Errorf("invalid index %q", inner)
+	}
+
+	return step{kind: stepIndex, index: index}, rest, nil
+}
+
+// String will return the selector as it was parsed.
+func (sel *Selector) String() string {
+	return sel.path
+}
+
+// Select will return the JSON value that the selector selects from the data. If the selector has a wildcard, a list
+// of the values it matched is returned, with the elements of the values that are lists in place of the lists, so that
+// the records of several envelopes are selected as one list. An error that matches "ErrNoMatch" is returned if a
+// selector without a wildcard matches nothing, and one that matches "ErrNotJSON" if the data is not JSON. A nil
+// selector returns the data as is.
+func (sel *Selector) Select(data []byte) ([]byte, error) {
+	if sel == nil {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotJSON, err)
+	}
+
+	matches := []interface{}{root}
+	for _, next := range sel.steps {
+		matches = next.apply(matches)
+	}
+
+	var selected interface{}
+
+	switch {
+	case sel.wildcard:
+		records := make([]interface{}, 0, len(matches))
+
+		for _, match := range matches {
+			if list, ok := match.([]interface{}); ok {
+				records = append(records, list...)
+			} else {
+				records = append(records, match)
+			}
+		}
+
+		selected = records
+	case len(matches) == 0:
+		return nil, fmt.Errorf("%w: %q", ErrNoMatch, sel.path)
+	default:
+		selected = matches[0]
+	}
+
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal selected data: %w", err)
+	}
+
+	return data, nil
+}
+
+// apply will return the values that the step selects from each of the values.
+func (next step) apply(vals []interface{}) []interface{} {
+	var matches []interface{}
+
+	for _, val := range vals {
+		switch val := val.(type) {
+		case map[string]interface{}:
+			switch next.kind {
+			case stepField:
+				if field, ok := val[next.field]; ok {
+					matches = append(matches, field)
+				}
+			case stepWildcard:
+				keys := make([]string, 0, len(val))
+				for key := range val {
+					keys = append(keys, key)
+				}
+
+				sort.Strings(keys)
+
+				for _, key := range keys {
+					matches = append(matches, val[key])
+				}
+			case stepIndex:
+			}
+		case []interface{}:
+			switch next.kind {
+			case stepIndex:
+				index := next.index
+				if index < 0 {
+					index += len(val)
+				}
+
+				if index >= 0 && index < len(val) {
+					matches = append(matches, val[index])
+				}
+			case stepWildcard:
+				matches = append(matches, val...)
+			case stepField:
+			}
+		}
+	}
+
+	return matches
+}
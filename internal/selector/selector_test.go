@@ -0,0 +1,89 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package selector
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{"$", "$.data.items", "$['data'][\"items\"]", "$.results[0].items", "$.pages[*].items",
+		"$.*", "$[-1]"} {
+		sel, err := Parse(path)
+		if err != nil {
+			t.Errorf("%q: failed to parse: %v", path, err)
+
+			continue
+		}
+
+		if sel.String() != path {
+			t.Errorf("%q: expected the path, got %q", path, sel.String())
+		}
+	}
+
+	for _, path := range []string{"data.items", "$..items", "$.", "$[0", "$[x]", "$data"} {
+		if _, err := Parse(path); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: expected %v, got %v", path, ErrInvalid, err)
+		}
+	}
+
+	if sel, err := Parse(""); sel != nil || err != nil {
+		t.Errorf("expected no selector, got %v and %v", sel, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{"data":{"items":[{"id":1,"px":1.50},{"id":2}],"next":"abc"},` +
+		`"pages":[{"items":[{"id":3}]},{"items":[{"id":4},{"id":5}]},{"items":{"id":6}}]}`)
+
+	for _, tcase := range []struct {
+		path     string
+		expected string
+		wantErr  error
+	}{
+		{path: "$.data.items", expected: `[{"id":1,"px":1.50},{"id":2}]`},
+		{path: "$['data']['next']", expected: `"abc"`},
+		{path: "$.data.items[-1]", expected: `{"id":2}`},
+		{path: "$.pages[*].items", expected: `[{"id":3},{"id":4},{"id":5},{"id":6}]`},
+		{path: "$.pages[5].items[*]", expected: `[]`},
+		{path: "$.data.records", wantErr: ErrNoMatch},
+		{path: "$.data.items[2]", wantErr: ErrNoMatch},
+	} {
+		sel, err := Parse(tcase.path)
+		if err != nil {
+			t.Fatalf("%q: failed to parse: %v", tcase.path, err)
+		}
+
+		selected, err := sel.Select(data)
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%q: expected %v, got %v", tcase.path, tcase.wantErr, err)
+		}
+
+		if string(selected) != tcase.expected {
+			t.Errorf("%q: expected %s, got %s", tcase.path, tcase.expected, selected)
+		}
+	}
+
+	sel, _ := Parse("$.data")
+	if _, err := sel.Select([]byte(`not json`)); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("expected %v, got %v", ErrNotJSON, err)
+	}
+
+	var nilSel *Selector
+
+	if selected, err := nilSel.Select([]byte(`not json`)); string(selected) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", selected, err)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// DefaultPrefix is the prefix of the name of every metric, unless another prefix is set.
+const DefaultPrefix = "gidari"
+
+// tagReplacer replaces the separators of the format in the values of tags.
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_")
+
+// Client emits the metrics of runs to a StatsD agent over UDP, e.g. for deployments that do not scrape Prometheus.
+// Metrics are tagged in the DogStatsD format with the table, the host of the web API, and the status of the request,
+// which the Datadog agent, Telegraf, and the Prometheus statsd_exporter all accept. Metrics are sent without waiting
+// for the agent, and are dropped if it is not running, so that a run never fails because of its metrics.
+type Client struct {
+	conn   net.Conn
+	prefix string
+}
+
+// Dial will return a client that emits metrics to the agent at the address, e.g. "localhost:8125", with the prefix
+// added to the name of each metric.
+func Dial(addr, prefix string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial statsd agent: %w", err)
+	}
+
+	return &Client{conn: conn, prefix: prefix}, nil
+}
+
+// Close will close the connection to the agent.
+func (client *Client) Close() error {
+	if err := client.conn.Close(); err != nil {
+		return fmt.Errorf("unable to close statsd connection: %w", err)
+	}
+
+	return nil
+}
+
+// Watch will emit the metrics of the run of the configuration:
+//
+//   - fetch.chunks, fetch.bytes, and fetch.rate_limit_wait for each chunk that is fetched
+//   - upsert.records for each batch of records that is upserted
+//   - requests, and records.received, records.inserted, records.updated, and records.failed for each request once
+//     its writes have been committed or rolled back, tagged with its status
+//
+// The progress functions of the configuration are still called.
+func (client *Client) Watch(cfg *config.Config) {
+	progress, onFetch, onUpsert := cfg.Progress, cfg.OnFetch, cfg.OnUpsert
+
+	host := ""
+	if cfg.URL != nil {
+		host = cfg.URL.Hostname()
+	}
+
+	cfg.OnFetch = func(fetch config.FetchProgress) {
+		// Each request is also reported once before any of its chunks have been fetched.
+		if fetch.Fetched > 0 {
+			tags := []string{"table:" + fetch.Table, "host:" + host}
+
+			client.count("fetch.chunks", 1, tags)
+			client.count("fetch.bytes", fetch.Bytes, tags)
+			client.timing("fetch.rate_limit_wait", fetch.RateLimitWait, tags)
+		}
+
+		if onFetch != nil {
+			onFetch(fetch)
+		}
+	}
+
+	cfg.OnUpsert = func(upsert config.UpsertProgress) {
+		client.count("upsert.records", upsert.Upserted, []string{"table:" + upsert.Table, "host:" + host})
+
+		if onUpsert != nil {
+			onUpsert(upsert)
+		}
+	}
+
+	cfg.Progress = func(rsp config.RequestProgress) {
+		tags := []string{"table:" + rsp.Table, "host:" + host, "status:" + rsp.Status}
+
+		client.count("requests", 1, tags)
+		client.count("records.received", rsp.Received, tags)
+		client.count("records.inserted", rsp.Inserted, tags)
+		client.count("records.updated", rsp.Updated, tags)
+		client.count("records.failed", rsp.Failed, tags)
+
+		if progress != nil {
+			progress(rsp)
+		}
+	}
+}
+
+// count will emit a counter.
+func (client *Client) count(name string, value int64, tags []string) {
+	client.send(name, fmt.Sprintf("%d|c", value), tags)
+}
+
+// timing will emit a timer, in milliseconds.
+func (client *Client) timing(name string, value time.Duration, tags []string) {
+	client.send(name, fmt.Sprintf("%d|ms", value.Milliseconds()), tags)
+}
+
+// send will write the metric to the agent, ignoring any error since the agent does not acknowledge metrics.
+func (client *Client) send(name, value string, tags []string) {
+	if client.prefix != "" {
+		name = client.prefix + "." + name
+	}
+
+	escaped := make([]string, len(tags))
+	for idx, tag := range tags {
+		escaped[idx] = tagReplacer.Replace(tag)
+	}
+
+	_, _ = fmt.Fprintf(client.conn, "%s:%s|#%s", name, value, strings.Join(escaped, ","))
+}
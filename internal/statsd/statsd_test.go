@@ -0,0 +1,129 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package statsd
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer agent.Close()
+
+	client, err := Dial(agent.LocalAddr().String(), DefaultPrefix)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	defer client.Close()
+
+	var fetches, upserts, requests int
+
+	cfg := &config.Config{
+		URL:      &url.URL{Scheme: "https", Host: "api.exchange.coinbase.com"},
+		OnFetch:  func(config.FetchProgress) { fetches++ },
+		OnUpsert: func(config.UpsertProgress) { upserts++ },
+		Progress: func(config.RequestProgress) { requests++ },
+	}
+
+	client.Watch(cfg)
+
+	cfg.OnFetch(config.FetchProgress{Table: "candles", Chunks: 2})
+	cfg.OnFetch(config.FetchProgress{Table: "candles", Fetched: 1, Chunks: 2, Bytes: 100,
+		RateLimitWait: 200 * time.Millisecond})
+	cfg.OnUpsert(config.UpsertProgress{Table: "candles", Upserted: 3})
+	cfg.Progress(config.RequestProgress{Table: "candles", Status: config.RequestCommitted, Received: 3,
+		Inserted: 2, Updated: 1})
+
+	if fetches != 2 || upserts != 1 || requests != 1 {
+		t.Errorf("expected the progress functions to still be called, got %d fetches, %d upserts, and %d "+
+			"requests", fetches, upserts, requests)
+	}
+
+	tags := "|#table:candles,host:api.exchange.coinbase.com"
+	expected := []string{
+		"gidari.fetch.chunks:1|c" + tags,
+		"gidari.fetch.bytes:100|c" + tags,
+		"gidari.fetch.rate_limit_wait:200|ms" + tags,
+		"gidari.upsert.records:3|c" + tags,
+		"gidari.requests:1|c" + tags + ",status:committed",
+		"gidari.records.received:3|c" + tags + ",status:committed",
+		"gidari.records.inserted:2|c" + tags + ",status:committed",
+		"gidari.records.updated:1|c" + tags + ",status:committed",
+		"gidari.records.failed:0|c" + tags + ",status:committed",
+	}
+
+	if err := agent.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	got := make([]string, 0, len(expected))
+	buf := make([]byte, 1024)
+
+	for range expected {
+		n, _, err := agent.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read metric: %v", err)
+		}
+
+		got = append(got, string(buf[:n]))
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected metrics %q, got %q", expected, got)
+	}
+}
+
+func TestSendEscapesTags(t *testing.T) {
+	t.Parallel()
+
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer agent.Close()
+
+	client, err := Dial(agent.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	defer client.Close()
+
+	client.count("requests", 1, []string{"table:a,b|c#d", "host:x"})
+
+	if err := agent.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	buf := make([]byte, 1024)
+
+	n, _, err := agent.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read metric: %v", err)
+	}
+
+	if got, expected := string(buf[:n]), "requests:1|c|#table:a_b_c_d,host:x"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// adaptiveLatencyFactor is how many times the lowest average latency of the workers their average latency may be
+// before they back off, if the configuration does not have a max latency. adaptiveBaselineDrift is the fraction of
+// the difference that the lowest average latency is raised by each interval that the average latency is higher, so
+// that an API that has become slower for good is not backed off from forever.
+const (
+	adaptiveLatencyFactor = 2
+	adaptiveBaselineDrift = 8
+)
+
+// adaptive limits how many of the workers are busy at once, which is raised by one each interval that the workers
+// were all busy and their latency and error rate were healthy, and halved once either degrades. It starts at a
+// quarter of the workers.
+type adaptive struct {
+	name         string
+	min, max     int
+	interval     time.Duration
+	maxLatency   time.Duration
+	maxErrorRate float64
+	logger       tools.Logger
+
+	mutex sync.Mutex
+
+	// limit is how many of the workers may be busy, and active how many are. changed is closed, and replaced,
+	// whenever a worker may go ahead.
+	limit   int
+	active  int
+	changed chan struct{}
+
+	// saturated is whether every worker that may be busy was during the interval, and succeeded, failed, and
+	// latency are the outcomes of the work of the interval.
+	saturated bool
+	succeeded int
+	failed    int
+	latency   time.Duration
+
+	// baseline is the lowest average latency of the workers, which their latency is compared with if there is no
+	// max latency.
+	baseline time.Duration
+}
+
+// newAdaptive will return the limit of the busy workers of the named kind, or nil if the configuration does not
+// adapt the workers or there is only one of them.
+func newAdaptive(cfg *config.Config, name string, workers int) (*adaptive, error) {
+	if cfg.Adaptive == nil || workers <= 1 {
+		return nil, nil
+	}
+
+	interval, err := cfg.Adaptive.IntervalDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	maxLatency, err := cfg.Adaptive.MaxLatencyDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &adaptive{
+		name:         name,
+		min:          cfg.Adaptive.MinimumWorkers(),
+		max:          workers,
+		interval:     interval,
+		maxLatency:   maxLatency,
+		maxErrorRate: cfg.Adaptive.MaximumErrorRate(),
+		logger:       cfg.Logger,
+		changed:      make(chan struct{}),
+	}
+
+	if ctrl.min > ctrl.max {
+		ctrl.min = ctrl.max
+	}
+
+	ctrl.limit = workers / 4
+	if ctrl.limit < ctrl.min {
+		ctrl.limit = ctrl.min
+	}
+
+	return ctrl, nil
+}
+
+// start will adjust the limit each interval until the returned function is called. A nil limit is not adjusted.
+func (ctrl *adaptive) start() func() {
+	if ctrl == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(ctrl.interval)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctrl.adjust()
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// acquire will wait until the worker may be busy. Once the context is done, the worker goes ahead at once, so that
+// the work that is left fails without waiting. A nil limit never waits.
+func (ctrl *adaptive) acquire(ctx context.Context) {
+	if ctrl == nil {
+		return
+	}
+
+	for {
+		ctrl.mutex.Lock()
+
+		if ctrl.active < ctrl.limit || ctx.Err() != nil {
+			ctrl.active++
+			ctrl.saturated = ctrl.saturated || ctrl.active >= ctrl.limit
+			ctrl.mutex.Unlock()
+
+			return
+		}
+
+		ctrl.saturated = true
+		changed := ctrl.changed
+		ctrl.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// release will let another worker be busy in place of the worker.
+func (ctrl *adaptive) release() {
+	if ctrl == nil {
+		return
+	}
+
+	ctrl.mutex.Lock()
+	defer ctrl.mutex.Unlock()
+
+	ctrl.active--
+	ctrl.notify()
+}
+
+// observe will record the outcome of a fetch or a write, which took "latency" if it succeeded. Work that failed
+// because the run was cancelled is not counted.
+func (ctrl *adaptive) observe(latency time.Duration, err error) {
+	if ctrl == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	ctrl.mutex.Lock()
+	defer ctrl.mutex.Unlock()
+
+	if err != nil {
+		ctrl.failed++
+
+		return
+	}
+
+	ctrl.succeeded++
+	ctrl.latency += latency
+}
+
+// adjust will raise the limit by one if the workers were all busy and healthy during the interval, or halve it if
+// their latency or error rate degraded. The limit is kept if there was no work.
+func (ctrl *adaptive) adjust() {
+	ctrl.mutex.Lock()
+	defer ctrl.mutex.Unlock()
+
+	saturated, succeeded, failed, latency := ctrl.saturated, ctrl.succeeded, ctrl.failed, ctrl.latency
+	ctrl.saturated, ctrl.succeeded, ctrl.failed, ctrl.latency = ctrl.active >= ctrl.limit, 0, 0, 0
+
+	if succeeded+failed == 0 {
+		return
+	}
+
+	var reason string
+
+	if rate := float64(failed) / float64(succeeded+failed); rate > ctrl.maxErrorRate {
+		reason = fmt.Sprintf("%.0f%% of them failed", rate*100)
+	}
+
+	if succeeded > 0 {
+		average := latency / time.Duration(succeeded)
+		if reason == "" && average > ctrl.latencyThreshold() {
+			reason = fmt.Sprintf("their average latency is %v", average.Round(time.Millisecond))
+		}
+
+		ctrl.updateBaseline(average)
+	}
+
+	switch {
+	case reason != "" && ctrl.limit > ctrl.min:
+		ctrl.limit /= 2
+		if ctrl.limit < ctrl.min {
+			ctrl.limit = ctrl.min
+		}
+
+		msg := fmt.Sprintf("backing off to %d of %d %s, since %s", ctrl.limit, ctrl.max, ctrl.name, reason)
+		tools.LogFormatter{Msg: msg}.Log(ctrl.logger, tools.LogLevelInfo)
+	case reason == "" && saturated && ctrl.limit < ctrl.max:
+		ctrl.limit++
+		ctrl.notify()
+
+		msg := fmt.Sprintf("raising to %d of %d %s", ctrl.limit, ctrl.max, ctrl.name)
+		tools.LogFormatter{Msg: msg}.Log(ctrl.logger, tools.LogLevelDebug)
+	}
+}
+
+// latencyThreshold will return the average latency above which the workers back off.
+func (ctrl *adaptive) latencyThreshold() time.Duration {
+	if ctrl.maxLatency > 0 {
+		return ctrl.maxLatency
+	}
+
+	if ctrl.baseline == 0 {
+		return math.MaxInt64
+	}
+
+	return adaptiveLatencyFactor * ctrl.baseline
+}
+
+// updateBaseline will lower the baseline to the average latency, or raise it towards the average latency by a
+// fraction of their difference.
+func (ctrl *adaptive) updateBaseline(average time.Duration) {
+	if ctrl.baseline == 0 || average < ctrl.baseline {
+		ctrl.baseline = average
+
+		return
+	}
+
+	ctrl.baseline += (average - ctrl.baseline) / adaptiveBaselineDrift
+}
+
+// notify will wake the workers that are waiting to be busy.
+func (ctrl *adaptive) notify() {
+	close(ctrl.changed)
+	ctrl.changed = make(chan struct{})
+}
@@ -0,0 +1,207 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdaptive(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	cfg := &config.Config{Logger: logger}
+
+	if ctrl, err := newAdaptive(cfg, "web workers", 8); ctrl != nil || err != nil {
+		t.Errorf("expected no limit without the configuration, got %v and %v", ctrl, err)
+	}
+
+	cfg.Adaptive = &config.Adaptive{}
+
+	if ctrl, err := newAdaptive(cfg, "web workers", 1); ctrl != nil || err != nil {
+		t.Errorf("expected no limit for a single worker, got %v and %v", ctrl, err)
+	}
+
+	ctrl, err := newAdaptive(cfg, "web workers", 8)
+	if err != nil {
+		t.Fatalf("failed to create limit: %v", err)
+	}
+
+	if ctrl.limit != 2 {
+		t.Fatalf("expected to start at a quarter of the workers, got %d", ctrl.limit)
+	}
+
+	ctx := context.Background()
+
+	ctrl.acquire(ctx)
+	ctrl.acquire(ctx)
+
+	acquired := make(chan struct{})
+
+	go func() {
+		ctrl.acquire(ctx)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("expected the worker to wait while the limit is reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctrl.release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the worker to go ahead once another is released")
+	}
+
+	// A worker goes ahead once the run is cancelled, so that its work fails without waiting.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	ctrl.acquire(cancelled)
+
+	if ctrl.active != 3 {
+		t.Errorf("expected 3 busy workers, got %d", ctrl.active)
+	}
+
+	// A nil limit never waits.
+	var nilCtrl *adaptive
+
+	nilCtrl.acquire(ctx)
+	nilCtrl.release()
+	nilCtrl.observe(time.Second, nil)
+	nilCtrl.start()()
+}
+
+func TestAdaptiveAdjust(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	errFetch := fmt.Errorf("status 503")
+
+	for _, tcase := range []struct {
+		name       string
+		maxLatency string
+		limit      int
+		saturated  bool
+
+		// baseline is the lowest average latency before the interval, and latencies and errs the outcomes of its
+		// work.
+		baseline  time.Duration
+		latencies []time.Duration
+		errs      int
+
+		expected int
+	}{
+		{name: "no work", limit: 4, saturated: true, expected: 4},
+		{
+			name:      "healthy",
+			limit:     4,
+			saturated: true,
+			latencies: []time.Duration{time.Millisecond},
+			expected:  5,
+		},
+		{
+			name:      "not saturated",
+			limit:     4,
+			latencies: []time.Duration{time.Millisecond},
+			expected:  4,
+		},
+		{
+			name:      "at the max",
+			limit:     8,
+			saturated: true,
+			latencies: []time.Duration{time.Millisecond},
+			expected:  8,
+		},
+		{
+			name:      "errors",
+			limit:     4,
+			saturated: true,
+			latencies: []time.Duration{time.Millisecond},
+			errs:      1,
+			expected:  2,
+		},
+		{
+			name:      "slower than the baseline",
+			limit:     5,
+			saturated: true,
+			baseline:  10 * time.Millisecond,
+			latencies: []time.Duration{30 * time.Millisecond, 20 * time.Millisecond},
+			expected:  2,
+		},
+		{
+			name:      "near the baseline",
+			limit:     5,
+			saturated: true,
+			baseline:  10 * time.Millisecond,
+			latencies: []time.Duration{15 * time.Millisecond},
+			expected:  6,
+		},
+		{
+			name:       "slower than the max latency",
+			maxLatency: "10ms",
+			limit:      4,
+			saturated:  true,
+			latencies:  []time.Duration{15 * time.Millisecond},
+			expected:   2,
+		},
+		{
+			name:      "at the min",
+			limit:     1,
+			saturated: true,
+			errs:      1,
+			expected:  1,
+		},
+	} {
+		cfg := &config.Config{Logger: logger, Adaptive: &config.Adaptive{MaxLatency: tcase.maxLatency}}
+
+		ctrl, err := newAdaptive(cfg, "web workers", 8)
+		if err != nil {
+			t.Fatalf("%s: failed to create limit: %v", tcase.name, err)
+		}
+
+		ctrl.limit = tcase.limit
+		ctrl.saturated = tcase.saturated
+		ctrl.baseline = tcase.baseline
+
+		for _, latency := range tcase.latencies {
+			ctrl.observe(latency, nil)
+		}
+
+		for i := 0; i < tcase.errs; i++ {
+			ctrl.observe(0, errFetch)
+		}
+
+		// Work that failed because the run was cancelled is not counted.
+		ctrl.observe(0, context.Canceled)
+
+		ctrl.adjust()
+
+		if ctrl.limit != tcase.expected {
+			t.Errorf("%s: expected a limit of %d, got %d", tcase.name, tcase.expected, ctrl.limit)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alpstable/gidari/internal/web"
+)
+
+// Categories of the errors of a request. Errors of responses that the web API rejected are categorized by their
+// status, e.g. "http 429".
+const (
+	errorDecode  = "decode"
+	errorSchema  = "schema"
+	errorFetch   = "fetch"
+	errorStorage = "storage"
+	errorPanic   = "panic"
+	errorOther   = "other"
+)
+
+// errorCategory will return the category of the error.
+func errorCategory(err error) string {
+	if code := web.StatusCode(err); code != 0 {
+		return fmt.Sprintf("http %d", code)
+	}
+
+	switch {
+	case errors.Is(err, ErrPanic):
+		return errorPanic
+	case errors.Is(err, ErrStorage):
+		return errorStorage
+	case errors.Is(err, ErrFetch):
+		return errorFetch
+	default:
+		return errorOther
+	}
+}
+
+// requestErrors are the errors of a request in a run, counted by category, so that they can be reported once the
+// run has finished rather than only logged as they occur. The first error of each category is kept as an example, and
+// the URLs of the chunks that failed to be fetched are kept to report which parts of the request failed.
+type requestErrors struct {
+	mutex        sync.Mutex
+	counts       map[string]int
+	first        map[string]string
+	failedChunks map[string]bool
+}
+
+func newRequestErrors() *requestErrors {
+	return &requestErrors{
+		counts:       make(map[string]int),
+		first:        make(map[string]string),
+		failedChunks: make(map[string]bool),
+	}
+}
+
+// addChunk will count the error in the category, as the error of the chunk with the URL.
+func (errs *requestErrors) addChunk(category, chunk string, err error) {
+	errs.add(category, err)
+
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	errs.failedChunks[chunk] = true
+}
+
+// chunks will return the URLs of the chunks that failed, in order.
+func (errs *requestErrors) chunks() []string {
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	chunks := make([]string, 0, len(errs.failedChunks))
+	for chunk := range errs.failedChunks {
+		chunks = append(chunks, chunk)
+	}
+
+	sort.Strings(chunks)
+
+	return chunks
+}
+
+// add will count the error in the category.
+func (errs *requestErrors) add(category string, err error) {
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	if errs.counts[category] == 0 {
+		errs.first[category] = err.Error()
+	}
+
+	errs.counts[category]++
+}
+
+// total will return the number of errors of the request.
+func (errs *requestErrors) total() int {
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	total := 0
+	for _, count := range errs.counts {
+		total += count
+	}
+
+	return total
+}
+
+// snapshot will return the number of errors in each category, or nil if there are none.
+func (errs *requestErrors) snapshot() map[string]int {
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	if len(errs.counts) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int, len(errs.counts))
+	for category, count := range errs.counts {
+		counts[category] = count
+	}
+
+	return counts
+}
+
+// String will return the number of errors in each category in order, with the first error of each, e.g.
+// "2 http 429 (unexpected status: 429 Too Many Requests), 1 decode (...)".
+func (errs *requestErrors) String() string {
+	errs.mutex.Lock()
+	defer errs.mutex.Unlock()
+
+	categories := make([]string, 0, len(errs.counts))
+	for category := range errs.counts {
+		categories = append(categories, category)
+	}
+
+	sort.Strings(categories)
+
+	parts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%d %s (%s)", errs.counts[category], category, errs.first[category]))
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,67 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/web"
+)
+
+func TestRequestErrors(t *testing.T) {
+	t.Parallel()
+
+	tooManyRequests := web.GettingResponseError(&http.Response{
+		Status:     "429 Too Many Requests",
+		StatusCode: http.StatusTooManyRequests,
+		Body:       io.NopCloser(strings.NewReader("")),
+	})
+
+	for _, tcase := range []struct {
+		err      error
+		expected string
+	}{
+		{err: classify(ErrFetch, tooManyRequests), expected: "http 429"},
+		{err: classify(ErrFetch, errFetch), expected: errorFetch},
+		{err: classify(ErrStorage, fmt.Errorf("deadlock")), expected: errorStorage},
+		{err: fmt.Errorf("failed to batch"), expected: errorOther},
+	} {
+		if category := errorCategory(tcase.err); category != tcase.expected {
+			t.Errorf("expected %q for %v, got %q", tcase.expected, tcase.err, category)
+		}
+	}
+
+	errs := newRequestErrors()
+	if errs.snapshot() != nil || errs.total() != 0 {
+		t.Fatalf("expected no errors, got %v", errs.snapshot())
+	}
+
+	errs.add("http 429", tooManyRequests)
+	errs.add(errorDecode, fmt.Errorf("invalid JSON"))
+	errs.add("http 429", fmt.Errorf("second"))
+
+	if counts := errs.snapshot(); !reflect.DeepEqual(counts, map[string]int{"http 429": 2, errorDecode: 1}) {
+		t.Errorf("expected the errors by category, got %v", counts)
+	}
+
+	if errs.total() != 3 {
+		t.Errorf("expected 3 errors, got %d", errs.total())
+	}
+
+	expected := "1 decode (invalid JSON), 2 http 429 (failed to get response: 429 Too Many Requests)"
+	if errs.String() != expected {
+		t.Errorf("expected %q, got %q", expected, errs.String())
+	}
+}
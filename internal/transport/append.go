@@ -0,0 +1,34 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields that are added to records in "append" mode.
+const (
+	// appendIDField is the surrogate key of an appended record.
+	appendIDField = "gidari_id"
+
+	// appendFetchedAtField is the time the appended record was fetched from the web API.
+	appendFetchedAtField = "gidari_fetched_at"
+)
+
+// appendFields will add a surrogate key and the fetch time to each JSON object in the data, which is either an object
+// or a list of objects.
+func appendFields(data []byte, fetchedAt time.Time) ([]byte, error) {
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		record[appendIDField] = uuid.New().String()
+		record[appendFetchedAtField] = fetchedAt.UTC().Format(time.RFC3339Nano)
+
+		return record, nil
+	})
+}
@@ -0,0 +1,82 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestAppendFields(t *testing.T) {
+	t.Parallel()
+
+	fetchedAt := time.Date(2022, 11, 1, 12, 30, 0, 0, time.UTC)
+
+	for _, data := range []string{`{"price":1}`, `[{"price":1},{"price":2}]`} {
+		appended, err := appendFields([]byte(data), fetchedAt)
+		if err != nil {
+			t.Fatalf("failed to append fields: %v", err)
+		}
+
+		var records []map[string]interface{}
+		if err := json.Unmarshal(appended, &records); err != nil {
+			var record map[string]interface{}
+			if err := json.Unmarshal(appended, &record); err != nil {
+				t.Fatalf("failed to unmarshal %s: %v", appended, err)
+			}
+
+			records = append(records, record)
+		}
+
+		ids := make(map[interface{}]bool)
+
+		for _, record := range records {
+			if record[appendFetchedAtField] != "2022-11-01T12:30:00Z" || record["price"] == nil {
+				t.Errorf("unexpected record %v", record)
+			}
+
+			ids[record[appendIDField]] = true
+		}
+
+		if len(ids) != len(records) {
+			t.Errorf("expected a unique id for each record, got %v", records)
+		}
+	}
+
+	if _, err := appendFields([]byte(`{`), fetchedAt); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestNewFlattenedRequest(t *testing.T) {
+	t.Parallel()
+
+	req := &config.Request{Table: "candles", WriteMode: config.WriteModeAppend}
+
+	flatReq := flattenRequest(req, url.URL{}, nil)
+	if !flatReq.appendMode || !reflect.DeepEqual(flatReq.primaryKeys, []string{appendIDField}) ||
+		flatReq.conflict != proto.ConflictFail {
+		t.Errorf("unexpected append request %+v", flatReq)
+	}
+
+	req = &config.Request{Table: "candles", PrimaryKey: []string{"time"}, Conflict: proto.ConflictInsertOnly}
+
+	flatReq = flattenRequest(req, url.URL{}, nil)
+	if flatReq.appendMode || !reflect.DeepEqual(flatReq.primaryKeys, req.PrimaryKey) ||
+		flatReq.conflict != proto.ConflictInsertOnly {
+		t.Errorf("unexpected upsert request %+v", flatReq)
+	}
+}
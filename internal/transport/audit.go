@@ -0,0 +1,173 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+)
+
+// auditTable is the table that the audit records of every run are written to, with the prefix and suffix of the
+// configuration.
+const auditTable = "gidari_runs"
+
+// auditIDField is the primary key of the audit records, which is the ID of the run and the index of the request.
+const auditIDField = "id"
+
+// auditRecord is the record of the execution of a request in a run, which is written to the audit table of each
+// destination that the request's table is routed to.
+type auditRecord struct {
+	ID         string `json:"id"`
+	RunID      string `json:"run_id"`
+	URL        string `json:"url"`
+	Endpoint   string `json:"endpoint"`
+	Table      string `json:"table"`
+	RangeStart string `json:"range_start,omitempty"`
+	RangeEnd   string `json:"range_end,omitempty"`
+	Records    int64  `json:"records"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+	DurationMS int64  `json:"duration_ms"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// auditURL will return the URL of the request without the time range of its timeseries, with any password redacted.
+func auditURL(cfg *config.Config, req *config.Request) string {
+	rurl := *cfg.URL
+	rurl.Path = path.Join(rurl.Path, req.Endpoint)
+
+	query := rurl.Query()
+	for key, value := range req.Query {
+		if req.Timeseries != nil && (key == req.Timeseries.StartName || key == req.Timeseries.EndName) {
+			continue
+		}
+
+		query.Set(key, value)
+	}
+
+	rurl.RawQuery = query.Encode()
+
+	return rurl.Redacted()
+}
+
+// auditRecords will return the audit records of the requests that are routed to the destination. The requests were
+// executed in the run that started at "started", and their duration is the time from then until they were committed
+// or rolled back.
+func auditRecords(cfg *config.Config, runID string, started time.Time, txns []*requestTxn,
+	dest *config.Destination,
+) []*auditRecord {
+	records := []*auditRecord{}
+
+	for idx, txn := range txns {
+		if !dest.Routes(txn.req.Table) {
+			continue
+		}
+
+		record := &auditRecord{
+			ID:         fmt.Sprintf("%s-%d", runID, idx+1),
+			RunID:      runID,
+			URL:        auditURL(cfg, txn.req),
+			Endpoint:   txn.req.Endpoint,
+			Table:      txn.table,
+			StartedAt:  started.UTC().Format(time.RFC3339Nano),
+			FinishedAt: txn.finished.UTC().Format(time.RFC3339Nano),
+			DurationMS: txn.finished.Sub(started).Milliseconds(),
+			Status:     config.RequestCommitted,
+		}
+
+		if txn.err != nil {
+			record.Status = config.RequestRolledBack
+			record.Error = txn.err.Error()
+		}
+
+		if timeseries := txn.req.Timeseries; timeseries != nil && len(timeseries.Chunks) > 0 {
+			record.RangeStart = timeseries.Chunks[0][0].UTC().Format(time.RFC3339)
+			record.RangeEnd = timeseries.Chunks[len(timeseries.Chunks)-1][1].UTC().Format(time.RFC3339)
+		}
+
+		for _, writes := range txn.writes {
+			if writes.repo.dest == dest {
+				record.Records = writes.written()
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// writeAudit will write the audit records of the run to the audit table of each destination, in a transaction on each
+// destination that is committed independently of the others.
+func writeAudit(ctx context.Context, cfg *config.Config, repos []*destinationRepo, runID string, started time.Time,
+	txns []*requestTxn,
+) error {
+	for _, repo := range repos {
+		records := auditRecords(cfg, runID, started, txns, repo.dest)
+		if len(records) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(records)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit records: %w", err)
+		}
+
+		txRepos, err := beginTx(ctx, []*destinationRepo{repo}, cfg.Logger)
+		if err != nil {
+			return err
+		}
+
+		req := &proto.UpsertRequest{
+			Table:       cfg.StorageTable(auditTable),
+			Data:        data,
+			PrimaryKeys: []string{auditIDField},
+		}
+
+		txRepos[0].Transact(recordsFn("audit", req, len(records), cfg.Logger))
+
+		if err := commit(txRepos, cfg.Logger); err != nil {
+			return fmt.Errorf("failed to write audit records: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordsFn will return a transaction function that upserts the records of the run that are kept alongside its data,
+// e.g. its audit records, where kind is what the records are.
+func recordsFn(kind string, req *proto.UpsertRequest, count int,
+	logger tools.Logger,
+) func(context.Context, repository.Generic) error {
+	return func(sctx context.Context, repo repository.Generic) error {
+		start := time.Now()
+
+		if _, err := repo.Upsert(sctx, req); err != nil {
+			return fmt.Errorf("error upserting %s records: %w", kind, err)
+		}
+
+		scheme := proto.SchemeFromStorageType(repo.Type())
+		logInfo := tools.LogFormatter{
+			Duration: time.Since(start),
+			Table:    req.Table,
+			Msg:      fmt.Sprintf("%s completed: %s.%s, %d records written", kind, scheme, req.Table, count),
+		}
+		logInfo.Log(logger, tools.LogLevelInfo)
+
+		return nil
+	}
+}
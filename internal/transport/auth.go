@@ -0,0 +1,101 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+	"golang.org/x/time/rate"
+)
+
+// ErrNoAuthProbe is returned when there is no endpoint to probe the credentials of a configuration with.
+var ErrNoAuthProbe = fmt.Errorf("no endpoint to probe")
+
+// AuthProbe is a request that was accepted by the web API with the credentials of a configuration.
+type AuthProbe struct {
+	Method string
+
+	// URL is the redacted URL of the request.
+	URL string
+
+	// Duration is how long the web API took to respond.
+	Duration time.Duration
+}
+
+// ProbeAuth will make a single request to the web API with the credentials of the configuration, to check that they
+// are accepted and that requests are signed correctly, without fetching every request. The endpoint is requested
+// with "GET", or, if it is empty, the "authProbe" of the configuration. If neither is set, the first request of the
+// configuration is made, with its method and query, for only the first chunk of a timeseries. Credentials that are
+// rejected return an error that matches "web.ErrUnauthorized".
+func ProbeAuth(ctx context.Context, cfg *config.Config, endpoint string) (*AuthProbe, error) {
+	shared := web.NewTransport(cfg.MaxConns())
+	defer shared.CloseIdleConnections()
+
+	client, err := connect(ctx, cfg, shared)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchConfig, err := probeFetchConfig(cfg, endpoint, client)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &AuthProbe{Method: fetchConfig.Method, URL: fetchConfig.URL.Redacted()}
+
+	start := time.Now()
+
+	rsp, err := web.Fetch(ctx, fetchConfig)
+	if err != nil {
+		return nil, classify(ErrFetch, fmt.Errorf("failed to probe %s %s: %w", probe.Method, probe.URL, err))
+	}
+
+	defer rsp.Body.Close()
+
+	probe.Duration = time.Since(start)
+
+	return probe, nil
+}
+
+// probeFetchConfig will return the request to probe the credentials of the configuration with. The probe is not rate
+// limited, since it is a single request.
+func probeFetchConfig(cfg *config.Config, endpoint string, client *web.Client) (*web.FetchConfig, error) {
+	if endpoint == "" {
+		endpoint = cfg.AuthProbe
+	}
+
+	if endpoint != "" {
+		fetchConfig := newFetchConfig(&config.Request{Endpoint: endpoint, Method: http.MethodGet}, *cfg.URL, client)
+		fetchConfig.RateLimiter = rate.NewLimiter(rate.Inf, 1)
+
+		return fetchConfig, nil
+	}
+
+	if len(cfg.Requests) == 0 {
+		return nil, ErrNoAuthProbe
+	}
+
+	flatReqs, err := flattenRequestTimeseries(planRequest(cfg.Requests[0]), *cfg.URL, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten %q: %w", cfg.Requests[0].Endpoint, err)
+	}
+
+	if len(flatReqs) == 0 {
+		return nil, ErrNoAuthProbe
+	}
+
+	fetchConfig := flatReqs[0].fetchConfig
+	fetchConfig.RateLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	return fetchConfig, nil
+}
@@ -0,0 +1,102 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+)
+
+func TestProbeAuth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			wtr.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	for _, tcase := range []struct {
+		name     string
+		bearer   string
+		probe    string
+		endpoint string
+		requests string
+		url      string
+		err      error
+	}{
+		{
+			name:     "first request",
+			bearer:   "secret",
+			requests: "requests: [{endpoint: /candles, query: {granularity: \"60\"}}, {endpoint: /trades}]",
+			url:      server.URL + "/candles?granularity=60",
+		},
+		{
+			name:     "auth probe",
+			bearer:   "secret",
+			probe:    "/accounts",
+			requests: "requests: [{endpoint: /candles}]",
+			url:      server.URL + "/accounts",
+		},
+		{
+			name:     "endpoint",
+			bearer:   "secret",
+			probe:    "/accounts",
+			endpoint: "/users/self",
+			url:      server.URL + "/users/self",
+		},
+		{
+			name:     "rejected",
+			bearer:   "wrong",
+			requests: "requests: [{endpoint: /candles}]",
+			err:      web.ErrUnauthorized,
+		},
+		{
+			name:   "no requests",
+			bearer: "secret",
+			err:    ErrNoAuthProbe,
+		},
+	} {
+		cfg, err := config.Parse(ctx, []byte(fmt.Sprintf(`
+url: %s
+authentication: {auth2: {bearer: %s}}
+authProbe: %q
+rateLimit: {burst: 1, period: 1s}
+connectionStrings: [stdout://]
+%s
+`, server.URL, tcase.bearer, tcase.probe, tcase.requests)))
+		if err != nil {
+			t.Fatalf("%s: failed to parse config: %v", tcase.name, err)
+		}
+
+		probe, err := ProbeAuth(ctx, cfg, tcase.endpoint)
+		if !errors.Is(err, tcase.err) {
+			t.Fatalf("%s: expected %v, got %v", tcase.name, tcase.err, err)
+		}
+
+		if tcase.err == web.ErrUnauthorized && !errors.Is(err, ErrFetch) {
+			t.Errorf("%s: expected %v to match %v", tcase.name, err, ErrFetch)
+		}
+
+		if err == nil && (probe.Method != http.MethodGet || probe.URL != tcase.url) {
+			t.Errorf("%s: expected GET %s, got %s %s", tcase.name, tcase.url, probe.Method, probe.URL)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/alpstable/gidari/tools"
+)
+
+// backpressure is how long the web workers waited for the repository worker of a request to receive the chunks that
+// they fetched, once the buffer of the request was full.
+type backpressure struct {
+	table  string
+	buffer int
+	logger tools.Logger
+
+	// engaged is whether the web workers have waited for the request, which is logged the first time, and total is
+	// how long they have waited, in nanoseconds.
+	engaged int32
+	total   int64
+}
+
+// newBackpressure will return the backpressure of the request for the table, whose buffer holds the number of chunks.
+func newBackpressure(table string, buffer int, logger tools.Logger) *backpressure {
+	return &backpressure{table: table, buffer: buffer, logger: logger}
+}
+
+// engage will record that a web worker has to wait for the repository worker, logging it the first time.
+func (bp *backpressure) engage() {
+	if bp == nil || !atomic.CompareAndSwapInt32(&bp.engaged, 0, 1) {
+		return
+	}
+
+	msg := fmt.Sprintf("backpressure engaged for %q: %d fetched chunks are waiting to be written", bp.table,
+		bp.buffer)
+	tools.LogFormatter{Table: bp.table, Msg: msg}.Log(bp.logger, tools.LogLevelInfo)
+}
+
+// add will add how long a web worker waited for the repository worker.
+func (bp *backpressure) add(wait time.Duration) {
+	if bp == nil {
+		return
+	}
+
+	atomic.AddInt64(&bp.total, int64(wait))
+}
+
+// waited will return how long the web workers have waited for the repository worker in total.
+func (bp *backpressure) waited() time.Duration {
+	if bp == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64(&bp.total))
+}
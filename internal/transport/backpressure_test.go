@@ -0,0 +1,89 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestBackpressure(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	req := &config.Request{Table: "candles"}
+	cfg := &config.Config{Requests: []*config.Request{req}, JobBuffer: 1, Logger: logger}
+
+	var flattenedRequests []*flattenedRequest
+
+	for idx := 0; idx < 3; idx++ {
+		flattenedRequests = append(flattenedRequests, newFlattenedRequest(req, &web.FetchConfig{
+			Method: http.MethodGet,
+			URL:    &url.URL{Scheme: "https", Host: "api.example.com", Path: "/candles"},
+		}))
+	}
+
+	txn := newRequestTxns(cfg, flattenedRequests)[0]
+	if cap(txn.jobs) != 1 {
+		t.Fatalf("expected a buffer of 1 chunk, got %d", cap(txn.jobs))
+	}
+
+	// send will send a chunk of the request in the background, and return a channel that is closed once it has
+	// been sent or dropped.
+	send := func(idx int) <-chan struct{} {
+		sent := make(chan struct{})
+
+		go func() {
+			defer close(sent)
+
+			newWebJob(cfg, "", flattenedRequests[idx], txn).send(&repoJob{table: "candles"})
+		}()
+
+		return sent
+	}
+
+	<-send(0)
+
+	// The buffer is full, so the second chunk waits for the first to be received.
+	second := send(1)
+
+	select {
+	case <-second:
+		t.Fatalf("expected the chunk to wait for the buffer")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	<-txn.jobs
+	<-second
+
+	if txn.backpressure.waited() == 0 {
+		t.Errorf("expected the wait for the buffer to be measured")
+	}
+
+	// A chunk of a request that is discarded is dropped rather than waiting for the buffer.
+	third := send(2)
+
+	txn.report(nil)
+	<-third
+
+	if len(txn.jobs) != 1 {
+		t.Errorf("expected the third chunk to be dropped, got %d buffered chunks", len(txn.jobs))
+	}
+}
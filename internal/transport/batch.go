@@ -0,0 +1,45 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// batchRecords will split a JSON list of records into lists of at most "size" records. Data that is not a list, or
+// that does not need to be split, is returned as a single batch.
+func batchRecords(data []byte, size int) ([][]byte, error) {
+	if size <= 0 {
+		return [][]byte{data}, nil
+	}
+
+	// Data that is not a list, e.g. a single object, is left to the storage device to decode.
+	var records []json.RawMessage
+	if json.Unmarshal(data, &records) != nil || len(records) <= size {
+		return [][]byte{data}, nil
+	}
+
+	batches := make([][]byte, 0, (len(records)+size-1)/size)
+
+	for start := 0; start < len(records); start += size {
+		end := start + size
+		if end > len(records) {
+			end = len(records)
+		}
+
+		batch, err := encodeList(records[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal batch: %w", err)
+		}
+
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
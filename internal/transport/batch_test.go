@@ -0,0 +1,50 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatchRecords(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		data     string
+		size     int
+		expected []string
+	}{
+		{name: "no batch size", data: `[{"id":1},{"id":2}]`, expected: []string{`[{"id":1},{"id":2}]`}},
+		{name: "object", data: `{"id":1}`, size: 1, expected: []string{`{"id":1}`}},
+		{name: "smaller than batch", data: `[{"id":1}]`, size: 2, expected: []string{`[{"id":1}]`}},
+		{
+			name:     "batched",
+			data:     `[{"id":1},{"id":2},{"id":3}]`,
+			size:     2,
+			expected: []string{`[{"id":1},{"id":2}]`, `[{"id":3}]`},
+		},
+	} {
+		batches, err := batchRecords([]byte(tcase.data), tcase.size)
+		if err != nil {
+			t.Fatalf("%s: failed to batch records: %v", tcase.name, err)
+		}
+
+		got := make([]string, len(batches))
+		for idx, batch := range batches {
+			got[idx] = string(batch)
+		}
+
+		if !reflect.DeepEqual(got, tcase.expected) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.expected, got)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// checkpointState is the content of a checkpoint file: the keys of the chunks that have been committed, see
+// "chunkKey".
+type checkpointState struct {
+	RunID     string    `json:"runId"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Completed []string  `json:"completed"`
+}
+
+// checkpoint is the progress of a run, which is saved to the checkpoint file of the configuration as each request is
+// committed, so that a run that crashed, was cancelled, or failed can be resumed without fetching the chunks that it
+// had already committed.
+type checkpoint struct {
+	path   string
+	logger tools.Logger
+
+	mutex     sync.Mutex
+	state     checkpointState
+	completed map[string]bool
+}
+
+// chunkKey will return the key of the flattened request in a checkpoint, which is a hash of its table, method, and
+// URL, so that the credentials in the query of the URL are not saved.
+func chunkKey(req *flattenedRequest) string {
+	hash := sha256.Sum256([]byte(req.table + "\n" + req.fetchConfig.Method + " " + req.fetchConfig.URL.String()))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// loadCheckpoint will return the checkpoint of the run, or nil if the configuration does not have one. If the run is
+// resumed, the chunks that were committed by the previous runs are read from the checkpoint file. Otherwise the
+// file is reset, so that a later run does not resume from a stale checkpoint.
+func loadCheckpoint(cfg *config.Config, runID string) (*checkpoint, error) {
+	if cfg.Checkpoint == "" {
+		return nil, nil
+	}
+
+	cp := &checkpoint{
+		path:      cfg.Checkpoint,
+		logger:    cfg.Logger,
+		state:     checkpointState{RunID: runID, Completed: []string{}},
+		completed: make(map[string]bool),
+	}
+
+	if !cfg.Resume {
+		return cp, cp.save()
+	}
+
+	bytes, err := os.ReadFile(cp.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var previous checkpointState
+	if err := json.Unmarshal(bytes, &previous); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", cp.path, err)
+	}
+
+	for _, key := range previous.Completed {
+		cp.complete(key)
+	}
+
+	return cp, nil
+}
+
+// complete will add the key to the completed chunks. The caller must not hold the mutex.
+func (cp *checkpoint) complete(key string) {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	if !cp.completed[key] {
+		cp.completed[key] = true
+		cp.state.Completed = append(cp.state.Completed, key)
+	}
+}
+
+// committed will return true if the flattened request was committed by a previous run.
+func (cp *checkpoint) committed(req *flattenedRequest) bool {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	return cp.completed[chunkKey(req)]
+}
+
+// commit will add the flattened requests of a request that has been committed to the checkpoint, and save it. The
+// run does not fail if the checkpoint cannot be saved, since its data has been written.
+func (cp *checkpoint) commit(reqs []*flattenedRequest) {
+	if cp == nil {
+		return
+	}
+
+	for _, req := range reqs {
+		cp.complete(chunkKey(req))
+	}
+
+	if err := cp.save(); err != nil {
+		tools.LogFormatter{Msg: err.Error()}.Log(cp.logger, tools.LogLevelWarn)
+	}
+}
+
+// save will write the checkpoint to a temporary file and rename it over the checkpoint file, so that the file is never
+// partially written.
+func (cp *checkpoint) save() error {
+	cp.mutex.Lock()
+	cp.state.UpdatedAt = time.Now().UTC()
+	bytes, err := json.Marshal(cp.state)
+	cp.mutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(cp.path), "."+filepath.Base(cp.path)+".tmp")
+	if err := os.WriteFile(tmp, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// remove will remove the checkpoint file once the run has succeeded, so that the next run starts from scratch.
+func (cp *checkpoint) remove() {
+	if cp == nil {
+		return
+	}
+
+	if err := os.Remove(cp.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		tools.LogFormatter{Msg: fmt.Sprintf("failed to remove checkpoint: %v", err)}.Log(cp.logger, tools.LogLevelWarn)
+	}
+}
+
+// resume will return the flattened requests that the run still has to fetch, and the requests that were committed
+// by a previous run and are skipped. Requests that truncate their table or soft delete its records are only skipped
+// if every one of their chunks was committed, and are otherwise run in full, since a partial run would delete the
+// records of the chunks that were committed.
+func (cp *checkpoint) resume(cfg *config.Config, flattenedRequests []*flattenedRequest,
+) ([]*flattenedRequest, map[*config.Request]bool) {
+	if cp == nil || !cfg.Resume {
+		return flattenedRequests, nil
+	}
+
+	pending := make(map[*flattenedRequest]bool)
+	skipped := make(map[*config.Request]bool)
+
+	for _, req := range cfg.Requests {
+		var all, remaining []*flattenedRequest
+
+		for _, flatReq := range flattenedRequests {
+			if flatReq.request != req {
+				continue
+			}
+
+			all = append(all, flatReq)
+
+			if !cp.committed(flatReq) {
+				remaining = append(remaining, flatReq)
+			}
+		}
+
+		switch {
+		case len(all) > 0 && len(remaining) == 0:
+			skipped[req] = true
+		case (req.Truncate != nil && *req.Truncate) || req.SoftDelete != "":
+			remaining = all
+		}
+
+		for _, flatReq := range remaining {
+			pending[flatReq] = true
+		}
+	}
+
+	remaining := []*flattenedRequest{}
+
+	for _, flatReq := range flattenedRequests {
+		if pending[flatReq] {
+			remaining = append(remaining, flatReq)
+		}
+	}
+
+	msg := fmt.Sprintf("resuming from checkpoint: %d of %d chunks and %d requests already committed",
+		len(flattenedRequests)-len(remaining), len(flattenedRequests), len(skipped))
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelInfo)
+
+	return remaining, skipped
+}
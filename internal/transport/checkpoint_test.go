@@ -0,0 +1,133 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	truncate := true
+
+	reqs := []*config.Request{
+		{Endpoint: "/candles", Table: "candles"},
+		{Endpoint: "/trades", Table: "trades"},
+		{Endpoint: "/stats", Table: "stats", Truncate: &truncate},
+	}
+
+	chunk := func(req *config.Request, query string) *flattenedRequest {
+		uri, err := url.Parse("https://api.test" + req.Endpoint + "?api_key=secret&" + query)
+		if err != nil {
+			t.Fatalf("failed to parse url: %v", err)
+		}
+
+		return &flattenedRequest{
+			request:     req,
+			table:       req.Table,
+			fetchConfig: &web.FetchConfig{Method: http.MethodGet, URL: uri},
+		}
+	}
+
+	flattenedRequests := []*flattenedRequest{
+		chunk(reqs[0], "start=1"), chunk(reqs[0], "start=2"),
+		chunk(reqs[1], "start=1"), chunk(reqs[1], "start=2"),
+		chunk(reqs[2], "start=1"), chunk(reqs[2], "start=2"),
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cfg := &config.Config{Requests: reqs, Checkpoint: path, Logger: logger}
+
+	// The candles and one chunk of each of the other requests were committed by the previous run.
+	previous, err := loadCheckpoint(cfg, "previous")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	previous.commit(flattenedRequests[:3])
+	previous.commit(flattenedRequests[4:5])
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+
+	if strings.Contains(string(bytes), "secret") {
+		t.Errorf("expected the checkpoint not to contain the query of the chunks, got %s", bytes)
+	}
+
+	// A run that is not resumed does not skip anything.
+	if remaining, skipped := previous.resume(cfg, flattenedRequests); len(remaining) != 6 || skipped != nil {
+		t.Errorf("expected every chunk to be run without resume, got %d and %v", len(remaining), skipped)
+	}
+
+	resumeCfg := *cfg
+	resumeCfg.Resume = true
+
+	resumed, err := loadCheckpoint(&resumeCfg, "resumed")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	remaining, skipped := resumed.resume(&resumeCfg, flattenedRequests)
+
+	// The stats truncate their table, so they are run in full.
+	want := []*flattenedRequest{flattenedRequests[3], flattenedRequests[4], flattenedRequests[5]}
+	if !reflect.DeepEqual(remaining, want) {
+		t.Errorf("expected the remaining chunks of the trades and every chunk of the stats, got %+v", remaining)
+	}
+
+	if len(skipped) != 1 || !skipped[reqs[0]] {
+		t.Errorf("expected the candles to be skipped, got %v", skipped)
+	}
+
+	// The checkpoint of a run that is not resumed is reset.
+	if _, err := loadCheckpoint(cfg, "reset"); err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	reset, err := loadCheckpoint(&resumeCfg, "after-reset")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	if remaining, _ := reset.resume(&resumeCfg, flattenedRequests); len(remaining) != 6 {
+		t.Errorf("expected every chunk to be run after a reset, got %d", len(remaining))
+	}
+
+	reset.remove()
+
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the checkpoint to be removed, got %v", err)
+	}
+
+	// A run without a checkpoint resumes from nothing.
+	if missing, err := loadCheckpoint(&resumeCfg, "missing"); err != nil || missing.committed(flattenedRequests[0]) {
+		t.Errorf("expected an empty checkpoint, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// coalescer accumulates the records of the chunks of a request, so that they are written to the request's table in
+// fewer, larger writes. The records are written once there are enough of them, or once the first of them has waited
+// for the interval.
+type coalescer struct {
+	records  int
+	interval time.Duration
+
+	// first is the first chunk whose records are accumulated, which the accumulated records are written like.
+	first    *repoJob
+	buffered []json.RawMessage
+	timer    *time.Timer
+}
+
+// newCoalescer will return the coalescer of a write of the request, or nil if the records of its chunks are not
+// coalesced.
+func newCoalescer(cfg *config.Coalesce) (*coalescer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	interval, err := cfg.IntervalDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	return &coalescer{records: cfg.Records, interval: interval}, nil
+}
+
+// add will accumulate the records of the chunk, returning false if they cannot be accumulated, i.e. the data of the
+// chunk is not a JSON list or object.
+func (co *coalescer) add(job *repoJob) bool {
+	if co == nil {
+		return false
+	}
+
+	var records []json.RawMessage
+	if err := json.Unmarshal(job.b, &records); err != nil {
+		var record map[string]json.RawMessage
+		if json.Unmarshal(job.b, &record) != nil {
+			return false
+		}
+
+		records = []json.RawMessage{job.b}
+	}
+
+	if co.first == nil {
+		co.first = job
+
+		if co.interval > 0 {
+			co.timer = time.NewTimer(co.interval)
+		}
+	}
+
+	co.buffered = append(co.buffered, records...)
+
+	return true
+}
+
+// full will return true if enough records have been accumulated to be written.
+func (co *coalescer) full() bool {
+	return co != nil && co.records > 0 && len(co.buffered) >= co.records
+}
+
+// due will return a channel that receives once the accumulated records have waited for the interval, or nil if they
+// are only written once there are enough of them.
+func (co *coalescer) due() <-chan time.Time {
+	if co == nil || co.timer == nil {
+		return nil
+	}
+
+	return co.timer.C
+}
+
+// flush will return the accumulated records as the data of a chunk, like the first chunk that they were accumulated
+// from, or nil if no records have been accumulated.
+func (co *coalescer) flush() (*repoJob, error) {
+	if co == nil || co.first == nil {
+		return nil, nil
+	}
+
+	if co.timer != nil {
+		co.timer.Stop()
+	}
+
+	data, err := encodeList(co.buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal coalesced records: %w", err)
+	}
+
+	job := &repoJob{
+		b:           data,
+		table:       co.first.table,
+		primaryKeys: co.first.primaryKeys,
+		conflict:    co.first.conflict,
+		span:        co.first.span,
+	}
+
+	co.first, co.buffered, co.timer = nil, nil, nil
+
+	return job, nil
+}
@@ -0,0 +1,119 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCoalescer(t *testing.T) {
+	t.Parallel()
+
+	coalescer, err := newCoalescer(&config.Coalesce{Records: 3, Interval: "10ms"})
+	if err != nil {
+		t.Fatalf("failed to create coalescer: %v", err)
+	}
+
+	if coalescer.due() != nil {
+		t.Errorf("expected no records to be due")
+	}
+
+	if !coalescer.add(&repoJob{table: "candles", b: []byte(`[{"id":1},{"id":2}]`)}) || coalescer.full() {
+		t.Fatalf("expected the list to be coalesced without filling the coalescer")
+	}
+
+	if coalescer.add(&repoJob{table: "candles", b: []byte(`"clob"`)}) {
+		t.Errorf("expected data that is not a list or object not to be coalesced")
+	}
+
+	if !coalescer.add(&repoJob{table: "candles", b: []byte(`{"id":3}`)}) || !coalescer.full() {
+		t.Fatalf("expected the object to be coalesced and fill the coalescer")
+	}
+
+	select {
+	case <-coalescer.due():
+	case <-time.After(time.Second):
+		t.Errorf("expected the records to be due after the interval")
+	}
+
+	job, err := coalescer.flush()
+	if err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	if job.table != "candles" || string(job.b) != `[{"id":1},{"id":2},{"id":3}]` {
+		t.Errorf("unexpected coalesced chunk %+v", job)
+	}
+
+	if job, _ := coalescer.flush(); job != nil || coalescer.due() != nil {
+		t.Errorf("expected the coalescer to be empty, got %+v", job)
+	}
+
+	if coalescer, _ := newCoalescer(nil); coalescer.add(job) || coalescer.full() {
+		t.Errorf("expected a nil coalescer not to coalesce")
+	}
+}
+
+func TestCoalesceWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	req := &config.Request{Table: "candles", PrimaryKey: []string{"id"}}
+
+	var upserts int64
+
+	cfg := &config.Config{
+		Requests: []*config.Request{req},
+		Coalesce: &config.Coalesce{Records: 4},
+		OnUpsert: func(config.UpsertProgress) { atomic.AddInt64(&upserts, 1) },
+	}
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{{request: req}, {request: req}, {request: req}})[0]
+
+	// The first two chunks are written together, and the last once there are no more chunks.
+	txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"1"},{"id":"2"}]`)}
+	txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"3"},{"id":"4"}]`)}
+	txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"5"}]`)}
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	if upserts != 2 {
+		t.Errorf("expected 2 upserts, got %d", upserts)
+	}
+
+	if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != 5 {
+		t.Errorf("expected 5 candles, got %v", candles)
+	}
+}
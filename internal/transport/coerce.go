@@ -0,0 +1,211 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+var ErrUnableToCoerce = fmt.Errorf("unable to coerce value")
+
+// epochUnits are the durations of the units of "epoch" coercions.
+var epochUnits = map[string]time.Duration{
+	"":   time.Second,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// numericPattern matches the text of a decimal number, optionally in scientific notation.
+var numericPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// coerceColumns will convert the values of the columns of the record with their coercions.
+func coerceColumns(record map[string]interface{}, coercions map[string]*config.Coercion) error {
+	for column, coercion := range coercions {
+		val, ok := record[column]
+		if !ok || val == nil {
+			continue
+		}
+
+		coerced, err := coerce(val, coercion)
+		if err != nil {
+			return fmt.Errorf("%w: %v for column %q: %v", ErrUnableToCoerce, val, column, err)
+		}
+
+		record[column] = coerced
+	}
+
+	return nil
+}
+
+// coerce will convert the value with the coercion.
+func coerce(val interface{}, coercion *config.Coercion) (interface{}, error) {
+	switch coercion.Type {
+	case config.CoerceTimestamp:
+		str, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", val)
+		}
+
+		layout := coercion.Layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+
+		ts, err := time.Parse(layout, str)
+		if err != nil {
+			return nil, err
+		}
+
+		return ts.UTC().Format(time.RFC3339Nano), nil
+	case config.CoerceDecimal:
+		str, ok := val.(string)
+		if !ok {
+			return val, nil
+		}
+
+		if _, err := strconv.ParseFloat(str, 64); err != nil {
+			return nil, err
+		}
+
+		// Keep the text of the number so that no precision is lost when it is encoded.
+		return json.Number(str), nil
+	case config.CoerceEpoch:
+		num, err := epochValue(val)
+		if err != nil {
+			return nil, err
+		}
+
+		// Convert the whole and fractional units separately to avoid rounding errors.
+		unit := epochUnits[coercion.Unit]
+		whole, frac := math.Modf(num)
+		nanos := time.Duration(whole)*unit + time.Duration(math.Round(frac*float64(unit)))
+
+		return time.Unix(0, int64(nanos)).UTC().Format(time.RFC3339Nano), nil
+	case config.CoerceNumeric:
+		var str string
+
+		switch val := val.(type) {
+		case json.Number:
+			str = val.String()
+		case string:
+			str = val
+		default:
+			return nil, fmt.Errorf("expected a number, got %T", val)
+		}
+
+		if !numericPattern.MatchString(str) {
+			return nil, fmt.Errorf("invalid number %q", str)
+		}
+
+		return str, nil
+	case config.CoerceInteger:
+		return integerValue(val)
+	case config.CoerceBoolean:
+		return booleanValue(val)
+	case config.CoerceString:
+		switch val := val.(type) {
+		case string:
+			return val, nil
+		case json.Number:
+			return val.String(), nil
+		case float64:
+			return strconv.FormatFloat(val, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(val), nil
+		default:
+			return nil, fmt.Errorf("expected a string, got %T", val)
+		}
+	}
+
+	return val, nil
+}
+
+// epochValue will return the number of an "epoch" value, which is either a number or a string of a number.
+func epochValue(val interface{}) (float64, error) {
+	switch val := val.(type) {
+	case float64:
+		return val, nil
+	case json.Number:
+		return val.Float64()
+	case string:
+		num, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return num, nil
+	}
+
+	return 0, fmt.Errorf("expected a number, got %T", val)
+}
+
+// integerValue will return the value of an "integer" coercion, which is either a whole number or a string of one, as
+// a number.
+func integerValue(val interface{}) (json.Number, error) {
+	var str string
+
+	switch val := val.(type) {
+	case json.Number:
+		str = val.String()
+	case float64:
+		str = strconv.FormatFloat(val, 'f', -1, 64)
+	case string:
+		str = strings.TrimSpace(val)
+	default:
+		return "", fmt.Errorf("expected a number, got %T", val)
+	}
+
+	if num, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(num, 10)), nil
+	}
+
+	// Whole numbers in other notations, e.g. "1.0" or "1e3", are integers too.
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil || num != math.Trunc(num) || math.Abs(num) >= math.MaxInt64 {
+		return "", fmt.Errorf("invalid integer %q", str)
+	}
+
+	return json.Number(strconv.FormatInt(int64(num), 10)), nil
+}
+
+// booleanValue will return the value of a "boolean" coercion, which is either a boolean, a string of one, or the
+// number 0 or 1.
+func booleanValue(val interface{}) (bool, error) {
+	switch val := val.(type) {
+	case bool:
+		return val, nil
+	case string:
+		boolean, err := strconv.ParseBool(strings.TrimSpace(val))
+		if err != nil {
+			return false, fmt.Errorf("invalid boolean %q", val)
+		}
+
+		return boolean, nil
+	case json.Number, float64:
+		switch fmt.Sprint(val) {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+
+		return false, fmt.Errorf("invalid boolean %v", val)
+	}
+
+	return false, fmt.Errorf("expected a boolean, got %T", val)
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/decoder"
+)
+
+// commandStderrLimit is the number of bytes of the stderr of a command that fails that are included in its error.
+const commandStderrLimit = 512
+
+// recordCommand is the external command of a request, with its executable resolved.
+type recordCommand struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+// newRecordCommand will resolve the executable of the command of the request, so that a command that does not exist,
+// or is not allowed by the configuration, fails the run before anything is fetched. A request without a command
+// returns nil.
+func newRecordCommand(req *config.Request, allowed bool) (*recordCommand, error) {
+	if req.Command == nil {
+		return nil, nil
+	}
+
+	if !allowed {
+		return nil, fmt.Errorf("%w: %q is only run with --allow-commands", config.ErrCommandNotAllowed,
+			req.Command.Path)
+	}
+
+	path, err := exec.LookPath(req.Command.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find command: %w", err)
+	}
+
+	return &recordCommand{path: path, args: req.Command.Args, timeout: req.Command.TimeoutDuration()}, nil
+}
+
+// apply will pipe the data through the command, and return the records that the command writes to its stdout. The
+// output is JSON, or NDJSON such as the output of "jq -c", and no output is no records. A nil command returns the data
+// as is.
+func (cmd *recordCommand) apply(ctx context.Context, data []byte) ([]byte, error) {
+	if cmd == nil {
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cmd.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	proc := exec.CommandContext(ctx, cmd.path, cmd.args...)
+	proc.Stdin = bytes.NewReader(data)
+	proc.Stdout = &stdout
+	proc.Stderr = &stderr
+
+	if err := proc.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v", cmd.timeout)
+		}
+
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > commandStderrLimit {
+			msg = msg[:commandStderrLimit] + "..."
+		}
+
+		return nil, fmt.Errorf("command %q failed: %w: %s", cmd.path, err, msg)
+	}
+
+	if json.Valid(stdout.Bytes()) {
+		return stdout.Bytes(), nil
+	}
+
+	records, err := (&decoder.NDJSON{}).Decode(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("command %q wrote invalid JSON: %w", cmd.path, err)
+	}
+
+	return records, nil
+}
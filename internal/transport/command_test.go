@@ -0,0 +1,80 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestRecordCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	for _, tcase := range []struct {
+		name     string
+		script   string
+		timeout  string
+		expected string
+
+		// errMsg is a part of the error of the command, if it fails.
+		errMsg string
+	}{
+		{name: "json", script: "cat", expected: `[{"id":1},{"id":2}]`},
+		{name: "ndjson", script: `printf '{"id":1}\n{"id":2}\n'`, expected: `[{"id":1},{"id":2}]`},
+		{name: "no output", script: "cat >/dev/null", expected: `[]`},
+		{name: "exit status", script: "echo oops >&2; exit 3", errMsg: "oops"},
+		{name: "invalid output", script: "echo not json", errMsg: "invalid JSON"},
+		{name: "timeout", script: "exec sleep 5", timeout: "50ms", errMsg: "timed out"},
+	} {
+		req := &config.Request{Command: &config.Command{Path: "sh", Args: []string{"-c", tcase.script},
+			Timeout: tcase.timeout}}
+
+		cmd, err := newRecordCommand(req, true)
+		if err != nil {
+			t.Fatalf("%s: failed to create command: %v", tcase.name, err)
+		}
+
+		data, err := cmd.apply(ctx, []byte(`[{"id":1},{"id":2}]`))
+		if tcase.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tcase.errMsg) {
+				t.Errorf("%s: expected an error with %q, got %v", tcase.name, tcase.errMsg, err)
+			}
+
+			continue
+		}
+
+		if err != nil || string(data) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s and %v", tcase.name, tcase.expected, data, err)
+		}
+	}
+
+	missing := &config.Request{Command: &config.Command{Path: "gidari-missing-command"}}
+	if _, err := newRecordCommand(missing, true); err == nil {
+		t.Errorf("expected an error for a missing command")
+	}
+
+	// A command that the configuration does not allow fails, even if it exists.
+	denied := &config.Request{Command: &config.Command{Path: "sh"}}
+	if _, err := newRecordCommand(denied, false); !errors.Is(err, config.ErrCommandNotAllowed) {
+		t.Errorf("expected %v, got %v", config.ErrCommandNotAllowed, err)
+	}
+
+	var nilCmd *recordCommand
+
+	if data, err := nilCmd.apply(ctx, []byte(`not json`)); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+// writeGate backs off the writes to a destination when it reports contention, so that the workers of a run stop
+// writing to it all at once. After contention, the writes are paused, and then made one at a time for the period of
+// the backoff. The pause doubles if the destination is still contended during the period.
+type writeGate struct {
+	pause  time.Duration
+	period time.Duration
+	name   string
+	logger tools.Logger
+
+	// serial is held by the write that is being made while the writes are made one at a time.
+	serial chan struct{}
+
+	mutex  sync.Mutex
+	wait   time.Duration
+	resume time.Time
+	until  time.Time
+}
+
+// newWriteGate will return the write gate of the destination, which is named by its storage scheme in the logs.
+func newWriteGate(dest *config.Destination, name string, logger tools.Logger) (*writeGate, error) {
+	pause, period, err := dest.WriteBackoff.Durations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeGate{pause: pause, period: period, name: name, logger: logger, serial: make(chan struct{}, 1)}, nil
+}
+
+// do will make the write through the gate. If the destination is backing off, the write waits for the pause and for
+// the other writes to the destination to finish first. A write that fails with contention starts the backoff. A nil
+// gate makes the write as is.
+func (gate *writeGate) do(ctx context.Context, write func() error) error {
+	if gate == nil {
+		return write()
+	}
+
+	if wait, backingOff := gate.state(); backingOff {
+		select {
+		case gate.serial <- struct{}{}:
+			defer func() { <-gate.serial }()
+		case <-ctx.Done():
+			return fmt.Errorf("context done while backing off writes: %w", ctx.Err())
+		}
+
+		if wait > 0 {
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := write()
+	if errors.Is(err, proto.ErrContention) {
+		gate.contended(err)
+	}
+
+	return err
+}
+
+// state will return how long the writes are still paused for, and whether the destination is backing off.
+func (gate *writeGate) state() (time.Duration, bool) {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	now := time.Now()
+
+	return gate.resume.Sub(now), now.Before(gate.until)
+}
+
+// contended will start the backoff of the destination, or double its pause if it is already backing off.
+func (gate *writeGate) contended(err error) {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	now := time.Now()
+
+	if now.Before(gate.until) {
+		if gate.wait *= 2; gate.wait > maxRetryBackoff {
+			gate.wait = maxRetryBackoff
+		}
+	} else {
+		gate.wait = gate.pause
+
+		msg := fmt.Sprintf("contention on %q, pausing writes for %v and writing one at a time for %v: %v",
+			gate.name, gate.wait, gate.period, err)
+		tools.LogFormatter{Msg: msg}.Log(gate.logger, tools.LogLevelWarn)
+	}
+
+	gate.resume = now.Add(gate.wait)
+	gate.until = gate.resume.Add(gate.period)
+}
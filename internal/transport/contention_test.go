@@ -0,0 +1,141 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWriteGate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	errLocked := proto.Contention(fmt.Errorf("could not obtain lock"))
+
+	newGate := func(t *testing.T, backoff *config.WriteBackoff) *writeGate {
+		t.Helper()
+
+		gate, err := newWriteGate(&config.Destination{WriteBackoff: backoff}, "postgresql", logger)
+		if err != nil {
+			t.Fatalf("failed to create write gate: %v", err)
+		}
+
+		return gate
+	}
+
+	t.Run("pause after contention", func(t *testing.T) {
+		t.Parallel()
+
+		gate := newGate(t, &config.WriteBackoff{Pause: "50ms", Period: "1s"})
+
+		if err := gate.do(ctx, func() error { return errLocked }); !errors.Is(err, errLocked) {
+			t.Fatalf("expected %v, got %v", errLocked, err)
+		}
+
+		start := time.Now()
+		if err := gate.do(ctx, func() error { return nil }); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("expected the write to be paused, it was made after %v", elapsed)
+		}
+	})
+
+	t.Run("one write at a time", func(t *testing.T) {
+		t.Parallel()
+
+		gate := newGate(t, &config.WriteBackoff{Pause: "0s", Period: "1s"})
+		_ = gate.do(ctx, func() error { return errLocked })
+
+		var (
+			wg                sync.WaitGroup
+			writing, maxWrite int32
+		)
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_ = gate.do(ctx, func() error {
+					current := atomic.AddInt32(&writing, 1)
+					defer atomic.AddInt32(&writing, -1)
+
+					for {
+						prev := atomic.LoadInt32(&maxWrite)
+						if current <= prev || atomic.CompareAndSwapInt32(&maxWrite, prev, current) {
+							break
+						}
+					}
+
+					time.Sleep(5 * time.Millisecond)
+
+					return nil
+				})
+			}()
+		}
+
+		wg.Wait()
+
+		if maxWrite != 1 {
+			t.Errorf("expected the writes to be made one at a time, got %d at once", maxWrite)
+		}
+	})
+
+	t.Run("pause doubles", func(t *testing.T) {
+		t.Parallel()
+
+		gate := newGate(t, &config.WriteBackoff{Pause: "10ms", Period: "1s"})
+		gate.contended(errLocked)
+		gate.contended(errLocked)
+
+		if gate.wait != 20*time.Millisecond {
+			t.Errorf("expected the pause to double to 20ms, got %v", gate.wait)
+		}
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		t.Parallel()
+
+		gate := newGate(t, nil)
+		_ = gate.do(ctx, func() error { return proto.Transient(fmt.Errorf("connection reset")) })
+
+		if _, backingOff := gate.state(); backingOff {
+			t.Errorf("expected only contention to back off the writes")
+		}
+	})
+
+	t.Run("nil gate", func(t *testing.T) {
+		t.Parallel()
+
+		var gate *writeGate
+		if err := gate.do(ctx, func() error { return errLocked }); !errors.Is(err, errLocked) {
+			t.Errorf("expected %v, got %v", errLocked, err)
+		}
+	})
+}
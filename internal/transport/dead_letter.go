@@ -0,0 +1,234 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+// Stages of a run that a payload failed in.
+const (
+	deadLetterDecode = "decode"
+	deadLetterUpsert = "upsert"
+)
+
+// deadLetterIDField is the primary key of the dead letters, which is the ID of the run and the index of the letter.
+const deadLetterIDField = "id"
+
+// deadLetter is a payload that failed to be decoded or upserted, with the context of the failure, so that it can be
+// replayed once the cause has been fixed. The payload of a decode failure is the body of the response, and of an
+// upsert failure the records that were to be written.
+type deadLetter struct {
+	ID       string `json:"id"`
+	RunID    string `json:"run_id"`
+	Endpoint string `json:"endpoint"`
+	Table    string `json:"table"`
+	URL      string `json:"url"`
+	Stage    string `json:"stage"`
+	Error    string `json:"error"`
+	FailedAt string `json:"failed_at"`
+	Payload  string `json:"payload"`
+
+	// table is the name of the request's table, which the letter is routed with.
+	table string
+}
+
+// deadLetters are the dead letters of a request. Payloads are only kept if the run has a dead letter, so the letters
+// of a nil collection are discarded.
+type deadLetters struct {
+	mutex   sync.Mutex
+	letters []*deadLetter
+}
+
+// newDeadLetters will return the collection of the dead letters of a request, or nil if the configuration does not
+// have a dead letter.
+func newDeadLetters(cfg *config.Config) *deadLetters {
+	if cfg.DeadLetter == nil {
+		return nil
+	}
+
+	return &deadLetters{}
+}
+
+// add will add the payload that was fetched with the URL for the request, which failed in the stage with the error.
+func (letters *deadLetters) add(req *config.Request, table string, uri *url.URL, stage string, payload []byte,
+	err error,
+) {
+	if letters == nil {
+		return
+	}
+
+	letter := &deadLetter{
+		Endpoint: req.Endpoint,
+		Table:    table,
+		Stage:    stage,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Payload:  string(payload),
+		table:    req.Table,
+	}
+
+	if uri != nil {
+		letter.URL = uri.Redacted()
+	}
+
+	letters.mutex.Lock()
+	defer letters.mutex.Unlock()
+
+	letters.letters = append(letters.letters, letter)
+}
+
+// reset will discard the letters, e.g. of a request that is retried.
+func (letters *deadLetters) reset() {
+	if letters == nil {
+		return
+	}
+
+	letters.mutex.Lock()
+	defer letters.mutex.Unlock()
+
+	letters.letters = nil
+}
+
+// snapshot will return the letters that have been added.
+func (letters *deadLetters) snapshot() []*deadLetter {
+	if letters == nil {
+		return nil
+	}
+
+	letters.mutex.Lock()
+	defer letters.mutex.Unlock()
+
+	return append([]*deadLetter{}, letters.letters...)
+}
+
+// deadLetterReceived will add the payloads of the request that were received to its dead letters, once the request
+// failed to be upserted with the error. Requests that failed to be fetched are not added, since the payloads that were
+// fetched are not the complete data of the request.
+func (txn *requestTxn) deadLetterReceived(err error) {
+	for _, job := range txn.received {
+		if job == nil || job.err != nil {
+			continue
+		}
+
+		txn.deadLetters.add(txn.req, txn.table, job.req.URL, deadLetterUpsert, job.b, err)
+	}
+}
+
+// writeDeadLetters will write the dead letters of the requests of the run to the directory and the table of the
+// dead letter of the configuration. The letters are numbered in the order of the requests.
+func writeDeadLetters(ctx context.Context, cfg *config.Config, repos []*destinationRepo, runID string,
+	txns []*requestTxn,
+) error {
+	var letters []*deadLetter
+
+	for _, txn := range txns {
+		letters = append(letters, txn.deadLetters.snapshot()...)
+	}
+
+	if len(letters) == 0 {
+		return nil
+	}
+
+	for idx, letter := range letters {
+		letter.ID = fmt.Sprintf("%s-%d", runID, idx+1)
+		letter.RunID = runID
+	}
+
+	if dir := cfg.DeadLetter.Dir; dir != "" {
+		if err := writeDeadLetterFiles(dir, letters); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DeadLetter.Table != "" {
+		if err := writeDeadLetterTable(ctx, cfg, repos, letters); err != nil {
+			return err
+		}
+	}
+
+	msg := fmt.Sprintf("%d failed payloads written to the dead letter", len(letters))
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelWarn)
+
+	return nil
+}
+
+// writeDeadLetterFiles will write each letter to the directory as a JSON file named by its ID.
+func writeDeadLetterFiles(dir string, letters []*deadLetter) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+
+	for _, letter := range letters {
+		data, err := json.MarshalIndent(letter, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal dead letter: %w", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, letter.ID+".json"), data, 0o600); err != nil {
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeDeadLetterTable will write the letters to the dead letter table of each destination that the tables of their
+// requests are routed to, in a transaction on each destination that is committed independently of the others.
+func writeDeadLetterTable(ctx context.Context, cfg *config.Config, repos []*destinationRepo,
+	letters []*deadLetter,
+) error {
+	for _, repo := range repos {
+		routedLetters := []*deadLetter{}
+
+		for _, letter := range letters {
+			if repo.dest.Routes(letter.table) {
+				routedLetters = append(routedLetters, letter)
+			}
+		}
+
+		if len(routedLetters) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(routedLetters)
+		if err != nil {
+			return fmt.Errorf("failed to marshal dead letters: %w", err)
+		}
+
+		txRepos, err := beginTx(ctx, []*destinationRepo{repo}, cfg.Logger)
+		if err != nil {
+			return err
+		}
+
+		req := &proto.UpsertRequest{
+			Table:       cfg.StorageTable(cfg.DeadLetter.Table),
+			Data:        data,
+			PrimaryKeys: []string{deadLetterIDField},
+		}
+
+		txRepos[0].Transact(recordsFn("dead letter", req, len(routedLetters), cfg.Logger))
+
+		if err := commit(txRepos, cfg.Logger); err != nil {
+			return fmt.Errorf("failed to write dead letters: %w", err)
+		}
+	}
+
+	return nil
+}
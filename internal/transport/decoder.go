@@ -0,0 +1,100 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/decoder"
+	"github.com/alpstable/gidari/internal/web"
+)
+
+// newDecoder will return the built-in or registered decoder of the responses of the request, or nil if they are JSON.
+// The descriptor of a protobuf message is read once for the request.
+func newDecoder(req *config.Request) (decoder.Decoder, error) {
+	switch req.Format {
+	case config.ResponseFormatCSV:
+		dec := &decoder.CSV{Comma: req.CSV.Comma(), Header: req.CSV.HasHeader()}
+		if req.CSV != nil {
+			dec.Columns = req.CSV.Columns
+		}
+
+		return dec, nil
+	case config.ResponseFormatXML:
+		return &decoder.XML{RecordPath: req.XML.Path()}, nil
+	case config.ResponseFormatNDJSON:
+		return &decoder.NDJSON{}, nil
+	case config.ResponseFormatMessagePack:
+		return &decoder.MessagePack{}, nil
+	case config.ResponseFormatProtobuf:
+		descriptorSet, err := os.ReadFile(req.Protobuf.Descriptor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read protobuf descriptor: %w", err)
+		}
+
+		dec, err := decoder.NewProtobuf(descriptorSet, req.Protobuf.Message, req.Protobuf.Delimited)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create protobuf decoder: %w", err)
+		}
+
+		return dec, nil
+	default:
+		if dec, ok := decoder.LookupFormat(req.Format); ok {
+			return dec, nil
+		}
+
+		return nil, nil
+	}
+}
+
+// responseDecoder will return the decoder of the response, which is the decoder of the request. If the responses of
+// the request are JSON, it is the decoder registered for the content type of the response, or an NDJSON decoder if
+// the content type is NDJSON.
+func (job *webJob) responseDecoder(rsp *web.FetchResponse) decoder.Decoder {
+	if job.decoder != nil {
+		return job.decoder
+	}
+
+	if dec, ok := decoder.LookupContentType(rsp.ContentType); ok {
+		return dec
+	}
+
+	if decoder.IsNDJSON(rsp.ContentType) {
+		return &decoder.NDJSON{}
+	}
+
+	return nil
+}
+
+// decodeFormat will decode the body of the response into a JSON list of records with the decoder of the response. A
+// body without a decoder that is not valid JSON is decoded as NDJSON if each of its lines is JSON, e.g. if the
+// response was replayed without its content type, and is returned as is otherwise.
+func (job *webJob) decodeFormat(data []byte, dec decoder.Decoder) ([]byte, error) {
+	if dec == nil {
+		if json.Valid(data) || len(bytes.TrimSpace(data)) == 0 {
+			return data, nil
+		}
+
+		if decoded, err := (&decoder.NDJSON{}).Decode(data); err == nil {
+			return decoded, nil
+		}
+
+		return data, nil
+	}
+
+	decoded, err := dec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", job.fetchConfig.URL.Redacted(), err)
+	}
+
+	return decoded, nil
+}
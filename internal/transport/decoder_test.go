@@ -0,0 +1,213 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/decoder"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestDecodeFormat(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte("id;price\n1;1.50\n2;2.25\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/prices.csv")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	req := &config.Request{Table: "prices", Format: config.ResponseFormatCSV, CSV: &config.CSV{Delimiter: ";"}}
+	cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+	if flatReq.decoder, err = newDecoder(req); err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+	txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+	if err := txn.newJob(flatReq).run(ctx, 1); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	prices := readLines(t, filepath.Join(dir, "prices.ndjson"))
+	if len(prices) != 2 || !strings.Contains(prices[0], `"price":"1.50"`) || !strings.Contains(prices[1], `"id":"2"`) {
+		t.Errorf("unexpected prices %v", prices)
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	t.Parallel()
+
+	// The descriptor set of the transport configuration includes the descriptors that it imports.
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(durationpb.File_google_protobuf_duration_proto),
+		protodesc.ToFileDescriptorProto(proto.File_config_proto),
+	}}
+
+	descriptorSet, err := protobuf.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+
+	descriptor := filepath.Join(t.TempDir(), "config.pb")
+	if err := os.WriteFile(descriptor, descriptorSet, 0o600); err != nil {
+		t.Fatalf("failed to write descriptor set: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		name     string
+		req      *config.Request
+		expected decoder.Decoder
+		wantErr  bool
+	}{
+		{name: "json", req: &config.Request{}},
+		{name: "ndjson", req: &config.Request{Format: config.ResponseFormatNDJSON}, expected: &decoder.NDJSON{}},
+		{name: "msgpack", req: &config.Request{Format: config.ResponseFormatMessagePack}, expected: &decoder.MessagePack{}},
+		{
+			name:     "csv",
+			req:      &config.Request{Format: config.ResponseFormatCSV},
+			expected: &decoder.CSV{Comma: ',', Header: true},
+		},
+		{
+			name:     "xml",
+			req:      &config.Request{Format: config.ResponseFormatXML, XML: &config.XML{RecordPath: "feed/entry"}},
+			expected: &decoder.XML{RecordPath: []string{"feed", "entry"}},
+		},
+		{
+			name: "protobuf",
+			req: &config.Request{
+				Format:   config.ResponseFormatProtobuf,
+				Protobuf: &config.Protobuf{Descriptor: descriptor, Message: "proto.TransportConfig"},
+			},
+		},
+		{
+			name: "missing descriptor",
+			req: &config.Request{
+				Format:   config.ResponseFormatProtobuf,
+				Protobuf: &config.Protobuf{Descriptor: descriptor + ".missing", Message: "proto.TransportConfig"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing message",
+			req: &config.Request{
+				Format:   config.ResponseFormatProtobuf,
+				Protobuf: &config.Protobuf{Descriptor: descriptor, Message: "proto.Candles"},
+			},
+			wantErr: true,
+		},
+	} {
+		dec, err := newDecoder(tcase.req)
+		if (err != nil) != tcase.wantErr {
+			t.Errorf("%s: expected an error %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if tcase.name == "protobuf" {
+			if _, ok := dec.(*decoder.Protobuf); !ok {
+				t.Errorf("%s: expected a protobuf decoder, got %T", tcase.name, dec)
+			}
+
+			continue
+		}
+
+		if !reflect.DeepEqual(dec, tcase.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tcase.name, tcase.expected, dec)
+		}
+	}
+}
+
+func TestResponseDecoder(t *testing.T) {
+	t.Parallel()
+
+	registered := &decoder.MessagePack{}
+	if err := decoder.RegisterFormat("transport-test", registered); err != nil {
+		t.Fatalf("failed to register format: %v", err)
+	}
+
+	if err := decoder.RegisterContentType("application/vnd.transport-test", registered); err != nil {
+		t.Fatalf("failed to register content type: %v", err)
+	}
+
+	dec, err := newDecoder(&config.Request{Format: "transport-test"})
+	if err != nil || dec != registered {
+		t.Errorf("expected the registered decoder, got %v and %v", dec, err)
+	}
+
+	csv := &decoder.CSV{Comma: ','}
+
+	for _, tcase := range []struct {
+		name        string
+		decoder     decoder.Decoder
+		contentType string
+		expected    decoder.Decoder
+	}{
+		{name: "json", contentType: "application/json"},
+		{name: "registered", contentType: "application/vnd.transport-test", expected: registered},
+		{name: "ndjson", contentType: "application/x-ndjson", expected: &decoder.NDJSON{}},
+		{name: "request", decoder: csv, contentType: "application/vnd.transport-test", expected: csv},
+	} {
+		job := &webJob{flattenedRequest: &flattenedRequest{decoder: tcase.decoder}}
+
+		dec := job.responseDecoder(&web.FetchResponse{ContentType: tcase.contentType})
+		if !reflect.DeepEqual(dec, tcase.expected) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.expected, dec)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+)
+
+// defineTables will declare the schema of each table with a schema that is routed to the destination of the
+// repository, so that storage with a schema creates the table with the declared columns, types, and primary keys.
+// Storage that does not create its tables from a declared schema stores the records as they are coerced.
+func defineTables(ctx context.Context, cfg *config.Config, repo repository.Generic, dest *config.Destination) error {
+	names := make([]string, 0, len(cfg.Tables))
+
+	for name, table := range cfg.Tables {
+		if table != nil && table.Schema != nil && dest.Routes(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := repo.DefineTable(ctx, cfg.Tables[name].Schema.Definition(cfg.StorageTable(name)))
+		if errors.Is(err, proto.ErrDefineTableNotSupported) {
+			msg := fmt.Sprintf("schemas are not declared on %q, the types of its columns are inferred",
+				proto.SchemeFromStorageType(repo.Type()))
+			tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelDebug)
+
+			return nil
+		}
+
+		if err != nil {
+			return classify(ErrStorage, fmt.Errorf("failed to define table %q: %w", name, err))
+		}
+	}
+
+	return nil
+}
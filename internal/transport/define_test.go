@@ -0,0 +1,105 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/file"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+// definingFile is file storage that keeps the tables that are defined on it.
+type definingFile struct {
+	*file.File
+	mutex   *sync.Mutex
+	defined map[string]*proto.DefineTableRequest
+}
+
+func (stg *definingFile) DefineTable(_ context.Context, req *proto.DefineTableRequest) error {
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	stg.defined[req.Table] = req
+
+	return nil
+}
+
+func TestDefineTables(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	mutex := &sync.Mutex{}
+	defined := make(map[string]*proto.DefineTableRequest)
+
+	err := proto.RegisterConstructor("definetest", func(ctx context.Context, _ string) (*proto.StorageService, error) {
+		stg, err := file.New(ctx, "file://"+t.TempDir())
+		if err != nil {
+			return nil, err
+		}
+
+		return &proto.StorageService{Storage: &definingFile{File: stg, mutex: mutex, defined: defined}}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register storage: %v", err)
+	}
+
+	schema := &config.Schema{Fields: []*config.SchemaField{
+		{Name: "trade_id", Type: config.SchemaInteger, Key: true},
+		{Name: "price", Type: config.SchemaDecimal, Required: true},
+		{Name: "side", Type: config.SchemaString},
+	}}
+
+	cfg := &config.Config{
+		Destinations: []*config.Destination{
+			{ConnectionString: "definetest://", Exclude: []string{"orders"}},
+			{ConnectionString: "file://" + t.TempDir()},
+		},
+		Tables: map[string]*config.Table{
+			"trades":  {Schema: schema},
+			"orders":  {Schema: schema},
+			"candles": {Columns: map[string]string{"px": "price"}},
+		},
+		TablePrefix: "dev_",
+		Logger:      logger,
+	}
+
+	_, closeRepos, err := repos(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create repositories: %v", err)
+	}
+
+	closeRepos()
+
+	expected := map[string]*proto.DefineTableRequest{"dev_trades": {
+		Table: "dev_trades",
+		Columns: []*proto.ColumnDefinition{
+			{Name: "trade_id", Type: proto.ColumnTypeInteger, Required: true},
+			{Name: "price", Type: proto.ColumnTypeDecimal, Required: true},
+			{Name: "side", Type: proto.ColumnTypeString},
+		},
+		PrimaryKeys: []string{"trade_id"},
+	}}
+
+	if !reflect.DeepEqual(defined, expected) {
+		t.Errorf("expected the trades table to be defined, got %+v", defined)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+)
+
+// DiagnosticTimeout is how long each check of a diagnosis can take before it fails.
+const DiagnosticTimeout = 10 * time.Second
+
+// maxSaneRate is the number of requests per second above which a rate limit is most likely a typo, e.g. a period of
+// "1ms" instead of "1s".
+const maxSaneRate = 100
+
+// Diagnosis is the outcome of the checks that a configuration can reach the web API and its storage devices.
+type Diagnosis struct {
+	Checks []*DiagnosticCheck
+}
+
+// DiagnosticCheck is the outcome of one check of a diagnosis.
+type DiagnosticCheck struct {
+	Name string
+
+	// Detail describes what was found, e.g. the addresses that a host resolves to.
+	Detail string
+
+	// Skipped is "true" if the check does not apply to the configuration, e.g. TLS for a web API served over HTTP.
+	Skipped bool
+
+	// Err is the reason that the check failed, or nil if it passed.
+	Err error
+}
+
+// Diagnose will check that the host of the web API resolves, that its TLS certificate is valid, that its credentials
+// are accepted, that each storage device can be connected to, and that the rate limit is sane, so that problems are
+// found before a real run. Every check is run, even if an earlier one fails.
+func Diagnose(ctx context.Context, cfg *config.Config) *Diagnosis {
+	diagnosis := &Diagnosis{}
+
+	check := func(name string, checkFn func(ctx context.Context) (string, error)) {
+		checkCtx, cancel := context.WithTimeout(ctx, DiagnosticTimeout)
+		defer cancel()
+
+		detail, err := checkFn(checkCtx)
+
+		result := &DiagnosticCheck{Name: name, Detail: detail}
+		if errors.Is(err, errSkipped) {
+			result.Skipped = true
+		} else {
+			result.Err = err
+		}
+
+		diagnosis.Checks = append(diagnosis.Checks, result)
+	}
+
+	check("dns", func(ctx context.Context) (string, error) { return diagnoseDNS(ctx, cfg) })
+	check("tls", func(ctx context.Context) (string, error) { return diagnoseTLS(ctx, cfg) })
+	check("auth", func(ctx context.Context) (string, error) { return diagnoseAuth(ctx, cfg) })
+
+	for _, dest := range cfg.DestinationList() {
+		dns := dest.ConnectionString
+		check("storage "+redactConnectionString(dns), func(ctx context.Context) (string, error) {
+			return diagnoseStorage(ctx, dns)
+		})
+	}
+
+	check("rate limit", func(context.Context) (string, error) { return diagnoseRateLimit(cfg) })
+
+	return diagnosis
+}
+
+// errSkipped is returned by a check that does not apply to the configuration.
+var errSkipped = fmt.Errorf("skipped")
+
+// diagnoseDNS will resolve the host of the web API.
+func diagnoseDNS(ctx context.Context, cfg *config.Config) (string, error) {
+	host := cfg.URL.Hostname()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+
+	return fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", ")), nil
+}
+
+// diagnoseTLS will connect to the web API over TLS and verify its certificate.
+func diagnoseTLS(ctx context.Context, cfg *config.Config) (string, error) {
+	if cfg.URL.Scheme != "https" {
+		return fmt.Sprintf("%s is not served over TLS", cfg.URL.Scheme), errSkipped
+	}
+
+	addr := cfg.URL.Host
+	if cfg.URL.Port() == "" {
+		addr = net.JoinHostPort(cfg.URL.Hostname(), "443")
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: cfg.URL.Hostname(), MinVersion: tls.VersionTLS12}}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok || len(tlsConn.ConnectionState().PeerCertificates) == 0 {
+		return "", fmt.Errorf("%s did not present a certificate", addr)
+	}
+
+	cert := tlsConn.ConnectionState().PeerCertificates[0]
+
+	return fmt.Sprintf("certificate for %s is valid until %s", cfg.URL.Hostname(),
+		cert.NotAfter.UTC().Format(time.RFC3339)), nil
+}
+
+// diagnoseAuth will probe the credentials of the configuration with a single request, see "ProbeAuth".
+func diagnoseAuth(ctx context.Context, cfg *config.Config) (string, error) {
+	probe, err := ProbeAuth(ctx, cfg, "")
+	if errors.Is(err, ErrNoAuthProbe) {
+		return "no endpoint to probe", errSkipped
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s accepted in %s", probe.Method, probe.URL, probe.Duration.Round(time.Millisecond)), nil
+}
+
+// diagnoseStorage will connect to the storage device and ping it.
+func diagnoseStorage(ctx context.Context, dns string) (string, error) {
+	repo, err := repository.New(ctx, dns)
+	if err != nil {
+		return "", classify(ErrStorage, fmt.Errorf("failed to connect: %w", err))
+	}
+
+	defer repo.Close()
+
+	// Storage devices are pinged without a context, so the ping is abandoned if it takes too long.
+	errs := make(chan error, 1)
+	go func() { errs <- repo.Ping() }()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			return "", classify(ErrStorage, fmt.Errorf("failed to ping: %w", err))
+		}
+	case <-ctx.Done():
+		return "", classify(ErrStorage, fmt.Errorf("ping timed out: %w", ctx.Err()))
+	}
+
+	return "connected", nil
+}
+
+// diagnoseRateLimit will check that the rate limit is not so high that it is most likely a typo, and estimate how long
+// the requests to the web API take at the rate limit.
+func diagnoseRateLimit(cfg *config.Config) (string, error) {
+	burst, period := *cfg.RateLimitConfig.Burst, *cfg.RateLimitConfig.Period
+	perSecond := float64(burst) / period.Seconds()
+
+	detail := fmt.Sprintf("%d requests per %s", burst, period)
+
+	if plan, err := NewPlan(cfg); err == nil {
+		minimum := time.Duration(float64(plan.Fetches()) / perSecond * float64(time.Second))
+		detail += fmt.Sprintf(", %d requests to the web API take at least %s", plan.Fetches(),
+			minimum.Round(time.Second))
+	}
+
+	if perSecond > maxSaneRate {
+		return detail, fmt.Errorf("%w: %.0f requests per second, check that the period is not a typo",
+			config.ErrInvalidRateLimit, perSecond)
+	}
+
+	return detail, nil
+}
+
+// Failed will return true if any check of the diagnosis failed.
+func (diagnosis *Diagnosis) Failed() bool {
+	for _, check := range diagnosis.Checks {
+		if check.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Write will write the diagnosis as text, with a line for each check and a summary of the checks that failed.
+func (diagnosis *Diagnosis) Write(wtr io.Writer) error {
+	var text strings.Builder
+
+	failed := 0
+
+	for _, check := range diagnosis.Checks {
+		switch {
+		case check.Err != nil:
+			failed++
+
+			fmt.Fprintf(&text, "FAIL %s: %v\n", check.Name, check.Err)
+		case check.Skipped:
+			fmt.Fprintf(&text, "SKIP %s: %s\n", check.Name, check.Detail)
+		default:
+			fmt.Fprintf(&text, "PASS %s: %s\n", check.Name, check.Detail)
+		}
+	}
+
+	fmt.Fprintf(&text, "Doctor: %d checks, %d failed.\n", len(diagnosis.Checks), failed)
+
+	if _, err := io.WriteString(wtr, text.String()); err != nil {
+		return fmt.Errorf("unable to write diagnosis: %w", err)
+	}
+
+	return nil
+}
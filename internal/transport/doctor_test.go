@@ -0,0 +1,118 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	handler := http.HandlerFunc(func(wtr http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			wtr.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// The certificate of the TLS server is self-signed, so it is not trusted.
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	t.Cleanup(tlsServer.Close)
+
+	for _, tcase := range []struct {
+		name      string
+		url       string
+		bearer    string
+		rateLimit string
+		dns       string
+		want      []string
+	}{
+		{
+			name:      "healthy",
+			url:       server.URL,
+			bearer:    "secret",
+			rateLimit: "{burst: 5, period: 1s}",
+			dns:       "stdout://",
+			want: []string{
+				"PASS dns: 127.0.0.1 resolves to 127.0.0.1",
+				"SKIP tls: http is not served over TLS",
+				"PASS auth: GET " + server.URL + "/candles accepted",
+				"PASS storage stdout:: connected",
+				"PASS rate limit: 5 requests per 1s, 1 requests to the web API take at least 0s",
+				"Doctor: 5 checks, 0 failed.",
+			},
+		},
+		{
+			name:      "unhealthy",
+			url:       tlsServer.URL,
+			bearer:    "wrong",
+			rateLimit: "{burst: 5, period: 1ms}",
+			dns:       "postgresql://root@127.0.0.1:1/db?sslmode=disable",
+			want: []string{
+				"PASS dns: 127.0.0.1 resolves to 127.0.0.1",
+				"FAIL tls: failed to connect to " + strings.TrimPrefix(tlsServer.URL, "https://"),
+				"FAIL auth: ",
+				"FAIL storage postgresql://root@127.0.0.1:1/db?sslmode=disable: ",
+				"FAIL rate limit: invalid rate limit configuration: 5000 requests per second",
+				"Doctor: 5 checks, 4 failed.",
+			},
+		},
+	} {
+		cfg, err := config.Parse(ctx, []byte(fmt.Sprintf(`
+url: %s
+authentication: {auth2: {bearer: %s}}
+rateLimit: %s
+connectionStrings: [%q]
+requests: [{endpoint: /candles}]
+`, tcase.url, tcase.bearer, tcase.rateLimit, tcase.dns)))
+		if err != nil {
+			t.Fatalf("%s: failed to parse config: %v", tcase.name, err)
+		}
+
+		diagnosis := Diagnose(ctx, cfg)
+
+		var out bytes.Buffer
+		if err := diagnosis.Write(&out); err != nil {
+			t.Fatalf("%s: failed to write diagnosis: %v", tcase.name, err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(tcase.want) {
+			t.Fatalf("%s: expected %d lines, got:\n%s", tcase.name, len(tcase.want), out.String())
+		}
+
+		for idx, want := range tcase.want {
+			if !strings.HasPrefix(lines[idx], want) {
+				t.Errorf("%s: expected line %d to start with %q, got %q", tcase.name, idx, want, lines[idx])
+			}
+		}
+
+		if diagnosis.Failed() != (tcase.name == "unhealthy") {
+			t.Errorf("%s: expected the diagnosis to fail only if a check failed", tcase.name)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alpstable/gidari/tools"
+)
+
+// runContextKey is the key of the context of the run in the context of its writes.
+type runContextKey struct{}
+
+// detachedContext has the values of its parent, e.g. its trace, but is not cancelled with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline will return that the context has no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done will return nil, since the context is never cancelled.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err will return nil, since the context is never cancelled.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value will return the value of the parent for the key.
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+// drainContext will return the context that the writes of the run are made with. Once the context of the run is
+// cancelled, the writes that are in flight are given the timeout to finish before the returned context is cancelled
+// too, so that the transactions of the requests that have been fetched can be committed instead of being interrupted.
+func drainContext(ctx context.Context, timeout time.Duration, logger tools.Logger) (context.Context, func()) {
+	drainCtx, cancel := context.WithCancel(context.WithValue(detachedContext{parent: ctx}, runContextKey{}, ctx))
+
+	go func() {
+		select {
+		case <-drainCtx.Done():
+			return
+		case <-ctx.Done():
+		}
+
+		msg := fmt.Sprintf("run cancelled, draining the writes in flight for up to %v", timeout)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-drainCtx.Done():
+		case <-timer.C:
+			cancel()
+		}
+	}()
+
+	return drainCtx, cancel
+}
+
+// runErr will return the error of the context of the run that the context of a write was drained from, or the error
+// of the context itself, so that the run does not start any new work once it has been cancelled.
+func runErr(ctx context.Context) error {
+	if runCtx, ok := ctx.Value(runContextKey{}).(context.Context); ok {
+		return runCtx.Err()
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,61 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDrainContext(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	type key struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+
+	drainCtx, stopDrain := drainContext(ctx, 50*time.Millisecond, logger)
+	defer stopDrain()
+
+	if drainCtx.Value(key{}) != "value" {
+		t.Errorf("expected the values of the run, got %v", drainCtx.Value(key{}))
+	}
+
+	cancel()
+
+	// The writes are drained for the timeout, but no new work is started.
+	if err := drainCtx.Err(); err != nil {
+		t.Errorf("expected the writes to be drained, got %v", err)
+	}
+
+	if err := runErr(drainCtx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the run to be cancelled, got %v", err)
+	}
+
+	select {
+	case <-drainCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the drain to time out")
+	}
+
+	// A context that was not drained is its own run.
+	if err := runErr(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error of the context, got %v", err)
+	}
+}
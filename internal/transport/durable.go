@@ -0,0 +1,250 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+)
+
+// durableEntryExt is the extension of the files of the durable queue, which hold the body of a response.
+const durableEntryExt = ".chunk"
+
+// durableQueue saves the body of each response to a file in the queue directory as it is read, until the request of
+// the response is committed, so that a run that crashed can be resumed without fetching the chunks that it had
+// fetched but not yet written. The entries of chunks are keyed like the checkpoint, see "chunkKey".
+type durableQueue struct {
+	dir    string
+	logger tools.Logger
+
+	mutex sync.Mutex
+
+	// pending are the keys of the entries that were saved by a previous run, which are replayed once rather than
+	// fetched. Entries that are saved by the run are not replayed, so that a chunk that is retried is fetched again.
+	pending  map[string]bool
+	replayed int
+	saved    int
+}
+
+// openDurableQueue will return the durable queue of the run, or nil if the configuration does not have one. If the
+// run is resumed, the entries that were saved by the previous runs are replayed. Otherwise they are removed, so that
+// a later run does not replay stale responses.
+func openDurableQueue(cfg *config.Config) (*durableQueue, error) {
+	if cfg.QueueDir == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(cfg.QueueDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create durable queue: %w", err)
+	}
+
+	entries, err := os.ReadDir(cfg.QueueDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read durable queue: %w", err)
+	}
+
+	queue := &durableQueue{dir: cfg.QueueDir, logger: cfg.Logger, pending: make(map[string]bool)}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		switch {
+		case strings.HasPrefix(name, "."):
+			// The entry was being saved when the previous run stopped.
+			os.Remove(filepath.Join(queue.dir, name))
+		case !strings.HasSuffix(name, durableEntryExt):
+		case cfg.Resume:
+			queue.pending[strings.TrimSuffix(name, durableEntryExt)] = true
+		default:
+			if err := os.Remove(filepath.Join(queue.dir, name)); err != nil {
+				return nil, fmt.Errorf("failed to reset durable queue: %w", err)
+			}
+		}
+	}
+
+	if len(queue.pending) > 0 {
+		msg := fmt.Sprintf("replaying %d fetched chunks from the durable queue", len(queue.pending))
+		tools.LogFormatter{Msg: msg}.Log(queue.logger, tools.LogLevelInfo)
+	}
+
+	return queue, nil
+}
+
+// path will return the path of the entry of the chunk with the key.
+func (queue *durableQueue) path(key string) string {
+	return filepath.Join(queue.dir, key+durableEntryExt)
+}
+
+// fetch will return the response of the flattened request from the durable queue if a previous run saved it, and
+// otherwise fetch it, saving its body to the queue as it is read. Without a durable queue, the response is fetched.
+func (queue *durableQueue) fetch(ctx context.Context, req *flattenedRequest) (*web.FetchResponse, error) {
+	if queue == nil {
+		return web.Fetch(ctx, req.fetchConfig)
+	}
+
+	key := chunkKey(req)
+
+	if rsp := queue.replay(ctx, req, key); rsp != nil {
+		return rsp, nil
+	}
+
+	rsp, err := web.Fetch(ctx, req.fetchConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(queue.dir, "."+key+"-")
+	if err != nil {
+		msg := fmt.Sprintf("unable to save %s to the durable queue: %v", req.fetchConfig.URL.Redacted(), err)
+		tools.LogFormatter{Msg: msg}.Log(queue.logger, tools.LogLevelWarn)
+
+		return rsp, nil
+	}
+
+	rsp.Body = &durableBody{ReadCloser: rsp.Body, queue: queue, file: file, path: queue.path(key)}
+
+	return rsp, nil
+}
+
+// replay will return the response of the flattened request that a previous run saved, or nil if it has none. The
+// entry is only replayed once.
+func (queue *durableQueue) replay(ctx context.Context, req *flattenedRequest, key string) *web.FetchResponse {
+	queue.mutex.Lock()
+	pending := queue.pending[key]
+	delete(queue.pending, key)
+	queue.mutex.Unlock()
+
+	if !pending {
+		return nil
+	}
+
+	file, err := os.Open(queue.path(key))
+	if err != nil {
+		msg := fmt.Sprintf("unable to replay %s from the durable queue: %v", req.fetchConfig.URL.Redacted(), err)
+		tools.LogFormatter{Msg: msg}.Log(queue.logger, tools.LogLevelWarn)
+
+		return nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.fetchConfig.Method, req.fetchConfig.URL.String(), nil)
+	if err != nil {
+		file.Close()
+
+		return nil
+	}
+
+	queue.mutex.Lock()
+	queue.replayed++
+	queue.mutex.Unlock()
+
+	return &web.FetchResponse{Request: httpReq, Body: file}
+}
+
+// ack will remove the entries of the flattened requests of a request that has been committed.
+func (queue *durableQueue) ack(reqs []*flattenedRequest) {
+	if queue == nil {
+		return
+	}
+
+	for _, req := range reqs {
+		err := os.Remove(queue.path(chunkKey(req)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			tools.LogFormatter{Msg: err.Error()}.Log(queue.logger, tools.LogLevelWarn)
+		}
+	}
+}
+
+// logSummary will log how many responses the run saved to and replayed from the durable queue.
+func (queue *durableQueue) logSummary() {
+	if queue == nil {
+		return
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	msg := fmt.Sprintf("run summary: %d chunks saved to and %d replayed from the durable queue", queue.saved,
+		queue.replayed)
+	tools.LogFormatter{Msg: msg}.Log(queue.logger, tools.LogLevelInfo)
+}
+
+// durableBody copies the body of a response to a temporary file as it is read. Once the body has been read in full,
+// the file is synced and renamed to the entry of its chunk before the data of the chunk is sent, so that an entry is
+// never partial. The file is removed if the body is closed before then.
+type durableBody struct {
+	io.ReadCloser
+
+	queue *durableQueue
+	file  *os.File
+	path  string
+
+	// done is whether the file has been saved or removed, and failed whether the body could not be copied to it.
+	done   bool
+	failed bool
+}
+
+// Read will read from the body, copying what was read to the file, which is saved once the body has been read.
+func (body *durableBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+
+	if n > 0 && !body.failed {
+		if _, writeErr := body.file.Write(p[:n]); writeErr != nil {
+			body.failed = true
+
+			msg := fmt.Sprintf("unable to save response to the durable queue: %v", writeErr)
+			tools.LogFormatter{Msg: msg}.Log(body.queue.logger, tools.LogLevelWarn)
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		body.finish(!body.failed)
+	}
+
+	return n, err
+}
+
+// Close will close the body, removing the file if the body was not read in full.
+func (body *durableBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.finish(false)
+
+	return err
+}
+
+// finish will sync and close the file, renaming it to the entry of its chunk if it is saved and removing it
+// otherwise.
+func (body *durableBody) finish(save bool) {
+	if body.done {
+		return
+	}
+
+	body.done = true
+
+	save = save && body.file.Sync() == nil
+	if err := body.file.Close(); err != nil || !save || os.Rename(body.file.Name(), body.path) != nil {
+		os.Remove(body.file.Name())
+
+		return
+	}
+
+	body.queue.mutex.Lock()
+	body.queue.saved++
+	body.queue.mutex.Unlock()
+}
@@ -0,0 +1,183 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestDurableQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	var fetches int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt64(&fetches, 1)
+
+		_, _ = wtr.Write([]byte(`[{"id":"1"},{"id":"2"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	queueDir := t.TempDir()
+
+	for _, tcase := range []struct {
+		name          string
+		streamRecords int
+	}{
+		{name: "whole"},
+		{name: "streamed", streamRecords: 1},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		req := &config.Request{Table: "candles"}
+		cfg := &config.Config{
+			Requests:      []*config.Request{req},
+			Logger:        logger,
+			QueueDir:      queueDir,
+			StreamRecords: tcase.streamRecords,
+		}
+
+		flatReq := newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+
+		// newTxn will return the transaction of the request in a run with the durable queue, once its chunk has
+		// been fetched.
+		newTxn := func(resume bool) (*requestTxn, *durableQueue) {
+			cfg.Resume = resume
+
+			queue, err := openDurableQueue(cfg)
+			if err != nil {
+				t.Fatalf("%s: failed to open durable queue: %v", tcase.name, err)
+			}
+
+			txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+			txn.queue = queue
+			txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+			go txn.newJob(flatReq).run(ctx, 1)
+
+			return txn, queue
+		}
+
+		// The first run stops before its chunk is written, as if it had crashed.
+		atomic.StoreInt64(&fetches, 0)
+
+		txn, _ := newTxn(false)
+		for job := txn.receive(0); job.more; {
+			job = txn.receive(0)
+		}
+
+		entry := filepath.Join(queueDir, chunkKey(flatReq)+durableEntryExt)
+		if _, err := os.Stat(entry); err != nil {
+			t.Fatalf("%s: expected the chunk to be saved to the durable queue: %v", tcase.name, err)
+		}
+
+		// The resumed run writes the chunk without fetching it again.
+		txn, queue := newTxn(true)
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+		if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+			t.Fatalf("%s: failed to upsert: %v", tcase.name, err)
+		}
+
+		if fetched := atomic.LoadInt64(&fetches); fetched != 1 || queue.replayed != 1 {
+			t.Errorf("%s: expected the chunk to be fetched once and replayed once, got %d and %d", tcase.name,
+				fetched, queue.replayed)
+		}
+
+		if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != 2 {
+			t.Errorf("%s: expected 2 candles, got %v", tcase.name, candles)
+		}
+
+		if _, err := os.Stat(entry); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the entry to be removed once committed, got %v", tcase.name, err)
+		}
+	}
+}
+
+func TestOpenDurableQueue(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	if queue, err := openDurableQueue(&config.Config{}); queue != nil || err != nil {
+		t.Errorf("expected no durable queue, got %v and %v", queue, err)
+	}
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"a" + durableEntryExt, ".b-123", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`[]`), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	queue, err := openDurableQueue(&config.Config{QueueDir: dir, Resume: true, Logger: logger})
+	if err != nil {
+		t.Fatalf("failed to open durable queue: %v", err)
+	}
+
+	if !queue.pending["a"] || len(queue.pending) != 1 {
+		t.Errorf("expected the entry to be pending, got %v", queue.pending)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".b-123")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial entry to be removed, got %v", err)
+	}
+
+	// A run that is not resumed starts from an empty queue.
+	if _, err := openDurableQueue(&config.Config{QueueDir: dir, Logger: logger}); err != nil {
+		t.Fatalf("failed to open durable queue: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read durable queue: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "notes.txt" {
+		t.Errorf("expected only the unrelated file to be kept, got %v", entries)
+	}
+}
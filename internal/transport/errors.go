@@ -0,0 +1,54 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import "fmt"
+
+// Classes of the errors of a run, so that callers can tell where a run failed with "errors.Is".
+var (
+	// ErrFetch is matched by errors fetching data from the web API.
+	ErrFetch = fmt.Errorf("unable to fetch data")
+
+	// ErrStorage is matched by errors opening, writing to, or committing the transactions of storage.
+	ErrStorage = fmt.Errorf("storage error")
+
+	// ErrAborted is matched by the error of a run that was aborted by a request that failed with the "fail" policy,
+	// and by the errors of the requests that were not written because of it.
+	ErrAborted = fmt.Errorf("run aborted")
+
+	// ErrMaxDuration is matched by the error of a run that was wound down because it took longer than the max
+	// duration of its configuration.
+	ErrMaxDuration = fmt.Errorf("run exceeded its max duration")
+
+	// ErrSchema is matched by the errors of chunks whose records do not have the fields that their table declares,
+	// with the strict decoding mode.
+	ErrSchema = fmt.Errorf("unexpected schema")
+
+	// ErrStalled is matched by the error of a run that was wound down because one of its workers made no progress
+	// for the stall period of its watchdog.
+	ErrStalled = fmt.Errorf("worker stalled")
+
+	// ErrPanic is matched by the errors of the jobs that a worker panicked on, which were recovered.
+	ErrPanic = fmt.Errorf("worker panicked")
+)
+
+// classError is an error that matches its class, while keeping the error it wraps.
+type classError struct{ class, err error }
+
+func (e *classError) Error() string        { return e.err.Error() }
+func (e *classError) Unwrap() error        { return e.err }
+func (e *classError) Is(target error) bool { return target == e.class }
+
+// classify will return the error so that it matches the class with "errors.Is", or nil if the error is nil.
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classError{class: class, err: err}
+}
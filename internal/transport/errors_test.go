@@ -0,0 +1,91 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestErrorClasses(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("classify", func(t *testing.T) {
+		t.Parallel()
+
+		if err := classify(ErrStorage, nil); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+
+		err := classify(ErrStorage, proto.Transient(errFetch))
+		if !errors.Is(err, ErrStorage) || errors.Is(err, ErrFetch) || !errors.Is(err, proto.ErrTransient) {
+			t.Errorf("expected only %v and the wrapped errors to match, got %v", ErrStorage, err)
+		}
+
+		if err.Error() != errFetch.Error() {
+			t.Errorf("expected the message of the wrapped error, got %q", err.Error())
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			status       int
+			unauthorized bool
+		}{
+			{status: http.StatusUnauthorized, unauthorized: true},
+			{status: http.StatusForbidden, unauthorized: true},
+			{status: http.StatusInternalServerError},
+		} {
+			server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+				wtr.WriteHeader(tcase.status)
+			}))
+			defer server.Close()
+
+			uri, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatalf("failed to parse URL: %v", err)
+			}
+
+			_, err = web.Fetch(ctx, &web.FetchConfig{
+				C:           &web.Client{},
+				Method:      http.MethodGet,
+				URL:         uri,
+				RateLimiter: rate.NewLimiter(rate.Inf, 1),
+			})
+			if !errors.Is(err, web.ErrGettingResponse) || errors.Is(err, web.ErrUnauthorized) != tcase.unauthorized {
+				t.Errorf("%d: expected unauthorized to be %v, got %v", tcase.status, tcase.unauthorized, err)
+			}
+		}
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &config.Config{ConnectionStrings: []string{"unknown://"}, Logger: tools.NewLogrusLogger(logrus.New())}
+		if _, _, err := repos(ctx, cfg); !errors.Is(err, ErrStorage) {
+			t.Errorf("expected %v, got %v", ErrStorage, err)
+		}
+	})
+}
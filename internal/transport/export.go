@@ -0,0 +1,138 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+)
+
+// ErrUnknownDestination is returned when a destination is not one of the destinations of a configuration.
+var ErrUnknownDestination = fmt.Errorf("unknown destination")
+
+// Export is a request to read the tables of a configuration back out of one of its destinations, and write them to
+// files.
+type Export struct {
+	// Destination is the connection string of the destination to read from, or its redacted form. The first
+	// destination of the configuration is read from if it is empty.
+	Destination string
+
+	// Tables are the names of the tables to export, without the prefix and suffix of the configuration. Every table
+	// that the requests of the configuration route to the destination is exported if it is empty.
+	Tables []string
+
+	// Dir is the directory that a file is written to for each table, named after the table in storage.
+	Dir string
+
+	// Format is the format of the files: "ndjson", "csv", or "parquet", see the "file" storage.
+	Format string
+}
+
+// ExportedTable is a table that was exported, with the number of records written to its file.
+type ExportedTable struct {
+	Table   string
+	Records int64
+}
+
+// RunExport will read every record of the tables of the export from its destination, and replace the file of each
+// table with them. The files are written in one transaction, so they are left as they were if any table cannot be
+// read. Destinations that cannot be queried, like files, cannot be exported from.
+func RunExport(ctx context.Context, cfg *config.Config, export *Export) ([]*ExportedTable, error) {
+	dest, err := exportDestination(cfg, export.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := repository.New(ctx, dest.ConnectionString)
+	if err != nil {
+		return nil, classify(ErrStorage, fmt.Errorf("failed to create repository: %w", err))
+	}
+
+	defer src.Close()
+
+	out, err := repository.NewTx(ctx, "file://"+export.Dir+"?"+url.Values{"format": {export.Format}}.Encode())
+	if err != nil {
+		return nil, classify(ErrStorage, fmt.Errorf("failed to create export repository: %w", err))
+	}
+
+	defer out.Close()
+
+	tables := export.Tables
+	if len(tables) == 0 {
+		tables = routedTables(cfg, dest)
+	}
+
+	exported := make([]*ExportedTable, 0, len(tables))
+
+	for _, table := range tables {
+		name := cfg.StorageTable(table)
+
+		rsp, err := src.Query(ctx, &proto.QueryRequest{Table: name})
+		if err != nil {
+			_ = out.Rollback()
+
+			return nil, classify(ErrStorage, fmt.Errorf("failed to read %q: %w", name, err))
+		}
+
+		data, err := json.Marshal(rsp.Records)
+		if err != nil {
+			_ = out.Rollback()
+
+			return nil, fmt.Errorf("failed to encode %q: %w", name, err)
+		}
+
+		out.Transact(func(ctx context.Context, repo repository.Generic) error {
+			if _, err := repo.Truncate(ctx, &proto.TruncateRequest{Tables: []string{name}}); err != nil {
+				return fmt.Errorf("error truncating %q: %w", name, err)
+			}
+
+			if len(rsp.Records) == 0 {
+				return nil
+			}
+
+			if _, err := repo.Upsert(ctx, &proto.UpsertRequest{Table: name, Data: data}); err != nil {
+				return fmt.Errorf("error writing %q: %w", name, err)
+			}
+
+			return nil
+		})
+
+		exported = append(exported, &ExportedTable{Table: name, Records: int64(len(rsp.Records))})
+	}
+
+	if err := out.Commit(); err != nil {
+		return nil, classify(ErrStorage, fmt.Errorf("unable to write export: %w", err))
+	}
+
+	return exported, nil
+}
+
+// exportDestination will return the destination of the configuration with the connection string, or its redacted
+// form, or the first destination if it is empty.
+func exportDestination(cfg *config.Config, connectionString string) (*config.Destination, error) {
+	dests := cfg.DestinationList()
+
+	for _, dest := range dests {
+		if connectionString == "" || dest.ConnectionString == connectionString ||
+			redactConnectionString(dest.ConnectionString) == connectionString {
+			return dest, nil
+		}
+	}
+
+	if connectionString == "" {
+		return nil, fmt.Errorf("%w: the configuration has no destinations", ErrUnknownDestination)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownDestination, redactConnectionString(connectionString))
+}
@@ -0,0 +1,125 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/file"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// queryableFile is file storage that can be queried for a fixed set of records in each table.
+type queryableFile struct {
+	*file.File
+	records map[string][]map[string]interface{}
+}
+
+func (stg *queryableFile) Query(_ context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
+	rsp := &proto.QueryResponse{}
+
+	for _, record := range stg.records[req.Table] {
+		data, err := structpb.NewStruct(record)
+		if err != nil {
+			return nil, err
+		}
+
+		rsp.Records = append(rsp.Records, data)
+	}
+
+	return rsp, nil
+}
+
+func TestRunExport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	err := proto.RegisterConstructor("exporttest", func(ctx context.Context, _ string) (*proto.StorageService, error) {
+		stg, err := file.New(ctx, "file://"+t.TempDir())
+		if err != nil {
+			return nil, err
+		}
+
+		return &proto.StorageService{Storage: &queryableFile{File: stg, records: map[string][]map[string]interface{}{
+			"dev_candles": {{"id": "1", "price": 1.5}, {"id": "2", "price": 1.6}},
+		}}}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register storage: %v", err)
+	}
+
+	cfg := &config.Config{
+		ConnectionStrings: []string{"exporttest://"},
+		Requests: []*config.Request{
+			{Endpoint: "/candles", Table: "candles"},
+			{Endpoint: "/trades", Table: "trades"},
+		},
+		TablePrefix: "dev_",
+		Logger:      logger,
+	}
+
+	dir := t.TempDir()
+
+	exported, err := RunExport(ctx, cfg, &Export{Dir: dir, Format: "csv"})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if len(exported) != 2 || *exported[0] != (ExportedTable{Table: "dev_candles", Records: 2}) ||
+		*exported[1] != (ExportedTable{Table: "dev_trades"}) {
+		t.Errorf("expected candles and trades to be exported, got %+v", exported)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "dev_candles.csv"))
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+
+	if expected := "id,price\n1,1.5\n2,1.6\n"; string(got) != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	for _, tcase := range []struct {
+		name   string
+		export *Export
+		cfg    *config.Config
+		err    error
+	}{
+		{
+			name:   "unknown destination",
+			export: &Export{Destination: "postgresql://localhost:5432/db", Dir: dir},
+			cfg:    cfg,
+			err:    ErrUnknownDestination,
+		},
+		{
+			name:   "not queryable",
+			export: &Export{Dir: dir},
+			cfg:    &config.Config{ConnectionStrings: []string{"file://" + t.TempDir()}, Requests: cfg.Requests},
+			err:    proto.ErrQueryNotSupported,
+		},
+	} {
+		if _, err := RunExport(ctx, tcase.cfg, tcase.export); !errors.Is(err, tcase.err) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.err, err)
+		}
+	}
+}
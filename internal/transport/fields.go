@@ -0,0 +1,147 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import "github.com/alpstable/gidari/config"
+
+// fieldTree is a tree of the paths of fields, keyed by field. A field whose subtree is nil names the whole field, and
+// one with a subtree names the fields of its nested objects.
+type fieldTree map[string]fieldTree
+
+// newFieldTree will build the tree of the paths of fields. A path that names a whole field takes precedence over the
+// paths that name its nested fields.
+func newFieldTree(paths []string) fieldTree {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	tree := make(fieldTree)
+
+	for _, path := range paths {
+		node := tree
+		fields := config.FieldPath(path)
+
+		for idx, field := range fields {
+			sub, ok := node[field]
+			if ok && sub == nil {
+				break
+			}
+
+			if idx == len(fields)-1 {
+				node[field] = nil
+
+				break
+			}
+
+			if !ok {
+				sub = make(fieldTree)
+				node[field] = sub
+			}
+
+			node = sub
+		}
+	}
+
+	return tree
+}
+
+// fieldFilter keeps the included fields of each record of a request, and then drops its excluded fields.
+type fieldFilter struct {
+	include fieldTree
+	exclude fieldTree
+}
+
+// newFieldFilter will return the filter of the included and excluded fields of the request, or nil if it has none.
+func newFieldFilter(req *config.Request) *fieldFilter {
+	if len(req.IncludeFields) == 0 && len(req.ExcludeFields) == 0 {
+		return nil
+	}
+
+	return &fieldFilter{include: newFieldTree(req.IncludeFields), exclude: newFieldTree(req.ExcludeFields)}
+}
+
+// apply will keep the included fields of each record in the data, and then drop the excluded fields. A nil filter
+// returns the data as is.
+func (filter *fieldFilter) apply(data []byte) ([]byte, error) {
+	if filter == nil {
+		return data, nil
+	}
+
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		if filter.include != nil {
+			record = includeFields(record, filter.include)
+		}
+
+		excludeFields(record, filter.exclude)
+
+		return record, nil
+	})
+}
+
+// includeFields will return the fields of the object that are in the tree. The nested fields of a field are included
+// in each of its objects, and in each object of its lists. Values that are not objects are kept as they are.
+func includeFields(obj map[string]interface{}, tree fieldTree) map[string]interface{} {
+	included := make(map[string]interface{}, len(tree))
+
+	for field, sub := range tree {
+		val, ok := obj[field]
+		if !ok {
+			continue
+		}
+
+		if sub != nil {
+			val = includeNested(val, sub)
+		}
+
+		included[field] = val
+	}
+
+	return included
+}
+
+// includeNested will include the fields in the tree of the value, if it is an object or a list.
+func includeNested(val interface{}, tree fieldTree) interface{} {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		return includeFields(val, tree)
+	case []interface{}:
+		for idx, elem := range val {
+			val[idx] = includeNested(elem, tree)
+		}
+
+		return val
+	default:
+		return val
+	}
+}
+
+// excludeFields will delete the fields of the object that are in the tree. The nested fields of a field are deleted
+// from each of its objects, and from each object of its lists.
+func excludeFields(obj map[string]interface{}, tree fieldTree) {
+	for field, sub := range tree {
+		if sub == nil {
+			delete(obj, field)
+
+			continue
+		}
+
+		excludeNested(obj[field], sub)
+	}
+}
+
+// excludeNested will exclude the fields in the tree from the value, if it is an object or a list.
+func excludeNested(val interface{}, tree fieldTree) {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		excludeFields(val, tree)
+	case []interface{}:
+		for _, elem := range val {
+			excludeNested(elem, tree)
+		}
+	}
+}
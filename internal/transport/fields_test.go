@@ -0,0 +1,78 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestFieldFilter(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`[{"id":1,"price":"1.5","debug":{"trace":"x"},"meta":{"request_id":"a","trace":"y",` +
+		`"tags":[{"k":"a","v":1},"b"]}},2]`)
+
+	for _, tcase := range []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected string
+	}{
+		{
+			name:     "include",
+			include:  []string{"id", "price", "missing"},
+			expected: `[{"id":1,"price":"1.5"},2]`,
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{"debug", "meta.trace", "meta.tags.v", "missing.field"},
+			expected: `[{"id":1,"meta":{"request_id":"a","tags":[{"k":"a"},"b"]},"price":"1.5"},2]`,
+		},
+		{
+			name:     "include nested",
+			include:  []string{"id", "meta.request_id", "meta.tags.k", "price.value"},
+			expected: `[{"id":1,"meta":{"request_id":"a","tags":[{"k":"a"},"b"]},"price":"1.5"},2]`,
+		},
+		{
+			name:     "include whole field",
+			include:  []string{"debug.trace", "debug"},
+			expected: `[{"debug":{"trace":"x"}},2]`,
+		},
+		{
+			name:     "include and exclude",
+			include:  []string{"id", "meta"},
+			exclude:  []string{"meta.tags", "meta.trace"},
+			expected: `[{"id":1,"meta":{"request_id":"a"}},2]`,
+		},
+	} {
+		filter := newFieldFilter(&config.Request{IncludeFields: tcase.include, ExcludeFields: tcase.exclude})
+
+		filtered, err := filter.apply(data)
+		if err != nil {
+			t.Fatalf("%s: failed to filter fields: %v", tcase.name, err)
+		}
+
+		if string(filtered) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, filtered)
+		}
+	}
+
+	if filter := newFieldFilter(&config.Request{}); filter != nil {
+		t.Errorf("expected no filter, got %v", filter)
+	}
+
+	var nilFilter *fieldFilter
+
+	if data, err := nilFilter.apply([]byte(`not json`)); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+}
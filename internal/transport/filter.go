@@ -0,0 +1,91 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/predicate"
+)
+
+// recordFilter stores only the records of a request that match each of its predicates.
+type recordFilter struct {
+	predicates []*predicate.Predicate
+}
+
+// newRecordFilter will parse the predicates of the filter of the request, returning nil if it has none.
+func newRecordFilter(req *config.Request) (*recordFilter, error) {
+	if len(req.Filter) == 0 {
+		return nil, nil
+	}
+
+	filter := &recordFilter{predicates: make([]*predicate.Predicate, 0, len(req.Filter))}
+
+	for _, expr := range req.Filter {
+		pred, err := predicate.Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse filter: %w", err)
+		}
+
+		filter.predicates = append(filter.predicates, pred)
+	}
+
+	return filter, nil
+}
+
+// match will return true if the record matches each of the predicates of the filter.
+func (filter *recordFilter) match(record map[string]interface{}) bool {
+	for _, pred := range filter.predicates {
+		if !pred.Match(record) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply will return the records in the data that match the filter, as a list. Values that are not records are kept.
+// A nil filter returns the data as is.
+func (filter *recordFilter) apply(data []byte) ([]byte, error) {
+	if filter == nil {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	values, ok := decoded.([]interface{})
+	if !ok {
+		values = []interface{}{decoded}
+	}
+
+	records := make([]interface{}, 0, len(values))
+
+	for _, val := range values {
+		if record, ok := val.(map[string]interface{}); ok && !filter.match(record) {
+			continue
+		}
+
+		records = append(records, val)
+	}
+
+	out, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	return out, nil
+}
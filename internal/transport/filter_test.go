@@ -0,0 +1,65 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestRecordFilter(t *testing.T) {
+	t.Parallel()
+
+	filter, err := newRecordFilter(&config.Request{Filter: []string{`status == "done"`, "volume > 0"}})
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		data     string
+		expected string
+	}{
+		{
+			data:     `[{"id":1,"status":"done","volume":"1.5"},{"id":2,"status":"open","volume":2},{"id":3,"status":"done"},2]`,
+			expected: `[{"id":1,"status":"done","volume":"1.5"},2]`,
+		},
+		{data: `{"id":1,"status":"done","volume":1}`, expected: `[{"id":1,"status":"done","volume":1}]`},
+		{data: `{"id":1,"status":"open","volume":1}`, expected: `[]`},
+		{data: `[]`, expected: `[]`},
+	} {
+		filtered, err := filter.apply([]byte(tcase.data))
+		if err != nil {
+			t.Fatalf("%s: failed to filter records: %v", tcase.data, err)
+		}
+
+		if string(filtered) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.data, tcase.expected, filtered)
+		}
+	}
+
+	if _, err := filter.apply([]byte(`not json`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+
+	if _, err := newRecordFilter(&config.Request{Filter: []string{"status = done"}}); err == nil {
+		t.Errorf("expected an error for an invalid predicate")
+	}
+
+	if filter, err := newRecordFilter(&config.Request{}); filter != nil || err != nil {
+		t.Errorf("expected no filter, got %v and %v", filter, err)
+	}
+
+	var nilFilter *recordFilter
+
+	if data, err := nilFilter.apply([]byte(`not json`)); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// recordHash will return a hash of the fields of the record, or of every field if no fields are given. The fields are
+// hashed as JSON with sorted keys, so that the hash does not depend on the order of the fields. Missing fields are
+// hashed as null.
+func recordHash(record map[string]interface{}, fields []string) (string, error) {
+	hashed := record
+	if len(fields) > 0 {
+		hashed = make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			hashed[field] = record[field]
+		}
+	}
+
+	data, err := json.Marshal(hashed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// addHashKeys will add the hash key to each JSON object in the data, which is either an object or a list of objects.
+func addHashKeys(data []byte, hashKey *config.HashKey) ([]byte, error) {
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		hash, err := recordHash(record, hashKey.Fields)
+		if err != nil {
+			return nil, err
+		}
+
+		record[hashKey.ColumnName()] = hash
+
+		return record, nil
+	})
+}
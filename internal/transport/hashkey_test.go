@@ -0,0 +1,83 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestAddHashKeys(t *testing.T) {
+	t.Parallel()
+
+	hashKey := &config.HashKey{Fields: []string{"trade_id", "price"}}
+
+	first, err := addHashKeys([]byte(`[{"trade_id":1,"price":2.5,"size":1}]`), hashKey)
+	if err != nil {
+		t.Fatalf("failed to add hash keys: %v", err)
+	}
+
+	// Fields that are not hashed, and the order of the fields, do not change the hash.
+	second, err := addHashKeys([]byte(`[{"size":2,"price":2.5,"trade_id":1}]`), hashKey)
+	if err != nil {
+		t.Fatalf("failed to add hash keys: %v", err)
+	}
+
+	firstRecords, err := decodeRecords(first)
+	if err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+
+	secondRecords, err := decodeRecords(second)
+	if err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+
+	hash := firstRecords[0][config.DefaultHashKeyColumn]
+	if hash == nil || hash != secondRecords[0][config.DefaultHashKeyColumn] {
+		t.Errorf("expected the same hash for both records, got %v and %v", firstRecords, secondRecords)
+	}
+
+	third, err := addHashKeys([]byte(`{"trade_id":1,"price":3}`), &config.HashKey{Column: "hash"})
+	if err != nil {
+		t.Fatalf("failed to add hash keys: %v", err)
+	}
+
+	thirdRecords, err := decodeRecords(third)
+	if err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+
+	if got := thirdRecords[0]["hash"]; got == nil || got == hash {
+		t.Errorf("expected a different hash in the configured column, got %v", thirdRecords)
+	}
+}
+
+func TestNewFlattenedRequestHashKey(t *testing.T) {
+	t.Parallel()
+
+	req := &config.Request{Table: "trades", HashKey: &config.HashKey{Column: "hash"}}
+
+	flatReq := flattenRequest(req, url.URL{}, nil)
+	if !reflect.DeepEqual(flatReq.primaryKeys, []string{"hash"}) || flatReq.conflict != proto.ConflictInsertOnly {
+		t.Errorf("unexpected hash key request %+v", flatReq)
+	}
+
+	req.Conflict = proto.ConflictReplace
+
+	flatReq = flattenRequest(req, url.URL{}, nil)
+	if flatReq.conflict != proto.ConflictReplace {
+		t.Errorf("expected the configured conflict strategy, got %q", flatReq.conflict)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// maskedField is a field of the records of a request that is masked, with the path of its nested objects.
+type maskedField struct {
+	path []string
+	mask *config.Mask
+}
+
+// recordMask masks the fields of each record of a request.
+type recordMask struct {
+	fields []maskedField
+}
+
+// newRecordMask will return the mask of the fields of the request, or nil if it has none.
+func newRecordMask(req *config.Request) *recordMask {
+	if len(req.Mask) == 0 {
+		return nil
+	}
+
+	fields := make([]maskedField, 0, len(req.Mask))
+	for _, mask := range req.Mask {
+		fields = append(fields, maskedField{path: config.FieldPath(mask.Field), mask: mask})
+	}
+
+	return &recordMask{fields: fields}
+}
+
+// apply will mask the fields of each record in the data, in the order of the masks. A nil mask returns the data as is.
+func (mask *recordMask) apply(data []byte) ([]byte, error) {
+	if mask == nil {
+		return data, nil
+	}
+
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		for _, field := range mask.fields {
+			if err := maskPath(record, field.path, field.mask); err != nil {
+				return nil, fmt.Errorf("failed to mask %q: %w", field.mask.Field, err)
+			}
+		}
+
+		return record, nil
+	})
+}
+
+// maskPath will mask the field at the path of the value, in each of its objects and in each object of its lists. A
+// field that is missing is not masked.
+func maskPath(val interface{}, path []string, mask *config.Mask) error {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		field, ok := val[path[0]]
+		if !ok {
+			return nil
+		}
+
+		if len(path) > 1 {
+			return maskPath(field, path[1:], mask)
+		}
+
+		masked, err := maskValue(field, mask)
+		if err != nil {
+			return err
+		}
+
+		val[path[0]] = masked
+	case []interface{}:
+		for _, elem := range val {
+			if err := maskPath(elem, path, mask); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// maskValue will return the masked value. Null is kept as null. Strings, numbers, and booleans are hashed and
+// truncated as their text, objects and lists are hashed as their JSON, and an object or list that is truncated is
+// replaced with null, so that none of it is stored.
+func maskValue(val interface{}, mask *config.Mask) (interface{}, error) {
+	if val == nil || mask.Action == config.MaskNull {
+		return nil, nil
+	}
+
+	var text string
+
+	switch val := val.(type) {
+	case string:
+		text = val
+	case json.Number:
+		text = val.String()
+	case bool:
+		text = fmt.Sprint(val)
+	default:
+		if mask.Action == config.MaskTruncate {
+			return nil, nil
+		}
+
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal value: %w", err)
+		}
+
+		text = string(data)
+	}
+
+	if mask.Action == config.MaskTruncate {
+		return truncateText(text, mask.Length), nil
+	}
+
+	if mask.Salt == "" {
+		sum := sha256.Sum256([]byte(text))
+
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	hash := hmac.New(sha256.New, []byte(mask.Salt))
+
+	// Don't handle error because hash.Write method never returns an error.
+	hash.Write([]byte(text))
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// truncateText will keep the first characters of the text, or the last ones if the length is negative.
+func truncateText(text string, length int) string {
+	runes := []rune(text)
+
+	switch {
+	case length >= 0 && len(runes) > length:
+		return string(runes[:length])
+	case length < 0 && len(runes) > -length:
+		return string(runes[len(runes)+length:])
+	default:
+		return text
+	}
+}
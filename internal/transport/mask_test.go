@@ -0,0 +1,113 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestRecordMask(t *testing.T) {
+	t.Parallel()
+
+	const (
+		emailHash  = "08168cd80dfd534ab0f10af10f1303fe00af2d43ab5c1432360d137f8197e17a"
+		emailHMAC  = "0607236cc2fc521ca815254262b7014cb54eb5488f266e4777158cc52a33cfe9"
+		objectHash = "eb8ed3ccb5023093b56f490a46501e88d09736687e609fdbc1c71b3df8b9ccd3"
+	)
+
+	data := []byte(`[{"email":"a@example.com","account":"GB82WEST12345698765432","pin":1234,"ssn":"123",` +
+		`"meta":{"b":1},"owners":[{"email":"a@example.com"},{"name":"b"}],"note":null},2]`)
+
+	for _, tcase := range []struct {
+		name     string
+		mask     *config.Mask
+		expected string
+	}{
+		{
+			name:     "hash",
+			mask:     &config.Mask{Field: "email", Action: config.MaskHash},
+			expected: `"email":"` + emailHash + `"`,
+		},
+		{
+			name:     "salted hash",
+			mask:     &config.Mask{Field: "email", Action: config.MaskHash, Salt: "secret"},
+			expected: `"email":"` + emailHMAC + `"`,
+		},
+		{
+			name:     "hash object",
+			mask:     &config.Mask{Field: "meta", Action: config.MaskHash},
+			expected: `"meta":"` + objectHash + `"`,
+		},
+		{
+			name:     "hash nested",
+			mask:     &config.Mask{Field: "owners.email", Action: config.MaskHash},
+			expected: `"owners":[{"email":"` + emailHash + `"},{"name":"b"}]`,
+		},
+		{
+			name:     "truncate last",
+			mask:     &config.Mask{Field: "account", Action: config.MaskTruncate, Length: -4},
+			expected: `"account":"5432"`,
+		},
+		{
+			name:     "truncate first",
+			mask:     &config.Mask{Field: "pin", Action: config.MaskTruncate, Length: 2},
+			expected: `"pin":"12"`,
+		},
+		{
+			name:     "truncate short",
+			mask:     &config.Mask{Field: "ssn", Action: config.MaskTruncate, Length: 4},
+			expected: `"ssn":"123"`,
+		},
+		{
+			name:     "truncate object",
+			mask:     &config.Mask{Field: "meta", Action: config.MaskTruncate, Length: 4},
+			expected: `"meta":null`,
+		},
+		{
+			name:     "null",
+			mask:     &config.Mask{Field: "ssn", Action: config.MaskNull},
+			expected: `"ssn":null`,
+		},
+		{
+			name:     "hash null",
+			mask:     &config.Mask{Field: "note", Action: config.MaskHash},
+			expected: `"note":null`,
+		},
+		{
+			name:     "missing",
+			mask:     &config.Mask{Field: "owner.email", Action: config.MaskNull},
+			expected: `"email":"a@example.com"`,
+		},
+	} {
+		mask := newRecordMask(&config.Request{Mask: []*config.Mask{tcase.mask}})
+
+		masked, err := mask.apply(data)
+		if err != nil {
+			t.Fatalf("%s: failed to mask: %v", tcase.name, err)
+		}
+
+		if !strings.Contains(string(masked), tcase.expected) {
+			t.Errorf("%s: expected %s in %s", tcase.name, tcase.expected, masked)
+		}
+	}
+
+	if mask := newRecordMask(&config.Request{}); mask != nil {
+		t.Errorf("expected no mask, got %v", mask)
+	}
+
+	var nilMask *recordMask
+
+	if data, err := nilMask.apply([]byte(`not json`)); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"net/url"
+	"time"
+)
+
+// Fields that are added to every record when metadata is enabled.
+const (
+	// metadataFetchedAtField is the time the record was fetched from the web API.
+	metadataFetchedAtField = "_gidari_fetched_at"
+
+	// metadataSourceURLField is the URL of the web API request that the record was fetched with.
+	metadataSourceURLField = "_gidari_source_url"
+
+	// metadataRunIDField is the ID of the run that stored the record.
+	metadataRunIDField = "_gidari_run_id"
+)
+
+// addMetadata will add the fetch time, the source URL, and the run ID to each JSON object in the data. Passwords in
+// the source URL are redacted and its query string is dropped, since web APIs often take their keys as query
+// parameters.
+func addMetadata(data []byte, fetchedAt time.Time, sourceURL *url.URL, runID string) ([]byte, error) {
+	stripped := *sourceURL
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+
+	source := stripped.Redacted()
+
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		record[metadataFetchedAtField] = fetchedAt.UTC().Format(time.RFC3339Nano)
+		record[metadataSourceURLField] = source
+		record[metadataRunIDField] = runID
+
+		return record, nil
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/alpstable/gidari/config"
+)
+
+var ErrNameCollision = fmt.Errorf("name collision")
+
+// nameWords will split a name into its words, which are separated by underscores, hyphens, or spaces, or by a change
+// of case, e.g. "HTTPStatus_code" is split into "HTTP", "Status", and "code". Digits belong to the preceding word.
+func nameWords(name string) []string {
+	var words []string
+
+	runes := []rune(name)
+	start := 0
+
+	for idx, char := range runes {
+		switch {
+		case char == '_' || char == '-' || char == ' ':
+			if idx > start {
+				words = append(words, string(runes[start:idx]))
+			}
+
+			start = idx + 1
+		case unicode.IsUpper(char) && idx > start:
+			prev := runes[idx-1]
+			nextLower := idx+1 < len(runes) && unicode.IsLower(runes[idx+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(runes[start:idx]))
+				start = idx
+			}
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// convertName will convert the name to the naming convention. Names that start with an underscore, e.g. "_id", are
+// reserved by some storage and are not converted.
+func convertName(name string, naming string) string {
+	words := nameWords(name)
+	if naming == "" || len(words) == 0 || strings.HasPrefix(name, "_") {
+		return name
+	}
+
+	for idx, word := range words {
+		word = strings.ToLower(word)
+
+		if naming == config.NamingPascalCase || (naming == config.NamingCamelCase && idx > 0) {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+
+		words[idx] = word
+	}
+
+	if naming == config.NamingSnakeCase {
+		return strings.Join(words, "_")
+	}
+
+	return strings.Join(words, "")
+}
+
+// convertNames will return a copy of the record with the names of its fields, and of the fields of nested objects,
+// converted to the naming convention. Fields of the record that are mapped to a column are not converted, since they
+// are renamed to their column.
+func convertNames(record map[string]interface{}, naming string,
+	columns map[string]string,
+) (map[string]interface{}, error) {
+	converted := make(map[string]interface{}, len(record))
+	fields := make(map[string]string, len(record))
+
+	for field, val := range record {
+		name := field
+		if _, ok := columns[field]; !ok {
+			name = convertName(field, naming)
+		}
+
+		if other, ok := fields[name]; ok {
+			return nil, fmt.Errorf("%w: fields %q and %q are both converted to %q", ErrNameCollision, other,
+				field, name)
+		}
+
+		val, err := convertNestedNames(val, naming)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[name] = field
+		converted[name] = val
+	}
+
+	return converted, nil
+}
+
+// convertNestedNames will convert the names of the fields of the value if it is an object, or of the objects in it if
+// it is a list.
+func convertNestedNames(val interface{}, naming string) (interface{}, error) {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		return convertNames(val, naming, nil)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+
+		for idx, elem := range val {
+			converted, err := convertNestedNames(elem, naming)
+			if err != nil {
+				return nil, err
+			}
+
+			list[idx] = converted
+		}
+
+		return list, nil
+	default:
+		return val, nil
+	}
+}
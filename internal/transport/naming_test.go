@@ -0,0 +1,77 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestConvertName(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name   string
+		naming string
+		want   string
+	}{
+		{name: "tradeId", naming: config.NamingSnakeCase, want: "trade_id"},
+		{name: "HTTPStatus", naming: config.NamingSnakeCase, want: "http_status"},
+		{name: "userID", naming: config.NamingSnakeCase, want: "user_id"},
+		{name: "volume24h", naming: config.NamingSnakeCase, want: "volume24h"},
+		{name: "v2Price", naming: config.NamingSnakeCase, want: "v2_price"},
+		{name: "best-bid price", naming: config.NamingSnakeCase, want: "best_bid_price"},
+		{name: "already_snake", naming: config.NamingSnakeCase, want: "already_snake"},
+		{name: "trade_id", naming: config.NamingCamelCase, want: "tradeId"},
+		{name: "TradeID", naming: config.NamingCamelCase, want: "tradeId"},
+		{name: "trade_id", naming: config.NamingPascalCase, want: "TradeId"},
+		{name: "_id", naming: config.NamingCamelCase, want: "_id"},
+		{name: "tradeId", want: "tradeId"},
+	} {
+		if got := convertName(tcase.name, tcase.naming); got != tcase.want {
+			t.Errorf("%q to %q: expected %q, got %q", tcase.name, tcase.naming, tcase.want, got)
+		}
+	}
+}
+
+func TestConvertNames(t *testing.T) {
+	t.Parallel()
+
+	record := map[string]interface{}{
+		"tradeId": "1",
+		"px":      "1.5",
+		"quote":   map[string]interface{}{"bestBid": 1.4},
+		"fills":   []interface{}{map[string]interface{}{"fillSize": 2}, "n/a"},
+	}
+
+	got, err := convertNames(record, config.NamingSnakeCase, map[string]string{"px": "price"})
+	if err != nil {
+		t.Fatalf("failed to convert names: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"trade_id": "1",
+		"px":       "1.5",
+		"quote":    map[string]interface{}{"best_bid": 1.4},
+		"fills":    []interface{}{map[string]interface{}{"fill_size": 2}, "n/a"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	collision := map[string]interface{}{"tradeId": "1", "trade_id": "2"}
+	if _, err := convertNames(collision, config.NamingSnakeCase, nil); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("expected %v, got %v", ErrNameCollision, err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// upsertRetried will upsert the request in its own transactions, retrying transactions that fail with a transient
+// error. If the request has the "retry-N" policy and still fails, its data is fetched again and it is retried up to N
+// times, waiting for the backoff of the retry policy before each retry. Every retry is taken from the budget of the
+// run.
+func (txn *requestTxn) upsertRetried(ctx context.Context, workerID int, repos []*destinationRepo,
+	policy *config.Retry, budget *retryBudget, logger tools.Logger,
+) error {
+	backoff := policy
+	if backoff == nil {
+		backoff = &config.Retry{}
+	}
+
+	wait, err := backoff.BackoffDuration()
+	if err != nil {
+		return err
+	}
+
+	// The repository worker is idle while it waits to retry the request.
+	upsert := func() error {
+		txn.watchdog.busy(txn.worker, fmt.Sprintf("writing %q", txn.table))
+		defer txn.watchdog.idle(txn.worker)
+
+		err := txn.upsert(ctx, workerID, repos, logger)
+		if txn.requeue && panicked(err) {
+			msg := fmt.Sprintf("requeuing the request for %q once after a panic", txn.table)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+			err = txn.upsert(ctx, workerID, repos, logger)
+		}
+
+		return err
+	}
+
+	name := fmt.Sprintf("request for %q", txn.table)
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+
+		err := retryTxn(ctx, policy, budget, name, logger, upsert)
+		if attempt > 0 {
+			budget.spend(time.Since(start))
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		msg := fmt.Sprintf("request rolled back for %q: %v", txn.table, err)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelError)
+
+		// Errors fetching the data of the request were counted when they occurred.
+		if !errors.Is(err, ErrFetch) {
+			txn.errors.add(errorCategory(err), err)
+		}
+
+		// The data of the request is not fetched again once the run has been cancelled.
+		retries := txn.req.OnErrorRetries()
+		if attempt >= retries || runErr(ctx) != nil || !budget.take(name, wait) {
+			return err
+		}
+
+		msg = fmt.Sprintf("retrying request for %q in %v (%d of %d)", txn.table, wait, attempt+1, retries)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+
+		if wait *= 2; wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+
+		txn.refetch(ctx, workerID)
+	}
+}
+
+// refetch will fetch the data of the request again, in place of the data that was received. The chunks are fetched
+// in the background while the request is written, and the chunks that are still being fetched for the previous
+// attempt are discarded.
+func (txn *requestTxn) refetch(ctx context.Context, workerID int) {
+	txn.discard()
+
+	txn.jobs = make(chan *repoJob, len(txn.flattenedRequests))
+	txn.discarded = make(chan struct{})
+	txn.received = nil
+	txn.deadLetters.reset()
+
+	atomic.StoreInt64(&txn.fetchedChunks, 0)
+
+	jobs := make([]*webJob, len(txn.flattenedRequests))
+	for idx, req := range txn.flattenedRequests {
+		jobs[idx] = txn.newJob(req)
+	}
+
+	// The chunks are no longer fetched once the attempt is discarded.
+	discarded := txn.discarded
+
+	go func() {
+		for _, job := range jobs {
+			select {
+			case <-discarded:
+				return
+			default:
+				job.run(ctx, workerID)
+			}
+		}
+	}()
+}
+
+// abort will roll back the requests that were not written because the run was aborted by the request for the
+// table.
+func abort(txns []*requestTxn, table string, logger tools.Logger) {
+	for _, txn := range txns {
+		msg := fmt.Sprintf("request aborted for %q after the request for %q failed", txn.table, table)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelError)
+
+		txn.chunkLogs.flush()
+		txn.report(fmt.Errorf("%w: the request for %q failed", ErrAborted, table))
+	}
+}
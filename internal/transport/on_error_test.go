@@ -0,0 +1,125 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestUpsertRequestsOnError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"id":"1"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		onError string
+
+		// failed is whether the run fails, with wantErr if it is set, and written are whether each request was written.
+		failed  bool
+		wantErr error
+		written []bool
+	}{
+		{onError: "", failed: true, written: []bool{false, true}},
+		{onError: config.OnErrorSkip, written: []bool{false, true}},
+		{onError: config.OnErrorFail, failed: true, wantErr: ErrAborted, written: []bool{false, false}},
+		{onError: "retry-2", written: []bool{true, true}},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		reqs := []*config.Request{{Table: "candles", OnError: tcase.onError}, {Table: "trades"}}
+		cfg := &config.Config{Requests: reqs, Logger: logger}
+
+		var progress []config.RequestProgress
+
+		cfg.Progress = func(prg config.RequestProgress) { progress = append(progress, prg) }
+
+		var flattenedRequests []*flattenedRequest
+
+		for _, req := range reqs {
+			flattenedRequests = append(flattenedRequests, newFlattenedRequest(req, &web.FetchConfig{
+				C:           &web.Client{},
+				Method:      http.MethodGet,
+				URL:         uri,
+				RateLimiter: rate.NewLimiter(rate.Inf, 1),
+			}))
+		}
+
+		txns := newRequestTxns(cfg, flattenedRequests)
+		for _, txn := range txns {
+			txn := txn
+			txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+		}
+
+		// The first fetch of the candles fails, and is only fetched again if the request is retried.
+		txns[0].jobs <- &repoJob{err: errFetch}
+		txns[1].jobs <- &repoJob{table: "trades", b: []byte(`[{"id":"1"}]`)}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+		err = upsertRequests(ctx, txns, repos, 1, &config.Retry{Backoff: "1ms"}, nil, logger)
+		if (err != nil) != tcase.failed || (tcase.wantErr != nil && !errors.Is(err, tcase.wantErr)) {
+			t.Errorf("%q: expected the run to fail with %v, got %v", tcase.onError, tcase.wantErr, err)
+		}
+
+		// The run is classified by the error of the request that aborted it.
+		if tcase.wantErr != nil && !errors.Is(err, ErrFetch) {
+			t.Errorf("%q: expected the fetch error of the request, got %v", tcase.onError, err)
+		}
+
+		if len(progress) != len(reqs) {
+			t.Fatalf("%q: expected the outcome of every request, got %+v", tcase.onError, progress)
+		}
+
+		for idx, table := range []string{"candles", "trades"} {
+			_, statErr := os.Stat(filepath.Join(dir, table+".ndjson"))
+			if written := statErr == nil; written != tcase.written[idx] {
+				t.Errorf("%q: expected %s to be written %v, got %v", tcase.onError, table, tcase.written[idx], written)
+			}
+
+			if committed := progress[idx].Status == config.RequestCommitted; committed != tcase.written[idx] {
+				t.Errorf("%q: expected %s to be committed %v, got %+v", tcase.onError, table, tcase.written[idx],
+					progress[idx])
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/alpstable/gidari/tools"
+)
+
+// recoverPanic will call fn, recovering a panic of the worker as an error that matches ErrPanic, so that a job that
+// panics fails on its own rather than crashing the process. The stack of the panic is logged.
+func recoverPanic(logger tools.Logger, worker, activity string, fn func() error) (err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			err = classify(ErrPanic, fmt.Errorf("%s panicked while %s: %v", worker, activity, val))
+
+			msg := fmt.Sprintf("%v\n%s", err, debug.Stack())
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelError)
+		}
+	}()
+
+	return fn()
+}
+
+// panicked will return true if the repository worker, or the storage, panicked while writing, which the data of a
+// chunk that a web worker panicked on is not.
+func panicked(err error) bool {
+	return errors.Is(err, ErrPanic) && !errors.Is(err, ErrFetch)
+}
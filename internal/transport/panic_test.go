@@ -0,0 +1,142 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	err := recoverPanic(logger, repositoryWorker, "writing", func() error { panic("boom") })
+	if !errors.Is(err, ErrPanic) || errorCategory(err) != errorPanic {
+		t.Errorf("expected %v, got %v", ErrPanic, err)
+	}
+
+	errInvalid := fmt.Errorf("invalid input syntax")
+	if err := recoverPanic(logger, repositoryWorker, "writing", func() error { return errInvalid }); err != errInvalid {
+		t.Errorf("expected %v, got %v", errInvalid, err)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"id":"1"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		name    string
+		requeue bool
+
+		// fetchPanics and upsertPanics are the number of times that fetching the chunk and upserting its data
+		// panic.
+		fetchPanics  int64
+		upsertPanics int64
+
+		committed bool
+	}{
+		{name: "fetch", fetchPanics: 1},
+		{name: "fetch requeued", fetchPanics: 1, requeue: true, committed: true},
+		{name: "fetch requeued once", fetchPanics: 2, requeue: true},
+		{name: "upsert", upsertPanics: 1},
+		{name: "upsert requeued", upsertPanics: 1, requeue: true, committed: true},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		var fetchPanics, upsertPanics int64
+
+		req := &config.Request{Table: "candles"}
+		cfg := &config.Config{
+			Requests:      []*config.Request{req},
+			Logger:        logger,
+			RequeuePanics: tcase.requeue,
+			OnUpsert: func(config.UpsertProgress) {
+				if atomic.AddInt64(&upsertPanics, 1) <= tcase.upsertPanics {
+					panic("upsert")
+				}
+			},
+		}
+
+		flatReq := newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+		txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+		job := txn.newJob(flatReq)
+		job.fetched = func(time.Duration, time.Duration, int) {
+			if atomic.AddInt64(&fetchPanics, 1) <= tcase.fetchPanics {
+				panic("fetch")
+			}
+		}
+
+		job.run(ctx, 1)
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+		err = upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger)
+		if tcase.committed && err != nil {
+			t.Errorf("%s: expected the request to be committed, got %v", tcase.name, err)
+		}
+
+		if !tcase.committed && !errors.Is(err, ErrPanic) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, ErrPanic, err)
+		}
+
+		_, statErr := os.Stat(filepath.Join(dir, "candles.ndjson"))
+		if written := statErr == nil; written != tcase.committed {
+			t.Errorf("%s: expected the candles to be written %v, got %v", tcase.name, tcase.committed, statErr)
+		}
+	}
+}
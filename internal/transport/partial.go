@@ -0,0 +1,67 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RequestFailure is a request of a run that was rolled back, with the chunks of the request that failed to be fetched,
+// if any.
+type RequestFailure struct {
+	Endpoint string
+	Table    string
+	Chunks   []string
+	Err      error
+}
+
+func (failure *RequestFailure) String() string {
+	var bldr strings.Builder
+
+	bldr.WriteString(fmt.Sprintf("%q (%s)", failure.Table, failure.Endpoint))
+
+	if len(failure.Chunks) > 0 {
+		bldr.WriteString(fmt.Sprintf(" chunks [%s]", strings.Join(failure.Chunks, ", ")))
+	}
+
+	bldr.WriteString(fmt.Sprintf(": %v", failure.Err))
+
+	return bldr.String()
+}
+
+// PartialError is the error of a run that completed with some of its requests rolled back. The requests that are not
+// failures were committed. It matches the class of the error of its first failure, e.g. "ErrFetch".
+type PartialError struct {
+	// Requests is the number of requests of the run.
+	Requests int
+
+	// Failures are the requests that were rolled back, in the order of the requests.
+	Failures []*RequestFailure
+}
+
+func (e *PartialError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for idx, failure := range e.Failures {
+		failures[idx] = failure.String()
+	}
+
+	return fmt.Sprintf("%d of %d requests failed: %s", len(e.Failures), e.Requests, strings.Join(failures, "; "))
+}
+
+func (e *PartialError) Unwrap() error { return e.Failures[0].Err }
+
+// failure will return the failure of the request, which was rolled back with the error.
+func (txn *requestTxn) failure(err error) *RequestFailure {
+	return &RequestFailure{
+		Endpoint: txn.req.Endpoint,
+		Table:    txn.table,
+		Chunks:   txn.errors.chunks(),
+		Err:      err,
+	}
+}
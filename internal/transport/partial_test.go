@@ -0,0 +1,105 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPartialError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	reqs := []*config.Request{
+		{Endpoint: "/candles", Table: "candles"},
+		{Endpoint: "/trades", Table: "trades"},
+		{Endpoint: "/stats", Table: "stats"},
+	}
+
+	flattenedRequests := []*flattenedRequest{
+		{request: reqs[0]}, {request: reqs[0]}, {request: reqs[0]}, {request: reqs[1]}, {request: reqs[2]},
+	}
+
+	txns := newRequestTxns(&config.Config{Requests: reqs}, flattenedRequests)
+
+	// Two of the chunks of the candles fail to be fetched, and the stats cannot be upserted.
+	chunks := []string{"https://api.test/candles?start=1", "https://api.test/candles?start=3"}
+	for _, chunk := range append(chunks, chunks[0]) {
+		txns[0].errors.addChunk(errorFetch, chunk, errFetch)
+	}
+
+	txns[0].jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"2"}]`)}
+	txns[0].jobs <- &repoJob{err: errFetch}
+	txns[0].jobs <- &repoJob{err: errFetch}
+	txns[1].jobs <- &repoJob{table: "trades", b: []byte(`[{"id":"1"}]`)}
+	txns[2].jobs <- &repoJob{table: "stats", b: []byte(`not json`)}
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+	err = upsertRequests(ctx, txns, repos, 1, nil, nil, logger)
+
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a partial error, got %v", err)
+	}
+
+	if partial.Requests != 3 || len(partial.Failures) != 2 {
+		t.Fatalf("expected 2 of 3 requests to fail, got %+v", partial)
+	}
+
+	candles, stats := partial.Failures[0], partial.Failures[1]
+	if candles.Table != "candles" || candles.Endpoint != "/candles" || !reflect.DeepEqual(candles.Chunks, chunks) ||
+		!errors.Is(candles.Err, ErrFetch) {
+		t.Errorf("expected the failed chunks of the candles, got %+v", candles)
+	}
+
+	if stats.Table != "stats" || len(stats.Chunks) != 0 || errors.Is(stats.Err, ErrFetch) {
+		t.Errorf("expected the stats to fail without any chunks, got %+v", stats)
+	}
+
+	// The run is classified by its first failure.
+	if !errors.Is(err, ErrFetch) {
+		t.Errorf("expected the run to match the error of the candles, got %v", err)
+	}
+
+	want := `2 of 3 requests failed: "candles" (/candles) chunks [https://api.test/candles?start=1, ` +
+		`https://api.test/candles?start=3]: unable to fetch data: connection refused; "stats" (/stats): `
+	if msg := err.Error(); !strings.HasPrefix(msg, want) {
+		t.Errorf("expected %q, got %q", want, msg)
+	}
+
+	// The request that did not fail was committed.
+	if _, err := os.Stat(filepath.Join(dir, "trades.ndjson")); err != nil {
+		t.Errorf("expected the trades to be committed: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode"
+
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// countingReader counts the bytes that are read from its reader.
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (counter *countingReader) Read(p []byte) (int, error) {
+	n, err := counter.reader.Read(p)
+	counter.count += n
+
+	return n, err
+}
+
+// isList will return true if the data of the reader is a JSON list, without consuming it.
+func isList(reader *bufio.Reader) bool {
+	for size := 1; ; size++ {
+		peeked, err := reader.Peek(size)
+		if len(peeked) < size {
+			return false
+		}
+
+		if char := rune(peeked[size-1]); !unicode.IsSpace(char) {
+			return char == '['
+		}
+
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// fetchParts will decode the records of a response that is a JSON list, or NDJSON, as they are received, and send
+// them to the transaction of the request in parts of the part size, so that the response is never held in memory as a
+// whole. The last part is sent once the response has been read, even if it has no records. A part that cannot be
+// transformed fails the chunk, since the parts before it may have been written.
+func (job *webJob) fetchParts(ctx context.Context, workerID int, rsp *web.FetchResponse, body *bufio.Reader,
+	ndjson bool, start time.Time, fetchSpan trace.Span,
+) error {
+	counter := &countingReader{reader: body}
+	decoder := json.NewDecoder(counter)
+
+	records := make([]json.RawMessage, 0, job.partSize)
+
+	// The time spent sending the parts is not part of the fetch of the response.
+	var sending time.Duration
+
+	send := func(more bool) error {
+		sendStart := time.Now()
+		err := job.sendPart(ctx, records, start, rsp, more)
+		sending += time.Since(sendStart)
+
+		return err
+	}
+
+	// read will read the records of the response, sending each part of them once it is full. The records of NDJSON
+	// are the values of the response, rather than the elements of a list.
+	read := func() error {
+		if !ndjson {
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		}
+
+		for decoder.More() {
+			var record json.RawMessage
+			if err := decoder.Decode(&record); err != nil {
+				return err
+			}
+
+			if records = append(records, record); len(records) < job.partSize {
+				continue
+			}
+
+			if err := send(true); err != nil {
+				return err
+			}
+
+			records = records[:0]
+		}
+
+		_, err := decoder.Token()
+		if ndjson && errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	err := read()
+	if err == nil {
+		// What follows the list is read too, so that the body is read in full, e.g. for the durable queue.
+		_, err = io.Copy(io.Discard, body)
+	}
+
+	fetchSpan.SetAttributes(attribute.Int("gidari.bytes", counter.count))
+	endSpan(fetchSpan, err)
+	job.fetched(rsp.RateLimitWait, time.Since(start)-rsp.RateLimitWait, counter.count)
+	job.idle += rsp.RateLimitWait
+	job.stages.add(stageFetch, time.Since(start)-rsp.RateLimitWait-sending)
+
+	if job.sent {
+		return err
+	}
+
+	if err != nil {
+		category := errorFetch
+
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			category = errorDecode
+		}
+
+		err = fmt.Errorf("failed to stream response body: %w", err)
+		job.partFailed(category, err)
+
+		return err
+	}
+
+	if err := send(false); err != nil {
+		return err
+	}
+
+	job.logCompleted(workerID, start, rsp)
+
+	return nil
+}
+
+// sendPart will transform the records of a part of the response, and send them to the transaction of the request.
+// The part fails the chunk if the records cannot be transformed, which is sent as the last part of the chunk.
+func (job *webJob) sendPart(ctx context.Context, records []json.RawMessage, start time.Time, rsp *web.FetchResponse,
+	more bool,
+) error {
+	stageStart := time.Now()
+
+	data, err := encodeList(records)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal part: %w", err)
+		job.partFailed(errorDecode, err)
+
+		return err
+	}
+
+	if err := job.checkFields(data); err != nil {
+		job.partFailed(errorSchema, err)
+
+		return err
+	}
+
+	stageStart = job.stages.since(stageDecode, stageStart)
+
+	_, transformSpan := job.tracer.Start(ctx, spanTransform)
+	data, quarantined, err := job.transform(data, start, rsp)
+	endSpan(transformSpan, err)
+	job.stages.since(stageTransform, stageStart)
+
+	if err != nil {
+		job.partFailed(errorDecode, err)
+
+		return err
+	}
+
+	part, err := job.newRepoJob(ctx, data, quarantined, rsp)
+	if err != nil {
+		job.partFailed(errorDecode, err)
+
+		return err
+	}
+
+	part.more = more
+	job.send(part)
+
+	return nil
+}
+
+// partFailed will fail the chunk with the error of a part of it.
+func (job *webJob) partFailed(category string, err error) {
+	tools.LogFormatter{Msg: err.Error()}.Log(job.logger, tools.LogLevelError)
+	job.errors.addChunk(category, job.fetchConfig.URL.Redacted(), err)
+	job.send(&repoJob{err: err})
+}
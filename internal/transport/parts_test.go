@@ -0,0 +1,157 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestIsList(t *testing.T) {
+	t.Parallel()
+
+	for data, expected := range map[string]bool{
+		`[{"id":1}]`:                    true,
+		" \n\t [":                       true,
+		`{"data":[]}`:                   false,
+		`not json`:                      false,
+		"":                              false,
+		strings.Repeat(" ", 5000) + "[": false,
+	} {
+		reader := bufio.NewReader(strings.NewReader(data))
+		if isList(reader) != expected {
+			t.Errorf("%q: expected %v", data, expected)
+		}
+
+		// The data is not consumed.
+		if rest, _ := io.ReadAll(reader); string(rest) != data {
+			t.Errorf("%q: expected the data not to be consumed, got %q", data, rest)
+		}
+	}
+}
+
+func TestFetchParts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	for _, tcase := range []struct {
+		name        string
+		body        string
+		contentType string
+		parts       int
+		failed      bool
+
+		// written is the number of records that are written, which none are if the request fails.
+		written int
+	}{
+		{name: "list", body: `[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"},{"id":"5"}]`, parts: 3, written: 5},
+		{name: "empty list", body: `[]`, parts: 1, written: 0},
+		{name: "object", body: `{"id":"1"}`, parts: 1, written: 1},
+		{name: "truncated", body: `[{"id":"1"},{"id":"2"},{"id":"3"},{"id`, failed: true},
+		{
+			name:        "ndjson",
+			body:        "{\"id\":\"1\"}\n{\"id\":\"2\"}\n\n{\"id\":\"3\"}\n{\"id\":\"4\"}\n{\"id\":\"5\"}\n",
+			contentType: "application/x-ndjson; charset=utf-8",
+			parts:       3,
+			written:     5,
+		},
+		{
+			name:        "truncated ndjson",
+			body:        "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n{\"id",
+			contentType: "application/jsonl",
+			failed:      true,
+		},
+		// NDJSON without its content type is not streamed, but is decoded once it has been read.
+		{name: "sniffed ndjson", body: "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}", parts: 1, written: 3},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+			if tcase.contentType != "" {
+				wtr.Header().Set("Content-Type", tcase.contentType)
+			}
+
+			_, _ = wtr.Write([]byte(tcase.body))
+		}))
+		t.Cleanup(server.Close)
+
+		uri, err := url.Parse(server.URL + "/candles")
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		var parts int64
+
+		req := &config.Request{Table: "candles"}
+		cfg := &config.Config{
+			Requests:      []*config.Request{req},
+			Logger:        logger,
+			StreamRecords: 2,
+			OnUpsert:      func(config.UpsertProgress) { atomic.AddInt64(&parts, 1) },
+		}
+
+		flatReq := newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+		txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+		// The buffer of the request holds a single part, so the parts are written while the response is read.
+		go txn.newJob(flatReq).run(ctx, 1)
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+		err = upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger)
+		if tcase.failed != (err != nil) {
+			t.Errorf("%s: expected the request to fail %v, got %v", tcase.name, tcase.failed, err)
+		}
+
+		if err != nil && !errors.Is(err, ErrFetch) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, ErrFetch, err)
+		}
+
+		if !tcase.failed && int(parts) != tcase.parts {
+			t.Errorf("%s: expected %d parts, got %d", tcase.name, tcase.parts, parts)
+		}
+
+		if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != tcase.written {
+			t.Errorf("%s: expected %d candles, got %v", tcase.name, tcase.written, candles)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// Plan is what a run of a configuration would do, without fetching or writing anything.
+type Plan struct {
+	Requests []*PlannedRequest
+}
+
+// PlannedRequest is a request of a configuration, with the requests to the web API that it is flattened into.
+type PlannedRequest struct {
+	Method   string
+	Endpoint string
+
+	// Table is the name of the table in storage, with the prefix and suffix of the configuration.
+	Table string
+
+	// Destinations are the redacted connection strings of the storage devices that the table is routed to.
+	Destinations []string
+
+	// Fetches are the requests to the web API, one for each chunk of a timeseries.
+	Fetches []*PlannedFetch
+}
+
+// PlannedFetch is a request to the web API. The start and end of the chunk are zero if the request is not a
+// timeseries.
+type PlannedFetch struct {
+	URL   string
+	Start time.Time
+	End   time.Time
+}
+
+// NewPlan will flatten the requests of the configuration into the requests to the web API that a run would make,
+// and route their tables to the destinations of the configuration. The configuration is not modified, so it can
+// still be run.
+func NewPlan(cfg *config.Config) (*Plan, error) {
+	plan := &Plan{Requests: make([]*PlannedRequest, 0, len(cfg.Requests))}
+
+	for _, req := range cfg.Requests {
+		req = planRequest(req)
+
+		flatReqs, err := flattenRequestTimeseries(req, *cfg.URL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan %q: %w", req.Endpoint, err)
+		}
+
+		planned := &PlannedRequest{
+			Method:   req.Method,
+			Endpoint: req.Endpoint,
+			Table:    cfg.StorageTable(req.Table),
+			Fetches:  make([]*PlannedFetch, len(flatReqs)),
+		}
+
+		for _, dest := range cfg.DestinationList() {
+			if dest.Routes(req.Table) {
+				planned.Destinations = append(planned.Destinations, redactConnectionString(dest.ConnectionString))
+			}
+		}
+
+		for idx, flatReq := range flatReqs {
+			fetch := &PlannedFetch{URL: flatReq.fetchConfig.URL.Redacted()}
+
+			if req.Timeseries != nil {
+				fetch.Start, fetch.End = req.Timeseries.Chunks[idx][0], req.Timeseries.Chunks[idx][1]
+			}
+
+			planned.Fetches[idx] = fetch
+		}
+
+		plan.Requests = append(plan.Requests, planned)
+	}
+
+	if len(plan.Requests) == 0 {
+		return nil, config.ErrNoRequests
+	}
+
+	return plan, nil
+}
+
+// planRequest will copy the request, so that flattening it does not chunk the timeseries or change the query of the
+// request in the configuration.
+func planRequest(req *config.Request) *config.Request {
+	planned := *req
+
+	planned.Query = make(map[string]string, len(req.Query))
+	for key, value := range req.Query {
+		planned.Query[key] = value
+	}
+
+	if req.Timeseries != nil {
+		timeseries := *req.Timeseries
+		timeseries.Chunks = nil
+		planned.Timeseries = &timeseries
+	}
+
+	return &planned
+}
+
+// redactConnectionString will return the connection string with its password redacted.
+func redactConnectionString(dns string) string {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return proto.SchemeFromConnectionString(dns) + "://"
+	}
+
+	return uri.Redacted()
+}
+
+// Fetches will return the number of requests to the web API that the run would make.
+func (plan *Plan) Fetches() int {
+	fetches := 0
+	for _, req := range plan.Requests {
+		fetches += len(req.Fetches)
+	}
+
+	return fetches
+}
+
+// Write will write the plan as text, with a line for each request to the web API.
+func (plan *Plan) Write(wtr io.Writer) error {
+	var text strings.Builder
+
+	tables := make(map[string]bool)
+
+	for _, req := range plan.Requests {
+		tables[req.Table] = true
+
+		fmt.Fprintf(&text, "%s %s -> %s (%d requests)\n", req.Method, req.Endpoint, req.Table, len(req.Fetches))
+
+		if len(req.Destinations) == 0 {
+			text.WriteString("  destination: none\n")
+		}
+
+		for _, dest := range req.Destinations {
+			fmt.Fprintf(&text, "  destination: %s\n", dest)
+		}
+
+		for _, fetch := range req.Fetches {
+			text.WriteString("  " + fetch.URL)
+
+			if !fetch.Start.IsZero() || !fetch.End.IsZero() {
+				fmt.Fprintf(&text, " [%s, %s]", fetch.Start.Format(time.RFC3339), fetch.End.Format(time.RFC3339))
+			}
+
+			text.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&text, "Plan: %d requests to the web API, writing to %d tables.\n", plan.Fetches(), len(tables))
+
+	if _, err := io.WriteString(wtr, text.String()); err != nil {
+		return fmt.Errorf("unable to write plan: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// poisonPayloads counts the times that each payload of a request has failed to be decoded or transformed, across the
+// retries of the request, so that a payload that fails every time is skipped instead of failing the request.
+type poisonPayloads struct {
+	threshold int
+
+	mutex    sync.Mutex
+	failures map[[sha256.Size]byte]int
+}
+
+func newPoisonPayloads(cfg *config.Config) *poisonPayloads {
+	return &poisonPayloads{threshold: cfg.PoisonThreshold, failures: make(map[[sha256.Size]byte]int)}
+}
+
+// fail will count a failure of the payload, and return true if it has failed as many times as the threshold, in which
+// case it is poison and should be skipped. Payloads are poison the first time they fail if there is no threshold.
+func (poison *poisonPayloads) fail(payload []byte) bool {
+	if poison == nil || poison.threshold <= 1 {
+		return true
+	}
+
+	key := sha256.Sum256(payload)
+
+	poison.mutex.Lock()
+	defer poison.mutex.Unlock()
+
+	poison.failures[key]++
+
+	return poison.failures[key] >= poison.threshold
+}
@@ -0,0 +1,152 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestPoisonPayloads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	for _, tcase := range []struct {
+		name            string
+		poisonThreshold int
+		onError         string
+
+		// bodies are the bodies of the responses to each fetch, the last of which is repeated.
+		bodies []string
+
+		// fetches is the number of times the chunk is fetched, and skipped is whether its payload is skipped as
+		// poison, in which case the request is committed without it.
+		fetches   int
+		committed bool
+		written   bool
+		skipped   bool
+	}{
+		{
+			name:    "skipped by default",
+			onError: "retry-2",
+			bodies:  []string{`not json`},
+			fetches: 1, committed: true, skipped: true,
+		},
+		{
+			name:            "skipped at the threshold",
+			poisonThreshold: 2,
+			onError:         "retry-2",
+			bodies:          []string{`not json`},
+			fetches:         2, committed: true, skipped: true,
+		},
+		{
+			name:            "fetched again",
+			poisonThreshold: 2,
+			onError:         "retry-2",
+			bodies:          []string{`not json`, `[{"id":"1"}]`},
+			fetches:         2, committed: true, written: true,
+		},
+		{
+			name:            "failed without retries",
+			poisonThreshold: 2,
+			bodies:          []string{`not json`},
+			fetches:         1,
+		},
+	} {
+		var fetches int64
+
+		server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+			fetch := int(atomic.AddInt64(&fetches, 1))
+			if fetch > len(tcase.bodies) {
+				fetch = len(tcase.bodies)
+			}
+
+			_, _ = wtr.Write([]byte(tcase.bodies[fetch-1]))
+		}))
+		defer server.Close()
+
+		uri, err := url.Parse(server.URL + "/candles")
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		req := &config.Request{Table: "candles", OnError: tcase.onError}
+		cfg := &config.Config{
+			Requests:        []*config.Request{req},
+			Logger:          logger,
+			PoisonThreshold: tcase.poisonThreshold,
+			DeadLetter:      &config.DeadLetter{Dir: t.TempDir()},
+		}
+
+		var progress []config.RequestProgress
+
+		cfg.Progress = func(prg config.RequestProgress) { progress = append(progress, prg) }
+
+		flatReq := newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+		txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+		txn.newJob(flatReq).run(ctx, 1)
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+		err = upsertRequests(ctx, []*requestTxn{txn}, repos, 1, &config.Retry{Backoff: "1ms"}, nil, logger)
+		if (err == nil) != tcase.committed || len(progress) != 1 ||
+			(progress[0].Status == config.RequestCommitted) != tcase.committed {
+			t.Errorf("%s: expected the request to be committed %v, got %v %+v", tcase.name, tcase.committed, err,
+				progress)
+		}
+
+		if int(fetches) != tcase.fetches {
+			t.Errorf("%s: expected %d fetches, got %d", tcase.name, tcase.fetches, fetches)
+		}
+
+		if _, statErr := os.Stat(filepath.Join(dir, "candles.ndjson")); (statErr == nil) != tcase.written {
+			t.Errorf("%s: expected the candles to be written %v, got %v", tcase.name, tcase.written, statErr)
+		}
+
+		// Only the payloads that were skipped are written to the dead letter.
+		letters := txn.deadLetters.snapshot()
+		if skipped := len(letters) == 1 && letters[0].Payload == `not json`; skipped != tcase.skipped ||
+			(!tcase.skipped && len(letters) != 0) {
+			t.Errorf("%s: expected the payload to be skipped %v, got %+v", tcase.name, tcase.skipped, letters)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which a buffer is not returned to its pool, so that the buffer of an unusually
+// large response is not kept in memory for the rest of the run.
+const maxPooledBuffer = 16 << 20
+
+// bufferPool holds the buffers that response bodies are read into and lists of records are encoded with, so that a
+// backfill of millions of records does not allocate and grow a buffer for each response and batch.
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// readerPool holds the readers that the bodies of responses whose records are streamed are read with.
+var readerPool = sync.Pool{New: func() interface{} { return bufio.NewReader(nil) }}
+
+// getBuffer will return an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf, _ := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+// putBuffer will return the buffer to the pool. The bytes of the buffer must no longer be referenced.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
+
+// getReader will return a reader of the body from the pool.
+func getReader(body io.Reader) *bufio.Reader {
+	reader, _ := readerPool.Get().(*bufio.Reader)
+	reader.Reset(body)
+
+	return reader
+}
+
+// putReader will return the reader to the pool.
+func putReader(reader *bufio.Reader) {
+	reader.Reset(nil)
+	readerPool.Put(reader)
+}
+
+// encodeList will encode the records as a JSON list, like "json.Marshal", in a buffer from the pool. The returned
+// bytes are a copy, which is allocated once at its final size.
+func encodeList(records []json.RawMessage) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteByte('[')
+
+	for idx, record := range records {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := json.Compact(buf, record); err != nil {
+			return nil, fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+
+	buf.WriteByte(']')
+
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// aliases will return true if the slices share their first byte, i.e. the data was returned as is from the buffer.
+func aliases(data, buf []byte) bool {
+	return len(data) > 0 && len(buf) > 0 && &data[0] == &buf[0]
+}
@@ -0,0 +1,72 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodeList(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		records  []json.RawMessage
+		expected string
+	}{
+		{records: []json.RawMessage{}, expected: `[]`},
+		{records: []json.RawMessage{[]byte(`{"id":1}`)}, expected: `[{"id":1}]`},
+		{records: []json.RawMessage{[]byte("{\n  \"id\": 1\n}"), []byte(`2`)}, expected: `[{"id":1},2]`},
+	} {
+		data, err := encodeList(tcase.records)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", tcase.expected, err)
+		}
+
+		if string(data) != tcase.expected {
+			t.Errorf("expected %s, got %s", tcase.expected, data)
+		}
+
+		// The encoded list is not overwritten once its buffer is reused.
+		if _, err := encodeList([]json.RawMessage{[]byte(`"overwritten"`)}); err != nil || string(data) != tcase.expected {
+			t.Errorf("expected %s to be kept, got %s", tcase.expected, data)
+		}
+	}
+
+	if _, err := encodeList([]json.RawMessage{[]byte(`{"id":`)}); err == nil {
+		t.Errorf("expected an error for an invalid record")
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	t.Parallel()
+
+	buf := getBuffer()
+	buf.WriteString("data")
+	putBuffer(buf)
+
+	if buf := getBuffer(); buf.Len() != 0 {
+		t.Errorf("expected an empty buffer, got %q", buf.String())
+	}
+
+	reader := getReader(strings.NewReader("body"))
+	if data, _ := io.ReadAll(reader); string(data) != "body" {
+		t.Errorf("expected the body, got %q", data)
+	}
+
+	putReader(reader)
+
+	data := []byte("data")
+	if !aliases(data[:2], data) || aliases(append([]byte(nil), data...), data) || aliases(nil, data) {
+		t.Errorf("expected only slices of the data to alias it")
+	}
+}
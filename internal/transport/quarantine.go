@@ -0,0 +1,127 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// quarantineIDField is the primary key of the quarantined records, which is a hash of the table and the record, so
+// that a record that is quarantined again replaces the last time it was quarantined.
+const quarantineIDField = "id"
+
+// quarantinedRecord is a record that failed to be mapped or coerced for its table, with the error. The record is kept
+// as it was received.
+type quarantinedRecord struct {
+	ID            string `json:"id"`
+	Table         string `json:"table"`
+	URL           string `json:"url"`
+	Error         string `json:"error"`
+	Record        string `json:"record"`
+	QuarantinedAt string `json:"quarantined_at"`
+}
+
+// quarantineRecords will apply the configuration of the table to each record in the data, which is either an object or
+// a list of objects, like "transformRecords". The records that fail to be transformed are removed from the data and
+// returned as the quarantined records of the table, rather than failing the data. The URL is the URL the data was
+// fetched with, if any.
+func quarantineRecords(data []byte, table *config.Table, name string,
+	uri *url.URL,
+) ([]byte, []*quarantinedRecord, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	vals, isList := decoded.([]interface{})
+	if !isList {
+		vals = []interface{}{decoded}
+	}
+
+	kept := make([]interface{}, 0, len(vals))
+
+	var quarantined []*quarantinedRecord
+
+	for _, val := range vals {
+		record, ok := val.(map[string]interface{})
+		if !ok {
+			kept = append(kept, val)
+
+			continue
+		}
+
+		// The record is encoded before it is transformed, since coercions change it in place.
+		original, err := json.Marshal(record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal record: %w", err)
+		}
+
+		transformed, err := transformRecord(record, table)
+		if err != nil {
+			quarantined = append(quarantined, newQuarantinedRecord(name, uri, original, err))
+
+			continue
+		}
+
+		kept = append(kept, transformed)
+	}
+
+	// An object that is quarantined leaves no records.
+	var out interface{} = kept
+	if !isList && len(kept) == 1 {
+		out = kept[0]
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	return data, quarantined, nil
+}
+
+// newQuarantinedRecord will return the quarantined record of the table, which failed with the error.
+func newQuarantinedRecord(table string, uri *url.URL, record []byte, err error) *quarantinedRecord {
+	hash := sha256.Sum256(append([]byte(table+"\n"), record...))
+
+	quarantined := &quarantinedRecord{
+		ID:            hex.EncodeToString(hash[:]),
+		Table:         table,
+		Error:         err.Error(),
+		Record:        string(record),
+		QuarantinedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if uri != nil {
+		quarantined.URL = uri.Redacted()
+	}
+
+	return quarantined
+}
+
+// quarantineRequest will return the upsert of the quarantined records to the quarantine table.
+func quarantineRequest(table string, records []*quarantinedRecord) (*proto.UpsertRequest, error) {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quarantined records: %w", err)
+	}
+
+	return &proto.UpsertRequest{Table: table, Data: data, PrimaryKeys: []string{quarantineIDField}}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import "sync"
+
+// writeQueue sends the writes of a transaction to its storage in the background, so that a repository worker writing
+// to several destinations is not held up by the slowest of them. At most "size" writes wait in the queue, after which
+// queueing a write waits for the destination to catch up.
+type writeQueue struct {
+	writes chan func()
+	done   chan struct{}
+	once   sync.Once
+}
+
+// newWriteQueue will return a queue of at most size writes, which sends them in order until it is drained.
+func newWriteQueue(size int) *writeQueue {
+	queue := &writeQueue{writes: make(chan func(), size), done: make(chan struct{})}
+
+	go func() {
+		defer close(queue.done)
+
+		for send := range queue.writes {
+			send()
+		}
+	}()
+
+	return queue
+}
+
+// send will queue the write. A nil queue sends the write as is.
+func (queue *writeQueue) send(send func()) {
+	if queue == nil {
+		send()
+
+		return
+	}
+
+	queue.writes <- send
+}
+
+// drain will stop the queue, and wait for the writes in it to be sent.
+func (queue *writeQueue) drain() {
+	if queue == nil {
+		return
+	}
+
+	queue.once.Do(func() { close(queue.writes) })
+	<-queue.done
+}
@@ -0,0 +1,135 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWriteQueue(t *testing.T) {
+	t.Parallel()
+
+	queue := newWriteQueue(2)
+
+	var sent []int
+
+	for idx := 0; idx < 5; idx++ {
+		idx := idx
+
+		queue.send(func() { sent = append(sent, idx) })
+	}
+
+	queue.drain()
+	queue.drain()
+
+	if len(sent) != 5 {
+		t.Fatalf("expected 5 writes to be sent, got %v", sent)
+	}
+
+	for idx, val := range sent {
+		if val != idx {
+			t.Errorf("expected the writes to be sent in order, got %v", sent)
+		}
+	}
+
+	// A nil queue sends the write as is.
+	var nilQueue *writeQueue
+
+	called := false
+	nilQueue.send(func() { called = true })
+	nilQueue.drain()
+
+	if !called {
+		t.Errorf("expected a nil queue to send the write")
+	}
+}
+
+func TestSlowDestination(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	newRepo := func(t *testing.T) *destinationRepo {
+		t.Helper()
+
+		repo, err := repository.New(ctx, "file://"+t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		return &destinationRepo{GenericService: repo, dest: &config.Destination{}, logger: logger, queueSize: 4}
+	}
+
+	txRepos, err := beginTx(ctx, []*destinationRepo{newRepo(t), newRepo(t)}, logger)
+	if err != nil {
+		t.Fatalf("failed to begin transactions: %v", err)
+	}
+
+	slow, fast := txRepos[0], txRepos[1]
+
+	release := make(chan struct{})
+
+	var slowWrites, fastWrites int32
+
+	slow.Transact(func(context.Context, repository.Generic) error {
+		<-release
+
+		return nil
+	})
+
+	// The writes to the fast destination are not held up by the slow one.
+	for idx := 0; idx < 3; idx++ {
+		slow.Transact(func(context.Context, repository.Generic) error {
+			atomic.AddInt32(&slowWrites, 1)
+
+			return nil
+		})
+
+		fast.Transact(func(context.Context, repository.Generic) error {
+			atomic.AddInt32(&fastWrites, 1)
+
+			return nil
+		})
+	}
+
+	if err := fast.Commit(); err != nil {
+		t.Fatalf("failed to commit the fast destination: %v", err)
+	}
+
+	if writes := atomic.LoadInt32(&fastWrites); writes != 3 {
+		t.Errorf("expected 3 writes to the fast destination, got %d", writes)
+	}
+
+	if writes := atomic.LoadInt32(&slowWrites); writes != 0 {
+		t.Errorf("expected the slow destination to still be writing, got %d writes", writes)
+	}
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+	if err := commit([]*destinationRepo{slow}, logger); err != nil {
+		t.Fatalf("failed to commit the slow destination: %v", err)
+	}
+
+	if writes := atomic.LoadInt32(&slowWrites); writes != 3 {
+		t.Errorf("expected 3 writes to the slow destination, got %d", writes)
+	}
+}
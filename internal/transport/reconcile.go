@@ -0,0 +1,81 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+)
+
+// clearDeleted will set the soft delete column of each JSON object in the data to false, since every fetched record
+// exists.
+func clearDeleted(data []byte, column string) ([]byte, error) {
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		record[column] = false
+
+		return record, nil
+	})
+}
+
+// recordKeys will return the primary key values of each JSON object in the data, which is either an object or a list
+// of objects.
+func recordKeys(data []byte, pks []string) ([]map[string]interface{}, error) {
+	records, err := decodeRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]map[string]interface{}, 0, len(records))
+
+	for _, record := range records {
+		key := make(map[string]interface{}, len(pks))
+		for _, pk := range pks {
+			key[pk] = record[pk]
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// reconcileFn will return a transaction function that flags the records of the table that were not fetched as
+// deleted. Storage devices that do not support soft deletes are skipped with a warning.
+func reconcileFn(req *proto.ReconcileRequest, logger tools.Logger) func(context.Context, repository.Generic) error {
+	return func(sctx context.Context, repo repository.Generic) error {
+		start := time.Now()
+		scheme := proto.SchemeFromStorageType(repo.Type())
+
+		rsp, err := repo.Reconcile(sctx, req)
+		if errors.Is(err, proto.ErrReconcileNotSupported) {
+			msg := fmt.Sprintf("soft deletes are not supported on %q, skipping %s", scheme, req.Table)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("error reconciling data: %w", err)
+		}
+
+		logInfo := tools.LogFormatter{
+			Duration: time.Since(start),
+			Table:    req.Table,
+			Msg:      fmt.Sprintf("reconcile completed: %s.%s, %d records flagged", scheme, req.Table, rsp.UpdatedCount),
+		}
+		logInfo.Log(logger, tools.LogLevelInfo)
+
+		return nil
+	}
+}
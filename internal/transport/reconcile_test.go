@@ -0,0 +1,172 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/file"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// reconcilingFile is file storage that keeps the reconcile requests that are sent to it.
+type reconcilingFile struct {
+	*file.File
+	mutex      sync.Mutex
+	reconciled []*proto.ReconcileRequest
+}
+
+func (stg *reconcilingFile) Reconcile(_ context.Context, req *proto.ReconcileRequest) (*proto.ReconcileResponse,
+	error,
+) {
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	stg.reconciled = append(stg.reconciled, req)
+
+	return &proto.ReconcileResponse{}, nil
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("clear deleted", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := clearDeleted([]byte(`[{"id":"1"},{"id":"2","deleted":true}]`), "deleted")
+		if err != nil {
+			t.Fatalf("failed to clear deleted flag: %v", err)
+		}
+
+		expected := `[{"deleted":false,"id":"1"},{"deleted":false,"id":"2"}]`
+		if string(got) != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	})
+
+	t.Run("record keys", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			data     string
+			expected []map[string]interface{}
+		}{
+			{
+				data: `[{"id":"1","product":"btc","price":1},{"id":"2","product":"eth"}]`,
+				expected: []map[string]interface{}{
+					{"id": "1", "product": "btc"},
+					{"id": "2", "product": "eth"},
+				},
+			},
+			{
+				data:     `{"id":"1","product":"btc"}`,
+				expected: []map[string]interface{}{{"id": "1", "product": "btc"}},
+			},
+			{
+				data:     `[]`,
+				expected: []map[string]interface{}{},
+			},
+		} {
+			got, err := recordKeys([]byte(tcase.data), []string{"id", "product"})
+			if err != nil {
+				t.Fatalf("failed to get record keys: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tcase.expected) {
+				t.Errorf("%s: expected %v, got %v", tcase.data, tcase.expected, got)
+			}
+		}
+
+		if _, err := recordKeys([]byte(`not json`), []string{"id"}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestReconcileHashKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"trade_id":1,"price":"1.5"},{"trade_id":2,"price":"2.5"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/trades")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	fileStg, err := file.New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file storage: %v", err)
+	}
+
+	stg := &reconcilingFile{File: fileStg}
+
+	hashKey := &config.HashKey{Fields: []string{"trade_id"}}
+	req := &config.Request{Table: "trades", HashKey: hashKey, SoftDelete: "deleted"}
+	cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+	txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+	txn.newJob(flatReq).run(ctx, 1)
+
+	repo := &repository.GenericService{Storage: stg}
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	if len(stg.reconciled) != 1 {
+		t.Fatalf("expected the table to be reconciled once, got %d", len(stg.reconciled))
+	}
+
+	// The records are reconciled on their hash key, which identifies them in storage.
+	reconciled := stg.reconciled[0]
+	if !reflect.DeepEqual(reconciled.PrimaryKeys, []string{config.DefaultHashKeyColumn}) {
+		t.Errorf("expected the hash key, got %v", reconciled.PrimaryKeys)
+	}
+
+	if len(reconciled.Keys) != 2 {
+		t.Fatalf("expected the keys of 2 records, got %v", reconciled.Keys)
+	}
+
+	for _, key := range reconciled.Keys {
+		if hash, ok := key[config.DefaultHashKeyColumn].(string); len(key) != 1 || !ok || hash == "" {
+			t.Errorf("expected only the hash of the record, got %v", key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// decodeRecords will decode the JSON objects in the data, which is either an object or a list of objects.
+func decodeRecords(data []byte) ([]map[string]interface{}, error) {
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		var record map[string]interface{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// mapRecords will replace each JSON object in the data, which is either an object or a list of objects, with the
+// result of calling fn on it. Values in a list that are not objects are left unchanged. Numbers are decoded as
+// "json.Number", so that they are encoded again with their exact text.
+func mapRecords(data []byte, fn func(map[string]interface{}) (map[string]interface{}, error)) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	mapRecord := func(val interface{}) (interface{}, error) {
+		record, ok := val.(map[string]interface{})
+		if !ok {
+			return val, nil
+		}
+
+		return fn(record)
+	}
+
+	var err error
+
+	switch records := decoded.(type) {
+	case []interface{}:
+		for idx, val := range records {
+			if records[idx], err = mapRecord(val); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if decoded, err = mapRecord(decoded); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	return data, nil
+}
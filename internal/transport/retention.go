@@ -0,0 +1,74 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// retainedTables will return the names of the tables with a retention, in order.
+func retainedTables(cfg *config.Config) []string {
+	names := []string{}
+
+	for name, table := range cfg.Tables {
+		if table != nil && table.Retention != nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// retentionRequest will return the request to delete the records of the table that are older than its retention.
+func retentionRequest(cfg *config.Config, name string, now time.Time) (*proto.DeleteRequest, error) {
+	retention := cfg.Tables[name].Retention
+
+	age, err := retention.Age()
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.DeleteRequest{
+		Table: cfg.StorageTable(name),
+		Range: &proto.TimeRange{Column: retention.Column, End: now.Add(-age)},
+	}, nil
+}
+
+// enforceRetention will delete the records that are older than the retention of their table, in a transaction on each
+// destination that the table is routed to.
+func enforceRetention(ctx context.Context, cfg *config.Config, repos []*destinationRepo, now time.Time) error {
+	for _, name := range retainedTables(cfg) {
+		req, err := retentionRequest(cfg, name, now)
+		if err != nil {
+			return err
+		}
+
+		txRepos, err := beginTx(ctx, routed(repos, name), cfg.Logger)
+		if err != nil {
+			return err
+		}
+
+		for _, repo := range txRepos {
+			repo.Transact(deleteFn(req, cfg.Logger))
+		}
+
+		if err := commit(txRepos, cfg.Logger); err != nil {
+			return fmt.Errorf("failed to enforce retention on %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
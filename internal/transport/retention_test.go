@@ -0,0 +1,53 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	cfg := &config.Config{
+		TablePrefix: "dev_",
+		Tables: map[string]*config.Table{
+			"trades":  {Retention: &config.Retention{Column: "time", MaxAge: "36h"}},
+			"candles": {Retention: &config.Retention{Column: "time", MaxAge: "90d"}},
+			"orders":  {Document: "body"},
+			"fills":   nil,
+		},
+	}
+
+	names := retainedTables(cfg)
+	if !reflect.DeepEqual(names, []string{"candles", "trades"}) {
+		t.Fatalf("expected the tables with a retention in order, got %v", names)
+	}
+
+	req, err := retentionRequest(cfg, "candles", now)
+	if err != nil {
+		t.Fatalf("failed to create retention request: %v", err)
+	}
+
+	expected := &proto.DeleteRequest{
+		Table: "dev_candles",
+		Range: &proto.TimeRange{Column: "time", End: now.Add(-90 * 24 * time.Hour)},
+	}
+	if !reflect.DeepEqual(req, expected) {
+		t.Errorf("expected %+v, got %+v", expected, req)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+// maxRetryBackoff is the longest wait between retries of a transaction.
+const maxRetryBackoff = time.Minute
+
+// retryBudget is the retries that are left to a run, shared by its transactions and requests.
+type retryBudget struct {
+	retries int
+	limit   time.Duration
+	logger  tools.Logger
+
+	mutex     sync.Mutex
+	used      int
+	spent     time.Duration
+	exhausted bool
+}
+
+// newRetryBudget will return the retry budget of the run, or nil if its retries are not limited.
+func newRetryBudget(cfg *config.Config) (*retryBudget, error) {
+	if cfg.RetryBudget == nil {
+		return nil, nil
+	}
+
+	limit, err := cfg.RetryBudget.TimeDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	return &retryBudget{retries: cfg.RetryBudget.Retries, limit: limit, logger: cfg.Logger}, nil
+}
+
+// take will take a retry of the named transaction or request from the budget, which waits for the backoff before it
+// is made. False is returned if the budget does not have enough retries or time left, in which case the run stops
+// retrying. A nil budget is never exhausted.
+func (budget *retryBudget) take(name string, wait time.Duration) bool {
+	if budget == nil {
+		return true
+	}
+
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	if !budget.exhausted && (budget.retries == 0 || budget.used < budget.retries) &&
+		(budget.limit == 0 || budget.spent+wait <= budget.limit) {
+		budget.used++
+		budget.spent += wait
+
+		return true
+	}
+
+	if !budget.exhausted {
+		msg := fmt.Sprintf("retry budget of the run exhausted after %d retries and %v, not retrying %s",
+			budget.used, budget.spent.Round(time.Millisecond), name)
+		tools.LogFormatter{Msg: msg}.Log(budget.logger, tools.LogLevelWarn)
+	}
+
+	budget.exhausted = true
+
+	return false
+}
+
+// spend will add the duration of a retry that was made to the time spent on retries.
+func (budget *retryBudget) spend(duration time.Duration) {
+	if budget == nil {
+		return
+	}
+
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	budget.spent += duration
+}
+
+// retryTxn will call fn until it succeeds, fails with an error that is not transient, or the retries of the policy or
+// the budget of the run are used up, waiting for the backoff of the policy before each retry. The backoff doubles
+// after each retry. Transactions are not retried if the policy is nil.
+func retryTxn(ctx context.Context, policy *config.Retry, budget *retryBudget, name string, logger tools.Logger,
+	fn func() error,
+) error {
+	if policy == nil {
+		return fn()
+	}
+
+	wait, err := policy.BackoffDuration()
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+
+		err := fn()
+		if attempt > 0 {
+			budget.spend(time.Since(start))
+		}
+
+		if err == nil || !errors.Is(err, proto.ErrTransient) || attempt >= policy.Retries || !budget.take(name, wait) {
+			return err
+		}
+
+		msg := fmt.Sprintf("retrying %s in %v after a transient error: %v", name, wait, err)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+
+		if wait *= 2; wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+	}
+}
+
+// sleep will wait for the duration before a retry, or return an error if the context is done first.
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+
+		return fmt.Errorf("context done while waiting to retry: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRetryTxn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	errDeadlock := proto.Transient(fmt.Errorf("deadlock detected"))
+	errInvalid := fmt.Errorf("invalid input syntax")
+
+	for _, tcase := range []struct {
+		name     string
+		policy   *config.Retry
+		budget   *config.RetryBudget
+		errs     []error
+		wantErr  error
+		expected int
+	}{
+		{
+			name:     "retried",
+			policy:   &config.Retry{Retries: 3, Backoff: "1ms"},
+			errs:     []error{errDeadlock, errDeadlock},
+			expected: 3,
+		},
+		{
+			name:     "retries used up",
+			policy:   &config.Retry{Retries: 1, Backoff: "1ms"},
+			errs:     []error{errDeadlock, errDeadlock},
+			wantErr:  errDeadlock,
+			expected: 2,
+		},
+		{
+			name:     "not transient",
+			policy:   &config.Retry{Retries: 3, Backoff: "1ms"},
+			errs:     []error{errInvalid},
+			wantErr:  errInvalid,
+			expected: 1,
+		},
+		{
+			name:     "budget used up",
+			policy:   &config.Retry{Retries: 3, Backoff: "1ms"},
+			budget:   &config.RetryBudget{Retries: 1},
+			errs:     []error{errDeadlock, errDeadlock},
+			wantErr:  errDeadlock,
+			expected: 2,
+		},
+		{
+			name:     "no policy",
+			errs:     []error{errDeadlock},
+			wantErr:  errDeadlock,
+			expected: 1,
+		},
+	} {
+		budget, err := newRetryBudget(&config.Config{RetryBudget: tcase.budget, Logger: logger})
+		if err != nil {
+			t.Fatalf("%s: failed to create retry budget: %v", tcase.name, err)
+		}
+
+		attempts := 0
+
+		err = retryTxn(ctx, tcase.policy, budget, "request", logger, func() error {
+			attempts++
+			if attempts <= len(tcase.errs) {
+				return tcase.errs[attempts-1]
+			}
+
+			return nil
+		})
+
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if attempts != tcase.expected {
+			t.Errorf("%s: expected %d attempts, got %d", tcase.name, tcase.expected, attempts)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	// The budget is shared by every transaction and request of the run.
+	budget, err := newRetryBudget(&config.Config{RetryBudget: &config.RetryBudget{Retries: 3, Time: "1m"}, Logger: logger})
+	if err != nil {
+		t.Fatalf("failed to create retry budget: %v", err)
+	}
+
+	if !budget.take("candles", 10*time.Second) || !budget.take("trades", 20*time.Second) {
+		t.Fatal("expected the retries to be taken from the budget")
+	}
+
+	budget.spend(20 * time.Second)
+
+	// The time left is not enough for the backoff of the retry.
+	if budget.take("candles", 20*time.Second) {
+		t.Error("expected the time of the budget to be used up")
+	}
+
+	// Once the budget is exhausted, nothing is retried.
+	if budget.take("trades", time.Millisecond) {
+		t.Error("expected the budget to be exhausted")
+	}
+
+	var unlimited *retryBudget
+	if !unlimited.take("candles", time.Hour) {
+		t.Error("expected retries to be unlimited without a budget")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	t.Parallel()
+
+	req := &config.Request{Table: "candles"}
+	flattenedRequests := []*flattenedRequest{{request: req}, {request: req}}
+
+	cfg := &config.Config{Requests: []*config.Request{req}, Retry: &config.Retry{Retries: 1}}
+
+	txn := newRequestTxns(cfg, flattenedRequests)[0]
+	txn.jobs <- &repoJob{b: []byte(`[{"id":"1"}]`)}
+	txn.jobs <- &repoJob{b: []byte(`[{"id":"2"}]`)}
+
+	first := []*repoJob{txn.receive(0), txn.receive(1)}
+
+	// The data is received again when the writes are retried.
+	if second := []*repoJob{txn.receive(0), txn.receive(1)}; first[0] != second[0] || first[1] != second[1] {
+		t.Errorf("expected the received data to be kept, got %v and %v", first, second)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alpstable/gidari/tools"
+)
+
+// chunkLogs logs the chunks of a request that are fetched and the batches of its records that are upserted. If they
+// are sampled, one message is logged for every "every" chunks or batches of the same kind, with their totals, so that
+// a backfill of many chunks does not produce unusable log volume, see "config.Config.LogSample".
+type chunkLogs struct {
+	mutex   sync.Mutex
+	logger  tools.Logger
+	every   int
+	pending map[string]*sampledLogs
+}
+
+// sampledLogs are the totals of the chunks or batches of a kind that have not been logged yet.
+type sampledLogs struct {
+	unit  string
+	count int
+	total int
+	entry tools.LogFormatter
+}
+
+// newChunkLogs will return the chunk logs of a request, which are sampled if "every" is greater than one.
+func newChunkLogs(logger tools.Logger, every int) *chunkLogs {
+	return &chunkLogs{logger: logger, every: every, pending: make(map[string]*sampledLogs)}
+}
+
+// log will log the entry of a chunk or batch, e.g. "web requests completed" for "chunks". If the logs are sampled, the
+// entry is added to the totals of its kind, which are logged once "every" entries have been added.
+func (logs *chunkLogs) log(kind, unit string, entry tools.LogFormatter) {
+	if logs.every <= 1 {
+		entry.Log(logs.logger, tools.LogLevelDebug)
+
+		return
+	}
+
+	logs.mutex.Lock()
+	defer logs.mutex.Unlock()
+
+	sampled, ok := logs.pending[kind]
+	if !ok {
+		sampled = &sampledLogs{unit: unit}
+		logs.pending[kind] = sampled
+	}
+
+	sampled.add(entry)
+
+	if sampled.count >= logs.every {
+		sampled.log(kind, logs.logger)
+	}
+}
+
+// flush will log the totals of the entries that have not been logged yet, e.g. once the request has been written.
+func (logs *chunkLogs) flush() {
+	logs.mutex.Lock()
+	defer logs.mutex.Unlock()
+
+	kinds := make([]string, 0, len(logs.pending))
+	for kind := range logs.pending {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		if logs.pending[kind].count > 0 {
+			logs.pending[kind].log(kind, logs.logger)
+		}
+	}
+}
+
+// add will add the entry to the totals. The worker and chunk of the entry are dropped, since the totals are of
+// chunks fetched by different workers.
+func (sampled *sampledLogs) add(entry tools.LogFormatter) {
+	sampled.count++
+	sampled.total++
+
+	sampled.entry.Host = entry.Host
+	sampled.entry.Endpoint = entry.Endpoint
+	sampled.entry.Table = entry.Table
+	sampled.entry.Duration += entry.Duration
+	sampled.entry.UpsertedCount += entry.UpsertedCount
+	sampled.entry.MatchedCount += entry.MatchedCount
+}
+
+// log will log the totals, with the total duration of the entries, and reset them.
+func (sampled *sampledLogs) log(kind string, logger tools.Logger) {
+	entry := sampled.entry
+	entry.Msg = fmt.Sprintf("%s: %d %s, %d in total", kind, sampled.count, sampled.unit, sampled.total)
+	entry.Log(logger, tools.LogLevelDebug)
+
+	sampled.count = 0
+	sampled.entry = tools.LogFormatter{}
+}
@@ -0,0 +1,77 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/tools"
+)
+
+// recordedLogs is a logger that records the messages that are logged with it.
+type recordedLogs struct {
+	entries []string
+}
+
+func (logs *recordedLogs) Log(_ tools.LogLevel, msg string, fields map[string]interface{}) {
+	logs.entries = append(logs.entries, fmt.Sprintf("%s (%v)", msg, fields["duration"]))
+}
+
+func TestChunkLogs(t *testing.T) {
+	t.Parallel()
+
+	entry := tools.LogFormatter{WorkerID: 1, Table: "candles", Duration: time.Second, Msg: "web request completed"}
+
+	t.Run("every chunk", func(t *testing.T) {
+		t.Parallel()
+
+		logger := &recordedLogs{}
+		logs := newChunkLogs(logger, 0)
+
+		logs.log("web requests completed", "chunks", entry)
+		logs.log("web requests completed", "chunks", entry)
+		logs.flush()
+
+		want := []string{
+			"web request completed (1s)",
+			"web request completed (1s)",
+		}
+		if !reflect.DeepEqual(logger.entries, want) {
+			t.Errorf("expected %q, got %q", want, logger.entries)
+		}
+	})
+
+	t.Run("sampled", func(t *testing.T) {
+		t.Parallel()
+
+		logger := &recordedLogs{}
+		logs := newChunkLogs(logger, 2)
+
+		for i := 0; i < 5; i++ {
+			logs.log("web requests completed", "chunks", entry)
+		}
+
+		logs.log("partial upserts completed: file.candles", "batches", entry)
+		logs.flush()
+
+		want := []string{
+			"web requests completed: 2 chunks, 2 in total (2s)",
+			"web requests completed: 2 chunks, 4 in total (2s)",
+			"partial upserts completed: file.candles: 1 batches, 1 in total (1s)",
+			"web requests completed: 1 chunks, 5 in total (1s)",
+		}
+		if !reflect.DeepEqual(logger.entries, want) {
+			t.Errorf("expected %q, got %q", want, logger.entries)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// diffFields will return the fields of the records in the data that are not declared, and the required fields that
+// are missing from any of the records, in order. Data that is not a JSON object or a list of JSON objects is not
+// checked.
+func diffFields(data []byte, declared, required []string) ([]string, []string) {
+	records, err := decodeRecords(data)
+	if err != nil {
+		return nil, nil
+	}
+
+	fields := make(map[string]bool, len(declared))
+	for _, field := range declared {
+		fields[field] = true
+	}
+
+	unexpected := make(map[string]bool)
+	missing := make(map[string]bool)
+
+	for _, record := range records {
+		for field := range record {
+			if !fields[field] {
+				unexpected[field] = true
+			}
+		}
+
+		for _, field := range required {
+			if _, ok := record[field]; !ok {
+				missing[field] = true
+			}
+		}
+	}
+
+	return sortedFields(unexpected), sortedFields(missing)
+}
+
+func sortedFields(set map[string]bool) []string {
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// checkFields will check the records of the response against the fields that their table declares. With the strict
+// decoding mode, an error that matches "ErrSchema" is returned if the records have unexpected fields or miss required
+// fields, which are the declared fields unless the table has a schema. Otherwise the differences are logged.
+func (job *webJob) checkFields(data []byte) error {
+	if len(job.tableConfig.Fields) == 0 || !json.Valid(data) {
+		return nil
+	}
+
+	unexpected, missing := diffFields(data, job.tableConfig.Fields, job.tableConfig.RequiredFields())
+	if len(unexpected) == 0 && len(missing) == 0 {
+		return nil
+	}
+
+	var diffs []string
+
+	if len(unexpected) > 0 {
+		diffs = append(diffs, fmt.Sprintf("unexpected fields %s", strings.Join(unexpected, ", ")))
+	}
+
+	if len(missing) > 0 {
+		diffs = append(diffs, fmt.Sprintf("missing fields %s", strings.Join(missing, ", ")))
+	}
+
+	err := fmt.Errorf("%w for %q in %s: %s", ErrSchema, job.storageTable, job.fetchConfig.URL.Redacted(),
+		strings.Join(diffs, "; "))
+
+	if job.tableConfig.Decoding == config.DecodingStrict {
+		return err
+	}
+
+	tools.LogFormatter{Msg: err.Error()}.Log(job.logger, tools.LogLevelWarn)
+
+	return nil
+}
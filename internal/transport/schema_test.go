@@ -0,0 +1,118 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheckFields(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	uri, err := url.Parse("https://api.test/candles?start=1")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	fields := []string{"id", "price", "size"}
+
+	schema := &config.Schema{Fields: []*config.SchemaField{
+		{Name: "id", Type: config.SchemaString, Key: true},
+		{Name: "price", Type: config.SchemaDecimal},
+		{Name: "size", Type: config.SchemaNumber, Required: true},
+	}}
+	cfg := &config.Config{Tables: map[string]*config.Table{
+		"candles": {Schema: schema, Decoding: config.DecodingStrict},
+	}}
+
+	for _, tcase := range []struct {
+		name       string
+		data       string
+		table      *config.Table
+		unexpected []string
+		missing    []string
+		wantErr    error
+	}{
+		{
+			name:       "declared",
+			data:       `[{"id":"1","price":"1.0","size":"2"},{"id":"2","price":"1.1","size":"3"}]`,
+			table:      &config.Table{Fields: fields, Decoding: config.DecodingStrict},
+			unexpected: []string{},
+			missing:    []string{},
+		},
+		{
+			name:       "strict",
+			data:       `[{"id":"1","price":"1.0","side":"buy"},{"id":"2","price":"1.1","size":"3"}]`,
+			table:      &config.Table{Fields: fields, Decoding: config.DecodingStrict},
+			unexpected: []string{"side"},
+			missing:    []string{"size"},
+			wantErr:    ErrSchema,
+		},
+		{
+			name:       "lenient",
+			data:       `{"id":"1","price":"1.0","size":"2","side":"buy"}`,
+			table:      &config.Table{Fields: fields},
+			unexpected: []string{"side"},
+			missing:    []string{},
+		},
+		{
+			name:       "schema",
+			data:       `[{"id":"1","side":"buy"},{"id":"2","price":"1.1","size":"3"}]`,
+			table:      cfg.TableFor("candles"),
+			unexpected: []string{"side"},
+			missing:    []string{"size"},
+			wantErr:    ErrSchema,
+		},
+		{
+			name:  "undeclared",
+			data:  `[{"side":"buy"}]`,
+			table: &config.Table{Decoding: config.DecodingStrict},
+		},
+	} {
+		if len(tcase.table.Fields) > 0 {
+			unexpected, missing := diffFields([]byte(tcase.data), tcase.table.Fields,
+				tcase.table.RequiredFields())
+			if !reflect.DeepEqual(unexpected, tcase.unexpected) || !reflect.DeepEqual(missing, tcase.missing) {
+				t.Errorf("%s: expected unexpected fields %v and missing fields %v, got %v and %v", tcase.name,
+					tcase.unexpected, tcase.missing, unexpected, missing)
+			}
+		}
+
+		job := &webJob{
+			flattenedRequest: &flattenedRequest{fetchConfig: &web.FetchConfig{URL: uri}},
+			tableConfig:      tcase.table,
+			storageTable:     "candles",
+			logger:           logger,
+		}
+
+		err := job.checkFields([]byte(tcase.data))
+		if !errors.Is(err, tcase.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		want := `unexpected schema for "candles" in https://api.test/candles?start=1: unexpected fields side; ` +
+			`missing fields size`
+		if err != nil && err.Error() != want {
+			t.Errorf("%s: expected %q, got %q", tcase.name, want, err.Error())
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alpstable/gidari/config"
+	"go.starlark.net/starlark"
+)
+
+// scriptMaxSteps is the maximum number of steps that a script can take for each record, so that a script that never
+// returns fails its chunk rather than hanging the run.
+const scriptMaxSteps = 1_000_000
+
+// recordScript is the compiled script of a request. Its globals are frozen, so that it is shared by the chunks of the
+// request.
+type recordScript struct {
+	name      string
+	transform *starlark.Function
+}
+
+// newRecordScript will compile the script of the request and look up its "transform" function. A request without a
+// script returns nil.
+func newRecordScript(req *config.Request) (*recordScript, error) {
+	if req.Script == nil {
+		return nil, nil
+	}
+
+	name, src := req.Endpoint, req.Script.Source
+
+	if req.Script.File != "" {
+		data, err := os.ReadFile(req.Script.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read script: %w", err)
+		}
+
+		name, src = req.Script.File, string(data)
+	}
+
+	thread := &starlark.Thread{Name: name, Print: func(*starlark.Thread, string) {}}
+
+	globals, err := starlark.ExecFile(thread, name, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run script %q: %w", name, err)
+	}
+
+	globals.Freeze()
+
+	transform, ok := globals["transform"].(*starlark.Function)
+	if !ok || transform.NumParams() < 1 || transform.NumParams() > 2 {
+		return nil, fmt.Errorf("script %q must define transform(record) or transform(record, params)", name)
+	}
+
+	return &recordScript{name: name, transform: transform}, nil
+}
+
+// apply will call the transform function of the script with each record of the data, which is an object or a list of
+// objects, and the query parameters of the URL of the chunk. The records that the function returns are returned as a
+// list. Values in a list that are not objects are passed through. A nil script returns the data as is.
+func (script *recordScript) apply(data []byte, uri *url.URL) ([]byte, error) {
+	if script == nil {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	values, ok := decoded.([]interface{})
+	if !ok {
+		values = []interface{}{decoded}
+	}
+
+	params := starlark.NewDict(len(uri.Query()))
+
+	for key, vals := range uri.Query() {
+		if len(vals) > 0 {
+			_ = params.SetKey(starlark.String(key), starlark.String(vals[0]))
+		}
+	}
+
+	params.Freeze()
+
+	thread := &starlark.Thread{Name: script.name, Print: func(*starlark.Thread, string) {}}
+	records := make([]interface{}, 0, len(values))
+
+	for _, val := range values {
+		record, ok := val.(map[string]interface{})
+		if !ok {
+			records = append(records, val)
+
+			continue
+		}
+
+		transformed, err := script.call(thread, record, params)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, transformed...)
+	}
+
+	out, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	return out, nil
+}
+
+// call will call the transform function with the record, returning the records that it returned.
+func (script *recordScript) call(thread *starlark.Thread, record map[string]interface{},
+	params *starlark.Dict,
+) ([]interface{}, error) {
+	arg, err := toStarlark(record)
+	if err != nil {
+		return nil, err
+	}
+
+	args := starlark.Tuple{arg}
+	if script.transform.NumParams() == 2 {
+		args = append(args, params)
+	}
+
+	thread.SetMaxExecutionSteps(thread.ExecutionSteps() + scriptMaxSteps)
+
+	result, err := starlark.Call(thread, script.transform, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run script %q: %w", script.name, err)
+	}
+
+	var results []starlark.Value
+
+	switch result := result.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.Dict:
+		results = []starlark.Value{result}
+	case *starlark.List:
+		for idx := 0; idx < result.Len(); idx++ {
+			results = append(results, result.Index(idx))
+		}
+	default:
+		return nil, fmt.Errorf("script %q returned a %s, not a record, a list of records, or None", script.name,
+			result.Type())
+	}
+
+	records := make([]interface{}, 0, len(results))
+
+	for _, result := range results {
+		if _, ok := result.(*starlark.Dict); !ok {
+			return nil, fmt.Errorf("script %q returned a %s in a list, not a record", script.name, result.Type())
+		}
+
+		record, err := fromStarlark(result)
+		if err != nil {
+			return nil, fmt.Errorf("script %q returned an invalid record: %w", script.name, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// toStarlark will convert a value that was decoded from JSON into a Starlark value. Numbers are converted to ints if
+// they are integers, and to floats otherwise.
+func toStarlark(val interface{}) (starlark.Value, error) {
+	switch val := val.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(val), nil
+	case string:
+		return starlark.String(val), nil
+	case json.Number:
+		if !strings.ContainsAny(val.String(), ".eE") {
+			if num, ok := new(big.Int).SetString(val.String(), 10); ok {
+				return starlark.MakeBigInt(num), nil
+			}
+		}
+
+		float, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", val, err)
+		}
+
+		return starlark.Float(float), nil
+	case []interface{}:
+		list := make([]starlark.Value, 0, len(val))
+
+		for _, elem := range val {
+			converted, err := toStarlark(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, converted)
+		}
+
+		return starlark.NewList(list), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		dict := starlark.NewDict(len(val))
+
+		for _, key := range keys {
+			converted, err := toStarlark(val[key])
+			if err != nil {
+				return nil, err
+			}
+
+			_ = dict.SetKey(starlark.String(key), converted)
+		}
+
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %T", val)
+	}
+}
+
+// fromStarlark will convert a Starlark value into a value that can be encoded as JSON. Ints are encoded with their
+// exact text.
+func fromStarlark(val starlark.Value) (interface{}, error) {
+	switch val := val.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(val), nil
+	case starlark.String:
+		return string(val), nil
+	case starlark.Int:
+		return json.Number(val.String()), nil
+	case starlark.Float:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			return nil, fmt.Errorf("%v cannot be stored", val)
+		}
+
+		return float64(val), nil
+	case *starlark.List:
+		return fromStarlarkIterable(val)
+	case starlark.Tuple:
+		return fromStarlarkIterable(val)
+	case *starlark.Dict:
+		fields := make(map[string]interface{}, val.Len())
+
+		for _, item := range val.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("key %v is not a string", item[0])
+			}
+
+			field, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+
+			fields[string(key)] = field
+		}
+
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s value", val.Type())
+	}
+}
+
+// fromStarlarkIterable will convert the elements of a Starlark list or tuple into a list.
+func fromStarlarkIterable(val starlark.Iterable) ([]interface{}, error) {
+	iter := val.Iterate()
+	defer iter.Done()
+
+	list := make([]interface{}, 0)
+
+	var elem starlark.Value
+	for iter.Next(&elem) {
+		converted, err := fromStarlark(elem)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, converted)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,115 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestRecordScript(t *testing.T) {
+	t.Parallel()
+
+	uri, err := url.Parse("https://api.example.com/candles?product_id=BTC-USD")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	// The script drops empty candles, splits candles with both sides, and adds the product to the others.
+	source := `
+def transform(record, params):
+    if record.get("volume") == 0:
+        return None
+    if "sides" in record:
+        return [{"id": record["id"], "side": side} for side in record.pop("sides")]
+    record["product_id"] = params["product_id"]
+    record["big"] = record["big"] + 1
+    return record
+`
+
+	script, err := newRecordScript(&config.Request{Endpoint: "/candles", Script: &config.Script{Source: source}})
+	if err != nil {
+		t.Fatalf("failed to compile script: %v", err)
+	}
+
+	data, err := script.apply([]byte(`[{"id":1,"volume":0},{"id":2,"sides":["buy","sell"]},`+
+		`{"id":3,"big":12345678901234567890,"px":1.5,"tags":[null,true]},4]`), uri)
+	if err != nil {
+		t.Fatalf("failed to apply script: %v", err)
+	}
+
+	expected := `[{"id":2,"side":"buy"},{"id":2,"side":"sell"},` +
+		`{"big":12345678901234567891,"id":3,"product_id":"BTC-USD","px":1.5,"tags":[null,true]},4]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// A script in a file that takes only the record.
+	file := filepath.Join(t.TempDir(), "object.star")
+	if err := os.WriteFile(file, []byte("def transform(record):\n    return record\n"), 0o600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	script, err = newRecordScript(&config.Request{Script: &config.Script{File: file}})
+	if err != nil {
+		t.Fatalf("failed to compile script: %v", err)
+	}
+
+	if data, err := script.apply([]byte(`{"id":1}`), uri); err != nil || string(data) != `[{"id":1}]` {
+		t.Errorf("expected the record, got %s and %v", data, err)
+	}
+
+	for _, tcase := range []struct {
+		name   string
+		source string
+
+		// compiled is whether the script compiles, and fails when it is applied.
+		compiled bool
+	}{
+		{name: "no transform", source: "x = 1"},
+		{name: "too many params", source: "def transform(a, b, c):\n    return a\n"},
+		{name: "top-level error", source: "fail('no')"},
+		{name: "runtime error", source: "def transform(record):\n    return record['missing']\n", compiled: true},
+		{name: "not a record", source: "def transform(record):\n    return 1\n", compiled: true},
+		{name: "list of not records", source: "def transform(record):\n    return [1]\n", compiled: true},
+		{name: "infinite loop", source: "def transform(record):\n    for x in range(1 << 40):\n        pass\n",
+			compiled: true},
+	} {
+		script, err := newRecordScript(&config.Request{Script: &config.Script{Source: tcase.source}})
+		if (err == nil) != tcase.compiled {
+			t.Errorf("%s: expected the script to compile %v, got %v", tcase.name, tcase.compiled, err)
+
+			continue
+		}
+
+		if !tcase.compiled {
+			continue
+		}
+
+		if _, err := script.apply([]byte(`{"id":1}`), uri); err == nil || !strings.Contains(err.Error(), "script") {
+			t.Errorf("%s: expected a script error, got %v", tcase.name, err)
+		}
+	}
+
+	var nilScript *recordScript
+
+	if data, err := nilScript.apply([]byte(`not json`), uri); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+
+	if script, err := newRecordScript(&config.Request{}); script != nil || err != nil {
+		t.Errorf("expected no script, got %v and %v", script, err)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// spill holds the payloads that are queued for the repository workers in memory up to the memory limit of the run, and
+// spills the rest to temporary files, which are read back once a repository worker receives them.
+type spill struct {
+	limit  int64
+	dir    string
+	logger tools.Logger
+
+	mutex sync.Mutex
+	held  int64
+
+	// tmpDir is the directory of the spilled payloads, which is created once the first payload is spilled.
+	tmpDir string
+
+	// spilled is the number of payloads that were spilled, and bytes their size.
+	spilled int64
+	bytes   int64
+}
+
+// newSpill will return the spill of the run, or nil if the payloads of the run are not limited.
+func newSpill(cfg *config.Config) (*spill, error) {
+	limit, err := cfg.MemoryLimitBytes()
+	if err != nil || limit == 0 {
+		return nil, err
+	}
+
+	return &spill{limit: limit, dir: cfg.SpillDir, logger: cfg.Logger}, nil
+}
+
+// hold will count the payload of the job against the memory limit, or spill it to a temporary file if the limit would
+// be exceeded. A payload that cannot be spilled is held in memory.
+func (sp *spill) hold(job *repoJob) {
+	if sp == nil || job == nil || job.err != nil {
+		return
+	}
+
+	size := int64(len(job.b))
+
+	sp.mutex.Lock()
+	if sp.held+size <= sp.limit {
+		sp.held += size
+		job.held = size
+		sp.mutex.Unlock()
+
+		return
+	}
+	sp.mutex.Unlock()
+
+	path, err := sp.write(job.b)
+	if err != nil {
+		msg := fmt.Sprintf("unable to spill payload of %q, holding it in memory: %v", job.table, err)
+		tools.LogFormatter{Msg: msg}.Log(sp.logger, tools.LogLevelWarn)
+
+		sp.mutex.Lock()
+		sp.held += size
+		job.held = size
+		sp.mutex.Unlock()
+
+		return
+	}
+
+	job.spilled = path
+	job.b = nil
+}
+
+// write will write the payload to a temporary file, returning its path.
+func (sp *spill) write(data []byte) (string, error) {
+	sp.mutex.Lock()
+
+	if sp.tmpDir == "" {
+		dir, err := os.MkdirTemp(sp.dir, "gidari-spill-")
+		if err != nil {
+			sp.mutex.Unlock()
+
+			return "", fmt.Errorf("failed to create spill directory: %w", err)
+		}
+
+		sp.tmpDir = dir
+
+		msg := fmt.Sprintf("memory limit of %d bytes reached, spilling queued payloads to %s", sp.limit, dir)
+		tools.LogFormatter{Msg: msg}.Log(sp.logger, tools.LogLevelInfo)
+	}
+
+	dir := sp.tmpDir
+	sp.mutex.Unlock()
+
+	file, err := os.CreateTemp(dir, "payload-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to write spill file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to close spill file: %w", err)
+	}
+
+	sp.mutex.Lock()
+	sp.spilled++
+	sp.bytes += int64(len(data))
+	sp.mutex.Unlock()
+
+	return file.Name(), nil
+}
+
+// release will read the payload of the job back if it was spilled, or stop counting it against the memory limit if it
+// was held, once a repository worker has received it.
+func (sp *spill) release(job *repoJob) error {
+	if sp == nil || job == nil {
+		return nil
+	}
+
+	if job.spilled == "" {
+		sp.drop(job)
+
+		return nil
+	}
+
+	path := job.spilled
+	job.spilled = ""
+
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read spilled payload: %w", err)
+	}
+
+	job.b = data
+
+	return nil
+}
+
+// drop will discard the payload of a job that is not received, e.g. since its request has finished.
+func (sp *spill) drop(job *repoJob) {
+	if sp == nil || job == nil {
+		return
+	}
+
+	if job.spilled != "" {
+		os.Remove(job.spilled)
+		job.spilled = ""
+
+		return
+	}
+
+	sp.mutex.Lock()
+	sp.held -= job.held
+	job.held = 0
+	sp.mutex.Unlock()
+}
+
+// close will remove the payloads that were spilled, and log how many there were.
+func (sp *spill) close() {
+	if sp == nil {
+		return
+	}
+
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.tmpDir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(sp.tmpDir); err != nil {
+		msg := fmt.Sprintf("failed to remove spill directory %s: %v", sp.tmpDir, err)
+		tools.LogFormatter{Msg: msg}.Log(sp.logger, tools.LogLevelWarn)
+	}
+
+	msg := fmt.Sprintf("run summary: %d payloads of %d bytes spilled to disk", sp.spilled, sp.bytes)
+	tools.LogFormatter{Msg: msg}.Log(sp.logger, tools.LogLevelInfo)
+}
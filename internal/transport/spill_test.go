@@ -0,0 +1,168 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestSpill(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+
+	spilled, err := newSpill(&config.Config{MemoryLimit: "8B", SpillDir: dir, Logger: logger})
+	if err != nil {
+		t.Fatalf("failed to create spill: %v", err)
+	}
+
+	held := &repoJob{b: []byte(`[1,2,3]`)}
+	spilled.hold(held)
+
+	if held.spilled != "" || spilled.held != 7 {
+		t.Errorf("expected the first payload to be held in memory, got %q and %d bytes", held.spilled, spilled.held)
+	}
+
+	overflow := &repoJob{b: []byte(`[4,5,6]`)}
+	spilled.hold(overflow)
+
+	if overflow.spilled == "" || overflow.b != nil {
+		t.Fatalf("expected the second payload to be spilled")
+	}
+
+	dropped := &repoJob{b: []byte(`[7]`)}
+	spilled.hold(dropped)
+
+	if err := spilled.release(held); err != nil || spilled.held != 0 {
+		t.Errorf("expected the held payload to be released, got %v and %d bytes", err, spilled.held)
+	}
+
+	if err := spilled.release(overflow); err != nil || string(overflow.b) != `[4,5,6]` {
+		t.Errorf("expected the spilled payload to be read back, got %v and %s", err, overflow.b)
+	}
+
+	spilled.drop(dropped)
+
+	entries, err := os.ReadDir(spilled.tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read spill directory: %v", err)
+	}
+
+	if len(entries) != 0 || spilled.spilled != 2 {
+		t.Errorf("expected 2 payloads to be spilled and removed, got %d spilled and %d left", spilled.spilled,
+			len(entries))
+	}
+
+	spilled.close()
+
+	if _, err := os.Stat(spilled.tmpDir); !os.IsNotExist(err) {
+		t.Errorf("expected the spill directory to be removed, got %v", err)
+	}
+
+	if spilled, err := newSpill(&config.Config{}); spilled != nil || err != nil {
+		t.Errorf("expected no spill without a memory limit, got %v and %v", spilled, err)
+	}
+}
+
+func TestSpillUpsert(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"id":"1"},{"id":"2"},{"id":"3"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	req := &config.Request{Table: "candles"}
+	cfg := &config.Config{
+		Requests:      []*config.Request{req},
+		Logger:        logger,
+		MemoryLimit:   "1B",
+		SpillDir:      t.TempDir(),
+		StreamRecords: 1,
+	}
+
+	spilled, err := newSpill(cfg)
+	if err != nil {
+		t.Fatalf("failed to create spill: %v", err)
+	}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+	txn.spill = spilled
+	txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+	// The parts of the chunk are spilled as they are queued, since none of them fit in memory.
+	go txn.newJob(flatReq).run(ctx, 1)
+
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != 3 {
+		t.Errorf("expected 3 candles, got %v", candles)
+	}
+
+	if spilled.spilled == 0 {
+		t.Errorf("expected payloads to be spilled")
+	}
+
+	entries, err := os.ReadDir(spilled.tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read spill directory: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("expected the spilled payloads to be removed once written, got %d", len(entries))
+	}
+
+	spilled.close()
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+// splitter splits the responses of a request that have more than "size" records into slices of "size" records, which
+// its workers write concurrently. Each worker writes in its own transactions on the destinations of the request,
+// which are begun once the first response is split, and are committed or rolled back with the transactions of the
+// request. The slices are handed to the workers in turn.
+type splitter struct {
+	ctx     context.Context
+	repos   []*destinationRepo
+	size    int
+	workers int
+	logger  tools.Logger
+
+	// writes are the transactions of each worker on each destination, with the totals of their upserts, and next
+	// is the worker that writes the next slice.
+	writes [][]*destinationWrites
+	next   int
+}
+
+// newSplitter will return the splitter of the request on the repositories, or nil if its responses are not split.
+func newSplitter(ctx context.Context, repos []*destinationRepo, size, workers int, logger tools.Logger) *splitter {
+	if size <= 0 {
+		return nil
+	}
+
+	return &splitter{ctx: ctx, repos: repos, size: size, workers: workers, logger: logger}
+}
+
+// slice will return the slices of the records, or a single slice if the records do not need to be split. A nil
+// splitter returns no slices.
+func (split *splitter) slice(data []byte) ([][]byte, error) {
+	if split == nil {
+		return nil, nil
+	}
+
+	return batchRecords(data, split.size)
+}
+
+// nextWrites will return the transactions of the worker that writes the next slice, beginning the transactions of
+// the workers if they have not been begun.
+func (split *splitter) nextWrites() ([]*destinationWrites, error) {
+	if split.writes == nil {
+		if err := split.begin(); err != nil {
+			return nil, err
+		}
+	}
+
+	writes := split.writes[split.next]
+	split.next = (split.next + 1) % len(split.writes)
+
+	return writes, nil
+}
+
+// begin will begin the transactions of each worker on the repositories.
+func (split *splitter) begin() error {
+	for worker := 0; worker < split.workers; worker++ {
+		txRepos, err := beginTx(split.ctx, split.repos, split.logger)
+		if err != nil {
+			rollback(split.txRepos(), split.logger)
+			split.writes = nil
+
+			return fmt.Errorf("failed to begin the transactions of split worker %d: %w", worker+1, err)
+		}
+
+		writes := make([]*destinationWrites, len(txRepos))
+		for idx, repo := range txRepos {
+			writes[idx] = &destinationWrites{repo: repo, totals: &proto.UpsertResponse{}}
+		}
+
+		split.writes = append(split.writes, writes)
+	}
+
+	return nil
+}
+
+// txRepos will return the transactions of the workers, if they have been begun.
+func (split *splitter) txRepos() []*destinationRepo {
+	if split == nil {
+		return nil
+	}
+
+	var txRepos []*destinationRepo
+
+	for _, writes := range split.writes {
+		for _, write := range writes {
+			txRepos = append(txRepos, write.repo)
+		}
+	}
+
+	return txRepos
+}
+
+// addTotals will add the totals of the upserts of the workers on each destination to the writes of the request on
+// the destination, in the order of the repositories.
+func (split *splitter) addTotals(writes []*destinationWrites) {
+	if split == nil {
+		return
+	}
+
+	for _, workerWrites := range split.writes {
+		for idx, write := range workerWrites {
+			addTotals(writes[idx].totals, write.totals)
+		}
+	}
+}
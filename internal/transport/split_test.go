@@ -0,0 +1,119 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSplitWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	errFetch := fmt.Errorf("status 503")
+
+	for _, tcase := range []struct {
+		name      string
+		failed    bool
+		committed int
+	}{
+		{name: "committed", committed: 7},
+		{name: "rolled back", failed: true},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("%s: failed to create repository: %v", tcase.name, err)
+		}
+
+		req := &config.Request{Table: "candles"}
+
+		var upserts int64
+
+		cfg := &config.Config{
+			Requests:     []*config.Request{req},
+			SplitRecords: 2,
+			SplitWorkers: 2,
+			OnUpsert:     func(config.UpsertProgress) { atomic.AddInt64(&upserts, 1) },
+		}
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{{request: req}, {request: req}})[0]
+
+		// The first response is split into 3 slices, and the second is written as is.
+		txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"},{"id":"5"}]`)}
+
+		if tcase.failed {
+			txn.jobs <- &repoJob{err: errFetch}
+		} else {
+			txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"6"},{"id":"7"}]`)}
+		}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+		err = upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger)
+		if tcase.failed != errors.Is(err, errFetch) {
+			t.Fatalf("%s: unexpected error %v", tcase.name, err)
+		}
+
+		if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != tcase.committed {
+			t.Errorf("%s: expected %d candles, got %v", tcase.name, tcase.committed, candles)
+		}
+
+		if tcase.failed {
+			continue
+		}
+
+		if upserts != 4 {
+			t.Errorf("%s: expected 4 upserts, got %d", tcase.name, upserts)
+		}
+
+		// The writes of the split workers are counted with the writes of the request.
+		if written := txn.writes[0].written(); written != 7 {
+			t.Errorf("%s: expected 7 records written, got %d", tcase.name, written)
+		}
+	}
+}
+
+func TestSplitterSlice(t *testing.T) {
+	t.Parallel()
+
+	var split *splitter
+
+	if slices, err := split.slice([]byte(`[1,2,3]`)); slices != nil || err != nil {
+		t.Errorf("expected a nil splitter not to split, got %v and %v", slices, err)
+	}
+
+	if split = newSplitter(context.Background(), nil, 0, 4, nil); split != nil {
+		t.Errorf("expected no splitter without a size")
+	}
+
+	split = newSplitter(context.Background(), nil, 2, 4, nil)
+
+	slices, err := split.slice([]byte(`[1,2,3]`))
+	if err != nil || len(slices) != 2 || string(slices[0]) != `[1,2]` || string(slices[1]) != `[3]` {
+		t.Errorf("expected 2 slices, got %q and %v", slices, err)
+	}
+}
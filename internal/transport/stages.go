@@ -0,0 +1,63 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// Stages of the chunks of a request that are timed, see "config.StageTimings".
+const (
+	stageFetch = iota
+	stageDecode
+	stageTransform
+	stageWrite
+	stageCount
+)
+
+// stageTimings are how long the chunks of a request spent in each stage, which the web and repository workers add to
+// concurrently.
+type stageTimings struct {
+	totals [stageCount]int64
+}
+
+// add will add the time that a chunk spent in the stage.
+func (timings *stageTimings) add(stage int, elapsed time.Duration) {
+	if timings == nil {
+		return
+	}
+
+	atomic.AddInt64(&timings.totals[stage], int64(elapsed))
+}
+
+// since will add the time since the start to the stage, and return the current time to start the next stage at.
+func (timings *stageTimings) since(stage int, start time.Time) time.Time {
+	now := time.Now()
+	timings.add(stage, now.Sub(start))
+
+	return now
+}
+
+// snapshot will return the timings of the stages so far.
+func (timings *stageTimings) snapshot() config.StageTimings {
+	if timings == nil {
+		return config.StageTimings{}
+	}
+
+	load := func(stage int) time.Duration { return time.Duration(atomic.LoadInt64(&timings.totals[stage])) }
+
+	return config.StageTimings{
+		Fetch:     load(stageFetch),
+		Decode:    load(stageDecode),
+		Transform: load(stageTransform),
+		Write:     load(stageWrite),
+	}
+}
@@ -0,0 +1,105 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestStageTimings(t *testing.T) {
+	t.Parallel()
+
+	var nilTimings *stageTimings
+
+	nilTimings.add(stageFetch, time.Second)
+
+	if snapshot := nilTimings.snapshot(); snapshot != (config.StageTimings{}) {
+		t.Errorf("expected no timings for nil timings, got %+v", snapshot)
+	}
+
+	timings := &stageTimings{}
+	timings.add(stageDecode, time.Second)
+	timings.add(stageDecode, time.Second)
+	timings.add(stageWrite, time.Millisecond)
+
+	want := config.StageTimings{Decode: 2 * time.Second, Write: time.Millisecond}
+	if snapshot := timings.snapshot(); snapshot != want {
+		t.Errorf("expected %+v, got %+v", want, snapshot)
+	}
+}
+
+func TestStages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+
+		_, _ = wtr.Write([]byte(`[{"id":"1"},{"id":"2"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	repo, err := repository.New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	var progress config.RequestProgress
+
+	req := &config.Request{Table: "candles"}
+	cfg := &config.Config{
+		Requests: []*config.Request{req},
+		Logger:   logger,
+		Progress: func(rsp config.RequestProgress) { progress = rsp },
+	}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+	txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+	txn.newJob(flatReq).run(ctx, 1)
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	if stages := progress.Stages; stages.Fetch < 10*time.Millisecond || stages.Decode <= 0 ||
+		stages.Transform <= 0 || stages.Write <= 0 {
+		t.Errorf("expected the time of every stage to be reported, got %+v", stages)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+var ErrMissingTable = fmt.Errorf("missing table")
+
+// Streamer writes batches of records that are streamed by a producer other than a web API to the destinations of a
+// configuration. The repositories of the destinations are opened once and shared by every stream.
+type Streamer struct {
+	cfg   *config.Config
+	repos []*destinationRepo
+	close repoCloser
+
+	// chunkLogs logs the batches that are upserted, which are flushed when the streamer is closed.
+	chunkLogs *chunkLogs
+}
+
+// NewStreamer will return a streamer for the destinations of the configuration.
+func NewStreamer(ctx context.Context, cfg *config.Config) (*Streamer, error) {
+	repos, closeRepos, err := repos(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Streamer{cfg: cfg, repos: repos, close: closeRepos, chunkLogs: newChunkLogs(cfg.Logger, cfg.LogSample)}, nil
+}
+
+// Close will close the repositories of the streamer.
+func (streamer *Streamer) Close() {
+	streamer.chunkLogs.flush()
+	streamer.close()
+}
+
+// Storages will return the storage devices of the destinations of the streamer.
+func (streamer *Streamer) Storages() []proto.Storage {
+	stgs := make([]proto.Storage, len(streamer.repos))
+	for idx, repo := range streamer.repos {
+		stgs[idx] = repo.Storage
+	}
+
+	return stgs
+}
+
+// Upsert will write each batch of records received from "recv", until it returns "io.EOF", to every destination that
+// the table of the batch is routed to. The configuration of the table is applied to the records, and they are written
+// in batches of at most the batch size of each destination. The writes of the stream are made in one transaction on
+// each destination, which is committed once the stream ends, or rolled back if any batch cannot be written. The
+// totals of the upserts on every destination are returned.
+func (streamer *Streamer) Upsert(ctx context.Context,
+	recv func() (*proto.UpsertRequest, error),
+) (*proto.UpsertResponse, error) {
+	logger := streamer.cfg.Logger
+
+	txRepos, err := beginTx(ctx, streamer.repos, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transactions of the destinations are written concurrently, so each has its own totals.
+	writes := make([]*destinationWrites, len(txRepos))
+	for idx, repo := range txRepos {
+		writes[idx] = &destinationWrites{repo: repo, totals: &proto.UpsertResponse{}}
+	}
+
+	for {
+		req, err := recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err == nil {
+			err = streamer.write(req, writes)
+		}
+
+		if err != nil {
+			rollback(txRepos, logger)
+
+			return nil, err
+		}
+	}
+
+	if err := commit(txRepos, logger); err != nil {
+		return nil, err
+	}
+
+	totals := &proto.UpsertResponse{}
+	for _, destWrites := range writes {
+		addTotals(totals, destWrites.totals)
+	}
+
+	msg := fmt.Sprintf("stream committed: %d records received, %d inserted, %d updated, %d failed",
+		totals.ReceivedCount, totals.InsertedCount, totals.UpdatedCount, totals.FailedCount)
+	tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelInfo)
+
+	return totals, nil
+}
+
+// write will send the batch of records to the transactions of the destinations that its table is routed to. Records
+// that fail to be transformed are sent to the quarantine table of their table, if it has one.
+func (streamer *Streamer) write(req *proto.UpsertRequest, writes []*destinationWrites) error {
+	if req.GetTable() == "" {
+		return ErrMissingTable
+	}
+
+	table := streamer.cfg.TableFor(req.GetTable())
+
+	var (
+		data        []byte
+		quarantined []*quarantinedRecord
+		quarantine  *proto.UpsertRequest
+		err         error
+	)
+
+	if table.Quarantine != "" {
+		data, quarantined, err = quarantineRecords(req.GetData(), table, streamer.cfg.StorageTable(req.GetTable()), nil)
+		if err == nil && len(quarantined) > 0 {
+			quarantine, err = quarantineRequest(streamer.cfg.StorageTable(table.Quarantine), quarantined)
+		}
+	} else {
+		data, err = transformRecords(req.GetData(), table)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to transform records: %w", err)
+	}
+
+	for _, destWrites := range writes {
+		repo := destWrites.repo
+		if !repo.dest.Routes(req.GetTable()) {
+			continue
+		}
+
+		batches, err := batchRecords(data, repo.batchSize)
+		if err != nil {
+			return fmt.Errorf("error batching data: %w", err)
+		}
+
+		for _, batch := range batches {
+			batchReq := &proto.UpsertRequest{
+				Table:       streamer.cfg.StorageTable(req.GetTable()),
+				Data:        batch,
+				DataType:    req.GetDataType(),
+				PrimaryKeys: req.GetPrimaryKeys(),
+				Conflict:    req.GetConflict(),
+			}
+
+			repo.Transact(upsertFn(0, batchReq, destWrites.totals, nil, streamer.chunkLogs))
+		}
+
+		if quarantine != nil {
+			repo.Transact(recordsFn("quarantine", quarantine, len(quarantined), streamer.cfg.Logger))
+		}
+	}
+
+	return nil
+}
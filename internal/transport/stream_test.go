@@ -0,0 +1,108 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+// streamOf will return a function that receives the requests, followed by "io.EOF".
+func streamOf(reqs ...*proto.UpsertRequest) func() (*proto.UpsertRequest, error) {
+	return func() (*proto.UpsertRequest, error) {
+		if len(reqs) == 0 {
+			return nil, io.EOF
+		}
+
+		req := reqs[0]
+		reqs = reqs[1:]
+
+		return req, nil
+	}
+}
+
+func TestStreamer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	candlesDir, tradesDir := t.TempDir(), t.TempDir()
+
+	cfg := &config.Config{
+		Destinations: []*config.Destination{
+			{ConnectionString: "file://" + candlesDir, Include: []string{"candles"}},
+			{ConnectionString: "file://" + tradesDir, Include: []string{"trades"}},
+		},
+		Tables:      map[string]*config.Table{"candles": {Columns: map[string]string{"px": "price"}}},
+		TablePrefix: "dev_",
+		Logger:      logger,
+	}
+
+	streamer, err := NewStreamer(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create streamer: %v", err)
+	}
+
+	t.Cleanup(streamer.Close)
+
+	totals, err := streamer.Upsert(ctx, streamOf(
+		&proto.UpsertRequest{Table: "candles", Data: []byte(`[{"id":"1","px":1.5},{"id":"2","px":1.6}]`)},
+		&proto.UpsertRequest{Table: "trades", Data: []byte(`{"id":"3"}`)},
+	))
+	if err != nil {
+		t.Fatalf("failed to upsert stream: %v", err)
+	}
+
+	if totals.GetReceivedCount() != 3 {
+		t.Errorf("expected 3 records received, got %d", totals.GetReceivedCount())
+	}
+
+	for _, tcase := range []struct {
+		path     string
+		expected string
+	}{
+		{path: filepath.Join(candlesDir, "dev_candles.ndjson"), expected: "{\"id\":\"1\",\"price\":1.5}\n{\"id\":\"2\",\"price\":1.6}\n"},
+		{path: filepath.Join(tradesDir, "dev_trades.ndjson"), expected: "{\"id\":\"3\"}\n"},
+	} {
+		got, err := os.ReadFile(tcase.path)
+		if err != nil {
+			t.Fatalf("failed to read table: %v", err)
+		}
+
+		if string(got) != tcase.expected {
+			t.Errorf("expected %q, got %q", tcase.expected, got)
+		}
+	}
+
+	_, err = streamer.Upsert(ctx, streamOf(
+		&proto.UpsertRequest{Table: "trades", Data: []byte(`{"id":"4"}`)},
+		&proto.UpsertRequest{Data: []byte(`{"id":"5"}`)},
+	))
+	if !errors.Is(err, ErrMissingTable) {
+		t.Fatalf("expected %v, got %v", ErrMissingTable, err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tradesDir, "dev_trades.ndjson"))
+	if err != nil || string(got) != "{\"id\":\"3\"}\n" {
+		t.Errorf("expected the stream to be rolled back, got %q: %v", got, err)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+)
+
+// destinationWrites are the totals of the upsert responses of a request on a destination.
+type destinationWrites struct {
+	repo   *destinationRepo
+	totals *proto.UpsertResponse
+}
+
+// written will return the number of records that were upserted or matched on the destination.
+func (writes *destinationWrites) written() int64 {
+	return writes.totals.GetUpsertedCount() + writes.totals.GetMatchedCount()
+}
+
+// addTotals will add the counts of the response to the totals.
+func addTotals(totals, rsp *proto.UpsertResponse) {
+	totals.UpsertedCount += rsp.GetUpsertedCount()
+	totals.MatchedCount += rsp.GetMatchedCount()
+	totals.ReceivedCount += rsp.GetReceivedCount()
+	totals.InsertedCount += rsp.GetInsertedCount()
+	totals.UpdatedCount += rsp.GetUpdatedCount()
+	totals.FailedCount += rsp.GetFailedCount()
+}
+
+// logSummary will log the summary of the run: the totals of the upserts on every destination, the errors of the
+// requests, and any discrepancies found when verifying the writes of the requests.
+func logSummary(cfg *config.Config, txns []*requestTxn) {
+	totals := &proto.UpsertResponse{}
+	discrepancies := []string{}
+
+	for _, txn := range txns {
+		for _, writes := range txn.writes {
+			addTotals(totals, writes.totals)
+		}
+
+		discrepancies = append(discrepancies, txn.discrepancies...)
+	}
+
+	msg := fmt.Sprintf("run summary: %d records received, %d inserted, %d updated, %d failed",
+		totals.ReceivedCount, totals.InsertedCount, totals.UpdatedCount, totals.FailedCount)
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelInfo)
+
+	logErrors(cfg, txns)
+	logBackpressure(cfg, txns)
+
+	if cfg.Verify == "" {
+		return
+	}
+
+	if len(discrepancies) == 0 {
+		tools.LogFormatter{Msg: "run summary: all writes verified"}.Log(cfg.Logger, tools.LogLevelInfo)
+
+		return
+	}
+
+	msg = fmt.Sprintf("run summary: %d discrepancies found", len(discrepancies))
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelWarn)
+
+	for _, discrepancy := range discrepancies {
+		tools.LogFormatter{Msg: discrepancy}.Log(cfg.Logger, tools.LogLevelWarn)
+	}
+}
+
+// logErrors will log the number of errors of the run, and the errors of each request by category.
+func logErrors(cfg *config.Config, txns []*requestTxn) {
+	total := 0
+	for _, txn := range txns {
+		total += txn.errors.total()
+	}
+
+	if total == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("run summary: %d errors", total)
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelWarn)
+
+	for _, txn := range txns {
+		if txn.errors.total() == 0 {
+			continue
+		}
+
+		logWarn := tools.LogFormatter{
+			Endpoint: txn.req.Endpoint,
+			Table:    txn.table,
+			Msg:      fmt.Sprintf("errors of %s: %s", txn.req.Endpoint, txn.errors),
+		}
+		logWarn.Log(cfg.Logger, tools.LogLevelWarn)
+	}
+}
+
+// logBackpressure will log how long the web workers waited for the storage workers of each request whose buffer
+// filled up.
+func logBackpressure(cfg *config.Config, txns []*requestTxn) {
+	for _, txn := range txns {
+		waited := txn.backpressure.waited()
+		if waited == 0 {
+			continue
+		}
+
+		logInfo := tools.LogFormatter{
+			Endpoint: txn.req.Endpoint,
+			Table:    txn.table,
+			Msg:      fmt.Sprintf("backpressure of %s: web workers waited %v to buffer chunks", txn.req.Endpoint, waited),
+		}
+		logInfo.Log(cfg.Logger, tools.LogLevelInfo)
+	}
+}
+
+// logTransport will log the requests that the web workers made with their shared transport, and how many of them
+// were made on pooled connections.
+func logTransport(cfg *config.Config, metrics web.TransportMetrics) {
+	msg := fmt.Sprintf("run summary: %d web requests, %d connections opened, %d requests on pooled connections",
+		metrics.Requests, metrics.Opened, metrics.Reused)
+	tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelInfo)
+}
@@ -0,0 +1,35 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"testing"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+func TestAddTotals(t *testing.T) {
+	t.Parallel()
+
+	totals := &proto.UpsertResponse{}
+
+	addTotals(totals, &proto.UpsertResponse{ReceivedCount: 3, InsertedCount: 2, UpdatedCount: 1, UpsertedCount: 2,
+		MatchedCount: 1})
+	addTotals(totals, &proto.UpsertResponse{ReceivedCount: 2, InsertedCount: 1, FailedCount: 1, UpsertedCount: 1})
+
+	if totals.ReceivedCount != 5 || totals.InsertedCount != 3 || totals.UpdatedCount != 1 || totals.FailedCount != 1 {
+		t.Errorf("expected 5 received, 3 inserted, 1 updated, and 1 failed, got %v", totals)
+	}
+
+	writes := &destinationWrites{totals: totals}
+	if written := writes.written(); written != 4 {
+		t.Errorf("expected 4 records written, got %d", written)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"fmt"
+
+	"github.com/alpstable/gidari/config"
+)
+
+var ErrRequiredColumn = fmt.Errorf("missing required column")
+
+// transformRecords will apply the configuration of the table to each record in the data before it is stored.
+func transformRecords(data []byte, table *config.Table) ([]byte, error) {
+	if len(table.Columns) == 0 && len(table.Coerce) == 0 && table.Document == "" && table.Naming == "" &&
+		table.Schema == nil {
+		return data, nil
+	}
+
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		return transformRecord(record, table)
+	})
+}
+
+// transformRecord will apply the configuration of the table to the record.
+func transformRecord(record map[string]interface{}, table *config.Table) (map[string]interface{}, error) {
+	if table.Naming != "" {
+		var err error
+		if record, err = convertNames(record, table.Naming, table.Columns); err != nil {
+			return nil, err
+		}
+	}
+
+	record = renameColumns(record, table.Columns)
+
+	if err := coerceColumns(record, table.Coerce); err != nil {
+		return nil, err
+	}
+
+	for _, column := range table.RequiredColumns() {
+		if record[column] == nil {
+			return nil, fmt.Errorf("%w: %q", ErrRequiredColumn, column)
+		}
+	}
+
+	if table.Document != "" {
+		record = documentRecord(record, table.Document, table.DocumentKeys)
+	}
+
+	return record, nil
+}
+
+// renameColumns will return a copy of the record with each field renamed to the column it is mapped to. Fields that
+// are mapped to "-" are dropped, and fields that are not mapped keep their name unless a mapped field takes it.
+func renameColumns(record map[string]interface{}, columns map[string]string) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(record))
+
+	for field, val := range record {
+		if _, ok := columns[field]; !ok {
+			renamed[field] = val
+		}
+	}
+
+	for field, column := range columns {
+		val, ok := record[field]
+		if !ok || column == config.DropColumn {
+			continue
+		}
+
+		renamed[column] = val
+	}
+
+	return renamed
+}
+
+// documentRecord will return a record that stores the entire record in the document column, along with the keys of
+// the record in their own columns.
+func documentRecord(record map[string]interface{}, document string, keys []string) map[string]interface{} {
+	doc := map[string]interface{}{document: record}
+
+	for _, key := range keys {
+		if val, ok := record[key]; ok {
+			doc[key] = val
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,192 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+)
+
+func TestTransformRecords(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		data     string
+		table    config.Table
+		expected string
+	}{
+		{
+			name:     "no configuration",
+			data:     `[{"price":"1.5"}]`,
+			expected: `[{"price":"1.5"}]`,
+		},
+		{
+			name:     "rename",
+			data:     `[{"px":1,"size":2},{"px":3},4]`,
+			table:    config.Table{Columns: map[string]string{"px": "price"}},
+			expected: `[{"price":1,"size":2},{"price":3},4]`,
+		},
+		{
+			name:     "drop",
+			data:     `{"id":1,"_links":{"self":"/"}}`,
+			table:    config.Table{Columns: map[string]string{"_links": config.DropColumn}},
+			expected: `{"id":1}`,
+		},
+		{
+			name:     "mapped field takes the column",
+			data:     `{"price":1,"mark_price":2}`,
+			table:    config.Table{Columns: map[string]string{"mark_price": "price", "price": "last_price"}},
+			expected: `{"last_price":1,"price":2}`,
+		},
+		{
+			name: "coerce",
+			data: `{"px":"1.50","time":"2022/11/01 12:30","epoch":1667305800123,"settled":"1667305800","note":null}`,
+			table: config.Table{
+				Columns: map[string]string{"px": "price"},
+				Coerce: map[string]*config.Coercion{
+					"price":   {Type: config.CoerceDecimal},
+					"time":    {Type: config.CoerceTimestamp, Layout: "2006/01/02 15:04"},
+					"epoch":   {Type: config.CoerceEpoch, Unit: "ms"},
+					"settled": {Type: config.CoerceEpoch},
+					"note":    {Type: config.CoerceDecimal},
+				},
+			},
+			expected: `{"epoch":"2022-11-01T12:30:00.123Z","note":null,"price":1.50,"settled":"2022-11-01T12:30:00Z",` +
+				`"time":"2022-11-01T12:30:00Z"}`,
+		},
+		{
+			name: "numeric",
+			data: `{"price":0.123456789012345678901,"size":"1234567890.123456789","volume":1e-30,"note":null}`,
+			table: config.Table{
+				Coerce: map[string]*config.Coercion{
+					"price":  {Type: config.CoerceNumeric},
+					"size":   {Type: config.CoerceNumeric},
+					"volume": {Type: config.CoerceNumeric},
+					"note":   {Type: config.CoerceNumeric},
+				},
+			},
+			expected: `{"note":null,"price":"0.123456789012345678901","size":"1234567890.123456789","volume":"1e-30"}`,
+		},
+		{
+			name: "integer, boolean, and string",
+			data: `{"id":"42","count":1e3,"open":"true","settled":0,"flag":false,"code":7,"side":"buy","ok":true}`,
+			table: config.Table{
+				Coerce: map[string]*config.Coercion{
+					"id":      {Type: config.CoerceInteger},
+					"count":   {Type: config.CoerceInteger},
+					"open":    {Type: config.CoerceBoolean},
+					"settled": {Type: config.CoerceBoolean},
+					"flag":    {Type: config.CoerceBoolean},
+					"code":    {Type: config.CoerceString},
+					"side":    {Type: config.CoerceString},
+					"ok":      {Type: config.CoerceString},
+				},
+			},
+			expected: `{"code":"7","count":1000,"flag":false,"id":42,"ok":"true","open":true,"settled":false,` +
+				`"side":"buy"}`,
+		},
+		{
+			name: "schema",
+			data: `{"px":"1.50","trade_id":"7","side":"buy","time":1667305800}`,
+			table: config.Table{
+				Columns: map[string]string{"px": "price"},
+				Schema: &config.Schema{Fields: []*config.SchemaField{
+					{Name: "trade_id", Type: config.SchemaInteger, Key: true},
+					{Name: "price", Type: config.SchemaDecimal, Required: true},
+					{Name: "time", Type: config.SchemaTimestamp, Unit: "s"},
+					{Name: "side", Type: config.SchemaJSON},
+				}},
+			},
+			expected: `{"price":"1.50","side":"buy","time":"2022-11-01T12:30:00Z","trade_id":7}`,
+		},
+		{
+			name:     "exact numbers",
+			data:     `{"price":0.123456789012345678901,"id":12345678901234567890}`,
+			table:    config.Table{Columns: map[string]string{"price": "px"}},
+			expected: `{"id":12345678901234567890,"px":0.123456789012345678901}`,
+		},
+		{
+			name: "document",
+			data: `[{"px":1,"id":"a","tags":["x"]}]`,
+			table: config.Table{
+				Columns:      map[string]string{"px": "price"},
+				Document:     "doc",
+				DocumentKeys: []string{"id", "missing"},
+			},
+			expected: `[{"doc":{"id":"a","price":1,"tags":["x"]},"id":"a"}]`,
+		},
+	} {
+		if tcase.table.Schema != nil {
+			cfg := &config.Config{Tables: map[string]*config.Table{"trades": &tcase.table}}
+			tcase.table = *cfg.TableFor("trades")
+		}
+
+		got, err := transformRecords([]byte(tcase.data), &tcase.table)
+		if err != nil {
+			t.Fatalf("%s: failed to transform records: %v", tcase.name, err)
+		}
+
+		if string(got) != tcase.expected {
+			t.Errorf("%s: expected %s, got %s", tcase.name, tcase.expected, got)
+		}
+	}
+}
+
+func TestCoerceColumns(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		val      interface{}
+		coercion config.Coercion
+	}{
+		{val: "n/a", coercion: config.Coercion{Type: config.CoerceDecimal}},
+		{val: "2022-11-01", coercion: config.Coercion{Type: config.CoerceTimestamp}},
+		{val: 1.5, coercion: config.Coercion{Type: config.CoerceTimestamp}},
+		{val: true, coercion: config.Coercion{Type: config.CoerceEpoch}},
+		{val: "1,000.50", coercion: config.Coercion{Type: config.CoerceNumeric}},
+		{val: true, coercion: config.Coercion{Type: config.CoerceNumeric}},
+		{val: "1.5", coercion: config.Coercion{Type: config.CoerceInteger}},
+		{val: 1e20, coercion: config.Coercion{Type: config.CoerceInteger}},
+		{val: true, coercion: config.Coercion{Type: config.CoerceInteger}},
+		{val: "yes", coercion: config.Coercion{Type: config.CoerceBoolean}},
+		{val: 2.0, coercion: config.Coercion{Type: config.CoerceBoolean}},
+		{val: []interface{}{"a"}, coercion: config.Coercion{Type: config.CoerceString}},
+	} {
+		record := map[string]interface{}{"col": tcase.val}
+
+		err := coerceColumns(record, map[string]*config.Coercion{"col": &tcase.coercion})
+		if !errors.Is(err, ErrUnableToCoerce) {
+			t.Errorf("%v as %q: expected %v, got %v", tcase.val, tcase.coercion.Type, ErrUnableToCoerce, err)
+		}
+	}
+}
+
+func TestRequiredColumns(t *testing.T) {
+	t.Parallel()
+
+	table := &config.Table{Schema: &config.Schema{Fields: []*config.SchemaField{
+		{Name: "trade_id", Type: config.SchemaInteger, Key: true},
+		{Name: "price", Type: config.SchemaDecimal, Required: true},
+		{Name: "side", Type: config.SchemaString},
+	}}}
+
+	if _, err := transformRecords([]byte(`[{"trade_id":1,"price":"1.5"}]`), table); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	for _, data := range []string{`[{"trade_id":1,"side":"buy"}]`, `[{"trade_id":null,"price":"1.5"}]`} {
+		if _, err := transformRecords([]byte(data), table); !errors.Is(err, ErrRequiredColumn) {
+			t.Errorf("%s: expected %v, got %v", data, ErrRequiredColumn, err)
+		}
+	}
+}
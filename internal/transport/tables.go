@@ -0,0 +1,174 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// Inventory is the tables that a configuration manages on each of its destinations, as they are in storage.
+type Inventory struct {
+	Tables []*ManagedTable
+}
+
+// ManagedTable is a table that the requests of a configuration are routed to on a destination.
+type ManagedTable struct {
+	// Destination is the redacted connection string of the storage device of the table.
+	Destination string
+
+	// Table is the name of the table in storage, with the prefix and suffix of the configuration.
+	Table string
+
+	// Exists is "true" if the table is in storage, i.e. a run has written to it.
+	Exists bool
+
+	// Rows and Size are the number of records and bytes in the table, if the storage device reports them.
+	Rows int64
+	Size int64
+
+	// LastFetchedAt is the latest time that a record of the table was fetched, from the metadata of its records. It
+	// is zero if the configuration does not track metadata, or if the storage device cannot be queried.
+	LastFetchedAt time.Time
+}
+
+// NewInventory will connect to each destination of the configuration and list the tables that its requests are routed
+// to there, with their row counts and the time they were last fetched.
+func NewInventory(ctx context.Context, cfg *config.Config) (*Inventory, error) {
+	repos, closeRepos, err := repos(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeRepos()
+
+	inventory := &Inventory{}
+
+	for _, repo := range repos {
+		tables, err := managedTables(ctx, cfg, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		inventory.Tables = append(inventory.Tables, tables...)
+	}
+
+	return inventory, nil
+}
+
+// managedTables will list the tables that the requests of the configuration are routed to on the destination, in the
+// order of the requests.
+func managedTables(ctx context.Context, cfg *config.Config, repo *destinationRepo) ([]*ManagedTable, error) {
+	rsp, err := repo.ListTables(ctx)
+	if err != nil {
+		return nil, classify(ErrStorage, fmt.Errorf("failed to list tables: %w", err))
+	}
+
+	dest := redactConnectionString(repo.dest.ConnectionString)
+
+	var tables []*ManagedTable
+
+	for _, routedTable := range routedTables(cfg, repo.dest) {
+		name := cfg.StorageTable(routedTable)
+		table := &ManagedTable{Destination: dest, Table: name}
+
+		if stored, ok := rsp.GetTableSet()[name]; ok {
+			table.Exists = true
+			table.Rows = stored.GetRowCount()
+			table.Size = stored.GetSize()
+			table.LastFetchedAt = lastFetchedAt(ctx, repo, name)
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// routedTables will return the tables of the requests of the configuration that are routed to the destination, in
+// the order of the requests.
+func routedTables(cfg *config.Config, dest *config.Destination) []string {
+	seen := make(map[string]bool)
+
+	var tables []string
+
+	for _, req := range cfg.Requests {
+		if seen[req.Table] || !dest.Routes(req.Table) {
+			continue
+		}
+
+		seen[req.Table] = true
+
+		tables = append(tables, req.Table)
+	}
+
+	return tables
+}
+
+// lastFetchedAt will return the latest fetch time in the metadata of the records of the table, or zero if it cannot be
+// read.
+func lastFetchedAt(ctx context.Context, repo *destinationRepo, table string) time.Time {
+	rsp, err := repo.Query(ctx, &proto.QueryRequest{
+		Table:      table,
+		Range:      &proto.TimeRange{Column: metadataFetchedAtField},
+		Limit:      1,
+		Descending: true,
+	})
+	if err != nil || len(rsp.Records) == 0 {
+		return time.Time{}
+	}
+
+	fetchedAt, err := time.Parse(time.RFC3339Nano, rsp.Records[0].GetFields()[metadataFetchedAtField].GetStringValue())
+	if err != nil {
+		return time.Time{}
+	}
+
+	return fetchedAt
+}
+
+// Write will write the inventory as text, with a line for each destination followed by a line for each of its tables.
+func (inventory *Inventory) Write(wtr io.Writer) error {
+	var text strings.Builder
+
+	dest := ""
+
+	for _, table := range inventory.Tables {
+		if table.Destination != dest {
+			dest = table.Destination
+			fmt.Fprintf(&text, "%s\n", dest)
+		}
+
+		if !table.Exists {
+			fmt.Fprintf(&text, "  %s: missing\n", table.Table)
+
+			continue
+		}
+
+		lastFetched := "unknown"
+		if !table.LastFetchedAt.IsZero() {
+			lastFetched = table.LastFetchedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(&text, "  %s: %d rows, %d bytes, last fetched %s\n", table.Table, table.Rows, table.Size,
+			lastFetched)
+	}
+
+	fmt.Fprintf(&text, "Tables: %d managed tables.\n", len(inventory.Tables))
+
+	if _, err := io.WriteString(wtr, text.String()); err != nil {
+		return fmt.Errorf("unable to write tables: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewInventory(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "dev_candles.ndjson"), []byte("{\"id\":\"1\"}\n"), 0o600); err != nil {
+		t.Fatalf("failed to write table: %v", err)
+	}
+
+	cfg := &config.Config{
+		Destinations: []*config.Destination{{ConnectionString: "file://" + dir, Exclude: []string{"orders"}}},
+		Requests: []*config.Request{
+			{Endpoint: "/candles", Table: "candles"},
+			{Endpoint: "/candles/daily", Table: "candles"},
+			{Endpoint: "/trades", Table: "trades"},
+			{Endpoint: "/orders", Table: "orders"},
+		},
+		TablePrefix: "dev_",
+		Logger:      logger,
+	}
+
+	inventory, err := NewInventory(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+
+	want := []*ManagedTable{
+		{Destination: "file://" + dir, Table: "dev_candles", Exists: true, Size: 11},
+		{Destination: "file://" + dir, Table: "dev_trades"},
+	}
+
+	if len(inventory.Tables) != len(want) {
+		t.Fatalf("expected %d tables, got %d", len(want), len(inventory.Tables))
+	}
+
+	for idx, table := range inventory.Tables {
+		if *table != *want[idx] {
+			t.Errorf("expected %+v, got %+v", want[idx], table)
+		}
+	}
+
+	inventory.Tables[0].LastFetchedAt = time.Date(2022, 5, 10, 1, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	if err := inventory.Write(&out); err != nil {
+		t.Fatalf("failed to write tables: %v", err)
+	}
+
+	expected := "file://" + dir + "\n" +
+		"  dev_candles: 0 rows, 11 bytes, last fetched 2022-05-10T01:00:00Z\n" +
+		"  dev_trades: missing\n" +
+		"Tables: 2 managed tables.\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer that runs are traced with.
+const instrumentationName = "github.com/alpstable/gidari"
+
+// Names of the spans of a run. Each flattened request is traced from the time it is fetched until its data is
+// written, with a span for each stage.
+const (
+	spanRequest   = "gidari.request"
+	spanFetch     = "gidari.fetch"
+	spanTransform = "gidari.transform"
+	spanUpsert    = "gidari.upsert"
+)
+
+// newTracer will return the tracer of the configuration, using the global tracer provider if the configuration does
+// not set one.
+func newTracer(cfg *config.Config) trace.Tracer {
+	provider := cfg.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return provider.Tracer(instrumentationName)
+}
+
+// endSpan will end the span, recording the error on it if it is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// tracedFn will wrap the transaction function in a span that is a child of the parent span, so that the writes of a
+// flattened request are in the same trace as its fetch. The function is returned as is if the tracer is nil.
+func tracedFn(tracer trace.Tracer, parent trace.SpanContext, name string, table string,
+	txnFn func(context.Context, repository.Generic) error,
+) func(context.Context, repository.Generic) error {
+	if tracer == nil {
+		return txnFn
+	}
+
+	return func(sctx context.Context, repo repository.Generic) error {
+		sctx, span := tracer.Start(trace.ContextWithSpanContext(sctx, parent), name,
+			trace.WithAttributes(
+				attribute.String("gidari.table", table),
+				attribute.String("gidari.storage", proto.SchemeFromStorageType(repo.Type())),
+			))
+
+		err := txnFn(sctx, repo)
+		endSpan(span, err)
+
+		return err
+	}
+}
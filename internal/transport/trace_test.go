@@ -0,0 +1,168 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// recordedSpan is a span that is recorded by a "spanRecorder".
+type recordedSpan struct {
+	trace.Span
+
+	name   string
+	ctx    trace.SpanContext
+	parent trace.SpanContext
+	status codes.Code
+	ended  bool
+}
+
+func (span *recordedSpan) SpanContext() trace.SpanContext          { return span.ctx }
+func (span *recordedSpan) SetStatus(code codes.Code, _ string)     { span.status = code }
+func (span *recordedSpan) End(...trace.SpanEndOption)              { span.ended = true }
+func (span *recordedSpan) IsRecording() bool                       { return !span.ended }
+func (span *recordedSpan) TracerProvider() trace.TracerProvider    { return nil }
+func (span *recordedSpan) RecordError(error, ...trace.EventOption) {}
+
+// spanRecorder is a tracer provider that records the spans that are started, so that they can be inspected without
+// exporting them.
+type spanRecorder struct {
+	mutex sync.Mutex
+	ids   uint64
+	spans []*recordedSpan
+}
+
+func (recorder *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer { return recorder }
+
+func (recorder *spanRecorder) Start(ctx context.Context, name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.ids++
+
+	var spanID trace.SpanID
+
+	binary.BigEndian.PutUint64(spanID[:], recorder.ids)
+
+	parent := trace.SpanContextFromContext(ctx)
+	if startConfig := trace.NewSpanStartConfig(opts...); startConfig.NewRoot() {
+		parent = trace.SpanContext{}
+	}
+
+	traceID := parent.TraceID()
+	if !parent.IsValid() {
+		binary.BigEndian.PutUint64(traceID[:], recorder.ids)
+	}
+
+	span := &recordedSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		name:   name,
+		ctx:    trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}),
+		parent: parent,
+	}
+	recorder.spans = append(recorder.spans, span)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"id":"1"}]`))
+	}))
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	repo, err := repository.New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	recorder := &spanRecorder{}
+	req := &config.Request{Endpoint: "/candles", Table: "candles"}
+	cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger, TracerProvider: recorder}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+
+	jobs := make(chan *webJob, 1)
+	jobs <- newWebJob(cfg, "", flatReq, txn)
+	close(jobs)
+
+	if err := webWorker(ctx, 1, jobs); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+	if err := txn.upsert(ctx, 1, repos, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	spans := make(map[string]*recordedSpan)
+	for _, span := range recorder.spans {
+		spans[span.name] = span
+	}
+
+	root := spans[spanRequest]
+	if root == nil || root.parent.IsValid() {
+		t.Fatalf("expected a root span for the request, got %v", recorder.spans)
+	}
+
+	for _, name := range []string{spanFetch, spanTransform, spanUpsert} {
+		span := spans[name]
+		if span == nil {
+			t.Errorf("expected a %q span", name)
+
+			continue
+		}
+
+		if span.parent.TraceID() != root.ctx.TraceID() || span.parent.SpanID() != root.ctx.SpanID() {
+			t.Errorf("expected %q to be a child of the request span", name)
+		}
+
+		if !span.ended || span.status == codes.Error {
+			t.Errorf("expected %q to end without an error", name)
+		}
+	}
+}
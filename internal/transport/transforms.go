@@ -0,0 +1,176 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/alpstable/gidari/config"
+)
+
+// chunkTransforms are the transforms of a request, with their templates executed for a chunk of the request.
+type chunkTransforms struct {
+	// table is the table that the records of the chunk are stored in, or empty if it is the table of the request.
+	table string
+
+	// fields are the fields that are added to each record of the chunk, and values the values of the fields of the
+	// records that are remapped.
+	fields map[string]string
+	values map[string]map[string]string
+}
+
+// newChunkTransforms will add the injected query parameters and path segments of the request to the fields of the
+// chunk, and then execute the templates of the transforms of the request with the query parameters of the URL of the
+// chunk and the injected path segments. If a parameter has several values, the first of them is used. A request
+// without transforms or injected fields returns nil.
+func newChunkTransforms(req *config.Request, uri *url.URL) (*chunkTransforms, error) {
+	if req.Transforms == nil && req.Inject == nil {
+		return nil, nil
+	}
+
+	params := make(map[string]string)
+
+	for key, values := range uri.Query() {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	chunk := &chunkTransforms{fields: make(map[string]string)}
+
+	if req.Inject != nil {
+		if err := injectFields(chunk.fields, params, req); err != nil {
+			return nil, err
+		}
+	}
+
+	transforms := req.Transforms
+	if transforms == nil {
+		return chunk, nil
+	}
+
+	chunk.values = transforms.Values
+
+	if transforms.Table != "" {
+		table, err := executeTransform("table", transforms.Table, params)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk.table = table
+	}
+
+	for field, text := range transforms.Fields {
+		value, err := executeTransform(field, text, params)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk.fields[field] = value
+	}
+
+	return chunk, nil
+}
+
+// injectFields will add the injected query parameters and path segments of the request to the fields, and the path
+// segments to the parameters of the templates. A query parameter that the chunk does not have, or a segment that the
+// path of the endpoint does not have, is an error.
+func injectFields(fields, params map[string]string, req *config.Request) error {
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+
+	segments := strings.Split(strings.Trim(endpoint.Path, "/"), "/")
+
+	for field, index := range req.Inject.Path {
+		if index < 0 {
+			index += len(segments)
+		}
+
+		if index < 0 || index >= len(segments) || segments[index] == "" {
+			return fmt.Errorf("failed to inject %q: no path segment %d in %q", field, req.Inject.Path[field],
+				endpoint.Path)
+		}
+
+		fields[field] = segments[index]
+		params[field] = segments[index]
+	}
+
+	for _, param := range req.Inject.Query {
+		value, ok := params[param]
+		if !ok {
+			return fmt.Errorf("failed to inject %q: no query parameter", param)
+		}
+
+		fields[param] = value
+	}
+
+	return nil
+}
+
+// executeTransform will execute the named template of a transform with the query parameters of a chunk.
+func executeTransform(name, text string, params map[string]string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse transform %q: %w", name, err)
+	}
+
+	var value strings.Builder
+	if err := tmpl.Execute(&value, params); err != nil {
+		return "", fmt.Errorf("failed to execute transform %q: %w", name, err)
+	}
+
+	return value.String(), nil
+}
+
+// apply will remap the values of the fields of each record in the data, and then add the fields to it. Nil transforms,
+// and transforms that only change the table, return the data as is.
+func (chunk *chunkTransforms) apply(data []byte) ([]byte, error) {
+	if chunk == nil || len(chunk.fields) == 0 && len(chunk.values) == 0 {
+		return data, nil
+	}
+
+	return mapRecords(data, func(record map[string]interface{}) (map[string]interface{}, error) {
+		for field, mapping := range chunk.values {
+			value, ok := transformValue(record[field])
+			if !ok {
+				continue
+			}
+
+			if remapped, ok := mapping[value]; ok {
+				record[field] = remapped
+			}
+		}
+
+		for field, value := range chunk.fields {
+			record[field] = value
+		}
+
+		return record, nil
+	})
+}
+
+// transformValue will return the value of a field as the string it is remapped by, or false if the field is missing
+// or its value is not a string, number, or boolean.
+func transformValue(val interface{}) (string, bool) {
+	switch val := val.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		return fmt.Sprint(val), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,187 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+func TestChunkTransforms(t *testing.T) {
+	t.Parallel()
+
+	uri, err := url.Parse("https://api.example.com/candles?product_id=BTC-USD&granularity=60")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	transforms := &config.Transforms{
+		Table:  "candles_{{ .granularity }}",
+		Fields: map[string]string{"product_id": "{{ .product_id }}"},
+		Values: map[string]map[string]string{"side": {"b": "buy", "s": "sell"}, "open": {"1": "true"}},
+	}
+
+	chunk, err := newChunkTransforms(&config.Request{Transforms: transforms}, uri)
+	if err != nil {
+		t.Fatalf("failed to create transforms: %v", err)
+	}
+
+	if chunk.table != "candles_60" {
+		t.Errorf("expected the table candles_60, got %q", chunk.table)
+	}
+
+	data, err := chunk.apply([]byte(`[{"side":"b","open":1},{"side":"x","product_id":"ETH-USD"},{"side":null},2]`))
+	if err != nil {
+		t.Fatalf("failed to apply transforms: %v", err)
+	}
+
+	expected := `[{"open":"true","product_id":"BTC-USD","side":"buy"},{"product_id":"BTC-USD","side":"x"},` +
+		`{"product_id":"BTC-USD","side":null},2]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// A parameter that the chunk does not have fails the transforms.
+	missing := &config.Transforms{Fields: map[string]string{"market": "{{ .market }}"}}
+	if _, err := newChunkTransforms(&config.Request{Transforms: missing}, uri); err == nil || !strings.Contains(err.Error(), "market") {
+		t.Errorf("expected an error for the missing parameter, got %v", err)
+	}
+
+	if chunk, err := newChunkTransforms(&config.Request{}, uri); chunk != nil || err != nil {
+		t.Errorf("expected no transforms, got %v and %v", chunk, err)
+	}
+
+	var nilChunk *chunkTransforms
+
+	if data, err := nilChunk.apply([]byte(`not json`)); string(data) != `not json` || err != nil {
+		t.Errorf("expected the data as is, got %s and %v", data, err)
+	}
+}
+
+func TestChunkInject(t *testing.T) {
+	t.Parallel()
+
+	uri, err := url.Parse("https://api.example.com/products/BTC-USD/candles?granularity=60")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	req := &config.Request{
+		Endpoint: "/products/BTC-USD/candles",
+		Inject: &config.Inject{
+			Query: []string{"granularity"},
+			Path:  map[string]int{"product_id": 1, "kind": -1},
+		},
+		Transforms: &config.Transforms{Table: "{{ .product_id }}_{{ .kind }}"},
+	}
+
+	chunk, err := newChunkTransforms(req, uri)
+	if err != nil {
+		t.Fatalf("failed to create transforms: %v", err)
+	}
+
+	if chunk.table != "BTC-USD_candles" {
+		t.Errorf("expected the table BTC-USD_candles, got %q", chunk.table)
+	}
+
+	data, err := chunk.apply([]byte(`[{"open":1}]`))
+	if err != nil {
+		t.Fatalf("failed to apply transforms: %v", err)
+	}
+
+	expected := `[{"granularity":"60","kind":"candles","open":1,"product_id":"BTC-USD"}]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// A query parameter that the chunk does not have, or a segment that the path does not have, fails the chunk.
+	for _, inject := range []*config.Inject{{Query: []string{"market"}}, {Path: map[string]int{"x": 3}}} {
+		req := &config.Request{Endpoint: req.Endpoint, Inject: inject}
+		if _, err := newChunkTransforms(req, uri); err == nil {
+			t.Errorf("expected an error for %+v", inject)
+		}
+	}
+}
+
+func TestTransformsTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`[{"id":"1","side":"b"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles?granularity=60")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	req := &config.Request{
+		Table: "candles",
+		Transforms: &config.Transforms{
+			Table:  "candles_{{ .granularity }}",
+			Fields: map[string]string{"granularity": "{{ .granularity }}"},
+			Values: map[string]map[string]string{"side": {"b": "buy"}},
+		},
+	}
+	cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger}
+
+	flatReq := newFlattenedRequest(req, &web.FetchConfig{
+		C:           &web.Client{},
+		Method:      http.MethodGet,
+		URL:         uri,
+		RateLimiter: rate.NewLimiter(rate.Inf, 1),
+	})
+
+	if flatReq.transforms, err = newChunkTransforms(req, uri); err != nil {
+		t.Fatalf("failed to create transforms: %v", err)
+	}
+
+	txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+	txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+	txn.newJob(flatReq).run(ctx, 1)
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+	if err := upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	candles := readLines(t, filepath.Join(dir, "candles_60.ndjson"))
+	if len(candles) != 1 || !strings.Contains(candles[0], `"side":"buy"`) ||
+		!strings.Contains(candles[0], `"granularity":"60"`) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+}
@@ -10,22 +10,28 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
-	"runtime"
 	"strings"
 	"time"
 
 	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/decoder"
 	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/ratelimit"
 	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/selector"
 	"github.com/alpstable/gidari/internal/web"
 	"github.com/alpstable/gidari/internal/web/auth"
 	"github.com/alpstable/gidari/tools"
-	"github.com/sirupsen/logrus"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -34,14 +40,16 @@ var (
 )
 
 // connect will attempt to connect to the web API client. Since there are multiple ways to build a transport given the
-// authentication data, this method will exhaust every transport option in the "Authentication" struct.
-func connect(ctx context.Context, cfg *config.Config) (*web.Client, error) {
+// authentication data, this method will exhaust every transport option in the "Authentication" struct. The requests
+// of the client are made with the shared transport.
+func connect(ctx context.Context, cfg *config.Config, shared *web.Transport) (*web.Client, error) {
 	if apiKey := cfg.Authentication.APIKey; apiKey != nil {
 		client, err := web.NewClient(ctx, auth.NewAPIKey().
 			SetURL(cfg.RawURL).
 			SetKey(apiKey.Key).
 			SetPassphrase(apiKey.Passphrase).
-			SetSecret(apiKey.Secret))
+			SetSecret(apiKey.Secret).
+			SetTransport(shared))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create API key client: %w", err)
 		}
@@ -50,7 +58,7 @@ func connect(ctx context.Context, cfg *config.Config) (*web.Client, error) {
 	}
 
 	if apiKey := cfg.Authentication.Auth2; apiKey != nil {
-		client, err := web.NewClient(ctx, auth.NewAuth2().SetBearer(apiKey.Bearer).SetURL(cfg.RawURL))
+		client, err := web.NewClient(ctx, auth.NewAuth2().SetBearer(apiKey.Bearer).SetURL(cfg.RawURL).SetTransport(shared))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client: %w", err)
 		}
@@ -59,7 +67,7 @@ func connect(ctx context.Context, cfg *config.Config) (*web.Client, error) {
 	}
 
 	// In the case of no authentication, create a client without an auth transport.
-	client, err := web.NewClient(ctx, nil)
+	client, err := web.NewClient(ctx, shared)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
@@ -69,22 +77,118 @@ func connect(ctx context.Context, cfg *config.Config) (*web.Client, error) {
 
 type repoCloser func()
 
-// repos will return a slice of generic repositories along with associated transaction instances.
-func repos(ctx context.Context, cfg *config.Config) ([]repository.Generic, repoCloser, error) {
-	repos := []repository.Generic{}
+// destinationRepo is a repository that only receives the tables routed to its destination, in batches of at most
+// "batchSize" records. A batch size of zero means that each response is written at once. The writes to the
+// destination are made through its gate, which is shared by every transaction on it. The writes to a transaction
+// wait in its queue of "queueSize" writes, so that each destination is written to concurrently with the others.
+type destinationRepo struct {
+	*repository.GenericService
+	dest      *config.Destination
+	batchSize int
+	gate      *writeGate
+	logger    tools.Logger
+	queueSize int
+	queue     *writeQueue
+}
+
+// begin will return a copy of the destination repository with a new transaction on its storage.
+func (repo *destinationRepo) begin(ctx context.Context) (*destinationRepo, error) {
+	var svc *repository.GenericService
+
+	err := repo.gate.do(ctx, func() error {
+		var err error
+
+		svc, err = repo.BeginTx(ctx)
 
-	for _, dns := range cfg.ConnectionStrings {
-		repo, err := repository.NewTx(ctx, dns)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	return &destinationRepo{
+		GenericService: svc,
+		dest:           repo.dest,
+		batchSize:      repo.batchSize,
+		gate:           repo.gate,
+		logger:         repo.logger,
+		queueSize:      repo.queueSize,
+		queue:          newWriteQueue(repo.queueSize),
+	}, nil
+}
+
+// Transact will send the function to the transaction of the repository, to be called through the write gate of the
+// destination. A panic of the function fails the transaction rather than the process. The function is sent through
+// the queue of the transaction, if it has one.
+func (repo *destinationRepo) Transact(fn func(context.Context, repository.Generic) error) {
+	repo.queue.send(func() {
+		repo.GenericService.Transact(func(ctx context.Context, generic repository.Generic) error {
+			activity := fmt.Sprintf("writing to %q", proto.SchemeFromStorageType(generic.Type()))
+
+			return repo.gate.do(ctx, func() error {
+				return recoverPanic(repo.logger, repositoryWorker, activity, func() error { return fn(ctx, generic) })
+			})
+		})
+	})
+}
+
+// Commit will commit the transaction once the writes in its queue have been sent.
+func (repo *destinationRepo) Commit() error {
+	repo.queue.drain()
+
+	return repo.GenericService.Commit()
+}
+
+// Rollback will roll back the transaction once the writes in its queue have been sent.
+func (repo *destinationRepo) Rollback() error {
+	repo.queue.drain()
+
+	return repo.GenericService.Rollback()
+}
+
+// name will return the storage scheme of the repository, which names it in the logs.
+func (repo *destinationRepo) name() string {
+	return proto.SchemeFromStorageType(repo.Type())
+}
+
+// repos will return a slice of generic repositories, one for each destination.
+func repos(ctx context.Context, cfg *config.Config) ([]*destinationRepo, repoCloser, error) {
+	repos := []*destinationRepo{}
+
+	for _, dest := range cfg.DestinationList() {
+		repo, err := repository.New(ctx, dest.ConnectionString)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create repository: %w", err)
+			return nil, nil, classify(ErrStorage, fmt.Errorf("failed to create repository: %w", err))
 		}
 
 		logInfo := tools.LogFormatter{
-			Msg: fmt.Sprintf("created repository for %q", dns),
+			Msg: fmt.Sprintf("created repository for %q", dest.ConnectionString),
+		}
+		logInfo.Log(cfg.Logger, tools.LogLevelInfo)
+
+		if dest.Pool != nil {
+			if err := configurePool(ctx, repo, dest.Pool, cfg.Logger); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if err := defineTables(ctx, cfg, repo, dest); err != nil {
+			return nil, nil, err
 		}
-		cfg.Logger.Info(logInfo.String())
 
-		repos = append(repos, repo)
+		gate, err := newWriteGate(dest, proto.SchemeFromStorageType(repo.Type()), cfg.Logger)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		repos = append(repos, &destinationRepo{
+			GenericService: repo,
+			dest:           dest,
+			batchSize:      cfg.BatchSizeFor(dest),
+			gate:           gate,
+			logger:         cfg.Logger,
+			queueSize:      cfg.JobBufferSize(),
+		})
 	}
 
 	return repos, func() {
@@ -94,11 +198,41 @@ func repos(ctx context.Context, cfg *config.Config) ([]repository.Generic, repoC
 			logInfo := tools.LogFormatter{
 				Msg: fmt.Sprintf("closed repository for %q", proto.SchemeFromStorageType(repo.Type())),
 			}
-			cfg.Logger.Info(logInfo.String())
+			logInfo.Log(cfg.Logger, tools.LogLevelInfo)
 		}
 	}, nil
 }
 
+// configurePool will configure the connection pool of the repository. Storage devices without a connection pool
+// that can be configured keep their defaults.
+func configurePool(ctx context.Context, repo repository.Generic, pool *config.Pool, logger tools.Logger) error {
+	lifetime, err := pool.Lifetime()
+	if err != nil {
+		return err
+	}
+
+	req := &proto.PoolRequest{
+		MaxOpenConns:    pool.MaxOpenConns,
+		MaxIdleConns:    pool.MaxIdleConns,
+		ConnMaxLifetime: lifetime,
+	}
+
+	err = repo.ConfigurePool(ctx, req)
+	if errors.Is(err, proto.ErrPoolNotSupported) {
+		msg := fmt.Sprintf("pool configuration is not supported on %q, skipping",
+			proto.SchemeFromStorageType(repo.Type()))
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to configure pool: %w", err)
+	}
+
+	return nil
+}
+
 // newFetchConfig will construct a new HTTP request from the transport request.
 func newFetchConfig(req *config.Request, rurl url.URL, client *web.Client) *web.FetchConfig {
 	rurl.Path = path.Join(rurl.Path, req.Endpoint)
@@ -124,21 +258,75 @@ func newFetchConfig(req *config.Request, rurl url.URL, client *web.Client) *web.
 // flattenedRequest contains all of the request information to create a web job. The number of flattened request  for an
 // operation should be 1-1 with the number of requests to the web API.
 type flattenedRequest struct {
+	request     *config.Request
 	fetchConfig *web.FetchConfig
 	table       string
 	clobColumn  string
-}
+	primaryKeys []string
+	conflict    string
+	appendMode  bool
+	hashKey     *config.HashKey
+	softDelete  string
 
-// flatten will compress the request information into a "web.FetchConfig" request and a "table" name for storage
-// interaction.
-func flattenRequest(req *config.Request, rurl url.URL, client *web.Client) *flattenedRequest {
-	fetchConfig := newFetchConfig(req, rurl, client)
+	// transforms are the transforms of the request for the chunk, if the request has any.
+	transforms *chunkTransforms
+
+	// selector selects the records of the response of the chunk from its envelope, if the request has one.
+	selector *selector.Selector
+
+	// decoder decodes the response of the chunk into records, if it is not JSON.
+	decoder decoder.Decoder
+
+	// script transforms each record of the chunk, and command pipes its records through an external command, if the
+	// request has them.
+	script  *recordScript
+	command *recordCommand
+
+	// filter are the predicates that the records of the chunk must match to be stored, and fields the fields of the
+	// records that are kept or dropped, if the request has them.
+	filter *recordFilter
+	fields *fieldFilter
+
+	// mask masks the fields of the records of the chunk, if the request has masked fields.
+	mask *recordMask
+}
 
-	return &flattenedRequest{
+// newFlattenedRequest will construct a flattened request for the fetch config of the request.
+func newFlattenedRequest(req *config.Request, fetchConfig *web.FetchConfig) *flattenedRequest {
+	flatReq := &flattenedRequest{
+		request:     req,
 		fetchConfig: fetchConfig,
 		table:       req.Table,
 		clobColumn:  req.ClobColumn,
+		primaryKeys: req.PrimaryKey,
+		conflict:    req.Conflict,
+		softDelete:  req.SoftDelete,
+	}
+
+	// Appended records are identified by their surrogate key, and are always inserted.
+	if req.WriteMode == config.WriteModeAppend {
+		flatReq.appendMode = true
+		flatReq.primaryKeys = []string{appendIDField}
+		flatReq.conflict = proto.ConflictFail
+	}
+
+	// Records with a hash key are identified by their hash, and duplicates are ignored by default.
+	if req.HashKey != nil {
+		flatReq.hashKey = req.HashKey
+		flatReq.primaryKeys = []string{req.HashKey.ColumnName()}
+
+		if flatReq.conflict == "" {
+			flatReq.conflict = proto.ConflictInsertOnly
+		}
 	}
+
+	return flatReq
+}
+
+// flatten will compress the request information into a "web.FetchConfig" request and a "table" name for storage
+// interaction.
+func flattenRequest(req *config.Request, rurl url.URL, client *web.Client) *flattenedRequest {
+	return newFlattenedRequest(req, newFetchConfig(req, rurl, client))
 }
 
 // chunkTimeseries will attempt to use the query string of a URL to partition the timeseries into "Chunks" of time for
@@ -221,19 +409,18 @@ func flattenRequestTimeseries(req *config.Request, rurl url.URL, client *web.Cli
 
 		fetchConfig := newFetchConfig(chunkReq, rurl, client)
 
-		requests = append(requests, &flattenedRequest{
-			fetchConfig: fetchConfig,
-			table:       req.Table,
-			clobColumn:  req.ClobColumn,
-		})
+		requests = append(requests, newFlattenedRequest(req, fetchConfig))
 	}
 
 	return requests, nil
 }
 
-// flattenConfigRequests will flatten the requests into a single slice for HTTP requests.
-func flattenConfigRequests(ctx context.Context, cfg *config.Config) ([]*flattenedRequest, error) {
-	client, err := connect(ctx, cfg)
+// flattenConfigRequests will flatten the requests into a single slice for HTTP requests, which are made with the
+// shared transport.
+func flattenConfigRequests(ctx context.Context, cfg *config.Config,
+	shared *web.Transport,
+) ([]*flattenedRequest, error) {
+	client, err := connect(ctx, cfg, shared)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to web API: %w", err)
 	}
@@ -246,6 +433,54 @@ func flattenConfigRequests(ctx context.Context, cfg *config.Config) ([]*flattene
 			return nil, err
 		}
 
+		sel, err := selector.Parse(req.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		dec, err := newDecoder(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %q: %w", req.Endpoint, err)
+		}
+
+		script, err := newRecordScript(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile script of %q: %w", req.Endpoint, err)
+		}
+
+		command, err := newRecordCommand(req, cfg.AllowCommands)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create command of %q: %w", req.Endpoint, err)
+		}
+
+		filter, err := newRecordFilter(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter %q: %w", req.Endpoint, err)
+		}
+
+		fields := newFieldFilter(req)
+		mask := newRecordMask(req)
+
+		if cfg.DumpsRequest(req) {
+			for _, flatReq := range flatReqs {
+				flatReq.fetchConfig.DumpDir = cfg.DumpDir
+			}
+		}
+
+		for _, flatReq := range flatReqs {
+			flatReq.selector = sel
+			flatReq.decoder = dec
+			flatReq.script = script
+			flatReq.command = command
+			flatReq.filter = filter
+			flatReq.fields = fields
+			flatReq.mask = mask
+
+			if flatReq.transforms, err = newChunkTransforms(req, flatReq.fetchConfig.URL); err != nil {
+				return nil, fmt.Errorf("failed to transform %q: %w", req.Endpoint, err)
+			}
+		}
+
 		flattenedRequests = append(flattenedRequests, flatReqs...)
 	}
 
@@ -256,282 +491,896 @@ func flattenConfigRequests(ctx context.Context, cfg *config.Config) ([]*flattene
 	return flattenedRequests, nil
 }
 
-type repoJob struct {
-	req   http.Request
-	b     []byte
-	table string
-}
-
-type repoConfig struct {
-	repos      []repository.Generic
-	closeRepos func()
-	jobs       chan *repoJob
-	done       chan bool
-	logger     *logrus.Logger
-}
+// sharedLimiter will return the rate limit that the configuration shares with other processes through Redis, keyed
+// by the host of its web API, or nil if its rate limit is not shared.
+func sharedLimiter(cfg *config.Config) (*ratelimit.Redis, error) {
+	rateLimit := cfg.RateLimitConfig
+	if rateLimit == nil || rateLimit.Redis == "" {
+		return nil, nil
+	}
 
-func newRepoConfig(ctx context.Context, cfg *config.Config, volume int) (*repoConfig, error) {
-	repos, closeRepos, err := repos(ctx, cfg)
+	limiter, err := ratelimit.NewRedis(rateLimit.Redis, ratelimit.KeyPrefix+cfg.URL.Host, *rateLimit.Burst,
+		*rateLimit.Period)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create shared rate limiter: %w", err)
 	}
 
-	return &repoConfig{
-		repos:      repos,
-		closeRepos: closeRepos,
-		jobs:       make(chan *repoJob, volume*len(repos)),
-		done:       make(chan bool, volume),
-		logger:     cfg.Logger,
-	}, nil
+	return limiter, nil
 }
 
-func repositoryWorker(_ context.Context, workerID int, cfg *repoConfig) {
-	for job := range cfg.jobs {
-		if job == nil {
-			cfg.done <- false
-
-			continue
-		}
-
-		reqs := []*proto.UpsertRequest{
-			{
-				Table: job.table,
-				Data:  job.b,
-			},
-		}
-
-		for _, req := range reqs {
-			for _, repo := range cfg.repos {
-				txfn := func(sctx context.Context, repo repository.Generic) error {
-					start := time.Now()
-
-					rsp, err := repo.Upsert(sctx, req)
-					if err != nil {
-						cfg.logger.Fatalf("error upserting data: %v", err)
-
-						return fmt.Errorf("error upserting data: %w", err)
-					}
+type repoJob struct {
+	req         http.Request
+	b           []byte
+	table       string
+	primaryKeys []string
+	conflict    string
 
-					rt := repo.Type()
+	// span is the span of the flattened request that the data was fetched for, which its writes are traced under.
+	span trace.SpanContext
 
-					msg := fmt.Sprintf("partial upsert completed: %s.%s", proto.SchemeFromStorageType(rt), req.Table)
-					logInfo := tools.LogFormatter{
-						WorkerID:      workerID,
-						WorkerName:    "repository",
-						Duration:      time.Since(start),
-						Msg:           msg,
-						UpsertedCount: rsp.UpsertedCount,
-						MatchedCount:  rsp.MatchedCount,
-					}
+	// err is the error fetching the data, which fails the request.
+	err error
 
-					cfg.logger.Infof(logInfo.String())
+	// quarantine is the upsert of the records of the data that failed to be transformed to the quarantine table of
+	// the request, or nil if none did, and quarantined is the number of those records.
+	quarantine  *proto.UpsertRequest
+	quarantined int
 
-					return nil
-				}
-				// Put the data onto the transaction channel for storage.
-				repo.Transact(txfn)
-			}
-		}
+	// more is whether more parts of the chunk follow, if the records of the chunk are streamed in parts.
+	more bool
 
-		cfg.done <- true
-	}
+	// held is the size of the data that is counted against the memory limit of the run while the job is queued,
+	// and spilled is the path of the file that the data was spilled to instead, see "spill".
+	held    int64
+	spilled string
 }
 
 type webJob struct {
 	*flattenedRequest
-	repoJobs chan<- *repoJob
-	logger   *logrus.Logger
+	tableConfig *config.Table
+	logger      tools.Logger
+	tracer      trace.Tracer
+
+	// repoJobs receives the data of the chunk for the transaction of its request, unless the transaction has been
+	// discarded. If the buffer of the transaction is full, the web worker waits for it, which backpressure measures.
+	repoJobs     chan<- *repoJob
+	discarded    <-chan struct{}
+	backpressure *backpressure
+
+	// spill holds the data of the chunk in memory while it is queued, or spills it to disk, if the run has a memory
+	// limit.
+	spill *spill
+
+	// queue saves the response of the chunk until its request is committed, or replays the response that a previous
+	// run saved, if the run has a durable queue.
+	queue *durableQueue
+
+	// chunkLogs logs that the chunk has been fetched.
+	chunkLogs *chunkLogs
+
+	// errors counts the errors fetching and transforming the chunk.
+	errors *requestErrors
+
+	// stages times the fetch, decode, and transform of the chunk.
+	stages *stageTimings
+
+	// deadLetters receives the body of the chunk if it cannot be decoded, once poison has counted it as poison.
+	deadLetters *deadLetters
+	poison      *poisonPayloads
+
+	// watchdog is told when a web worker starts and finishes fetching the chunk, if the run has a watchdog. worker is
+	// the name of the web worker that fetches the chunk.
+	watchdog *watchdog
+	worker   string
+
+	// fetchers limits how many web workers fetch at once, if the run adapts its workers, and is told how long the
+	// chunk took to be fetched. idle is how long the web worker waited for the rate limiter and the transaction of
+	// the request, which is not part of its latency.
+	fetchers *adaptive
+	idle     time.Duration
+
+	// sent is whether the data of the chunk, or its error, has been sent to the transaction of its request, and
+	// requeue whether the chunk is fetched once more if fetching it panics. parts is the number of parts of the
+	// chunk that have been sent before its last part, if its records are streamed in parts of partSize records.
+	sent     bool
+	requeue  bool
+	parts    int
+	partSize int
+
+	// fetched is called once the request has been fetched, or has failed to be fetched, with how long it waited
+	// for the rate limiter, how long it took to be fetched after that, and the size of its body.
+	fetched func(time.Duration, time.Duration, int)
+
+	// storageTable is the name of the request's table in storage, and quarantineTable of the table that its records
+	// which fail to be transformed are written to, if the table has one.
+	storageTable    string
+	quarantineTable string
+
+	// runID is the ID of the run to add to the metadata of the records, or empty if metadata is disabled.
+	runID string
 }
 
-func newWebJob(cfg *config.Config, req *flattenedRequest, repoJobs chan<- *repoJob) *webJob {
-	return &webJob{
+func newWebJob(cfg *config.Config, runID string, req *flattenedRequest, txn *requestTxn) *webJob {
+	job := &webJob{
 		flattenedRequest: req,
-		repoJobs:         repoJobs,
+		tableConfig:      cfg.TableFor(req.table),
+		storageTable:     cfg.StorageTable(req.table),
+		repoJobs:         txn.jobs,
+		discarded:        txn.discarded,
+		backpressure:     txn.backpressure,
+		spill:            txn.spill,
+		queue:            txn.queue,
 		logger:           cfg.Logger,
+		tracer:           txn.tracer,
+		chunkLogs:        txn.chunkLogs,
+		errors:           txn.errors,
+		stages:           txn.stages,
+		deadLetters:      txn.deadLetters,
+		poison:           txn.poison,
+		watchdog:         txn.watchdog,
+		fetchers:         txn.fetchers,
+		requeue:          cfg.RequeuePanics,
+		partSize:         cfg.StreamRecords,
+		fetched:          txn.reportFetch,
+	}
+
+	// The records of a chunk whose table is transformed are still transformed with the table of the request.
+	if req.transforms != nil && req.transforms.table != "" {
+		job.storageTable = cfg.StorageTable(req.transforms.table)
+	}
+
+	if job.tableConfig.Quarantine != "" {
+		job.quarantineTable = cfg.StorageTable(job.tableConfig.Quarantine)
+	}
+
+	if cfg.Metadata {
+		job.runID = runID
 	}
+
+	return job
 }
 
-func webWorker(ctx context.Context, workerID int, jobs <-chan *webJob) {
+// webWorker will fetch the data of each job and send it to the transaction of its request. Each job is traced in its
+// own trace, which its writes are added to.
+//
+// The errors of the jobs are sent to the transactions of their requests, so that a request that fails does not stop
+// the others. Once the context is cancelled, the jobs that are left fail without being fetched, so that every
+// transaction receives the data of each of its jobs, and the error of the context is returned.
+//
+// If the run adapts its workers, the web worker waits until it may fetch before each job.
+func webWorker(ctx context.Context, workerID int, jobs <-chan *webJob) error {
 	for job := range jobs {
+		job.fetchers.acquire(ctx)
+
+		job.worker = webWorkerName(workerID)
+		job.watchdog.busy(job.worker, "fetching "+job.fetchConfig.URL.Redacted())
+
 		start := time.Now()
+		err := job.run(ctx, workerID)
 
-		rsp, err := web.Fetch(ctx, job.fetchConfig)
-		if err != nil {
-			job.logger.Fatal(err)
-		}
+		job.watchdog.idle(job.worker)
+		job.fetchers.release()
+		job.fetchers.observe(time.Since(start)-job.idle, err)
+	}
 
-		bytes, err := io.ReadAll(rsp.Body)
-		if err != nil {
-			job.logger.Fatal(err)
-		}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("web worker %d stopped: %w", workerID, err)
+	}
 
-		if !json.Valid(bytes) {
-			if job.flattenedRequest.clobColumn == "" {
-				job.repoJobs <- nil
-				msg := fmt.Sprintf("response body for %s was invalid JSON, "+
-					"discarding data since no 'clobColumn' was defined in the configuration file",
-					job.fetchConfig.URL)
-				logInfo := tools.LogFormatter{Msg: msg}
-				job.logger.Warnf(logInfo.String())
+	return nil
+}
 
-				continue
-			}
+// run will fetch the data of the job in its own trace, which its writes are added to, and return the error fetching
+// it.
+func (job *webJob) run(ctx context.Context, workerID int) error {
+	reqCtx, span := job.tracer.Start(ctx, spanRequest, trace.WithNewRoot(), trace.WithAttributes(
+		attribute.String("gidari.endpoint", job.request.Endpoint),
+		attribute.String("gidari.table", job.storageTable),
+		attribute.String("http.url", job.fetchConfig.URL.Redacted()),
+	))
 
-			data := make(map[string]string)
-			data[job.flattenedRequest.clobColumn] = string(bytes)
+	err := job.fetchRecovered(reqCtx, workerID)
+	endSpan(span, err)
 
-			bytes, err = json.Marshal(data)
-			if err != nil {
-				job.repoJobs <- nil
-				job.logger.Errorf("failed to marhsal data: %s", err)
-			}
-		}
+	return err
+}
 
-		job.repoJobs <- &repoJob{b: bytes, req: *rsp.Request, table: job.table}
+// fetchRecovered will fetch the data of the job, recovering a panic of the web worker. If the chunk has not been sent
+// to the transaction of its request when the worker panics, it is fetched once more if the job is requeued and none of
+// its parts have been sent, and otherwise fails with the panic.
+func (job *webJob) fetchRecovered(ctx context.Context, workerID int) error {
+	activity := "fetching " + job.fetchConfig.URL.Redacted()
+	fetch := func() error { return job.fetch(ctx, workerID) }
 
-		// strings.Replace is used to ensure no line endings are present in the user input.
-		escapedPath := strings.ReplaceAll(rsp.Request.URL.Path, "\n", "")
-		escapedPath = strings.ReplaceAll(escapedPath, "\r", "")
+	err := recoverPanic(job.logger, webWorkerName(workerID), activity, fetch)
+	if !errors.Is(err, ErrPanic) || job.sent {
+		return err
+	}
 
-		escapedHost := strings.ReplaceAll(rsp.Request.URL.Host, "\n", "")
-		escapedHost = strings.ReplaceAll(escapedHost, "\r", "")
+	// The parts of the chunk that have been sent would be sent again.
+	if job.requeue && job.parts == 0 {
+		msg := fmt.Sprintf("requeuing %s once after a panic", job.fetchConfig.URL.Redacted())
+		tools.LogFormatter{Msg: msg}.Log(job.logger, tools.LogLevelWarn)
 
-		logInfo := tools.LogFormatter{
-			WorkerID:   workerID,
-			WorkerName: "web",
-			Duration:   time.Since(start),
-			Host:       escapedHost,
-			Msg:        fmt.Sprintf("web request completed: %s", escapedPath),
+		err = recoverPanic(job.logger, webWorkerName(workerID), activity, fetch)
+		if !errors.Is(err, ErrPanic) || job.sent {
+			return err
 		}
-		job.logger.Infof(logInfo.String())
 	}
+
+	job.errors.addChunk(errorPanic, job.fetchConfig.URL.Redacted(), err)
+	job.send(&repoJob{err: err})
+
+	return err
 }
 
-func truncate(ctx context.Context, cfg *config.Config, truncateRequest *proto.TruncateRequest) error {
+// send will send the data of the chunk, or its error, to the transaction of its request. If the buffer of the
+// transaction is full, the web worker waits for the repository worker to receive data, and does not stall while it
+// waits. The data is dropped if the transaction is discarded.
+func (job *webJob) send(data *repoJob) {
+	if data != nil && data.more {
+		job.parts++
+	} else {
+		job.sent = true
+	}
+
+	job.spill.hold(data)
+
+	select {
+	case job.repoJobs <- data:
+		return
+	case <-job.discarded:
+		job.spill.drop(data)
+
+		return
+	default:
+	}
+
+	job.backpressure.engage()
+	job.watchdog.idle(job.worker)
+
 	start := time.Now()
 
-	repos, closeRepos, err := repos(ctx, cfg)
+	select {
+	case job.repoJobs <- data:
+	case <-job.discarded:
+		job.spill.drop(data)
+	}
+
+	waited := time.Since(start)
+	job.backpressure.add(waited)
+	job.idle += waited
+	job.watchdog.busy(job.worker, "fetching "+job.fetchConfig.URL.Redacted())
+}
+
+// fetch will fetch and transform the data of the job, and send it to the repository workers. Data that cannot be
+// transformed is discarded.
+func (job *webJob) fetch(ctx context.Context, workerID int) error {
+	start := time.Now()
+
+	fetchCtx, fetchSpan := job.tracer.Start(ctx, spanFetch)
+
+	rsp, err := job.queue.fetch(fetchCtx, job.flattenedRequest)
 	if err != nil {
+		endSpan(fetchSpan, err)
+		job.fetched(0, 0, 0)
+		job.errors.addChunk(errorCategory(classify(ErrFetch, err)), job.fetchConfig.URL.Redacted(), err)
+		job.send(&repoJob{err: err})
+
 		return err
 	}
 
-	defer closeRepos()
+	defer rsp.Body.Close()
 
-	for _, repo := range repos {
-		start := time.Now()
+	// The records of a response that is a list or NDJSON are streamed, if they are streamed in parts and are not
+	// selected from the response.
+	reader := io.Reader(rsp.Body)
 
-		_, err := repo.Truncate(ctx, truncateRequest)
-		if err != nil {
-			return fmt.Errorf("unable to truncate tables: %w", err)
-		}
+	dec := job.responseDecoder(rsp)
+	_, ndjson := dec.(*decoder.NDJSON)
 
-		rt := repo.Type()
-		tables := strings.Join(truncateRequest.Tables, ", ")
-		msg := fmt.Sprintf("truncated tables on %q: %v", proto.SchemeFromStorageType(rt), tables)
+	if job.partSize > 0 && job.selector == nil && (dec == nil || ndjson) {
+		buffered := getReader(rsp.Body)
+		defer putReader(buffered)
 
-		logInfo := tools.LogFormatter{
-			Duration: time.Since(start),
-			Msg:      msg,
+		if ndjson || isList(buffered) {
+			return job.fetchParts(ctx, workerID, rsp, buffered, ndjson, start, fetchSpan)
 		}
-		cfg.Logger.Infof(logInfo.String())
+
+		reader = buffered
 	}
 
-	logInfo := tools.LogFormatter{
-		Duration: time.Since(start),
-		Msg:      "truncate completed",
+	// The body is read into a buffer from the pool, which is returned once the data of the chunk has been sent.
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	_, err = buf.ReadFrom(reader)
+	bytes := buf.Bytes()
+	fetchSpan.SetAttributes(attribute.Int("gidari.bytes", len(bytes)))
+	endSpan(fetchSpan, err)
+	job.fetched(rsp.RateLimitWait, time.Since(start)-rsp.RateLimitWait, len(bytes))
+	job.idle += rsp.RateLimitWait
+
+	stageStart := job.stages.since(stageFetch, start.Add(rsp.RateLimitWait))
+
+	if err != nil {
+		err = fmt.Errorf("failed to read response body: %w", err)
+		job.errors.addChunk(errorFetch, job.fetchConfig.URL.Redacted(), err)
+		job.send(&repoJob{err: err})
+
+		return err
+	}
+
+	body := bytes
+
+	// A response that is not JSON is decoded into records before they are selected.
+	bytes, err = job.decodeFormat(bytes, dec)
+	if err != nil {
+		job.decodeFailed(body, rsp, err)
+
+		return err
+	}
+
+	// The records are selected from the envelope of the response before they are checked against their table.
+	bytes, err = job.selectRecords(bytes)
+	if err != nil {
+		job.decodeFailed(body, rsp, err)
+
+		return err
+	}
+
+	// A chunk whose records do not have the fields of their table is not skipped like a payload that cannot be
+	// decoded, since the web API has changed.
+	if err := job.checkFields(bytes); err != nil {
+		tools.LogFormatter{Msg: err.Error()}.Log(job.logger, tools.LogLevelError)
+		job.errors.addChunk(errorSchema, job.fetchConfig.URL.Redacted(), err)
+		job.send(&repoJob{err: err})
+
+		return err
+	}
+
+	_, transformSpan := job.tracer.Start(ctx, spanTransform)
+
+	bytes, err = job.decode(bytes)
+	stageStart = job.stages.since(stageDecode, stageStart)
+
+	var quarantined []*quarantinedRecord
+	if err == nil {
+		bytes, quarantined, err = job.transform(bytes, start, rsp)
+		job.stages.since(stageTransform, stageStart)
+	}
+
+	endSpan(transformSpan, err)
+
+	if err != nil {
+		job.decodeFailed(body, rsp, err)
+
+		return err
+	}
+
+	// Data that was not transformed is copied out of the buffer, which is reused.
+	if aliases(bytes, body) {
+		bytes = append([]byte(nil), bytes...)
 	}
-	cfg.Logger.Info(logInfo.String())
+
+	dataJob, err := job.newRepoJob(ctx, bytes, quarantined, rsp)
+	if err != nil {
+		job.decodeFailed(body, rsp, err)
+
+		return err
+	}
+
+	job.send(dataJob)
+	job.logCompleted(workerID, start, rsp)
 
 	return nil
 }
 
-// Truncate will truncate the defined tables in the configuration.
-func Truncate(ctx context.Context, cfg *config.Config) error {
-	// truncateRequest is a special request that will truncate the table before upserting data.
-	truncateRequest := new(proto.TruncateRequest)
+// newRepoJob will return the data of the chunk, with the upsert of the records that were quarantined to the quarantine
+// table of the request, if any.
+func (job *webJob) newRepoJob(ctx context.Context, bytes []byte, quarantined []*quarantinedRecord,
+	rsp *web.FetchResponse,
+) (*repoJob, error) {
+	dataJob := &repoJob{
+		b:           bytes,
+		req:         *rsp.Request,
+		table:       job.storageTable,
+		primaryKeys: job.primaryKeys,
+		conflict:    job.conflict,
+		span:        trace.SpanContextFromContext(ctx),
+	}
 
-	if cfg.Truncate {
-		for _, req := range cfg.Requests {
-			// Add the table to the list of tables to truncate.
-			if req.Truncate != nil && *req.Truncate {
-				truncateRequest.Tables = append(truncateRequest.Tables, req.Table)
-			}
-		}
+	if len(quarantined) == 0 {
+		return dataJob, nil
+	}
+
+	msg := fmt.Sprintf("%d records of %q quarantined to %q: %s", len(quarantined), job.storageTable,
+		job.quarantineTable, quarantined[0].Error)
+	tools.LogFormatter{Msg: msg}.Log(job.logger, tools.LogLevelWarn)
+
+	quarantine, err := quarantineRequest(job.quarantineTable, quarantined)
+	if err != nil {
+		return nil, err
+	}
+
+	dataJob.quarantine = quarantine
+	dataJob.quarantined = len(quarantined)
+
+	return dataJob, nil
+}
+
+// logCompleted will log that the chunk has been fetched and sent to the transaction of its request.
+func (job *webJob) logCompleted(workerID int, start time.Time, rsp *web.FetchResponse) {
+	// strings.Replace is used to ensure no line endings are present in the user input.
+	escapedPath := strings.ReplaceAll(rsp.Request.URL.Path, "\n", "")
+	escapedPath = strings.ReplaceAll(escapedPath, "\r", "")
+
+	escapedHost := strings.ReplaceAll(rsp.Request.URL.Host, "\n", "")
+	escapedHost = strings.ReplaceAll(escapedHost, "\r", "")
+
+	escapedChunk := strings.ReplaceAll(rsp.Request.URL.Redacted(), "\n", "")
+	escapedChunk = strings.ReplaceAll(escapedChunk, "\r", "")
+
+	logInfo := tools.LogFormatter{
+		WorkerID:   workerID,
+		WorkerName: "web",
+		Duration:   time.Since(start),
+		Host:       escapedHost,
+		Endpoint:   job.request.Endpoint,
+		Table:      job.storageTable,
+		Chunk:      escapedChunk,
+		Msg:        fmt.Sprintf("web request completed: %s", escapedPath),
+	}
+	job.chunkLogs.log("web requests completed", "chunks", logInfo)
+}
+
+// decodeFailed will skip the body of the response that failed to be decoded or transformed, and write it to the dead
+// letter, if it is poison. Otherwise the chunk fails, so that it is fetched again if the request is retried.
+func (job *webJob) decodeFailed(body []byte, rsp *web.FetchResponse, err error) {
+	if !job.poison.fail(body) {
+		tools.LogFormatter{Msg: err.Error()}.Log(job.logger, tools.LogLevelError)
+		job.errors.addChunk(errorDecode, job.fetchConfig.URL.Redacted(), err)
+		job.send(&repoJob{err: err})
+
+		return
+	}
+
+	msg := fmt.Sprintf("skipping poison payload: %v", err)
+	tools.LogFormatter{Msg: msg}.Log(job.logger, tools.LogLevelWarn)
+
+	job.errors.add(errorDecode, err)
+	job.deadLetters.add(job.request, job.storageTable, rsp.Request.URL, deadLetterDecode, body, err)
+	job.send(nil)
+}
+
+// selectRecords will return the records that the selector of the request selects from the body of the response. The
+// body is returned as is if the request has no selector, or if the body is not JSON, which "decode" stores in the CLOB
+// column of the request or fails.
+func (job *webJob) selectRecords(bytes []byte) ([]byte, error) {
+	selected, err := job.selector.Select(bytes)
+	if errors.Is(err, selector.ErrNotJSON) {
+		return bytes, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to select records of %s: %w", job.fetchConfig.URL.Redacted(), err)
+	}
+
+	return selected, nil
+}
+
+// decode will check that the body of the response is valid JSON, or store it in the CLOB column of the request if it
+// has one.
+func (job *webJob) decode(bytes []byte) ([]byte, error) {
+	if json.Valid(bytes) {
+		return bytes, nil
+	}
+
+	if job.flattenedRequest.clobColumn == "" {
+		return nil, fmt.Errorf("response body for %s was invalid JSON, and no 'clobColumn' was defined "+
+			"in the configuration file", job.fetchConfig.URL.Redacted())
+	}
+
+	data := make(map[string]string)
+	data[job.flattenedRequest.clobColumn] = string(bytes)
+
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marhsal data: %w", err)
+	}
+
+	return bytes, nil
+}
+
+// transform will transform the records of the decoded body of the response for storage. If the table has a
+// quarantine, the records that fail to be transformed are returned to be quarantined rather than failing the
+// response.
+func (job *webJob) transform(bytes []byte, start time.Time,
+	rsp *web.FetchResponse,
+) ([]byte, []*quarantinedRecord, error) {
+	// The records are filtered, and then their fields are kept or dropped, first, so that the filter can match fields
+	// that are dropped, and the command, the transforms, and the script never see the records and fields that are
+	// dropped. They are applied to the records as they were received, before the table.
+	bytes, err := job.filter.apply(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to filter records: %w", err)
+	}
+
+	bytes, err = job.fields.apply(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to filter fields: %w", err)
+	}
+
+	bytes, err = job.command.apply(rsp.Request.Context(), bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply command: %w", err)
+	}
+
+	bytes, err = job.transforms.apply(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply transforms: %w", err)
+	}
+
+	bytes, err = job.script.apply(bytes, rsp.Request.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply script: %w", err)
+	}
+
+	// The fields are masked last, so that the fields that the transforms and the script add are masked too.
+	bytes, err = job.mask.apply(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to mask fields: %w", err)
+	}
+
+	var quarantined []*quarantinedRecord
+
+	if job.quarantineTable != "" {
+		bytes, quarantined, err = quarantineRecords(bytes, job.tableConfig, job.storageTable, rsp.Request.URL)
 	} else {
-		// checking for request-specific truncate
-		for _, req := range cfg.Requests {
-			if table := req.Table; req.Truncate != nil && *req.Truncate && table != "" {
-				truncateRequest.Tables = append(truncateRequest.Tables, table)
-			}
+		bytes, err = transformRecords(bytes, job.tableConfig)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to transform records: %w", err)
+	}
+
+	// The hash key is added before the metadata, which differs each time a record is fetched.
+	if job.hashKey != nil {
+		bytes, err = addHashKeys(bytes, job.hashKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add hash keys: %w", err)
 		}
 	}
 
-	return truncate(ctx, cfg, truncateRequest)
+	if job.runID != "" {
+		bytes, err = addMetadata(bytes, start, rsp.Request.URL, job.runID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to add metadata: %w", err)
+		}
+	}
+
+	if job.softDelete != "" {
+		bytes, err = clearDeleted(bytes, job.softDelete)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to clear deleted flag: %w", err)
+		}
+	}
+
+	if job.appendMode {
+		bytes, err = appendFields(bytes, start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to append fields: %w", err)
+		}
+	}
+
+	return bytes, quarantined, nil
 }
 
-// Upsert will use the configuration file to upsert data from the
+// Upsert will use the configuration file to upsert data from the web API into the destinations.
 //
-// For each DNS entry in the configuration file, a repository will be created and used to upsert data. For each
-// repository, a transaction will be created and used to upsert data. The transaction will be committed at the end
-// of the upsert operation. If the transaction fails, the transaction will be rolled back. Note that it is possible
-// for some repository transactions to succeed and others to fail.
+// For each request in the configuration file, a transaction is started on every destination that the request's table
+// is routed to. If the request truncates its table, the truncation is the first operation of the transaction, so that
+// the table is only emptied if all of the request's data is reloaded. A table that several requests truncate is only
+// truncated by the first of them, which is written once the requests of the table before it have finished, and before
+// the requests of the table after it. The transactions of a request are committed once all of its data has been
+// written, and rolled back if any write fails. Note that it is possible for some requests to succeed and others to
+// fail, and for some destination transactions of a request to be committed before another fails to commit.
+//
+// If the configuration truncates its tables first, they are instead truncated before anything is fetched, in a
+// transaction on every destination that is committed before any data is written.
+//
+// The chunks of the requests are fetched concurrently by the web workers of the configuration, and the requests are
+// written concurrently by its storage workers, which take the requests in the order of the configuration.
+//
+// If the transaction scope of the configuration is "run", a single transaction is started on every destination for
+// all of the requests, and the transactions are only committed once every request has been written.
+//
+// After the requests are written, the records that are older than the retention of their table are deleted, and if
+// the run is audited, a record of each request is written to the "gidari_runs" table of each destination.
+//
+// If the context is cancelled, the chunks that have not been fetched fail, and the writes in flight are given the drain
+// timeout of the configuration to finish, so that the requests that have been fetched are committed and the rest are
+// rolled back. The run then fails with the error of the context. A run that takes longer than the max duration of the
+// configuration is wound down the same way, and fails with "ErrMaxDuration".
+//
+// If the configuration has a watchdog, the web and repository workers that are busy but make no progress for its stall
+// period are logged. If the watchdog fails runs, the run is then wound down like a cancelled run, and fails with
+// "ErrStalled".
+//
+// If the configuration adapts its workers, how many of the web and storage workers are busy at once is raised while
+// their latency and error rate are healthy, and halved once either degrades.
+//
+// If the configuration has a checkpoint, the flattened requests of each request are saved to it once the request has
+// been committed, and a run that resumes from it skips them.
+//
+// Each invocation is a run with a unique ID, which is added to every message that is logged, and to the metadata and
+// the audit records of the run, so that the data can be correlated with the run that produced it.
 func Upsert(ctx context.Context, cfg *config.Config) error {
 	start := time.Now()
-	threads := runtime.NumCPU()
+	runID := uuid.New().String()
+
+	// The logger of the run is set on a copy of the configuration, so that the configuration can be run again.
+	runCfg := *cfg
+	runCfg.Logger = tools.WithFields(cfg.Logger, map[string]interface{}{"runID": runID})
+	cfg = &runCfg
+
+	drainTimeout, err := cfg.DrainTimeoutDuration()
+	if err != nil {
+		return err
+	}
+
+	maxDuration, err := cfg.MaxRunDuration()
+	if err != nil {
+		return err
+	}
+
+	budget, err := newRetryBudget(cfg)
+	if err != nil {
+		return err
+	}
+
+	dog, err := newWatchdog(cfg)
+	if err != nil {
+		return err
+	}
+
+	// The payloads that are queued beyond the memory limit of the run are spilled to disk until they are written.
+	spilled, err := newSpill(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer spilled.close()
+
+	queue, err := openDurableQueue(cfg)
+	if err != nil {
+		return err
+	}
+
+	// A run that takes longer than its max duration is wound down as if it had been cancelled.
+	parentCtx := ctx
+
+	if maxDuration > 0 {
+		var cancel context.CancelFunc
 
-	if err := Truncate(ctx, cfg); err != nil {
+		ctx, cancel = context.WithTimeout(ctx, maxDuration)
+		defer cancel()
+	}
+
+	// A run whose watchdog fails runs is wound down the same way once a worker stalls.
+	ctx, stopWatch := dog.watch(ctx)
+	defer stopWatch()
+
+	// The web workers share the connections of a single transport.
+	shared := web.NewTransport(cfg.MaxConns())
+	defer shared.CloseIdleConnections()
+
+	flattenedRequests, err := flattenConfigRequests(ctx, cfg, shared)
+	if err != nil {
 		return err
 	}
 
-	flattenedRequests, err := flattenConfigRequests(ctx, cfg)
+	// The requests of a rate limit that is shared through Redis also wait for the other processes sharing it.
+	limiter, err := sharedLimiter(cfg)
 	if err != nil {
 		return err
 	}
 
-	repoConfig, err := newRepoConfig(ctx, cfg, len(flattenedRequests))
+	defer limiter.Close()
+
+	if limiter != nil {
+		for _, flatReq := range flattenedRequests {
+			flatReq.fetchConfig.SharedLimiter = limiter
+		}
+	}
+
+	repos, closeRepos, err := repos(ctx, cfg)
 	if err != nil {
 		return err
 	}
 
-	defer repoConfig.closeRepos()
+	defer closeRepos()
 
-	// Start the repository workers.
-	for id := 1; id <= threads; id++ {
-		go repositoryWorker(ctx, id, repoConfig)
+	checkpoint, err := loadCheckpoint(cfg, runID)
+	if err != nil {
+		return err
 	}
 
-	cfg.Logger.Info(tools.LogFormatter{Msg: "repository workers started"}.String())
+	// The requests are written one at a time in the run transaction scope, so only the web workers are adapted.
+	fetchers, err := newAdaptive(cfg, "web workers", cfg.WebWorkers())
+	if err != nil {
+		return err
+	}
+
+	var writers *adaptive
+
+	if cfg.Transaction != config.TransactionRun {
+		if writers, err = newAdaptive(cfg, "storage workers", cfg.StorageWorkers()); err != nil {
+			return err
+		}
+	}
+
+	stopFetchers := fetchers.start()
+	defer stopFetchers()
+
+	stopWriters := writers.start()
+	defer stopWriters()
+
+	flattenedRequests, committed := checkpoint.resume(cfg, flattenedRequests)
+
+	// The requests that were committed by a previous run are skipped.
+	txns := make([]*requestTxn, 0, len(cfg.Requests))
+
+	for _, txn := range newRequestTxns(cfg, flattenedRequests) {
+		if !committed[txn.req] {
+			txn.checkpoint = checkpoint
+			txn.watchdog = dog
+			txn.spill = spilled
+			txn.queue = queue
+			txn.fetchers = fetchers
+			txn.writers = writers
+			txns = append(txns, txn)
+
+			continue
+		}
+
+		// The requests of the table after a skipped request are not kept waiting for it.
+		close(txn.done)
+	}
+
+	// The jobs of every request are created before they are fetched, so that a request can create its jobs again
+	// to be retried while the jobs of the other requests are fetched.
+	jobs := make([]*webJob, 0, len(flattenedRequests))
+
+	for _, txn := range txns {
+		txn := txn
+		txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, runID, req, txn) }
+
+		for _, req := range txn.flattenedRequests {
+			jobs = append(jobs, txn.newJob(req))
+		}
+	}
+
+	if cfg.OnFetch != nil {
+		for _, txn := range txns {
+			cfg.OnFetch(config.FetchProgress{Endpoint: txn.req.Endpoint, Table: txn.table,
+				Chunks: len(txn.flattenedRequests)})
+		}
+	}
+
+	tools.LogFormatter{Msg: "run started"}.Log(cfg.Logger, tools.LogLevelInfo)
+
+	if cfg.TruncateFirst && cfg.Transaction != config.TransactionRun {
+		if err := truncateTables(ctx, txns, repos, cfg.Retry, budget, cfg.Logger); err != nil {
+			return err
+		}
+	}
 
 	webWorkerJobs := make(chan *webJob, len(cfg.Requests))
 
-	// Start the same number of web workers as the cores on the machine.
-	for id := 1; id <= threads; id++ {
-		go webWorker(ctx, id, webWorkerJobs)
+	// The workers are stopped once the requests have been written, so that none of them outlive the run.
+	fetchCtx, stopFetch := context.WithCancel(ctx)
+	defer stopFetch()
+
+	workers, fetchCtx := errgroup.WithContext(fetchCtx)
+
+	for id := 1; id <= cfg.WebWorkers(); id++ {
+		workerID := id
+
+		workers.Go(func() error { return webWorker(fetchCtx, workerID, webWorkerJobs) })
 	}
 
-	cfg.Logger.Info(tools.LogFormatter{Msg: "web workers started"}.String())
+	tools.LogFormatter{Msg: "web workers started"}.Log(cfg.Logger, tools.LogLevelDebug)
+
+	// Enqueue the worker jobs, the data of each request is written as soon as it has been fetched.
+	workers.Go(func() error {
+		defer close(webWorkerJobs)
+
+		for _, job := range jobs {
+			webWorkerJobs <- job
+		}
+
+		tools.LogFormatter{Msg: "web worker jobs enqueued"}.Log(cfg.Logger, tools.LogLevelDebug)
+
+		return nil
+	})
 
-	// Enqueue the worker jobs
-	for _, req := range flattenedRequests {
-		webWorkerJobs <- newWebJob(cfg, req, repoConfig.jobs)
+	// The writes are drained instead of interrupted if the run is cancelled, while the chunks that have not been
+	// fetched fail, so that the requests that have been fetched are committed and the rest are rolled back.
+	writeCtx, stopDrain := drainContext(ctx, drainTimeout, cfg.Logger)
+	defer stopDrain()
+
+	if cfg.Transaction == config.TransactionRun {
+		err = upsertRun(writeCtx, txns, repos, cfg.Retry, budget, cfg.Logger)
+	} else {
+		err = upsertRequests(writeCtx, txns, repos, cfg.StorageWorkers(), cfg.Retry, budget, cfg.Logger)
+	}
+
+	// The chunks that have not been fetched once the requests have been written, e.g. of a run that was rolled back,
+	// are not fetched.
+	stopFetch()
+
+	// The workers only fail if the run was cancelled, which the run fails with below.
+	if workersErr := workers.Wait(); workersErr != nil {
+		tools.LogFormatter{Msg: workersErr.Error()}.Log(cfg.Logger, tools.LogLevelDebug)
+	}
+
+	// Retention is enforced even if some requests failed, so that tables do not grow unbounded while an endpoint
+	// is unavailable, but not once the run has been cancelled.
+	if ctx.Err() == nil {
+		if retentionErr := enforceRetention(ctx, cfg, repos, time.Now()); err == nil {
+			err = retentionErr
+		}
 	}
 
-	cfg.Logger.Info(tools.LogFormatter{Msg: "web worker jobs enqueued"}.String())
+	// The audit records of failed requests are written too, so that every execution has a record.
+	if cfg.Audit {
+		if auditErr := writeAudit(writeCtx, cfg, repos, runID, start, txns); err == nil {
+			err = auditErr
+		}
+	}
 
-	// Wait for all of the data to flush.
-	for a := 1; a <= len(flattenedRequests); a++ {
-		<-repoConfig.done
+	// Like the audit records, the failed payloads are written even if the run failed.
+	if cfg.DeadLetter != nil {
+		if deadLetterErr := writeDeadLetters(writeCtx, cfg, repos, runID, txns); err == nil {
+			err = deadLetterErr
+		}
 	}
 
-	// Commit the transactions and check for errors.
-	for _, repo := range repoConfig.repos {
-		if err := repo.Commit(); err != nil {
-			return fmt.Errorf("unable to commit transaction: %w", err)
+	logSummary(cfg, txns)
+	logTransport(cfg, shared.Metrics())
+	queue.logSummary()
+
+	// The requests that were rolled back by the cancellation are in the summary, and the run fails with the error
+	// of its context.
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		msg := "run cancelled"
+		if err != nil {
+			msg = fmt.Sprintf("run cancelled: %v", err)
+		}
+
+		tools.LogFormatter{Msg: msg}.Log(cfg.Logger, tools.LogLevelError)
+
+		if stalledErr := dog.err(); stalledErr != nil {
+			return stalledErr
+		}
+
+		if parentCtx.Err() == nil {
+			return classify(ErrMaxDuration, fmt.Errorf("run exceeded its max duration of %v: %w", maxDuration, ctxErr))
 		}
+
+		return ctxErr
+	}
+
+	if err != nil {
+		return err
 	}
 
+	// A run that succeeded has nothing to resume.
+	checkpoint.remove()
+
 	logInfo := tools.LogFormatter{Duration: time.Since(start), Msg: "upsert completed"}
-	cfg.Logger.Info(logInfo.String())
+	logInfo.Log(cfg.Logger, tools.LogLevelInfo)
 
 	return nil
 }
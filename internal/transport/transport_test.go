@@ -11,14 +11,23 @@ package transport
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"path"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/internal/selector"
 	"github.com/alpstable/gidari/internal/web"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 )
 
@@ -310,7 +319,7 @@ func Test_flattenConfigRequests(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotReqs, err := flattenConfigRequests(tt.args.ctx, tt.args.cfg)
+			gotReqs, err := flattenConfigRequests(tt.args.ctx, tt.args.cfg, web.NewTransport(0))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("flattenConfigRequests() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -341,3 +350,139 @@ func compareRequests(request1, request2 *flattenedRequest) bool {
 	}
 	return true
 }
+
+func TestConfigurePool(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	repo, err := repository.New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	// Storage without a connection pool keeps its defaults.
+	if err := configurePool(ctx, repo, &config.Pool{MaxOpenConns: 10}, logger); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if err := configurePool(ctx, repo, &config.Pool{ConnMaxLifetime: "forever"}, logger); err == nil {
+		t.Errorf("expected an error for an invalid lifetime")
+	}
+}
+
+func TestWebWorkerCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	uri, err := url.Parse("https://api.example.com/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	req := &config.Request{Endpoint: "/candles", Table: "candles"}
+	cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger}
+
+	var flatReqs []*flattenedRequest
+
+	for chunk := 0; chunk < 2; chunk++ {
+		flatReqs = append(flatReqs, newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+		}))
+	}
+
+	txn := newRequestTxns(cfg, flatReqs)[0]
+
+	jobs := make(chan *webJob, len(flatReqs))
+	for _, flatReq := range flatReqs {
+		jobs <- newWebJob(cfg, "", flatReq, txn)
+	}
+
+	close(jobs)
+
+	if err := webWorker(ctx, 1, jobs); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the worker to stop with the context, got %v", err)
+	}
+
+	// Every job is still received by the transaction, so that the request fails rather than waiting for it.
+	for idx := range flatReqs {
+		if job := txn.receive(idx); job == nil || job.err == nil {
+			t.Errorf("expected job %d to fail, got %+v", idx, job)
+		}
+	}
+}
+
+func TestSelectRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(http.HandlerFunc(func(wtr http.ResponseWriter, _ *http.Request) {
+		_, _ = wtr.Write([]byte(`{"data":{"items":[{"id":"1"},{"id":"2"}]},"next":null}`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse(server.URL + "/candles")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	for _, tcase := range []struct {
+		selector string
+		expected int
+		wantErr  bool
+	}{
+		{selector: "$.data.items", expected: 2},
+		{selector: "", expected: 1},
+		{selector: "$.records", wantErr: true},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		req := &config.Request{Table: "candles", Selector: tcase.selector}
+		cfg := &config.Config{Requests: []*config.Request{req}, Logger: logger}
+
+		flatReq := newFlattenedRequest(req, &web.FetchConfig{
+			C:           &web.Client{},
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+
+		if flatReq.selector, err = selector.Parse(req.Selector); err != nil {
+			t.Fatalf("%q: failed to parse selector: %v", tcase.selector, err)
+		}
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{flatReq})[0]
+		txn.newJob = func(req *flattenedRequest) *webJob { return newWebJob(cfg, "", req, txn) }
+
+		if err := txn.newJob(flatReq).run(ctx, 1); (err != nil) != tcase.wantErr {
+			t.Errorf("%q: unexpected error %v", tcase.selector, err)
+		}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+		_ = upsertRequests(ctx, []*requestTxn{txn}, repos, 1, nil, nil, logger)
+
+		if candles := readLines(t, filepath.Join(dir, "candles.ndjson")); len(candles) != tcase.expected {
+			t.Errorf("%q: expected %d candles, got %v", tcase.selector, tcase.expected, candles)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+)
+
+// deleteRequest will return the request to delete the records of the table that match the "truncateWhere" of the
+// request. If the time range of the request's timeseries is empty, nil is returned since there is nothing to delete.
+func deleteRequest(req *config.Request, table string) (*proto.DeleteRequest, error) {
+	where := req.TruncateWhere
+	delReq := &proto.DeleteRequest{Table: table, Match: make(map[string]interface{}, len(where.Match))}
+
+	if where.TimeColumn != "" {
+		chunks := req.Timeseries.Chunks
+		if len(chunks) == 0 {
+			return nil, nil
+		}
+
+		delReq.Range = &proto.TimeRange{
+			Column: where.TimeColumn,
+			Start:  chunks[0][0],
+			End:    chunks[len(chunks)-1][1],
+		}
+	}
+
+	for column, text := range where.Match {
+		tmpl, err := template.New(column).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse match for %q: %w", column, err)
+		}
+
+		var value strings.Builder
+		if err := tmpl.Execute(&value, req.Query); err != nil {
+			return nil, fmt.Errorf("failed to execute match for %q: %w", column, err)
+		}
+
+		delReq.Match[column] = value.String()
+	}
+
+	return delReq, nil
+}
+
+// truncate will return the transaction function that truncates the request's table, or only the part of it that
+// matches the "truncateWhere" of the request. If there is nothing to truncate, nil is returned.
+func (txn *requestTxn) truncate(logger tools.Logger) (func(context.Context, repository.Generic) error, error) {
+	if txn.req.TruncateWhere == nil {
+		return truncateFn(txn.table, logger), nil
+	}
+
+	req, err := deleteRequest(txn.req, txn.table)
+	if err != nil || req == nil {
+		return nil, err
+	}
+
+	return deleteFn(req, logger), nil
+}
+
+// deleteFn will return a transaction function that deletes the records of the table that match the request. Storage
+// devices that cannot delete part of a table are skipped with a warning, rather than emptying the entire table.
+func deleteFn(req *proto.DeleteRequest, logger tools.Logger) func(context.Context, repository.Generic) error {
+	return func(sctx context.Context, repo repository.Generic) error {
+		start := time.Now()
+		scheme := proto.SchemeFromStorageType(repo.Type())
+
+		rsp, err := repo.Delete(sctx, req)
+		if errors.Is(err, proto.ErrDeleteNotSupported) {
+			msg := fmt.Sprintf("deletes are not supported on %q, skipping %s", scheme, req.Table)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to delete records: %w", err)
+		}
+
+		logInfo := tools.LogFormatter{
+			Duration: time.Since(start),
+			Table:    req.Table,
+			Msg:      fmt.Sprintf("delete completed: %s.%s, %d records deleted", scheme, req.Table, rsp.DeletedCount),
+		}
+		logInfo.Log(logger, tools.LogLevelInfo)
+
+		return nil
+	}
+}
+
+// orderTruncates will have the first request that empties each table truncate it in its transactions, which is
+// written once the requests of the table before it have finished, and before the requests of the table after it. A
+// table that several requests truncate is only truncated by the first of them, since the later truncates would delete
+// the records of the requests before them. The requests that only delete the records that match their
+// "truncateWhere" each delete them in their transactions.
+func orderTruncates(txns []*requestTxn) {
+	truncatedBy := make(map[string]*requestTxn)
+	before := make(map[string][]*requestTxn)
+
+	for _, txn := range txns {
+		partial := txn.truncates() && txn.req.TruncateWhere != nil
+		txn.sendTruncate = partial
+
+		if first, ok := truncatedBy[txn.table]; ok {
+			txn.after = []*requestTxn{first}
+
+			continue
+		}
+
+		if txn.truncates() && !partial {
+			txn.sendTruncate = true
+			txn.after = before[txn.table]
+			truncatedBy[txn.table] = txn
+
+			continue
+		}
+
+		before[txn.table] = append(before[txn.table], txn)
+	}
+}
+
+// waitAfter will wait until the requests that the request is written after have been committed or rolled back, or
+// until the context is done.
+func (txn *requestTxn) waitAfter(ctx context.Context) {
+	for _, prev := range txn.after {
+		select {
+		case <-prev.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendTruncates will send the truncates of the requests to the transactions of the repositories that their tables are
+// routed to, in the order of the requests. As in "orderTruncates", a table that several requests truncate is only
+// truncated by the first of them, and each request that only deletes the records that match its "truncateWhere" deletes
+// them.
+func sendTruncates(txns []*requestTxn, txRepos []*destinationRepo, logger tools.Logger) error {
+	truncated := make(map[string]bool)
+
+	for _, txn := range txns {
+		partial := txn.req.TruncateWhere != nil
+		if !txn.truncates() || (!partial && truncated[txn.table]) {
+			continue
+		}
+
+		truncate, err := txn.truncate(logger)
+		if err != nil {
+			return err
+		}
+
+		if truncate == nil {
+			continue
+		}
+
+		if !partial {
+			truncated[txn.table] = true
+		}
+
+		for _, repo := range routed(txRepos, txn.req.Table) {
+			repo.Transact(truncate)
+		}
+	}
+
+	return nil
+}
+
+// truncateTables will truncate the tables of the requests before any of their data is written, in a transaction on
+// each destination that a truncated table is routed to, if the configuration truncates its tables first. Since the
+// truncates are committed before the requests are written, a request that fails leaves its table truncated. The
+// truncates are retried if they failed with a transient error.
+func truncateTables(ctx context.Context, txns []*requestTxn, repos []*destinationRepo, policy *config.Retry,
+	budget *retryBudget, logger tools.Logger,
+) error {
+	truncatedRepos := []*destinationRepo{}
+
+	for _, repo := range repos {
+		for _, txn := range txns {
+			if txn.truncates() && repo.dest.Routes(txn.req.Table) {
+				truncatedRepos = append(truncatedRepos, repo)
+
+				break
+			}
+		}
+	}
+
+	if len(truncatedRepos) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+
+	err := retryTxn(ctx, policy, budget, "truncate", logger, func() error {
+		txRepos, err := beginTx(ctx, truncatedRepos, logger)
+		if err != nil {
+			return err
+		}
+
+		send := func() error { return sendTruncates(txns, txRepos, logger) }
+		if err := recoverPanic(logger, repositoryWorker, "truncating tables", send); err != nil {
+			rollback(txRepos, logger)
+
+			return err
+		}
+
+		return commit(txRepos, logger)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to truncate tables: %w", err)
+	}
+
+	tools.LogFormatter{Duration: time.Since(start), Msg: "truncates completed"}.Log(logger, tools.LogLevelInfo)
+
+	return nil
+}
@@ -0,0 +1,188 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDeleteRequest(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	for _, tcase := range []struct {
+		name     string
+		req      *config.Request
+		expected *proto.DeleteRequest
+		wantErr  bool
+	}{
+		{
+			name: "time range",
+			req: &config.Request{
+				Query: map[string]string{"product_id": "BTC-USD"},
+				Timeseries: &config.Timeseries{Chunks: [][2]time.Time{
+					{start, start.Add(time.Hour)},
+					{start.Add(time.Hour), start.Add(90 * time.Minute)},
+				}},
+				TruncateWhere: &config.TruncateWhere{
+					TimeColumn: "time",
+					Match:      map[string]string{"product_id": "{{ .product_id }}"},
+				},
+			},
+			expected: &proto.DeleteRequest{
+				Table: "candles",
+				Match: map[string]interface{}{"product_id": "BTC-USD"},
+				Range: &proto.TimeRange{Column: "time", Start: start, End: start.Add(90 * time.Minute)},
+			},
+		},
+		{
+			name: "empty time range",
+			req: &config.Request{
+				Timeseries:    &config.Timeseries{},
+				TruncateWhere: &config.TruncateWhere{TimeColumn: "time"},
+			},
+		},
+		{
+			name: "missing query parameter",
+			req: &config.Request{
+				TruncateWhere: &config.TruncateWhere{Match: map[string]string{"product_id": "{{ .product_id }}"}},
+			},
+			wantErr: true,
+		},
+	} {
+		got, err := deleteRequest(tcase.req, "candles")
+		if (err != nil) != tcase.wantErr {
+			t.Fatalf("%s: expected error %v, got %v", tcase.name, tcase.wantErr, err)
+		}
+
+		if !reflect.DeepEqual(got, tcase.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tcase.name, tcase.expected, got)
+		}
+	}
+}
+
+func TestTruncateTables(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	truncate := true
+
+	for _, tcase := range []struct {
+		name  string
+		cfg   *config.Config
+		first bool
+
+		// candles are the IDs of the candles that are kept.
+		candles []string
+	}{
+		{
+			// The candles are truncated in the transaction of the second request, after the first is written.
+			name:    "request",
+			cfg:     &config.Config{},
+			candles: []string{"2", "3"},
+		},
+		{
+			name:    "run",
+			cfg:     &config.Config{Transaction: config.TransactionRun},
+			candles: []string{"2", "3"},
+		},
+		{
+			// The candles are truncated before any of the requests are written.
+			name:    "first",
+			cfg:     &config.Config{TruncateFirst: true},
+			candles: []string{"1", "2", "3"},
+		},
+	} {
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		for _, table := range []string{"candles", "trades"} {
+			path := filepath.Join(dir, table+".ndjson")
+			if err := os.WriteFile(path, []byte("{\"id\":\"0\"}\n"), 0o600); err != nil {
+				t.Fatalf("failed to write table: %v", err)
+			}
+		}
+
+		// The second and third requests truncate the candles, and the trades are kept.
+		tcase.cfg.Requests = []*config.Request{
+			{Table: "candles"},
+			{Table: "candles", Truncate: &truncate},
+			{Table: "candles", Truncate: &truncate},
+			{Table: "trades"},
+		}
+
+		flattenedRequests := make([]*flattenedRequest, len(tcase.cfg.Requests))
+		for idx, req := range tcase.cfg.Requests {
+			flattenedRequests[idx] = &flattenedRequest{request: req}
+		}
+
+		txns := newRequestTxns(tcase.cfg, flattenedRequests)
+		for idx, txn := range txns {
+			txn.jobs <- &repoJob{table: txn.table, b: []byte(fmt.Sprintf(`[{"id":"%d"}]`, idx+1))}
+		}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}, logger: logger}}
+
+		switch {
+		case tcase.cfg.Transaction == config.TransactionRun:
+			err = writeRun(ctx, txns, repos, logger)
+		case tcase.cfg.TruncateFirst:
+			if err = truncateTables(ctx, txns, repos, nil, nil, logger); err == nil {
+				err = upsertRequests(ctx, txns, repos, len(txns), nil, nil, logger)
+			}
+		default:
+			err = upsertRequests(ctx, txns, repos, len(txns), nil, nil, logger)
+		}
+
+		if err != nil {
+			t.Fatalf("%s: failed to upsert: %v", tcase.name, err)
+		}
+
+		// The truncate of the third request does not delete the records of the second.
+		candles := readLines(t, filepath.Join(dir, "candles.ndjson"))
+		sort.Strings(candles)
+
+		expected := make([]string, len(tcase.candles))
+		for idx, id := range tcase.candles {
+			expected[idx] = fmt.Sprintf(`{"id":"%s"}`, id)
+		}
+
+		if !reflect.DeepEqual(candles, expected) {
+			t.Errorf("%s: expected candles %v, got %v", tcase.name, expected, candles)
+		}
+
+		if trades := readLines(t, filepath.Join(dir, "trades.ndjson")); len(trades) != 2 {
+			t.Errorf("%s: expected the trades to be kept, got %v", tcase.name, trades)
+		}
+	}
+}
@@ -0,0 +1,936 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestTxn groups the writes of a single configured request so that they can be made in one transaction on each
+// destination that the request's table is routed to, or as part of a transaction for the entire run.
+type requestTxn struct {
+	req               *config.Request
+	flattenedRequests []*flattenedRequest
+
+	// table is the name of the request's table in storage.
+	table string
+
+	// writes are the totals of the upserts of the request on each destination.
+	writes []*destinationWrites
+
+	// verify is how the writes of the request are verified. The number of fetched records, and the records
+	// themselves if their checksum is verified, are compared with what was written to each destination.
+	verify         string
+	fetched        int64
+	fetchedRecords []map[string]interface{}
+
+	// discrepancies are the differences between the fetched and written records found by verifying the writes.
+	discrepancies []string
+
+	// jobs receives the data fetched for each of the flattened requests, or nil if the data was discarded. If the
+	// transactions of the request can be retried, the received data is kept so that it can be written again.
+	jobs     chan *repoJob
+	retain   bool
+	received []*repoJob
+
+	// discarded is closed once the request no longer receives the data of its jobs, so that the web workers do not
+	// wait for it. backpressure is how long the web workers waited for the buffer of the jobs.
+	discarded    chan struct{}
+	backpressure *backpressure
+
+	// spill holds the data of the jobs in memory while they are queued, or spills it to disk, if the run has a memory
+	// limit. The data is read back once it is received.
+	spill *spill
+
+	// queue holds the responses of the request until it is committed, if the run has a durable queue.
+	queue *durableQueue
+
+	// progress is called with the outcome of the request, if it is set.
+	progress func(config.RequestProgress)
+
+	// onFetch and onUpsert are called as the chunks of the request are fetched and its records are upserted, if
+	// they are set. fetchedChunks counts the chunks that have been fetched, which are fetched concurrently.
+	onFetch       func(config.FetchProgress)
+	onUpsert      func(config.UpsertProgress)
+	fetchedChunks int64
+
+	// tracer traces the fetch, transform, and upsert of each flattened request of the request.
+	tracer trace.Tracer
+
+	// newJob will return the job that fetches the data of a flattened request of the request, so that its data can
+	// be fetched again if it is retried.
+	newJob func(*flattenedRequest) *webJob
+
+	// chunkLogs logs the chunks of the request that are fetched and the batches that are upserted.
+	chunkLogs *chunkLogs
+
+	// errors are the errors of the request by category, which are reported in the summary of the run.
+	errors *requestErrors
+
+	// stages are how long the chunks of the request spent in each stage, which are reported with its outcome.
+	stages *stageTimings
+
+	// deadLetters are the payloads of the request that failed to be decoded or upserted, if the run has a dead
+	// letter. The received data is then kept, so that it can be added if the request fails to be upserted.
+	deadLetters *deadLetters
+
+	// poison counts the payloads of the request that failed to be decoded, across its retries.
+	poison *poisonPayloads
+
+	// checkpoint saves the flattened requests of the request once its writes have been committed, if the run has a
+	// checkpoint.
+	checkpoint *checkpoint
+
+	// coalesce is how the records of the chunks of the request are accumulated before they are written, if they are.
+	coalesce *config.Coalesce
+
+	// watchdog is told when the repository worker writes the request, and when it waits for its data, if the run has
+	// a watchdog. worker is the name of the repository worker that writes the request.
+	watchdog *watchdog
+	worker   string
+
+	// fetchers and writers limit how many web and repository workers fetch and write at once, if the run adapts its
+	// workers. writers is told how long each batch of the request took to be upserted.
+	fetchers *adaptive
+	writers  *adaptive
+
+	// split splits the responses of the request that have more than splitSize records into slices, which
+	// splitWorkers write concurrently in their own transactions, if the responses of the request are split.
+	split        *splitter
+	splitSize    int
+	splitWorkers int
+
+	// requeue is whether the request is written once more if the repository worker panics while writing it. The
+	// received data is then kept, so that it can be written again.
+	requeue bool
+
+	// sendTruncate is whether the request truncates its table as the first write of its transactions, which only the
+	// first request that empties a table does. after are the requests of the table that are written before the
+	// request, and done is closed once the request has been committed or rolled back, so that a table is truncated in
+	// the order of its requests and the truncate never deletes the records of the requests after it.
+	sendTruncate bool
+	after        []*requestTxn
+	done         chan struct{}
+
+	// finished is when the writes of the request were committed or rolled back, and err is why they were rolled
+	// back, which are written to the audit table if the run is audited.
+	finished time.Time
+	err      error
+}
+
+// newRequestTxns will group the flattened requests by the configured request they were flattened from, in the order
+// of the configured requests.
+func newRequestTxns(cfg *config.Config, flattenedRequests []*flattenedRequest) []*requestTxn {
+	txns := make([]*requestTxn, 0, len(cfg.Requests))
+
+	for _, req := range cfg.Requests {
+		txn := &requestTxn{
+			req:       req,
+			table:     cfg.StorageTable(req.Table),
+			verify:    cfg.Verify,
+			retain:    (cfg.Retry != nil && cfg.Retry.Retries > 0) || cfg.DeadLetter != nil || cfg.RequeuePanics,
+			requeue:   cfg.RequeuePanics,
+			worker:    repositoryWorker,
+			coalesce:  cfg.Coalesce,
+			progress:  cfg.Progress,
+			onFetch:   cfg.OnFetch,
+			onUpsert:  cfg.OnUpsert,
+			tracer:    newTracer(cfg),
+			chunkLogs: newChunkLogs(cfg.Logger, cfg.LogSample),
+			errors:    newRequestErrors(),
+			stages:    &stageTimings{},
+
+			deadLetters: newDeadLetters(cfg),
+			poison:      newPoisonPayloads(cfg),
+
+			splitSize:    cfg.SplitRecords,
+			splitWorkers: cfg.SplitWorkerCount(),
+		}
+
+		for _, flatReq := range flattenedRequests {
+			if flatReq.request == req {
+				txn.flattenedRequests = append(txn.flattenedRequests, flatReq)
+			}
+		}
+
+		// The web workers wait once the buffer of the request is full, so that the data fetched for a request that
+		// is written slowly does not fill memory.
+		buffer := cfg.JobBufferSize()
+		if buffer > len(txn.flattenedRequests) {
+			buffer = len(txn.flattenedRequests)
+		}
+
+		txn.jobs = make(chan *repoJob, buffer)
+		txn.discarded = make(chan struct{})
+		txn.backpressure = newBackpressure(txn.table, buffer, cfg.Logger)
+		txn.done = make(chan struct{})
+		txns = append(txns, txn)
+	}
+
+	if !cfg.TruncateFirst || cfg.Transaction == config.TransactionRun {
+		orderTruncates(txns)
+	}
+
+	return txns
+}
+
+// receive will return the data fetched for the flattened requests at the index, in the order that it was received,
+// waiting for it if it has not been received yet. The parts of a chunk whose records are streamed are received one at
+// a time.
+func (txn *requestTxn) receive(idx int) *repoJob {
+	job, _ := txn.receiveBefore(idx, nil)
+
+	return job
+}
+
+// receiveBefore will return the data fetched for the flattened request at the index like "receive", unless "due"
+// receives before the data does, in which case it returns false.
+func (txn *requestTxn) receiveBefore(idx int, due <-chan time.Time) (*repoJob, bool) {
+	if idx < len(txn.received) {
+		return txn.received[idx], true
+	}
+
+	// The repository worker does not stall while it waits for the web workers.
+	txn.watchdog.idle(txn.worker)
+	defer txn.watchdog.busy(txn.worker, fmt.Sprintf("writing %q", txn.table))
+
+	var job *repoJob
+
+	select {
+	case job = <-txn.jobs:
+	case <-due:
+		return nil, false
+	}
+
+	if err := txn.spill.release(job); err != nil {
+		job = &repoJob{err: err}
+	}
+
+	if txn.retain {
+		txn.received = append(txn.received, job)
+	}
+
+	return job, true
+}
+
+// report will record the outcome of the request, and call the progress function of the request with it and the
+// totals of its writes.
+func (txn *requestTxn) report(err error) {
+	txn.finished = time.Now()
+	txn.err = err
+	txn.discard()
+
+	if err == nil {
+		txn.checkpoint.commit(txn.flattenedRequests)
+		txn.queue.ack(txn.flattenedRequests)
+	}
+
+	if txn.progress == nil {
+		return
+	}
+
+	totals := &proto.UpsertResponse{}
+	for _, writes := range txn.writes {
+		addTotals(totals, writes.totals)
+	}
+
+	progress := config.RequestProgress{
+		Endpoint: txn.req.Endpoint,
+		Table:    txn.table,
+		Status:   config.RequestCommitted,
+		Received: totals.GetReceivedCount(),
+		Inserted: totals.GetInsertedCount(),
+		Updated:  totals.GetUpdatedCount(),
+		Failed:   totals.GetFailedCount(),
+		Errors:   txn.errors.snapshot(),
+		Stages:   txn.stages.snapshot(),
+	}
+
+	if err != nil {
+		progress.Status = config.RequestRolledBack
+		progress.Error = err.Error()
+	}
+
+	txn.progress(progress)
+}
+
+// discard will stop the request from receiving the data of its jobs, which the web workers then drop rather than wait
+// to send.
+func (txn *requestTxn) discard() {
+	if txn.discarded == nil {
+		return
+	}
+
+	select {
+	case <-txn.discarded:
+	default:
+		close(txn.discarded)
+	}
+
+	// The data that is still queued is no longer held against the memory limit of the run.
+	for txn.spill != nil {
+		select {
+		case job := <-txn.jobs:
+			txn.spill.drop(job)
+		default:
+			return
+		}
+	}
+}
+
+// reportFetch will count a chunk of the request as fetched, and call the fetch function of the request with the
+// progress of its chunks. The chunk waited for the rate limiter for "wait", then took "latency" to be fetched, and its
+// body was "size" bytes.
+func (txn *requestTxn) reportFetch(wait, latency time.Duration, size int) {
+	fetched := atomic.AddInt64(&txn.fetchedChunks, 1)
+
+	if txn.onFetch == nil {
+		return
+	}
+
+	txn.onFetch(config.FetchProgress{
+		Endpoint:      txn.req.Endpoint,
+		Table:         txn.table,
+		Fetched:       int(fetched),
+		Chunks:        len(txn.flattenedRequests),
+		RateLimitWait: wait,
+		Bytes:         int64(size),
+		Latency:       latency,
+	})
+}
+
+// reportUpsert will call the upsert function of the request with the records of the response, which took "elapsed"
+// to be upserted.
+func (txn *requestTxn) reportUpsert(rsp *proto.UpsertResponse, elapsed time.Duration) {
+	txn.watchdog.beat(txn.worker)
+	txn.stages.add(stageWrite, elapsed)
+	txn.writers.observe(elapsed, nil)
+
+	if txn.onUpsert == nil {
+		return
+	}
+
+	txn.onUpsert(config.UpsertProgress{
+		Endpoint: txn.req.Endpoint,
+		Table:    txn.table,
+		Upserted: rsp.GetUpsertedCount() + rsp.GetMatchedCount(),
+	})
+}
+
+// truncates will return true if the request's table should be emptied before its data is written.
+func (txn *requestTxn) truncates() bool {
+	return txn.req.Truncate != nil && *txn.req.Truncate && txn.req.Table != ""
+}
+
+// primaryKeys will return the fields that identify the records of the request, which is the hash key of the records
+// if the request has one.
+func (txn *requestTxn) primaryKeys() []string {
+	if txn.req.HashKey != nil {
+		return []string{txn.req.HashKey.ColumnName()}
+	}
+
+	return txn.req.PrimaryKey
+}
+
+// beginTx will start a transaction on each of the repositories, beginning them concurrently.
+func beginTx(ctx context.Context, repos []*destinationRepo, logger tools.Logger) ([]*destinationRepo, error) {
+	txRepos := make([]*destinationRepo, len(repos))
+	errs := make([]error, len(repos))
+
+	var wg sync.WaitGroup
+
+	for idx, repo := range repos {
+		wg.Add(1)
+
+		go func(idx int, repo *destinationRepo) {
+			defer wg.Done()
+
+			txRepos[idx], errs[idx] = repo.begin(ctx)
+		}(idx, repo)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		begun := []*destinationRepo{}
+
+		for _, txRepo := range txRepos {
+			if txRepo != nil {
+				begun = append(begun, txRepo)
+			}
+		}
+
+		rollback(begun, logger)
+
+		return nil, classify(ErrStorage, err)
+	}
+
+	return txRepos, nil
+}
+
+// routed will return the repositories that the table is routed to.
+func routed(repos []*destinationRepo, table string) []*destinationRepo {
+	routedRepos := []*destinationRepo{}
+
+	for _, repo := range repos {
+		if repo.dest.Routes(table) {
+			routedRepos = append(routedRepos, repo)
+		}
+	}
+
+	return routedRepos
+}
+
+// write will send the writes of the request to the transactions of the repositories that the request's table is
+// routed to. If the request truncates its table, the truncate is sent before any of the data fetched for the request,
+// and if it soft deletes records, the table is reconciled with the fetched records after all of the data.
+func (txn *requestTxn) write(workerID int, txRepos []*destinationRepo, logger tools.Logger) error {
+	txRepos = routed(txRepos, txn.req.Table)
+
+	if txn.sendTruncate {
+		truncate, err := txn.truncate(logger)
+		if err != nil {
+			return err
+		}
+
+		for _, repo := range txRepos {
+			if truncate == nil {
+				break
+			}
+
+			repo.Transact(truncate)
+		}
+	}
+
+	// The writes are verified against the records of the last attempt.
+	txn.fetched = 0
+	txn.fetchedRecords = nil
+
+	txn.writes = make([]*destinationWrites, len(txRepos))
+	for idx, repo := range txRepos {
+		txn.writes[idx] = &destinationWrites{repo: repo, totals: &proto.UpsertResponse{}}
+	}
+
+	// The keys of the fetched records are only reconciled if every response of the request was stored.
+	reconcile := &proto.ReconcileRequest{
+		Table:         txn.table,
+		PrimaryKeys:   txn.primaryKeys(),
+		DeletedColumn: txn.req.SoftDelete,
+	}
+	complete := true
+
+	coalescer, err := newCoalescer(txn.coalesce)
+	if err != nil {
+		return err
+	}
+
+	// The parts of a chunk whose records are streamed are received until its last part.
+	for idx, chunks := 0, 0; chunks < len(txn.flattenedRequests); idx++ {
+		job, received := txn.receiveBefore(idx, coalescer.due())
+		if !received {
+			// The coalesced records have waited long enough, and are written while the chunk is fetched.
+			if err := txn.writeCoalesced(workerID, coalescer); err != nil {
+				return err
+			}
+
+			job = txn.receive(idx)
+		}
+
+		if job == nil || !job.more {
+			chunks++
+		}
+
+		if job == nil {
+			complete = false
+
+			continue
+		}
+
+		if job.err != nil {
+			return classify(ErrFetch, fmt.Errorf("unable to fetch data: %w", job.err))
+		}
+
+		if reconcile.DeletedColumn != "" {
+			keys, err := recordKeys(job.b, reconcile.PrimaryKeys)
+			if err != nil {
+				return err
+			}
+
+			reconcile.Keys = append(reconcile.Keys, keys...)
+		}
+
+		if txn.verify != "" {
+			if err := txn.verifiedRecords(job.b); err != nil {
+				return err
+			}
+		}
+
+		// The quarantined records are committed or rolled back with the rest of the data.
+		if job.quarantine != nil {
+			for _, repo := range txRepos {
+				repo.Transact(recordsFn("quarantine", job.quarantine, job.quarantined, logger))
+			}
+		}
+
+		if coalescer.add(job) {
+			if coalescer.full() {
+				if err := txn.writeCoalesced(workerID, coalescer); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		// The records that were coalesced before the chunk are written first, so that the last write of a record
+		// wins.
+		if err := txn.writeCoalesced(workerID, coalescer); err != nil {
+			return err
+		}
+
+		if err := txn.writeRecords(workerID, job); err != nil {
+			return err
+		}
+	}
+
+	if err := txn.writeCoalesced(workerID, coalescer); err != nil {
+		return err
+	}
+
+	if reconcile.DeletedColumn == "" {
+		return nil
+	}
+
+	if !complete {
+		msg := fmt.Sprintf("skipping soft deletes for %q, since some responses were discarded", txn.table)
+		tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+		return nil
+	}
+
+	for _, repo := range txRepos {
+		repo.Transact(reconcileFn(reconcile, logger))
+	}
+
+	return nil
+}
+
+// writeRecords will send the upserts of the records of the chunk to the transactions of the request. The records of
+// a chunk that has more records than the request splits at are split into slices, which are sent to the
+// transactions of the split workers in turn.
+func (txn *requestTxn) writeRecords(workerID int, job *repoJob) error {
+	slices, err := txn.split.slice(job.b)
+	if err != nil {
+		return fmt.Errorf("error splitting data: %w", err)
+	}
+
+	if len(slices) <= 1 {
+		return txn.writeBatches(workerID, txn.writes, job, job.b)
+	}
+
+	msg := fmt.Sprintf("splitting a response of %q into %d slices", txn.table, len(slices))
+	tools.LogFormatter{Msg: msg}.Log(txn.split.logger, tools.LogLevelDebug)
+
+	for _, slice := range slices {
+		writes, err := txn.split.nextWrites()
+		if err != nil {
+			return err
+		}
+
+		if err := txn.writeBatches(workerID, writes, job, slice); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeBatches will send the upserts of the records of the chunk to the transactions of the writes, in batches of the
+// batch size of each repository.
+func (txn *requestTxn) writeBatches(workerID int, writes []*destinationWrites, job *repoJob, data []byte) error {
+	for _, write := range writes {
+		batches, err := batchRecords(data, write.repo.batchSize)
+		if err != nil {
+			return fmt.Errorf("error batching data: %w", err)
+		}
+
+		for _, batch := range batches {
+			req := &proto.UpsertRequest{
+				Table:       job.table,
+				Data:        batch,
+				PrimaryKeys: job.primaryKeys,
+				Conflict:    job.conflict,
+			}
+
+			// Put the data onto the transaction channel for storage.
+			upsert := upsertFn(workerID, req, write.totals, txn.reportUpsert, txn.chunkLogs)
+			write.repo.Transact(tracedFn(txn.tracer, job.span, spanUpsert, job.table, upsert))
+		}
+	}
+
+	return nil
+}
+
+// writeCoalesced will send the upserts of the records that the coalescer accumulated, if any.
+func (txn *requestTxn) writeCoalesced(workerID int, coalescer *coalescer) error {
+	job, err := coalescer.flush()
+	if err != nil || job == nil {
+		return err
+	}
+
+	return txn.writeRecords(workerID, job)
+}
+
+// upsert will write the request to each repository that its table is routed to, in a transaction that is committed
+// once all of the data has been written, or rolled back if any of it cannot be written.
+func (txn *requestTxn) upsert(ctx context.Context, workerID int, repos []*destinationRepo,
+	logger tools.Logger,
+) error {
+	repos = routed(repos, txn.req.Table)
+
+	txRepos, err := beginTx(ctx, repos, logger)
+	if err != nil {
+		return err
+	}
+
+	txn.split = newSplitter(ctx, repos, txn.splitSize, txn.splitWorkers, logger)
+
+	write := func() error { return txn.write(workerID, txRepos, logger) }
+	if err := recoverPanic(logger, txn.worker, fmt.Sprintf("writing %q", txn.table), write); err != nil {
+		rollback(append(txRepos, txn.split.txRepos()...), logger)
+
+		return err
+	}
+
+	if err := commit(append(txRepos, txn.split.txRepos()...), logger); err != nil {
+		return err
+	}
+
+	txn.split.addTotals(txn.writes)
+
+	if txn.verify != "" {
+		txn.verifyWrites(ctx, logger)
+	}
+
+	return nil
+}
+
+// upsertRequests will upsert each request in its own transactions, with the number of repository workers writing
+// requests concurrently. A request that fails is rolled back without affecting the other requests, and retried if it
+// failed with a transient error. If some requests fail, the run completes with the others committed, and a
+// "PartialError" that lists the requests and chunks that failed is returned. A request that fails the run aborts the
+// requests that have not started to be written.
+func upsertRequests(ctx context.Context, txns []*requestTxn, repos []*destinationRepo, workers int,
+	policy *config.Retry, budget *retryBudget, logger tools.Logger,
+) error {
+	if workers > len(txns) {
+		workers = len(txns)
+	}
+
+	var (
+		// mutex guards the next request to be written and the outcome of the run, and serializes the reports of
+		// the requests, so that their progress functions are called one at a time.
+		mutex    sync.Mutex
+		next     int
+		failures = make([]*RequestFailure, len(txns))
+		abortErr error
+	)
+
+	// take will return the index of the next request to be written, or false if every request has been taken.
+	take := func() (int, bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if next == len(txns) {
+			return 0, false
+		}
+
+		next++
+
+		return next - 1, true
+	}
+
+	finish := func(idx int, err error) {
+		txn := txns[idx]
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		txn.chunkLogs.flush()
+		txn.report(err)
+		txn.received = nil
+
+		if err == nil {
+			return
+		}
+
+		switch txn.req.OnError {
+		case config.OnErrorSkip:
+			msg := fmt.Sprintf("request skipped for %q: %v", txn.table, err)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+		case config.OnErrorFail:
+			if abortErr != nil {
+				return
+			}
+
+			abort(txns[next:], txn.table, logger)
+			next = len(txns)
+
+			// The error of the request is kept, so that the class of the error that aborted the run is matched too.
+			abortErr = classify(ErrAborted, fmt.Errorf("run aborted by the request for %q: %w", txn.table, err))
+		default:
+			failures[idx] = txn.failure(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for id := 1; id <= workers; id++ {
+		worker := repositoryWorkerName(id)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx, ok := take(); ok; idx, ok = take() {
+				txn := txns[idx]
+				txn.worker = worker
+
+				txn.waitAfter(ctx)
+				txn.writers.acquire(ctx)
+				err := txn.upsertRetried(ctx, idx+1, repos, policy, budget, logger)
+				txn.writers.release()
+
+				// A request that failed to be fetched says nothing about the health of the writes.
+				if err != nil && !errors.Is(err, ErrFetch) {
+					txn.writers.observe(0, err)
+					txn.deadLetterReceived(err)
+				}
+
+				finish(idx, err)
+				close(txn.done)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if abortErr != nil {
+		return abortErr
+	}
+
+	// The failures are listed in the order of the requests.
+	var failed []*RequestFailure
+
+	for _, failure := range failures {
+		if failure != nil {
+			failed = append(failed, failure)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &PartialError{Requests: len(txns), Failures: failed}
+	}
+
+	return nil
+}
+
+// upsertRun will upsert every request in a single transaction on each repository, which is committed once all of the
+// requests have been written, or rolled back if any of them cannot be written. The run is retried if it failed with
+// a transient error.
+func upsertRun(ctx context.Context, txns []*requestTxn, repos []*destinationRepo, policy *config.Retry,
+	budget *retryBudget, logger tools.Logger,
+) error {
+	// The requests of a run share its watchdog and whether they are requeued.
+	var (
+		dog     *watchdog
+		requeue bool
+	)
+
+	if len(txns) > 0 {
+		dog, requeue = txns[0].watchdog, txns[0].requeue
+	}
+
+	err := retryTxn(ctx, policy, budget, "run", logger, func() error {
+		dog.busy(repositoryWorker, "writing the run")
+		defer dog.idle(repositoryWorker)
+
+		err := writeRun(ctx, txns, repos, logger)
+		if requeue && panicked(err) {
+			tools.LogFormatter{Msg: "requeuing the run once after a panic"}.Log(logger, tools.LogLevelWarn)
+
+			err = writeRun(ctx, txns, repos, logger)
+		}
+
+		return err
+	})
+
+	// The requests are committed or rolled back together.
+	for _, txn := range txns {
+		if err != nil && !errors.Is(err, ErrFetch) {
+			txn.deadLetterReceived(err)
+		}
+
+		txn.chunkLogs.flush()
+		txn.report(err)
+	}
+
+	return err
+}
+
+// writeRun will write every request in a single transaction on each repository.
+func writeRun(ctx context.Context, txns []*requestTxn, repos []*destinationRepo, logger tools.Logger) error {
+	txRepos, err := beginTx(ctx, repos, logger)
+	if err != nil {
+		return err
+	}
+
+	for idx, txn := range txns {
+		write := func() error { return txn.write(idx+1, txRepos, logger) }
+		if err := recoverPanic(logger, repositoryWorker, fmt.Sprintf("writing %q", txn.table), write); err != nil {
+			rollback(txRepos, logger)
+
+			return err
+		}
+	}
+
+	if err := commit(txRepos, logger); err != nil {
+		tools.LogFormatter{Msg: fmt.Sprintf("run rolled back: %v", err)}.Log(logger, tools.LogLevelError)
+
+		return err
+	}
+
+	for _, txn := range txns {
+		if txn.verify != "" {
+			txn.verifyWrites(ctx, logger)
+		}
+	}
+
+	return nil
+}
+
+// truncateFn will return a transaction function that truncates the table.
+func truncateFn(table string, logger tools.Logger) func(context.Context, repository.Generic) error {
+	return func(sctx context.Context, repo repository.Generic) error {
+		start := time.Now()
+
+		if _, err := repo.Truncate(sctx, &proto.TruncateRequest{Tables: []string{table}}); err != nil {
+			return fmt.Errorf("unable to truncate tables: %w", err)
+		}
+
+		msg := fmt.Sprintf("truncated tables on %q: %v", proto.SchemeFromStorageType(repo.Type()), table)
+		logInfo := tools.LogFormatter{
+			Duration: time.Since(start),
+			Msg:      msg,
+		}
+		logInfo.Log(logger, tools.LogLevelInfo)
+
+		return nil
+	}
+}
+
+// upsertFn will return a transaction function that upserts the request, adding the counts of the response to
+// "totals" and reporting the response, if "report" is set.
+func upsertFn(workerID int, req *proto.UpsertRequest, totals *proto.UpsertResponse,
+	report func(*proto.UpsertResponse, time.Duration), logs *chunkLogs,
+) func(context.Context, repository.Generic) error {
+	return func(sctx context.Context, repo repository.Generic) error {
+		start := time.Now()
+
+		rsp, err := repo.Upsert(sctx, req)
+		if err != nil {
+			return fmt.Errorf("error upserting data: %w", err)
+		}
+
+		addTotals(totals, rsp)
+
+		trace.SpanFromContext(sctx).SetAttributes(
+			attribute.Int64("gidari.upserted", rsp.UpsertedCount),
+			attribute.Int64("gidari.matched", rsp.MatchedCount),
+		)
+
+		if report != nil {
+			report(rsp, time.Since(start))
+		}
+
+		rt := repo.Type()
+
+		scheme := proto.SchemeFromStorageType(rt)
+		logInfo := tools.LogFormatter{
+			WorkerID:      workerID,
+			WorkerName:    "repository",
+			Duration:      time.Since(start),
+			Table:         req.Table,
+			Msg:           fmt.Sprintf("partial upsert completed: %s.%s", scheme, req.Table),
+			UpsertedCount: rsp.UpsertedCount,
+			MatchedCount:  rsp.MatchedCount,
+		}
+
+		logs.log(fmt.Sprintf("partial upserts completed: %s.%s", scheme, req.Table), "batches", logInfo)
+
+		return nil
+	}
+}
+
+// commit will commit the transaction on each repository. If a commit fails, the transactions that have not yet been
+// committed are rolled back. Transient errors are only returned if no transaction was committed, since retrying
+// would otherwise write the data to some of the repositories twice. Since the writes to each repository are sent
+// concurrently, the commits only wait for the slowest repository once.
+func commit(txRepos []*destinationRepo, logger tools.Logger) error {
+	for idx, repo := range txRepos {
+		if err := repo.Commit(); err != nil {
+			rollback(txRepos[idx+1:], logger)
+
+			if idx > 0 {
+				return classify(ErrStorage, fmt.Errorf("unable to commit transaction on %q after committing %d: %v",
+					repo.name(), idx, err))
+			}
+
+			return classify(ErrStorage, fmt.Errorf("unable to commit transaction on %q: %w", repo.name(), err))
+		}
+	}
+
+	return nil
+}
+
+// rollback will roll back the transaction on each repository concurrently, logging any errors.
+func rollback(txRepos []*destinationRepo, logger tools.Logger) {
+	var wg sync.WaitGroup
+
+	for _, repo := range txRepos {
+		wg.Add(1)
+
+		go func(repo *destinationRepo) {
+			defer wg.Done()
+
+			if err := repo.Rollback(); err != nil {
+				msg := fmt.Sprintf("unable to roll back transaction on %q: %v", repo.name(), err)
+				tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelError)
+			}
+		}(repo)
+	}
+
+	wg.Wait()
+}
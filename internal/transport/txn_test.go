@@ -0,0 +1,340 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+var errFetch = fmt.Errorf("connection refused")
+
+func TestRequestTxn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	t.Run("group flattened requests", func(t *testing.T) {
+		t.Parallel()
+
+		reqs := []*config.Request{{Table: "candles"}, {Table: "trades"}}
+		flattenedRequests := []*flattenedRequest{{request: reqs[1]}, {request: reqs[0]}, {request: reqs[1]}}
+
+		txns := newRequestTxns(&config.Config{Requests: reqs, TablePrefix: "dev_", TableSuffix: "_v1"}, flattenedRequests)
+		if len(txns) != 2 || txns[0].req != reqs[0] || txns[1].req != reqs[1] {
+			t.Fatalf("expected a transaction for each request in order, got %v", txns)
+		}
+
+		if txns[0].table != "dev_candles_v1" || txns[1].table != "dev_trades_v1" {
+			t.Errorf("expected namespaced tables, got %q and %q", txns[0].table, txns[1].table)
+		}
+
+		if len(txns[0].flattenedRequests) != 1 || len(txns[1].flattenedRequests) != 2 {
+			t.Errorf("expected 1 and 2 flattened requests, got %d and %d", len(txns[0].flattenedRequests),
+				len(txns[1].flattenedRequests))
+		}
+	})
+
+	t.Run("truncate and reload", func(t *testing.T) {
+		t.Parallel()
+
+		truncate := true
+
+		for _, tcase := range []struct {
+			name     string
+			data     []string
+			expected string
+			wantErr  bool
+		}{
+			{
+				name:     "reloaded",
+				data:     []string{`[{"id":"2"}]`, `[{"id":"3"}]`},
+				expected: "{\"id\":\"2\"}\n{\"id\":\"3\"}\n",
+			},
+			{
+				// The truncate is rolled back with the request, so the table is never left partially reloaded.
+				name:     "failed",
+				data:     []string{`[{"id":"2"}]`, `not json`},
+				expected: "{\"id\":\"1\"}\n",
+				wantErr:  true,
+			},
+		} {
+			dir := t.TempDir()
+
+			repo, err := repository.New(ctx, "file://"+dir)
+			if err != nil {
+				t.Fatalf("failed to create repository: %v", err)
+			}
+
+			if err := os.WriteFile(filepath.Join(dir, "candles.ndjson"), []byte("{\"id\":\"1\"}\n"), 0o600); err != nil {
+				t.Fatalf("failed to write table: %v", err)
+			}
+
+			req := &config.Request{Table: "candles", Truncate: &truncate}
+
+			flattenedRequests := make([]*flattenedRequest, len(tcase.data))
+			for idx := range flattenedRequests {
+				flattenedRequests[idx] = &flattenedRequest{request: req}
+			}
+
+			txn := newRequestTxns(&config.Config{Requests: []*config.Request{req}}, flattenedRequests)[0]
+			for _, data := range tcase.data {
+				txn.jobs <- &repoJob{table: "candles", b: []byte(data)}
+			}
+
+			repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+			err = txn.upsert(ctx, 1, repos, logger)
+			if (err != nil) != tcase.wantErr {
+				t.Fatalf("%s: expected error %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "candles.ndjson"))
+			if err != nil {
+				t.Fatalf("failed to read table: %v", err)
+			}
+
+			if string(got) != tcase.expected {
+				t.Errorf("%s: expected %q, got %q", tcase.name, tcase.expected, got)
+			}
+		}
+	})
+
+	t.Run("fetch error", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		repo, err := repository.New(ctx, "file://"+dir)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		req := &config.Request{Table: "candles"}
+		flattenedRequests := []*flattenedRequest{{request: req}, {request: req}}
+
+		txn := newRequestTxns(&config.Config{Requests: []*config.Request{req}}, flattenedRequests)[0]
+		txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"1"}]`)}
+		txn.jobs <- &repoJob{err: errFetch}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+		err = txn.upsert(ctx, 1, repos, logger)
+		if !errors.Is(err, errFetch) || !errors.Is(err, ErrFetch) {
+			t.Fatalf("expected %v and %v, got %v", errFetch, ErrFetch, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "candles.ndjson")); !os.IsNotExist(err) {
+			t.Errorf("expected the request to be rolled back, got %v", err)
+		}
+	})
+
+	t.Run("live progress", func(t *testing.T) {
+		t.Parallel()
+
+		repo, err := repository.New(ctx, "file://"+t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		req := &config.Request{Endpoint: "/candles", Table: "candles"}
+
+		var (
+			fetches []config.FetchProgress
+			upserts []config.UpsertProgress
+		)
+
+		cfg := &config.Config{
+			Requests: []*config.Request{req},
+			OnFetch:  func(progress config.FetchProgress) { fetches = append(fetches, progress) },
+			OnUpsert: func(progress config.UpsertProgress) { upserts = append(upserts, progress) },
+		}
+
+		txn := newRequestTxns(cfg, []*flattenedRequest{{request: req}, {request: req}})[0]
+
+		txn.reportFetch(time.Second, 200*time.Millisecond, 12)
+		txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"1"}]`)}
+		txn.reportFetch(0, 100*time.Millisecond, 12)
+		txn.jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"2"}]`)}
+
+		repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+		if err := txn.upsert(ctx, 1, repos, logger); err != nil {
+			t.Fatalf("failed to upsert: %v", err)
+		}
+
+		want := []config.FetchProgress{
+			{
+				Endpoint: "/candles", Table: "candles", Fetched: 1, Chunks: 2, RateLimitWait: time.Second, Bytes: 12,
+				Latency: 200 * time.Millisecond,
+			},
+			{Endpoint: "/candles", Table: "candles", Fetched: 2, Chunks: 2, Bytes: 12, Latency: 100 * time.Millisecond},
+		}
+		if !reflect.DeepEqual(fetches, want) {
+			t.Errorf("expected fetches %+v, got %+v", want, fetches)
+		}
+
+		if len(upserts) != 2 || upserts[0].Table != "candles" || upserts[1].Endpoint != "/candles" {
+			t.Errorf("expected an upsert for each batch, got %+v", upserts)
+		}
+	})
+
+	t.Run("run", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tcase := range []struct {
+			name     string
+			data     []string
+			expected []string
+			status   string
+			wantErr  bool
+		}{
+			{
+				name:     "committed",
+				data:     []string{`[{"id":"1"}]`, `[{"id":"2"}]`},
+				expected: []string{"{\"id\":\"1\"}\n", "{\"id\":\"2\"}\n"},
+				status:   config.RequestCommitted,
+			},
+			{
+				name:     "rolled back",
+				data:     []string{`[{"id":"1"}]`, `not json`},
+				expected: []string{"", ""},
+				status:   config.RequestRolledBack,
+				wantErr:  true,
+			},
+		} {
+			dir := t.TempDir()
+
+			repo, err := repository.New(ctx, "file://"+dir)
+			if err != nil {
+				t.Fatalf("failed to create repository: %v", err)
+			}
+
+			reqs := []*config.Request{{Table: "candles"}, {Table: "trades"}}
+			flattenedRequests := []*flattenedRequest{{request: reqs[0]}, {request: reqs[1]}}
+
+			var progress []config.RequestProgress
+
+			cfg := &config.Config{
+				Requests: reqs,
+				Progress: func(rsp config.RequestProgress) { progress = append(progress, rsp) },
+			}
+
+			txns := newRequestTxns(cfg, flattenedRequests)
+			for idx, data := range tcase.data {
+				txns[idx].jobs <- &repoJob{table: reqs[idx].Table, b: []byte(data)}
+			}
+
+			repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+			err = upsertRun(ctx, txns, repos, nil, nil, logger)
+			if (err != nil) != tcase.wantErr {
+				t.Fatalf("%s: expected error %v, got %v", tcase.name, tcase.wantErr, err)
+			}
+
+			for idx, req := range reqs {
+				got, err := os.ReadFile(filepath.Join(dir, req.Table+".ndjson"))
+				if err != nil && !os.IsNotExist(err) {
+					t.Fatalf("failed to read table: %v", err)
+				}
+
+				if string(got) != tcase.expected[idx] {
+					t.Errorf("%s: expected %q, got %q", tcase.name, tcase.expected[idx], got)
+				}
+			}
+
+			if len(progress) != len(reqs) {
+				t.Fatalf("%s: expected the progress of %d requests, got %+v", tcase.name, len(reqs), progress)
+			}
+
+			for idx, rsp := range progress {
+				if rsp.Table != reqs[idx].Table || rsp.Status != tcase.status || (rsp.Error != "") != tcase.wantErr {
+					t.Errorf("%s: unexpected progress %+v", tcase.name, rsp)
+				}
+			}
+		}
+	})
+}
+
+func TestUpsertRequestsWorkers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	dir := t.TempDir()
+
+	repo, err := repository.New(ctx, "file://"+dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	reqs := []*config.Request{{Table: "candles"}, {Table: "trades"}, {Table: "stats"}}
+	flattenedRequests := []*flattenedRequest{{request: reqs[0]}, {request: reqs[1]}, {request: reqs[2]}}
+
+	committed := make(chan string, len(reqs))
+	cfg := &config.Config{
+		Requests: reqs,
+		Progress: func(prg config.RequestProgress) { committed <- prg.Table },
+	}
+
+	txns := newRequestTxns(cfg, flattenedRequests)
+
+	// The candles are only received once the trades have been committed, which the trades are not if they wait for
+	// the candles to be written.
+	txns[1].jobs <- &repoJob{table: "trades", b: []byte(`[{"id":"1"}]`)}
+	txns[2].jobs <- &repoJob{table: "stats", b: []byte(`[{"id":"1"}]`)}
+
+	go func() {
+		for table := range committed {
+			if table == "trades" {
+				txns[0].jobs <- &repoJob{table: "candles", b: []byte(`[{"id":"1"}]`)}
+			}
+		}
+	}()
+
+	repos := []*destinationRepo{{GenericService: repo, dest: &config.Destination{}}}
+
+	if err := upsertRequests(ctx, txns, repos, 2, nil, nil, logger); err != nil {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	close(committed)
+
+	for _, txn := range txns {
+		if txn.err != nil {
+			t.Errorf("expected %q to be committed, got %v", txn.table, txn.err)
+		}
+
+		if txn.worker != repositoryWorkerName(1) && txn.worker != repositoryWorkerName(2) {
+			t.Errorf("expected %q to be written by a repository worker, got %q", txn.table, txn.worker)
+		}
+
+		if lines := readLines(t, filepath.Join(dir, txn.table+".ndjson")); len(lines) != 1 {
+			t.Errorf("expected 1 record in %q, got %v", txn.table, lines)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/tools"
+)
+
+// verifiedRecords will count the fetched records of the request, and keep them if their checksum is verified.
+func (txn *requestTxn) verifiedRecords(data []byte) error {
+	records, err := decodeRecords(data)
+	if err != nil {
+		return err
+	}
+
+	txn.fetched += int64(len(records))
+
+	if txn.verify == config.VerifyChecksum {
+		txn.fetchedRecords = append(txn.fetchedRecords, records...)
+	}
+
+	return nil
+}
+
+// checksum will return a checksum of the records that does not depend on their order, or on the order of their
+// fields.
+func checksum(records []map[string]interface{}) (string, error) {
+	lines := make([]string, len(records))
+
+	for idx, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal record: %w", err)
+		}
+
+		lines[idx] = string(line)
+	}
+
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		hash.Write([]byte(line + "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readBack will read each of the records back out of the destination by its primary keys, keeping only the fields
+// that were fetched. Records that are not found are read back as empty records.
+func readBack(ctx context.Context, repo *destinationRepo, table string, pks []string,
+	records []map[string]interface{},
+) ([]map[string]interface{}, error) {
+	stored := make([]map[string]interface{}, len(records))
+
+	for idx, record := range records {
+		match := make(map[string]interface{}, len(pks))
+		for _, pk := range pks {
+			match[pk] = record[pk]
+		}
+
+		rsp, err := repo.Query(ctx, &proto.QueryRequest{Table: table, Match: match, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		stored[idx] = make(map[string]interface{}, len(record))
+
+		if len(rsp.Records) == 0 {
+			continue
+		}
+
+		fields := rsp.Records[0].AsMap()
+		for field := range record {
+			stored[idx][field] = fields[field]
+		}
+	}
+
+	return stored, nil
+}
+
+// verifyChecksum will compare the checksum of the fetched records with the checksum of the records read back out of
+// the destination, returning a discrepancy if they differ.
+func (txn *requestTxn) verifyChecksum(ctx context.Context, repo *destinationRepo) (string, error) {
+	stored, err := readBack(ctx, repo, txn.table, txn.primaryKeys(), txn.fetchedRecords)
+	if err != nil {
+		return "", err
+	}
+
+	// Values are compared as JSON, so that e.g. integers read from storage match the numbers that were fetched.
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal records: %w", err)
+	}
+
+	if stored, err = decodeRecords(data); err != nil {
+		return "", err
+	}
+
+	fetchedSum, err := checksum(txn.fetchedRecords)
+	if err != nil {
+		return "", err
+	}
+
+	storedSum, err := checksum(stored)
+	if err != nil {
+		return "", err
+	}
+
+	if fetchedSum == storedSum {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%s on %q: checksum of the fetched records %s does not match the stored records %s", txn.table,
+		proto.SchemeFromStorageType(repo.Type()), fetchedSum[:12], storedSum[:12]), nil
+}
+
+// verifyWrites will compare the records written to each destination with the records that were fetched, adding any
+// discrepancies to the request. Checksums are only verified for requests with primary keys, on destinations that can
+// be queried.
+func (txn *requestTxn) verifyWrites(ctx context.Context, logger tools.Logger) {
+	for _, writes := range txn.writes {
+		scheme := proto.SchemeFromStorageType(writes.repo.Type())
+
+		if written := writes.written(); written != txn.fetched {
+			txn.discrepancies = append(txn.discrepancies, fmt.Sprintf("%s on %q: fetched %d records, wrote %d",
+				txn.table, scheme, txn.fetched, written))
+		}
+
+		if txn.verify != config.VerifyChecksum {
+			continue
+		}
+
+		if len(txn.primaryKeys()) == 0 {
+			msg := fmt.Sprintf("skipping checksum of %s on %q, since the request has no primaryKey", txn.table, scheme)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+			continue
+		}
+
+		discrepancy, err := txn.verifyChecksum(ctx, writes.repo)
+		if errors.Is(err, proto.ErrQueryNotSupported) {
+			msg := fmt.Sprintf("skipping checksum of %s, since %q cannot be queried", txn.table, scheme)
+			tools.LogFormatter{Msg: msg}.Log(logger, tools.LogLevelWarn)
+
+			continue
+		}
+
+		if err != nil {
+			discrepancy = fmt.Sprintf("%s on %q: unable to verify checksum: %v", txn.table, scheme, err)
+		}
+
+		if discrepancy != "" {
+			txn.discrepancies = append(txn.discrepancies, discrepancy)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	first, err := checksum([]map[string]interface{}{{"id": "1", "price": 1.5}, {"id": "2"}})
+	if err != nil {
+		t.Fatalf("failed to checksum records: %v", err)
+	}
+
+	second, err := checksum([]map[string]interface{}{{"id": "2"}, {"price": 1.5, "id": "1"}})
+	if err != nil {
+		t.Fatalf("failed to checksum records: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("expected the checksum not to depend on order, got %s and %s", first, second)
+	}
+
+	third, err := checksum([]map[string]interface{}{{"id": "1", "price": 2.5}, {"id": "2"}})
+	if err != nil {
+		t.Fatalf("failed to checksum records: %v", err)
+	}
+
+	if first == third {
+		t.Errorf("expected different records to have different checksums, got %s", first)
+	}
+}
+
+func TestVerifyWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	for _, tcase := range []struct {
+		name          string
+		verify        string
+		data          []string
+		written       int64
+		discrepancies int
+	}{
+		{
+			name:    "rows",
+			verify:  config.VerifyRows,
+			data:    []string{`[{"id":"1"},{"id":"2"}]`, `[{"id":"3"}]`},
+			written: 3,
+		},
+		{
+			name:          "missing rows",
+			verify:        config.VerifyRows,
+			data:          []string{`[{"id":"1"},{"id":"2"}]`},
+			written:       1,
+			discrepancies: 1,
+		},
+		{
+			// Checksums are skipped for storage that cannot be queried.
+			name:    "checksum",
+			verify:  config.VerifyChecksum,
+			data:    []string{`[{"id":"1"}]`},
+			written: 1,
+		},
+	} {
+		repo, err := repository.New(ctx, "file://"+t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+
+		txn := &requestTxn{req: &config.Request{PrimaryKey: []string{"id"}}, table: "candles", verify: tcase.verify}
+		for _, data := range tcase.data {
+			if err := txn.verifiedRecords([]byte(data)); err != nil {
+				t.Fatalf("%s: failed to count records: %v", tcase.name, err)
+			}
+		}
+
+		txn.writes = []*destinationWrites{{
+			repo:   &destinationRepo{GenericService: repo, dest: &config.Destination{}},
+			totals: &proto.UpsertResponse{UpsertedCount: tcase.written},
+		}}
+
+		txn.verifyWrites(ctx, logger)
+
+		if len(txn.discrepancies) != tcase.discrepancies {
+			t.Errorf("%s: expected %d discrepancies, got %v", tcase.name, tcase.discrepancies, txn.discrepancies)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+)
+
+// repositoryWorker is the name of the worker that writes the requests of a run to storage in a single transaction.
+const repositoryWorker = "repository worker"
+
+// webWorkerName will return the name of the web worker with the ID.
+func webWorkerName(workerID int) string {
+	return fmt.Sprintf("web worker %d", workerID)
+}
+
+// repositoryWorkerName will return the name of the repository worker with the ID, which writes requests to storage
+// concurrently with the other repository workers.
+func repositoryWorkerName(workerID int) string {
+	return fmt.Sprintf("%s %d", repositoryWorker, workerID)
+}
+
+// workerProgress is what a worker is doing, and when it last made progress.
+type workerProgress struct {
+	activity string
+	busy     bool
+	last     time.Time
+	stalled  bool
+}
+
+// watchdog watches the workers of a run. A worker that is busy, but has not made progress for the stall period, is
+// logged as stalled, and fails the run if the watchdog fails runs. Workers that are waiting for work are idle, and
+// never stall.
+type watchdog struct {
+	stall  time.Duration
+	fail   bool
+	logger tools.Logger
+
+	mutex   sync.Mutex
+	workers map[string]*workerProgress
+	cancel  context.CancelFunc
+
+	// stalled is the first worker that stalled, once the run has been failed because of it.
+	stalled string
+}
+
+// newWatchdog will return the watchdog of the run, or nil if the configuration does not have one.
+func newWatchdog(cfg *config.Config) (*watchdog, error) {
+	if cfg.Watchdog == nil {
+		return nil, nil
+	}
+
+	stall, err := cfg.Watchdog.StallDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	return &watchdog{
+		stall:   stall,
+		fail:    cfg.Watchdog.Fail,
+		logger:  cfg.Logger,
+		workers: make(map[string]*workerProgress),
+	}, nil
+}
+
+// watch will check the workers until the returned function is called. The returned context is cancelled if a worker
+// stalls and the watchdog fails runs. A nil watchdog returns the context as is.
+func (dog *watchdog) watch(ctx context.Context) (context.Context, func()) {
+	if dog == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	dog.mutex.Lock()
+	dog.cancel = cancel
+	dog.mutex.Unlock()
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(dog.stall / 4)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				dog.check(now)
+			}
+		}
+	}()
+
+	return ctx, func() {
+		ticker.Stop()
+		close(done)
+		cancel()
+	}
+}
+
+// busy will mark the worker as busy with the activity, which is progress.
+func (dog *watchdog) busy(worker, activity string) {
+	if dog == nil {
+		return
+	}
+
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+
+	progress := dog.progress(worker)
+	progress.activity = activity
+	progress.busy = true
+	dog.progressed(worker, progress)
+}
+
+// beat will record that the worker made progress with its activity.
+func (dog *watchdog) beat(worker string) {
+	if dog == nil {
+		return
+	}
+
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+
+	dog.progressed(worker, dog.progress(worker))
+}
+
+// idle will mark the worker as waiting for work.
+func (dog *watchdog) idle(worker string) {
+	if dog == nil {
+		return
+	}
+
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+
+	progress := dog.progress(worker)
+	progress.busy = false
+	dog.progressed(worker, progress)
+}
+
+// progress will return the progress of the worker, adding it if it is new.
+func (dog *watchdog) progress(worker string) *workerProgress {
+	progress, ok := dog.workers[worker]
+	if !ok {
+		progress = &workerProgress{}
+		dog.workers[worker] = progress
+	}
+
+	return progress
+}
+
+// progressed will record that the worker made progress, logging it if the worker had stalled.
+func (dog *watchdog) progressed(worker string, progress *workerProgress) {
+	now := time.Now()
+
+	if progress.stalled {
+		msg := fmt.Sprintf("%s made progress again after %v", worker, now.Sub(progress.last).Round(time.Second))
+		tools.LogFormatter{Msg: msg}.Log(dog.logger, tools.LogLevelInfo)
+
+		progress.stalled = false
+	}
+
+	progress.last = now
+}
+
+// check will log the workers that are busy and have not made progress for the stall period, failing the run if the
+// watchdog fails runs. A heartbeat with the number of busy workers is logged at debug level.
+func (dog *watchdog) check(now time.Time) {
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+
+	workers := make([]string, 0, len(dog.workers))
+	for worker := range dog.workers {
+		workers = append(workers, worker)
+	}
+
+	sort.Strings(workers)
+
+	busy := 0
+
+	for _, worker := range workers {
+		progress := dog.workers[worker]
+		if !progress.busy {
+			continue
+		}
+
+		busy++
+
+		idle := now.Sub(progress.last)
+		if progress.stalled || idle < dog.stall {
+			continue
+		}
+
+		progress.stalled = true
+
+		msg := fmt.Sprintf("%s has made no progress for %v while %s", worker, idle.Round(time.Second),
+			progress.activity)
+		tools.LogFormatter{Msg: msg}.Log(dog.logger, tools.LogLevelError)
+
+		if dog.fail && dog.stalled == "" {
+			dog.stalled = msg
+			dog.cancel()
+		}
+	}
+
+	msg := fmt.Sprintf("heartbeat: %d of %d workers busy", busy, len(workers))
+	tools.LogFormatter{Msg: msg}.Log(dog.logger, tools.LogLevelDebug)
+}
+
+// err will return the error of a run that was failed by the watchdog, or nil if it was not.
+func (dog *watchdog) err() error {
+	if dog == nil {
+		return nil
+	}
+
+	dog.mutex.Lock()
+	defer dog.mutex.Unlock()
+
+	if dog.stalled == "" {
+		return nil
+	}
+
+	return classify(ErrStalled, fmt.Errorf("run failed by its watchdog: %s", dog.stalled))
+}
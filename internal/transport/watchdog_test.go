@@ -0,0 +1,103 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/config"
+	"github.com/alpstable/gidari/tools"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWatchdog(t *testing.T) {
+	t.Parallel()
+
+	logger := tools.NewLogrusLogger(logrus.New())
+	logger.SetOutput(io.Discard)
+
+	newDog := func(t *testing.T, fail bool) *watchdog {
+		t.Helper()
+
+		dog, err := newWatchdog(&config.Config{Watchdog: &config.Watchdog{Stall: "1m", Fail: fail}, Logger: logger})
+		if err != nil {
+			t.Fatalf("failed to create watchdog: %v", err)
+		}
+
+		return dog
+	}
+
+	t.Run("stalled", func(t *testing.T) {
+		t.Parallel()
+
+		dog := newDog(t, false)
+		dog.busy(repositoryWorker, `writing "candles"`)
+		dog.busy(webWorkerName(1), "fetching")
+		dog.idle(webWorkerName(1))
+
+		dog.check(time.Now().Add(time.Minute))
+
+		if !dog.workers[repositoryWorker].stalled || dog.workers[webWorkerName(1)].stalled {
+			t.Errorf("expected only the busy worker to stall, got %+v", dog.workers)
+		}
+
+		// A worker that makes progress again is no longer stalled.
+		dog.beat(repositoryWorker)
+
+		if dog.workers[repositoryWorker].stalled {
+			t.Errorf("expected the worker to make progress again")
+		}
+
+		if err := dog.err(); err != nil {
+			t.Errorf("expected a watchdog that does not fail runs not to fail the run, got %v", err)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		t.Parallel()
+
+		dog := newDog(t, true)
+
+		ctx, stop := dog.watch(context.Background())
+		defer stop()
+
+		dog.busy(webWorkerName(2), "fetching")
+		dog.check(time.Now().Add(30 * time.Second))
+
+		if ctx.Err() != nil || dog.err() != nil {
+			t.Fatalf("expected the worker not to stall before the stall period")
+		}
+
+		dog.check(time.Now().Add(time.Minute))
+
+		if ctx.Err() == nil || !errors.Is(dog.err(), ErrStalled) {
+			t.Errorf("expected the run to fail with %v, got %v", ErrStalled, dog.err())
+		}
+	})
+
+	t.Run("nil watchdog", func(t *testing.T) {
+		t.Parallel()
+
+		var dog *watchdog
+
+		ctx, stop := dog.watch(context.Background())
+		defer stop()
+
+		dog.busy(repositoryWorker, "writing")
+
+		if ctx.Err() != nil || dog.err() != nil {
+			t.Errorf("expected a nil watchdog to do nothing")
+		}
+	})
+}
@@ -31,6 +31,7 @@ type APIKey struct {
 	passphrase string
 	secret     string
 	url        *url.URL
+	base       http.RoundTripper
 }
 
 // NewAPIKey will return an APIKey authentication transport.
@@ -66,6 +67,14 @@ func (auth *APIKey) SetURL(u string) *APIKey {
 	return auth
 }
 
+// SetTransport will set the transport that the authorized requests are made with, e.g. to share its pooled
+// connections. The default transport of the http package is used if it is not set.
+func (auth *APIKey) SetTransport(base http.RoundTripper) *APIKey {
+	auth.base = base
+
+	return auth
+}
+
 // RoundTrip authorizes the request with a signed API Key Authorization header.
 func (auth *APIKey) RoundTrip(req *http.Request) (*http.Response, error) {
 	if auth.url == nil {
@@ -91,7 +100,7 @@ func (auth *APIKey) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Add("cb-access-sign", sig)
 	req.Header.Add("cb-access-timestamp", timestamp)
 
-	rsp, err := http.DefaultTransport.RoundTrip(req)
+	rsp, err := roundTrip(auth.base, req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
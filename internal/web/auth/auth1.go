@@ -77,6 +77,7 @@ type Auth1 struct {
 	consumerKey       string
 	consumerSecret    string
 	url               *url.URL
+	base              http.RoundTripper
 }
 
 // NewAuth1 will return an OAuth1 http transpoauth.
@@ -84,6 +85,14 @@ func NewAuth1() *Auth1 {
 	return new(Auth1)
 }
 
+// SetTransport will set the transport that the authorized requests are made with, e.g. to share its pooled
+// connections. The default transport of the http package is used if it is not set.
+func (auth *Auth1) SetTransport(base http.RoundTripper) *Auth1 {
+	auth.base = base
+
+	return auth
+}
+
 // RoundTrip authorizes the request with a signed OAuth1 Authorization header.
 func (auth *Auth1) RoundTrip(req *http.Request) (*http.Response, error) {
 	if auth.url == nil {
@@ -98,7 +107,7 @@ func (auth *Auth1) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, err
 	}
 
-	rsp, err := http.DefaultTransport.RoundTrip(req)
+	rsp, err := roundTrip(auth.base, req)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %w", err, ErrRequestFailed)
 	}
@@ -17,6 +17,7 @@ import (
 type Auth2 struct {
 	bearer string
 	url    *url.URL
+	base   http.RoundTripper
 }
 
 // NewAuth2 will return an OAuth2 http transport.
@@ -38,6 +39,14 @@ func (auth *Auth2) SetURL(u string) *Auth2 {
 	return auth
 }
 
+// SetTransport will set the transport that the authorized requests are made with, e.g. to share its pooled
+// connections. The default transport of the http package is used if it is not set.
+func (auth *Auth2) SetTransport(base http.RoundTripper) *Auth2 {
+	auth.base = base
+
+	return auth
+}
+
 // RoundTrip authorizes the request with a signed OAuth1 Authorization header using the author and TokenSource.
 func (auth *Auth2) RoundTrip(req *http.Request) (*http.Response, error) {
 	if auth.url == nil {
@@ -48,7 +57,7 @@ func (auth *Auth2) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.URL.Host = auth.url.Host
 	req.Header.Set(authorizationHeaderParam, fmt.Sprintf("%s %s", bearerHeaderPrefix, auth.bearer))
 
-	rsp, err := http.DefaultTransport.RoundTrip(req)
+	rsp, err := roundTrip(auth.base, req)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
 	}
@@ -20,3 +20,13 @@ var (
 type Transport interface {
 	http.RoundTripper
 }
+
+// roundTrip will make the request with the base transport, or with the default transport of the http package if the
+// base is nil.
+func roundTrip(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
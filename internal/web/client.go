@@ -14,6 +14,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/alpstable/gidari/internal/web/auth"
 	"golang.org/x/time/rate"
@@ -34,6 +35,9 @@ var (
 
 	// ErrMissingFetchConfigField is returned when a required field is missing.
 	ErrMissingFetchConfigField = errors.New("missing required field on FetchConfig")
+
+	// ErrUnauthorized is matched by the errors of responses that the web API rejected for their credentials.
+	ErrUnauthorized = errors.New("unauthorized")
 )
 
 // CreateRequestError is returned when the request fails to create.
@@ -51,15 +55,48 @@ func MissingFetchConfigFieldError(field string) error {
 	return fmt.Errorf("%w: %q", ErrMissingFetchConfigField, field)
 }
 
-// GettingResponseError is returned when the response fails to get.
+// GettingResponseError is returned when the response fails to get. It also matches ErrUnauthorized if the web API
+// rejected the credentials of the request.
 func GettingResponseError(rsp *http.Response) error {
 	if _, err := io.ReadAll(rsp.Body); err != nil {
 		return fmt.Errorf("%w: %v", ErrGettingResponse, err)
 	}
 
-	return fmt.Errorf("%w: %v", ErrGettingResponse, rsp.Status)
+	var err error = &statusError{code: rsp.StatusCode, err: fmt.Errorf("%w: %v", ErrGettingResponse, rsp.Status)}
+	if rsp.StatusCode == http.StatusUnauthorized || rsp.StatusCode == http.StatusForbidden {
+		return &unauthorizedError{err: err}
+	}
+
+	return err
+}
+
+// StatusCode will return the status code of the response that the error is for, or zero if it is not the error of a
+// response, e.g. to group the errors of a run by status.
+func StatusCode(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code
+	}
+
+	return 0
+}
+
+// statusError is the error of a response that was rejected by the web API, with the status code of the response.
+type statusError struct {
+	code int
+	err  error
 }
 
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// unauthorizedError is an error that matches ErrUnauthorized, while keeping the error it wraps.
+type unauthorizedError struct{ err error }
+
+func (e *unauthorizedError) Error() string        { return e.err.Error() }
+func (e *unauthorizedError) Unwrap() error        { return e.err }
+func (e *unauthorizedError) Is(target error) bool { return target == ErrUnauthorized }
+
 // Client is a wrapper around the http.Client that will handle authentication and rate limiting.
 type Client struct{ http.Client }
 
@@ -101,11 +138,25 @@ func validateResponse(res *http.Response) error {
 	return nil
 }
 
+// Limiter limits the requests to a web API beyond the rate limiter of a fetch, e.g. across processes.
+type Limiter interface {
+	// Wait will block until a request may be made, or return an error if it may not.
+	Wait(ctx context.Context) error
+}
+
 type FetchConfig struct {
 	C           *Client
 	Method      string
 	URL         *url.URL
 	RateLimiter *rate.Limiter
+
+	// SharedLimiter is waited for after the rate limiter, if it is set, e.g. to stay under a rate limit that is
+	// shared with other processes.
+	SharedLimiter Limiter
+
+	// DumpDir is the directory that the request and the raw response are written to, if it is not empty, e.g. to
+	// diagnose the quirks of a web API.
+	DumpDir string
 }
 
 func (cfg *FetchConfig) validate() error {
@@ -135,12 +186,20 @@ type FetchResponse struct {
 
 	// Body is the response body from the server.
 	Body io.ReadCloser
+
+	// RateLimitWait is how long the request waited for the rate limiter.
+	RateLimitWait time.Duration
+
+	// ContentType is the content type of the response, if the server set it.
+	ContentType string
 }
 
-func newFetchResponse(req *http.Request, body io.ReadCloser) *FetchResponse {
+func newFetchResponse(req *http.Request, body io.ReadCloser, wait time.Duration, contentType string) *FetchResponse {
 	return &FetchResponse{
-		Request: req,
-		Body:    body,
+		Request:       req,
+		Body:          body,
+		RateLimitWait: wait,
+		ContentType:   contentType,
 	}
 }
 
@@ -151,10 +210,19 @@ func Fetch(ctx context.Context, cfg *FetchConfig) (*FetchResponse, error) {
 	}
 
 	// If the rate limiter is not set, set it with defaults.
+	start := time.Now()
 	if err := cfg.RateLimiter.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("rate limiter error: %w", err)
 	}
 
+	if cfg.SharedLimiter != nil {
+		if err := cfg.SharedLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("shared rate limiter error: %w", err)
+		}
+	}
+
+	wait := time.Since(start)
+
 	req, err := newHTTPRequest(ctx, cfg.Method, cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -169,11 +237,20 @@ func Fetch(ctx context.Context, cfg *FetchConfig) (*FetchResponse, error) {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 
+	// Responses are dumped before they are validated, so that the bodies of errors are dumped too.
+	if cfg.DumpDir != "" {
+		if err := dump(cfg.DumpDir, rsp); err != nil {
+			rsp.Body.Close()
+
+			return nil, err
+		}
+	}
+
 	if err := validateResponse(rsp); err != nil {
 		rsp.Body.Close()
 
 		return nil, fmt.Errorf("error validating response: %w", err)
 	}
 
-	return newFetchResponse(req, rsp.Body), nil
+	return newFetchResponse(req, rsp.Body, wait, rsp.Header.Get("Content-Type")), nil
 }
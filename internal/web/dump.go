@@ -0,0 +1,81 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// redacted replaces the values of the headers that hold credentials in a dump.
+const redacted = "[REDACTED]"
+
+// credentialHeaders are the parts of the names of the headers whose values are redacted in a dump, e.g.
+// "Authorization" and "CB-ACCESS-KEY".
+var credentialHeaders = []string{"authorization", "cookie", "key", "passphrase", "secret", "sign", "token"}
+
+// unsafeFileChars are the characters of a URL path that are replaced in the name of a dump.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// dumps counts the responses that have been dumped, so that the dumps of a directory are named in order.
+var dumps int64
+
+// dump will write the headers of the request of the response, and the status, headers, and raw body of the response to
+// a new file in the directory, e.g. "000001-products_BTC-USD_candles.http". The values of headers that hold
+// credentials are redacted. The body of the response can still be read once it has been dumped.
+func dump(dir string, rsp *http.Response) error {
+	req := rsp.Request.Clone(rsp.Request.Context())
+
+	for name := range req.Header {
+		if isCredentialHeader(name) {
+			req.Header.Set(name, redacted)
+		}
+	}
+
+	reqDump, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return fmt.Errorf("unable to dump request: %w", err)
+	}
+
+	rspDump, err := httputil.DumpResponse(rsp, true)
+	if err != nil {
+		return fmt.Errorf("unable to dump response: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("unable to create dump directory: %w", err)
+	}
+
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(req.URL.Path, "_"), "_")
+	path := filepath.Join(dir, fmt.Sprintf("%06d-%s.http", atomic.AddInt64(&dumps, 1), name))
+
+	if err := os.WriteFile(path, append(reqDump, rspDump...), 0o600); err != nil {
+		return fmt.Errorf("unable to write dump: %w", err)
+	}
+
+	return nil
+}
+
+// isCredentialHeader will return true if the header holds credentials.
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, part := range credentialHeaders {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+
+	return false
+}
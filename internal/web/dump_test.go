@@ -0,0 +1,107 @@
+//go:build utest
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/web/auth"
+	"golang.org/x/time/rate"
+)
+
+func TestFetchDump(t *testing.T) {
+	t.Parallel()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("product") == "unknown" {
+			rw.WriteHeader(http.StatusNotFound)
+			io.WriteString(rw, `{"message":"NotFound"}`)
+
+			return
+		}
+
+		rw.Header().Set("X-Quirk", "1")
+		io.WriteString(rw, `[{"id":"BTC"}]`)
+	}))
+	defer testServer.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	tripper := auth.NewAuth2()
+	tripper.SetBearer("AbCd1234")
+	tripper.SetURL(testServer.URL)
+
+	client, err := NewClient(ctx, tripper)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	for _, query := range []string{"product=BTC", "product=unknown"} {
+		uri, err := url.Parse(testServer.URL + "/products/candles?" + query)
+		if err != nil {
+			t.Fatalf("error parsing url: %v", err)
+		}
+
+		rsp, err := Fetch(ctx, &FetchConfig{
+			C:           client,
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+			DumpDir:     dir,
+		})
+		if query == "product=unknown" {
+			if code := StatusCode(err); code != http.StatusNotFound {
+				t.Fatalf("expected the not found response to fail with its status, got %d: %v", code, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("fetch error: %v", err)
+		}
+
+		// The body is still read once it has been dumped.
+		body, err := io.ReadAll(rsp.Body)
+		if err != nil || string(body) != `[{"id":"BTC"}]` {
+			t.Errorf("expected the body to be read, got %q: %v", body, err)
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*-products_candles.http"))
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("expected a dump of each response, got %v: %v", paths, err)
+	}
+
+	for idx, want := range []string{`[{"id":"BTC"}]`, `{"message":"NotFound"}`} {
+		dumped, err := os.ReadFile(paths[idx])
+		if err != nil {
+			t.Fatalf("failed to read dump: %v", err)
+		}
+
+		text := string(dumped)
+		if !strings.Contains(text, "Authorization: [REDACTED]") || strings.Contains(text, "AbCd1234") {
+			t.Errorf("expected the credentials to be redacted, got %q", text)
+		}
+
+		if !strings.HasSuffix(text, want) {
+			t.Errorf("expected the dump to end with the body %q, got %q", want, text)
+		}
+	}
+}
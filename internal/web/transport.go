@@ -0,0 +1,94 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package web
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// defaultMaxIdleConns is the number of idle connections that a transport keeps to all hosts, at least.
+const defaultMaxIdleConns = 100
+
+// Transport is an HTTP transport that is tuned to be shared by every web worker of a run, so that their connections
+// to the web API are pooled rather than opened for each request. It counts the connections that its requests are
+// made on.
+type Transport struct {
+	base *http.Transport
+
+	requests int64
+	opened   int64
+	reused   int64
+}
+
+// TransportMetrics are the counts of the requests of a transport, and of the connections that they were made on.
+type TransportMetrics struct {
+	// Requests is the number of requests that were made.
+	Requests int64
+
+	// Opened is the number of connections that were opened for a request, and Reused is the number of requests
+	// that were made on a pooled connection.
+	Opened int64
+	Reused int64
+}
+
+// NewTransport will return a transport that makes at most maxConnsPerHost concurrent connections to each host, and
+// keeps as many of them idle for the next requests. A max of zero does not limit the connections.
+func NewTransport(maxConnsPerHost int) *Transport {
+	base, _ := http.DefaultTransport.(*http.Transport)
+	base = base.Clone()
+
+	base.MaxConnsPerHost = maxConnsPerHost
+	base.MaxIdleConnsPerHost = maxConnsPerHost
+
+	if maxConnsPerHost > defaultMaxIdleConns {
+		base.MaxIdleConns = maxConnsPerHost
+	}
+
+	return &Transport{base: base}
+}
+
+// RoundTrip will make the request on a pooled connection, or on a new connection if none is idle.
+func (transport *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&transport.requests, 1)
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddInt64(&transport.reused, 1)
+			} else {
+				atomic.AddInt64(&transport.opened, 1)
+			}
+		},
+	}
+
+	return transport.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// Metrics will return the counts of the requests of the transport so far. A nil transport has no requests.
+func (transport *Transport) Metrics() TransportMetrics {
+	if transport == nil {
+		return TransportMetrics{}
+	}
+
+	return TransportMetrics{
+		Requests: atomic.LoadInt64(&transport.requests),
+		Opened:   atomic.LoadInt64(&transport.opened),
+		Reused:   atomic.LoadInt64(&transport.reused),
+	}
+}
+
+// CloseIdleConnections will close the connections of the transport that are idle, e.g. once a run is done.
+func (transport *Transport) CloseIdleConnections() {
+	if transport == nil {
+		return
+	}
+
+	transport.base.CloseIdleConnections()
+}
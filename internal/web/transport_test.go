@@ -0,0 +1,72 @@
+//go:build utest
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/web/auth"
+	"golang.org/x/time/rate"
+)
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`[]`))
+	}))
+	t.Cleanup(testServer.Close)
+
+	uri, err := url.Parse(testServer.URL)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	transport := NewTransport(1)
+	t.Cleanup(transport.CloseIdleConnections)
+
+	// The authorized requests are made with the shared transport too.
+	client, err := NewClient(ctx, auth.NewBasic().SetURL(testServer.URL).SetTransport(transport))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	for idx := 0; idx < 3; idx++ {
+		rsp, err := Fetch(ctx, &FetchConfig{
+			C:           client,
+			Method:      http.MethodGet,
+			URL:         uri,
+			RateLimiter: rate.NewLimiter(rate.Inf, 1),
+		})
+		if err != nil {
+			t.Fatalf("failed to fetch: %v", err)
+		}
+
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		rsp.Body.Close()
+	}
+
+	expected := TransportMetrics{Requests: 3, Opened: 1, Reused: 2}
+	if metrics := transport.Metrics(); metrics != expected {
+		t.Errorf("expected %+v, got %+v", expected, metrics)
+	}
+
+	var nilTransport *Transport
+	if metrics := nilTransport.Metrics(); metrics != (TransportMetrics{}) {
+		t.Errorf("expected no metrics for a nil transport, got %+v", metrics)
+	}
+}
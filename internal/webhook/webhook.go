@@ -0,0 +1,443 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/web/auth"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+
+	// maxBackoff is the longest time to wait between attempts.
+	maxBackoff = time.Minute
+)
+
+var (
+	ErrInvalidHeader       = fmt.Errorf("invalid webhook header")
+	ErrTransactionNotFound = fmt.Errorf("transaction not found")
+	ErrUnexpectedStatus    = fmt.Errorf("unexpected status")
+)
+
+// UnexpectedStatusError is returned when the webhook responds with a status that is not 2xx.
+func UnexpectedStatusError(rsp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+	return fmt.Errorf("%w: %s: %s", ErrUnexpectedStatus, rsp.Status, strings.TrimSpace(string(msg)))
+}
+
+// webhookTxType is a type alias for the webhook transaction type.
+type webhookTxType uint8
+
+const (
+	basicWebhookTxID webhookTxType = iota
+)
+
+// batch is the encoded body of a request for a single table.
+type batch struct {
+	table string
+	body  []byte
+}
+
+// stage holds the batches of a transaction until it is committed.
+type stage struct {
+	mutex   sync.Mutex
+	batches []batch
+}
+
+// payload is the request body sent to the webhook.
+type payload struct {
+	Table   string                   `json:"table"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// Webhook is a storage device that sends each batch of records to an HTTP endpoint as a POST request. Requests are
+// retried with exponential backoff on network errors, "429 Too Many Requests" and 5xx responses.
+type Webhook struct {
+	client  *http.Client
+	url     string
+	headers http.Header
+	retries int
+	backoff time.Duration
+
+	// sleep waits between attempts, returning early if the context is done.
+	sleep func(context.Context, time.Duration) error
+
+	sizeMutex sync.Mutex
+
+	// sizes are the number of bytes sent for each table.
+	sizes map[string]int64
+
+	// activeTx are the transactions that are currently active on this storage device. Batches written within the
+	// context of a transaction are held in memory until the transaction is committed.
+	activeTx sync.Map
+}
+
+// New will return a new Webhook storage device. The connection string is of the form
+//
+//	webhook://[user:password@]host/path?token=secret&retries=3&backoff=1s&header=X-Source:gidari
+//
+// Requests are made over HTTPS unless "insecure" is "true". If "token" is set, requests are authorized with a bearer
+// token, otherwise the user and password are used for basic authentication. "header" may be repeated to add custom
+// headers to every request. "retries" is the number of times to retry a failed request, and "backoff" is the wait
+// before the first retry, which doubles after each attempt.
+func New(_ context.Context, dns string) (*Webhook, error) {
+	uri, err := url.Parse(dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse webhook connection string: %w", err)
+	}
+
+	query := uri.Query()
+
+	endpoint := url.URL{Scheme: "https", Host: uri.Host, Path: uri.Path}
+	if query.Get("insecure") == "true" {
+		endpoint.Scheme = "http"
+	}
+
+	hook := &Webhook{
+		client:   &http.Client{},
+		url:      endpoint.String(),
+		headers:  make(http.Header),
+		retries:  defaultRetries,
+		backoff:  defaultBackoff,
+		sleep:    sleep,
+		sizes:    make(map[string]int64),
+		activeTx: sync.Map{},
+	}
+
+	switch {
+	case query.Get("token") != "":
+		hook.client.Transport = auth.NewAuth2().SetBearer(query.Get("token")).SetURL(hook.url)
+	case uri.User != nil:
+		password, _ := uri.User.Password()
+		hook.client.Transport = auth.NewBasic().SetEmail(uri.User.Username()).SetPassword(password).SetURL(hook.url)
+	}
+
+	for _, header := range query["header"] {
+		name, val, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, header)
+		}
+
+		hook.headers.Add(strings.TrimSpace(name), strings.TrimSpace(val))
+	}
+
+	if retries := query.Get("retries"); retries != "" {
+		if hook.retries, err = strconv.Atoi(retries); err != nil {
+			return nil, fmt.Errorf("unable to parse retries: %w", err)
+		}
+	}
+
+	if backoff := query.Get("backoff"); backoff != "" {
+		if hook.backoff, err = time.ParseDuration(backoff); err != nil {
+			return nil, fmt.Errorf("unable to parse backoff: %w", err)
+		}
+	}
+
+	return hook, nil
+}
+
+// sleep will wait for the duration, returning early if the context is done.
+func sleep(ctx context.Context, dur time.Duration) error {
+	timer := time.NewTimer(dur)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("context done while waiting to retry: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Close will close the idle connections of the HTTP client.
+func (hook *Webhook) Close() { hook.client.CloseIdleConnections() }
+
+// IsNoSQL returns "true" since records are sent as schemaless JSON.
+func (hook *Webhook) IsNoSQL() bool { return true }
+
+// Type returns the type of storage.
+func (hook *Webhook) Type() uint8 { return proto.WebhookType }
+
+// Ping is a no-op since webhooks are not required to respond to anything other than a batch of records.
+func (hook *Webhook) Ping() error { return nil }
+
+// retryable returns "true" if a request with the response status should be retried.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryAfter returns the wait requested by the "Retry-After" header of the response in seconds, if any.
+func retryAfter(rsp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(rsp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+
+	return time.Duration(secs) * time.Second, true
+}
+
+// post will send the body to the webhook, retrying failed requests.
+func (hook *Webhook) post(ctx context.Context, body []byte) error {
+	wait := hook.backoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("unable to create request: %w", err)
+		}
+
+		for name, vals := range hook.headers {
+			req.Header[name] = vals
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		rsp, err := hook.client.Do(req)
+		if err == nil {
+			if rsp.StatusCode >= http.StatusOK && rsp.StatusCode < http.StatusMultipleChoices {
+				return rsp.Body.Close()
+			}
+
+			err = UnexpectedStatusError(rsp)
+			rsp.Body.Close()
+
+			if !retryable(rsp.StatusCode) {
+				return err
+			}
+		}
+
+		if attempt >= hook.retries {
+			return fmt.Errorf("failed after %d attempts: %w", attempt+1, err)
+		}
+
+		delay := wait
+		if rsp != nil {
+			if after, ok := retryAfter(rsp); ok {
+				delay = after
+			}
+		}
+
+		if err := hook.sleep(ctx, delay); err != nil {
+			return err
+		}
+
+		if wait *= 2; wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+}
+
+// send will post the batch and record the number of bytes sent for the table.
+func (hook *Webhook) send(ctx context.Context, bch batch) error {
+	if err := hook.post(ctx, bch.body); err != nil {
+		return fmt.Errorf("unable to send %s records: %w", bch.table, err)
+	}
+
+	hook.sizeMutex.Lock()
+	defer hook.sizeMutex.Unlock()
+
+	hook.sizes[bch.table] += int64(len(bch.body))
+
+	return nil
+}
+
+// getStage will return the transaction stage assigned to the context, if there is one.
+func (hook *Webhook) getStage(ctx context.Context) (*stage, error) {
+	txID, ok := ctx.Value(basicWebhookTxID).(string)
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := hook.activeTx.Load(txID)
+	if !ok {
+		return nil, nil
+	}
+
+	stg, ok := val.(*stage)
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+
+	return stg, nil
+}
+
+// write will send the records to the webhook, or stage them if the context has a transaction.
+func (hook *Webhook) write(ctx context.Context, table string, records []*structpb.Struct) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	pld := payload{Table: table, Records: make([]map[string]interface{}, len(records))}
+	for idx, record := range records {
+		pld.Records[idx] = record.AsMap()
+	}
+
+	body, err := json.Marshal(pld)
+	if err != nil {
+		return fmt.Errorf("unable to encode records: %w", err)
+	}
+
+	stg, err := hook.getStage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stg == nil {
+		return hook.send(ctx, batch{table: table, body: body})
+	}
+
+	stg.mutex.Lock()
+	defer stg.mutex.Unlock()
+
+	stg.batches = append(stg.batches, batch{table: table, body: body})
+
+	return nil
+}
+
+// Upsert will send the records on the request to the webhook.
+func (hook *Webhook) Upsert(ctx context.Context, req *proto.UpsertRequest) (*proto.UpsertResponse, error) {
+	records, err := proto.DecodeUpsertRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if err := hook.write(ctx, req.GetTable(), records); err != nil {
+		return nil, err
+	}
+
+	return &proto.UpsertResponse{
+		UpsertedCount: int64(len(records)),
+		ReceivedCount: int64(len(records)),
+		InsertedCount: int64(len(records)),
+	}, nil
+}
+
+// UpsertBinary will send the "property bag" records on the request to the webhook.
+func (hook *Webhook) UpsertBinary(ctx context.Context,
+	req *proto.UpsertBinaryRequest,
+) (*proto.UpsertBinaryResponse, error) {
+	records, err := proto.DecodeUpsertBinaryRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode records: %w", err)
+	}
+
+	if err := hook.write(ctx, req.GetTable(), records); err != nil {
+		return nil, err
+	}
+
+	return &proto.UpsertBinaryResponse{}, nil
+}
+
+// Truncate is a no-op since records that have been sent to a webhook cannot be removed.
+func (hook *Webhook) Truncate(_ context.Context, _ *proto.TruncateRequest) (*proto.TruncateResponse, error) {
+	return &proto.TruncateResponse{}, nil
+}
+
+// ListTables will return the tables that have been sent to the webhook by this storage device, along with the number
+// of bytes sent for each.
+func (hook *Webhook) ListTables(_ context.Context) (*proto.ListTablesResponse, error) {
+	hook.sizeMutex.Lock()
+	defer hook.sizeMutex.Unlock()
+
+	rsp := &proto.ListTablesResponse{TableSet: make(map[string]*proto.Table)}
+	for table, size := range hook.sizes {
+		rsp.TableSet[table] = &proto.Table{Size: size}
+	}
+
+	return rsp, nil
+}
+
+// ListPrimaryKeys will return an empty list of primary keys for every table, since a webhook has no notion of primary
+// keys.
+func (hook *Webhook) ListPrimaryKeys(ctx context.Context) (*proto.ListPrimaryKeysResponse, error) {
+	tables, err := hook.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+
+	rsp := &proto.ListPrimaryKeysResponse{PKSet: make(map[string]*proto.PrimaryKeys)}
+	for table := range tables.GetTableSet() {
+		rsp.PKSet[table] = &proto.PrimaryKeys{}
+	}
+
+	return rsp, nil
+}
+
+// commitStage will send all of the staged batches in the order they were written.
+func (hook *Webhook) commitStage(ctx context.Context, stg *stage) error {
+	for _, bch := range stg.batches {
+		if err := hook.send(ctx, bch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartTx will start a transaction on the webhook. Batches written to the transaction are held in memory, and sent on
+// commit or discarded on rollback. Batches that were sent before a failed request of a commit cannot be recalled.
+func (hook *Webhook) StartTx(ctx context.Context) (*proto.Txn, error) {
+	txn := &proto.Txn{
+		FunctionCh: make(chan proto.TxnChanFn),
+		DoneCh:     make(chan error, 1),
+		CommitCh:   make(chan bool, 1),
+	}
+
+	txnID := uuid.New().String()
+	stg := &stage{}
+
+	hook.activeTx.Store(txnID, stg)
+
+	// Create a copy of the parent context with a transaction ID.
+	hookCtx := context.WithValue(ctx, basicWebhookTxID, txnID)
+
+	go func() {
+		defer hook.activeTx.Delete(txnID)
+
+		var err error
+
+		for fn := range txn.FunctionCh {
+			if err != nil {
+				continue
+			}
+
+			err = fn(hookCtx, hook)
+		}
+
+		if err != nil {
+			txn.DoneCh <- err
+
+			return
+		}
+
+		if <-txn.CommitCh {
+			txn.DoneCh <- hook.commitStage(ctx, stg)
+		} else {
+			txn.DoneCh <- nil
+		}
+	}()
+
+	return txn, nil
+}
@@ -0,0 +1,170 @@
+//go:build utests
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alpstable/gidari/internal/proto"
+)
+
+// fakeWebhook records the payloads it receives, failing the first "failures" requests with the status.
+type fakeWebhook struct {
+	mutex    sync.Mutex
+	failures int
+	status   int
+	attempts int
+	payloads []payload
+	auth     string
+	header   string
+}
+
+func (srv *fakeWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	srv.attempts++
+	srv.auth = r.Header.Get("Authorization")
+	srv.header = r.Header.Get("X-Source")
+
+	if srv.attempts <= srv.failures {
+		w.WriteHeader(srv.status)
+
+		return
+	}
+
+	var pld payload
+	if err := json.NewDecoder(r.Body).Decode(&pld); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	srv.payloads = append(srv.payloads, pld)
+}
+
+// newTestWebhook will return a webhook storage device for the server that does not wait between retries.
+func newTestWebhook(t *testing.T, server *httptest.Server, params string) *Webhook {
+	t.Helper()
+
+	dns := "webhook://" + strings.TrimPrefix(server.URL, "http://") + "/ingest?insecure=true&" + params
+
+	hook, err := New(context.Background(), dns)
+	if err != nil {
+		t.Fatalf("failed to create webhook storage: %v", err)
+	}
+
+	hook.sleep = func(context.Context, time.Duration) error { return nil }
+
+	return hook
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []struct {
+		name     string
+		params   string
+		failures int
+		status   int
+		err      error
+		attempts int
+	}{
+		{name: "bearer token", params: "token=secret&header=X-Source:gidari", attempts: 1},
+		{name: "retry", params: "retries=2", failures: 2, status: http.StatusServiceUnavailable, attempts: 3},
+		{
+			name: "retries exhausted", params: "retries=1", failures: 2, status: http.StatusTooManyRequests,
+			err: ErrUnexpectedStatus, attempts: 2,
+		},
+		{
+			name: "client error", params: "retries=3", failures: 1, status: http.StatusBadRequest,
+			err: ErrUnexpectedStatus, attempts: 1,
+		},
+	} {
+		tcase := tcase
+
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := &fakeWebhook{failures: tcase.failures, status: tcase.status}
+
+			server := httptest.NewServer(srv)
+			defer server.Close()
+
+			hook := newTestWebhook(t, server, tcase.params)
+
+			_, err := hook.Upsert(context.Background(), &proto.UpsertRequest{
+				Table: "candles",
+				Data:  []byte(`[{"id":1},{"id":2}]`),
+			})
+			if !errors.Is(err, tcase.err) {
+				t.Fatalf("expected error %v, got %v", tcase.err, err)
+			}
+
+			if srv.attempts != tcase.attempts {
+				t.Fatalf("expected %d attempts, got %d", tcase.attempts, srv.attempts)
+			}
+
+			if tcase.err != nil {
+				return
+			}
+
+			if len(srv.payloads) != 1 || srv.payloads[0].Table != "candles" || len(srv.payloads[0].Records) != 2 {
+				t.Fatalf("unexpected payloads %v", srv.payloads)
+			}
+
+			if strings.Contains(tcase.params, "token") && (srv.auth != "Bearer secret" || srv.header != "gidari") {
+				t.Fatalf("unexpected authorization %q and header %q", srv.auth, srv.header)
+			}
+		})
+	}
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		srv := &fakeWebhook{}
+
+		server := httptest.NewServer(srv)
+		defer server.Close()
+
+		hook := newTestWebhook(t, server, "")
+
+		txn, err := hook.StartTx(context.Background())
+		if err != nil {
+			t.Fatalf("failed to start transaction: %v", err)
+		}
+
+		for _, table := range []string{"candles", "trades"} {
+			table := table
+
+			txn.Send(func(sctx context.Context, stg proto.Storage) error {
+				_, err := stg.Upsert(sctx, &proto.UpsertRequest{Table: table, Data: []byte(`[{"id":1}]`)})
+
+				return err
+			})
+		}
+
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+
+		if len(srv.payloads) != 2 || srv.payloads[0].Table != "candles" || srv.payloads[1].Table != "trades" {
+			t.Fatalf("unexpected payloads %v", srv.payloads)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+
+// Package storage is the public interface for storage devices. Custom storage devices can be registered for a
+// connection string scheme, so that the scheme can be used in a configuration like any built-in storage:
+//
+//	func init() {
+//		storage.Register("clickhouse", func(ctx context.Context, dns string) (storage.Storage, error) {
+//			return clickhouse.New(ctx, dns)
+//		})
+//	}
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpstable/gidari/internal/proto"
+	"github.com/alpstable/gidari/internal/repository"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Storage is the interface that a storage device must implement.
+type Storage = proto.Storage
+
+// Txn is a transaction on a storage device. Implementations of "StartTx" should run the functions sent to the
+// transaction in order, and then commit or roll back the result of all of them.
+type Txn = proto.Txn
+
+// TxnChanFn is a function that is sent to a transaction.
+type TxnChanFn = proto.TxnChanFn
+
+// Request and response types of the Storage interface.
+type (
+	UpsertRequest           = proto.UpsertRequest
+	UpsertResponse          = proto.UpsertResponse
+	UpsertBinaryRequest     = proto.UpsertBinaryRequest
+	UpsertBinaryResponse    = proto.UpsertBinaryResponse
+	TruncateRequest         = proto.TruncateRequest
+	TruncateResponse        = proto.TruncateResponse
+	ListTablesResponse      = proto.ListTablesResponse
+	ListPrimaryKeysResponse = proto.ListPrimaryKeysResponse
+	ListColumnsResponse     = proto.ListColumnsResponse
+	Table                   = proto.Table
+	PrimaryKeys             = proto.PrimaryKeys
+	Columns                 = proto.Columns
+	QueryRequest            = proto.QueryRequest
+	QueryResponse           = proto.QueryResponse
+	TimeRange               = proto.TimeRange
+	PoolRequest             = proto.PoolRequest
+	DefineTableRequest      = proto.DefineTableRequest
+	ColumnDefinition        = proto.ColumnDefinition
+)
+
+// Types of the columns of a declared table, which each storage device maps to its own types.
+const (
+	ColumnTypeString    = proto.ColumnTypeString
+	ColumnTypeInteger   = proto.ColumnTypeInteger
+	ColumnTypeNumber    = proto.ColumnTypeNumber
+	ColumnTypeDecimal   = proto.ColumnTypeDecimal
+	ColumnTypeBoolean   = proto.ColumnTypeBoolean
+	ColumnTypeTimestamp = proto.ColumnTypeTimestamp
+	ColumnTypeJSON      = proto.ColumnTypeJSON
+)
+
+// ColumnLister is an optional interface for storage devices with a schema, to list the columns of their tables and
+// their types.
+type ColumnLister = proto.ColumnLister
+
+// Querier is an optional interface for storage devices that can read the records of a table back out.
+type Querier = proto.Querier
+
+// Pooler is an optional interface for storage devices with a connection pool that can be configured.
+type Pooler = proto.Pooler
+
+// TableDefiner is an optional interface for storage devices with a schema, to create their tables from the schema that
+// is declared for them instead of inferring it from the records.
+type TableDefiner = proto.TableDefiner
+
+var (
+	// ErrListColumnsNotSupported is returned by "ListColumns" for storage devices that do not implement
+	// "ColumnLister".
+	ErrListColumnsNotSupported = proto.ErrListColumnsNotSupported
+
+	// ErrQueryNotSupported is returned by "Query" for storage devices that do not implement "Querier".
+	ErrQueryNotSupported = proto.ErrQueryNotSupported
+
+	// ErrPoolNotSupported is returned by "ConfigurePool" for storage devices that do not implement "Pooler".
+	ErrPoolNotSupported = proto.ErrPoolNotSupported
+
+	// ErrDefineTableNotSupported is returned by "DefineTable" for storage devices that do not implement
+	// "TableDefiner".
+	ErrDefineTableNotSupported = proto.ErrDefineTableNotSupported
+
+	// ErrTransient is matched by the errors of transactions that are expected to succeed if they are retried.
+	ErrTransient = proto.ErrTransient
+
+	// ErrContention is matched by transient errors of a storage device that is contended, such as lock timeouts.
+	ErrContention = proto.ErrContention
+)
+
+// Transient will return the error so that it matches ErrTransient, e.g. for a deadlock, so that the transaction it
+// occurred in is retried by runs with a retry policy.
+func Transient(err error) error {
+	return proto.Transient(err)
+}
+
+// Contention will return the error so that it matches ErrContention and ErrTransient, e.g. for a lock timeout, so that
+// the writes to the storage device back off before the transaction it occurred in is retried.
+func Contention(err error) error {
+	return proto.Contention(err)
+}
+
+// Factory constructs a storage device from a connection string.
+type Factory func(ctx context.Context, dns string) (Storage, error)
+
+// Register will make a storage device available for connection strings with the scheme, e.g. "clickhouse" for
+// "clickhouse://localhost:9000". Register panics if the scheme is built in or has already been registered, so it
+// should be called from an "init" function.
+func Register(scheme string, factory Factory) {
+	var constructor proto.Constructor
+
+	if factory != nil {
+		constructor = func(ctx context.Context, dns string) (*proto.StorageService, error) {
+			stg, err := factory(ctx, dns)
+			if err != nil {
+				return nil, err
+			}
+
+			return &proto.StorageService{Storage: stg}, nil
+		}
+	}
+
+	if err := proto.RegisterConstructor(scheme, constructor); err != nil {
+		panic(fmt.Sprintf("storage: unable to register %q: %v", scheme, err))
+	}
+}
+
+// New will construct the storage device for the connection string, using either a built-in or a registered storage
+// device.
+func New(ctx context.Context, dns string) (Storage, error) {
+	stg, err := repository.NewStorage(ctx, dns)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create storage: %w", err)
+	}
+
+	return stg, nil
+}
+
+// ListColumns will list the columns of every table on the storage device and their types. If the storage device does
+// not have a schema, an error wrapping ErrListColumnsNotSupported is returned.
+func ListColumns(ctx context.Context, stg Storage) (*ListColumnsResponse, error) {
+	rsp, err := proto.ListColumns(ctx, stg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list columns: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// Query will read the records of a table on the storage device that match the request, e.g. those in a time range:
+//
+//	rsp, err := storage.Query(ctx, stg, &storage.QueryRequest{
+//		Table: "candles",
+//		Range: &storage.TimeRange{Column: "time", Start: time.Now().Add(-time.Hour)},
+//		Limit: 100,
+//	})
+//
+// If the storage device cannot read records, an error wrapping ErrQueryNotSupported is returned.
+func Query(ctx context.Context, stg Storage, req *QueryRequest) (*QueryResponse, error) {
+	rsp, err := proto.Query(ctx, stg, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query: %w", err)
+	}
+
+	return rsp, nil
+}
+
+// ConfigurePool will configure the connection pool of the storage device, e.g. to limit the number of connections
+// that are opened to a database. If the storage device does not have a connection pool that can be configured, an
+// error wrapping ErrPoolNotSupported is returned.
+func ConfigurePool(ctx context.Context, stg Storage, req *PoolRequest) error {
+	if err := proto.ConfigurePool(ctx, stg, req); err != nil {
+		return fmt.Errorf("unable to configure pool: %w", err)
+	}
+
+	return nil
+}
+
+// DefineTable will declare the schema of a table on the storage device, so that the table is created with its columns,
+// types, and primary keys. If the storage device does not create its tables from a declared schema, an error wrapping
+// ErrDefineTableNotSupported is returned.
+func DefineTable(ctx context.Context, stg Storage, req *DefineTableRequest) error {
+	if err := proto.DefineTable(ctx, stg, req); err != nil {
+		return fmt.Errorf("unable to define table: %w", err)
+	}
+
+	return nil
+}
+
+// DecodeUpsertRequest will decode the records on an upsert request.
+func DecodeUpsertRequest(req *UpsertRequest) ([]*structpb.Struct, error) {
+	records, err := proto.DecodeUpsertRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode upsert request: %w", err)
+	}
+
+	return records, nil
+}
+
+// DecodeUpsertBinaryRequest will decode the "property bag" records on an upsert binary request.
+func DecodeUpsertBinaryRequest(req *UpsertBinaryRequest) ([]*structpb.Struct, error) {
+	records, err := proto.DecodeUpsertBinaryRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode upsert binary request: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alpstable/gidari/internal/file"
+)
+
+// registerPanics returns "true" if registering the scheme panics.
+func registerPanics(scheme string, factory Factory) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+
+	Register(scheme, factory)
+
+	return false
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// "testfile" is a custom scheme that writes to a local directory.
+	factory := func(ctx context.Context, dns string) (Storage, error) {
+		return file.New(ctx, "file://"+dir+"?format="+strings.TrimPrefix(dns, "testfile://"))
+	}
+
+	Register("testfile", factory)
+
+	stg, err := New(ctx, "testfile://csv")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	rsp, err := stg.Upsert(ctx, &UpsertRequest{Table: "candles", Data: []byte(`[{"id":1}]`)})
+	if err != nil || rsp.GetUpsertedCount() != 1 {
+		t.Fatalf("failed to upsert: %v", err)
+	}
+
+	tables, err := stg.ListTables(ctx)
+	if err != nil || tables.GetTableSet()["candles"] == nil {
+		t.Fatalf("expected table candles, got %v: %v", tables, err)
+	}
+
+	for _, scheme := range []string{"testfile", "postgresql", "stdout", ""} {
+		if !registerPanics(scheme, factory) {
+			t.Errorf("expected registering %q to panic", scheme)
+		}
+	}
+
+	if !registerPanics("testnil", nil) {
+		t.Errorf("expected registering a nil factory to panic")
+	}
+
+	if _, err := New(ctx, "testunknown://"); err == nil {
+		t.Errorf("expected an error for an unregistered scheme")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	stg, err := New(ctx, "file://"+t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	if _, err := Query(ctx, stg, &QueryRequest{Table: "candles"}); !errors.Is(err, ErrQueryNotSupported) {
+		t.Errorf("expected %v, got %v", ErrQueryNotSupported, err)
+	}
+
+	if _, err := ListColumns(ctx, stg); !errors.Is(err, ErrListColumnsNotSupported) {
+		t.Errorf("expected %v, got %v", ErrListColumnsNotSupported, err)
+	}
+}
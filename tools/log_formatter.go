@@ -22,6 +22,12 @@ type LogFormatter struct {
 	Msg           string
 	UpsertedCount int64
 	MatchedCount  int64
+
+	// Endpoint, Table, and Chunk identify the request, the table in storage, and the URL of the chunk of the request
+	// that the message is about.
+	Endpoint string
+	Table    string
+	Chunk    string
 }
 
 const (
@@ -45,39 +51,108 @@ const (
 
 	// LogFormmaterMatchedCount the label of the matched count.
 	LogFormatterMatchedCount = "c"
+
+	// LogFormatterEndpoint the label of the endpoint.
+	LogFormatterEndpoint = "endpoint"
+
+	// LogFormatterTable the label of the table.
+	LogFormatterTable = "table"
+
+	// LogFormatterChunk the label of the chunk.
+	LogFormatterChunk = "chunk"
+
+	// LogFormatterRunID the label of the run id.
+	LogFormatterRunID = "run"
 )
 
 // String uses the data from the LogFormatter object to build a log message.
 func (lf LogFormatter) String() string {
+	return formatText(lf.Msg, lf.Fields())
+}
+
+// textLabels are the labels of the fields of a message in its text format, in the order they are written.
+var textLabels = []struct {
+	field string
+	label string
+}{
+	{field: "runID", label: LogFormatterRunID},
+	{field: "workerID", label: LogFormatterWorkerID},
+	{field: "worker", label: LogFormatterWorkerName},
+	{field: "duration", label: LogFormatterDuration},
+	{field: "host", label: LogFormatterHostName},
+	{field: "endpoint", label: LogFormatterEndpoint},
+	{field: "table", label: LogFormatterTable},
+	{field: "chunk", label: LogFormatterChunk},
+	{field: "upserted", label: LogFormatterUpsertedCount},
+	{field: "matched", label: LogFormatterMatchedCount},
+}
+
+// formatText will format the message and its fields as text, e.g. "{w:1, worker:web, m:web request completed}".
+func formatText(msg string, fields map[string]interface{}) string {
 	var bldr strings.Builder
 
+	for _, label := range textLabels {
+		if value, ok := fields[label.field]; ok {
+			bldr.WriteString(fmt.Sprintf("%s:%v, ", label.label, value))
+		}
+	}
+
+	if msg != "" {
+		bldr.WriteString(fmt.Sprintf("%s:%s, ", LogFormatterMsg, msg))
+	}
+
+	return fmt.Sprintf("{%s}", strings.TrimSuffix(bldr.String(), ", "))
+}
+
+// Fields will return the data of the log message, without the message, as the fields of a structured log entry.
+func (lf LogFormatter) Fields() map[string]interface{} {
+	fields := make(map[string]interface{})
+
 	if lf.WorkerID > 0 {
-		bldr.WriteString(fmt.Sprintf("%s:%d, ", LogFormatterWorkerID, lf.WorkerID))
+		fields["workerID"] = lf.WorkerID
 	}
 
 	if lf.WorkerName != "" {
-		bldr.WriteString(fmt.Sprintf("%s:%s, ", LogFormatterWorkerName, lf.WorkerName))
+		fields["worker"] = lf.WorkerName
 	}
 
 	if lf.Duration > 0 {
-		bldr.WriteString(fmt.Sprintf("%s:%s, ", LogFormatterDuration, lf.Duration))
+		fields["duration"] = lf.Duration.String()
 	}
 
 	if lf.Host != "" {
-		bldr.WriteString(fmt.Sprintf("%s:%s, ", LogFormatterHostName, lf.Host))
+		fields["host"] = lf.Host
+	}
+
+	if lf.Endpoint != "" {
+		fields["endpoint"] = lf.Endpoint
+	}
+
+	if lf.Table != "" {
+		fields["table"] = lf.Table
+	}
+
+	if lf.Chunk != "" {
+		fields["chunk"] = lf.Chunk
 	}
 
 	if lf.UpsertedCount > 0 {
-		bldr.WriteString(fmt.Sprintf("%s:%d, ", LogFormatterUpsertedCount, lf.UpsertedCount))
+		fields["upserted"] = lf.UpsertedCount
 	}
 
 	if lf.MatchedCount > 0 {
-		bldr.WriteString(fmt.Sprintf("%s:%d, ", LogFormatterMatchedCount, lf.MatchedCount))
+		fields["matched"] = lf.MatchedCount
 	}
 
-	if lf.Msg != "" {
-		bldr.WriteString(fmt.Sprintf("%s:%s, ", LogFormatterMsg, lf.Msg))
+	return fields
+}
+
+// Log will log the message at the level, with its data as fields, see "Fields". Nothing is logged if the logger is
+// nil.
+func (lf LogFormatter) Log(logger Logger, level LogLevel) {
+	if logger == nil {
+		return
 	}
 
-	return fmt.Sprintf("{%s}", strings.TrimSuffix(bldr.String(), ", "))
+	logger.Log(level, lf.Msg, lf.Fields())
 }
@@ -10,8 +10,13 @@
 package tools
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestLogFormatter(t *testing.T) {
@@ -82,4 +87,59 @@ func TestLogFormatter(t *testing.T) {
 			t.Errorf("expected '{w:1, worker:worker, d:1s, host:localhost, u:1, m:hello}', got '%s'", lf.String())
 		}
 	})
+	t.Run("request", func(t *testing.T) {
+		t.Parallel()
+		lf := LogFormatter{Endpoint: "/candles", Table: "candles", Chunk: "https://api.test/candles?start=1", Msg: "hello"}
+		if lf.String() != "{endpoint:/candles, table:candles, chunk:https://api.test/candles?start=1, m:hello}" {
+			t.Errorf("expected the request fields, got '%s'", lf.String())
+		}
+	})
+}
+
+func TestLogFormatterLog(t *testing.T) {
+	t.Parallel()
+
+	lf := LogFormatter{WorkerID: 1, WorkerName: "web", Table: "candles", UpsertedCount: 2, Msg: "hello"}
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+
+		logger := NewLogrusLogger(logrus.New())
+		logger.SetOutput(&out)
+
+		lf.Log(logger, LogLevelInfo)
+
+		if !strings.Contains(out.String(), lf.String()) {
+			t.Errorf("expected %q to contain %q", out.String(), lf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+
+		logger := NewLogrusLogger(logrus.New())
+		logger.SetOutput(&out)
+		logger.SetFormatter(&logrus.JSONFormatter{})
+
+		lf.Log(logger, LogLevelInfo)
+		lf.Log(logger, LogLevelDebug)
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatalf("expected one JSON entry, got %q: %v", out.String(), err)
+		}
+
+		want := map[string]interface{}{
+			"level": "info", "msg": "hello", "workerID": 1.0, "worker": "web", "table": "candles", "upserted": 2.0,
+		}
+		for key, value := range want {
+			if entry[key] != value {
+				t.Errorf("expected %s to be %v, got %v", key, value, entry[key])
+			}
+		}
+	})
 }
@@ -0,0 +1,149 @@
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package tools
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevel is the severity of a log message.
+type LogLevel int
+
+// Levels of log messages, from the most to the least severe.
+const (
+	LogLevelError LogLevel = iota
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+)
+
+// Logger logs the messages of a run, so that programs that embed gidari can route its logs through their own logger.
+// The fields are the structured data of the message, e.g. the table and the worker that it is about. Adapters are
+// provided for logrus, zap, and slog.
+type Logger interface {
+	Log(level LogLevel, msg string, fields map[string]interface{})
+}
+
+// fieldsLogger is a logger that logs every message with the same fields.
+type fieldsLogger struct {
+	logger Logger
+	fields map[string]interface{}
+}
+
+// WithFields will return a logger that adds the fields to every message that it logs with the logger, e.g. the ID of
+// the run that the messages are about. The fields of a message take precedence. If the logger is nil, nil is returned.
+func WithFields(logger Logger, fields map[string]interface{}) Logger {
+	if logger == nil {
+		return nil
+	}
+
+	return &fieldsLogger{logger: logger, fields: fields}
+}
+
+// Log will log the message at the level, with the fields of the logger.
+func (logger *fieldsLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	merged := make(map[string]interface{}, len(logger.fields)+len(fields))
+
+	for key, value := range logger.fields {
+		merged[key] = value
+	}
+
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	logger.logger.Log(level, msg, merged)
+}
+
+// LogrusLogger is a logger that logs with logrus. It is the logger of configurations that are parsed from YAML.
+//
+// Messages are logged as "LogFormatter.String" by the text formatter, and with their fields by the JSON formatter,
+// so that they can be indexed by a log pipeline. The methods of the logrus logger, e.g. "SetLevel", are promoted,
+// except for "Log", which is replaced by the method of "Logger".
+type LogrusLogger struct {
+	*logrus.Logger
+}
+
+// NewLogrusLogger will return a logger that logs with the logrus logger.
+func NewLogrusLogger(logger *logrus.Logger) *LogrusLogger {
+	return &LogrusLogger{Logger: logger}
+}
+
+// Log will log the message at the level.
+func (logger *LogrusLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	logrusLevel := logrusLevels[level]
+
+	if _, ok := logger.Formatter.(*logrus.JSONFormatter); ok {
+		logger.WithFields(fields).Log(logrusLevel, msg)
+
+		return
+	}
+
+	logger.Logger.Log(logrusLevel, formatText(msg, fields))
+}
+
+// logrusLevels are the logrus levels of each level.
+var logrusLevels = map[LogLevel]logrus.Level{
+	LogLevelError: logrus.ErrorLevel,
+	LogLevelWarn:  logrus.WarnLevel,
+	LogLevelInfo:  logrus.InfoLevel,
+	LogLevelDebug: logrus.DebugLevel,
+}
+
+// ZapSugaredLogger is the part of "*zap.SugaredLogger" that gidari logs with, so that zap loggers can be used without
+// gidari depending on zap.
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// ZapLogger is a logger that logs with zap.
+type ZapLogger struct {
+	sugar ZapSugaredLogger
+}
+
+// NewZapLogger will return a logger that logs with the sugared zap logger, e.g. "zap.NewProduction().Sugar()".
+func NewZapLogger(sugar ZapSugaredLogger) *ZapLogger {
+	return &ZapLogger{sugar: sugar}
+}
+
+// Log will log the message at the level, with the fields as its keys and values.
+func (logger *ZapLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	var keysAndValues []interface{}
+
+	for _, key := range sortedKeys(fields) {
+		keysAndValues = append(keysAndValues, key, fields[key])
+	}
+
+	switch level {
+	case LogLevelError:
+		logger.sugar.Errorw(msg, keysAndValues...)
+	case LogLevelWarn:
+		logger.sugar.Warnw(msg, keysAndValues...)
+	case LogLevelInfo:
+		logger.sugar.Infow(msg, keysAndValues...)
+	case LogLevelDebug:
+		logger.sugar.Debugw(msg, keysAndValues...)
+	}
+}
+
+// sortedKeys will return the keys of the fields in order, so that they are logged in the same order every time.
+func sortedKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
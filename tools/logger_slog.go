@@ -0,0 +1,43 @@
+//go:build go1.21
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package tools
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger is a logger that logs with slog, which is only available when gidari is built with Go 1.21 or later.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger will return a logger that logs with the slog logger, e.g. "slog.Default()".
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{logger: logger}
+}
+
+// Log will log the message at the level, with the fields as its attributes.
+func (logger *SlogLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, key := range sortedKeys(fields) {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+
+	logger.logger.LogAttrs(context.Background(), slogLevels[level], msg, attrs...)
+}
+
+// slogLevels are the slog levels of each level.
+var slogLevels = map[LogLevel]slog.Level{
+	LogLevelError: slog.LevelError,
+	LogLevelWarn:  slog.LevelWarn,
+	LogLevelInfo:  slog.LevelInfo,
+	LogLevelDebug: slog.LevelDebug,
+}
@@ -0,0 +1,33 @@
+//go:build utest && go1.21
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package tools
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	LogFormatter{WorkerID: 1, Table: "candles", Msg: "hello"}.Log(logger, LogLevelWarn)
+	LogFormatter{Msg: "hidden"}.Log(logger, LogLevelDebug)
+
+	if got := out.String(); !strings.Contains(got, `level=WARN msg=hello table=candles workerID=1`) ||
+		strings.Contains(got, "hidden") {
+		t.Errorf("expected one warning with the fields as attributes, got %q", got)
+	}
+}
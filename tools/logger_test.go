@@ -0,0 +1,77 @@
+//go:build utest
+
+// Copyright 2022 The Gidari Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// sugaredLogger records the messages that are logged with it, like "*zap.SugaredLogger".
+type sugaredLogger struct {
+	entries []string
+}
+
+func (sugar *sugaredLogger) log(level, msg string, keysAndValues []interface{}) {
+	sugar.entries = append(sugar.entries, fmt.Sprintf("%s %s %v", level, msg, keysAndValues))
+}
+
+func (sugar *sugaredLogger) Debugw(msg string, kv ...interface{}) { sugar.log("debug", msg, kv) }
+func (sugar *sugaredLogger) Infow(msg string, kv ...interface{})  { sugar.log("info", msg, kv) }
+func (sugar *sugaredLogger) Warnw(msg string, kv ...interface{})  { sugar.log("warn", msg, kv) }
+func (sugar *sugaredLogger) Errorw(msg string, kv ...interface{}) { sugar.log("error", msg, kv) }
+
+func TestZapLogger(t *testing.T) {
+	t.Parallel()
+
+	sugar := &sugaredLogger{}
+	logger := NewZapLogger(sugar)
+
+	lf := LogFormatter{WorkerID: 1, Table: "candles", Msg: "hello"}
+	lf.Log(logger, LogLevelInfo)
+	lf.Log(logger, LogLevelError)
+	LogFormatter{Msg: "bye"}.Log(logger, LogLevelDebug)
+
+	want := []string{
+		"info hello [table candles workerID 1]",
+		"error hello [table candles workerID 1]",
+		"debug bye []",
+	}
+	if !reflect.DeepEqual(sugar.entries, want) {
+		t.Errorf("expected %q, got %q", want, sugar.entries)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	t.Parallel()
+
+	if WithFields(nil, map[string]interface{}{"runID": "abc"}) != nil {
+		t.Fatalf("expected a nil logger to stay nil")
+	}
+
+	sugar := &sugaredLogger{}
+	logger := WithFields(NewZapLogger(sugar), map[string]interface{}{"runID": "abc"})
+
+	LogFormatter{Table: "candles", Msg: "hello"}.Log(logger, LogLevelInfo)
+	LogFormatter{Msg: "bye"}.Log(logger, LogLevelDebug)
+
+	want := []string{
+		"info hello [runID abc table candles]",
+		"debug bye [runID abc]",
+	}
+	if !reflect.DeepEqual(sugar.entries, want) {
+		t.Errorf("expected %q, got %q", want, sugar.entries)
+	}
+
+	if text := formatText("hello", map[string]interface{}{"runID": "abc", "workerID": 1}); text != "{run:abc, w:1, m:hello}" {
+		t.Errorf("expected the run to be formatted first, got %q", text)
+	}
+}
@@ -7,5 +7,60 @@
 //	http://www.apache.org/licenses/LICENSE-2.0
 package version
 
+import (
+	"runtime"
+	"runtime/debug"
+)
+
 // Gidari is the version of the Gidari library and CLI.
 const Gidari = "v0.0.0-alpha"
+
+// Commit and Date are the commit and date that the binary was built from. They are set at build time with
+// -ldflags "-X github.com/alpstable/gidari/version.Commit=<sha> -X github.com/alpstable/gidari/version.Date=<date>",
+// see the Makefile.
+var (
+	Commit string
+	Date   string
+)
+
+// Build is the version of a binary with the metadata of its build.
+type Build struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+	Platform  string
+}
+
+// ReadBuild will return the metadata of the build of the running binary. The commit and date fall back to the
+// version control information that Go embeds in binaries built from a repository, and are "unknown" otherwise.
+func ReadBuild() Build {
+	build := Build{
+		Version:   Gidari,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && build.Commit == "":
+				build.Commit = setting.Value
+			case setting.Key == "vcs.time" && build.Date == "":
+				build.Date = setting.Value
+			}
+		}
+	}
+
+	if build.Commit == "" {
+		build.Commit = "unknown"
+	}
+
+	if build.Date == "" {
+		build.Date = "unknown"
+	}
+
+	return build
+}